@@ -0,0 +1,194 @@
+package aggregator
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/songvi/robo/models"
+)
+
+// maxCycles is the number of cycles whose statistics are kept; the least recently
+// updated cycle is dropped first
+const maxCycles = 64
+
+// Aggregator keeps streaming statistics of the job results of recent cycles as they
+// arrive, so reports do not scan the raw jobs
+type Aggregator interface {
+	// Record adds the result of a finished job; other jobs are ignored
+	Record(job *models.Job)
+	// CycleStats returns the statistics of a cycle, or false when none are kept
+	CycleStats(cycleUUID string) (models.CycleStats, bool)
+	// TakeRollups returns the per-minute rollups of the results recorded since it was
+	// last called
+	TakeRollups() []models.MetricRollup
+}
+
+// rollupKey identifies the rollup of one action of a cycle within one minute
+type rollupKey struct {
+	cycleUUID, action string
+	minute            int64
+}
+
+// actionAggregate accumulates the results of one action
+type actionAggregate struct {
+	durations Histogram
+	failed    int
+}
+
+// cycleAggregate accumulates the results of one cycle
+type cycleAggregate struct {
+	updated time.Time
+	actions map[string]*actionAggregate
+	minutes map[int64]map[string]*actionAggregate  // Start of the minute -> action
+	targets map[string]map[string]*actionAggregate // Strategy target -> action
+}
+
+// aggregatorImpl is the implementation of the Aggregator interface
+type aggregatorImpl struct {
+	mu      sync.Mutex
+	cycles  map[string]*cycleAggregate
+	rollups map[rollupKey]*models.MetricRollup // Not taken yet
+}
+
+// NewAggregator creates an empty Aggregator
+func NewAggregator() Aggregator {
+	return &aggregatorImpl{
+		cycles:  make(map[string]*cycleAggregate),
+		rollups: make(map[rollupKey]*models.MetricRollup),
+	}
+}
+
+// Record adds the result of a completed or failed job
+func (a *aggregatorImpl) Record(job *models.Job) {
+	if job.Status != "completed" && job.Status != "failed" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.cycles[job.CycleUUID]
+	if !ok {
+		a.evict()
+		c = &cycleAggregate{
+			actions: make(map[string]*actionAggregate),
+			minutes: make(map[int64]map[string]*actionAggregate),
+			targets: make(map[string]map[string]*actionAggregate),
+		}
+		a.cycles[job.CycleUUID] = c
+	}
+	c.updated = time.Now()
+
+	minute := job.DoneAt - job.DoneAt%60
+	key := rollupKey{cycleUUID: job.CycleUUID, action: job.Name, minute: minute}
+	rollup, ok := a.rollups[key]
+	if !ok {
+		rollup = &models.MetricRollup{CycleUUID: job.CycleUUID, Action: job.Name, Minute: minute}
+		a.rollups[key] = rollup
+	}
+	rollup.Record(job.DurationMs, job.Status == "failed")
+
+	if c.minutes[minute] == nil {
+		c.minutes[minute] = make(map[string]*actionAggregate)
+	}
+	aggregates := []map[string]*actionAggregate{c.actions, c.minutes[minute]}
+	if job.Target != "" {
+		if c.targets[job.Target] == nil {
+			c.targets[job.Target] = make(map[string]*actionAggregate)
+		}
+		aggregates = append(aggregates, c.targets[job.Target])
+	}
+	for _, actions := range aggregates {
+		agg, ok := actions[job.Name]
+		if !ok {
+			agg = &actionAggregate{}
+			actions[job.Name] = agg
+		}
+		agg.durations.Record(job.DurationMs)
+		if job.Status == "failed" {
+			agg.failed++
+		}
+	}
+}
+
+// evict drops the least recently updated cycle once maxCycles are kept
+func (a *aggregatorImpl) evict() {
+	if len(a.cycles) < maxCycles {
+		return
+	}
+	var oldest string
+	for uuid, c := range a.cycles {
+		if oldest == "" || c.updated.Before(a.cycles[oldest].updated) {
+			oldest = uuid
+		}
+	}
+	delete(a.cycles, oldest)
+}
+
+// CycleStats returns the statistics of a cycle
+func (a *aggregatorImpl) CycleStats(cycleUUID string) (models.CycleStats, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c, ok := a.cycles[cycleUUID]
+	if !ok {
+		return models.CycleStats{}, false
+	}
+
+	stats := models.CycleStats{CycleUUID: cycleUUID, Actions: summarize(c.actions)}
+	minutes := make([]int64, 0, len(c.minutes))
+	for minute := range c.minutes {
+		minutes = append(minutes, minute)
+	}
+	slices.Sort(minutes)
+	for _, minute := range minutes {
+		stats.Minutes = append(stats.Minutes, models.MinuteStats{Minute: minute, Actions: summarize(c.minutes[minute])})
+	}
+	for target, actions := range c.targets {
+		if stats.Targets == nil {
+			stats.Targets = make(map[string]map[string]models.ActionStats, len(c.targets))
+		}
+		stats.Targets[target] = summarize(actions)
+	}
+	return stats, true
+}
+
+// TakeRollups returns the rollups recorded since the last call
+func (a *aggregatorImpl) TakeRollups() []models.MetricRollup {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rollups := make([]models.MetricRollup, 0, len(a.rollups))
+	for _, rollup := range a.rollups {
+		rollups = append(rollups, *rollup)
+	}
+	clear(a.rollups)
+	return rollups
+}
+
+// summarize turns the aggregates of each action into ActionStats
+func summarize(actions map[string]*actionAggregate) map[string]models.ActionStats {
+	out := make(map[string]models.ActionStats, len(actions))
+	for name, agg := range actions {
+		h := &agg.durations
+		out[name] = models.ActionStats{
+			Jobs:   int(h.Count()),
+			Failed: agg.failed,
+			MinMs:  h.Min(),
+			MaxMs:  h.Max(),
+			MeanMs: h.Mean(),
+			P50Ms:  h.Quantile(0.50),
+			P90Ms:  h.Quantile(0.90),
+			P95Ms:  h.Quantile(0.95),
+			P99Ms:  h.Quantile(0.99),
+		}
+	}
+	return out
+}
+
+// Module defines the Fx module for the result aggregator, which saves its rollups
+var Module = fx.Module(
+	"aggregator",
+	fx.Provide(NewAggregator),
+	fx.Invoke(startRollups),
+)
@@ -0,0 +1,80 @@
+package aggregator
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var h Histogram
+	values := make([]int64, 100000)
+	for i := range values {
+		values[i] = int64(rng.ExpFloat64() * 800)
+		h.Record(values[i])
+	}
+	slices.Sort(values)
+
+	assert.Equal(t, uint64(len(values)), h.Count())
+	assert.Equal(t, values[0], h.Min())
+	assert.Equal(t, values[len(values)-1], h.Max())
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99} {
+		exact := values[int(q*float64(len(values)))-1]
+		assert.InEpsilon(t, exact, h.Quantile(q), 0.011, "q=%v", q)
+	}
+	assert.LessOrEqual(t, len(h.buckets), 1000, "memory does not grow with the values")
+
+	var merged Histogram
+	merged.Merge(&h)
+	merged.Merge(&Histogram{})
+	assert.Equal(t, h.Quantile(0.95), merged.Quantile(0.95))
+	assert.Zero(t, (&Histogram{}).Quantile(0.5))
+}
+
+func TestAggregatorCycleStats(t *testing.T) {
+	a := NewAggregator()
+	for i := 0; i < 10; i++ {
+		a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: int64(100 * (i + 1)), DoneAt: 1200 + int64(i)*10})
+	}
+	a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "failed", DurationMs: 50, DoneAt: 1300})
+	a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "cancelled", DoneAt: 1300})
+
+	stats, ok := a.CycleStats("c1")
+	require.True(t, ok)
+	upload := stats.Actions["upload_file"]
+	assert.Equal(t, 11, upload.Jobs)
+	assert.Equal(t, 1, upload.Failed)
+	assert.Equal(t, int64(50), upload.MinMs)
+	assert.Equal(t, int64(1000), upload.MaxMs)
+
+	require.Len(t, stats.Minutes, 2)
+	assert.Equal(t, int64(1200), stats.Minutes[0].Minute)
+	assert.Equal(t, 6, stats.Minutes[0].Actions["upload_file"].Jobs)
+	assert.Equal(t, 5, stats.Minutes[1].Actions["upload_file"].Jobs)
+
+	_, ok = a.CycleStats("c2")
+	assert.False(t, ok)
+}
+
+func TestAggregatorTargetStats(t *testing.T) {
+	a := NewAggregator()
+	a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: 100, Target: "blue"})
+	a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "failed", DurationMs: 300, Target: "green"})
+
+	stats, ok := a.CycleStats("c1")
+	require.True(t, ok)
+	assert.Equal(t, 2, stats.Actions["upload_file"].Jobs)
+	require.Len(t, stats.Targets, 2)
+	assert.Equal(t, int64(100), stats.Targets["blue"]["upload_file"].MaxMs)
+	assert.Equal(t, 1, stats.Targets["green"]["upload_file"].Failed)
+
+	a.Record(&models.Job{CycleUUID: "c2", Name: "upload_file", Status: "completed"})
+	stats, _ = a.CycleStats("c2")
+	assert.Nil(t, stats.Targets, "cycles without targets have no target stats")
+}
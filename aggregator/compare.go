@@ -0,0 +1,87 @@
+package aggregator
+
+import (
+	"github.com/songvi/robo/models"
+)
+
+// Summarize returns the statistics of the completed and failed jobs of jobs per action
+func Summarize(jobs []models.Job) map[string]models.ActionStats {
+	actions := make(map[string]*actionAggregate)
+	for _, job := range jobs {
+		if job.Status != "completed" && job.Status != "failed" {
+			continue
+		}
+		agg, ok := actions[job.Name]
+		if !ok {
+			agg = &actionAggregate{}
+			actions[job.Name] = agg
+		}
+		agg.durations.Record(job.DurationMs)
+		if job.Status == "failed" {
+			agg.failed++
+		}
+	}
+	return summarize(actions)
+}
+
+// CompareCycles compares the job results of a candidate cycle with those of a baseline
+// action by action. An action regresses when its p95 latency grew by more than
+// maxLatencyPct percent or its throughput dropped by more than maxThroughputPct percent;
+// actions missing from either cycle do not.
+func CompareCycles(baseline, candidate *models.Cycle, baselineJobs, candidateJobs []models.Job, maxLatencyPct, maxThroughputPct float64) models.CycleComparison {
+	comparison := models.CycleComparison{
+		Template:                   candidate.Template,
+		Baseline:                   baseline.UUID,
+		Candidate:                  candidate.UUID,
+		MaxLatencyRegressionPct:    maxLatencyPct,
+		MaxThroughputRegressionPct: maxThroughputPct,
+		Actions:                    make(map[string]models.ActionComparison),
+	}
+	before, after := Summarize(baselineJobs), Summarize(candidateJobs)
+	beforeSeconds, afterSeconds := loadSeconds(baseline), loadSeconds(candidate)
+
+	for name, b := range before {
+		c := models.ActionComparison{Baseline: &b, BaselineThroughput: float64(b.Jobs) / beforeSeconds}
+		comparison.Actions[name] = c
+	}
+	for name, a := range after {
+		c := comparison.Actions[name]
+		c.Candidate = &a
+		c.CandidateThroughput = float64(a.Jobs) / afterSeconds
+		if b := c.Baseline; b != nil {
+			c.P50DeltaPct = deltaPct(float64(b.P50Ms), float64(a.P50Ms))
+			c.P95DeltaPct = deltaPct(float64(b.P95Ms), float64(a.P95Ms))
+			c.P99DeltaPct = deltaPct(float64(b.P99Ms), float64(a.P99Ms))
+			c.ThroughputDeltaPct = deltaPct(c.BaselineThroughput, c.CandidateThroughput)
+			c.ErrorRateDelta = errorRate(a) - errorRate(*b)
+			c.Regressed = c.P95DeltaPct > maxLatencyPct || -c.ThroughputDeltaPct > maxThroughputPct
+		}
+		comparison.Actions[name] = c
+		comparison.Regressed = comparison.Regressed || c.Regressed
+	}
+	return comparison
+}
+
+// loadSeconds is how long the load phase of a finished cycle lasted, at least a second
+func loadSeconds(cycle *models.Cycle) float64 {
+	start := cycle.StartedAt
+	if cycle.LoadStartedAt > 0 {
+		start = cycle.LoadStartedAt
+	}
+	return float64(max(cycle.DoneAt-start, 1))
+}
+
+// deltaPct is the change from before to after in percent of before; zero when before is
+func deltaPct(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / before * 100
+}
+
+func errorRate(stats models.ActionStats) float64 {
+	if stats.Jobs == 0 {
+		return 0
+	}
+	return float64(stats.Failed) / float64(stats.Jobs)
+}
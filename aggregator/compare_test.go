@@ -0,0 +1,47 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestCompareCycles(t *testing.T) {
+	results := func(action string, n int, durationMs int64, failed int) []models.Job {
+		jobs := make([]models.Job, n)
+		for i := range jobs {
+			jobs[i] = models.Job{Name: action, Status: "completed", DurationMs: durationMs}
+			if i < failed {
+				jobs[i].Status = "failed"
+			}
+		}
+		return jobs
+	}
+	baseline := &models.Cycle{UUID: "b", Template: "smoke", StartedAt: 0, DoneAt: 100}
+	candidate := &models.Cycle{UUID: "c", Template: "smoke", StartedAt: 0, LoadStartedAt: 20, DoneAt: 120}
+
+	before := append(results("upload_file", 100, 1000, 0), results("consult_file", 100, 200, 0)...)
+	after := append(results("upload_file", 100, 1300, 5), results("consult_file", 100, 200, 0)...)
+	after = append(after, results("delete_file", 10, 50, 0)...)
+
+	comparison := CompareCycles(baseline, candidate, before, after, 10, 10)
+	assert.True(t, comparison.Regressed)
+	assert.Equal(t, "smoke", comparison.Template)
+
+	upload := comparison.Actions["upload_file"]
+	assert.True(t, upload.Regressed)
+	assert.InDelta(t, 30, upload.P95DeltaPct, 1)
+	assert.InDelta(t, 0, upload.ThroughputDeltaPct, 0.01, "throughput counts the load phase only")
+	assert.InDelta(t, 0.05, upload.ErrorRateDelta, 0.001)
+
+	assert.False(t, comparison.Actions["consult_file"].Regressed)
+	deleted := comparison.Actions["delete_file"]
+	require.NotNil(t, deleted.Candidate)
+	assert.Nil(t, deleted.Baseline)
+	assert.False(t, deleted.Regressed, "new actions do not regress")
+
+	assert.False(t, CompareCycles(baseline, candidate, before, after, 50, 10).Regressed)
+}
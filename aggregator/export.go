@@ -0,0 +1,169 @@
+package aggregator
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/songvi/robo/models"
+)
+
+// ErrExportFormat is returned for an export format other than "csv" and "parquet"
+var ErrExportFormat = errors.New("unsupported export format")
+
+// JobRow is a job as exported, without its input and output data
+type JobRow struct {
+	UUID         string `parquet:"uuid"`
+	CycleUUID    string `parquet:"cycle_uuid"`
+	SessionID    string `parquet:"session_id"`
+	Name         string `parquet:"name"`
+	Phase        string `parquet:"phase"`
+	Target       string `parquet:"target"`
+	Status       string `parquet:"status"`
+	WorkerID     string `parquet:"worker_id"`
+	StartAt      int64  `parquet:"start_at"`
+	DoneAt       int64  `parquet:"done_at"`
+	DispatchedAt int64  `parquet:"dispatched_at"`
+	DurationMs   int64  `parquet:"duration_ms"`
+	Error        string `parquet:"error"`
+}
+
+var jobColumns = []string{"uuid", "cycle_uuid", "session_id", "name", "phase", "target", "status",
+	"worker_id", "start_at", "done_at", "dispatched_at", "duration_ms", "error"}
+
+func (r JobRow) record() []string {
+	return []string{r.UUID, r.CycleUUID, r.SessionID, r.Name, r.Phase, r.Target, r.Status, r.WorkerID,
+		strconv.FormatInt(r.StartAt, 10), strconv.FormatInt(r.DoneAt, 10), strconv.FormatInt(r.DispatchedAt, 10),
+		strconv.FormatInt(r.DurationMs, 10), r.Error}
+}
+
+func newJobRow(job models.Job) JobRow {
+	return JobRow{
+		UUID: job.UUID, CycleUUID: job.CycleUUID, SessionID: job.SessionID, Name: job.Name, Phase: job.Phase,
+		Target: job.Target, Status: job.Status, WorkerID: job.WorkerID, StartAt: job.StartAt, DoneAt: job.DoneAt,
+		DispatchedAt: job.DispatchedAt, DurationMs: job.DurationMs, Error: job.Error,
+	}
+}
+
+// StatsRow is the statistics of one action as exported
+type StatsRow struct {
+	Action string  `parquet:"action"`
+	Jobs   int64   `parquet:"jobs"`
+	Failed int64   `parquet:"failed"`
+	MinMs  int64   `parquet:"min_ms"`
+	MaxMs  int64   `parquet:"max_ms"`
+	MeanMs float64 `parquet:"mean_ms"`
+	P50Ms  int64   `parquet:"p50_ms"`
+	P90Ms  int64   `parquet:"p90_ms"`
+	P95Ms  int64   `parquet:"p95_ms"`
+	P99Ms  int64   `parquet:"p99_ms"`
+}
+
+var statsColumns = []string{"action", "jobs", "failed", "min_ms", "max_ms", "mean_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms"}
+
+func (r StatsRow) record() []string {
+	record := []string{r.Action}
+	for _, n := range []int64{r.Jobs, r.Failed, r.MinMs, r.MaxMs} {
+		record = append(record, strconv.FormatInt(n, 10))
+	}
+	record = append(record, strconv.FormatFloat(r.MeanMs, 'f', 3, 64))
+	for _, n := range []int64{r.P50Ms, r.P90Ms, r.P95Ms, r.P99Ms} {
+		record = append(record, strconv.FormatInt(n, 10))
+	}
+	return record
+}
+
+// JobExporter writes jobs to an export, which is complete once closed
+type JobExporter interface {
+	Write(jobs []models.Job) error
+	Close() error
+}
+
+// NewJobExporter returns a JobExporter writing format, "csv" or "parquet", to w
+func NewJobExporter(w io.Writer, format string) (JobExporter, error) {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(jobColumns); err != nil {
+			return nil, err
+		}
+		return &csvJobExporter{w: cw}, nil
+	case "parquet":
+		return &parquetJobExporter{w: parquet.NewGenericWriter[JobRow](w)}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrExportFormat, format)
+}
+
+type csvJobExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvJobExporter) Write(jobs []models.Job) error {
+	for _, job := range jobs {
+		if err := e.w.Write(newJobRow(job).record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvJobExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type parquetJobExporter struct {
+	w *parquet.GenericWriter[JobRow]
+}
+
+func (e *parquetJobExporter) Write(jobs []models.Job) error {
+	rows := make([]JobRow, len(jobs))
+	for i, job := range jobs {
+		rows[i] = newJobRow(job)
+	}
+	_, err := e.w.Write(rows)
+	return err
+}
+
+func (e *parquetJobExporter) Close() error {
+	return e.w.Close()
+}
+
+// ExportStats writes the statistics of every action, by name, to w in format, "csv" or
+// "parquet"
+func ExportStats(w io.Writer, format string, stats map[string]models.ActionStats) error {
+	actions := make([]string, 0, len(stats))
+	for action := range stats {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	rows := make([]StatsRow, len(actions))
+	for i, action := range actions {
+		s := stats[action]
+		rows[i] = StatsRow{Action: action, Jobs: int64(s.Jobs), Failed: int64(s.Failed), MinMs: s.MinMs, MaxMs: s.MaxMs,
+			MeanMs: s.MeanMs, P50Ms: s.P50Ms, P90Ms: s.P90Ms, P95Ms: s.P95Ms, P99Ms: s.P99Ms}
+	}
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(statsColumns)
+		for _, row := range rows {
+			cw.Write(row.record())
+		}
+		cw.Flush()
+		return cw.Error()
+	case "parquet":
+		pw := parquet.NewGenericWriter[StatsRow](w)
+		if _, err := pw.Write(rows); err != nil {
+			return err
+		}
+		return pw.Close()
+	}
+	return fmt.Errorf("%w: %q", ErrExportFormat, format)
+}
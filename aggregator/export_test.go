@@ -0,0 +1,64 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestExport(t *testing.T) {
+	jobs := []models.Job{
+		{UUID: "j1", CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: 120, InputData: []byte(`{"secret":1}`)},
+		{UUID: "j2", CycleUUID: "c1", Name: "upload_file", Status: "failed", DurationMs: 80, Error: "timeout, retried"},
+	}
+
+	var buf bytes.Buffer
+	exporter, err := NewJobExporter(&buf, "csv")
+	require.NoError(t, err)
+	require.NoError(t, exporter.Write(jobs[:1]))
+	require.NoError(t, exporter.Write(jobs[1:]))
+	require.NoError(t, exporter.Close())
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, jobColumns, records[0])
+	assert.Equal(t, "j1", records[1][0])
+	assert.Equal(t, "timeout, retried", records[2][len(jobColumns)-1])
+	assert.NotContains(t, buf.String(), "secret", "input data is not exported")
+
+	buf.Reset()
+	exporter, err = NewJobExporter(&buf, "parquet")
+	require.NoError(t, err)
+	require.NoError(t, exporter.Write(jobs))
+	require.NoError(t, exporter.Close())
+	rows, err := parquet.Read[JobRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, JobRow{UUID: "j2", CycleUUID: "c1", Name: "upload_file", Status: "failed", DurationMs: 80, Error: "timeout, retried"}, rows[1])
+
+	_, err = NewJobExporter(&buf, "xlsx")
+	assert.ErrorIs(t, err, ErrExportFormat)
+
+	stats := Summarize(jobs)
+	buf.Reset()
+	require.NoError(t, ExportStats(&buf, "parquet", stats))
+	statsRows, err := parquet.Read[StatsRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, statsRows, 1)
+	assert.Equal(t, "upload_file", statsRows[0].Action)
+	assert.Equal(t, int64(2), statsRows[0].Jobs)
+	assert.Equal(t, int64(1), statsRows[0].Failed)
+
+	buf.Reset()
+	require.NoError(t, ExportStats(&buf, "csv", stats))
+	records, err = csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"upload_file", "2", "1"}, records[1][:3])
+}
@@ -0,0 +1,115 @@
+package aggregator
+
+import (
+	"math"
+	"slices"
+)
+
+// histogramGrowth is the ratio between the bounds of consecutive buckets, which keeps
+// quantiles within 1% of the recorded values
+const histogramGrowth = 1.02
+
+var logGrowth = math.Log(histogramGrowth)
+
+// Histogram records non-negative values, such as latencies in milliseconds, in
+// logarithmic buckets, so it answers quantiles in constant memory whatever the number
+// of values. The zero value is ready to use.
+type Histogram struct {
+	buckets map[int]uint64 // Bucket index -> count; values below 1 go to bucket -1
+	count   uint64
+	sum     float64
+	min     int64
+	max     int64
+}
+
+// bucketOf returns the index of the bucket holding v
+func bucketOf(v int64) int {
+	if v < 1 {
+		return -1
+	}
+	return int(math.Floor(math.Log(float64(v)) / logGrowth))
+}
+
+// bucketValue returns the value a bucket stands for, the middle of its bounds
+func bucketValue(i int) int64 {
+	if i < 0 {
+		return 0
+	}
+	return int64(math.Round(math.Pow(histogramGrowth, float64(i)+0.5)))
+}
+
+// Record adds one value
+func (h *Histogram) Record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	if h.buckets == nil {
+		h.buckets = make(map[int]uint64)
+	}
+	h.buckets[bucketOf(v)]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += float64(v)
+}
+
+// Merge adds every value of other
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+	if h.buckets == nil {
+		h.buckets = make(map[int]uint64, len(other.buckets))
+	}
+	for i, n := range other.buckets {
+		h.buckets[i] += n
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	h.max = max(h.max, other.max)
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Count returns the number of values recorded
+func (h *Histogram) Count() uint64 { return h.count }
+
+// Min and Max return the exact extremes of the values, or 0 when there are none
+func (h *Histogram) Min() int64 { return h.min }
+func (h *Histogram) Max() int64 { return h.max }
+
+// Mean returns the exact mean of the values, or 0 when there are none
+func (h *Histogram) Mean() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / float64(h.count)
+}
+
+// Quantile returns the nearest-rank q-quantile (0 < q <= 1) of the values, within 1%,
+// or 0 when there are none
+func (h *Histogram) Quantile(q float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	rank = min(max(rank, 1), h.count)
+
+	indexes := make([]int, 0, len(h.buckets))
+	for i := range h.buckets {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	var seen uint64
+	for _, i := range indexes {
+		if seen += h.buckets[i]; seen >= rank {
+			return min(max(bucketValue(i), h.min), h.max)
+		}
+	}
+	return h.max
+}
@@ -0,0 +1,67 @@
+package aggregator
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// rollupInterval is how often the rollups of job results are saved
+const rollupInterval = 15 * time.Second
+
+// rollupWriter saves the rollups of an Aggregator to the store, keeping those it failed
+// to save for the next attempt
+type rollupWriter struct {
+	results Aggregator
+	store   store.Store
+	logger  logger.Logger
+	failed  []models.MetricRollup
+}
+
+// flush saves the rollups taken since the last flush and those it failed to save
+func (w *rollupWriter) flush(ctx context.Context) {
+	rollups := append(w.failed, w.results.TakeRollups()...)
+	if err := w.store.MergeMetricRollups(ctx, rollups); err != nil {
+		w.logger.Error(ctx, "Failed to save metric rollups", "rollups", len(rollups), "error", err)
+		w.failed = rollups
+		return
+	}
+	w.failed = nil
+}
+
+// startRollups saves the rollups of results every rollupInterval, and once more when
+// the application stops
+func startRollups(lc fx.Lifecycle, results Aggregator, store store.Store, logger logger.Logger) {
+	w := &rollupWriter{results: results, store: store, logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(rollupInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						w.flush(ctx)
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			<-done
+			w.flush(stopCtx)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// rollupStore saves rollups once it stops failing
+type rollupStore struct {
+	store.Store
+	fail  bool
+	saved []models.MetricRollup
+}
+
+func (s *rollupStore) MergeMetricRollups(ctx context.Context, rollups []models.MetricRollup) error {
+	if s.fail {
+		return errors.New("database is down")
+	}
+	s.saved = append(s.saved, rollups...)
+	return nil
+}
+
+func TestRollups(t *testing.T) {
+	a := NewAggregator()
+	for i := 0; i < 10; i++ {
+		a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: int64(20 * (i + 1)), DoneAt: 1200 + int64(i)*10})
+	}
+	a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "failed", DurationMs: 20000, DoneAt: 1250})
+	a.Record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "cancelled", DoneAt: 1250})
+
+	st := &rollupStore{fail: true}
+	w := &rollupWriter{results: a, store: st, logger: logger.NewSlogLogger()}
+	w.flush(context.Background())
+	assert.Len(t, w.failed, 2, "rollups the store failed to save are kept")
+	a.Record(&models.Job{CycleUUID: "c1", Name: "login", Status: "completed", DurationMs: 5, DoneAt: 1310})
+	st.fail = false
+	w.flush(context.Background())
+	assert.Empty(t, w.failed)
+	assert.Empty(t, a.TakeRollups(), "rollups are taken once")
+
+	require.Len(t, st.saved, 3)
+	slices.SortFunc(st.saved, func(a, b models.MetricRollup) int { return int(a.Minute - b.Minute) })
+	first := st.saved[0]
+	assert.Equal(t, int64(1200), first.Minute)
+	assert.Equal(t, int64(7), first.Jobs)
+	assert.Equal(t, int64(1), first.Failed)
+	assert.Equal(t, int64(20), first.DurationMinMs)
+	assert.Equal(t, int64(20000), first.DurationMaxMs)
+	assert.Equal(t, []int64{0, 1, 1, 3, 1, 0, 0, 0, 0, 0, 1}, first.Buckets)
+	assert.Equal(t, int64(100), first.QuantileMs(0.5))
+	assert.Equal(t, int64(20000), first.QuantileMs(0.99), "the last bucket yields the maximum")
+}
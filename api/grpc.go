@@ -0,0 +1,557 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+
+	robov1 "github.com/songvi/robo/api/proto/robo/v1"
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/job"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// GRPC returns the control-plane API as a gRPC server, with the rate limits, projects
+// and creators of the HTTP API applied by interceptors
+func (s *Server) GRPC() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	robov1.RegisterCycleServiceServer(server, cycleService{Server: s})
+	robov1.RegisterTemplateServiceServer(server, templateService{Server: s})
+	robov1.RegisterJobServiceServer(server, jobService{Server: s})
+	robov1.RegisterWorkerServiceServer(server, workerService{Server: s})
+	robov1.RegisterProjectServiceServer(server, projectService{Server: s})
+	return server
+}
+
+// serveGRPC serves the gRPC API on addr for the lifetime of the application
+func (s *Server) serveGRPC(lc fx.Lifecycle, addr string) {
+	server := s.GRPC()
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %v", addr, err)
+			}
+			go func() {
+				if err := server.Serve(lis); err != nil {
+					s.logger.Error(context.Background(), "gRPC server stopped", "addr", addr, "error", err)
+				}
+			}()
+			s.logger.Info(ctx, "gRPC server listening", "addr", addr)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// Watches only end with their cycle, so they are cut once ctx expires
+			stopped := make(chan struct{})
+			go func() {
+				server.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				server.Stop()
+			}
+			return nil
+		},
+	})
+}
+
+// startsCycleMethod reports whether the gRPC method starts a cycle
+func startsCycleMethod(method string) bool {
+	return method == robov1.CycleService_StartCycle_FullMethodName ||
+		method == robov1.TemplateService_StartTemplateCycle_FullMethodName
+}
+
+// admitCall applies the rate limits to a call and scopes its context to the project
+// and creator of its metadata, as withProject and withCreator do for HTTP requests.
+// The returned release frees the concurrency slot of the call.
+func (s *Server) admitCall(ctx context.Context, method string) (context.Context, func(), error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	apiKey, project := firstValue(md, "x-api-key"), firstValue(md, "x-project")
+	addr := peerAddr(ctx)
+	release, retryAfter, ok := s.limiter.admit(s.limiter.tenant(apiKey, addr), startsCycleMethod(method))
+	if !ok {
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", retryAfter.Round(time.Second))
+	}
+	ctx = models.WithCreator(ctx, creatorOf(apiKey, addr))
+	if project != "" {
+		if _, err := s.store.GetProject(ctx, project); err != nil {
+			release()
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				err = fmt.Errorf("%w: %s", store.ErrUnknownProject, project)
+			}
+			return nil, nil, grpcError(err)
+		}
+		ctx = models.WithProject(ctx, project)
+	}
+	return ctx, release, nil
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, release, err := s.admitCall(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, release, err := s.admitCall(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return handler(srv, scopedStream{ServerStream: ss, ctx: ctx})
+}
+
+// scopedStream is a server stream with the context set by admitCall
+type scopedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s scopedStream) Context() context.Context {
+	return s.ctx
+}
+
+// firstValue returns the first value of key in md, or ""
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerAddr returns the host of the client of ctx
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcError maps err to the status code of its HTTP counterpart in writeStoreError and
+// the handlers
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, store.ErrUnknownProject),
+		errors.Is(err, store.ErrCycleNotFound), errors.Is(err, dispatcher.ErrUnknownWorker):
+		code = codes.NotFound
+	case errors.Is(err, store.ErrDuplicateUUID):
+		code = codes.AlreadyExists
+	case errors.Is(err, store.ErrQuotaExceeded):
+		code = codes.PermissionDenied
+	case errors.Is(err, job.ErrInvalidStrategy), errors.Is(err, store.ErrInvalidPage):
+		code = codes.InvalidArgument
+	case errors.Is(err, job.ErrCycleState), errors.Is(err, job.ErrJobState), errors.Is(err, store.ErrConflict):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
+
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// cycleMessage returns the cycle with its current progress
+func (s *Server) cycleMessage(ctx context.Context, uuid string) (*robov1.Cycle, error) {
+	cycle, err := s.store.GetCycle(ctx, uuid)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if cycle.Progress, err = s.jobs.CycleProgress(ctx, uuid); err != nil {
+		return nil, grpcError(err)
+	}
+	msg, err := toCycleMessage(cycle)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return msg, nil
+}
+
+// startedCycle answers a call that started cycle
+func startedCycle(cycle models.Cycle, err error) (*robov1.Cycle, error) {
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	msg, err := toCycleMessage(&cycle)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return msg, nil
+}
+
+// cycleService implements robov1.CycleServiceServer
+type cycleService struct {
+	robov1.UnimplementedCycleServiceServer
+	*Server
+}
+
+func (c cycleService) StartCycle(ctx context.Context, req *robov1.StartCycleRequest) (*robov1.Cycle, error) {
+	strategy, err := fromStrategyJSON(req.GetStrategyJson())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := validateLabels(req.GetLabels()); err != nil {
+		return nil, invalidArgument(err)
+	}
+	// Planning outlives a client that stops waiting
+	return startedCycle(c.jobs.StartCycle(context.WithoutCancel(ctx), models.Cycle{
+		Name: req.GetName(), Description: req.GetDescription(), Labels: req.GetLabels(), Seed: req.GetSeed(), Strategy: strategy,
+	}))
+}
+
+func (c cycleService) ValidateCycle(ctx context.Context, req *robov1.StartCycleRequest) (*robov1.CyclePlan, error) {
+	strategy, err := fromStrategyJSON(req.GetStrategyJson())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	plan, err := c.jobs.ValidateCycle(ctx, models.Cycle{Name: req.GetName(), Seed: req.GetSeed(), Strategy: strategy})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPlanMessage(plan), nil
+}
+
+func (c cycleService) GetCycle(ctx context.Context, req *robov1.GetCycleRequest) (*robov1.Cycle, error) {
+	return c.cycleMessage(ctx, req.GetUuid())
+}
+
+func (c cycleService) ListCycles(ctx context.Context, req *robov1.ListCyclesRequest) (*robov1.ListCyclesResponse, error) {
+	cycles, err := c.store.ListCycles(ctx, store.CycleFilter{
+		Statuses: req.GetStatuses(), Template: req.GetTemplate(), Labels: req.GetLabels(),
+	})
+	if err == nil {
+		err = c.jobs.CyclesProgress(ctx, cycles)
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &robov1.ListCyclesResponse{}
+	for i := range cycles {
+		msg, err := toCycleMessage(&cycles[i])
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		resp.Cycles = append(resp.Cycles, msg)
+	}
+	return resp, nil
+}
+
+// WatchCycle sends the cycle at once and then whenever the store publishes a change of
+// it or of its jobs, or its progress changed since the last poll
+func (c cycleService) WatchCycle(req *robov1.WatchCycleRequest, stream grpc.ServerStreamingServer[robov1.Cycle]) error {
+	if req.GetIntervalSeconds() < 0 {
+		return invalidArgument(errors.New("interval_seconds must not be negative"))
+	}
+	interval := time.Second
+	if req.GetIntervalSeconds() > 0 {
+		interval = time.Duration(req.GetIntervalSeconds()) * time.Second
+	}
+	ctx := stream.Context()
+	changed := make(chan struct{}, 1)
+	unsubscribe := c.events.Subscribe(func(_ context.Context, change store.Change) {
+		if change.CycleUUID != req.GetUuid() {
+			return
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *robov1.Cycle
+	for {
+		cycle, err := c.cycleMessage(ctx, req.GetUuid())
+		if err != nil {
+			return err
+		}
+		if !proto.Equal(cycle, last) {
+			if err := stream.Send(cycle); err != nil {
+				return err
+			}
+			last = cycle
+		}
+		if cycle.DoneAt != 0 && cycle.Status != "running" && cycle.Status != "paused" {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c cycleService) PauseCycle(ctx context.Context, req *robov1.CycleRequest) (*robov1.Cycle, error) {
+	return c.changeCycle(ctx, req.GetUuid(), c.jobs.PauseCycle)
+}
+
+func (c cycleService) ResumeCycle(ctx context.Context, req *robov1.CycleRequest) (*robov1.Cycle, error) {
+	return c.changeCycle(ctx, req.GetUuid(), c.jobs.ResumeCycle)
+}
+
+func (c cycleService) AbortCycle(ctx context.Context, req *robov1.CycleRequest) (*robov1.Cycle, error) {
+	return c.changeCycle(ctx, req.GetUuid(), c.jobs.AbortCycle)
+}
+
+// changeCycle applies a status change to the cycle and returns it as changed
+func (c cycleService) changeCycle(ctx context.Context, uuid string, change func(context.Context, string) error) (*robov1.Cycle, error) {
+	if err := change(ctx, uuid); err != nil {
+		return nil, grpcError(err)
+	}
+	return c.cycleMessage(ctx, uuid)
+}
+
+// templateService implements robov1.TemplateServiceServer
+type templateService struct {
+	robov1.UnimplementedTemplateServiceServer
+	*Server
+}
+
+func (t templateService) ListTemplates(ctx context.Context, _ *robov1.ListTemplatesRequest) (*robov1.ListTemplatesResponse, error) {
+	templates, err := t.store.ListCycleTemplates(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &robov1.ListTemplatesResponse{}
+	for i := range templates {
+		msg, err := toTemplateMessage(&templates[i])
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		resp.Templates = append(resp.Templates, msg)
+	}
+	return resp, nil
+}
+
+func (t templateService) GetTemplate(ctx context.Context, req *robov1.GetTemplateRequest) (*robov1.Template, error) {
+	template, err := t.store.GetCycleTemplate(ctx, req.GetName())
+	if err == nil {
+		var msg *robov1.Template
+		if msg, err = toTemplateMessage(template); err == nil {
+			return msg, nil
+		}
+	}
+	return nil, grpcError(err)
+}
+
+// fromTemplateMessage decodes a template and validates its strategy
+func fromTemplateMessage(msg *robov1.Template) (*models.CycleTemplate, error) {
+	strategy, err := fromStrategyJSON(msg.GetStrategyJson())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := job.ValidateStrategy(strategy); err != nil {
+		return nil, invalidArgument(err)
+	}
+	return &models.CycleTemplate{Name: msg.GetName(), Description: msg.GetDescription(), Strategy: strategy}, nil
+}
+
+func (t templateService) CreateTemplate(ctx context.Context, req *robov1.Template) (*robov1.Template, error) {
+	template, err := fromTemplateMessage(req)
+	if err != nil {
+		return nil, err
+	}
+	if template.Name == "" {
+		return nil, invalidArgument(errors.New("name is required"))
+	}
+	if _, err := t.store.GetCycleTemplate(ctx, template.Name); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "template %s already exists", template.Name)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, grpcError(err)
+	}
+	if err := t.store.CreateCycleTemplate(ctx, template); err != nil {
+		return nil, grpcError(err)
+	}
+	return t.GetTemplate(ctx, &robov1.GetTemplateRequest{Name: template.Name})
+}
+
+func (t templateService) UpdateTemplate(ctx context.Context, req *robov1.Template) (*robov1.Template, error) {
+	template, err := fromTemplateMessage(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.store.UpdateCycleTemplate(ctx, template); err != nil {
+		return nil, grpcError(err)
+	}
+	return t.GetTemplate(ctx, &robov1.GetTemplateRequest{Name: template.Name})
+}
+
+func (t templateService) DeleteTemplate(ctx context.Context, req *robov1.GetTemplateRequest) (*robov1.DeleteResponse, error) {
+	if err := t.store.DeleteCycleTemplate(ctx, req.GetName()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &robov1.DeleteResponse{}, nil
+}
+
+func (t templateService) StartTemplateCycle(ctx context.Context, req *robov1.StartTemplateCycleRequest) (*robov1.Cycle, error) {
+	if err := validateLabels(req.GetLabels()); err != nil {
+		return nil, invalidArgument(err)
+	}
+	var overrides json.RawMessage
+	if req.GetOverridesJson() != "" {
+		overrides = json.RawMessage(req.GetOverridesJson())
+	}
+	return startedCycle(t.jobs.StartTemplateCycle(context.WithoutCancel(ctx), req.GetTemplate(), models.Cycle{
+		Name: req.GetName(), Description: req.GetDescription(), Labels: req.GetLabels(), Seed: req.GetSeed(),
+	}, overrides))
+}
+
+// jobService implements robov1.JobServiceServer
+type jobService struct {
+	robov1.UnimplementedJobServiceServer
+	*Server
+}
+
+func (j jobService) ListJobs(ctx context.Context, req *robov1.ListJobsRequest) (*robov1.ListJobsResponse, error) {
+	if req.GetSince() < 0 || req.GetUntil() < 0 {
+		return nil, invalidArgument(errors.New("since and until must be Unix times in seconds"))
+	}
+	if req.GetLimit() < 0 {
+		return nil, invalidArgument(errors.New("limit must not be negative"))
+	}
+	result, err := j.store.ListJobs(ctx, store.JobFilter{
+		CycleUUID: req.GetCycleUuid(),
+		SessionID: req.GetSessionId(),
+		Statuses:  req.GetStatuses(),
+		WorkerID:  req.GetWorkerId(),
+		Since:     req.GetSince(),
+		Until:     req.GetUntil(),
+	}, store.Page{Sort: req.GetSort(), Desc: req.GetDesc(), Limit: int(req.GetLimit()), Cursor: req.GetCursor()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &robov1.ListJobsResponse{NextCursor: result.NextCursor}
+	for i := range result.Jobs {
+		resp.Jobs = append(resp.Jobs, toJobMessage(&result.Jobs[i]))
+	}
+	return resp, nil
+}
+
+func (j jobService) GetJob(ctx context.Context, req *robov1.GetJobRequest) (*robov1.Job, error) {
+	job, err := j.store.GetJob(ctx, req.GetUuid())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toJobMessage(job), nil
+}
+
+func (j jobService) RetryJob(ctx context.Context, req *robov1.GetJobRequest) (*robov1.Job, error) {
+	if err := j.jobs.RetryJob(ctx, req.GetUuid()); err != nil {
+		return nil, grpcError(err)
+	}
+	return j.GetJob(ctx, req)
+}
+
+// workerService implements robov1.WorkerServiceServer
+type workerService struct {
+	robov1.UnimplementedWorkerServiceServer
+	*Server
+}
+
+func (w workerService) ListWorkers(context.Context, *robov1.ListWorkersRequest) (*robov1.ListWorkersResponse, error) {
+	resp := &robov1.ListWorkersResponse{}
+	for _, worker := range w.dispatcher.GetActiveWorkers() {
+		resp.Workers = append(resp.Workers, toWorkerMessage(worker))
+	}
+	return resp, nil
+}
+
+func (w workerService) DrainWorker(ctx context.Context, req *robov1.DrainWorkerRequest) (*robov1.DeleteResponse, error) {
+	if err := w.dispatcher.DrainWorker(ctx, req.GetUuid()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &robov1.DeleteResponse{}, nil
+}
+
+// projectService implements robov1.ProjectServiceServer
+type projectService struct {
+	robov1.UnimplementedProjectServiceServer
+	*Server
+}
+
+func (p projectService) ListProjects(ctx context.Context, _ *robov1.ListProjectsRequest) (*robov1.ListProjectsResponse, error) {
+	projects, err := p.store.ListProjects(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &robov1.ListProjectsResponse{}
+	for i := range projects {
+		resp.Projects = append(resp.Projects, toProjectMessage(&projects[i]))
+	}
+	return resp, nil
+}
+
+func (p projectService) GetProject(ctx context.Context, req *robov1.GetProjectRequest) (*robov1.Project, error) {
+	project, err := p.store.GetProject(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toProjectMessage(project), nil
+}
+
+func (p projectService) CreateProject(ctx context.Context, req *robov1.Project) (*robov1.Project, error) {
+	project := fromProjectMessage(req)
+	if err := validateProjectID(project.ID); err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := validateQuota(project.Quota); err != nil {
+		return nil, invalidArgument(err)
+	}
+	if _, err := p.store.GetProject(ctx, project.ID); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "project %s already exists", project.ID)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, grpcError(err)
+	}
+	if err := p.store.CreateProject(ctx, project); err != nil {
+		return nil, grpcError(err)
+	}
+	return toProjectMessage(project), nil
+}
+
+func (p projectService) UpdateProject(ctx context.Context, req *robov1.Project) (*robov1.Project, error) {
+	project := fromProjectMessage(req)
+	if err := validateQuota(project.Quota); err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := p.store.UpdateProject(ctx, project); err != nil {
+		return nil, grpcError(err)
+	}
+	return p.GetProject(ctx, &robov1.GetProjectRequest{Id: project.ID})
+}
+
+func (p projectService) DeleteProject(ctx context.Context, req *robov1.GetProjectRequest) (*robov1.DeleteResponse, error) {
+	if err := p.store.DeleteProject(ctx, req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &robov1.DeleteResponse{}, nil
+}
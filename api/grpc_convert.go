@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	robov1 "github.com/songvi/robo/api/proto/robo/v1"
+	"github.com/songvi/robo/models"
+)
+
+// Conversions between the models and the messages of the gRPC API. Strategies travel
+// as JSON, as they do in the REST API, so the messages do not follow every field the
+// strategy grows.
+
+func toCycleMessage(cycle *models.Cycle) (*robov1.Cycle, error) {
+	msg := &robov1.Cycle{
+		Uuid:        cycle.UUID,
+		Name:        cycle.Name,
+		Status:      cycle.Status,
+		StartedAt:   cycle.StartedAt,
+		DoneAt:      cycle.DoneAt,
+		Reason:      cycle.Reason,
+		Description: cycle.Description,
+		Labels:      maps.Clone(cycle.Labels),
+		Seed:        cycle.Seed,
+		Template:    cycle.Template,
+		Phase:       cycle.Phase,
+		ProjectId:   cycle.ProjectID,
+		Progress: &robov1.CycleProgress{
+			TotalJobs:       int32(cycle.Progress.TotalJobs),
+			CompletedJobs:   int32(cycle.Progress.CompletedJobs),
+			FailedJobs:      int32(cycle.Progress.FailedJobs),
+			CancelledJobs:   int32(cycle.Progress.CancelledJobs),
+			EstimatedDoneAt: cycle.Progress.EstimatedDoneAt,
+		},
+	}
+	for _, b := range cycle.SLOBreaches {
+		msg.SloBreaches = append(msg.SloBreaches, &robov1.SLOBreach{
+			Action: b.SLO.Action, Metric: b.Metric, Value: b.Value, Threshold: b.Threshold, Samples: int32(b.Samples), At: b.At,
+		})
+	}
+	if cycle.Strategy != nil {
+		data, err := json.Marshal(cycle.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode strategy: %v", err)
+		}
+		msg.StrategyJson = string(data)
+	}
+	return msg, nil
+}
+
+// fromStrategyJSON decodes a strategy sent as JSON; empty leaves it unset
+func fromStrategyJSON(data string) (*models.Strategy, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var strategy models.Strategy
+	if err := json.Unmarshal([]byte(data), &strategy); err != nil {
+		return nil, fmt.Errorf("invalid strategy_json: %v", err)
+	}
+	return &strategy, nil
+}
+
+func toPlanMessage(plan models.CyclePlan) *robov1.CyclePlan {
+	return &robov1.CyclePlan{
+		Sessions:       int32(plan.Sessions),
+		Targets:        toInt32Map(plan.Targets),
+		TotalJobs:      int32(plan.TotalJobs),
+		WarmUpJobs:     int32(plan.WarmUpJobs),
+		TeardownJobs:   int32(plan.TeardownJobs),
+		Jobs:           toInt32Map(plan.Jobs),
+		GeneratedFiles: int32(plan.GeneratedFiles),
+		GeneratedBytes: plan.GeneratedBytes,
+		RequiredBytes:  plan.RequiredBytes,
+		Workers:        int32(plan.Workers),
+		Problems:       plan.Problems,
+	}
+}
+
+func toInt32Map(m map[string]int) map[string]int32 {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]int32, len(m))
+	for k, v := range m {
+		out[k] = int32(v)
+	}
+	return out
+}
+
+func toTemplateMessage(template *models.CycleTemplate) (*robov1.Template, error) {
+	msg := &robov1.Template{Name: template.Name, Description: template.Description}
+	if template.Strategy != nil {
+		data, err := json.Marshal(template.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode strategy: %v", err)
+		}
+		msg.StrategyJson = string(data)
+	}
+	return msg, nil
+}
+
+func toJobMessage(job *models.Job) *robov1.Job {
+	return &robov1.Job{
+		Uuid:       job.UUID,
+		Name:       job.Name,
+		Status:     job.Status,
+		CycleUuid:  job.CycleUUID,
+		SessionId:  job.SessionID,
+		WorkerId:   job.WorkerID,
+		Error:      job.Error,
+		StartAt:    job.StartAt,
+		DoneAt:     job.DoneAt,
+		DurationMs: job.DurationMs,
+		Phase:      job.Phase,
+		Target:     job.Target,
+		InputJson:  string(job.InputData),
+		OutputJson: string(job.OutputData),
+	}
+}
+
+func toWorkerMessage(worker models.Worker) *robov1.Worker {
+	return &robov1.Worker{
+		Uuid:         worker.UUID,
+		Name:         worker.Name,
+		Labels:       maps.Clone(worker.Labels),
+		Capabilities: worker.Capabilities,
+		Draining:     worker.Draining,
+		Status:       worker.Status,
+		LastSeenAt:   worker.LastSeenAt,
+		Version:      worker.Version,
+	}
+}
+
+func toProjectMessage(project *models.Project) *robov1.Project {
+	return &robov1.Project{
+		Id:          project.ID,
+		Description: project.Description,
+		Quota: &robov1.ProjectQuota{
+			MaxRunningCycles: int32(project.Quota.MaxRunningCycles),
+			MaxCycleJobs:     int32(project.Quota.MaxCycleJobs),
+		},
+	}
+}
+
+func fromProjectMessage(msg *robov1.Project) *models.Project {
+	return &models.Project{
+		ID:          msg.GetId(),
+		Description: msg.GetDescription(),
+		Quota: models.ProjectQuota{
+			MaxRunningCycles: int(msg.GetQuota().GetMaxRunningCycles()),
+			MaxCycleJobs:     int(msg.GetQuota().GetMaxCycleJobs()),
+		},
+	}
+}
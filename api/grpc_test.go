@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	robov1 "github.com/songvi/robo/api/proto/robo/v1"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// watchedStore is a fakeStore whose cycle c1 can be finished
+type watchedStore struct {
+	*fakeStore
+	mu   sync.Mutex
+	done bool
+}
+
+func (w *watchedStore) GetCycle(ctx context.Context, uuid string) (*models.Cycle, error) {
+	cycle, err := w.fakeStore.GetCycle(ctx, uuid)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil && w.done {
+		cycle.Status, cycle.DoneAt = "completed", 100
+	}
+	return cycle, err
+}
+
+func (w *watchedStore) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+}
+
+func TestGRPCServer(t *testing.T) {
+	jobs, st := &fakeJobs{}, &watchedStore{fakeStore: &fakeStore{}}
+	events := store.NewEvents()
+	s := &Server{
+		logger:     logger.NewSlogLogger(),
+		jobs:       jobs,
+		store:      st,
+		dispatcher: &fakeDispatcher{},
+		events:     events,
+		limiter:    NewRateLimiter(RateLimitConfig{CyclesPerMinute: 2}),
+	}
+	lis := bufconn.Listen(1 << 20)
+	server := s.GRPC()
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	cycles, workers := robov1.NewCycleServiceClient(conn), robov1.NewWorkerServiceClient(conn)
+	ctx := context.Background()
+
+	cycle, err := cycles.StartCycle(ctx, &robov1.StartCycleRequest{
+		Name: "nightly", StrategyJson: `{"max_rate":5}`, Labels: map[string]string{"env": "staging"}, Seed: 42,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", cycle.Uuid)
+	assert.Equal(t, 5.0, jobs.started.Strategy.MaxRate)
+	assert.Equal(t, map[string]string{"env": "staging"}, jobs.started.Labels)
+	assert.Equal(t, int64(42), jobs.started.Seed)
+	_, err = cycles.StartCycle(ctx, &robov1.StartCycleRequest{StrategyJson: `{"max_rate":-1}`})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = cycles.StartCycle(ctx, &robov1.StartCycleRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "cycle starts are rate limited")
+
+	inProject := func(project string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "x-project", project)
+	}
+	_, err = cycles.ListCycles(inProject("team-a"), &robov1.ListCyclesRequest{Statuses: []string{"running"}})
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", st.project)
+	assert.Equal(t, []string{"running"}, st.filter.Statuses)
+	_, err = cycles.ListCycles(inProject("team-b"), &robov1.ListCyclesRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err), "unknown projects are rejected")
+
+	cycle, err = cycles.GetCycle(ctx, &robov1.GetCycleRequest{Uuid: "c1"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), cycle.Progress.TotalJobs)
+	_, err = cycles.GetCycle(ctx, &robov1.GetCycleRequest{Uuid: "c2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = cycles.AbortCycle(ctx, &robov1.CycleRequest{Uuid: "c1"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = workers.DrainWorker(ctx, &robov1.DrainWorkerRequest{Uuid: "w2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The watch sends the cycle at once, then on the changes the store publishes until
+	// the cycle is over
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := cycles.WatchCycle(watchCtx, &robov1.WatchCycleRequest{Uuid: "c1", IntervalSeconds: 60})
+	require.NoError(t, err)
+	cycle, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "running", cycle.Status)
+	st.finish()
+	observed := store.NewObservedStore(st.fakeStore, events)
+	require.NoError(t, observed.UpdateJob(ctx, &models.Job{UUID: "j1", CycleUUID: "c1", Status: "completed"}))
+	cycle, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "completed", cycle.Status)
+	_, err = stream.Recv()
+	assert.Error(t, err, "the watch ends with the cycle")
+}
@@ -0,0 +1,2290 @@
+// Control-plane API of robo. It mirrors the REST admin API of the api package, with
+// typed clients and a streaming progress watch for orchestration tools.
+//
+// Requests are scoped to a project by the x-project metadata key, as REST requests are
+// by the X-Project header, and identify their caller by x-api-key.
+//
+// Regenerate the Go bindings of this directory with:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative api/proto/robo/v1/control.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/proto/robo/v1/control.proto
+
+package robov1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartCycleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// strategy_json is a models.Strategy in JSON; empty uses the configured strategy
+	StrategyJson string            `protobuf:"bytes,2,opt,name=strategy_json,json=strategyJson,proto3" json:"strategy_json,omitempty"`
+	Description  string            `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Labels       map[string]string `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// seed replays the cycle started with it; zero picks a random one
+	Seed          int64 `protobuf:"varint,5,opt,name=seed,proto3" json:"seed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCycleRequest) Reset() {
+	*x = StartCycleRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCycleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCycleRequest) ProtoMessage() {}
+
+func (x *StartCycleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCycleRequest.ProtoReflect.Descriptor instead.
+func (*StartCycleRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartCycleRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StartCycleRequest) GetStrategyJson() string {
+	if x != nil {
+		return x.StrategyJson
+	}
+	return ""
+}
+
+func (x *StartCycleRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *StartCycleRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *StartCycleRequest) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+type GetCycleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCycleRequest) Reset() {
+	*x = GetCycleRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCycleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCycleRequest) ProtoMessage() {}
+
+func (x *GetCycleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCycleRequest.ProtoReflect.Descriptor instead.
+func (*GetCycleRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCycleRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+type ListCyclesRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Statuses []string               `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	Template string                 `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"`
+	// labels must all be set to their value
+	Labels        map[string]string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCyclesRequest) Reset() {
+	*x = ListCyclesRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCyclesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCyclesRequest) ProtoMessage() {}
+
+func (x *ListCyclesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCyclesRequest.ProtoReflect.Descriptor instead.
+func (*ListCyclesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListCyclesRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *ListCyclesRequest) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *ListCyclesRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type ListCyclesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cycles        []*Cycle               `protobuf:"bytes,1,rep,name=cycles,proto3" json:"cycles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCyclesResponse) Reset() {
+	*x = ListCyclesResponse{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCyclesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCyclesResponse) ProtoMessage() {}
+
+func (x *ListCyclesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCyclesResponse.ProtoReflect.Descriptor instead.
+func (*ListCyclesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListCyclesResponse) GetCycles() []*Cycle {
+	if x != nil {
+		return x.Cycles
+	}
+	return nil
+}
+
+type WatchCycleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Uuid  string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// interval_seconds is how often progress is polled between the changes the store
+	// publishes; defaults to 1
+	IntervalSeconds int32 `protobuf:"varint,2,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WatchCycleRequest) Reset() {
+	*x = WatchCycleRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCycleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCycleRequest) ProtoMessage() {}
+
+func (x *WatchCycleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCycleRequest.ProtoReflect.Descriptor instead.
+func (*WatchCycleRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WatchCycleRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *WatchCycleRequest) GetIntervalSeconds() int32 {
+	if x != nil {
+		return x.IntervalSeconds
+	}
+	return 0
+}
+
+type CycleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CycleRequest) Reset() {
+	*x = CycleRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleRequest) ProtoMessage() {}
+
+func (x *CycleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleRequest.ProtoReflect.Descriptor instead.
+func (*CycleRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CycleRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+type Cycle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	StartedAt     int64                  `protobuf:"varint,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	DoneAt        int64                  `protobuf:"varint,5,opt,name=done_at,json=doneAt,proto3" json:"done_at,omitempty"`
+	Progress      *CycleProgress         `protobuf:"bytes,6,opt,name=progress,proto3" json:"progress,omitempty"`
+	SloBreaches   []*SLOBreach           `protobuf:"bytes,7,rep,name=slo_breaches,json=sloBreaches,proto3" json:"slo_breaches,omitempty"`
+	StrategyJson  string                 `protobuf:"bytes,8,opt,name=strategy_json,json=strategyJson,proto3" json:"strategy_json,omitempty"`
+	Reason        string                 `protobuf:"bytes,9,opt,name=reason,proto3" json:"reason,omitempty"`
+	Description   string                 `protobuf:"bytes,10,opt,name=description,proto3" json:"description,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Seed          int64                  `protobuf:"varint,12,opt,name=seed,proto3" json:"seed,omitempty"`
+	Template      string                 `protobuf:"bytes,13,opt,name=template,proto3" json:"template,omitempty"`
+	Phase         string                 `protobuf:"bytes,14,opt,name=phase,proto3" json:"phase,omitempty"`
+	ProjectId     string                 `protobuf:"bytes,15,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cycle) Reset() {
+	*x = Cycle{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cycle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cycle) ProtoMessage() {}
+
+func (x *Cycle) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cycle.ProtoReflect.Descriptor instead.
+func (*Cycle) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Cycle) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Cycle) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Cycle) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Cycle) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *Cycle) GetDoneAt() int64 {
+	if x != nil {
+		return x.DoneAt
+	}
+	return 0
+}
+
+func (x *Cycle) GetProgress() *CycleProgress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+func (x *Cycle) GetSloBreaches() []*SLOBreach {
+	if x != nil {
+		return x.SloBreaches
+	}
+	return nil
+}
+
+func (x *Cycle) GetStrategyJson() string {
+	if x != nil {
+		return x.StrategyJson
+	}
+	return ""
+}
+
+func (x *Cycle) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Cycle) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Cycle) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Cycle) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *Cycle) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *Cycle) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *Cycle) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+type CycleProgress struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TotalJobs       int32                  `protobuf:"varint,1,opt,name=total_jobs,json=totalJobs,proto3" json:"total_jobs,omitempty"`
+	CompletedJobs   int32                  `protobuf:"varint,2,opt,name=completed_jobs,json=completedJobs,proto3" json:"completed_jobs,omitempty"`
+	FailedJobs      int32                  `protobuf:"varint,3,opt,name=failed_jobs,json=failedJobs,proto3" json:"failed_jobs,omitempty"`
+	CancelledJobs   int32                  `protobuf:"varint,4,opt,name=cancelled_jobs,json=cancelledJobs,proto3" json:"cancelled_jobs,omitempty"`
+	EstimatedDoneAt int64                  `protobuf:"varint,5,opt,name=estimated_done_at,json=estimatedDoneAt,proto3" json:"estimated_done_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CycleProgress) Reset() {
+	*x = CycleProgress{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleProgress) ProtoMessage() {}
+
+func (x *CycleProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleProgress.ProtoReflect.Descriptor instead.
+func (*CycleProgress) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CycleProgress) GetTotalJobs() int32 {
+	if x != nil {
+		return x.TotalJobs
+	}
+	return 0
+}
+
+func (x *CycleProgress) GetCompletedJobs() int32 {
+	if x != nil {
+		return x.CompletedJobs
+	}
+	return 0
+}
+
+func (x *CycleProgress) GetFailedJobs() int32 {
+	if x != nil {
+		return x.FailedJobs
+	}
+	return 0
+}
+
+func (x *CycleProgress) GetCancelledJobs() int32 {
+	if x != nil {
+		return x.CancelledJobs
+	}
+	return 0
+}
+
+func (x *CycleProgress) GetEstimatedDoneAt() int64 {
+	if x != nil {
+		return x.EstimatedDoneAt
+	}
+	return 0
+}
+
+type SLOBreach struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Action        string                 `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	Metric        string                 `protobuf:"bytes,2,opt,name=metric,proto3" json:"metric,omitempty"`
+	Value         float64                `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,4,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Samples       int32                  `protobuf:"varint,5,opt,name=samples,proto3" json:"samples,omitempty"`
+	At            int64                  `protobuf:"varint,6,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SLOBreach) Reset() {
+	*x = SLOBreach{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SLOBreach) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SLOBreach) ProtoMessage() {}
+
+func (x *SLOBreach) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SLOBreach.ProtoReflect.Descriptor instead.
+func (*SLOBreach) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SLOBreach) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *SLOBreach) GetMetric() string {
+	if x != nil {
+		return x.Metric
+	}
+	return ""
+}
+
+func (x *SLOBreach) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *SLOBreach) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *SLOBreach) GetSamples() int32 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *SLOBreach) GetAt() int64 {
+	if x != nil {
+		return x.At
+	}
+	return 0
+}
+
+type CyclePlan struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Sessions       int32                  `protobuf:"varint,1,opt,name=sessions,proto3" json:"sessions,omitempty"`
+	Targets        map[string]int32       `protobuf:"bytes,2,rep,name=targets,proto3" json:"targets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	TotalJobs      int32                  `protobuf:"varint,3,opt,name=total_jobs,json=totalJobs,proto3" json:"total_jobs,omitempty"`
+	WarmUpJobs     int32                  `protobuf:"varint,4,opt,name=warm_up_jobs,json=warmUpJobs,proto3" json:"warm_up_jobs,omitempty"`
+	TeardownJobs   int32                  `protobuf:"varint,5,opt,name=teardown_jobs,json=teardownJobs,proto3" json:"teardown_jobs,omitempty"`
+	Jobs           map[string]int32       `protobuf:"bytes,6,rep,name=jobs,proto3" json:"jobs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	GeneratedFiles int32                  `protobuf:"varint,7,opt,name=generated_files,json=generatedFiles,proto3" json:"generated_files,omitempty"`
+	GeneratedBytes int64                  `protobuf:"varint,8,opt,name=generated_bytes,json=generatedBytes,proto3" json:"generated_bytes,omitempty"`
+	RequiredBytes  int64                  `protobuf:"varint,9,opt,name=required_bytes,json=requiredBytes,proto3" json:"required_bytes,omitempty"`
+	Workers        int32                  `protobuf:"varint,10,opt,name=workers,proto3" json:"workers,omitempty"`
+	Problems       []string               `protobuf:"bytes,11,rep,name=problems,proto3" json:"problems,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CyclePlan) Reset() {
+	*x = CyclePlan{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CyclePlan) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CyclePlan) ProtoMessage() {}
+
+func (x *CyclePlan) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CyclePlan.ProtoReflect.Descriptor instead.
+func (*CyclePlan) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CyclePlan) GetSessions() int32 {
+	if x != nil {
+		return x.Sessions
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetTargets() map[string]int32 {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *CyclePlan) GetTotalJobs() int32 {
+	if x != nil {
+		return x.TotalJobs
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetWarmUpJobs() int32 {
+	if x != nil {
+		return x.WarmUpJobs
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetTeardownJobs() int32 {
+	if x != nil {
+		return x.TeardownJobs
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetJobs() map[string]int32 {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *CyclePlan) GetGeneratedFiles() int32 {
+	if x != nil {
+		return x.GeneratedFiles
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetGeneratedBytes() int64 {
+	if x != nil {
+		return x.GeneratedBytes
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetRequiredBytes() int64 {
+	if x != nil {
+		return x.RequiredBytes
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *CyclePlan) GetProblems() []string {
+	if x != nil {
+		return x.Problems
+	}
+	return nil
+}
+
+type ListTemplatesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTemplatesRequest) Reset() {
+	*x = ListTemplatesRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTemplatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTemplatesRequest) ProtoMessage() {}
+
+func (x *ListTemplatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTemplatesRequest.ProtoReflect.Descriptor instead.
+func (*ListTemplatesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{10}
+}
+
+type ListTemplatesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Templates     []*Template            `protobuf:"bytes,1,rep,name=templates,proto3" json:"templates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTemplatesResponse) Reset() {
+	*x = ListTemplatesResponse{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTemplatesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTemplatesResponse) ProtoMessage() {}
+
+func (x *ListTemplatesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTemplatesResponse.ProtoReflect.Descriptor instead.
+func (*ListTemplatesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListTemplatesResponse) GetTemplates() []*Template {
+	if x != nil {
+		return x.Templates
+	}
+	return nil
+}
+
+type GetTemplateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTemplateRequest) Reset() {
+	*x = GetTemplateRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTemplateRequest) ProtoMessage() {}
+
+func (x *GetTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTemplateRequest.ProtoReflect.Descriptor instead.
+func (*GetTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetTemplateRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Template struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Name        string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// strategy_json is a models.Strategy in JSON
+	StrategyJson  string `protobuf:"bytes,3,opt,name=strategy_json,json=strategyJson,proto3" json:"strategy_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Template) Reset() {
+	*x = Template{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Template) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Template) ProtoMessage() {}
+
+func (x *Template) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Template.ProtoReflect.Descriptor instead.
+func (*Template) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Template) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Template) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Template) GetStrategyJson() string {
+	if x != nil {
+		return x.StrategyJson
+	}
+	return ""
+}
+
+type StartTemplateCycleRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Template    string                 `protobuf:"bytes,1,opt,name=template,proto3" json:"template,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Labels      map[string]string      `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Seed        int64                  `protobuf:"varint,5,opt,name=seed,proto3" json:"seed,omitempty"`
+	// overrides_json is a JSON merge patch of the template strategy
+	OverridesJson string `protobuf:"bytes,6,opt,name=overrides_json,json=overridesJson,proto3" json:"overrides_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartTemplateCycleRequest) Reset() {
+	*x = StartTemplateCycleRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartTemplateCycleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartTemplateCycleRequest) ProtoMessage() {}
+
+func (x *StartTemplateCycleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartTemplateCycleRequest.ProtoReflect.Descriptor instead.
+func (*StartTemplateCycleRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StartTemplateCycleRequest) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *StartTemplateCycleRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StartTemplateCycleRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *StartTemplateCycleRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *StartTemplateCycleRequest) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *StartTemplateCycleRequest) GetOverridesJson() string {
+	if x != nil {
+		return x.OverridesJson
+	}
+	return ""
+}
+
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{15}
+}
+
+type ListJobsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Statuses []string               `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	// cycle_uuid narrows the jobs to one cycle
+	CycleUuid string `protobuf:"bytes,2,opt,name=cycle_uuid,json=cycleUuid,proto3" json:"cycle_uuid,omitempty"`
+	SessionId string `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	WorkerId  string `protobuf:"bytes,4,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	// since and until bound the start of the jobs in Unix seconds
+	Since int64 `protobuf:"varint,5,opt,name=since,proto3" json:"since,omitempty"`
+	Until int64 `protobuf:"varint,6,opt,name=until,proto3" json:"until,omitempty"`
+	// sort is the column to sort by, start_at by default
+	Sort  string `protobuf:"bytes,7,opt,name=sort,proto3" json:"sort,omitempty"`
+	Desc  bool   `protobuf:"varint,8,opt,name=desc,proto3" json:"desc,omitempty"`
+	Limit int32  `protobuf:"varint,9,opt,name=limit,proto3" json:"limit,omitempty"`
+	// cursor is the next_cursor of the previous page
+	Cursor        string `protobuf:"bytes,10,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListJobsRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetCycleUuid() string {
+	if x != nil {
+		return x.CycleUuid
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetDesc() bool {
+	if x != nil {
+		return x.Desc
+	}
+	return false
+}
+
+func (x *ListJobsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListJobsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Jobs  []*Job                 `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	// next_cursor is empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListJobsResponse) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *ListJobsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetJobRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	CycleUuid     string                 `protobuf:"bytes,4,opt,name=cycle_uuid,json=cycleUuid,proto3" json:"cycle_uuid,omitempty"`
+	SessionId     string                 `protobuf:"bytes,5,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	WorkerId      string                 `protobuf:"bytes,6,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	StartAt       int64                  `protobuf:"varint,8,opt,name=start_at,json=startAt,proto3" json:"start_at,omitempty"`
+	DoneAt        int64                  `protobuf:"varint,9,opt,name=done_at,json=doneAt,proto3" json:"done_at,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,10,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Phase         string                 `protobuf:"bytes,11,opt,name=phase,proto3" json:"phase,omitempty"`
+	Target        string                 `protobuf:"bytes,12,opt,name=target,proto3" json:"target,omitempty"`
+	InputJson     string                 `protobuf:"bytes,13,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"`
+	OutputJson    string                 `protobuf:"bytes,14,opt,name=output_json,json=outputJson,proto3" json:"output_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Job) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Job) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetCycleUuid() string {
+	if x != nil {
+		return x.CycleUuid
+	}
+	return ""
+}
+
+func (x *Job) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Job) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetStartAt() int64 {
+	if x != nil {
+		return x.StartAt
+	}
+	return 0
+}
+
+func (x *Job) GetDoneAt() int64 {
+	if x != nil {
+		return x.DoneAt
+	}
+	return 0
+}
+
+func (x *Job) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *Job) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *Job) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Job) GetInputJson() string {
+	if x != nil {
+		return x.InputJson
+	}
+	return ""
+}
+
+func (x *Job) GetOutputJson() string {
+	if x != nil {
+		return x.OutputJson
+	}
+	return ""
+}
+
+type ListWorkersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWorkersRequest) Reset() {
+	*x = ListWorkersRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWorkersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWorkersRequest) ProtoMessage() {}
+
+func (x *ListWorkersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWorkersRequest.ProtoReflect.Descriptor instead.
+func (*ListWorkersRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{20}
+}
+
+type ListWorkersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Workers       []*Worker              `protobuf:"bytes,1,rep,name=workers,proto3" json:"workers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWorkersResponse) Reset() {
+	*x = ListWorkersResponse{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWorkersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWorkersResponse) ProtoMessage() {}
+
+func (x *ListWorkersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWorkersResponse.ProtoReflect.Descriptor instead.
+func (*ListWorkersResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListWorkersResponse) GetWorkers() []*Worker {
+	if x != nil {
+		return x.Workers
+	}
+	return nil
+}
+
+type DrainWorkerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainWorkerRequest) Reset() {
+	*x = DrainWorkerRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainWorkerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainWorkerRequest) ProtoMessage() {}
+
+func (x *DrainWorkerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainWorkerRequest.ProtoReflect.Descriptor instead.
+func (*DrainWorkerRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DrainWorkerRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+type Worker struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Capabilities  []string               `protobuf:"bytes,4,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Draining      bool                   `protobuf:"varint,5,opt,name=draining,proto3" json:"draining,omitempty"`
+	Status        string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	LastSeenAt    int64                  `protobuf:"varint,7,opt,name=last_seen_at,json=lastSeenAt,proto3" json:"last_seen_at,omitempty"`
+	Version       string                 `protobuf:"bytes,8,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Worker) Reset() {
+	*x = Worker{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Worker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Worker) ProtoMessage() {}
+
+func (x *Worker) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Worker.ProtoReflect.Descriptor instead.
+func (*Worker) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Worker) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Worker) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Worker) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Worker) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *Worker) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+func (x *Worker) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Worker) GetLastSeenAt() int64 {
+	if x != nil {
+		return x.LastSeenAt
+	}
+	return 0
+}
+
+func (x *Worker) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type ListProjectsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProjectsRequest) Reset() {
+	*x = ListProjectsRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProjectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProjectsRequest) ProtoMessage() {}
+
+func (x *ListProjectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProjectsRequest.ProtoReflect.Descriptor instead.
+func (*ListProjectsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{24}
+}
+
+type ListProjectsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Projects      []*Project             `protobuf:"bytes,1,rep,name=projects,proto3" json:"projects,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProjectsResponse) Reset() {
+	*x = ListProjectsResponse{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProjectsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProjectsResponse) ProtoMessage() {}
+
+func (x *ListProjectsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProjectsResponse.ProtoReflect.Descriptor instead.
+func (*ListProjectsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListProjectsResponse) GetProjects() []*Project {
+	if x != nil {
+		return x.Projects
+	}
+	return nil
+}
+
+type GetProjectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProjectRequest) Reset() {
+	*x = GetProjectRequest{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProjectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProjectRequest) ProtoMessage() {}
+
+func (x *GetProjectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProjectRequest.ProtoReflect.Descriptor instead.
+func (*GetProjectRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetProjectRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Project struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Quota         *ProjectQuota          `protobuf:"bytes,3,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Project) Reset() {
+	*x = Project{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Project) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Project) ProtoMessage() {}
+
+func (x *Project) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Project.ProtoReflect.Descriptor instead.
+func (*Project) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Project) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Project) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Project) GetQuota() *ProjectQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type ProjectQuota struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	MaxRunningCycles int32                  `protobuf:"varint,1,opt,name=max_running_cycles,json=maxRunningCycles,proto3" json:"max_running_cycles,omitempty"`
+	MaxCycleJobs     int32                  `protobuf:"varint,2,opt,name=max_cycle_jobs,json=maxCycleJobs,proto3" json:"max_cycle_jobs,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ProjectQuota) Reset() {
+	*x = ProjectQuota{}
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProjectQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProjectQuota) ProtoMessage() {}
+
+func (x *ProjectQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_robo_v1_control_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProjectQuota.ProtoReflect.Descriptor instead.
+func (*ProjectQuota) Descriptor() ([]byte, []int) {
+	return file_api_proto_robo_v1_control_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ProjectQuota) GetMaxRunningCycles() int32 {
+	if x != nil {
+		return x.MaxRunningCycles
+	}
+	return 0
+}
+
+func (x *ProjectQuota) GetMaxCycleJobs() int32 {
+	if x != nil {
+		return x.MaxCycleJobs
+	}
+	return 0
+}
+
+var File_api_proto_robo_v1_control_proto protoreflect.FileDescriptor
+
+const file_api_proto_robo_v1_control_proto_rawDesc = "" +
+	"\n" +
+	"\x1fapi/proto/robo/v1/control.proto\x12\arobo.v1\"\xfd\x01\n" +
+	"\x11StartCycleRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12#\n" +
+	"\rstrategy_json\x18\x02 \x01(\tR\fstrategyJson\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12>\n" +
+	"\x06labels\x18\x04 \x03(\v2&.robo.v1.StartCycleRequest.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04seed\x18\x05 \x01(\x03R\x04seed\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"%\n" +
+	"\x0fGetCycleRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\xc6\x01\n" +
+	"\x11ListCyclesRequest\x12\x1a\n" +
+	"\bstatuses\x18\x01 \x03(\tR\bstatuses\x12\x1a\n" +
+	"\btemplate\x18\x02 \x01(\tR\btemplate\x12>\n" +
+	"\x06labels\x18\x03 \x03(\v2&.robo.v1.ListCyclesRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"<\n" +
+	"\x12ListCyclesResponse\x12&\n" +
+	"\x06cycles\x18\x01 \x03(\v2\x0e.robo.v1.CycleR\x06cycles\"R\n" +
+	"\x11WatchCycleRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12)\n" +
+	"\x10interval_seconds\x18\x02 \x01(\x05R\x0fintervalSeconds\"\"\n" +
+	"\fCycleRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\x9d\x04\n" +
+	"\x05Cycle\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x04 \x01(\x03R\tstartedAt\x12\x17\n" +
+	"\adone_at\x18\x05 \x01(\x03R\x06doneAt\x122\n" +
+	"\bprogress\x18\x06 \x01(\v2\x16.robo.v1.CycleProgressR\bprogress\x125\n" +
+	"\fslo_breaches\x18\a \x03(\v2\x12.robo.v1.SLOBreachR\vsloBreaches\x12#\n" +
+	"\rstrategy_json\x18\b \x01(\tR\fstrategyJson\x12\x16\n" +
+	"\x06reason\x18\t \x01(\tR\x06reason\x12 \n" +
+	"\vdescription\x18\n" +
+	" \x01(\tR\vdescription\x122\n" +
+	"\x06labels\x18\v \x03(\v2\x1a.robo.v1.Cycle.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04seed\x18\f \x01(\x03R\x04seed\x12\x1a\n" +
+	"\btemplate\x18\r \x01(\tR\btemplate\x12\x14\n" +
+	"\x05phase\x18\x0e \x01(\tR\x05phase\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x0f \x01(\tR\tprojectId\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc9\x01\n" +
+	"\rCycleProgress\x12\x1d\n" +
+	"\n" +
+	"total_jobs\x18\x01 \x01(\x05R\ttotalJobs\x12%\n" +
+	"\x0ecompleted_jobs\x18\x02 \x01(\x05R\rcompletedJobs\x12\x1f\n" +
+	"\vfailed_jobs\x18\x03 \x01(\x05R\n" +
+	"failedJobs\x12%\n" +
+	"\x0ecancelled_jobs\x18\x04 \x01(\x05R\rcancelledJobs\x12*\n" +
+	"\x11estimated_done_at\x18\x05 \x01(\x03R\x0festimatedDoneAt\"\x99\x01\n" +
+	"\tSLOBreach\x12\x16\n" +
+	"\x06action\x18\x01 \x01(\tR\x06action\x12\x16\n" +
+	"\x06metric\x18\x02 \x01(\tR\x06metric\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x01R\x05value\x12\x1c\n" +
+	"\tthreshold\x18\x04 \x01(\x01R\tthreshold\x12\x18\n" +
+	"\asamples\x18\x05 \x01(\x05R\asamples\x12\x0e\n" +
+	"\x02at\x18\x06 \x01(\x03R\x02at\"\x9e\x04\n" +
+	"\tCyclePlan\x12\x1a\n" +
+	"\bsessions\x18\x01 \x01(\x05R\bsessions\x129\n" +
+	"\atargets\x18\x02 \x03(\v2\x1f.robo.v1.CyclePlan.TargetsEntryR\atargets\x12\x1d\n" +
+	"\n" +
+	"total_jobs\x18\x03 \x01(\x05R\ttotalJobs\x12 \n" +
+	"\fwarm_up_jobs\x18\x04 \x01(\x05R\n" +
+	"warmUpJobs\x12#\n" +
+	"\rteardown_jobs\x18\x05 \x01(\x05R\fteardownJobs\x120\n" +
+	"\x04jobs\x18\x06 \x03(\v2\x1c.robo.v1.CyclePlan.JobsEntryR\x04jobs\x12'\n" +
+	"\x0fgenerated_files\x18\a \x01(\x05R\x0egeneratedFiles\x12'\n" +
+	"\x0fgenerated_bytes\x18\b \x01(\x03R\x0egeneratedBytes\x12%\n" +
+	"\x0erequired_bytes\x18\t \x01(\x03R\rrequiredBytes\x12\x18\n" +
+	"\aworkers\x18\n" +
+	" \x01(\x05R\aworkers\x12\x1a\n" +
+	"\bproblems\x18\v \x03(\tR\bproblems\x1a:\n" +
+	"\fTargetsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1a7\n" +
+	"\tJobsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\x16\n" +
+	"\x14ListTemplatesRequest\"H\n" +
+	"\x15ListTemplatesResponse\x12/\n" +
+	"\ttemplates\x18\x01 \x03(\v2\x11.robo.v1.TemplateR\ttemplates\"(\n" +
+	"\x12GetTemplateRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"e\n" +
+	"\bTemplate\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12#\n" +
+	"\rstrategy_json\x18\x03 \x01(\tR\fstrategyJson\"\xab\x02\n" +
+	"\x19StartTemplateCycleRequest\x12\x1a\n" +
+	"\btemplate\x18\x01 \x01(\tR\btemplate\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12F\n" +
+	"\x06labels\x18\x04 \x03(\v2..robo.v1.StartTemplateCycleRequest.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04seed\x18\x05 \x01(\x03R\x04seed\x12%\n" +
+	"\x0eoverrides_json\x18\x06 \x01(\tR\roverridesJson\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x10\n" +
+	"\x0eDeleteResponse\"\x8a\x02\n" +
+	"\x0fListJobsRequest\x12\x1a\n" +
+	"\bstatuses\x18\x01 \x03(\tR\bstatuses\x12\x1d\n" +
+	"\n" +
+	"cycle_uuid\x18\x02 \x01(\tR\tcycleUuid\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x03 \x01(\tR\tsessionId\x12\x1b\n" +
+	"\tworker_id\x18\x04 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05since\x18\x05 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05until\x18\x06 \x01(\x03R\x05until\x12\x12\n" +
+	"\x04sort\x18\a \x01(\tR\x04sort\x12\x12\n" +
+	"\x04desc\x18\b \x01(\bR\x04desc\x12\x14\n" +
+	"\x05limit\x18\t \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\n" +
+	" \x01(\tR\x06cursor\"U\n" +
+	"\x10ListJobsResponse\x12 \n" +
+	"\x04jobs\x18\x01 \x03(\v2\f.robo.v1.JobR\x04jobs\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"#\n" +
+	"\rGetJobRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\xf9\x02\n" +
+	"\x03Job\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"cycle_uuid\x18\x04 \x01(\tR\tcycleUuid\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x05 \x01(\tR\tsessionId\x12\x1b\n" +
+	"\tworker_id\x18\x06 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12\x19\n" +
+	"\bstart_at\x18\b \x01(\x03R\astartAt\x12\x17\n" +
+	"\adone_at\x18\t \x01(\x03R\x06doneAt\x12\x1f\n" +
+	"\vduration_ms\x18\n" +
+	" \x01(\x03R\n" +
+	"durationMs\x12\x14\n" +
+	"\x05phase\x18\v \x01(\tR\x05phase\x12\x16\n" +
+	"\x06target\x18\f \x01(\tR\x06target\x12\x1d\n" +
+	"\n" +
+	"input_json\x18\r \x01(\tR\tinputJson\x12\x1f\n" +
+	"\voutput_json\x18\x0e \x01(\tR\n" +
+	"outputJson\"\x14\n" +
+	"\x12ListWorkersRequest\"@\n" +
+	"\x13ListWorkersResponse\x12)\n" +
+	"\aworkers\x18\x01 \x03(\v2\x0f.robo.v1.WorkerR\aworkers\"(\n" +
+	"\x12DrainWorkerRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\xb4\x02\n" +
+	"\x06Worker\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x123\n" +
+	"\x06labels\x18\x03 \x03(\v2\x1b.robo.v1.Worker.LabelsEntryR\x06labels\x12\"\n" +
+	"\fcapabilities\x18\x04 \x03(\tR\fcapabilities\x12\x1a\n" +
+	"\bdraining\x18\x05 \x01(\bR\bdraining\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12 \n" +
+	"\flast_seen_at\x18\a \x01(\x03R\n" +
+	"lastSeenAt\x12\x18\n" +
+	"\aversion\x18\b \x01(\tR\aversion\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x15\n" +
+	"\x13ListProjectsRequest\"D\n" +
+	"\x14ListProjectsResponse\x12,\n" +
+	"\bprojects\x18\x01 \x03(\v2\x10.robo.v1.ProjectR\bprojects\"#\n" +
+	"\x11GetProjectRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"h\n" +
+	"\aProject\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12+\n" +
+	"\x05quota\x18\x03 \x01(\v2\x15.robo.v1.ProjectQuotaR\x05quota\"b\n" +
+	"\fProjectQuota\x12,\n" +
+	"\x12max_running_cycles\x18\x01 \x01(\x05R\x10maxRunningCycles\x12$\n" +
+	"\x0emax_cycle_jobs\x18\x02 \x01(\x05R\fmaxCycleJobs2\xe2\x03\n" +
+	"\fCycleService\x128\n" +
+	"\n" +
+	"StartCycle\x12\x1a.robo.v1.StartCycleRequest\x1a\x0e.robo.v1.Cycle\x12?\n" +
+	"\rValidateCycle\x12\x1a.robo.v1.StartCycleRequest\x1a\x12.robo.v1.CyclePlan\x124\n" +
+	"\bGetCycle\x12\x18.robo.v1.GetCycleRequest\x1a\x0e.robo.v1.Cycle\x12E\n" +
+	"\n" +
+	"ListCycles\x12\x1a.robo.v1.ListCyclesRequest\x1a\x1b.robo.v1.ListCyclesResponse\x12:\n" +
+	"\n" +
+	"WatchCycle\x12\x1a.robo.v1.WatchCycleRequest\x1a\x0e.robo.v1.Cycle0\x01\x123\n" +
+	"\n" +
+	"PauseCycle\x12\x15.robo.v1.CycleRequest\x1a\x0e.robo.v1.Cycle\x124\n" +
+	"\vResumeCycle\x12\x15.robo.v1.CycleRequest\x1a\x0e.robo.v1.Cycle\x123\n" +
+	"\n" +
+	"AbortCycle\x12\x15.robo.v1.CycleRequest\x1a\x0e.robo.v1.Cycle2\xa2\x03\n" +
+	"\x0fTemplateService\x12N\n" +
+	"\rListTemplates\x12\x1d.robo.v1.ListTemplatesRequest\x1a\x1e.robo.v1.ListTemplatesResponse\x12=\n" +
+	"\vGetTemplate\x12\x1b.robo.v1.GetTemplateRequest\x1a\x11.robo.v1.Template\x126\n" +
+	"\x0eCreateTemplate\x12\x11.robo.v1.Template\x1a\x11.robo.v1.Template\x126\n" +
+	"\x0eUpdateTemplate\x12\x11.robo.v1.Template\x1a\x11.robo.v1.Template\x12F\n" +
+	"\x0eDeleteTemplate\x12\x1b.robo.v1.GetTemplateRequest\x1a\x17.robo.v1.DeleteResponse\x12H\n" +
+	"\x12StartTemplateCycle\x12\".robo.v1.StartTemplateCycleRequest\x1a\x0e.robo.v1.Cycle2\xaf\x01\n" +
+	"\n" +
+	"JobService\x12?\n" +
+	"\bListJobs\x12\x18.robo.v1.ListJobsRequest\x1a\x19.robo.v1.ListJobsResponse\x12.\n" +
+	"\x06GetJob\x12\x16.robo.v1.GetJobRequest\x1a\f.robo.v1.Job\x120\n" +
+	"\bRetryJob\x12\x16.robo.v1.GetJobRequest\x1a\f.robo.v1.Job2\x9e\x01\n" +
+	"\rWorkerService\x12H\n" +
+	"\vListWorkers\x12\x1b.robo.v1.ListWorkersRequest\x1a\x1c.robo.v1.ListWorkersResponse\x12C\n" +
+	"\vDrainWorker\x12\x1b.robo.v1.DrainWorkerRequest\x1a\x17.robo.v1.DeleteResponse2\xc9\x02\n" +
+	"\x0eProjectService\x12K\n" +
+	"\fListProjects\x12\x1c.robo.v1.ListProjectsRequest\x1a\x1d.robo.v1.ListProjectsResponse\x12:\n" +
+	"\n" +
+	"GetProject\x12\x1a.robo.v1.GetProjectRequest\x1a\x10.robo.v1.Project\x123\n" +
+	"\rCreateProject\x12\x10.robo.v1.Project\x1a\x10.robo.v1.Project\x123\n" +
+	"\rUpdateProject\x12\x10.robo.v1.Project\x1a\x10.robo.v1.Project\x12D\n" +
+	"\rDeleteProject\x12\x1a.robo.v1.GetProjectRequest\x1a\x17.robo.v1.DeleteResponseB1Z/github.com/songvi/robo/api/proto/robo/v1;robov1b\x06proto3"
+
+var (
+	file_api_proto_robo_v1_control_proto_rawDescOnce sync.Once
+	file_api_proto_robo_v1_control_proto_rawDescData []byte
+)
+
+func file_api_proto_robo_v1_control_proto_rawDescGZIP() []byte {
+	file_api_proto_robo_v1_control_proto_rawDescOnce.Do(func() {
+		file_api_proto_robo_v1_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_robo_v1_control_proto_rawDesc), len(file_api_proto_robo_v1_control_proto_rawDesc)))
+	})
+	return file_api_proto_robo_v1_control_proto_rawDescData
+}
+
+var file_api_proto_robo_v1_control_proto_msgTypes = make([]protoimpl.MessageInfo, 36)
+var file_api_proto_robo_v1_control_proto_goTypes = []any{
+	(*StartCycleRequest)(nil),         // 0: robo.v1.StartCycleRequest
+	(*GetCycleRequest)(nil),           // 1: robo.v1.GetCycleRequest
+	(*ListCyclesRequest)(nil),         // 2: robo.v1.ListCyclesRequest
+	(*ListCyclesResponse)(nil),        // 3: robo.v1.ListCyclesResponse
+	(*WatchCycleRequest)(nil),         // 4: robo.v1.WatchCycleRequest
+	(*CycleRequest)(nil),              // 5: robo.v1.CycleRequest
+	(*Cycle)(nil),                     // 6: robo.v1.Cycle
+	(*CycleProgress)(nil),             // 7: robo.v1.CycleProgress
+	(*SLOBreach)(nil),                 // 8: robo.v1.SLOBreach
+	(*CyclePlan)(nil),                 // 9: robo.v1.CyclePlan
+	(*ListTemplatesRequest)(nil),      // 10: robo.v1.ListTemplatesRequest
+	(*ListTemplatesResponse)(nil),     // 11: robo.v1.ListTemplatesResponse
+	(*GetTemplateRequest)(nil),        // 12: robo.v1.GetTemplateRequest
+	(*Template)(nil),                  // 13: robo.v1.Template
+	(*StartTemplateCycleRequest)(nil), // 14: robo.v1.StartTemplateCycleRequest
+	(*DeleteResponse)(nil),            // 15: robo.v1.DeleteResponse
+	(*ListJobsRequest)(nil),           // 16: robo.v1.ListJobsRequest
+	(*ListJobsResponse)(nil),          // 17: robo.v1.ListJobsResponse
+	(*GetJobRequest)(nil),             // 18: robo.v1.GetJobRequest
+	(*Job)(nil),                       // 19: robo.v1.Job
+	(*ListWorkersRequest)(nil),        // 20: robo.v1.ListWorkersRequest
+	(*ListWorkersResponse)(nil),       // 21: robo.v1.ListWorkersResponse
+	(*DrainWorkerRequest)(nil),        // 22: robo.v1.DrainWorkerRequest
+	(*Worker)(nil),                    // 23: robo.v1.Worker
+	(*ListProjectsRequest)(nil),       // 24: robo.v1.ListProjectsRequest
+	(*ListProjectsResponse)(nil),      // 25: robo.v1.ListProjectsResponse
+	(*GetProjectRequest)(nil),         // 26: robo.v1.GetProjectRequest
+	(*Project)(nil),                   // 27: robo.v1.Project
+	(*ProjectQuota)(nil),              // 28: robo.v1.ProjectQuota
+	nil,                               // 29: robo.v1.StartCycleRequest.LabelsEntry
+	nil,                               // 30: robo.v1.ListCyclesRequest.LabelsEntry
+	nil,                               // 31: robo.v1.Cycle.LabelsEntry
+	nil,                               // 32: robo.v1.CyclePlan.TargetsEntry
+	nil,                               // 33: robo.v1.CyclePlan.JobsEntry
+	nil,                               // 34: robo.v1.StartTemplateCycleRequest.LabelsEntry
+	nil,                               // 35: robo.v1.Worker.LabelsEntry
+}
+var file_api_proto_robo_v1_control_proto_depIdxs = []int32{
+	29, // 0: robo.v1.StartCycleRequest.labels:type_name -> robo.v1.StartCycleRequest.LabelsEntry
+	30, // 1: robo.v1.ListCyclesRequest.labels:type_name -> robo.v1.ListCyclesRequest.LabelsEntry
+	6,  // 2: robo.v1.ListCyclesResponse.cycles:type_name -> robo.v1.Cycle
+	7,  // 3: robo.v1.Cycle.progress:type_name -> robo.v1.CycleProgress
+	8,  // 4: robo.v1.Cycle.slo_breaches:type_name -> robo.v1.SLOBreach
+	31, // 5: robo.v1.Cycle.labels:type_name -> robo.v1.Cycle.LabelsEntry
+	32, // 6: robo.v1.CyclePlan.targets:type_name -> robo.v1.CyclePlan.TargetsEntry
+	33, // 7: robo.v1.CyclePlan.jobs:type_name -> robo.v1.CyclePlan.JobsEntry
+	13, // 8: robo.v1.ListTemplatesResponse.templates:type_name -> robo.v1.Template
+	34, // 9: robo.v1.StartTemplateCycleRequest.labels:type_name -> robo.v1.StartTemplateCycleRequest.LabelsEntry
+	19, // 10: robo.v1.ListJobsResponse.jobs:type_name -> robo.v1.Job
+	23, // 11: robo.v1.ListWorkersResponse.workers:type_name -> robo.v1.Worker
+	35, // 12: robo.v1.Worker.labels:type_name -> robo.v1.Worker.LabelsEntry
+	27, // 13: robo.v1.ListProjectsResponse.projects:type_name -> robo.v1.Project
+	28, // 14: robo.v1.Project.quota:type_name -> robo.v1.ProjectQuota
+	0,  // 15: robo.v1.CycleService.StartCycle:input_type -> robo.v1.StartCycleRequest
+	0,  // 16: robo.v1.CycleService.ValidateCycle:input_type -> robo.v1.StartCycleRequest
+	1,  // 17: robo.v1.CycleService.GetCycle:input_type -> robo.v1.GetCycleRequest
+	2,  // 18: robo.v1.CycleService.ListCycles:input_type -> robo.v1.ListCyclesRequest
+	4,  // 19: robo.v1.CycleService.WatchCycle:input_type -> robo.v1.WatchCycleRequest
+	5,  // 20: robo.v1.CycleService.PauseCycle:input_type -> robo.v1.CycleRequest
+	5,  // 21: robo.v1.CycleService.ResumeCycle:input_type -> robo.v1.CycleRequest
+	5,  // 22: robo.v1.CycleService.AbortCycle:input_type -> robo.v1.CycleRequest
+	10, // 23: robo.v1.TemplateService.ListTemplates:input_type -> robo.v1.ListTemplatesRequest
+	12, // 24: robo.v1.TemplateService.GetTemplate:input_type -> robo.v1.GetTemplateRequest
+	13, // 25: robo.v1.TemplateService.CreateTemplate:input_type -> robo.v1.Template
+	13, // 26: robo.v1.TemplateService.UpdateTemplate:input_type -> robo.v1.Template
+	12, // 27: robo.v1.TemplateService.DeleteTemplate:input_type -> robo.v1.GetTemplateRequest
+	14, // 28: robo.v1.TemplateService.StartTemplateCycle:input_type -> robo.v1.StartTemplateCycleRequest
+	16, // 29: robo.v1.JobService.ListJobs:input_type -> robo.v1.ListJobsRequest
+	18, // 30: robo.v1.JobService.GetJob:input_type -> robo.v1.GetJobRequest
+	18, // 31: robo.v1.JobService.RetryJob:input_type -> robo.v1.GetJobRequest
+	20, // 32: robo.v1.WorkerService.ListWorkers:input_type -> robo.v1.ListWorkersRequest
+	22, // 33: robo.v1.WorkerService.DrainWorker:input_type -> robo.v1.DrainWorkerRequest
+	24, // 34: robo.v1.ProjectService.ListProjects:input_type -> robo.v1.ListProjectsRequest
+	26, // 35: robo.v1.ProjectService.GetProject:input_type -> robo.v1.GetProjectRequest
+	27, // 36: robo.v1.ProjectService.CreateProject:input_type -> robo.v1.Project
+	27, // 37: robo.v1.ProjectService.UpdateProject:input_type -> robo.v1.Project
+	26, // 38: robo.v1.ProjectService.DeleteProject:input_type -> robo.v1.GetProjectRequest
+	6,  // 39: robo.v1.CycleService.StartCycle:output_type -> robo.v1.Cycle
+	9,  // 40: robo.v1.CycleService.ValidateCycle:output_type -> robo.v1.CyclePlan
+	6,  // 41: robo.v1.CycleService.GetCycle:output_type -> robo.v1.Cycle
+	3,  // 42: robo.v1.CycleService.ListCycles:output_type -> robo.v1.ListCyclesResponse
+	6,  // 43: robo.v1.CycleService.WatchCycle:output_type -> robo.v1.Cycle
+	6,  // 44: robo.v1.CycleService.PauseCycle:output_type -> robo.v1.Cycle
+	6,  // 45: robo.v1.CycleService.ResumeCycle:output_type -> robo.v1.Cycle
+	6,  // 46: robo.v1.CycleService.AbortCycle:output_type -> robo.v1.Cycle
+	11, // 47: robo.v1.TemplateService.ListTemplates:output_type -> robo.v1.ListTemplatesResponse
+	13, // 48: robo.v1.TemplateService.GetTemplate:output_type -> robo.v1.Template
+	13, // 49: robo.v1.TemplateService.CreateTemplate:output_type -> robo.v1.Template
+	13, // 50: robo.v1.TemplateService.UpdateTemplate:output_type -> robo.v1.Template
+	15, // 51: robo.v1.TemplateService.DeleteTemplate:output_type -> robo.v1.DeleteResponse
+	6,  // 52: robo.v1.TemplateService.StartTemplateCycle:output_type -> robo.v1.Cycle
+	17, // 53: robo.v1.JobService.ListJobs:output_type -> robo.v1.ListJobsResponse
+	19, // 54: robo.v1.JobService.GetJob:output_type -> robo.v1.Job
+	19, // 55: robo.v1.JobService.RetryJob:output_type -> robo.v1.Job
+	21, // 56: robo.v1.WorkerService.ListWorkers:output_type -> robo.v1.ListWorkersResponse
+	15, // 57: robo.v1.WorkerService.DrainWorker:output_type -> robo.v1.DeleteResponse
+	25, // 58: robo.v1.ProjectService.ListProjects:output_type -> robo.v1.ListProjectsResponse
+	27, // 59: robo.v1.ProjectService.GetProject:output_type -> robo.v1.Project
+	27, // 60: robo.v1.ProjectService.CreateProject:output_type -> robo.v1.Project
+	27, // 61: robo.v1.ProjectService.UpdateProject:output_type -> robo.v1.Project
+	15, // 62: robo.v1.ProjectService.DeleteProject:output_type -> robo.v1.DeleteResponse
+	39, // [39:63] is the sub-list for method output_type
+	15, // [15:39] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_robo_v1_control_proto_init() }
+func file_api_proto_robo_v1_control_proto_init() {
+	if File_api_proto_robo_v1_control_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_robo_v1_control_proto_rawDesc), len(file_api_proto_robo_v1_control_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   36,
+			NumExtensions: 0,
+			NumServices:   5,
+		},
+		GoTypes:           file_api_proto_robo_v1_control_proto_goTypes,
+		DependencyIndexes: file_api_proto_robo_v1_control_proto_depIdxs,
+		MessageInfos:      file_api_proto_robo_v1_control_proto_msgTypes,
+	}.Build()
+	File_api_proto_robo_v1_control_proto = out.File
+	file_api_proto_robo_v1_control_proto_goTypes = nil
+	file_api_proto_robo_v1_control_proto_depIdxs = nil
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/songvi/robo/config"
+)
+
+// RateLimitConfig defines per-tenant limits applied to the control-plane API; it is
+// read from the api section of the config
+type RateLimitConfig = config.RateLimitConfig
+
+// sweepInterval is how often the buckets of idle tenants are dropped
+const sweepInterval = time.Minute
+
+// tokenBucket tracks the remaining tokens and in-flight requests of one tenant
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	inFlight int
+}
+
+// bucketSet holds the token buckets of the tenants for one limit
+type bucketSet struct {
+	rate          float64 // Tokens per second; zero disables the limit
+	burst         int
+	maxConcurrent int
+	buckets       map[string]*tokenBucket
+	swept         time.Time
+}
+
+func newBucketSet(rate float64, burst, maxConcurrent int) *bucketSet {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &bucketSet{rate: rate, burst: burst, maxConcurrent: maxConcurrent, buckets: make(map[string]*tokenBucket)}
+}
+
+// refill adds the tokens earned since the bucket was last used
+func (s *bucketSet) refill(b *tokenBucket, now time.Time) {
+	b.tokens = math.Min(float64(s.burst), b.tokens+now.Sub(b.last).Seconds()*s.rate)
+	b.last = now
+}
+
+// sweep drops the buckets without requests in flight that refilled completely, which
+// are no different from the new bucket a tenant gets
+func (s *bucketSet) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for key, b := range s.buckets {
+		if s.refill(b, now); b.inFlight == 0 && b.tokens >= float64(s.burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// acquire takes a token and, with slot, a concurrency slot for key, returning the
+// remaining tokens and, when refused, how long until the next token is available
+func (s *bucketSet) acquire(key string, now time.Time, slot bool) (remaining int, retryAfter time.Duration, ok bool) {
+	s.sweep(now)
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: float64(s.burst), last: now}
+		s.buckets[key] = b
+	}
+	s.refill(b, now)
+
+	if slot && s.maxConcurrent > 0 && b.inFlight >= s.maxConcurrent {
+		return int(b.tokens), time.Second, false
+	}
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / s.rate * float64(time.Second))
+		return 0, wait, false
+	}
+	b.tokens--
+	if slot {
+		b.inFlight++
+	}
+	return int(b.tokens), 0, true
+}
+
+// RateLimiter is a token bucket limiter of the requests and the cycle starts of each
+// tenant, identified by its API key when configured and by its address otherwise
+type RateLimiter struct {
+	config   RateLimitConfig
+	mu       sync.Mutex
+	requests *bucketSet
+	cycles   *bucketSet
+	now      func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter with the given limits
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	requests := newBucketSet(config.RequestsPerSecond, config.Burst, config.MaxConcurrent)
+	config.Burst = requests.burst
+	cycleBurst := config.CycleBurst
+	if cycleBurst <= 0 {
+		cycleBurst = int(math.Ceil(config.CyclesPerMinute))
+	}
+	return &RateLimiter{
+		config:   config,
+		requests: requests,
+		cycles:   newBucketSet(config.CyclesPerMinute/60, cycleBurst, 0),
+		now:      time.Now,
+	}
+}
+
+// clientAddr returns the host of the client of r
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// tenantKey identifies the caller by the tenant of its API key, falling back to the
+// client address for keys the config does not list
+func (rl *RateLimiter) tenantKey(r *http.Request) string {
+	return rl.tenant(r.Header.Get("X-API-Key"), clientAddr(r))
+}
+
+// tenant identifies the caller of apiKey at addr
+func (rl *RateLimiter) tenant(apiKey, addr string) string {
+	if tenant, ok := rl.config.Tenants[apiKey]; ok {
+		return "tenant:" + tenant
+	}
+	return "addr:" + addr
+}
+
+// startsCycle reports whether r starts a cycle, from scratch or from a template
+func startsCycle(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	return r.URL.Path == "/cycles" ||
+		(strings.HasPrefix(r.URL.Path, "/templates/") && strings.HasSuffix(r.URL.Path, "/cycles"))
+}
+
+// acquire takes a token and a concurrency slot for key, returning the remaining tokens
+// and, when refused, how long until the next token is available
+func (rl *RateLimiter) acquire(key string) (remaining int, retryAfter time.Duration, ok bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.requests.acquire(key, rl.now(), true)
+}
+
+// acquireCycle takes a cycle start token for key
+func (rl *RateLimiter) acquireCycle(key string) (retryAfter time.Duration, ok bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	_, retryAfter, ok = rl.cycles.acquire(key, rl.now(), false)
+	return retryAfter, ok
+}
+
+// release frees the concurrency slot taken by acquire
+func (rl *RateLimiter) release(key string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if b, ok := rl.requests.buckets[key]; ok && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+// admit applies the limits of key to a call of the gRPC API, which reports no quota in
+// headers. It returns the release of the concurrency slot the call takes or, when
+// refused, how long until it may be retried.
+func (rl *RateLimiter) admit(key string, cycleStart bool) (release func(), retryAfter time.Duration, ok bool) {
+	release = func() {}
+	if rl.config.RequestsPerSecond > 0 {
+		if _, retryAfter, ok := rl.acquire(key); !ok {
+			return nil, retryAfter, false
+		}
+		release = func() { rl.release(key) }
+	}
+	if rl.config.CyclesPerMinute > 0 && cycleStart {
+		if retryAfter, ok := rl.acquireCycle(key); !ok {
+			release()
+			return nil, retryAfter, false
+		}
+	}
+	return release, 0, true
+}
+
+// reject answers 429 with how long to wait before retrying
+func (rl *RateLimiter) reject(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(rl.now().Add(retryAfter).Unix(), 10))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
+
+// Middleware rejects requests over the tenant's limits with 429 and reports the
+// remaining quota in X-RateLimit-* headers
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if rl.config.RequestsPerSecond <= 0 && rl.config.CyclesPerMinute <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := rl.tenantKey(r)
+		if rl.config.RequestsPerSecond > 0 {
+			remaining, retryAfter, ok := rl.acquire(key)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.config.Burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if !ok {
+				rl.reject(w, retryAfter, "rate limit exceeded")
+				return
+			}
+			defer rl.release(key)
+		}
+		if rl.config.CyclesPerMinute > 0 && startsCycle(r) {
+			if retryAfter, ok := rl.acquireCycle(key); !ok {
+				rl.reject(w, retryAfter, "cycle start limit exceeded")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	rl := NewRateLimiter(RateLimitConfig{
+		RequestsPerSecond: 1, Burst: 2, CyclesPerMinute: 1,
+		Tenants: map[string]string{"ci-key": "ci", "other-key": "other"},
+	})
+	rl.now = func() time.Time { return now }
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/cycles", "ci-key").Code)
+	rec := call(http.MethodGet, "/cycles", "ci-key")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = call(http.MethodGet, "/cycles", "ci-key")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "burst exhausted")
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/cycles", "other-key").Code, "tenants have independent buckets")
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/cycles", "random-1").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/cycles", "random-2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, call(http.MethodGet, "/cycles", "random-3").Code,
+		"unknown keys are limited by address")
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/cycles", "ci-key").Code, "tokens refill over time")
+	now = now.Add(time.Second)
+	rec = call(http.MethodPost, "/templates/nightly/cycles", "ci-key")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "one cycle per minute")
+	assert.Equal(t, "59", rec.Header().Get("Retry-After"))
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/cycles/validate", "ci-key").Code, "validation starts no cycle")
+
+	now = now.Add(time.Hour)
+	call(http.MethodGet, "/cycles", "ci-key")
+	assert.Len(t, rl.requests.buckets, 1, "the buckets of idle tenants are dropped")
+}
@@ -0,0 +1,889 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/aggregator"
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/job"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// Server is the control-plane HTTP API for cycles, jobs and workers
+type Server struct {
+	logger     logger.Logger
+	jobs       job.JobService
+	store      store.Store
+	dispatcher dispatcher.Dispatcher
+	results    aggregator.Aggregator
+	metrics    *store.QueryMetrics
+	events     *store.Events
+	limiter    *RateLimiter
+}
+
+// ServerParams are the dependencies of the API server
+type ServerParams struct {
+	fx.In
+	Lifecycle  fx.Lifecycle
+	Config     config.ConfigService
+	Logger     logger.Logger
+	Jobs       job.JobService
+	Store      store.Store
+	Dispatcher dispatcher.Dispatcher
+	Results    aggregator.Aggregator
+	Metrics    *store.QueryMetrics
+	Events     *store.Events
+}
+
+// NewServer creates the API server and serves it for the lifetime of the application
+func NewServer(p ServerParams) *Server {
+	cfg := p.Config.GetConfig().API
+	s := &Server{
+		logger:     p.Logger,
+		jobs:       p.Jobs,
+		store:      p.Store,
+		dispatcher: p.Dispatcher,
+		results:    p.Results,
+		metrics:    p.Metrics,
+		events:     p.Events,
+		limiter:    NewRateLimiter(cfg.RateLimit),
+	}
+
+	addr := cfg.Addr
+	httpServer := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					p.Logger.Error(context.Background(), "API server stopped", "addr", addr, "error", err)
+				}
+			}()
+			p.Logger.Info(ctx, "API server listening", "addr", addr)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return httpServer.Shutdown(ctx)
+		},
+	})
+	if cfg.GRPCAddr != "" {
+		s.serveGRPC(p.Lifecycle, cfg.GRPCAddr)
+	}
+	return s
+}
+
+// Handler routes the API behind the rate limiter
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /cycles", s.listCycles)
+	mux.HandleFunc("POST /cycles", s.startCycle)
+	mux.HandleFunc("POST /cycles/validate", s.validateCycle)
+	mux.HandleFunc("GET /cycles/{id}", s.getCycle)
+	mux.HandleFunc("DELETE /cycles/{id}", s.abortCycle)
+	mux.HandleFunc("POST /cycles/{id}/pause", s.pauseCycle)
+	mux.HandleFunc("POST /cycles/{id}/resume", s.resumeCycle)
+	mux.HandleFunc("GET /cycles/{id}/compare", s.compareCycle)
+	mux.HandleFunc("GET /cycles/{id}/export", s.exportCycle)
+	mux.HandleFunc("GET /cycles/{id}/timeseries", s.cycleTimeSeries)
+	mux.HandleFunc("GET /jobs", s.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	mux.HandleFunc("POST /jobs/{id}/retry", s.retryJob)
+	mux.HandleFunc("GET /workers", s.listWorkers)
+	mux.HandleFunc("POST /workers/{id}/drain", s.drainWorker)
+	mux.HandleFunc("GET /templates", s.listTemplates)
+	mux.HandleFunc("POST /templates", s.createTemplate)
+	mux.HandleFunc("GET /templates/{name}", s.getTemplate)
+	mux.HandleFunc("PUT /templates/{name}", s.updateTemplate)
+	mux.HandleFunc("DELETE /templates/{name}", s.deleteTemplate)
+	mux.HandleFunc("POST /templates/{name}/cycles", s.startTemplateCycle)
+	mux.HandleFunc("GET /metrics", s.writeMetrics)
+	mux.HandleFunc("POST /purge", s.purge)
+	mux.HandleFunc("GET /events", s.streamEvents)
+	mux.HandleFunc("GET /projects", s.listProjects)
+	mux.HandleFunc("POST /projects", s.createProject)
+	mux.HandleFunc("GET /projects/{id}", s.getProject)
+	mux.HandleFunc("PUT /projects/{id}", s.updateProject)
+	mux.HandleFunc("DELETE /projects/{id}", s.deleteProject)
+	return s.limiter.Middleware(withCreator(s.withProject(mux)))
+}
+
+// withProject scopes a request to the project named by its X-Project header, which
+// must exist; requests without one see every project and create in the default one
+func (s *Server) withProject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Project")
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := s.store.GetProject(r.Context(), id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				err = fmt.Errorf("%w: %s", store.ErrUnknownProject, id)
+			}
+			writeStoreError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(models.WithProject(r.Context(), id)))
+	})
+}
+
+// withCreator records the caller as the creator of the rows its request creates
+func withCreator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creator := creatorOf(r.Header.Get("X-API-Key"), clientAddr(r))
+		next.ServeHTTP(w, r.WithContext(models.WithCreator(r.Context(), creator)))
+	})
+}
+
+// creatorOf identifies the caller of apiKey at addr by a digest of its API key, so the
+// key is not stored, or by its address
+func creatorOf(apiKey, addr string) string {
+	if apiKey == "" {
+		return "addr:" + addr
+	}
+	digest := sha256.Sum256([]byte(apiKey))
+	return "key:" + hex.EncodeToString(digest[:6])
+}
+
+// startCycleRequest starts a cycle; a missing strategy uses the configured one and a
+// missing seed a random one
+type startCycleRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+	Seed        int64             `json:"seed"`
+	Strategy    *models.Strategy  `json:"strategy"`
+}
+
+// startCycle handles POST /cycles
+func (s *Server) startCycle(w http.ResponseWriter, r *http.Request) {
+	var req startCycleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateLabels(req.Labels); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Planning outlives a client that stops waiting
+	cycle, err := s.jobs.StartCycle(context.WithoutCancel(r.Context()), models.Cycle{
+		Name: req.Name, Description: req.Description, Labels: req.Labels, Seed: req.Seed, Strategy: req.Strategy,
+	})
+	writeStartedCycle(w, cycle, err)
+}
+
+// validateCycle handles POST /cycles/validate: it plans the cycle of a start request
+// without starting it
+func (s *Server) validateCycle(w http.ResponseWriter, r *http.Request) {
+	var req startCycleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	plan, err := s.jobs.ValidateCycle(r.Context(), models.Cycle{Name: req.Name, Seed: req.Seed, Strategy: req.Strategy})
+	if err != nil {
+		if errors.Is(err, job.ErrInvalidStrategy) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// validateLabels rejects label keys that cannot be filtered on
+func validateLabels(labels map[string]string) error {
+	for k := range labels {
+		if k == "" || strings.Contains(k, "=") {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+	}
+	return nil
+}
+
+// listCycles handles GET /cycles[?status=...][&template=...][&label=KEY=VALUE]; status
+// takes a comma-separated list and label repeats, every label having to match
+func (s *Server) listCycles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.CycleFilter{Template: query.Get("template")}
+	if status := query.Get("status"); status != "" {
+		filter.Statuses = strings.Split(status, ",")
+	}
+	for _, label := range query["label"] {
+		k, v, ok := strings.Cut(label, "=")
+		if !ok || k == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid label %q, want KEY=VALUE", label))
+			return
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[k] = v
+	}
+	cycles, err := s.store.ListCycles(r.Context(), filter)
+	if err == nil {
+		err = s.jobs.CyclesProgress(r.Context(), cycles)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if cycles == nil {
+		cycles = []models.Cycle{}
+	}
+	writeJSON(w, http.StatusOK, cycles)
+}
+
+// writeStartedCycle answers a request that started cycle
+func writeStartedCycle(w http.ResponseWriter, cycle models.Cycle, err error) {
+	if err != nil {
+		if errors.Is(err, job.ErrInvalidStrategy) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, cycle)
+}
+
+// cycleResponse is a cycle with its current progress and job statistics
+type cycleResponse struct {
+	models.Cycle
+	Stats *models.CycleStats `json:"stats,omitempty"`
+}
+
+// getCycle handles GET /cycles/{id}
+func (s *Server) getCycle(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	cycle, err := s.store.GetCycle(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if cycle.Progress, err = s.jobs.CycleProgress(r.Context(), id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	resp := cycleResponse{Cycle: *cycle}
+	if stats, ok := s.results.CycleStats(id); ok {
+		resp.Stats = &stats
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// defaultMaxRegressionPct bounds the latency growth and throughput drop of a compared
+// cycle when the request leaves them unset
+const defaultMaxRegressionPct = 10
+
+// compareCycle handles GET /cycles/{id}/compare?baseline=...[&max_latency_regression=PCT]
+// [&max_throughput_regression=PCT]. Both cycles must have finished their jobs and come
+// from the same template.
+func (s *Server) compareCycle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	baselineID := query.Get("baseline")
+	if baselineID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("baseline is required"))
+		return
+	}
+	thresholds := make(map[string]float64, 2)
+	for _, name := range []string{"max_latency_regression", "max_throughput_regression"} {
+		thresholds[name] = defaultMaxRegressionPct
+		if v := query.Get(name); v != "" {
+			pct, err := strconv.ParseFloat(v, 64)
+			if err != nil || pct < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be a non-negative percentage", name))
+				return
+			}
+			thresholds[name] = pct
+		}
+	}
+
+	var cycles [2]*models.Cycle
+	var results [2][]models.Job
+	for i, id := range []string{baselineID, r.PathValue("id")} {
+		cycle, err := s.store.GetCycle(r.Context(), id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if cycle.Status != "completed" && cycle.Status != "failed_slo" {
+			writeError(w, http.StatusConflict, fmt.Errorf("cycle %s is %s, expected completed or failed_slo", id, cycle.Status))
+			return
+		}
+		if results[i], err = s.store.GetCycleResults(r.Context(), id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		cycles[i] = cycle
+	}
+	if cycles[0].Template == "" || cycles[0].Template != cycles[1].Template {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("cycles must start from the same template, got %q and %q", cycles[0].Template, cycles[1].Template))
+		return
+	}
+	writeJSON(w, http.StatusOK, aggregator.CompareCycles(cycles[0], cycles[1], results[0], results[1],
+		thresholds["max_latency_regression"], thresholds["max_throughput_regression"]))
+}
+
+// exportContentTypes maps the export formats to their media types
+var exportContentTypes = map[string]string{
+	"csv":     "text/csv",
+	"parquet": "application/vnd.apache.parquet",
+}
+
+// exportCycle handles GET /cycles/{id}/export[?format=csv|parquet][&data=jobs|stats]:
+// every job of the cycle, or the statistics of each action of its load phase, as CSV
+// (default) or Parquet. Jobs are streamed without their input and output data.
+func (s *Server) exportCycle(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %q", aggregator.ErrExportFormat, format))
+		return
+	}
+	data := query.Get("data")
+	if data != "" && data != "jobs" && data != "stats" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("data must be jobs or stats, got %q", data))
+		return
+	}
+	if _, err := s.store.GetCycle(r.Context(), id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if data == "stats" {
+		results, err := s.store.GetCycleResults(r.Context(), id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-stats.%s"`, id, format))
+		if err := aggregator.ExportStats(w, format, aggregator.Summarize(results)); err != nil {
+			s.logger.Error(r.Context(), "Failed to export cycle statistics", "cycle_uuid", id, "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-jobs.%s"`, id, format))
+	exporter, err := aggregator.NewJobExporter(w, format)
+	if err == nil {
+		err = s.store.ScanCycleJobs(r.Context(), id, exporter.Write)
+	}
+	if err == nil {
+		err = exporter.Close()
+	}
+	// The response is under way, so the export can only stop short
+	if err != nil {
+		s.logger.Error(r.Context(), "Failed to export cycle jobs", "cycle_uuid", id, "error", err)
+	}
+}
+
+// timeSeriesPoint is the rollup of the jobs of one action within one minute with the
+// statistics it yields
+type timeSeriesPoint struct {
+	models.MetricRollup
+	MeanMs float64 `json:"mean_ms"`
+	P95Ms  int64   `json:"p95_ms"`
+}
+
+// cycleTimeSeries handles GET /cycles/{id}/timeseries[?from=T][&to=T]: the per-minute
+// rollups of the finished jobs of a cycle, by minute and action, from the saved rollups
+// rather than the jobs
+func (s *Server) cycleTimeSeries(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var from, to int64
+	for name, bound := range map[string]*int64{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(name); v != "" {
+			t, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || t < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be a Unix time in seconds", name))
+				return
+			}
+			*bound = t
+		}
+	}
+	if _, err := s.store.GetCycle(r.Context(), id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	rollups, err := s.store.ListMetricRollups(r.Context(), id, from, to)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	points := make([]timeSeriesPoint, len(rollups))
+	for i, rollup := range rollups {
+		points[i] = timeSeriesPoint{MetricRollup: rollup, P95Ms: rollup.QuantileMs(0.95)}
+		if rollup.Jobs > 0 {
+			points[i].MeanMs = float64(rollup.DurationSumMs) / float64(rollup.Jobs)
+		}
+	}
+	writeJSON(w, http.StatusOK, points)
+}
+
+// abortCycle handles DELETE /cycles/{id}
+func (s *Server) abortCycle(w http.ResponseWriter, r *http.Request) {
+	s.changeStatus(w, r, s.jobs.AbortCycle)
+}
+
+// pauseCycle handles POST /cycles/{id}/pause
+func (s *Server) pauseCycle(w http.ResponseWriter, r *http.Request) {
+	s.changeStatus(w, r, s.jobs.PauseCycle)
+}
+
+// resumeCycle handles POST /cycles/{id}/resume
+func (s *Server) resumeCycle(w http.ResponseWriter, r *http.Request) {
+	s.changeStatus(w, r, s.jobs.ResumeCycle)
+}
+
+// changeStatus applies a status change to the cycle or job of the path
+func (s *Server) changeStatus(w http.ResponseWriter, r *http.Request, change func(context.Context, string) error) {
+	if err := change(r.Context(), r.PathValue("id")); err != nil {
+		if errors.Is(err, job.ErrCycleState) || errors.Is(err, job.ErrJobState) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listJobs handles GET /jobs?[status=a,b][&cycle=...][&session=...][&worker=...]
+// [&since=...][&until=...][&sort=[-]column][&limit=...][&cursor=...], a page of jobs
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.JobFilter{
+		CycleUUID: query.Get("cycle"),
+		SessionID: query.Get("session"),
+		WorkerID:  query.Get("worker"),
+	}
+	if status := query.Get("status"); status != "" {
+		filter.Statuses = strings.Split(status, ",")
+	}
+	page := store.Page{Cursor: query.Get("cursor")}
+	page.Sort, page.Desc = strings.CutPrefix(query.Get("sort"), "-")
+	for name, bound := range map[string]*int64{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			t, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || t < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be a Unix time in seconds", name))
+				return
+			}
+			*bound = t
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive number"))
+			return
+		}
+		page.Limit = n
+	}
+	result, err := s.store.ListJobs(r.Context(), filter, page)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidPage) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+	if result.Jobs == nil {
+		result.Jobs = []models.Job{}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// getJob handles GET /jobs/{id}
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.store.GetJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// retryJob handles POST /jobs/{id}/retry
+func (s *Server) retryJob(w http.ResponseWriter, r *http.Request) {
+	s.changeStatus(w, r, s.jobs.RetryJob)
+}
+
+// listWorkers handles GET /workers
+func (s *Server) listWorkers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.dispatcher.GetActiveWorkers())
+}
+
+// drainWorker handles POST /workers/{id}/drain
+func (s *Server) drainWorker(w http.ResponseWriter, r *http.Request) {
+	if err := s.dispatcher.DrainWorker(r.Context(), r.PathValue("id")); err != nil {
+		if errors.Is(err, dispatcher.ErrUnknownWorker) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listTemplates handles GET /templates
+func (s *Server) listTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.store.ListCycleTemplates(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if templates == nil {
+		templates = []models.CycleTemplate{}
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// getTemplate handles GET /templates/{name}
+func (s *Server) getTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := s.store.GetCycleTemplate(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+// decodeTemplate reads a template from the request body and validates its strategy
+func decodeTemplate(w http.ResponseWriter, r *http.Request) (*models.CycleTemplate, bool) {
+	var template models.CycleTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	if err := job.ValidateStrategy(template.Strategy); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return &template, true
+}
+
+// createTemplate handles POST /templates
+func (s *Server) createTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := decodeTemplate(w, r)
+	if !ok {
+		return
+	}
+	if template.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("name is required"))
+		return
+	}
+	if _, err := s.store.GetCycleTemplate(r.Context(), template.Name); err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("template %s already exists", template.Name))
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeStoreError(w, err)
+		return
+	}
+	if err := s.store.CreateCycleTemplate(r.Context(), template); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// updateTemplate handles PUT /templates/{name}
+func (s *Server) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := decodeTemplate(w, r)
+	if !ok {
+		return
+	}
+	template.Name = r.PathValue("name")
+	if err := s.store.UpdateCycleTemplate(r.Context(), template); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.getTemplate(w, r)
+}
+
+// deleteTemplate handles DELETE /templates/{name}
+func (s *Server) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.DeleteCycleTemplate(r.Context(), r.PathValue("name")); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startTemplateCycleRequest names and labels the cycle and overrides the template
+// strategy as a JSON merge patch
+type startTemplateCycleRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+	Seed        int64             `json:"seed"`
+	Overrides   json.RawMessage   `json:"overrides"`
+}
+
+// startTemplateCycle handles POST /templates/{name}/cycles
+func (s *Server) startTemplateCycle(w http.ResponseWriter, r *http.Request) {
+	var req startTemplateCycleRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if err := validateLabels(req.Labels); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	cycle, err := s.jobs.StartTemplateCycle(context.WithoutCancel(r.Context()), r.PathValue("name"),
+		models.Cycle{Name: req.Name, Description: req.Description, Labels: req.Labels, Seed: req.Seed}, req.Overrides)
+	writeStartedCycle(w, cycle, err)
+}
+
+// listProjects handles GET /projects
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := s.store.ListProjects(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// getProject handles GET /projects/{id}
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request) {
+	project, err := s.store.GetProject(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, project)
+}
+
+// decodeProject reads a project from the request body and validates its quota
+func decodeProject(w http.ResponseWriter, r *http.Request) (*models.Project, bool) {
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	if err := validateQuota(project.Quota); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return &project, true
+}
+
+// validateProjectID rejects project IDs that cannot be used in paths and headers
+func validateProjectID(id string) error {
+	if id == "" || strings.ContainsAny(id, "/?# ") {
+		return fmt.Errorf("invalid project ID %q", id)
+	}
+	return nil
+}
+
+func validateQuota(quota models.ProjectQuota) error {
+	if quota.MaxRunningCycles < 0 || quota.MaxCycleJobs < 0 {
+		return errors.New("quotas must not be negative")
+	}
+	return nil
+}
+
+// createProject handles POST /projects
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := decodeProject(w, r)
+	if !ok {
+		return
+	}
+	if err := validateProjectID(project.ID); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.store.GetProject(r.Context(), project.ID); err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("project %s already exists", project.ID))
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeStoreError(w, err)
+		return
+	}
+	if err := s.store.CreateProject(r.Context(), project); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, project)
+}
+
+// updateProject handles PUT /projects/{id}
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := decodeProject(w, r)
+	if !ok {
+		return
+	}
+	project.ID = r.PathValue("id")
+	if err := s.store.UpdateProject(r.Context(), project); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.getProject(w, r)
+}
+
+// deleteProject handles DELETE /projects/{id}
+func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.DeleteProject(r.Context(), r.PathValue("id")); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeMetrics handles GET /metrics: the store query metrics, for Prometheus scrapes
+func (s *Server) writeMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.metrics.WriteMetrics(w); err != nil {
+		s.logger.Error(r.Context(), "Failed to write metrics", "error", err)
+	}
+}
+
+// purgeRequest purges the rows soft deleted before a Unix time, now when unset
+type purgeRequest struct {
+	DeletedBefore int64 `json:"deleted_before"`
+}
+
+// purge handles POST /purge: it deletes soft-deleted rows for good and answers how many
+// it deleted per table
+func (s *Server) purge(w http.ResponseWriter, r *http.Request) {
+	var req purgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.DeletedBefore == 0 {
+		req.DeletedBefore = time.Now().Unix()
+	}
+	purged, err := s.store.Purge(r.Context(), req.DeletedBefore)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, purged)
+}
+
+// eventBuffer is the number of changes held for a slow events client before they are
+// dropped
+const eventBuffer = 256
+
+// streamEvents handles GET /events[?cycle=ID]: the status changes of the jobs and
+// cycles of one cycle, or of all, as server-sent events until the client disconnects.
+// Changes a slow client misses are counted in a "dropped" event, after which it reads
+// the state it missed from the API.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	cycleUUID := r.URL.Query().Get("cycle")
+	project := models.ProjectFrom(r.Context())
+	changes := make(chan store.Change, eventBuffer)
+	var dropped atomic.Int64
+	unsubscribe := s.events.Subscribe(func(ctx context.Context, change store.Change) {
+		if cycleUUID != "" && change.CycleUUID != cycleUUID || project != "" && change.ProjectID != project {
+			return
+		}
+		select {
+		case changes <- change:
+		default:
+			dropped.Add(1)
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case change := <-changes:
+			if n := dropped.Swap(0); n > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", n)
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				s.logger.Error(r.Context(), "Failed to encode change", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", change.Kind, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeStoreError answers 404 for missing records and projects, 403 beyond the quota of
+// a project and 500 otherwise
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, store.ErrUnknownProject) || errors.Is(err, store.ErrCycleNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, store.ErrDuplicateUUID) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, store.ErrQuotaExceeded) {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// Module defines the Fx module for the API server
+var Module = fx.Module(
+	"api",
+	fx.Provide(NewServer),
+	fx.Invoke(func(*Server) {}),
+)
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/aggregator"
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/job"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+type fakeJobs struct {
+	job.JobService
+	started models.Cycle
+}
+
+func (f *fakeJobs) StartCycle(ctx context.Context, cycle models.Cycle) (models.Cycle, error) {
+	if cycle.Strategy != nil && cycle.Strategy.MaxRate < 0 {
+		return cycle, fmt.Errorf("%w: max_rate must not be negative", job.ErrInvalidStrategy)
+	}
+	if models.ProjectFrom(ctx) == "full" {
+		return cycle, fmt.Errorf("failed to save cycle: %w", store.ErrQuotaExceeded)
+	}
+	cycle.UUID, cycle.Status = "c1", "running"
+	f.started = cycle
+	return cycle, nil
+}
+
+func (f *fakeJobs) CycleProgress(ctx context.Context, cycleUUID string) (models.CycleProgress, error) {
+	return models.CycleProgress{TotalJobs: 4, CompletedJobs: 1}, nil
+}
+
+func (f *fakeJobs) CyclesProgress(ctx context.Context, cycles []models.Cycle) error {
+	for i := range cycles {
+		cycles[i].Progress.TotalJobs = 4
+	}
+	return nil
+}
+
+func (f *fakeJobs) AbortCycle(ctx context.Context, cycleUUID string) error {
+	return fmt.Errorf("cycle %s is completed: %w", cycleUUID, job.ErrCycleState)
+}
+
+func (f *fakeJobs) RetryJob(ctx context.Context, jobUUID string) error {
+	return fmt.Errorf("job %s is completed: %w", jobUUID, job.ErrJobState)
+}
+
+type fakeDispatcher struct {
+	dispatcher.Dispatcher
+	drained []string
+}
+
+func (f *fakeDispatcher) DrainWorker(ctx context.Context, workerID string) error {
+	if workerID != "w1" {
+		return fmt.Errorf("%w: %s", dispatcher.ErrUnknownWorker, workerID)
+	}
+	f.drained = append(f.drained, workerID)
+	return nil
+}
+
+type fakeStore struct {
+	store.Store
+	filter    store.CycleFilter
+	jobFilter store.JobFilter
+	page      store.Page
+	purged    int64  // The time rows were purged before
+	creator   string // The creator of the rows of the purge request
+	project   string // The project cycles were listed in
+	created   *models.Project
+}
+
+func (f *fakeStore) GetCycle(ctx context.Context, uuid string) (*models.Cycle, error) {
+	if uuid != "c1" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Cycle{UUID: "c1", Name: "nightly", Status: "running"}, nil
+}
+
+func (f *fakeStore) ListCycles(ctx context.Context, filter store.CycleFilter) ([]models.Cycle, error) {
+	f.filter, f.project = filter, models.ProjectFrom(ctx)
+	return []models.Cycle{{UUID: "c1", Status: "running"}}, nil
+}
+
+func (f *fakeStore) ListJobs(ctx context.Context, filter store.JobFilter, page store.Page) (store.JobPage, error) {
+	if page.Cursor == "bad" {
+		return store.JobPage{}, store.ErrInvalidPage
+	}
+	f.jobFilter, f.page = filter, page
+	return store.JobPage{Jobs: []models.Job{{UUID: "j1", Status: filter.Statuses[0]}}, NextCursor: "next"}, nil
+}
+
+func (f *fakeStore) ScanCycleJobs(ctx context.Context, cycleUUID string, fn func(jobs []models.Job) error) error {
+	return fn([]models.Job{{UUID: "j1", CycleUUID: cycleUUID, Name: "upload_file", Status: "completed", DurationMs: 10}})
+}
+
+func (f *fakeStore) GetCycleResults(ctx context.Context, cycleUUID string) ([]models.Job, error) {
+	return []models.Job{{UUID: "j1", CycleUUID: cycleUUID, Name: "upload_file", Status: "completed", DurationMs: 10}}, nil
+}
+
+func (f *fakeStore) Purge(ctx context.Context, deletedBefore int64) (map[string]int64, error) {
+	f.purged, f.creator = deletedBefore, models.CreatorFrom(ctx)
+	return map[string]int64{"cycles": 2}, nil
+}
+
+func (f *fakeStore) UpdateJob(ctx context.Context, job *models.Job) error { return nil }
+
+func (f *fakeStore) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	if id != "team-a" && id != "full" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Project{ID: id}, nil
+}
+
+func (f *fakeStore) CreateProject(ctx context.Context, project *models.Project) error {
+	f.created = project
+	return nil
+}
+
+func (f *fakeStore) ListMetricRollups(ctx context.Context, cycleUUID string, from, to int64) ([]models.MetricRollup, error) {
+	rollup := models.MetricRollup{CycleUUID: cycleUUID, Action: "login", Minute: 60}
+	rollup.Record(20, false)
+	rollup.Record(40, true)
+	return []models.MetricRollup{rollup}, nil
+}
+
+func TestStreamEvents(t *testing.T) {
+	events := store.NewEvents()
+	s := &Server{logger: logger.NewSlogLogger(), events: events, limiter: NewRateLimiter(RateLimitConfig{})}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events?cycle=c1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The stream is subscribed once its headers are sent
+	observed := store.NewObservedStore(&fakeStore{}, events)
+	require.NoError(t, observed.UpdateJob(context.Background(), &models.Job{UUID: "j0", CycleUUID: "c2", Status: "failed"}))
+	require.NoError(t, observed.UpdateJob(context.Background(), &models.Job{UUID: "j1", CycleUUID: "c1", Status: "failed"}))
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: job\n", line, "the changes of other cycles are left out")
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	var change store.Change
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change))
+	assert.Equal(t, "j1", change.UUID)
+	assert.Equal(t, "failed", change.Status)
+}
+
+func TestServer(t *testing.T) {
+	jobs, workers, st := &fakeJobs{}, &fakeDispatcher{}, &fakeStore{}
+	s := &Server{
+		logger:     logger.NewSlogLogger(),
+		jobs:       jobs,
+		store:      st,
+		dispatcher: workers,
+		results:    aggregator.NewAggregator(),
+		metrics:    store.NewQueryMetrics(),
+		limiter:    NewRateLimiter(RateLimitConfig{}),
+	}
+	handler := s.Handler()
+	call := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := call(http.MethodPost, "/cycles", `{"name":"nightly","strategy":{"max_rate":5}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, "nightly", jobs.started.Name)
+	assert.Equal(t, 5.0, jobs.started.Strategy.MaxRate)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/cycles", `{"strategy":{"max_rate":-1}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/cycles", `{`).Code)
+	rec = call(http.MethodPost, "/cycles", `{"description":"release check","labels":{"env":"staging","build":"1.4.2"},"seed":42}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, map[string]string{"env": "staging", "build": "1.4.2"}, jobs.started.Labels)
+	assert.Equal(t, "release check", jobs.started.Description)
+	assert.Equal(t, int64(42), jobs.started.Seed)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/cycles", `{"labels":{"":"x"}}`).Code)
+
+	rec = call(http.MethodGet, "/cycles?status=completed,failed_slo&template=smoke&label=env=staging&label=build=1.4.2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var cycles []models.Cycle
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cycles))
+	require.Len(t, cycles, 1)
+	assert.Equal(t, 4, cycles[0].Progress.TotalJobs, "listed cycles report their progress")
+	assert.Equal(t, store.CycleFilter{
+		Statuses: []string{"completed", "failed_slo"},
+		Template: "smoke",
+		Labels:   map[string]string{"env": "staging", "build": "1.4.2"},
+	}, st.filter)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/cycles?label=env", "").Code)
+	assert.Empty(t, st.project, "requests without a project see every project")
+
+	callIn := func(project, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Project", project)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusOK, callIn("team-a", http.MethodGet, "/cycles", "").Code)
+	assert.Equal(t, "team-a", st.project)
+	assert.Equal(t, http.StatusNotFound, callIn("team-b", http.MethodGet, "/cycles", "").Code, "unknown projects are rejected")
+	assert.Equal(t, http.StatusForbidden, callIn("full", http.MethodPost, "/cycles", `{"name":"nightly"}`).Code)
+	rec = call(http.MethodPost, "/projects", `{"id":"team-b","quota":{"max_running_cycles":2}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, 2, st.created.Quota.MaxRunningCycles)
+	assert.Equal(t, http.StatusConflict, call(http.MethodPost, "/projects", `{"id":"team-a"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/projects", `{"id":"a/b"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/projects", `{"id":"team-c","quota":{"max_cycle_jobs":-1}}`).Code)
+
+	rec = call(http.MethodGet, "/cycles/c1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var cycle cycleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cycle))
+	assert.Equal(t, "nightly", cycle.Name)
+	assert.Equal(t, 4, cycle.Progress.TotalJobs)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/cycles/c2", "").Code)
+
+	assert.Equal(t, http.StatusConflict, call(http.MethodDelete, "/cycles/c1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/cycles/c1/compare", "").Code)
+	assert.Equal(t, http.StatusConflict, call(http.MethodGet, "/cycles/c1/compare?baseline=c1", "").Code, "running cycles are not compared")
+
+	rec = call(http.MethodGet, "/cycles/c1/export", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "uuid,cycle_uuid,"), rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "j1,c1,")
+	rec = call(http.MethodGet, "/cycles/c1/export?data=stats&format=parquet", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/vnd.apache.parquet", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "PAR1", rec.Body.String()[:4])
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/cycles/c1/export?format=xlsx", "").Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/cycles/c1/export?data=files", "").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/cycles/c9/export", "").Code)
+
+	rec = call(http.MethodGet, "/cycles/c1/timeseries?from=0&to=120", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var points []timeSeriesPoint
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &points))
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(2), points[0].Jobs)
+	assert.Equal(t, int64(1), points[0].Failed)
+	assert.Equal(t, 30.0, points[0].MeanMs)
+	assert.Equal(t, int64(40), points[0].P95Ms)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/cycles/c1/timeseries?from=yesterday", "").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/cycles/c2/timeseries", "").Code)
+
+	rec = call(http.MethodGet, "/metrics", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "# TYPE robo_store_query_duration_seconds histogram")
+
+	req := httptest.NewRequest(http.MethodPost, "/purge", strings.NewReader(`{"deleted_before":100}`))
+	req.Header.Set("X-API-Key", "secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"cycles":2}`, rec.Body.String())
+	assert.Equal(t, int64(100), st.purged)
+	assert.True(t, strings.HasPrefix(st.creator, "key:"), st.creator)
+	assert.NotContains(t, st.creator, "secret", "the API key is not recorded")
+	require.Equal(t, http.StatusOK, call(http.MethodPost, "/purge", "").Code)
+	assert.Greater(t, st.purged, int64(100), "purging defaults to now")
+	assert.Equal(t, "addr:192.0.2.1", st.creator)
+
+	rec = call(http.MethodGet, "/jobs?status=failed,cancelled&cycle=c1&worker=w1&since=100&sort=-done_at&limit=20", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed store.JobPage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Jobs, 1)
+	assert.Equal(t, "failed", listed.Jobs[0].Status)
+	assert.Equal(t, "next", listed.NextCursor)
+	assert.Equal(t, store.JobFilter{CycleUUID: "c1", WorkerID: "w1", Statuses: []string{"failed", "cancelled"}, Since: 100}, st.jobFilter)
+	assert.Equal(t, store.Page{Sort: "done_at", Desc: true, Limit: 20}, st.page)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/jobs?status=failed&cursor=bad", "").Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/jobs?limit=0", "").Code)
+	assert.Equal(t, http.StatusConflict, call(http.MethodPost, "/jobs/j1/retry", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, call(http.MethodPost, "/workers/w1/drain", "").Code)
+	assert.Equal(t, []string{"w1"}, workers.drained)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodPost, "/workers/w2/drain", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/templates", `{"name":"smoke"}`).Code, "a template needs a strategy")
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/templates", `{"name":"smoke","strategy":{"max_rate":-1}}`).Code)
+}
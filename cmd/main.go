@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"flag"
 
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 
+	"github.com/songvi/robo/aggregator"
+	"github.com/songvi/robo/api"
 	"github.com/songvi/robo/config"
 	"github.com/songvi/robo/dispatcher"
 	"github.com/songvi/robo/generator"
 	"github.com/songvi/robo/job"
+	"github.com/songvi/robo/ledger"
 	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/notify"
 	"github.com/songvi/robo/store"
 )
 
@@ -60,46 +65,25 @@ func (l *CustomFxLogger) LogEvent(event fxevent.Event) {
 }
 
 func main() {
+	configFile := flag.String("config", "", "config file; ROBO_CONFIG or the first of config.SearchPaths by default")
+	profile := flag.String("profile", "", "profile of the config file, e.g. dev, staging or perf; ROBO_PROFILE by default")
+	flag.Parse()
+
 	app := fx.New(
 		fx.WithLogger(func(logger logger.Logger) fxevent.Logger {
 			return &CustomFxLogger{logger: logger}
 		}),
 		logger.ProvideLogger(),
+		fx.Supply(config.File(*configFile), config.Profile(*profile)),
 		config.Module,
 		generator.Module,
 		dispatcher.Module,
 		job.Module,
+		ledger.Module,
 		store.Module,
-		// fx.Invoke(func(d dispatcher.Dispatcher, logger logger.Logger) {
-		// 	ctx := context.Background()
-		// 	logger.Info(ctx, "Invoking Dispatcher lifecycle")
-
-		// 	go func() {
-		// 		time.Sleep(5 * time.Second) // Wait for worker to register
-		// 		job := &models.Job{
-		// 			UUID:      uuid.New().String(),
-		// 			Name:      "test-job",
-		// 			InputData: json.RawMessage(`{"task":"process_file"}`),
-		// 			Status:    "pending",
-		// 		}
-		// 		// Retry up to 3 times
-		// 		for attempt := 1; attempt <= 5; attempt++ {
-		// 			workers := d.GetActiveWorkers()
-		// 			logger.Debug(ctx, "Attempting to dispatch job", "job_uuid", job.UUID, "attempt", attempt, "active_workers", len(workers))
-		// 			if err := d.DispatchJob(ctx, job); err != nil {
-		// 				logger.Error(ctx, "Failed to dispatch test job", "job_uuid", job.UUID, "attempt", attempt, "error", err)
-		// 				if attempt < 5 {
-		// 					time.Sleep(2 * time.Second)
-		// 					continue
-		// 				}
-		// 			} else {
-		// 				logger.Info(ctx, "Test job dispatched successfully", "job_uuid", job.UUID)
-		// 				break
-		// 			}
-		// 		}
-		// 	}()
-
-		// }),
+		aggregator.Module,
+		notify.Module,
+		api.Module,
 		fx.Invoke(func(lc fx.Lifecycle, logger logger.Logger) {
 			lc.Append(fx.Hook{
 				OnStart: func(ctx context.Context) error {
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/songvi/robo/models"
+)
+
+func newWorkerCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "List and drain workers",
+	}
+	cmd.AddCommand(
+		listCommand("list", "List the active workers", opts, "/workers"),
+		idCommand("drain ID", "Stop dispatching jobs to a worker", opts, completeIDs(opts, "/workers", "uuid"), func(c *client, id string) error {
+			return c.do("POST", "/workers/"+id+"/drain", nil, nil)
+		}),
+	)
+	return cmd
+}
+
+func newJobCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "job",
+		Short: "List, inspect and retry jobs",
+	}
+	cmd.AddCommand(
+		newJobListCommand(opts),
+		idCommand("inspect ID", "Show a job", opts, nil, func(c *client, id string) error {
+			return get(c, "/jobs/"+id)
+		}),
+		idCommand("retry ID", "Dispatch a failed or cancelled job again", opts, nil, func(c *client, id string) error {
+			return c.do("POST", "/jobs/"+id+"/retry", nil, nil)
+		}),
+	)
+	return cmd
+}
+
+func newJobListCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List a page of jobs",
+		Long: `List a page of jobs started from --since to --until (Unix seconds), sorted by
+start_at, done_at, duration_ms or dispatched_at; --cursor, set to the next_cursor of a
+page, lists the next one.`,
+		Args: cobra.NoArgs,
+	}
+	flags := cmd.Flags()
+	params := map[string]*string{
+		"status":  flags.String("status", "", "job statuses, comma-separated"),
+		"cycle":   flags.String("cycle", "", "cycle UUID"),
+		"session": flags.String("session", "", "session UUID"),
+		"worker":  flags.String("worker", "", "worker UUID"),
+		"since":   flags.String("since", "", "earliest start, in Unix seconds"),
+		"until":   flags.String("until", "", "latest start (excluded), in Unix seconds"),
+		"sort":    flags.String("sort", "", "column to sort by, descending with a - prefix"),
+		"limit":   flags.String("limit", "", "jobs per page"),
+		"cursor":  flags.String("cursor", "", "next_cursor of the previous page"),
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		query := url.Values{}
+		for name, v := range params {
+			if *v != "" {
+				query.Set(name, *v)
+			}
+		}
+		return get(opts.client(), "/jobs?"+query.Encode())
+	}
+	cmd.RegisterFlagCompletionFunc("status", completeValues(
+		"pending", "dispatched", "processing", "completed", "failed", "cancelled"))
+	cmd.RegisterFlagCompletionFunc("cycle", completeIDs(opts, "/cycles", "uuid"))
+	cmd.RegisterFlagCompletionFunc("worker", completeIDs(opts, "/workers", "uuid"))
+	cmd.RegisterFlagCompletionFunc("sort", completeValues(
+		"start_at", "done_at", "duration_ms", "dispatched_at"))
+	return cmd
+}
+
+func newTemplateCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Manage the cycle templates",
+	}
+	templateName := completeIDs(opts, "/templates", "name")
+	cmd.AddCommand(
+		listCommand("list", "List the cycle templates", opts, "/templates"),
+		idCommand("show NAME", "Show a cycle template", opts, templateName, func(c *client, name string) error {
+			return get(c, "/templates/"+name)
+		}),
+		&cobra.Command{
+			Use:               "save FILE",
+			Short:             "Create or replace a cycle template, JSON or YAML",
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeFiles,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return templateSave(opts.client(), args[0])
+			},
+		},
+		idCommand("delete NAME", "Delete a cycle template", opts, templateName, func(c *client, name string) error {
+			return c.do("DELETE", "/templates/"+name, nil, nil)
+		}),
+	)
+	return cmd
+}
+
+// templateSave updates the template of a file, or creates it when there is none
+func templateSave(c *client, path string) error {
+	var template models.CycleTemplate
+	if err := readFile(path, &template); err != nil {
+		return err
+	}
+	if template.Name == "" {
+		return fmt.Errorf("%s has no template name", path)
+	}
+	var saved models.CycleTemplate
+	err := c.do("PUT", "/templates/"+url.PathEscape(template.Name), template, &saved)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		err = c.do("POST", "/templates", template, &saved)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(saved)
+}
+
+func newProjectCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "project",
+		Short: "Manage the projects and their quotas",
+	}
+	projectID := completeIDs(opts, "/projects", "id")
+	cmd.AddCommand(
+		listCommand("list", "List the projects", opts, "/projects"),
+		idCommand("show ID", "Show a project with its quota", opts, projectID, func(c *client, id string) error {
+			return get(c, "/projects/"+id)
+		}),
+		&cobra.Command{
+			Use:               "save FILE",
+			Short:             "Create or replace a project, JSON or YAML",
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeFiles,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return projectSave(opts.client(), args[0])
+			},
+		},
+		idCommand("delete ID", "Delete a project without cycles", opts, projectID, func(c *client, id string) error {
+			return c.do("DELETE", "/projects/"+id, nil, nil)
+		}),
+	)
+	return cmd
+}
+
+// projectSave updates the project of a file, or creates it when there is none
+func projectSave(c *client, path string) error {
+	var project models.Project
+	if err := readFile(path, &project); err != nil {
+		return err
+	}
+	if project.ID == "" {
+		return fmt.Errorf("%s has no project ID", path)
+	}
+	var saved models.Project
+	err := c.do("PUT", "/projects/"+url.PathEscape(project.ID), project, &saved)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		err = c.do("POST", "/projects", project, &saved)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(saved)
+}
+
+func newDataCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "data",
+		Short: "Maintain the data of the control plane",
+	}
+	var before int64
+	purge := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete for good the rows soft deleted before --before",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var purged map[string]int64
+			if err := opts.client().do("POST", "/purge", map[string]int64{"deleted_before": before}, &purged); err != nil {
+				return err
+			}
+			return printJSON(purged)
+		},
+	}
+	purge.Flags().Int64Var(&before, "before", 0, "purge the rows soft deleted before this Unix time; now when unset")
+	cmd.AddCommand(purge)
+	return cmd
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// client calls the admin API of the robo control plane
+type client struct {
+	addr    string
+	apiKey  string
+	project string // Sent as X-Project when set
+	http    *http.Client
+}
+
+func newClient(addr, apiKey string) *client {
+	return &client{
+		addr:   strings.TrimRight(addr, "/"),
+		apiKey: apiKey,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError is an error response of the admin API
+type apiError struct {
+	method, path string
+	status       int
+	message      string
+}
+
+func (e *apiError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.method, e.path, e.status, http.StatusText(e.status))
+	if e.message != "" {
+		msg += ": " + e.message
+	}
+	return msg
+}
+
+// do sends a request with body encoded as JSON and decodes the response into out, or
+// copies it to out when out is an io.Writer; error responses are returned as *apiError
+func (c *client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.addr+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.project != "" {
+		req.Header.Set("X-Project", c.project)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return &apiError{method: method, path: path, status: resp.StatusCode, message: body.Error}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if w, ok := out.(io.Writer); ok {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
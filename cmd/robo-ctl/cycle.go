@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/songvi/robo/models"
+)
+
+func newCycleCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cycle",
+		Short: "Start, follow and compare cycles",
+	}
+	cycleID := completeIDs(opts, "/cycles", "uuid")
+	cmd.AddCommand(
+		newCycleStartCommand(opts),
+		newCycleValidateCommand(opts),
+		newCycleListCommand(opts),
+		idCommand("stop ID", "Abort a running or paused cycle", opts, cycleID, func(c *client, id string) error {
+			return c.do("DELETE", "/cycles/"+id, nil, nil)
+		}),
+		idCommand("pause ID", "Pause a running cycle", opts, cycleID, func(c *client, id string) error {
+			return c.do("POST", "/cycles/"+id+"/pause", nil, nil)
+		}),
+		idCommand("resume ID", "Resume a paused cycle", opts, cycleID, func(c *client, id string) error {
+			return c.do("POST", "/cycles/"+id+"/resume", nil, nil)
+		}),
+		idCommand("status ID", "Show a cycle with its progress and statistics", opts, cycleID, func(c *client, id string) error {
+			return get(c, "/cycles/"+id)
+		}),
+		newCycleCompareCommand(opts, cycleID),
+		newCycleExportCommand(opts, cycleID),
+	)
+	return cmd
+}
+
+func newCycleStartCommand(opts *options) *cobra.Command {
+	var (
+		name, strategyFile, template, overrides, description string
+		seed                                                 int64
+		labels                                               map[string]string
+	)
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start a cycle, with the configured strategy by default",
+		Long: `Start a cycle with the strategy of --strategy, the configured one by default, or
+from the template --template names, merging --overrides into its strategy.
+
+--seed replays the data of an earlier cycle.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := opts.client()
+			var cycle models.Cycle
+			if template != "" {
+				req := struct {
+					Name        string            `json:"name"`
+					Description string            `json:"description,omitempty"`
+					Labels      map[string]string `json:"labels,omitempty"`
+					Seed        int64             `json:"seed,omitempty"`
+					Overrides   json.RawMessage   `json:"overrides,omitempty"`
+				}{Name: name, Description: description, Labels: labels, Seed: seed}
+				if overrides != "" {
+					if !json.Valid([]byte(overrides)) {
+						return fmt.Errorf("overrides are not valid JSON")
+					}
+					req.Overrides = json.RawMessage(overrides)
+				}
+				if err := c.do("POST", "/templates/"+url.PathEscape(template)+"/cycles", req, &cycle); err != nil {
+					return err
+				}
+				return printJSON(cycle)
+			}
+
+			req := struct {
+				Name        string            `json:"name"`
+				Description string            `json:"description,omitempty"`
+				Labels      map[string]string `json:"labels,omitempty"`
+				Seed        int64             `json:"seed,omitempty"`
+				Strategy    *models.Strategy  `json:"strategy,omitempty"`
+			}{Name: name, Description: description, Labels: labels, Seed: seed}
+			if strategyFile != "" {
+				req.Strategy = &models.Strategy{}
+				if err := readFile(strategyFile, req.Strategy); err != nil {
+					return err
+				}
+			}
+			if err := c.do("POST", "/cycles", req, &cycle); err != nil {
+				return err
+			}
+			return printJSON(cycle)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&name, "name", "", "cycle name")
+	flags.StringVar(&strategyFile, "strategy", "", "strategy file, JSON or YAML")
+	flags.StringVar(&template, "template", "", "template to start the cycle from")
+	flags.StringVar(&overrides, "overrides", "", "JSON merge patch of the template strategy")
+	flags.StringVar(&description, "description", "", "what the cycle tests")
+	flags.Int64Var(&seed, "seed", 0, "seed of the generated data, random when zero")
+	flags.StringToStringVar(&labels, "label", nil, "KEY=VALUE label of the cycle, repeatable")
+	cmd.MarkFlagFilename("strategy", "json", "yaml", "yml")
+	cmd.MarkFlagsMutuallyExclusive("strategy", "template")
+	cmd.RegisterFlagCompletionFunc("template", completeIDs(opts, "/templates", "name"))
+	return cmd
+}
+
+// newCycleValidateCommand prints the plan of a cycle and fails when it has problems
+func newCycleValidateCommand(opts *options) *cobra.Command {
+	var strategyFile string
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Plan a cycle without starting it",
+		Long: `Plan a cycle without starting it and print the plan; robo-ctl exits with status 1
+when the plan has problems.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := struct {
+				Strategy *models.Strategy `json:"strategy,omitempty"`
+			}{}
+			if strategyFile != "" {
+				req.Strategy = &models.Strategy{}
+				if err := readFile(strategyFile, req.Strategy); err != nil {
+					return err
+				}
+			}
+			var plan models.CyclePlan
+			if err := opts.client().do("POST", "/cycles/validate", req, &plan); err != nil {
+				return err
+			}
+			if err := printJSON(plan); err != nil {
+				return err
+			}
+			if len(plan.Problems) > 0 {
+				return fmt.Errorf("the cycle has %d problems", len(plan.Problems))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&strategyFile, "strategy", "", "strategy file, JSON or YAML; the configured one by default")
+	cmd.MarkFlagFilename("strategy", "json", "yaml", "yml")
+	return cmd
+}
+
+func newCycleListCommand(opts *options) *cobra.Command {
+	var (
+		statuses []string
+		template string
+		labels   map[string]string
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cycles, most recent first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			if len(statuses) > 0 {
+				query.Set("status", strings.Join(statuses, ","))
+			}
+			if template != "" {
+				query.Set("template", template)
+			}
+			for k, v := range labels {
+				query.Add("label", k+"="+v)
+			}
+			return get(opts.client(), "/cycles?"+query.Encode())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringSliceVar(&statuses, "status", nil, "cycle statuses, comma-separated")
+	flags.StringVar(&template, "template", "", "template the cycles started from")
+	flags.StringToStringVar(&labels, "label", nil, "KEY=VALUE label the cycles have, repeatable")
+	cmd.RegisterFlagCompletionFunc("status", completeValues(
+		"running", "paused", "completed", "aborted", "expired", "failed_slo", "preflight_failed"))
+	cmd.RegisterFlagCompletionFunc("template", completeIDs(opts, "/templates", "name"))
+	return cmd
+}
+
+// newCycleCompareCommand prints the comparison of a cycle with a baseline and fails when
+// the cycle regressed, so it can gate a CI pipeline
+func newCycleCompareCommand(opts *options, cycleID cobra.CompletionFunc) *cobra.Command {
+	var (
+		baseline                  string
+		maxLatency, maxThroughput float64
+	)
+	cmd := &cobra.Command{
+		Use:   "compare --baseline ID ID",
+		Short: "Compare a cycle with a baseline of its template",
+		Long: `Compare a cycle with a baseline of its template; robo-ctl exits with status 1 on a
+regression, so it can gate a CI pipeline.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cycleID,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{
+				"baseline":                  {baseline},
+				"max_latency_regression":    {strconv.FormatFloat(maxLatency, 'f', -1, 64)},
+				"max_throughput_regression": {strconv.FormatFloat(maxThroughput, 'f', -1, 64)},
+			}
+			var comparison models.CycleComparison
+			if err := opts.client().do("GET", "/cycles/"+url.PathEscape(args[0])+"/compare?"+query.Encode(), nil, &comparison); err != nil {
+				return err
+			}
+			if err := printJSON(comparison); err != nil {
+				return err
+			}
+			if comparison.Regressed {
+				return fmt.Errorf("cycle %s regressed against %s", comparison.Candidate, comparison.Baseline)
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&baseline, "baseline", "", "baseline cycle UUID")
+	flags.Float64Var(&maxLatency, "max-latency", 10, "tolerated p95 latency growth, in percent")
+	flags.Float64Var(&maxThroughput, "max-throughput", 10, "tolerated throughput drop, in percent")
+	cmd.MarkFlagRequired("baseline")
+	cmd.RegisterFlagCompletionFunc("baseline", cycleID)
+	return cmd
+}
+
+func newCycleExportCommand(opts *options, cycleID cobra.CompletionFunc) *cobra.Command {
+	var format, data string
+	cmd := &cobra.Command{
+		Use:               "export ID",
+		Short:             "Write the jobs or the per-action statistics of a cycle to stdout",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cycleID,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{"format": {format}, "data": {data}}
+			return opts.client().do("GET", "/cycles/"+url.PathEscape(args[0])+"/export?"+query.Encode(), nil, os.Stdout)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", "csv", "csv or parquet")
+	flags.StringVar(&data, "data", "jobs", "jobs or stats")
+	cmd.RegisterFlagCompletionFunc("format", completeValues("csv", "parquet"))
+	cmd.RegisterFlagCompletionFunc("data", completeValues("jobs", "stats"))
+	return cmd
+}
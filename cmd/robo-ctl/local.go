@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/generator"
+	"github.com/songvi/robo/store"
+)
+
+// configFlags are the flags of the local commands that load a config file
+type configFlags struct {
+	file    string
+	profile string
+}
+
+func addConfigFlags(cmd *cobra.Command) *configFlags {
+	f := &configFlags{}
+	cmd.Flags().StringVar(&f.file, "config", "", "config file; searched for by default")
+	cmd.Flags().StringVar(&f.profile, "profile", "", "profile of the config file; ROBO_PROFILE by default")
+	cmd.MarkFlagFilename("config", "json", "yaml", "yml")
+	return f
+}
+
+func (f *configFlags) load() (config.Config, error) {
+	return config.Load(f.file, f.profile)
+}
+
+// openDatabase opens the database of the config file
+func (f *configFlags) openDatabase() (*gorm.DB, error) {
+	cfg, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return config.OpenDatabase(cfg.Driver, cfg.DSN)
+}
+
+func newDatasetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dataset",
+		Short: "Generate test data locally",
+	}
+	var users, files int
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate users and files as JSON lines",
+		Long: `Generate users and files with the generator config of a config file and write them
+to stdout, one JSON object per line.`,
+		Args: cobra.NoArgs,
+	}
+	cfgFlags := addConfigFlags(generate)
+	generate.Flags().IntVar(&users, "users", 10, "number of users")
+	generate.Flags().IntVar(&files, "files", 0, "number of files")
+	generate.RunE = func(cmd *cobra.Command, args []string) error {
+		return datasetGenerate(cfgFlags, users, files)
+	}
+	cmd.AddCommand(generate)
+	return cmd
+}
+
+// datasetGenerate generates users and files with the generator config of a config
+// file and writes them to stdout, one JSON object per line
+func datasetGenerate(cfgFlags *configFlags, users, files int) error {
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+
+	var gen generator.Generator
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(cfg.Generator),
+		generator.Module,
+		fx.Populate(&gen),
+	)
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+	defer app.Stop(ctx)
+
+	enc := json.NewEncoder(os.Stdout)
+	generated, err := gen.GenerateUsers(ctx, users)
+	if err != nil {
+		return err
+	}
+	for _, u := range generated {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	if files == 0 {
+		return nil
+	}
+	generatedFiles, err := gen.GenerateFiles(ctx, files)
+	if err != nil {
+		return err
+	}
+	for _, f := range generatedFiles {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Write and validate config files",
+	}
+	cmd.AddCommand(newConfigInitCommand(), newConfigValidateCommand())
+	return cmd
+}
+
+// newConfigInitCommand writes the example config to a file, which it does not
+// overwrite unless --force is set
+func newConfigInitCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "init [FILE]",
+		Short: "Write an example config with every setting documented, to config.yaml by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "config.yaml"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+			if force {
+				mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			}
+			file, err := os.OpenFile(path, mode, 0o644)
+			if errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("%s exists; set --force to overwrite it", path)
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := file.Write(config.Sample); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+			fmt.Printf("wrote %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing file")
+	return cmd
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	var profile string
+	cmd := &cobra.Command{
+		Use:               "validate [FILE]",
+		Short:             "Validate a config file with the environment overrides, the one the servers find by default",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var file string
+			if len(args) > 0 {
+				file = args[0]
+			}
+			path, err := config.FindConfig(file)
+			if err != nil {
+				return err
+			}
+			cfg, err := config.Load(path, profile)
+			if err != nil {
+				return err
+			}
+			if cfg.Version < config.CurrentVersion {
+				fmt.Printf("%s has the layout of version %d, upgraded to %d as it is loaded\n", path, cfg.Version, config.CurrentVersion)
+			}
+			if profile != "" {
+				fmt.Printf("%s is valid with profile %s\n", path, profile)
+				return nil
+			}
+			fmt.Printf("%s is valid\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&profile, "profile", "", "profile of the config file; ROBO_PROFILE by default")
+	return cmd
+}
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the database of a config file",
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "List the database migrations and whether they are applied",
+		Args:  cobra.NoArgs,
+	}
+	statusFlags := addConfigFlags(status)
+	status.RunE = func(cmd *cobra.Command, args []string) error {
+		db, err := statusFlags.openDatabase()
+		if err != nil {
+			return err
+		}
+		migrations, err := store.Migrations(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			state := "pending"
+			if m.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %s\n", state, m.ID)
+		}
+		return nil
+	}
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the migrations the database is missing",
+		Args:  cobra.NoArgs,
+	}
+	upFlags := addConfigFlags(up)
+	up.RunE = func(cmd *cobra.Command, args []string) error {
+		db, err := upFlags.openDatabase()
+		if err != nil {
+			return err
+		}
+		if err := store.Migrate(db); err != nil {
+			return err
+		}
+		fmt.Println("the database is up to date")
+		return nil
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the last applied migrations, one by default",
+		Args:  cobra.NoArgs,
+	}
+	downFlags := addConfigFlags(down)
+	down.Flags().IntVar(&steps, "steps", 1, "number of migrations to revert")
+	down.RunE = func(cmd *cobra.Command, args []string) error {
+		if steps < 1 {
+			return fmt.Errorf("--steps must be at least 1")
+		}
+		db, err := downFlags.openDatabase()
+		if err != nil {
+			return err
+		}
+		reverted, err := store.RollbackMigrations(db, steps)
+		for _, id := range reverted {
+			fmt.Printf("reverted %s\n", id)
+		}
+		return err
+	}
+
+	cmd.AddCommand(status, up, down)
+	return cmd
+}
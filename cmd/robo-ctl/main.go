@@ -0,0 +1,169 @@
+// robo-ctl operates a running robo control plane through its admin API, and validates
+// config, generates datasets and migrates the database locally
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// options are the flags of every command, which the admin API commands call it with
+type options struct {
+	addr    string
+	apiKey  string
+	project string
+}
+
+func (o *options) client() *client {
+	c := newClient(o.addr, o.apiKey)
+	c.project = o.project
+	return c
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "robo-ctl:", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:   "robo-ctl",
+		Short: "Operate a robo control plane",
+		Long: `robo-ctl operates a running robo control plane through its admin API, and validates
+config, generates datasets and migrates the database locally.
+
+The local commands read the config file --config names, else the one ROBO_CONFIG names,
+else the first config.json or config.yaml of ., $XDG_CONFIG_HOME/robo and /etc/robo;
+--profile, or ROBO_PROFILE, selects one of its profiles, e.g. dev, staging or perf, whose
+settings replace those of the file; ROBO_BROKER, ROBO_DSN and the other ROBO_* variables
+override them all.`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.addr, "addr", envOr("ROBO_ADDR", "http://localhost:8080"), "admin API address")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("ROBO_API_KEY"), "API key sent as X-API-Key")
+	flags.StringVar(&opts.project, "project", os.Getenv("ROBO_PROJECT"), "project sent as X-Project, scoping the commands to it")
+
+	root.AddCommand(
+		newCycleCommand(opts),
+		newWorkerCommand(opts),
+		newJobCommand(opts),
+		newTemplateCommand(opts),
+		newProjectCommand(opts),
+		newDataCommand(opts),
+		newDatasetCommand(),
+		newConfigCommand(),
+		newMigrateCommand(),
+	)
+	return root
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// idCommand builds a command that takes exactly one ID, escaped for a path, which
+// complete suggests
+func idCommand(use, short string, opts *options, complete cobra.CompletionFunc, run func(c *client, id string) error) *cobra.Command {
+	return &cobra.Command{
+		Use:               use,
+		Short:             short,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: complete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(opts.client(), url.PathEscape(args[0]))
+		},
+	}
+}
+
+// completeIDs completes an argument with the key field of the objects the admin API
+// lists at path
+func completeIDs(opts *options, path, key string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var items []map[string]interface{}
+		if err := opts.client().do("GET", path, nil, &items); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		ids := make([]string, 0, len(items))
+		for _, item := range items {
+			if id, ok := item[key].(string); ok && strings.HasPrefix(id, toComplete) {
+				ids = append(ids, id)
+			}
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// listCommand builds a command without arguments that prints the response of a GET
+func listCommand(use, short string, opts *options, path string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return get(opts.client(), path)
+		},
+	}
+}
+
+// completeValues completes a flag with a fixed set of values
+func completeValues(values ...string) cobra.CompletionFunc {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeFiles completes an argument with the JSON and YAML files
+func completeFiles(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{"json", "yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// get prints the response of a GET request as indented JSON
+func get(c *client, path string) error {
+	var out interface{}
+	if err := c.do("GET", path, nil, &out); err != nil {
+		return err
+	}
+	return printJSON(out)
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// readFile decodes a JSON or YAML file, by extension, into v
+func readFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, v)
+	default:
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/config"
+)
+
+// request is a request the fake admin API received
+type request struct {
+	method, path, query string
+	apiKey, project     string
+	body                map[string]interface{}
+}
+
+// adminAPI is a fake admin API that records its requests and answers with the
+// response of their path, or 404
+type adminAPI struct {
+	*httptest.Server
+	requests  []request
+	responses map[string]interface{}
+}
+
+func newAdminAPI(t *testing.T) *adminAPI {
+	api := &adminAPI{responses: make(map[string]interface{})}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.EscapedPath(), query: r.URL.RawQuery,
+			apiKey: r.Header.Get("X-API-Key"), project: r.Header.Get("X-Project")}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		api.requests = append(api.requests, req)
+		resp, ok := api.responses[r.Method+" "+r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "not found"})
+			return
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// run runs robo-ctl with args and returns what it printed to stdout
+func run(t *testing.T, args ...string) (string, error) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+
+	root := newRootCommand()
+	root.SetArgs(args)
+	err = root.Execute()
+	w.Close()
+	return string(<-out), err
+}
+
+func TestCycleStart(t *testing.T) {
+	api := newAdminAPI(t)
+	api.responses["POST /cycles"] = map[string]string{"uuid": "c1", "name": "nightly"}
+	api.responses["POST /templates/smoke%20test/cycles"] = map[string]string{"uuid": "c2"}
+
+	out, err := run(t, "--addr", api.URL, "--api-key", "key", "--project", "p1",
+		"cycle", "start", "--name", "nightly", "--seed", "42", "--label", "team=qa")
+	require.NoError(t, err)
+	require.Len(t, api.requests, 1)
+	req := api.requests[0]
+	assert.Equal(t, "POST", req.method)
+	assert.Equal(t, "/cycles", req.path)
+	assert.Equal(t, "key", req.apiKey)
+	assert.Equal(t, "p1", req.project)
+	assert.Equal(t, map[string]interface{}{"name": "nightly", "seed": float64(42),
+		"labels": map[string]interface{}{"team": "qa"}}, req.body)
+	var cycle map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &cycle))
+	assert.Equal(t, "c1", cycle["uuid"])
+
+	strategy := filepath.Join(t.TempDir(), "strategy.yaml")
+	require.NoError(t, os.WriteFile(strategy, []byte("max_users: 3\n"), 0o644))
+	_, err = run(t, "--addr", api.URL, "cycle", "start", "--strategy", strategy)
+	require.NoError(t, err)
+	require.Contains(t, api.requests[1].body, "strategy", "the strategy file is sent")
+	assert.Equal(t, float64(3), api.requests[1].body["strategy"].(map[string]interface{})["max_users"])
+
+	_, err = run(t, "--addr", api.URL, "cycle", "start", "--template", "smoke test", "--overrides", `{"max_users":2}`)
+	require.NoError(t, err)
+	assert.Equal(t, "/templates/smoke%20test/cycles", api.requests[2].path)
+	assert.Equal(t, map[string]interface{}{"max_users": float64(2)}, api.requests[2].body["overrides"])
+
+	_, err = run(t, "--addr", api.URL, "cycle", "start", "--template", "smoke", "--overrides", "{")
+	assert.EqualError(t, err, "overrides are not valid JSON")
+	_, err = run(t, "--addr", api.URL, "cycle", "start", "--template", "smoke", "--strategy", strategy)
+	assert.Error(t, err, "a template and a strategy are exclusive")
+	assert.Len(t, api.requests, 3)
+}
+
+func TestCycleList(t *testing.T) {
+	api := newAdminAPI(t)
+	api.responses["GET /cycles"] = []map[string]string{{"uuid": "c1"}, {"uuid": "c2"}}
+
+	out, err := run(t, "--addr", api.URL, "cycle", "list", "--status", "running,paused", "--template", "nightly")
+	require.NoError(t, err)
+	require.Len(t, api.requests, 1)
+	assert.Equal(t, "GET", api.requests[0].method)
+	assert.Equal(t, "status=running%2Cpaused&template=nightly", api.requests[0].query)
+	var cycles []map[string]string
+	require.NoError(t, json.Unmarshal([]byte(out), &cycles))
+	assert.Len(t, cycles, 2)
+}
+
+func TestWorkerDrain(t *testing.T) {
+	api := newAdminAPI(t)
+	api.responses["POST /workers/w1/drain"] = nil
+
+	_, err := run(t, "--addr", api.URL, "worker", "drain", "w1")
+	require.NoError(t, err)
+	require.Len(t, api.requests, 1)
+	assert.Equal(t, "POST", api.requests[0].method)
+	assert.Equal(t, "/workers/w1/drain", api.requests[0].path)
+
+	_, err = run(t, "--addr", api.URL, "worker", "drain", "w2")
+	assert.EqualError(t, err, "POST /workers/w2/drain: 404 Not Found: not found")
+	_, err = run(t, "--addr", api.URL, "worker", "drain")
+	assert.Error(t, err, "the worker is required")
+}
+
+func TestJobRetry(t *testing.T) {
+	api := newAdminAPI(t)
+	api.responses["POST /jobs/j1/retry"] = nil
+
+	_, err := run(t, "--addr", api.URL, "job", "retry", "j1")
+	require.NoError(t, err)
+	require.Len(t, api.requests, 1)
+	assert.Equal(t, "POST", api.requests[0].method)
+	assert.Equal(t, "/jobs/j1/retry", api.requests[0].path)
+
+	_, err = run(t, "--addr", api.URL, "job", "retry", "a/b")
+	assert.Error(t, err)
+	assert.Equal(t, "/jobs/a%2Fb/retry", api.requests[1].path, "the ID is escaped")
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, config.Sample, 0o644))
+
+	out, err := run(t, "config", "validate", path)
+	require.NoError(t, err)
+	assert.Equal(t, path+" is valid\n", out)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("driver: [\n"), 0o644))
+	_, err = run(t, "config", "validate", invalid)
+	assert.Error(t, err)
+
+	_, err = run(t, "config", "validate", filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
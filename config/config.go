@@ -1,24 +1,119 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"go.uber.org/fx"
-	"gorm.io/driver/sqlite"
+	"gopkg.in/yaml.v3"
 	"gorm.io/gorm"
 
 	"github.com/songvi/robo/generator"
 	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
 )
 
 // Config defines the application configuration
 type Config struct {
-	Broker      string                    `json:"broker"`
-	Generator   generator.GeneratorConfig `json:"generator"`
-	DSN         string                    `json:"dsn"`
-	JobStrategy map[string]interface{}    `json:"job_strategy"`
+	// Version is the layout of the file, 1 when missing; older layouts are upgraded to
+	// CurrentVersion as they are loaded
+	Version int `json:"version" yaml:"version"`
+	// Broker is the URL of the NATS server, or the comma-separated URLs of a cluster;
+	// defaults to nats://localhost:4222
+	Broker    string                    `json:"broker" yaml:"broker"`
+	Generator generator.GeneratorConfig `json:"generator" yaml:"generator"`
+	// Driver of the database at DSN: "sqlite" (default), "postgres" or "mysql"; sqlite
+	// serializes writes, so fleets of workers call for one of the others
+	Driver      string                 `json:"driver" yaml:"driver"`
+	DSN         string                 `json:"dsn" yaml:"dsn"` // Required but with sqlite, which defaults to robo.db
+	Store       store.StoreConfig      `json:"store" yaml:"store"`
+	JobStrategy map[string]interface{} `json:"job_strategy" yaml:"job_strategy"`
+	Schedules   []models.CycleSchedule `json:"schedules" yaml:"schedules"` // Cycles started unattended
+	// Recovery decides what happens at startup to the jobs left in flight by unfinished
+	// cycles: "requeue" (default) dispatches them again, "expire" fails them
+	Recovery string       `json:"recovery" yaml:"recovery"`
+	Upload   UploadConfig `json:"upload" yaml:"upload"`
+	Ledger   LedgerConfig `json:"ledger" yaml:"ledger"`
+	Worker   WorkerConfig `json:"worker" yaml:"worker"`
+	Remote   RemoteConfig `json:"remote" yaml:"remote"`
+	Notify   NotifyConfig `json:"notify" yaml:"notify"`
+	API      APIConfig    `json:"api" yaml:"api"`
+	// Log replaces, once the config is loaded, the log settings ROBO_LOG_LEVEL,
+	// ROBO_LOG_FORMAT and ROBO_LOG_OUTPUT select until then
+	Log logger.Config `json:"log" yaml:"log"`
+}
+
+// APIConfig defines the control-plane HTTP API
+type APIConfig struct {
+	Addr      string          `json:"addr" yaml:"addr"`           // Listen address; defaults to :8080
+	GRPCAddr  string          `json:"grpc_addr" yaml:"grpc_addr"` // Listen address of the gRPC API; empty disables it
+	RateLimit RateLimitConfig `json:"rate_limit" yaml:"rate_limit"`
+}
+
+// RateLimitConfig defines per-tenant limits applied to the control-plane API
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requests_per_second" yaml:"requests_per_second"` // Token refill rate; zero disables rate limiting
+	Burst             int     `json:"burst" yaml:"burst"`                             // Bucket capacity
+	MaxConcurrent     int     `json:"max_concurrent" yaml:"max_concurrent"`           // In-flight requests per tenant; zero means unlimited
+	// CyclesPerMinute caps the cycles each tenant starts, on top of its requests; zero
+	// means no cap
+	CyclesPerMinute float64 `json:"cycles_per_minute" yaml:"cycles_per_minute"`
+	CycleBurst      int     `json:"cycle_burst" yaml:"cycle_burst"` // Cycles started at once; defaults to CyclesPerMinute
+	// Tenants maps the API keys callers send in X-API-Key to the tenant they are limited
+	// as. Callers without a key listed here are limited by address, so rotating keys
+	// does not get around the limits.
+	Tenants map[string]string `json:"tenants" yaml:"tenants"`
+}
+
+// NotifyConfig selects where cycle and fleet events are sent
+type NotifyConfig struct {
+	Targets []NotifyTarget `json:"targets" yaml:"targets"`
+	// MinWorkers is the fleet size below which workers_degraded fires; zero fires on
+	// every worker lost
+	MinWorkers int `json:"min_workers" yaml:"min_workers"`
+}
+
+// NotifyTarget is one destination of events
+type NotifyTarget struct {
+	Type string `json:"type" yaml:"type"` // "webhook", "slack" or "email"
+	// Events are the event types sent; empty sends all but job_failed and
+	// cycle_status_changed, which are sent only when listed
+	Events []string `json:"events" yaml:"events"`
+	// Template is a text/template over the event for the webhook body, the Slack text
+	// or the email body; empty sends the event as JSON or a one-line summary
+	Template string            `json:"template" yaml:"template"`
+	URL      string            `json:"url" yaml:"url"`         // Webhook or Slack incoming webhook URL
+	Headers  map[string]string `json:"headers" yaml:"headers"` // Extra webhook headers, e.g. authorization
+	SMTPAddr string            `json:"smtp_addr" yaml:"smtp_addr"`
+	From     string            `json:"from" yaml:"from"`
+	To       []string          `json:"to" yaml:"to"`
+}
+
+// WorkerConfig describes a worker to the dispatcher
+type WorkerConfig struct {
+	Labels map[string]string `json:"labels" yaml:"labels"` // Pools the worker belongs to, matched by Strategy.WorkerLabels
+}
+
+// LedgerConfig selects where completed cycle summaries are recorded
+type LedgerConfig struct {
+	Type          string `json:"type" yaml:"type"`                     // "file", "webhook", or empty to disable
+	Path          string `json:"path" yaml:"path"`                     // JSONL file for the file ledger, e.g. on shared storage
+	URL           string `json:"url" yaml:"url"`                       // Endpoint for the webhook ledger
+	Deployment    string `json:"deployment" yaml:"deployment"`         // Name of this robo instance in the ledger
+	TargetVersion string `json:"target_version" yaml:"target_version"` // Version of the system under test
+}
+
+// UploadConfig defines how workers upload files to the target
+type UploadConfig struct {
+	Endpoint  string `json:"endpoint" yaml:"endpoint"`     // tus creation endpoint on the target; empty disables real uploads
+	ChunkSize int    `json:"chunk_size" yaml:"chunk_size"` // Bytes per PATCH request
+	// InterruptProbability is the chance each chunk is deliberately cut short and resumed
+	InterruptProbability float64 `json:"interrupt_probability" yaml:"interrupt_probability"`
 }
 
 // ConfigService defines the interface for configuration management
@@ -36,42 +131,145 @@ func (c *configServiceImpl) GetConfig() Config {
 	return c.config
 }
 
+// File is the config file named on the command line; empty looks for one as FindConfig
+// does
+type File string
+
+type configServiceParams struct {
+	fx.In
+	Logger  logger.Logger
+	File    File    `optional:"true"`
+	Profile Profile `optional:"true"`
+}
+
 // NewConfigService creates a new ConfigService instance
-func NewConfigService(logger logger.Logger) (ConfigService, error) {
+func NewConfigService(p configServiceParams) (ConfigService, error) {
 	ctx := context.Background()
-	logger.Debug(ctx, "Loading config from config.json")
-	file, err := os.Open("config.json")
+	path, err := FindConfig(string(p.File))
 	if err != nil {
-		logger.Error(ctx, "Failed to open config.json", "error", err)
+		p.Logger.Error(ctx, "Failed to find a config file", "error", err)
 		return nil, err
 	}
-	defer file.Close()
-
-	var config Config
-	if err := json.NewDecoder(file).Decode(&config); err != nil {
-		logger.Error(ctx, "Failed to decode config.json", "error", err)
+	p.Logger.Debug(ctx, "Loading config", "path", path, "profile", p.Profile)
+	config, err := Load(path, string(p.Profile))
+	if err != nil {
+		p.Logger.Error(ctx, "Failed to load config", "path", path, "profile", p.Profile, "error", err)
 		return nil, err
 	}
 
-	logger.Info(ctx, "Config loaded successfully", "broker", config.Broker)
+	if config.Version < CurrentVersion {
+		p.Logger.Info(ctx, "Config file has an older layout, upgraded at load", "path", path, "version", config.Version, "current_version", CurrentVersion)
+	}
+	p.Logger.Info(ctx, "Config loaded successfully", "path", path, "profile", p.Profile, "broker", config.Broker)
 	return &configServiceImpl{config: config}, nil
 }
 
+// Load reads the config file at path, or the one FindConfig finds when path is empty,
+// with the settings of profile, or of the one ROBO_PROFILE names when empty. It then
+// applies the environment overrides, validates the result and applies the defaults.
+func Load(path, profile string) (Config, error) {
+	path, err := FindConfig(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	config, err := decodeConfig(path, profile)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := ApplyEnv(&config); err != nil {
+		return Config{}, err
+	}
+	if err := config.validate(path); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// LoadConfig reads and validates the config file at path and applies the defaults,
+// without profile nor environment overrides
+func LoadConfig(path string) (Config, error) {
+	config, err := decodeConfig(path, "")
+	if err != nil {
+		return Config{}, err
+	}
+	if err := config.validate(path); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// decodeConfig reads the config file at path, YAML when its extension is .yaml or .yml
+// and JSON otherwise, with the settings of profile when set
+func decodeConfig(path, profile string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	unmarshal, marshal := json.Unmarshal, json.Marshal
+	// Numbers stay as written until the config is decoded
+	unmarshalRaw := func(data []byte, v interface{}) error {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		return dec.Decode(v)
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		unmarshal, unmarshalRaw, marshal = yaml.Unmarshal, yaml.Unmarshal, yaml.Marshal
+	}
+
+	// Profiles are merged in the layout of the file, before it is decoded
+	var raw map[string]interface{}
+	if err := unmarshalRaw(data, &raw); err != nil {
+		return Config{}, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	if err := upgradeSettings(raw); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	if raw, err = applyProfile(raw, profile); err != nil {
+		return Config{}, fmt.Errorf("invalid profile in %s: %v", path, err)
+	}
+	if data, err = marshal(raw); err != nil {
+		return Config{}, fmt.Errorf("failed to encode %s: %v", path, err)
+	}
+	var config Config
+	if err := unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// validate validates the config read from path and applies the defaults
+func (c *Config) validate(path string) error {
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid config %s:\n%w", path, err)
+	}
+	c.ApplyDefaults()
+	return nil
+}
+
 func NewGeneratorConfig(cfg ConfigService, logger logger.Logger) (generator.GeneratorConfig, error) {
 	return cfg.GetConfig().Generator, nil
 }
 
+// NewStoreConfig returns the store section of the configuration
+func NewStoreConfig(cfg ConfigService) store.StoreConfig {
+	return cfg.GetConfig().Store
+}
+
 // Module defines the Fx module for ConfigService and GORM DB
 var Module = fx.Module(
 	"config",
 	fx.Provide(NewGeneratorConfig),
+	fx.Provide(NewStoreConfig),
 	fx.Provide(NewConfigService),
 	fx.Provide(func(lc fx.Lifecycle, configSvc ConfigService, logger logger.Logger) (*gorm.DB, error) {
 		ctx := context.Background()
 		cfg := configSvc.GetConfig()
-		db, err := gorm.Open(sqlite.Open(cfg.DSN), &gorm.Config{})
+		db, err := OpenDatabase(cfg.Driver, cfg.DSN)
 		if err != nil {
-			logger.Error(ctx, "Failed to open GORM database connection", "dsn", cfg.DSN, "error", err)
+			logger.Error(ctx, "Failed to open GORM database connection", "driver", cfg.Driver, "dsn", cfg.DSN, "error", err)
 			return nil, err
 		}
 
@@ -87,7 +285,14 @@ var Module = fx.Module(
 			},
 		})
 
-		logger.Info(ctx, "GORM database connection established", "dsn", cfg.DSN)
+		logger.Info(ctx, "GORM database connection established", "driver", cfg.Driver, "dsn", cfg.DSN)
 		return db, nil
 	}),
+	fx.Invoke(func(configSvc ConfigService, l logger.Logger) error {
+		if err := logger.Configure(l, configSvc.GetConfig().Log); err != nil {
+			l.Error(context.Background(), "Failed to apply log settings", "error", err)
+			return err
+		}
+		return nil
+	}),
 )
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// OpenDatabase opens the GORM database of driver at dsn: "sqlite" (default), "postgres"
+// or "mysql"
+func OpenDatabase(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case "", "sqlite":
+		dialector = sqlite.Open(dsn)
+	case "postgres":
+		dialector = postgresDialector{postgres.Dialector{Config: &postgres.Config{DSN: dsn}}}
+	case "mysql":
+		dialector = mysqlDialector{*mysql.Open(dsn).(*mysql.Dialector)}
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+	return gorm.Open(dialector, &gorm.Config{})
+}
+
+// postgresDialector stores UUID columns as varchar: the models leave the UUIDs of unset
+// references empty, which the uuid type rejects
+type postgresDialector struct {
+	postgres.Dialector
+}
+
+func (d postgresDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return postgres.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+func (d postgresDialector) DataTypeOf(field *schema.Field) string {
+	if field.DataType == "uuid" {
+		return "varchar(36)"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// mysqlDialector stores UUID columns as char(36), MySQL having no uuid type, and text
+// keys as varchar, MySQL not indexing text columns without a prefix length
+type mysqlDialector struct {
+	mysql.Dialector
+}
+
+func (d mysqlDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return mysql.Migrator{
+		Migrator: migrator.Migrator{Config: migrator.Config{
+			DB:        db,
+			Dialector: d,
+		}},
+		Dialector: d.Dialector,
+	}
+}
+
+func (d mysqlDialector) DataTypeOf(field *schema.Field) string {
+	switch {
+	case field.DataType == "uuid":
+		return "char(36)"
+	case field.DataType == "text" && (field.PrimaryKey || field.TagSettings["UNIQUE"] != "" || field.TagSettings["INDEX"] != ""):
+		return "varchar(191)"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/songvi/robo/logger"
+)
+
+// FileEnv names the config file when no -config flag does
+const FileEnv = "ROBO_CONFIG"
+
+// SearchPaths returns where the config file is looked for when neither the -config flag
+// nor ROBO_CONFIG name it, in order: config.json then config.yaml in the working
+// directory, the user config directory and /etc/robo
+func SearchPaths() []string {
+	dirs := []string{"."}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "robo"))
+	}
+	var paths []string
+	for _, dir := range append(dirs, "/etc/robo") {
+		paths = append(paths, filepath.Join(dir, "config.json"), filepath.Join(dir, "config.yaml"))
+	}
+	return paths
+}
+
+// FindConfig returns the config file to load: path when set, then the one ROBO_CONFIG
+// names, then the first of SearchPaths that exists
+func FindConfig(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if path := os.Getenv(FileEnv); path != "" {
+		return path, nil
+	}
+	paths := SearchPaths()
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read %s: %v", path, err)
+		}
+	}
+	return "", fmt.Errorf("no config file in %s; name one with -config or %s", strings.Join(paths, ", "), FileEnv)
+}
+
+// envOverride sets a setting of the config from an environment variable
+type envOverride struct {
+	name string
+	set  func(c *Config, value string) error
+}
+
+// envOverrides are the settings environment variables take over from the config file,
+// so containers can be configured without one
+var envOverrides = []envOverride{
+	{"ROBO_BROKER", func(c *Config, v string) error { c.Broker = v; return nil }},
+	{"ROBO_DRIVER", func(c *Config, v string) error { c.Driver = v; return nil }},
+	{"ROBO_DSN", func(c *Config, v string) error { c.DSN = v; return nil }},
+	{"ROBO_RECOVERY", func(c *Config, v string) error { c.Recovery = v; return nil }},
+	{"ROBO_API_ADDR", func(c *Config, v string) error { c.API.Addr = v; return nil }},
+	{"ROBO_GRPC_ADDR", func(c *Config, v string) error { c.API.GRPCAddr = v; return nil }},
+	{"ROBO_UPLOAD_ENDPOINT", func(c *Config, v string) error { c.Upload.Endpoint = v; return nil }},
+	{"ROBO_LEDGER_DEPLOYMENT", func(c *Config, v string) error { c.Ledger.Deployment = v; return nil }},
+	{"ROBO_LEDGER_TARGET_VERSION", func(c *Config, v string) error { c.Ledger.TargetVersion = v; return nil }},
+	{"ROBO_REMOTE_BUCKET", func(c *Config, v string) error { c.Remote.Bucket = v; return nil }},
+	{"ROBO_REMOTE_KEY", func(c *Config, v string) error { c.Remote.Key = v; return nil }},
+	{"ROBO_STORE_BATCH_SIZE", func(c *Config, v string) error { return setInt(&c.Store.BatchSize, v) }},
+	{"ROBO_FILE_STORE_PATH", func(c *Config, v string) error { c.Generator.FileStore.FilePath = v; return nil }},
+	{logger.LevelEnv, func(c *Config, v string) error { c.Log.Level = v; return nil }},
+	{logger.FormatEnv, func(c *Config, v string) error { c.Log.Format = v; return nil }},
+	{logger.OutputEnv, func(c *Config, v string) error { c.Log.Output = v; return nil }},
+}
+
+func setInt(field *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*field = n
+	return nil
+}
+
+// ApplyEnv overrides the settings of config with the environment variables of
+// envOverrides that are set
+func ApplyEnv(config *Config) error {
+	return applyEnv(config, os.LookupEnv)
+}
+
+func applyEnv(config *Config, lookup func(string) (string, bool)) error {
+	for _, o := range envOverrides {
+		value, ok := lookup(o.name)
+		if !ok {
+			continue
+		}
+		if err := o.set(config, value); err != nil {
+			return fmt.Errorf("invalid %s: %v", o.name, err)
+		}
+	}
+	return nil
+}
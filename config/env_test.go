@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWithEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "robo.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"broker": "nats://file:4222", "dsn": "file.db", "store": {"batch_size": 10}}`), 0o644))
+	t.Setenv("ROBO_DSN", "env.db")
+	t.Setenv("ROBO_STORE_BATCH_SIZE", "20")
+
+	config, err := Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "nats://file:4222", config.Broker, "settings without a variable come from the file")
+	assert.Equal(t, "env.db", config.DSN)
+	assert.Equal(t, 20, config.Store.BatchSize)
+	config, err = LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "file.db", config.DSN)
+
+	t.Setenv("ROBO_STORE_BATCH_SIZE", "many")
+	_, err = Load(path, "")
+	assert.ErrorContains(t, err, "ROBO_STORE_BATCH_SIZE")
+}
+
+func TestFindConfig(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	path, err := FindConfig("robo.json")
+	require.NoError(t, err)
+	assert.Equal(t, "robo.json", path, "the flag wins")
+	t.Setenv(FileEnv, "env.json")
+	path, err = FindConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "env.json", path)
+
+	t.Setenv(FileEnv, "")
+	if _, err := os.Stat("/etc/robo/config.json"); err != nil {
+		_, err = FindConfig("")
+		assert.ErrorContains(t, err, "no config file")
+	}
+	require.NoError(t, os.WriteFile("config.json", []byte(`{}`), 0o644))
+	path, err = FindConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "config.json", path)
+}
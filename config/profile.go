@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ProfileEnv selects the profile of the config file when no -profile flag does
+const ProfileEnv = "ROBO_PROFILE"
+
+// profilesKey holds the profiles of a config file, e.g. dev, staging and perf. Each
+// profile holds settings in the layout of the file, which replace those of the file
+// when the profile is selected; maps are merged key by key while lists and values are
+// replaced. A profile may extend another one with the extends key.
+const profilesKey = "profiles"
+
+// extendsKey names the profile a profile inherits from
+const extendsKey = "extends"
+
+// Profile is the profile named on the command line; empty selects the one ROBO_PROFILE
+// names, or none
+type Profile string
+
+// applyProfile returns the settings of raw, a decoded config file, with those of
+// profile and of the profiles it extends merged over them. The profiles are left out.
+func applyProfile(raw map[string]interface{}, profile string) (map[string]interface{}, error) {
+	profiles, _ := raw[profilesKey].(map[string]interface{})
+	delete(raw, profilesKey)
+	if profile == "" {
+		return raw, nil
+	}
+
+	// Resolve the chain of profiles, from the most basic to the one selected
+	var chain []map[string]interface{}
+	seen := make(map[string]bool)
+	for name := profile; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("profile %s extends itself", name)
+		}
+		seen[name] = true
+		settings, ok := profiles[name].(map[string]interface{})
+		if !ok {
+			if _, exists := profiles[name]; exists {
+				return nil, fmt.Errorf("profile %s is not a map of settings", name)
+			}
+			return nil, fmt.Errorf("unknown profile %s; the file has %s", name, profileNames(profiles))
+		}
+		chain = append([]map[string]interface{}{settings}, chain...)
+		extends, _ := settings[extendsKey].(string)
+		name = extends
+	}
+	for _, settings := range chain {
+		settings = maps.Clone(settings)
+		delete(settings, extendsKey)
+		raw = mergeSettings(raw, settings)
+	}
+	return raw, nil
+}
+
+// mergeSettings merges over into base: maps are merged key by key, anything else in over
+// replaces its counterpart in base
+func mergeSettings(base, over map[string]interface{}) map[string]interface{} {
+	merged := maps.Clone(base)
+	if merged == nil {
+		merged = make(map[string]interface{}, len(over))
+	}
+	for key, value := range over {
+		overMap, isMap := value.(map[string]interface{})
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		if isMap && baseIsMap {
+			merged[key] = mergeSettings(baseMap, overMap)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func profileNames(profiles map[string]interface{}) string {
+	if len(profiles) == 0 {
+		return "no profiles"
+	}
+	return "profiles " + strings.Join(slices.Sorted(maps.Keys(profiles)), ", ")
+}
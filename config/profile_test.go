@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profilesYAML = `
+broker: nats://localhost:4222
+dsn: dev.db
+store: {batch_size: 100, slow_query_ms: 50}
+worker: {labels: {region: eu, tier: small}}
+profiles:
+  staging:
+    driver: postgres
+    dsn: host=staging
+    store: {batch_size: 500}
+    worker: {labels: {tier: large}}
+  perf:
+    extends: staging
+    broker: nats://perf:4222
+  loop:
+    extends: loop
+`
+
+func TestProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(profilesYAML), 0o644))
+
+	config, err := Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "dev.db", config.DSN, "the file stands without a profile")
+
+	config, err = Load(path, "perf")
+	require.NoError(t, err)
+	assert.Equal(t, "nats://perf:4222", config.Broker)
+	assert.Equal(t, "host=staging", config.DSN, "perf extends staging")
+	assert.Equal(t, 500, config.Store.BatchSize)
+	assert.Equal(t, 50, config.Store.SlowQueryMs, "maps are merged key by key")
+	assert.Equal(t, map[string]string{"region": "eu", "tier": "large"}, config.Worker.Labels)
+
+	t.Setenv(ProfileEnv, "staging")
+	config, err = Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "nats://localhost:4222", config.Broker)
+	assert.Equal(t, "postgres", config.Driver)
+
+	_, err = Load(path, "prod")
+	assert.ErrorContains(t, err, "unknown profile prod; the file has profiles loop, perf, staging")
+	_, err = Load(path, "loop")
+	assert.ErrorContains(t, err, "extends itself")
+
+	jsonPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"dsn": "a.db", "store": {"batch_size": 100000000000}, "profiles": {"ci": {"dsn": "ci.db"}}}`), 0o644))
+	config, err = Load(jsonPath, "ci")
+	require.NoError(t, err)
+	assert.Equal(t, "ci.db", config.DSN)
+	assert.Equal(t, 100000000000, config.Store.BatchSize, "numbers survive the merge")
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/songvi/robo/logger"
+)
+
+// DefaultRemoteKey is the key of the worker settings in the remote bucket
+const DefaultRemoteKey = "worker"
+
+// RemoteConfig shares the worker settings of the control plane with the fleet through a
+// NATS JetStream key-value bucket, so workers are reconfigured without distributing
+// config files: the control plane publishes them at startup and workers apply them,
+// then every update, while they run
+type RemoteConfig struct {
+	Bucket string `json:"bucket" yaml:"bucket"` // Empty disables remote configuration
+	Key    string `json:"key" yaml:"key"`       // Defaults to "worker", e.g. one key per pool
+}
+
+func (r RemoteConfig) key() string {
+	if r.Key == "" {
+		return DefaultRemoteKey
+	}
+	return r.Key
+}
+
+// WorkerSettings are the sections of the config workers take from the control plane
+type WorkerSettings struct {
+	Upload UploadConfig `json:"upload" yaml:"upload"`
+	Worker WorkerConfig `json:"worker" yaml:"worker"`
+}
+
+// WorkerSettings returns the sections of the config published to workers
+func (c Config) WorkerSettings() WorkerSettings {
+	return WorkerSettings{Upload: c.Upload, Worker: c.Worker}
+}
+
+// WithWorkerSettings returns the config with the worker settings s, which must leave it
+// valid
+func (c Config) WithWorkerSettings(s WorkerSettings) (Config, error) {
+	c.Upload, c.Worker = s.Upload, s.Worker
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// PublishWorkerSettings puts the worker settings of cfg under the key of its remote
+// bucket, creating the bucket when missing
+func PublishWorkerSettings(ctx context.Context, nc *nats.Conn, cfg Config) error {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("failed to open JetStream: %v", err)
+	}
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      cfg.Remote.Bucket,
+		Description: "Worker settings published by the robo control plane",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open bucket %s: %v", cfg.Remote.Bucket, err)
+	}
+	data, err := json.Marshal(cfg.WorkerSettings())
+	if err != nil {
+		return fmt.Errorf("failed to marshal worker settings: %v", err)
+	}
+	if _, err := kv.Put(ctx, cfg.Remote.key(), data); err != nil {
+		return fmt.Errorf("failed to publish worker settings: %v", err)
+	}
+	return nil
+}
+
+// WatchWorkerSettings calls apply with the worker settings published in the remote
+// bucket: with the current ones, if any, before it returns, then with every update until
+// ctx is done. Settings that cannot be decoded are logged and skipped.
+func WatchWorkerSettings(ctx context.Context, nc *nats.Conn, remote RemoteConfig, logger logger.Logger, apply func(WorkerSettings)) error {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("failed to open JetStream: %v", err)
+	}
+	kv, err := js.KeyValue(ctx, remote.Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to open bucket %s: %v", remote.Bucket, err)
+	}
+	watcher, err := kv.Watch(ctx, remote.key())
+	if err != nil {
+		return fmt.Errorf("failed to watch %s in bucket %s: %v", remote.key(), remote.Bucket, err)
+	}
+	handle := func(entry jetstream.KeyValueEntry) {
+		if entry.Operation() != jetstream.KeyValuePut {
+			return
+		}
+		var settings WorkerSettings
+		if err := json.Unmarshal(entry.Value(), &settings); err != nil {
+			logger.Error(ctx, "Failed to decode remote worker settings", "bucket", remote.Bucket, "key", entry.Key(), "revision", entry.Revision(), "error", err)
+			return
+		}
+		apply(settings)
+	}
+
+	// The current value comes first, followed by a nil entry
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		handle(entry)
+	}
+	go func() {
+		<-ctx.Done()
+		if err := watcher.Stop(); err != nil {
+			logger.Error(ctx, "Failed to stop watching remote worker settings", "bucket", remote.Bucket, "error", err)
+		}
+	}()
+	go func() {
+		for entry := range watcher.Updates() {
+			if entry != nil {
+				handle(entry)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,8 @@
+package config
+
+import _ "embed"
+
+// Sample is an example config file in YAML, with every setting and its documentation
+//
+//go:embed sample.yaml
+var Sample []byte
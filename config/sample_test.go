@@ -0,0 +1,27 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSample(t *testing.T) {
+	var config Config
+	dec := yaml.NewDecoder(bytes.NewReader(Sample))
+	dec.KnownFields(true)
+	require.NoError(t, dec.Decode(&config), "every setting of the sample exists")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, Sample, 0o644))
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBroker, config.Broker)
+	assert.Equal(t, "/tmp/robo/files", config.Generator.FileStore.FilePath)
+	assert.Equal(t, 3600, config.JobStrategy["cycle_duration"])
+}
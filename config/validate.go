@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Defaults of the settings the config file may leave out
+const (
+	DefaultBroker   = "nats://localhost:4222"
+	DefaultDriver   = "sqlite"
+	DefaultDSN      = "robo.db" // sqlite only: the other drivers need a DSN
+	DefaultRecovery = "requeue"
+	DefaultAPIAddr  = ":8080"
+)
+
+// brokerSchemes are the URL schemes NATS servers are reached with
+var brokerSchemes = []string{"nats", "tls", "ws", "wss"}
+
+// Validate checks the settings of the config, reporting every problem found at once
+// rather than the first
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.Driver {
+	case "", "sqlite":
+	case "postgres", "mysql":
+		if c.DSN == "" {
+			add("dsn: required by the %s driver", c.Driver)
+		}
+	default:
+		add("driver: unsupported %q, use sqlite, postgres or mysql", c.Driver)
+	}
+	if c.Broker != "" {
+		// A broker may list several servers of a cluster
+		for _, server := range strings.Split(c.Broker, ",") {
+			if err := checkBrokerURL(strings.TrimSpace(server)); err != nil {
+				add("broker: %v", err)
+			}
+		}
+	}
+	switch c.Recovery {
+	case "", "requeue", "expire":
+	default:
+		add("recovery: unknown policy %q, use requeue or expire", c.Recovery)
+	}
+
+	g := &c.Generator
+	for name, size := range map[string]int{
+		"file_buffer":      g.FileBuffer,
+		"user_buffer":      g.UserBuffer,
+		"workspace_buffer": g.WorkspaceBuffer,
+	} {
+		if size < 0 {
+			add("generator.%s: %d is negative", name, size)
+		}
+	}
+	// Reject or rescale strategy probabilities that do not form a distribution
+	if err := g.Strategy.Validate(g.NormalizeProbabilities); err != nil {
+		add("generator.strategy.%v", err)
+	}
+
+	for name, n := range map[string]int{
+		"store.batch_size":                 c.Store.BatchSize,
+		"store.slow_query_ms":              c.Store.SlowQueryMs,
+		"store.cache.size":                 c.Store.Cache.Size,
+		"store.cache.ttl_seconds":          c.Store.Cache.TTLSeconds,
+		"store.retention.max_age_days":     c.Store.Retention.MaxAgeDays,
+		"store.retention.interval_minutes": c.Store.Retention.IntervalMinutes,
+		"upload.chunk_size":                c.Upload.ChunkSize,
+		"notify.min_workers":               c.Notify.MinWorkers,
+		"api.rate_limit.burst":             c.API.RateLimit.Burst,
+		"api.rate_limit.max_concurrent":    c.API.RateLimit.MaxConcurrent,
+		"api.rate_limit.cycle_burst":       c.API.RateLimit.CycleBurst,
+	} {
+		if n < 0 {
+			add("%s: %d is negative", name, n)
+		}
+	}
+	if c.API.RateLimit.RequestsPerSecond < 0 {
+		add("api.rate_limit.requests_per_second: %v is negative", c.API.RateLimit.RequestsPerSecond)
+	}
+	if c.API.RateLimit.CyclesPerMinute < 0 {
+		add("api.rate_limit.cycles_per_minute: %v is negative", c.API.RateLimit.CyclesPerMinute)
+	}
+	if p := c.Upload.InterruptProbability; p < 0 || p > 1 {
+		add("upload.interrupt_probability: %v is outside [0, 1]", p)
+	}
+	if err := c.Log.Validate(); err != nil {
+		add("log.%v", err)
+	}
+
+	// Map iteration does not keep the order of the problems
+	slices.Sort(problems)
+	errs := make([]error, len(problems))
+	for i, problem := range problems {
+		errs[i] = errors.New(problem)
+	}
+	return errors.Join(errs...)
+}
+
+func checkBrokerURL(server string) error {
+	u, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(brokerSchemes, u.Scheme) {
+		return fmt.Errorf("%q has no nats://, tls://, ws:// or wss:// scheme", server)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%q has no host", server)
+	}
+	return nil
+}
+
+// ApplyDefaults sets the settings the config leaves out to their defaults, so the
+// components reading it need no fallbacks of their own
+func (c *Config) ApplyDefaults() {
+	if c.Broker == "" {
+		c.Broker = DefaultBroker
+	}
+	if c.Driver == "" {
+		c.Driver = DefaultDriver
+	}
+	if c.DSN == "" && c.Driver == DefaultDriver {
+		c.DSN = DefaultDSN
+	}
+	if c.Recovery == "" {
+		c.Recovery = DefaultRecovery
+	}
+	if c.API.Addr == "" {
+		c.API.Addr = DefaultAPIAddr
+	}
+	c.Generator.SetDefaults()
+}
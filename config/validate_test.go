@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	config := Config{Driver: "postgres", Broker: "nats://a:4222, localhost:4222"}
+	config.Generator.FileBuffer = -1
+	config.Generator.Strategy.UserStrategy.UserLang = []string{"en", "fr"}
+	config.Generator.Strategy.UserStrategy.LangProbability = []float64{1}
+	config.Log.Format = "xml"
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Equal(t, `broker: "localhost:4222" has no nats://, tls://, ws:// or wss:// scheme
+dsn: required by the postgres driver
+generator.file_buffer: -1 is negative
+generator.strategy.user_strategy.user_lang: 1 probabilities for 2 values
+log.format: unknown "xml", use json or text`, err.Error(), "every problem is reported")
+
+	config = Config{}
+	require.NoError(t, config.Validate())
+	config.ApplyDefaults()
+	assert.Equal(t, DefaultBroker, config.Broker)
+	assert.Equal(t, DefaultDSN, config.DSN)
+	assert.Equal(t, DefaultAPIAddr, config.API.Addr)
+	assert.Equal(t, 5, config.Generator.FileBuffer)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the layout of the config files this robo writes. Files without a
+// version have the first layout, and older layouts are upgraded as they are loaded.
+const CurrentVersion = 2
+
+const versionKey = "version"
+
+// upgrades[i] turns the settings of a file of version i+1 into those of version i+2
+var upgrades = []func(settings map[string]interface{}){
+	upgradeV1,
+}
+
+// upgradeV1 renames the settings whose names did not follow the layout of the others:
+// job_strategy.max_workspaces, ignored until then, and generator.file_store.FilePath
+func upgradeV1(settings map[string]interface{}) {
+	if strategy, ok := settings["job_strategy"].(map[string]interface{}); ok {
+		renameSetting(strategy, "max_workspaces", "max_workspace")
+	}
+	if generator, ok := settings["generator"].(map[string]interface{}); ok {
+		if store, ok := generator["file_store"].(map[string]interface{}); ok {
+			renameSetting(store, "FilePath", "file_path")
+		}
+	}
+}
+
+// renameSetting moves the setting from to to, unless to is set already
+func renameSetting(settings map[string]interface{}, from, to string) {
+	value, ok := settings[from]
+	if !ok {
+		return
+	}
+	delete(settings, from)
+	if _, set := settings[to]; !set {
+		settings[to] = value
+	}
+}
+
+// fileVersion returns the layout version of raw, a decoded config file
+func fileVersion(raw map[string]interface{}) (int, error) {
+	var version int
+	switch v := raw[versionKey].(type) {
+	case nil:
+		return 1, nil
+	case int:
+		version = v
+	case float64:
+		version = int(v)
+		if float64(version) != v {
+			return 0, fmt.Errorf("version %v is not an integer", v)
+		}
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("version %v is not an integer", v)
+		}
+		version = int(n)
+	default:
+		return 0, fmt.Errorf("version %v is not an integer", v)
+	}
+	if version < 1 {
+		return 0, fmt.Errorf("version %d is not a config version", version)
+	}
+	if version > CurrentVersion {
+		return 0, fmt.Errorf("version %d is newer than the %d this robo reads; upgrade robo", version, CurrentVersion)
+	}
+	return version, nil
+}
+
+// upgradeSettings brings raw, a decoded config file, and its profiles to the current
+// layout. The version of raw is left as the file has it, 1 when missing.
+func upgradeSettings(raw map[string]interface{}) error {
+	version, err := fileVersion(raw)
+	if err != nil {
+		return err
+	}
+	raw[versionKey] = version
+	profiles, _ := raw[profilesKey].(map[string]interface{})
+	for _, upgrade := range upgrades[version-1:] {
+		upgrade(raw)
+		for _, profile := range profiles {
+			if settings, ok := profile.(map[string]interface{}); ok {
+				upgrade(settings)
+			}
+		}
+	}
+	return nil
+}
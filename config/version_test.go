@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	// Files without a version have the first layout
+	path := write("v1.json", `{
+		"generator": {"file_store": {"FilePath": "/tmp/files"}},
+		"job_strategy": {"max_files": 50, "max_workspaces": 20},
+		"profiles": {"ci": {"job_strategy": {"max_workspaces": 2}}}
+	}`)
+	config, err := Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.Version)
+	assert.Equal(t, "/tmp/files", config.Generator.FileStore.FilePath)
+	assert.Equal(t, float64(20), config.JobStrategy["max_workspace"])
+	assert.NotContains(t, config.JobStrategy, "max_workspaces")
+
+	config, err = Load(path, "ci")
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), config.JobStrategy["max_workspace"], "profiles are upgraded with the file")
+
+	config, err = Load(write("v2.yaml", "version: 2\njob_strategy: {max_workspace: 5}\n"), "")
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, config.Version)
+	assert.Equal(t, 5, config.JobStrategy["max_workspace"])
+
+	_, err = Load(write("v3.yaml", "version: 3\n"), "")
+	assert.ErrorContains(t, err, "version 3 is newer than the 2 this robo reads; upgrade robo")
+	_, err = Load(write("v0.yaml", "version: 0\n"), "")
+	assert.ErrorContains(t, err, "version 0 is not a config version")
+	_, err = Load(write("text.json", `{"version": "2"}`), "")
+	assert.ErrorContains(t, err, "version 2 is not an integer")
+}
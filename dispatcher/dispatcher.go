@@ -3,6 +3,7 @@ package dispatcher
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
@@ -14,24 +15,41 @@ import (
 	"github.com/songvi/robo/config"
 	"github.com/songvi/robo/logger"
 	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/notify"
+	"github.com/songvi/robo/store"
 )
 
 // WorkerRegistrationMessage defines the structure of worker registration messages
 type WorkerRegistrationMessage struct {
-	WorkerID     string   `json:"worker_id"`
-	Name         string   `json:"name"`
-	Capabilities []string `json:"capabilities"`
-	Status       string   `json:"status"`
+	WorkerID     string            `json:"worker_id"`
+	Name         string            `json:"name"`
+	Capabilities []string          `json:"capabilities"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Version      string            `json:"version,omitempty"`
+	Status       string            `json:"status"`
 }
 
 // Dispatcher defines the interface for the dispatcher service
 type Dispatcher interface {
 	Publish(ctx context.Context, subject string, data []byte) error
 	Subscribe(ctx context.Context, subject string) (<-chan *nats.Msg, error)
+	Request(ctx context.Context, subject string, data []byte) (*nats.Msg, error)
 	GetActiveWorkers() []models.Worker
 	DispatchJob(ctx context.Context, job *models.Job) error
+	// DispatchJobToPool sends a job to an active worker carrying every label of labels
+	DispatchJobToPool(ctx context.Context, job *models.Job, labels map[string]string) error
+	// DispatchBatchToPool sends jobs as one batch to an active worker carrying every
+	// label of labels
+	DispatchBatchToPool(ctx context.Context, jobs []models.Job, labels map[string]string) error
+	// CancelCycle tells every worker to stop the jobs of a cycle dispatched so far
+	CancelCycle(ctx context.Context, cycleUUID string) error
+	// DrainWorker stops dispatching jobs to an active worker, e.g. before it is shut down
+	DrainWorker(ctx context.Context, workerID string) error
 }
 
+// ErrUnknownWorker is returned for a worker that is not active
+var ErrUnknownWorker = errors.New("unknown worker")
+
 // dispatcherImpl is the implementation of the Dispatcher interface
 type dispatcherImpl struct {
 	nc            *nats.Conn
@@ -40,15 +58,16 @@ type dispatcherImpl struct {
 	workerMu      sync.RWMutex
 	lastHeartbeat map[string]time.Time
 	heartbeatMu   sync.RWMutex
+	store         store.Store // The registry of workers, which outlives the dispatcher
+	notifier      notify.Notifier
+	minWorkers    int  // Fleet size below which the fleet is degraded
+	degraded      bool // Whether workers_degraded fired since the fleet was last healthy
 }
 
 // NewDispatcher creates a new Dispatcher instance
-func NewDispatcher(lc fx.Lifecycle, configService config.ConfigService, logger logger.Logger) (Dispatcher, error) {
-	config := configService.GetConfig()
-	broker := config.Broker
-	if broker == "" {
-		broker = "nats://localhost:4222"
-	}
+func NewDispatcher(lc fx.Lifecycle, configService config.ConfigService, logger logger.Logger, notifier notify.Notifier, store store.Store) (Dispatcher, error) {
+	cfg := configService.GetConfig()
+	broker := cfg.Broker
 
 	// Connect to NATS
 	nc, err := nats.Connect(broker)
@@ -62,6 +81,9 @@ func NewDispatcher(lc fx.Lifecycle, configService config.ConfigService, logger l
 		logger:        logger,
 		workers:       make(map[string]models.Worker),
 		lastHeartbeat: make(map[string]time.Time),
+		store:         store,
+		notifier:      notifier,
+		minWorkers:    cfg.Notify.MinWorkers,
 	}
 
 	// Start worker registration and heartbeat handling
@@ -72,6 +94,14 @@ func NewDispatcher(lc fx.Lifecycle, configService config.ConfigService, logger l
 			if err := d.startWorkerManagement(ctx); err != nil {
 				return err
 			}
+			if cfg.Remote.Bucket != "" {
+				// Workers keep their own settings when none are published
+				if err := config.PublishWorkerSettings(ctx, nc, cfg); err != nil {
+					d.logger.Error(ctx, "Failed to publish worker settings", "bucket", cfg.Remote.Bucket, "error", err)
+				} else {
+					d.logger.Info(ctx, "Published worker settings", "bucket", cfg.Remote.Bucket)
+				}
+			}
 			return nil
 		},
 		OnStop: func(context.Context) error {
@@ -87,16 +117,34 @@ func NewDispatcher(lc fx.Lifecycle, configService config.ConfigService, logger l
 
 // DispatchJob sends a job to an active worker
 func (d *dispatcherImpl) DispatchJob(ctx context.Context, job *models.Job) error {
-	// Get active workers
-	workers := d.GetActiveWorkers()
+	return d.DispatchJobToPool(ctx, job, nil)
+}
+
+// pickWorker selects an active worker carrying every label of labels that is not
+// draining
+func (d *dispatcherImpl) pickWorker(labels map[string]string) (models.Worker, error) {
+	var workers []models.Worker
+	for _, w := range d.GetActiveWorkers() {
+		if !w.Draining && w.HasLabels(labels) {
+			workers = append(workers, w)
+		}
+	}
 	if len(workers) == 0 {
-		d.logger.Error(ctx, "No active workers available to dispatch job", "job_uuid", job.UUID)
-		return fmt.Errorf("no active workers available")
+		return models.Worker{}, fmt.Errorf("no active workers available")
 	}
-
 	// Select a worker randomly (modify for a different strategy if needed)
-	worker := workers[rand.Intn(len(workers))]
+	return workers[rand.Intn(len(workers))], nil
+}
+
+// DispatchJobToPool sends a job to an active worker carrying every label of labels
+func (d *dispatcherImpl) DispatchJobToPool(ctx context.Context, job *models.Job, labels map[string]string) error {
+	worker, err := d.pickWorker(labels)
+	if err != nil {
+		d.logger.Error(ctx, "No active workers available to dispatch job", "job_uuid", job.UUID, "labels", labels)
+		return err
+	}
 	job.WorkerID = worker.UUID
+	job.DispatchedAt = time.Now().UnixNano()
 
 	// Serialize job to JSON
 	data, err := json.Marshal(job)
@@ -116,6 +164,67 @@ func (d *dispatcherImpl) DispatchJob(ctx context.Context, job *models.Job) error
 	return nil
 }
 
+// DispatchBatchToPool sends jobs as one batch to an active worker carrying every label
+// of labels, on the subject of its single jobs
+func (d *dispatcherImpl) DispatchBatchToPool(ctx context.Context, jobs []models.Job, labels map[string]string) error {
+	worker, err := d.pickWorker(labels)
+	if err != nil {
+		d.logger.Error(ctx, "No active workers available to dispatch job batch", "jobs", len(jobs), "labels", labels)
+		return err
+	}
+	now := time.Now().UnixNano()
+	for i := range jobs {
+		jobs[i].WorkerID = worker.UUID
+		jobs[i].DispatchedAt = now
+	}
+
+	data, err := json.Marshal(models.JobBatch{Jobs: jobs})
+	if err != nil {
+		return fmt.Errorf("failed to marshal job batch: %w", err)
+	}
+	subject := fmt.Sprintf("dispatcher.job.%s", worker.UUID)
+	if err := d.Publish(ctx, subject, data); err != nil {
+		d.logger.Error(ctx, "Failed to dispatch job batch", "jobs", len(jobs), "worker_id", worker.UUID, "error", err)
+		return fmt.Errorf("failed to dispatch job batch: %w", err)
+	}
+
+	d.logger.Info(ctx, "Dispatched job batch to worker", "jobs", len(jobs), "worker_id", worker.UUID, "session_id", jobs[0].SessionID)
+	return nil
+}
+
+// CancelCycle tells every worker to stop the jobs of a cycle dispatched so far: the one
+// running is cancelled and queued ones are dropped. Jobs dispatched afterwards run.
+func (d *dispatcherImpl) CancelCycle(ctx context.Context, cycleUUID string) error {
+	data, err := json.Marshal(models.CycleCancellation{CycleUUID: cycleUUID, At: time.Now().UnixNano()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cycle cancellation: %w", err)
+	}
+	if err := d.Publish(ctx, "dispatcher.job.cancel", data); err != nil {
+		return fmt.Errorf("failed to cancel cycle jobs: %w", err)
+	}
+	d.logger.Info(ctx, "Cancelled cycle jobs on workers", "cycle_uuid", cycleUUID)
+	return nil
+}
+
+// DrainWorker marks an active worker as draining; it stays so until it registers again
+func (d *dispatcherImpl) DrainWorker(ctx context.Context, workerID string) error {
+	d.workerMu.Lock()
+	worker, ok := d.workers[workerID]
+	if ok {
+		worker.Draining = true
+		d.workers[workerID] = worker
+	}
+	d.workerMu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownWorker, workerID)
+	}
+	if err := d.store.UpsertWorker(ctx, &worker); err != nil {
+		d.logger.Error(ctx, "Failed to save draining worker", "worker_id", workerID, "error", err)
+	}
+	d.logger.Info(ctx, "Worker draining", "worker_id", workerID)
+	return nil
+}
+
 // startWorkerManagement sets up subscriptions for worker registration, heartbeats, and deregistration
 func (d *dispatcherImpl) startWorkerManagement(ctx context.Context) error {
 	// Subscribe to worker registration
@@ -157,19 +266,29 @@ func (d *dispatcherImpl) handleRegistrations(ctx context.Context, regCh <-chan *
 			continue
 		}
 
+		now := time.Now()
 		worker := models.Worker{
-			Name: regMsg.Name,
-			UUID: regMsg.WorkerID,
+			Name:         regMsg.Name,
+			UUID:         regMsg.WorkerID,
+			Labels:       regMsg.Labels,
+			Capabilities: regMsg.Capabilities,
+			Status:       models.WorkerActive,
+			LastSeenAt:   now.Unix(),
+			Version:      regMsg.Version,
 		}
 		d.workerMu.Lock()
 		d.workers[regMsg.WorkerID] = worker
 		d.workerMu.Unlock()
 
 		d.heartbeatMu.Lock()
-		d.lastHeartbeat[regMsg.WorkerID] = time.Now()
+		d.lastHeartbeat[regMsg.WorkerID] = now
 		d.heartbeatMu.Unlock()
 
-		d.logger.Info(ctx, "Worker registered", "worker_id", regMsg.WorkerID, "name", regMsg.Name, "capabilities", regMsg.Capabilities)
+		if err := d.store.UpsertWorker(ctx, &worker); err != nil {
+			d.logger.Error(ctx, "Failed to save registered worker", "worker_id", regMsg.WorkerID, "error", err)
+		}
+
+		d.logger.Info(ctx, "Worker registered", "worker_id", regMsg.WorkerID, "name", regMsg.Name, "capabilities", regMsg.Capabilities, "labels", regMsg.Labels)
 	}
 }
 
@@ -185,14 +304,41 @@ func (d *dispatcherImpl) handleHeartbeats(ctx context.Context, hbCh <-chan *nats
 			continue
 		}
 
+		now := time.Now()
 		d.heartbeatMu.Lock()
-		d.lastHeartbeat[hbMsg.WorkerID] = time.Now()
+		d.lastHeartbeat[hbMsg.WorkerID] = now
 		d.heartbeatMu.Unlock()
 
+		if err := d.store.TouchWorker(ctx, hbMsg.WorkerID, now.Unix()); err != nil {
+			d.logger.Error(ctx, "Failed to save worker heartbeat", "worker_id", hbMsg.WorkerID, "error", err)
+		} else {
+			d.restoreWorker(ctx, hbMsg.WorkerID)
+		}
+
 		d.logger.Info(ctx, "Received heartbeat", "worker_id", hbMsg.WorkerID)
 	}
 }
 
+// restoreWorker makes a worker that registered before the dispatcher started, or that
+// went inactive, active again from the registry
+func (d *dispatcherImpl) restoreWorker(ctx context.Context, workerID string) {
+	d.workerMu.RLock()
+	_, active := d.workers[workerID]
+	d.workerMu.RUnlock()
+	if active {
+		return
+	}
+	worker, err := d.store.GetWorker(ctx, workerID)
+	if err != nil {
+		d.logger.Error(ctx, "Failed to restore worker from the registry", "worker_id", workerID, "error", err)
+		return
+	}
+	d.workerMu.Lock()
+	d.workers[workerID] = *worker
+	d.workerMu.Unlock()
+	d.logger.Info(ctx, "Worker restored from the registry", "worker_id", workerID, "name", worker.Name)
+}
+
 // handleDeregistrations processes worker deregistration messages
 func (d *dispatcherImpl) handleDeregistrations(ctx context.Context, derCh <-chan *nats.Msg) {
 	for msg := range derCh {
@@ -213,6 +359,10 @@ func (d *dispatcherImpl) handleDeregistrations(ctx context.Context, derCh <-chan
 		delete(d.lastHeartbeat, derMsg.WorkerID)
 		d.heartbeatMu.Unlock()
 
+		if err := d.store.UpdateWorkerStatus(ctx, derMsg.WorkerID, models.WorkerDeregistered); err != nil {
+			d.logger.Error(ctx, "Failed to save deregistered worker", "worker_id", derMsg.WorkerID, "error", err)
+		}
+
 		d.logger.Info(ctx, "Worker deregistered", "worker_id", derMsg.WorkerID)
 	}
 }
@@ -229,6 +379,7 @@ func (d *dispatcherImpl) cleanupInactiveWorkers(ctx context.Context) {
 		case <-ticker.C:
 			d.heartbeatMu.Lock()
 			now := time.Now()
+			var removed []string
 			for workerID, lastHB := range d.lastHeartbeat {
 				if now.Sub(lastHB) > 15*time.Second {
 					d.workerMu.Lock()
@@ -236,13 +387,71 @@ func (d *dispatcherImpl) cleanupInactiveWorkers(ctx context.Context) {
 					d.workerMu.Unlock()
 					delete(d.lastHeartbeat, workerID)
 					d.logger.Info(ctx, "Removed inactive worker", "worker_id", workerID)
+					removed = append(removed, workerID)
 				}
 			}
 			d.heartbeatMu.Unlock()
+			for _, workerID := range removed {
+				if err := d.store.UpdateWorkerStatus(ctx, workerID, models.WorkerInactive); err != nil {
+					d.logger.Error(ctx, "Failed to save inactive worker", "worker_id", workerID, "error", err)
+				}
+				d.requeueJobs(ctx, workerID)
+			}
+			d.checkFleet(ctx, len(removed))
 		}
 	}
 }
 
+// requeueJobs puts the jobs in flight on a worker that stopped sending heartbeats back
+// in line, so they are dispatched to another worker
+func (d *dispatcherImpl) requeueJobs(ctx context.Context, workerID string) {
+	filter := store.JobFilter{WorkerID: workerID, Statuses: []string{"dispatched", "processing"}}
+	page := store.Page{Limit: store.MaxPageLimit}
+	var uuids []string
+	for {
+		jobs, err := d.store.ListJobs(ctx, filter, page)
+		if err != nil {
+			d.logger.Error(ctx, "Failed to list jobs of inactive worker", "worker_id", workerID, "error", err)
+			return
+		}
+		for _, job := range jobs.Jobs {
+			uuids = append(uuids, job.UUID)
+		}
+		if jobs.NextCursor == "" {
+			break
+		}
+		page.Cursor = jobs.NextCursor
+	}
+	requeued, err := d.store.UpdateJobsStatus(ctx, uuids, "pending")
+	if err != nil {
+		d.logger.Error(ctx, "Failed to requeue jobs of inactive worker", "worker_id", workerID, "jobs", len(uuids), "error", err)
+		return
+	}
+	if requeued > 0 {
+		d.logger.Info(ctx, "Requeued jobs of inactive worker", "worker_id", workerID, "jobs", requeued)
+	}
+}
+
+// checkFleet fires workers_degraded when losing workers leaves fewer than minWorkers
+// active, once until the fleet recovers, or on every loss when minWorkers is zero
+func (d *dispatcherImpl) checkFleet(ctx context.Context, removed int) {
+	active := len(d.GetActiveWorkers())
+	if d.minWorkers == 0 {
+		if removed > 0 {
+			d.notifier.Notify(ctx, notify.Event{Type: notify.WorkersDegraded, Workers: active})
+		}
+		return
+	}
+	switch {
+	case active >= d.minWorkers:
+		d.degraded = false
+	case !d.degraded && removed > 0:
+		d.degraded = true
+		d.logger.Error(ctx, "Worker fleet degraded", "active_workers", active, "min_workers", d.minWorkers)
+		d.notifier.Notify(ctx, notify.Event{Type: notify.WorkersDegraded, Workers: active})
+	}
+}
+
 // Publish publishes a message to the specified subject
 func (d *dispatcherImpl) Publish(ctx context.Context, subject string, data []byte) error {
 	if err := d.nc.Publish(subject, data); err != nil {
@@ -274,6 +483,17 @@ func (d *dispatcherImpl) Subscribe(ctx context.Context, subject string) (<-chan
 	return msgCh, nil
 }
 
+// Request sends a message to the specified subject and waits for a single reply until ctx expires
+func (d *dispatcherImpl) Request(ctx context.Context, subject string, data []byte) (*nats.Msg, error) {
+	msg, err := d.nc.RequestWithContext(ctx, subject, data)
+	if err != nil {
+		d.logger.Error(ctx, "Request failed", "subject", subject, "error", err)
+		return nil, err
+	}
+	d.logger.Info(ctx, "Received reply", "subject", subject)
+	return msg, nil
+}
+
 // GetActiveWorkers returns the list of active workers
 func (d *dispatcherImpl) GetActiveWorkers() []models.Worker {
 	d.workerMu.RLock()
@@ -1,31 +1,140 @@
 package generator
 
 import (
+	"github.com/songvi/robo/generator/file"
 	"github.com/songvi/robo/models"
 )
 
 type GeneratorConfig struct {
-	Strategy        Strategy  `json:"strategy" yaml:"strategy"`
-	FileStore       FileStore `json:"file_store" yaml:"file_store"`
-	DBStore         DBStore   `json:"db_store" yaml:"db_store"`
-	FileBuffer      int       `json:"file_buffer" yaml:"file_buffer"`
-	UserBuffer      int       `json:"user_buffer" yaml:"user_buffer"`
-	WorkspaceBuffer int       `json:"workspace_buffer" yaml:"workspace_buffer"`
-	DBConfig        DBConfig  `json:"db_config" yaml:"db_config"`
+	Strategy        Strategy          `json:"strategy" yaml:"strategy"`
+	FileStore       FileStore         `json:"file_store" yaml:"file_store"`
+	DBStore         DBStore           `json:"db_store" yaml:"db_store"`
+	FileBuffer      int               `json:"file_buffer" yaml:"file_buffer"`
+	UserBuffer      int               `json:"user_buffer" yaml:"user_buffer"`
+	WorkspaceBuffer int               `json:"workspace_buffer" yaml:"workspace_buffer"`
+	CorpusCache     CorpusCacheConfig `json:"corpus_cache" yaml:"corpus_cache"`
+	Corpora         CorporaConfig     `json:"corpora" yaml:"corpora"`
+	Templates       TemplatesConfig   `json:"content_templates" yaml:"content_templates"`
+	// Fonts maps a script (latin, cjk, arabic, hebrew, thai, devanagari) to the TrueType
+	// font PDFs write it with; Latin falls back to an embedded Noto Sans
+	Fonts      map[string]string `json:"fonts" yaml:"fonts"`
+	Quota      QuotaConfig       `json:"quota" yaml:"quota"`
+	Throughput ThroughputConfig  `json:"throughput" yaml:"throughput"`
+	Pool       PoolConfig        `json:"pool" yaml:"pool"`
+	Growth     GrowthConfig      `json:"growth" yaml:"growth"`
+	Metrics    MetricsConfig     `json:"metrics" yaml:"metrics"`
+	// NormalizeProbabilities rescales strategy probability lists that do not sum to 1
+	// instead of rejecting the configuration
+	NormalizeProbabilities bool `json:"normalize_probabilities" yaml:"normalize_probabilities"`
+	// Lazy starts each channel worker only when its channel is first requested and
+	// hands items over unbuffered, so nothing is generated ahead of consumers
+	Lazy bool `json:"lazy" yaml:"lazy"`
+	// DryRun produces file records (names, sizes, a pseudo ContentID) without writing
+	// any content, to plan corpus composition and exercise downstream logic quickly
+	DryRun bool `json:"dry_run" yaml:"dry_run"`
+	// Seed makes generation reproducible: two runs with the same non-zero seed
+	// produce identical users, files and workspaces. Zero means time-based.
+	Seed int64 `json:"seed" yaml:"seed"`
 }
 
-// DBConfig holds the database configuration for GORM
-type DBConfig struct {
-	DSN string `json:"dsn" yaml:"dsn"` // Data Source Name for database connection
+// Default buffer sizes of the generator channels
+const (
+	DefaultUserBuffer      = 10
+	DefaultFileBuffer      = 5 // Smaller due to the potential size of files
+	DefaultWorkspaceBuffer = 10
+)
+
+// SetDefaults sets the buffer sizes left unset to their defaults
+func (c *GeneratorConfig) SetDefaults() {
+	if c.UserBuffer <= 0 {
+		c.UserBuffer = DefaultUserBuffer
+	}
+	if c.FileBuffer <= 0 {
+		c.FileBuffer = DefaultFileBuffer
+	}
+	if c.WorkspaceBuffer <= 0 {
+		c.WorkspaceBuffer = DefaultWorkspaceBuffer
+	}
 }
+
 type Strategy struct {
+	// Preset names a shipped strategy the other fields extend; see StrategyPresets
+	Preset            string                   `json:"preset,omitempty" yaml:"preset,omitempty"`
 	FileStrategy      models.FileStrategy      `json:"file_strategy" yaml:"file_strategy"`
 	UserStrategy      models.UserStrategy      `json:"user_strategy" yaml:"user_strategy"`
 	WorkspaceStrategy models.WorkspaceStrategy `json:"workspace_strategy" yaml:"workspace_strategy"`
+	ActionStrategy    models.ActionStrategy    `json:"action_strategy" yaml:"action_strategy"`
+}
+
+// CorpusCacheConfig enables the compressed sentence cache used for large text documents
+type CorpusCacheConfig struct {
+	Dir        string  `json:"dir" yaml:"dir"`               // Cache directory; empty disables the cache
+	Uniqueness float64 `json:"uniqueness" yaml:"uniqueness"` // Fraction of freshly generated sentences (0..1)
+}
+
+// CorporaConfig plugs real text corpora into sentence generation via Markov chains
+type CorporaConfig struct {
+	Paths map[string]string `json:"paths" yaml:"paths"` // Language code -> plain-text corpus file
+	Order int               `json:"order" yaml:"order"` // Tokens per chain state; defaults to 2
+}
+
+// TemplatesConfig renders documents from text/template files instead of random prose
+type TemplatesConfig struct {
+	Paths map[string][]string `json:"paths" yaml:"paths"` // File extension -> template files, one picked per document
+	Ratio float64             `json:"ratio" yaml:"ratio"` // Share of those documents rendered from a template; 0 means all
+}
+
+// ThroughputConfig caps how fast the background workers generate; zero means unlimited.
+// On-demand GenerateUsers/GenerateFiles calls are not throttled.
+type ThroughputConfig struct {
+	UsersPerSecond      float64 `json:"users_per_second" yaml:"users_per_second"`
+	FilesPerSecond      float64 `json:"files_per_second" yaml:"files_per_second"`
+	WorkspacesPerSecond float64 `json:"workspaces_per_second" yaml:"workspaces_per_second"`
+	MaxBytesPerSecond   int64   `json:"max_bytes_per_second" yaml:"max_bytes_per_second"` // File content written per second
+}
+
+// PoolConfig generates files on several workers at once. With fewer than two workers
+// files are generated one at a time.
+type PoolConfig struct {
+	Workers   int `json:"workers" yaml:"workers"`       // Files generated concurrently
+	QueueSize int `json:"queue_size" yaml:"queue_size"` // Jobs queued beyond the busy workers; defaults to Workers
+	// MaxPendingBytes pauses dispatching new files while generated files not yet handed
+	// to consumers add up to this many bytes; zero means no limit
+	MaxPendingBytes int64 `json:"max_pending_bytes" yaml:"max_pending_bytes"`
+}
+
+// GrowthConfig grows the file corpus continuously at a daily rate instead of as fast
+// as consumers read, to simulate organic growth of the target over time
+type GrowthConfig struct {
+	FilesPerDay float64 `json:"files_per_day" yaml:"files_per_day"` // Files added per weekday; zero disables growth mode
+	// HourlyActivity weights the 24 hours of the day; defaults to an office-hours curve
+	HourlyActivity []float64 `json:"hourly_activity" yaml:"hourly_activity"`
+	// WeekendActivity scales Saturdays and Sundays relative to weekdays; defaults to 0.3
+	WeekendActivity *float64 `json:"weekend_activity,omitempty" yaml:"weekend_activity,omitempty"`
+	Location        string   `json:"location" yaml:"location"` // IANA time zone of the activity curve; defaults to local time
+}
+
+// MetricsConfig exposes the generator metrics to Prometheus
+type MetricsConfig struct {
+	Addr string `json:"addr" yaml:"addr"` // Listen address of the /metrics endpoint, e.g. ":9102"; empty disables it
+}
+
+// QuotaConfig bounds the disk used by generated files kept locally; the oldest files
+// are deleted first. Zero values disable the corresponding limit.
+type QuotaConfig struct {
+	MaxBytes         int64 `json:"max_bytes" yaml:"max_bytes"`
+	MaxCycleBytes    int64 `json:"max_cycle_bytes" yaml:"max_cycle_bytes"`
+	RetentionSeconds int   `json:"retention_seconds" yaml:"retention_seconds"`
 }
 
 type FileStore struct {
-	FilePath string
+	FilePath string `json:"file_path" yaml:"file_path"` // Local directory files are generated into
+	// Type selects where generated files end up: local (default), s3 or webdav
+	Type      string            `json:"type" yaml:"type"`
+	Dir       string            `json:"dir" yaml:"dir"` // local: destination directory; empty keeps files in FilePath
+	S3        file.S3Config     `json:"s3" yaml:"s3"`
+	WebDAV    file.WebDAVConfig `json:"webdav" yaml:"webdav"`
+	KeepLocal bool              `json:"keep_local" yaml:"keep_local"` // Keep local copies of remotely stored files
 }
 
 type DBStore struct {
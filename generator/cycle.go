@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"io"
+	"math/rand"
+	"sync"
+
+	"github.com/songvi/robo/models"
+)
+
+// cycleKey is the context key of the cycle files are generated for
+type cycleKey struct{}
+
+// WithCycle returns a context under which GenerateFiles stamps the files it generates
+// with cycleID and stores them in the namespace of that cycle
+func WithCycle(ctx context.Context, cycleID string) context.Context {
+	return context.WithValue(ctx, cycleKey{}, cycleID)
+}
+
+// CycleFromContext returns the cycle set by WithCycle, or "" outside any cycle
+func CycleFromContext(ctx context.Context) string {
+	cycleID, _ := ctx.Value(cycleKey{}).(string)
+	return cycleID
+}
+
+// usersKey is the context key of the users set by WithUsers
+type usersKey struct{}
+
+// WithUsers returns a context under which GenerateWorkspaces makes workspaces of users,
+// the users of a cycle, instead of users of the application store
+func WithUsers(ctx context.Context, users []models.User) context.Context {
+	return context.WithValue(ctx, usersKey{}, users)
+}
+
+// usersFromContext returns the users set by WithUsers, and whether they were set
+func usersFromContext(ctx context.Context) ([]models.User, bool) {
+	users, ok := ctx.Value(usersKey{}).([]models.User)
+	return users, ok
+}
+
+// seedKey is the context key of the sources seeded by WithSeed
+type seedKey struct{}
+
+// sources are the random sources of each kind of generated item
+type sources struct {
+	user, file, workspace, action *rand.Rand
+	// ids draws the IDs and secrets of the rows of a cycle, see IDReader
+	ids   *rand.Rand
+	idsMu sync.Mutex
+}
+
+// newSources seeds the sources of every kind from seed
+func newSources(seed int64) *sources {
+	return &sources{
+		user:      rand.New(rand.NewSource(seed)),
+		file:      rand.New(rand.NewSource(seed + 1)),
+		workspace: rand.New(rand.NewSource(seed + 2)),
+		action:    rand.New(rand.NewSource(seed + 3)),
+		ids:       rand.New(rand.NewSource(seed + 4)),
+	}
+}
+
+// Read fills p from the ids source
+func (s *sources) Read(p []byte) (int, error) {
+	s.idsMu.Lock()
+	defer s.idsMu.Unlock()
+	return s.ids.Read(p)
+}
+
+// IDReader returns the reader the UUIDs and passwords of the rows of a cycle are drawn
+// from: a source seeded by WithSeed, so a cycle started again with its seed draws the
+// same bytes, or crypto/rand outside a seeded context. Row UUIDs must still be named
+// within their cycle, as a replay saves its rows next to those of the original.
+func IDReader(ctx context.Context) io.Reader {
+	if src, ok := ctx.Value(seedKey{}).(*sources); ok {
+		return src
+	}
+	return cryptorand.Reader
+}
+
+// WithSeed returns a context under which the on-demand generation methods draw from
+// sources of their own seeded with seed, instead of those of the generator, so the
+// same calls under the same seed generate the same items. The sources advance with
+// every call made under the context.
+func WithSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, seedKey{}, newSources(seed))
+}
@@ -0,0 +1,127 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/songvi/robo/models"
+)
+
+// archiveInnerExtensions are the generators reused for files bundled inside an archive
+var archiveInnerExtensions = []string{"txt", "csv", "json", "xml", "md"}
+
+// generateInnerFiles generates 2 to 6 files in dir whose sizes add up to roughly targetSize
+func (g *FileContentGenerator) generateInnerFiles(dir string, lang Language, targetSize int) ([]string, error) {
+	numFiles := 2 + g.rng.Intn(5)
+	inner := NewFileContentGenerator(dir, g.rng)
+
+	var paths []string
+	for i := 0; i < numFiles; i++ {
+		f := &models.File{
+			Name:          fmt.Sprintf("%s %d", GenerateFilename(g.rng, []string{lang.draw(g.rng)}), i+1),
+			FileExtension: archiveInnerExtensions[g.rng.Intn(len(archiveInnerExtensions))],
+			FileSize:      targetSize / numFiles,
+		}
+		if err := inner.GenerateContent(f, lang); err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(dir, LocalName(f)))
+	}
+	return paths, nil
+}
+
+// generateArchive bundles generated inner files into a zip or tar.gz archive at fullPath.
+// The uncompressed size of the inner files approximates targetSize.
+func (g *FileContentGenerator) generateArchive(fullPath, format string, lang Language, targetSize int) error {
+	dir, err := os.MkdirTemp("", "robo-archive-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := g.generateInnerFiles(dir, lang, targetSize)
+	if err != nil {
+		return fmt.Errorf("failed to generate archive entries: %v", err)
+	}
+
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %v", err)
+	}
+	defer out.Close()
+
+	switch format {
+	case "zip":
+		return writeZip(out, paths)
+	case "tar.gz":
+		return writeTarGz(out, paths)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+// writeZip writes the given files into a zip archive
+func writeZip(w io.Writer, paths []string) error {
+	zw := zip.NewWriter(w)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Method = zip.Deflate
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(entry, path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz writes the given files into a gzip-compressed tar archive
+func writeTarGz(w io.Writer, paths []string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := copyFile(tw, path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// copyFile streams the file at path into w
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,286 @@
+package file
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3" // Registers the sqlite3 driver for generated databases
+)
+
+// generateBinary produces a file of the given binary format whose size is about
+// targetSize: a valid header with the format's magic number followed by structured
+// or random payload
+func generateBinary(rng *rand.Rand, format string, targetSize int) []byte {
+	switch format {
+	case "mp3":
+		return generateMP3(rng, targetSize)
+	case "mp4":
+		return generateMP4(rng, targetSize)
+	case "exe":
+		return generatePE(rng, targetSize)
+	case "elf":
+		return generateELF(rng, targetSize)
+	case "pcap":
+		return generatePCAP(rng, targetSize)
+	}
+	return nil
+}
+
+// randomBytes returns n random bytes
+func randomBytes(rng *rand.Rand, n int) []byte {
+	b := make([]byte, max(0, n))
+	rng.Read(b)
+	return b
+}
+
+// generateMP3 writes an ID3v2.3 tag followed by MPEG-1 Layer III frames at 128 kbit/s
+// and 44.1 kHz. Frame payloads are random, so decoders play noise at worst.
+func generateMP3(rng *rand.Rand, targetSize int) []byte {
+	var buf bytes.Buffer
+	// ID3v2.3 header with a single title frame; the tag size is syncsafe
+	title := append([]byte{0}, []byte(fmt.Sprintf("Track %d", 1+rng.Intn(99)))...)
+	frame := append([]byte("TIT2"), 0, 0, 0, byte(len(title)), 0, 0)
+	frame = append(frame, title...)
+	buf.WriteString("ID3\x03\x00\x00")
+	size := len(frame)
+	buf.Write([]byte{byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)})
+	buf.Write(frame)
+
+	for buf.Len() < targetSize {
+		// 144 * 128000 / 44100 = 417 bytes per frame, plus one when padded
+		padded := rng.Intn(3) == 0
+		length := 417
+		header := []byte{0xFF, 0xFB, 0x90, 0x64}
+		if padded {
+			length++
+			header[2] |= 0x02
+		}
+		buf.Write(header)
+		buf.Write(randomBytes(rng, length-len(header)))
+	}
+	return buf.Bytes()
+}
+
+// mp4Box encodes an ISO base media box
+func mp4Box(kind string, payload []byte) []byte {
+	box := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(box, uint32(8+len(payload)))
+	copy(box[4:], kind)
+	return append(box, payload...)
+}
+
+// generateMP4 writes an ISO base media file: an ftyp box naming the isom and mp42
+// brands, a movie header and an mdat box of random media data
+func generateMP4(rng *rand.Rand, targetSize int) []byte {
+	ftyp := mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41mp42"))
+
+	// Version 0 mvhd: times, timescale, duration, rate 1.0, volume 1.0, identity matrix
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], uint32(1000*(1+rng.Intn(600))))
+	binary.BigEndian.PutUint32(mvhd[20:], 0x00010000)
+	binary.BigEndian.PutUint16(mvhd[24:], 0x0100)
+	for i, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		binary.BigEndian.PutUint32(mvhd[36+4*i:], v)
+	}
+	binary.BigEndian.PutUint32(mvhd[96:], 2)
+	moov := mp4Box("moov", mp4Box("mvhd", mvhd))
+
+	data := append(ftyp, moov...)
+	return append(data, mp4Box("mdat", randomBytes(rng, targetSize-len(data)-8))...)
+}
+
+// generatePE writes a Windows PE32+ executable header: the MZ DOS stub, the PE
+// signature, a COFF header for x86-64, an optional header and one .text section
+// holding the random body
+func generatePE(rng *rand.Rand, targetSize int) []byte {
+	const (
+		peOffset       = 0x80
+		optionalSize   = 240
+		sectionsOffset = peOffset + 24 + optionalSize
+		headersSize    = 0x400
+	)
+	bodySize := max(0x200, (targetSize-headersSize+0x1ff)&^0x1ff)
+	data := make([]byte, headersSize, headersSize+bodySize)
+
+	copy(data, "MZ")
+	binary.LittleEndian.PutUint16(data[2:], 0x90)
+	binary.LittleEndian.PutUint32(data[0x3c:], peOffset)
+	copy(data[0x40:], "\x0e\x1f\xba\x0e\x00\xb4\x09\xcd\x21\xb8\x01\x4c\xcd\x21This program cannot be run in DOS mode.\r\r\n$")
+
+	pe := data[peOffset:]
+	copy(pe, "PE\x00\x00")
+	binary.LittleEndian.PutUint16(pe[4:], 0x8664)                           // Machine: x86-64
+	binary.LittleEndian.PutUint16(pe[6:], 1)                                // One section
+	binary.LittleEndian.PutUint32(pe[8:], uint32(1600000000+rng.Intn(1e8))) // Timestamp
+	binary.LittleEndian.PutUint16(pe[20:], optionalSize)
+	binary.LittleEndian.PutUint16(pe[22:], 0x0022) // Executable, large address aware
+
+	opt := pe[24:]
+	binary.LittleEndian.PutUint16(opt, 0x20b) // PE32+
+	binary.LittleEndian.PutUint32(opt[4:], uint32(bodySize))
+	binary.LittleEndian.PutUint32(opt[16:], 0x1000) // Entry point
+	binary.LittleEndian.PutUint32(opt[20:], 0x1000)
+	binary.LittleEndian.PutUint64(opt[24:], 0x140000000) // Image base
+	binary.LittleEndian.PutUint32(opt[32:], 0x1000)      // Section alignment
+	binary.LittleEndian.PutUint32(opt[36:], 0x200)       // File alignment
+	binary.LittleEndian.PutUint16(opt[40:], 6)
+	binary.LittleEndian.PutUint16(opt[48:], 6)
+	binary.LittleEndian.PutUint32(opt[56:], uint32(0x1000+(bodySize+0xfff)&^0xfff)) // Image size
+	binary.LittleEndian.PutUint32(opt[60:], headersSize)
+	binary.LittleEndian.PutUint16(opt[68:], 3)        // Console subsystem
+	binary.LittleEndian.PutUint64(opt[72:], 0x100000) // Stack reserve and commit, heap reserve and commit
+	binary.LittleEndian.PutUint64(opt[80:], 0x1000)
+	binary.LittleEndian.PutUint64(opt[88:], 0x100000)
+	binary.LittleEndian.PutUint64(opt[96:], 0x1000)
+	binary.LittleEndian.PutUint32(opt[108:], 16)
+
+	section := data[sectionsOffset:]
+	copy(section, ".text")
+	binary.LittleEndian.PutUint32(section[8:], uint32(bodySize))
+	binary.LittleEndian.PutUint32(section[12:], 0x1000)
+	binary.LittleEndian.PutUint32(section[16:], uint32(bodySize))
+	binary.LittleEndian.PutUint32(section[20:], headersSize)
+	binary.LittleEndian.PutUint32(section[36:], 0x60000020) // Code, execute, read
+
+	return append(data, randomBytes(rng, bodySize)...)
+}
+
+// generateELF writes a 64-bit little-endian x86-64 ELF executable header with one
+// loadable segment covering the random body
+func generateELF(rng *rand.Rand, targetSize int) []byte {
+	const headerSize, phSize = 64, 56
+	const base = 0x400000
+	size := max(headerSize+phSize+16, targetSize)
+	data := make([]byte, headerSize+phSize, size)
+
+	copy(data, "\x7fELF\x02\x01\x01")              // 64-bit, little-endian, version 1, System V ABI
+	binary.LittleEndian.PutUint16(data[16:], 2)    // ET_EXEC
+	binary.LittleEndian.PutUint16(data[18:], 0x3e) // x86-64
+	binary.LittleEndian.PutUint32(data[20:], 1)
+	binary.LittleEndian.PutUint64(data[24:], base+headerSize+phSize) // Entry point
+	binary.LittleEndian.PutUint64(data[32:], headerSize)             // Program headers
+	binary.LittleEndian.PutUint16(data[52:], headerSize)
+	binary.LittleEndian.PutUint16(data[54:], phSize)
+	binary.LittleEndian.PutUint16(data[56:], 1)
+	binary.LittleEndian.PutUint16(data[58:], 64) // Section header entry size, no sections
+
+	ph := data[headerSize:]
+	binary.LittleEndian.PutUint32(ph, 1)     // PT_LOAD
+	binary.LittleEndian.PutUint32(ph[4:], 5) // Read and execute
+	binary.LittleEndian.PutUint64(ph[16:], base)
+	binary.LittleEndian.PutUint64(ph[24:], base)
+	binary.LittleEndian.PutUint64(ph[32:], uint64(size))
+	binary.LittleEndian.PutUint64(ph[40:], uint64(size))
+	binary.LittleEndian.PutUint64(ph[48:], 0x1000)
+
+	return append(data, randomBytes(rng, size-len(data))...)
+}
+
+// ipv4Checksum computes the IPv4 header checksum
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// generatePCAP writes a libpcap capture of Ethernet/IPv4/UDP packets between hosts
+// of a private network, with random payloads and increasing timestamps
+func generatePCAP(rng *rand.Rand, targetSize int) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535) // Snapshot length
+	binary.LittleEndian.PutUint32(header[20:], 1)     // Ethernet
+	buf.Write(header)
+
+	seconds := uint32(1600000000 + rng.Intn(1e8))
+	micros := uint32(0)
+	for buf.Len() < targetSize {
+		payload := randomBytes(rng, 16+rng.Intn(1200))
+		packet := make([]byte, 14+20+8, 14+20+8+len(payload))
+		// Ethernet: locally administered MACs, IPv4 ethertype
+		copy(packet, []byte{0x02, 0, 0, 0, 0, byte(1 + rng.Intn(254)), 0x02, 0, 0, 0, 0, byte(1 + rng.Intn(254)), 0x08, 0x00})
+		ip := packet[14:34]
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+8+len(payload)))
+		binary.BigEndian.PutUint16(ip[4:], uint16(rng.Intn(65536)))
+		ip[8] = 64
+		ip[9] = 17 // UDP
+		copy(ip[12:], []byte{10, 0, byte(rng.Intn(4)), byte(1 + rng.Intn(254))})
+		copy(ip[16:], []byte{10, 0, byte(rng.Intn(4)), byte(1 + rng.Intn(254))})
+		binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip))
+		udp := packet[34:42]
+		binary.BigEndian.PutUint16(udp, uint16(1024+rng.Intn(60000)))
+		binary.BigEndian.PutUint16(udp[2:], []uint16{53, 123, 443, 5353}[rng.Intn(4)])
+		binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+		packet = append(packet, payload...)
+
+		micros += uint32(rng.Intn(200000))
+		seconds += micros / 1000000
+		micros %= 1000000
+		record := make([]byte, 16)
+		binary.LittleEndian.PutUint32(record, seconds)
+		binary.LittleEndian.PutUint32(record[4:], micros)
+		binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+		binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+		buf.Write(record)
+		buf.Write(packet)
+	}
+	return buf.Bytes()
+}
+
+// generateSQLite creates a SQLite database at fullPath with a table of generated
+// records in lang, inserting rows until the file reaches about targetSize
+func generateSQLite(fullPath string, rng *rand.Rand, lang Language, targetSize int) error {
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db, err := sql.Open("sqlite3", fullPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE records (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		amount REAL,
+		note TEXT
+	)`); err != nil {
+		return err
+	}
+	const batch = 100
+	for size := 0; size < targetSize; {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < batch; i++ {
+			name := GenerateFolderName(rng, lang.draw(rng))
+			note := generateSentence(rng, lang.draw(rng))
+			if _, err := tx.Exec(`INSERT INTO records (name, created_at, amount, note) VALUES (?, ?, ?, ?)`,
+				name, randomDate(rng).Format("2006-01-02 15:04:05"), float64(rng.Intn(1000000))/100, note); err != nil {
+				tx.Rollback()
+				return err
+			}
+			size += len(name) + len(note) + 32
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,46 +2,74 @@ package file
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/jpeg"
-	"image/png"
-	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/jung-kurt/gofpdf"
 	"github.com/songvi/robo/models"
-	"github.com/unidoc/unioffice/document"
-	"github.com/xuri/excelize/v2"
 )
 
 // FileContentGenerator generates file content based on extension and size
 type FileContentGenerator struct {
-	RepositoryPath string // Base directory for storing files
+	RepositoryPath string           // Base directory for storing files
+	Cache          *CorpusCache     // Optional sentence cache for large text documents
+	Store          FileStore        // Optional destination for generated files; nil keeps them in RepositoryPath
+	KeepLocal      bool             // Keep the local copy of files handed to a remote Store
+	Quota          *RepositoryQuota // Optional disk quota evicting the oldest local files
+	// Templates render documents of their extension instead of random prose, for the
+	// share of files given by TemplateRatio (0 means every file)
+	Templates     map[string][]*ContentTemplate
+	TemplateRatio float64
+	Fonts         *FontSet // PDF fonts per script; nil only handles Latin text
+	// DryRun fills in file metadata and a pseudo ContentID without writing anything
+	DryRun bool
+	// CycleID is stamped on the files generated, which are then staged and stored in
+	// the namespace of that cycle
+	CycleID string
+	rng     *rand.Rand
+	sources *duplicateSources // Generated files per extension, used as duplicate sources
+	names   *nameRegistry     // File names handed out, shared by forks
 }
 
-// NewFileContentGenerator initializes a new FileContentGenerator
-func NewFileContentGenerator(repositoryPath string) *FileContentGenerator {
+// NewFileContentGenerator initializes a new FileContentGenerator drawing randomness from rng
+func NewFileContentGenerator(repositoryPath string, rng *rand.Rand) *FileContentGenerator {
 	return &FileContentGenerator{
 		RepositoryPath: repositoryPath,
+		rng:            rng,
+		sources:        &duplicateSources{byExt: make(map[string][]string), planned: make(map[string][]plannedSource)},
+		names:          &nameRegistry{used: make(map[string]bool)},
 	}
 }
 
-// GenerateSentence generates a rich sentence in the specified language, defaulting to English
-func generateSentence(lang string) string {
+// Fork returns a generator drawing from rng that shares the configuration, quota and
+// duplicate sources of g, so forks can generate files concurrently
+func (g *FileContentGenerator) Fork(rng *rand.Rand) *FileContentGenerator {
+	fork := *g
+	fork.rng = rng
+	return &fork
+}
+
+// GenerateSentence generates a rich sentence in the specified language, defaulting to English.
+// Languages with a registered Markov model draw from their corpus instead of the templates.
+func generateSentence(rng *rand.Rand, lang string) string {
+	if model := markovModel(lang); model != nil {
+		return model.Sentence(rng)
+	}
+
 	type sentencePattern struct {
-		subjects   []string
-		verbs      []string
-		objects    []string
-		adjectives []string
-		adverbs    []string
-		connectors []string
-		isSOV      bool
+		subjects       []string
+		verbs          []string
+		objects        []string
+		adjectives     []string
+		adverbs        []string
+		connectors     []string
+		isSOV          bool
+		adjectiveAfter bool   // Adjectives follow the noun they qualify
+		unspaced       bool   // Words within a clause are written without spaces
+		terminator     string // Sentence-ending punctuation
 	}
 
 	patterns := map[string]sentencePattern{
@@ -53,6 +81,7 @@ func generateSentence(lang string) string {
 			adverbs:    []string{"beautifully", "gracefully", "silently", "boldly", "softly", "swiftly", "calmly", "elegantly"},
 			connectors: []string{"and", "while", "as", "under", "beneath", "across", "within", "beyond"},
 			isSOV:      false,
+			terminator: ".",
 		},
 		"cn": {
 			subjects:   []string{"天空", "森林", "鸟儿", "河流", "月亮", "孩子", "风", "山峰"},
@@ -62,6 +91,7 @@ func generateSentence(lang string) string {
 			adverbs:    []string{"美丽地", "优雅地", "静静地", "大胆地", "柔和地", "迅速地", "平静地", "高雅地"},
 			connectors: []string{"并且", "当", "如同", "在…之下", "在…下面", "穿过", "在…之中", "超越"},
 			isSOV:      false,
+			terminator: "。",
 		},
 		"kn": {
 			subjects:   []string{"하늘", "숲", "새", "강", "달", "아이", "바람", "산"},
@@ -71,6 +101,7 @@ func generateSentence(lang string) string {
 			adverbs:    []string{"아름답게", "우아하게", "조용히", "대담하게", "부드럽게", "빠르게", "차분히", "고상하게"},
 			connectors: []string{"그리고", "하면서", "처럼", "아래", "밑에", "건너", "안에", "넘어"},
 			isSOV:      true,
+			terminator: ".",
 		},
 		"tl": {
 			subjects:   []string{"Ang langit", "Ang gubat", "Isang ibon", "Ang ilog", "Ang buwan", "Isang bata", "Ang hangin", "Ang bundok"},
@@ -80,6 +111,7 @@ func generateSentence(lang string) string {
 			adverbs:    []string{"nang maganda", "nang magaan", "nang tahimik", "nang matapang", "nang malambot", "nang mabilis", "nang kalmado", "nang elegante"},
 			connectors: []string{"at", "habang", "tulad ng", "sa ilalim", "sa baba", "sa kabila", "sa loob", "lampas sa"},
 			isSOV:      false,
+			terminator: ".",
 		},
 		"jp": {
 			subjects:   []string{"空", "森", "鳥", "川", "月", "子", "風", "山"},
@@ -89,6 +121,7 @@ func generateSentence(lang string) string {
 			adverbs:    []string{"美しく", "優雅に", "静かに", "大胆に", "柔らかく", "速く", "穏やかに", "上品に"},
 			connectors: []string{"そして", "ながら", "ように", "下で", "下に", "越えて", "中に", "超えて"},
 			isSOV:      true,
+			terminator: "。",
 		},
 		"ar": {
 			subjects:   []string{"السماء", "الغابة", "طائر", "النهر", "القمر", "طفل", "الريح", "الجبل"},
@@ -98,6 +131,71 @@ func generateSentence(lang string) string {
 			adverbs:    []string{"بجمال", "بأناقة", "بهدوء", "بجرأة", "بلطف", "بسرعة", "بهدوء", "بأناقة"},
 			connectors: []string{"و", "بينما", "كما", "تحت", "أسفل", "عبر", "داخل", "وراء"},
 			isSOV:      false,
+			terminator: ".",
+		},
+		"ru": { // Masculine subjects so the adjective forms agree
+			subjects:   []string{"лес", "ветер", "месяц", "ручей", "ребёнок", "город", "сад", "дождь"},
+			verbs:      []string{"поёт", "танцует", "течёт", "сияет", "шепчет", "поднимается", "парит", "отдыхает"},
+			objects:    []string{"сладкую мелодию", "сквозь деревья", "под звёздами", "ночью", "с изяществом", "к звёздам", "над рекой", "под солнцем"},
+			adjectives: []string{"тихий", "яркий", "спокойный", "величественный", "нежный", "живой", "молчаливый", "сверкающий"},
+			adverbs:    []string{"красиво", "изящно", "тихо", "смело", "мягко", "быстро", "спокойно", "элегантно"},
+			connectors: []string{"и", "пока", "как", "когда", "а", "но", "хотя", "потому что"},
+			isSOV:      false,
+			terminator: ".",
+		},
+		"hi": { // Masculine subjects so the verb forms agree
+			subjects:   []string{"आकाश", "जंगल", "पक्षी", "चाँद", "बच्चा", "पहाड़", "बादल", "समुद्र"},
+			verbs:      []string{"गाता है", "नाचता है", "बहता है", "चमकता है", "फुसफुसाता है", "चढ़ता है", "उड़ता है", "आराम करता है"},
+			objects:    []string{"मीठा गीत", "पेड़ों के बीच", "रात में", "सितारों की ओर", "सूरज के नीचे", "नदी के किनारे", "हवा में", "घाटी में"},
+			adjectives: []string{"शांत", "चमकदार", "सुंदर", "विशाल", "कोमल", "जीवंत", "मौन", "उज्ज्वल"},
+			adverbs:    []string{"सुंदरता से", "धीरे से", "चुपचाप", "साहस से", "कोमलता से", "तेज़ी से", "शांति से", "आराम से"},
+			connectors: []string{"और", "जबकि", "जैसे", "जब", "लेकिन", "क्योंकि", "तभी", "फिर"},
+			isSOV:      true,
+			terminator: "।",
+		},
+		"he": { // Masculine subjects; adjectives carry the definite article of their noun
+			subjects:       []string{"היער", "הנהר", "הירח", "הילד", "ההר", "הים", "העץ", "הענן"},
+			verbs:          []string{"שר", "רוקד", "זורם", "זורח", "לוחש", "מטפס", "ממריא", "נח"},
+			objects:        []string{"מנגינה מתוקה", "בין העצים", "בלילה", "אל הכוכבים", "תחת השמש", "מעל העמק", "ליד המים", "בין ההרים"},
+			adjectives:     []string{"השקט", "הזוהר", "השליו", "המלכותי", "העדין", "התוסס", "הדומם", "הנוצץ"},
+			adverbs:        []string{"ביופי", "בחן", "בשקט", "באומץ", "ברכות", "במהירות", "ברוגע", "באלגנטיות"},
+			connectors:     []string{"כאשר", "בעוד", "כמו", "אך", "ואילו", "כי", "אחרי ש", "לפני ש"},
+			isSOV:          false,
+			adjectiveAfter: true,
+			terminator:     ".",
+		},
+		"th": { // Spaces only separate clauses and no terminal punctuation is written
+			subjects:       []string{"ท้องฟ้า", "ป่า", "นก", "แม่น้ำ", "ดวงจันทร์", "เด็ก", "ลม", "ภูเขา"},
+			verbs:          []string{"ร้องเพลง", "เต้นรำ", "ไหล", "ส่องแสง", "กระซิบ", "ปีน", "บิน", "พักผ่อน"},
+			objects:        []string{"ทำนองอันไพเราะ", "ผ่านต้นไม้", "ในยามค่ำคืน", "สู่ดวงดาว", "ใต้แสงอาทิตย์", "ริมน้ำ", "บนยอดเขา", "กลางทุ่ง"},
+			adjectives:     []string{"ที่เงียบสงบ", "ที่สดใส", "ที่สงบ", "ที่ยิ่งใหญ่", "ที่อ่อนโยน", "ที่มีชีวิตชีวา", "ที่เงียบ", "ที่ระยิบระยับ"},
+			adverbs:        []string{"อย่างสวยงาม", "อย่างนุ่มนวล", "อย่างเงียบๆ", "อย่างกล้าหาญ", "อย่างอ่อนโยน", "อย่างรวดเร็ว", "อย่างใจเย็น", "อย่างสง่างาม"},
+			connectors:     []string{"และ", "ขณะที่", "เหมือน", "เมื่อ", "แต่", "เพราะ", "แล้ว", "ส่วน"},
+			isSOV:          false,
+			adjectiveAfter: true,
+			unspaced:       true,
+		},
+		"es": { // Gender-invariable adjectives agree with every subject
+			subjects:       []string{"El cielo", "El bosque", "Un pájaro", "El río", "La luna", "Un niño", "El viento", "La montaña"},
+			verbs:          []string{"canta", "baila", "fluye", "brilla", "susurra", "sube", "vuela", "descansa"},
+			objects:        []string{"una dulce melodía", "entre los árboles", "en la noche", "hacia las estrellas", "bajo el sol", "junto al agua", "sobre el valle", "en paz"},
+			adjectives:     []string{"radiante", "alegre", "suave", "brillante", "grande", "verde", "libre", "imponente"},
+			adverbs:        []string{"bellamente", "con elegancia", "en silencio", "con valentía", "dulcemente", "rápidamente", "con calma", "elegantemente"},
+			connectors:     []string{"y", "mientras", "como", "cuando", "pero", "aunque", "porque", "donde"},
+			isSOV:          false,
+			adjectiveAfter: true,
+			terminator:     ".",
+		},
+		"pt": { // Gender-invariable adjectives agree with every subject
+			subjects:       []string{"O céu", "A floresta", "Um pássaro", "O rio", "A lua", "Uma criança", "O vento", "A montanha"},
+			verbs:          []string{"canta", "dança", "flui", "brilha", "sussurra", "sobe", "voa", "descansa"},
+			objects:        []string{"uma doce melodia", "entre as árvores", "à noite", "rumo às estrelas", "sob o sol", "junto à água", "sobre o vale", "em paz"},
+			adjectives:     []string{"radiante", "alegre", "suave", "brilhante", "grande", "verde", "livre", "imponente"},
+			adverbs:        []string{"lindamente", "com elegância", "em silêncio", "com coragem", "docemente", "rapidamente", "com calma", "elegantemente"},
+			connectors:     []string{"e", "enquanto", "como", "quando", "mas", "embora", "porque", "onde"},
+			isSOV:          false,
+			adjectiveAfter: true,
+			terminator:     ".",
 		},
 	}
 
@@ -105,76 +203,101 @@ func generateSentence(lang string) string {
 	if !exists {
 		pattern = patterns["en"]
 	}
+	separator := " "
+	if pattern.unspaced {
+		separator = ""
+	}
 
-	hasAdjective := rand.Float32() < 0.7
-	hasAdverb := rand.Float32() < 0.6
-	hasConnector := rand.Float32() < 0.4
+	// clause orders subject, verb, object and the optional modifiers for the language
+	clause := func(adjective, adverb string) string {
+		subject := pattern.subjects[rng.Intn(len(pattern.subjects))]
+		verb := pattern.verbs[rng.Intn(len(pattern.verbs))]
+		object := pattern.objects[rng.Intn(len(pattern.objects))]
+
+		var words []string
+		if adjective != "" && !pattern.adjectiveAfter {
+			words = append(words, adjective)
+		}
+		words = append(words, subject)
+		if adjective != "" && pattern.adjectiveAfter {
+			words = append(words, adjective)
+		}
+		if pattern.isSOV {
+			// Verb-final languages place the adverb before the verb
+			words = append(words, object)
+			if adverb != "" {
+				words = append(words, adverb)
+			}
+			words = append(words, verb)
+		} else {
+			words = append(words, verb, object)
+			if adverb != "" {
+				words = append(words, adverb)
+			}
+		}
+		return strings.Join(words, separator)
+	}
 
-	subject := pattern.subjects[rand.Intn(len(pattern.subjects))]
-	verb := pattern.verbs[rand.Intn(len(pattern.verbs))]
-	object := pattern.objects[rand.Intn(len(pattern.objects))]
 	var adjective, adverb string
+	hasAdjective := rng.Float32() < 0.7
+	hasAdverb := rng.Float32() < 0.6
+	hasConnector := rng.Float32() < 0.4
 	if hasAdjective {
-		adjective = pattern.adjectives[rand.Intn(len(pattern.adjectives))] + " "
+		adjective = pattern.adjectives[rng.Intn(len(pattern.adjectives))]
 	}
 	if hasAdverb {
-		adverb = pattern.adverbs[rand.Intn(len(pattern.adverbs))]
-	}
-
-	var sentence string
-	if pattern.isSOV {
-		sentence = fmt.Sprintf("%s%s %s %s", adjective, subject, object, verb)
-		if hasAdverb {
-			sentence += " " + adverb
-		}
-	} else {
-		sentence = fmt.Sprintf("%s%s %s %s", adjective, subject, verb, object)
-		if hasAdverb {
-			sentence += " " + adverb
-		}
+		adverb = pattern.adverbs[rng.Intn(len(pattern.adverbs))]
 	}
+	sentence := clause(adjective, adverb)
 
 	if hasConnector {
-		connector := pattern.connectors[rand.Intn(len(pattern.connectors))]
-		subject2 := pattern.subjects[rand.Intn(len(pattern.subjects))]
-		verb2 := pattern.verbs[rand.Intn(len(pattern.verbs))]
-		object2 := pattern.objects[rand.Intn(len(pattern.objects))]
-		hasAdjective2 := rand.Float32() < 0.5
+		connector := pattern.connectors[rng.Intn(len(pattern.connectors))]
 		var adjective2 string
-		if hasAdjective2 {
-			adjective2 = pattern.adjectives[rand.Intn(len(pattern.adjectives))] + " "
+		if rng.Float32() < 0.5 {
+			adjective2 = pattern.adjectives[rng.Intn(len(pattern.adjectives))]
 		}
-
-		var clause string
-		if pattern.isSOV {
-			clause = fmt.Sprintf("%s%s %s %s", adjective2, subject2, object2, verb2)
-		} else {
-			clause = fmt.Sprintf("%s%s %s %s", adjective2, subject2, verb2, object2)
-		}
-		sentence += fmt.Sprintf(" %s %s", connector, clause)
+		sentence += fmt.Sprintf(" %s %s", connector, clause(adjective2, ""))
 	}
 
-	if lang == "cn" || lang == "jp" {
-		sentence += "。"
-	} else if lang == "ar" {
-		sentence += "."
-	} else {
-		sentence += "."
-	}
+	// Capitalize the first letter for scripts with letter case
+	first, size := utf8.DecodeRuneInString(sentence)
+	sentence = string(unicode.ToUpper(first)) + sentence[size:]
 
-	return sentence
+	return sentence + pattern.terminator
+}
+
+// textLines returns roughly targetSize bytes of sentences, served from the corpus cache when configured
+func (g *FileContentGenerator) textLines(lang Language, targetSize int) ([]string, error) {
+	if g.Cache != nil {
+		return g.Cache.Lines(g.rng, lang, targetSize)
+	}
+	var lines []string
+	size := 0
+	for size < targetSize {
+		line := generateSentence(g.rng, lang.draw(g.rng))
+		lines = append(lines, line)
+		size += len(line) + 1
+	}
+	return lines, nil
 }
 
 // GenerateContent generates file content and saves it to the repository
-func (g *FileContentGenerator) GenerateContent(file *models.File, lang string) error {
-	rand.Seed(time.Now().UnixNano())
+func (g *FileContentGenerator) GenerateContent(file *models.File, lang Language) error {
+	if g.DryRun {
+		g.plan(file)
+		return nil
+	}
 
 	// Create the full file path in the repository
-	fullPath := filepath.Join(g.RepositoryPath, file.Name+"."+file.FileExtension)
+	fullPath := g.LocalPath(file)
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %v", err)
 	}
 
+	if t := g.template(strings.ToLower(file.FileExtension)); t != nil {
+		return g.generateFromTemplate(file, t, lang, fullPath)
+	}
+
 	switch strings.ToLower(file.FileExtension) {
 	case "txt":
 		var content strings.Builder
@@ -186,8 +309,12 @@ func (g *FileContentGenerator) GenerateContent(file *models.File, lang string) e
 			targetSize = 5 * 1024 * 1024
 		}
 
-		for content.Len() < targetSize {
-			content.WriteString(generateSentence(lang) + "\n")
+		lines, err := g.textLines(lang, targetSize)
+		if err != nil {
+			return fmt.Errorf("failed to generate text: %v", err)
+		}
+		for _, line := range lines {
+			content.WriteString(line + "\n")
 		}
 		contentStr := content.String()
 		if len(contentStr) > targetSize {
@@ -199,120 +326,123 @@ func (g *FileContentGenerator) GenerateContent(file *models.File, lang string) e
 		}
 		file.FileContent = "Generated text content"
 
-	case "pdf":
-		// Generate PDF with non-Latin text
-		pdf := gofpdf.New("P", "mm", "A4", "")
-		const fontFile = "/tmp/NotoSans-Regular.ttf"
-		if _, err := os.Stat(fontFile); os.IsNotExist(err) {
-			log.Printf("Font file %s not found", fontFile)
-			return fmt.Errorf("font file %s not found", fontFile)
+	case "csv", "json", "xml", "md", "eml", "ics":
+		targetSize := clampSize(file.FileSize, 1024, 5*1024*1024)
+		var data []byte
+		var err error
+		switch strings.ToLower(file.FileExtension) {
+		case "csv":
+			data, err = generateCSV(g.rng, lang, targetSize)
+		case "json":
+			data, err = generateJSON(g.rng, lang, targetSize)
+		case "xml":
+			data, err = generateXML(g.rng, lang, targetSize)
+		case "md":
+			data = generateMarkdown(g.rng, lang, targetSize)
+		case "eml":
+			data, err = generateEML(g.rng, lang, targetSize)
+		case "ics":
+			data = generateICS(g.rng, lang, targetSize)
 		}
-		pdf.AddUTF8Font("NotoSans", "", fontFile)
-		pdf.AddPage()
-		pdf.SetFont("NotoSans", "", 12)
-		if pdf.Err() {
-			log.Printf("Failed to set font for PDF: %v", pdf.Error())
-			return fmt.Errorf("failed to set font for PDF: %v", pdf.Error())
+		if err != nil {
+			return fmt.Errorf("failed to generate %s content: %v", file.FileExtension, err)
 		}
 
-		targetSize := file.FileSize
-		if targetSize < 1024 {
-			targetSize = 1024
-		}
-		if targetSize > 5*1024*1024 {
-			targetSize = 5 * 1024 * 1024
+		if err := os.WriteFile(fullPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s file: %v", file.FileExtension, err)
 		}
+		file.FileContent = fmt.Sprintf("Generated %s content", strings.ToUpper(file.FileExtension))
 
-		for i := 0; pdf.GetY() < 270 && i*len(generateSentence(lang)) < targetSize; i++ {
-			pdf.Write(5, generateSentence(lang)+"\n")
-			if pdf.Err() {
-				log.Printf("PDF write error: %v", pdf.Error())
-				return fmt.Errorf("failed to write to PDF: %v", pdf.Error())
-			}
+	case "zip", "tar.gz":
+		targetSize := clampSize(file.FileSize, 4096, 50*1024*1024)
+		if err := g.generateArchive(fullPath, strings.ToLower(file.FileExtension), lang, targetSize); err != nil {
+			return fmt.Errorf("failed to write %s file: %v", file.FileExtension, err)
 		}
+		file.FileContent = "Generated archive content"
 
-		if err := pdf.OutputFileAndClose(fullPath); err != nil {
-			log.Printf("Failed to save PDF file: %v", err)
+	case "pdf":
+		// Each text run is written with the font configured for its script
+		fontSet := g.Fonts
+		if fontSet == nil {
+			fontSet = defaultFonts
+		}
+		targetSize := clampSize(file.FileSize, 1024, 50*1024*1024)
+		f, err := os.Create(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to create pdf file: %v", err)
+		}
+		defer f.Close()
+		if err := generatePDF(f, g.rng, lang, fontSet, targetSize); err != nil {
 			return fmt.Errorf("failed to write pdf file: %v", err)
 		}
 		file.FileContent = "Generated PDF content"
 
 	case "docx":
-		doc := document.New()
-		targetSize := file.FileSize
-		if targetSize < 1024 {
-			targetSize = 1024
-		}
-		if targetSize > 5*1024*1024 {
-			targetSize = 5 * 1024 * 1024
+		targetSize := clampSize(file.FileSize, 1024, 5*1024*1024)
+		lines, err := g.textLines(lang, targetSize)
+		if err != nil {
+			return fmt.Errorf("failed to generate text: %v", err)
 		}
-
-		for i := 0; i*len(generateSentence(lang)) < targetSize; i++ {
-			para := doc.AddParagraph()
-			para.AddRun().AddText(generateSentence(lang))
+		f, err := os.Create(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to create docx file: %v", err)
 		}
-
-		if err := doc.SaveToFile(fullPath); err != nil {
+		defer f.Close()
+		if err := writeDOCX(f, lines, lang); err != nil {
 			return fmt.Errorf("failed to write docx file: %v", err)
 		}
 		file.FileContent = "Generated DOCX content"
 
-	case "xlsx":
-		f := excelize.NewFile()
-		targetSize := file.FileSize
-		if targetSize < 1024 {
-			targetSize = 1024
+	case "pptx":
+		targetSize := clampSize(file.FileSize, 1024, 5*1024*1024)
+		f, err := os.Create(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to create pptx file: %v", err)
 		}
-		if targetSize > 5*1024*1024 {
-			targetSize = 5 * 1024 * 1024
+		defer f.Close()
+		if err := generatePPTX(f, g.rng, lang, targetSize); err != nil {
+			return fmt.Errorf("failed to write pptx file: %v", err)
 		}
+		file.FileContent = "Generated PPTX content"
 
-		for i := 1; i <= 100 && i*len(generateSentence(lang)) < targetSize; i++ {
-			cell := fmt.Sprintf("A%d", i)
-			f.SetCellValue("Sheet1", cell, generateSentence(lang))
+	case "xlsx":
+		targetSize := clampSize(file.FileSize, 4096, 50*1024*1024)
+		f, err := os.Create(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to create xlsx file: %v", err)
 		}
-
-		if err := f.SaveAs(fullPath); err != nil {
+		defer f.Close()
+		if err := generateXLSX(f, g.rng, lang, targetSize); err != nil {
 			return fmt.Errorf("failed to write xlsx file: %v", err)
 		}
 		file.FileContent = "Generated XLSX content"
 
-	case "jpeg", "png":
-		img := image.NewRGBA(image.Rect(0, 0, 100, 100))
-		draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{255, 0, 0, 255}}, image.Point{}, draw.Src)
-
-		targetSize := file.FileSize
-		if targetSize < 1024 {
-			targetSize = 1024
-		}
-		if targetSize > 5*1024*1024 {
-			targetSize = 5 * 1024 * 1024
-		}
-
-		f, err := os.Create(fullPath)
+	case "jpeg", "jpg", "png":
+		targetSize := clampSize(file.FileSize, 1024, 5*1024*1024)
+		format := strings.ToLower(file.FileExtension)
+		data, err := generateImage(g.rng, format, targetSize)
 		if err != nil {
-			return fmt.Errorf("failed to create image file: %v", err)
+			return fmt.Errorf("failed to encode %s image: %v", format, err)
 		}
-		defer f.Close()
+		if err := os.WriteFile(fullPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s file: %v", format, err)
+		}
+		file.FileContent = "Generated image content"
 
-		if file.FileExtension == "jpeg" {
-			if err := jpeg.Encode(f, img, &jpeg.Options{Quality: 75}); err != nil {
-				return fmt.Errorf("failed to write jpeg file: %v", err)
-			}
-		} else {
-			if err := png.Encode(f, img); err != nil {
-				return fmt.Errorf("failed to write png file: %v", err)
-			}
+	case "mp3", "mp4", "exe", "elf", "pcap":
+		targetSize := clampSize(file.FileSize, 1024, 1024*1024*1024)
+		format := strings.ToLower(file.FileExtension)
+		if err := os.WriteFile(fullPath, generateBinary(g.rng, format, targetSize), 0644); err != nil {
+			return fmt.Errorf("failed to write %s file: %v", format, err)
 		}
+		file.FileContent = fmt.Sprintf("Generated %s content", strings.ToUpper(format))
 
-		f.Seek(0, 2)
-		currentSize, _ := f.Seek(0, 1)
-		if int(currentSize) < targetSize {
-			padding := make([]byte, targetSize-int(currentSize))
-			rand.Read(padding)
-			f.Write(padding)
+	case "sqlite", "db":
+		targetSize := clampSize(file.FileSize, 8192, 100*1024*1024)
+		if err := generateSQLite(fullPath, g.rng, lang, targetSize); err != nil {
+			return fmt.Errorf("failed to write sqlite database: %v", err)
 		}
-		file.FileContent = "Generated image content"
+		file.FileContent = "Generated SQLite database"
 
 	case "bin":
 		targetSize := file.FileSize
@@ -324,7 +454,7 @@ func (g *FileContentGenerator) GenerateContent(file *models.File, lang string) e
 		}
 
 		data := make([]byte, targetSize)
-		rand.Read(data)
+		g.rng.Read(data)
 
 		if err := os.WriteFile(fullPath, data, 0644); err != nil {
 			return fmt.Errorf("failed to write bin file: %v", err)
@@ -335,5 +465,6 @@ func (g *FileContentGenerator) GenerateContent(file *models.File, lang string) e
 		return fmt.Errorf("unsupported file extension: %s", file.FileExtension)
 	}
 
+	g.remember(strings.ToLower(file.FileExtension), fullPath)
 	return nil
 }
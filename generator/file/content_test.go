@@ -0,0 +1,595 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/songvi/robo/models"
+)
+
+// generateTestFile runs GenerateContent for the given extension and returns the written bytes
+func generateTestFile(t *testing.T, ext string, size int, lang string) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(1)))
+	f := &models.File{Name: "sample", FileExtension: ext, FileSize: size}
+	require.NoError(t, g.GenerateContent(f, NewLanguage(lang)))
+	data, err := os.ReadFile(filepath.Join(dir, "sample."+ext))
+	require.NoError(t, err)
+	return data
+}
+
+func TestGenerateStructuredContent(t *testing.T) {
+	for _, lang := range []string{"en", "jp", "ar"} {
+		t.Run("csv/"+lang, func(t *testing.T) {
+			data := generateTestFile(t, "csv", 8192, lang)
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			require.NoError(t, err, "csv should parse")
+			assert.Greater(t, len(records), 1, "csv should contain data rows")
+			assert.GreaterOrEqual(t, len(data), 8192)
+		})
+
+		t.Run("json/"+lang, func(t *testing.T) {
+			data := generateTestFile(t, "json", 8192, lang)
+			var docs []map[string]any
+			require.NoError(t, json.Unmarshal(data, &docs), "json should parse")
+			assert.NotEmpty(t, docs)
+			assert.IsType(t, map[string]any{}, docs[0]["author"], "documents should be nested")
+		})
+
+		t.Run("xml/"+lang, func(t *testing.T) {
+			data := generateTestFile(t, "xml", 8192, lang)
+			var root xmlDocuments
+			require.NoError(t, xml.Unmarshal(data, &root), "xml should parse")
+			assert.NotEmpty(t, root.Documents)
+		})
+
+		t.Run("md/"+lang, func(t *testing.T) {
+			data := generateTestFile(t, "md", 8192, lang)
+			assert.True(t, bytes.HasPrefix(data, []byte("# ")), "markdown should start with a heading")
+			assert.GreaterOrEqual(t, len(data), 8192)
+		})
+	}
+}
+
+func TestGenerateArchiveContent(t *testing.T) {
+	t.Run("zip", func(t *testing.T) {
+		data := generateTestFile(t, "zip", 16384, "en")
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err, "zip should open")
+		assert.GreaterOrEqual(t, len(zr.File), 2, "zip should bundle several files")
+		total := 0
+		for _, f := range zr.File {
+			total += int(f.UncompressedSize64)
+		}
+		assert.InDelta(t, 16384, total, 16384*0.5, "inner files should approximate the requested size")
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		data := generateTestFile(t, "tar.gz", 16384, "vi")
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err, "gzip should open")
+		tr := tar.NewReader(gr)
+		entries := 0
+		for {
+			_, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			entries++
+		}
+		assert.GreaterOrEqual(t, entries, 2, "tar should bundle several files")
+	})
+}
+
+func TestCorpusCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	cache := NewCorpusCache(cacheDir, 0.1)
+	lines, err := cache.Lines(rand.New(rand.NewSource(1)), NewLanguage("en"), 10000)
+	require.NoError(t, err)
+	assert.NotEmpty(t, lines)
+
+	_, err = os.Stat(filepath.Join(cacheDir, "en-16384.zst"))
+	require.NoError(t, err, "corpus should be persisted compressed per language and size bucket")
+
+	// A fresh cache reuses the persisted corpus
+	reloaded := NewCorpusCache(cacheDir, 0)
+	reloadedLines, err := reloaded.Lines(rand.New(rand.NewSource(2)), NewLanguage("en"), 10000)
+	require.NoError(t, err)
+	corpus := reloaded.corpora["en-16384"]
+	for _, line := range reloadedLines {
+		assert.Contains(t, corpus, line, "with zero uniqueness every line comes from the cached corpus")
+	}
+}
+
+func TestGenerateMailContent(t *testing.T) {
+	t.Run("eml", func(t *testing.T) {
+		data := generateTestFile(t, "eml", 16384, "jp")
+		msg, err := mail.ReadMessage(bytes.NewReader(data))
+		require.NoError(t, err, "eml should parse as RFC 5322")
+		_, err = mail.ParseAddress(msg.Header.Get("From"))
+		assert.NoError(t, err, "From should be a valid address")
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/mixed", mediaType)
+		mr := multipart.NewReader(msg.Body, params["boundary"])
+		parts := 0
+		for {
+			_, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			parts++
+		}
+		assert.GreaterOrEqual(t, parts, 2, "message should contain a body and attachments")
+	})
+
+	t.Run("ics", func(t *testing.T) {
+		data := generateTestFile(t, "ics", 8192, "ar")
+		text := string(data)
+		assert.True(t, strings.HasPrefix(text, "BEGIN:VCALENDAR\r\n"))
+		assert.True(t, strings.HasSuffix(text, "END:VCALENDAR\r\n"))
+		assert.Contains(t, text, "BEGIN:VEVENT")
+		for _, line := range strings.Split(text, "\r\n") {
+			assert.LessOrEqual(t, len(line), 75, "content lines should be folded to 75 octets")
+		}
+	})
+}
+
+// assertOOXMLParts checks that every part of an OOXML package is well-formed XML and returns the part names
+func assertOOXMLParts(t *testing.T, data []byte) map[string]bool {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err, "package should be a zip container")
+	parts := make(map[string]bool)
+	for _, f := range zr.File {
+		parts[f.Name] = true
+		rc, err := f.Open()
+		require.NoError(t, err)
+		decoder := xml.NewDecoder(rc)
+		for {
+			_, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, "part %s should be well-formed XML", f.Name)
+		}
+		rc.Close()
+	}
+	return parts
+}
+
+func TestGeneratePPTXContent(t *testing.T) {
+	data := generateTestFile(t, "pptx", 20000, "kn")
+	parts := assertOOXMLParts(t, data)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "ppt/presentation.xml", "ppt/slides/slide1.xml", "ppt/theme/theme1.xml"} {
+		assert.True(t, parts[name], "package should contain %s", name)
+	}
+	assert.True(t, parts["ppt/slides/slide2.xml"], "larger targets should produce several slides")
+	assert.InDelta(t, 20000, len(data), 20000*0.5, "package size should approach the target")
+}
+
+func TestGenerateDOCXContent(t *testing.T) {
+	for _, lang := range []string{"en", "he", "cn"} {
+		t.Run(lang, func(t *testing.T) {
+			data := generateTestFile(t, "docx", 8192, lang)
+			parts := assertOOXMLParts(t, data)
+			for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/styles.xml"} {
+				assert.True(t, parts[name], "package should contain %s", name)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			rc, err := zr.Open("word/document.xml")
+			require.NoError(t, err)
+			document, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Equal(t, lang == "he", bytes.Contains(document, []byte("<w:bidi/>")), "only right-to-left text is laid out bidi")
+			assert.Greater(t, bytes.Count(document, []byte("<w:p>")), 1)
+		})
+	}
+}
+
+func TestGenerateImageContent(t *testing.T) {
+	for _, ext := range []string{"jpeg", "png"} {
+		t.Run(ext, func(t *testing.T) {
+			data := generateTestFile(t, ext, 200*1024, "en")
+			img, format, err := image.Decode(bytes.NewReader(data))
+			require.NoError(t, err, "image should decode without trailing garbage")
+			assert.Equal(t, ext, format)
+			assert.Greater(t, img.Bounds().Dx(), 100, "dimensions should scale with the target size")
+			assert.InDelta(t, 200*1024, len(data), 200*1024*0.3, "encoded size should approach the target")
+			if ext == "jpeg" {
+				assert.Equal(t, []byte{0xFF, 0xD8, 0xFF, 0xE1}, data[:4], "EXIF APP1 should follow SOI")
+				assert.Contains(t, string(data[:512]), "Exif\x00\x00II")
+			}
+		})
+	}
+}
+
+func TestMarkovSentences(t *testing.T) {
+	corpus := "The quick brown fox jumps over the lazy dog. The quick red fox runs past the sleepy cat.\n" +
+		"A lazy dog sleeps under the old tree. The old tree stands over the quiet river!"
+	model, err := NewMarkovModel(corpus, 2)
+	require.NoError(t, err)
+
+	vocabulary := strings.Fields(corpus)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		sentence := model.Sentence(rng)
+		require.NotEmpty(t, sentence)
+		for _, word := range strings.Fields(sentence) {
+			assert.Contains(t, vocabulary, word, "every token comes from the corpus")
+		}
+	}
+
+	// Corpora without spaces are chained per character
+	cjk, err := NewMarkovModel("天空在夜晚静静地闪耀。河流在阳光下轻轻地流动。", 2)
+	require.NoError(t, err)
+	assert.NotContains(t, cjk.Sentence(rng), " ")
+
+	// A registered model replaces the templates for its language only
+	RegisterMarkovModel("en", model)
+	defer RegisterMarkovModel("en", nil)
+	for _, word := range strings.Fields(generateSentence(rng, "en")) {
+		assert.Contains(t, vocabulary, word)
+	}
+
+	_, err = NewMarkovModel("Too short.", 2)
+	assert.Error(t, err)
+}
+
+func TestGenerateSentenceLanguages(t *testing.T) {
+	scripts := map[string]*unicode.RangeTable{
+		"ru": unicode.Cyrillic,
+		"hi": unicode.Devanagari,
+		"he": unicode.Hebrew,
+		"th": unicode.Thai,
+		"es": unicode.Latin,
+		"pt": unicode.Latin,
+		"tl": unicode.Latin,
+	}
+	rng := rand.New(rand.NewSource(1))
+	for lang, script := range scripts {
+		for i := 0; i < 10; i++ {
+			for _, text := range []string{generateSentence(rng, lang), generateWord(rng, lang)} {
+				for _, r := range text {
+					if unicode.IsLetter(r) {
+						assert.True(t, unicode.Is(script, r), "%s text %q contains foreign letter %q", lang, text, r)
+					}
+				}
+			}
+		}
+	}
+
+	// Adjectives follow the noun in Spanish
+	for i := 0; i < 20; i++ {
+		sentence := generateSentence(rng, "es")
+		assert.False(t, strings.HasPrefix(sentence, "Radiante"), sentence)
+		assert.True(t, strings.HasSuffix(sentence, "."), sentence)
+	}
+	assert.True(t, strings.HasSuffix(generateSentence(rng, "hi"), "।"))
+}
+
+func TestGenerateEdgeCaseFilename(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dir := t.TempDir()
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		name := GenerateEdgeCaseFilename(rng, "quarterly report", "docx")
+		require.True(t, utf8.ValidString(name), "names stay valid UTF-8")
+		require.LessOrEqual(t, len(name)+len(".docx"), maxFilenameBytes)
+		require.NotContains(t, name, "/")
+		// Every variant must be storable on a POSIX filesystem
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".docx"), nil, 0644), name)
+		seen[name] = true
+	}
+	assert.True(t, seen["quarterly report"+"."] || seen["quarterly report"+" "] || seen["quarterly report"+".."], "trailing dots and spaces are generated")
+	assert.Len(t, longFilename("日本語", "txt"), maxFilenameBytes-len(".txt"))
+}
+
+func TestLanguageMix(t *testing.T) {
+	mix := MixLanguages([]string{"en", "ru"}, []float64{3, 1})
+	assert.Equal(t, map[string]float64{"en": 0.75, "ru": 0.25}, mix.Breakdown())
+	assert.Equal(t, "en", mix.Primary())
+	assert.Nil(t, NewLanguage("ru").Breakdown())
+	assert.Equal(t, "ru", NewLanguage("ru").Primary())
+	assert.Equal(t, NewLanguage("en"), MixLanguages([]string{"fr"}, []float64{0}), "a mix without weights is English")
+
+	rng := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 400; i++ {
+		sentence := generateSentence(rng, mix.draw(rng))
+		if strings.ContainsFunc(sentence, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) {
+			counts["ru"]++
+		} else {
+			counts["en"]++
+		}
+	}
+	assert.InDelta(t, 300, counts["en"], 40, "sentences follow the mix weights")
+	assert.InDelta(t, 100, counts["ru"], 40)
+
+	// Cached corpora are mixed per run of sentences
+	cache := NewCorpusCache(t.TempDir(), 0)
+	lines, err := cache.Lines(rng, mix, 64*1024)
+	require.NoError(t, err)
+	assert.NotEmpty(t, lines)
+	assert.Len(t, cache.corpora, 2, "each language of the mix has its own corpus")
+
+	// Names and metadata of mixed documents are in a language of the mix
+	data, err := generateJSON(rng, mix, 8*1024)
+	require.NoError(t, err)
+	var docs []jsonDocument
+	require.NoError(t, json.Unmarshal(data, &docs))
+	for _, doc := range docs {
+		assert.Equal(t, "en", doc.Author.Language)
+		if !strings.ContainsFunc(doc.Title, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) {
+			assert.Equal(t, strings.ToUpper(doc.Title[:1]), doc.Title[:1], "English names are capitalized")
+		}
+	}
+}
+
+func TestContentTemplates(t *testing.T) {
+	dir := t.TempDir()
+	invoice := filepath.Join(dir, "invoice.json.tmpl")
+	require.NoError(t, os.WriteFile(invoice, []byte(`{
+  "invoice": "INV-{{digits 6}}",
+  "issued": "{{date}}",
+  "customer": {{printf "%q" company}},
+  "lines": [{{range $i, $n := seq (int 2 4)}}{{if $i}}, {{end}}
+    {"item": {{printf "%q" (words 2)}}, "quantity": {{int 1 9}}, "price": {{amount 5 500}}}{{end}}
+  ]
+}
+`), 0644))
+
+	templates, err := LoadContentTemplates(map[string][]string{"JSON": {invoice}})
+	require.NoError(t, err)
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(1)))
+	g.Templates = templates
+
+	f := &models.File{Name: "invoice", FileExtension: "json", FileSize: 1 << 20}
+	require.NoError(t, g.GenerateContent(f, NewLanguage("fr")))
+	data, err := os.ReadFile(filepath.Join(dir, "invoice.json"))
+	require.NoError(t, err)
+	assert.Equal(t, len(data), f.FileSize, "the size follows the rendered template")
+
+	var doc struct {
+		Invoice string `json:"invoice"`
+		Lines   []struct {
+			Quantity int     `json:"quantity"`
+			Price    float64 `json:"price"`
+		} `json:"lines"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc), "rendered template should be valid json: %s", data)
+	assert.Regexp(t, `^INV-\d{6}$`, doc.Invoice)
+	assert.NotEmpty(t, doc.Lines)
+
+	_, err = LoadContentTemplates(map[string][]string{"png": {invoice}})
+	assert.Error(t, err, "binary formats cannot be templated")
+}
+
+func TestBundledContentTemplates(t *testing.T) {
+	templates, err := LoadContentTemplates(map[string][]string{
+		"txt": {"../templates/invoice.txt.tmpl"},
+		"md":  {"../templates/contract.md.tmpl"},
+		"csv": {"../templates/report.csv.tmpl"},
+	})
+	require.NoError(t, err)
+	dir := t.TempDir()
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(2)))
+	g.Templates = templates
+	for _, ext := range []string{"txt", "md", "csv"} {
+		f := &models.File{Name: "document", FileExtension: ext, FileSize: 4096}
+		require.NoError(t, g.GenerateContent(f, NewLanguage("en")), ext)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "document.csv"))
+	require.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err, "the report template renders valid csv")
+	assert.Len(t, records[0], 6)
+}
+
+func TestPDFFonts(t *testing.T) {
+	data := generateTestFile(t, "pdf", 4096, "vi")
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")), "Latin text uses the embedded font")
+
+	dir := t.TempDir()
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(1)))
+	err := g.GenerateContent(&models.File{Name: "sample", FileExtension: "pdf", FileSize: 4096}, NewLanguage("jp"))
+	require.Error(t, err, "CJK text without a CJK font must not produce a broken pdf")
+	assert.Contains(t, err.Error(), `"cjk"`)
+
+	fontPath := filepath.Join(dir, "cjk.ttf")
+	require.NoError(t, os.WriteFile(fontPath, defaultFonts.fonts[ScriptLatin], 0644))
+	g.Fonts, err = LoadFonts(map[string]string{ScriptCJK: fontPath})
+	require.NoError(t, err)
+	require.NoError(t, g.GenerateContent(&models.File{Name: "sample", FileExtension: "pdf", FileSize: 4096}, NewLanguage("jp")))
+
+	_, err = LoadFonts(map[string]string{"klingon": fontPath})
+	assert.Error(t, err)
+	assert.Equal(t, ScriptArabic, scriptOf("Report تقرير"))
+	assert.Equal(t, ScriptLatin, scriptOf("Привет, мир"))
+}
+
+func TestGenerateRichPDF(t *testing.T) {
+	data := generateTestFile(t, "pdf", 400000, "en")
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+	assert.InDelta(t, 400000, len(data), 400000*0.25, "pdf size should approach the target")
+	assert.Greater(t, bytes.Count(data, []byte("/Type /Page\n")), 3, "large targets span several pages")
+	assert.Contains(t, string(data), "/Outlines", "sections are bookmarked")
+	assert.Contains(t, string(data), "/DCTDecode", "figures are embedded as jpeg")
+}
+
+func TestGenerateXLSXContent(t *testing.T) {
+	data := generateTestFile(t, "xlsx", 200000, "jp")
+	assert.InDelta(t, 200000, len(data), 200000*0.25, "workbook size should approach the target")
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer f.Close()
+	sheets := f.GetSheetList()
+	require.GreaterOrEqual(t, len(sheets), 3, "a summary and at least two data sheets")
+
+	formula, err := f.GetCellFormula(sheets[0], "B2")
+	require.NoError(t, err)
+	assert.Contains(t, formula, sheets[1], "the summary links to the data sheets")
+	assert.True(t, assertOOXMLParts(t, data)["xl/charts/chart1.xml"], "the summary carries a chart")
+
+	rows, err := f.GetRows(sheets[1])
+	require.NoError(t, err)
+	assert.Greater(t, len(rows), 100)
+	formula, err = f.GetCellFormula(sheets[1], "E2")
+	require.NoError(t, err)
+	assert.Equal(t, "C2*D2", formula)
+	value, err := f.GetCellValue(sheets[1], "B2")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\d{1,2}-\d{1,2}-\d{2}$`, value, "dates are stored as formatted date cells")
+}
+
+func TestGenerateBinaryFormats(t *testing.T) {
+	magics := map[string]string{
+		"mp3":    "ID3\x03",
+		"exe":    "MZ",
+		"elf":    "\x7fELF",
+		"pcap":   "\xd4\xc3\xb2\xa1",
+		"sqlite": "SQLite format 3\x00",
+	}
+	for ext, magic := range magics {
+		t.Run(ext, func(t *testing.T) {
+			data := generateTestFile(t, ext, 50000, "en")
+			assert.True(t, bytes.HasPrefix(data, []byte(magic)), "%s should start with its magic number", ext)
+			assert.InDelta(t, 50000, len(data), 50000*0.5)
+		})
+	}
+
+	data := generateTestFile(t, "mp4", 50000, "en")
+	assert.Equal(t, "ftypisom", string(data[4:12]))
+	assert.Len(t, data, 50000)
+
+	data = generateTestFile(t, "exe", 50000, "en")
+	peOffset := binary.LittleEndian.Uint32(data[0x3c:])
+	assert.Equal(t, "PE\x00\x00", string(data[peOffset:peOffset+4]))
+}
+
+func TestInjectPII(t *testing.T) {
+	rng := rand.New(rand.NewSource(31))
+	dir := t.TempDir()
+	g := NewFileContentGenerator(dir, rng)
+
+	for _, ext := range []string{"txt", "md", "csv", "json", "xml"} {
+		f := &models.File{Name: "pii", FileExtension: ext, FileSize: 4096}
+		require.NoError(t, g.GenerateContent(f, NewLanguage("en")))
+		require.NoError(t, g.InjectPII(f, nil, 6))
+		require.Len(t, f.PII, 6, ext)
+
+		data, err := os.ReadFile(filepath.Join(dir, "pii."+ext))
+		require.NoError(t, err)
+		assert.Equal(t, len(data), f.FileSize)
+		for _, pii := range f.PII {
+			require.LessOrEqual(t, pii.Offset+len(pii.Value), len(data))
+			assert.Equal(t, pii.Value, string(data[pii.Offset:pii.Offset+len(pii.Value)]), "%s offset points at the value", ext)
+		}
+		switch ext {
+		case "json":
+			assert.True(t, json.Valid(data), "PII keeps JSON valid")
+		case "xml":
+			decoder := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				_, err := decoder.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, "PII keeps XML well-formed")
+			}
+		case "csv":
+			_, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			assert.NoError(t, err, "PII keeps CSV parseable")
+		}
+	}
+
+	// Every value passes the checks a DLP engine applies
+	for i := 0; i < 200; i++ {
+		card := strings.NewReplacer(" ", "", "-", "").Replace(generateCardNumber(rng))
+		assert.Equal(t, card[len(card)-1], luhnCheckDigit(card[:len(card)-1]), "card %s passes Luhn", card)
+
+		iban := strings.ReplaceAll(generateIBAN(rng), " ", "")
+		assert.Equal(t, iban[2:4], ibanCheckDigits(iban[:2], iban[4:]), "IBAN %s has valid check digits", iban)
+
+		assert.Regexp(t, `^(00[1-9]|0[1-9]\d|[1-8]\d\d)-\d\d-\d{4}$`, generateSSN(rng))
+		assert.NotEqual(t, "666", generateSSN(rng)[:3])
+	}
+	assert.Equal(t, byte('3'), luhnCheckDigit("7992739871"), "known Luhn check digit")
+	assert.Equal(t, "82", ibanCheckDigits("GB", "WEST12345698765432"), "known IBAN check digits")
+
+	// Binary formats are left alone
+	f := &models.File{Name: "pii", FileExtension: "png", FileSize: 2048}
+	require.NoError(t, g.GenerateContent(f, NewLanguage("en")))
+	require.NoError(t, g.InjectPII(f, nil, 3))
+	assert.Empty(t, f.PII)
+	_, err := GeneratePII(rng, "passport")
+	assert.Error(t, err)
+}
+
+func TestUniqueName(t *testing.T) {
+	dir := t.TempDir()
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(3)))
+
+	assert.Equal(t, "report", g.UniqueName("report", "txt", 1))
+	second := g.UniqueName("report", "txt", 1)
+	assert.Regexp(t, `^report-[0-9a-f]{4}$`, second, "a repeated name gets a short suffix")
+	assert.NotEqual(t, second, g.UniqueName("Report", "txt", 1), "names differing only in case collide")
+	assert.Equal(t, "report", g.UniqueName("report", "csv", 1), "other extensions do not collide")
+
+	// Files left in the repository by earlier runs are not overwritten
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("x"), 0644))
+	assert.NotEqual(t, "old", g.UniqueName("old", "txt", 1))
+
+	// Forks share the names handed out
+	fork := g.Fork(rand.New(rand.NewSource(4)))
+	assert.NotEqual(t, "report", fork.UniqueName("report", "txt", 1))
+
+	long := longFilename("long", "txt")
+	g.UniqueName(long, "txt", 1)
+	suffixed := g.UniqueName(long, "txt", 1)
+	assert.LessOrEqual(t, len(suffixed)+len(".txt"), maxFilenameBytes, "suffixes fit within the name limit")
+	assert.True(t, utf8.ValidString(suffixed))
+	versioned := g.UniqueName(longFilename("versioned", "txt"), "txt", 12)
+	assert.Len(t, LocalName(&models.File{Name: versioned, FileExtension: "txt", Version: 12}), maxFilenameBytes,
+		"the name leaves room for the marker of the last version")
+	assert.Len(t, LocalName(&models.File{Name: versioned, FileExtension: "txt", Version: 1}), maxFilenameBytes-len(".v12"))
+
+	assert.Equal(t, "a_b_c", SanitizeFilename("a/b\\c"))
+	assert.Equal(t, "_..", SanitizeFilename(".."))
+	assert.Equal(t, "x_y", g.UniqueName("x\x00y", "txt", 1))
+
+	f := &models.File{Name: "plan", FileExtension: "md", Version: 2}
+	assert.Equal(t, filepath.Join(dir, "plan.v2.md"), g.LocalPath(f))
+}
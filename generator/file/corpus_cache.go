@@ -0,0 +1,126 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// minCorpusBucket is the smallest size bucket kept in the corpus cache
+const minCorpusBucket = 4 * 1024
+
+// CorpusCache keeps zstd-compressed sentence corpora on disk per (language, size bucket)
+// so large text documents can be assembled by slicing cached sentences instead of
+// generating every sentence from scratch.
+type CorpusCache struct {
+	Dir string // Directory holding the compressed corpora
+	// Uniqueness is the fraction (0..1) of sentences freshly generated per document;
+	// the rest is sliced from the cached corpus.
+	Uniqueness float64
+
+	mu      sync.Mutex
+	corpora map[string][]string
+}
+
+// NewCorpusCache initializes a CorpusCache stored in dir
+func NewCorpusCache(dir string, uniqueness float64) *CorpusCache {
+	return &CorpusCache{
+		Dir:        dir,
+		Uniqueness: uniqueness,
+		corpora:    make(map[string][]string),
+	}
+}
+
+// sizeBucket rounds size up to the next power-of-two bucket
+func sizeBucket(size int) int {
+	bucket := minCorpusBucket
+	for bucket < size && bucket < 4*1024*1024 {
+		bucket *= 2
+	}
+	return bucket
+}
+
+// corpus returns the cached sentences for lang and bucket, building and persisting them on first use
+func (c *CorpusCache) corpus(rng *rand.Rand, lang string, bucket int) ([]string, error) {
+	key := fmt.Sprintf("%s-%d", lang, bucket)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sentences, ok := c.corpora[key]; ok {
+		return sentences, nil
+	}
+
+	path := filepath.Join(c.Dir, key+".zst")
+	if compressed, err := os.ReadFile(path); err == nil {
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		data, err := decoder.DecodeAll(compressed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode corpus %s: %v", path, err)
+		}
+		sentences := strings.Split(string(data), "\n")
+		c.corpora[key] = sentences
+		return sentences, nil
+	}
+
+	// Build a corpus large enough to fill the bucket on its own
+	var sentences []string
+	var buf bytes.Buffer
+	for buf.Len() < bucket {
+		sentence := generateSentence(rng, lang)
+		sentences = append(sentences, sentence)
+		buf.WriteString(sentence + "\n")
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create corpus directory: %v", err)
+	}
+	if err := os.WriteFile(path, encoder.EncodeAll([]byte(strings.Join(sentences, "\n")), nil), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write corpus %s: %v", path, err)
+	}
+
+	c.corpora[key] = sentences
+	return sentences, nil
+}
+
+// Lines assembles roughly targetSize bytes of sentences by slicing random runs from the
+// cached corpus, mixing in freshly generated sentences according to Uniqueness.
+// With a language mix every run comes from the corpus of one language of the mix.
+func (c *CorpusCache) Lines(rng *rand.Rand, lang Language, targetSize int) ([]string, error) {
+	var lines []string
+	size := 0
+	for size < targetSize {
+		runLang := lang.draw(rng)
+		sentences, err := c.corpus(rng, runLang, sizeBucket(targetSize))
+		if err != nil {
+			return nil, err
+		}
+
+		// Take a run of consecutive sentences from a random offset
+		start := rng.Intn(len(sentences))
+		runLength := 5 + rng.Intn(46)
+		for i := 0; i < runLength && size < targetSize; i++ {
+			line := sentences[(start+i)%len(sentences)]
+			if rng.Float64() < c.Uniqueness {
+				line = generateSentence(rng, runLang)
+			}
+			lines = append(lines, line)
+			size += len(line) + 1
+		}
+	}
+	return lines, nil
+}
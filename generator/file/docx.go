@@ -0,0 +1,95 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// languageTags maps the generator's language codes to BCP 47 tags
+var languageTags = map[string]string{
+	"en": "en-US", "vi": "vi-VN", "jp": "ja-JP", "cn": "zh-CN", "kn": "ko-KR",
+	"ar": "ar-SA", "fr": "fr-FR", "ge": "de-DE", "ru": "ru-RU", "hi": "hi-IN",
+	"he": "he-IL", "th": "th-TH", "es": "es-ES", "pt": "pt-PT", "tl": "fil-PH",
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`</Types>`
+
+const docxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`</Relationships>`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const docxNamespace = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+// docxStyles sets fonts covering Latin, East Asian and complex scripts and the
+// document language
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles ` + docxNamespace + `><w:docDefaults><w:rPrDefault><w:rPr>` +
+	`<w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="MS Mincho" w:cs="Arial"/>` +
+	`<w:sz w:val="22"/><w:lang w:val="%s" w:eastAsia="%s" w:bidi="%s"/>` +
+	`</w:rPr></w:rPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`</w:styles>`
+
+// isRightToLeft reports whether text contains Hebrew or Arabic letters
+func isRightToLeft(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Hebrew, unicode.Arabic) {
+			return true
+		}
+	}
+	return false
+}
+
+// docxParagraph renders a paragraph, laid out right-to-left when its script requires it
+func docxParagraph(text string) string {
+	if isRightToLeft(text) {
+		return `<w:p><w:pPr><w:bidi/></w:pPr><w:r><w:rPr><w:rtl/></w:rPr><w:t xml:space="preserve">` + xmlEscape(text) + `</w:t></w:r></w:p>`
+	}
+	return `<w:p><w:r><w:t xml:space="preserve">` + xmlEscape(text) + `</w:t></w:r></w:p>`
+}
+
+// writeDOCX writes a WordprocessingML document with one paragraph per entry. lang
+// sets the document language; mixed documents use their dominant language.
+func writeDOCX(w io.Writer, paragraphs []string, lang Language) error {
+	tag, ok := languageTags[lang.Primary()]
+	if !ok {
+		tag = "en-US"
+	}
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document ` + docxNamespace + `><w:body>`)
+	for _, text := range paragraphs {
+		body.WriteString(docxParagraph(text))
+	}
+	body.WriteString(`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/>` +
+		`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>`)
+	body.WriteString(`</w:body></w:document>`)
+
+	pkg := newOOXMLPackage(w)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/styles.xml", fmt.Sprintf(docxStyles, tag, tag, tag)},
+		{"word/document.xml", body.String()},
+	} {
+		if err := pkg.add(part.name, part.content); err != nil {
+			return err
+		}
+	}
+	return pkg.close()
+}
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/songvi/robo/models"
+)
+
+// plannedSource is a file planned in dry-run mode, remembered as a duplicate source
+type plannedSource struct {
+	name string
+	id   string
+	size int
+}
+
+// contentID returns the hex SHA-256 of the file at path
+func contentID(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// pseudoContentID draws a digest shaped like a SHA-256 for content never written
+func (g *FileContentGenerator) pseudoContentID() string {
+	sum := make([]byte, sha256.Size)
+	g.rng.Read(sum)
+	return hex.EncodeToString(sum)
+}
+
+// plan fills in file as if its content had been written, without touching the disk
+func (g *FileContentGenerator) plan(file *models.File) {
+	file.ContentID = g.pseudoContentID()
+	file.FileContent = fmt.Sprintf("Planned %s content", strings.ToUpper(file.FileExtension))
+
+	s := g.sources
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ext := strings.ToLower(file.FileExtension)
+	planned := append(s.planned[ext], plannedSource{name: LocalName(file), id: file.ContentID, size: file.FileSize})
+	if len(planned) > maxDuplicateSources {
+		planned = planned[1:]
+	}
+	s.planned[ext] = planned
+}
+
+// planDuplicate is Duplicate in dry-run mode: an exact duplicate takes the ContentID
+// and size of its source, a near-duplicate a fresh ContentID and the size its edits
+// would give. It returns the source name, or "" when no source was planned yet.
+func (g *FileContentGenerator) planDuplicate(file *models.File, nearDuplicate bool) string {
+	ext := strings.ToLower(file.FileExtension)
+	s := g.sources
+	s.mu.Lock()
+	planned := s.planned[ext]
+	var source plannedSource
+	if len(planned) > 0 {
+		source = planned[g.rng.Intn(len(planned))]
+	}
+	s.mu.Unlock()
+	if source.name == "" {
+		return ""
+	}
+
+	file.FileSize = source.size
+	if !nearDuplicate {
+		file.ContentID = source.id
+		file.FileContent = fmt.Sprintf("Planned %s content", strings.ToUpper(file.FileExtension))
+		return source.name
+	}
+	if !letterMutableExtensions[ext] {
+		file.FileSize += 16 + g.rng.Intn(241)
+	}
+	g.plan(file)
+	return source.name
+}
+
+// planRevision is Revise in dry-run mode, sizing next as its edits would
+func (g *FileContentGenerator) planRevision(prev, next *models.File) {
+	ext := strings.ToLower(next.FileExtension)
+	next.FileSize = prev.FileSize
+	switch {
+	case ext == "txt" || ext == "md":
+		next.FileSize = max(1, next.FileSize+g.rng.Intn(401)-200)
+	case tailTolerantExtensions[ext]:
+		next.FileSize += 16 + g.rng.Intn(241)
+	}
+	g.plan(next)
+}
@@ -0,0 +1,135 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/songvi/robo/models"
+)
+
+// maxDuplicateSources bounds how many generated files per extension are remembered
+// as sources for duplicates
+const maxDuplicateSources = 256
+
+// letterMutableExtensions are formats where near-duplicates swap a few letters
+var letterMutableExtensions = map[string]bool{"txt": true, "md": true, "csv": true}
+
+// tailTolerantExtensions are formats whose readers ignore bytes appended after the
+// payload, so near-duplicates append a short random tail
+var tailTolerantExtensions = map[string]bool{
+	"jpeg": true, "jpg": true, "png": true, "pdf": true, "bin": true,
+	"zip": true, "docx": true, "xlsx": true, "pptx": true,
+	"mp3": true, "exe": true, "elf": true,
+}
+
+// duplicateSources remembers generated files per extension; forks of a
+// FileContentGenerator share one so workers can duplicate each other's files
+type duplicateSources struct {
+	mu      sync.Mutex
+	byExt   map[string][]string
+	planned map[string][]plannedSource // Dry-run files per extension
+}
+
+// remember records a generated file as a future duplicate source
+func (g *FileContentGenerator) remember(ext, path string) {
+	s := g.sources
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := append(s.byExt[ext], path)
+	if len(paths) > maxDuplicateSources {
+		paths = paths[1:]
+	}
+	s.byExt[ext] = paths
+}
+
+// forget drops a file that no longer exists locally from the duplicate sources
+func (g *FileContentGenerator) forget(path string) {
+	s := g.sources
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ext, paths := range s.byExt {
+		for i, p := range paths {
+			if p == path {
+				s.byExt[ext] = append(paths[:i:i], paths[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// pickSource returns a random duplicate source for ext, or "" when there is none
+func (g *FileContentGenerator) pickSource(ext string) string {
+	s := g.sources
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := s.byExt[ext]
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[g.rng.Intn(len(paths))]
+}
+
+// Duplicate writes file as a copy of a previously generated file with the same extension.
+// A near-duplicate carries a few small edits; formats that cannot be edited without
+// breaking them get no near-duplicates. It returns the source path, or "" when no
+// suitable source exists and the caller should generate fresh content.
+func (g *FileContentGenerator) Duplicate(file *models.File, nearDuplicate bool) (string, error) {
+	ext := strings.ToLower(file.FileExtension)
+	if nearDuplicate && !letterMutableExtensions[ext] && !tailTolerantExtensions[ext] {
+		return "", nil
+	}
+	if g.DryRun {
+		return g.planDuplicate(file, nearDuplicate), nil
+	}
+	source := g.pickSource(ext)
+	if source == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to read duplicate source: %v", err)
+	}
+
+	if nearDuplicate {
+		if letterMutableExtensions[ext] {
+			data = g.mutateLetters(data)
+		} else {
+			tail := make([]byte, 16+g.rng.Intn(241))
+			g.rng.Read(tail)
+			data = append(data, tail...)
+		}
+	}
+
+	fullPath := g.LocalPath(file)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write duplicate file: %v", err)
+	}
+	file.FileSize = len(data)
+	g.remember(ext, fullPath)
+	return source, nil
+}
+
+// mutateLetters returns a copy of data with a handful of ASCII letters replaced
+func (g *FileContentGenerator) mutateLetters(data []byte) []byte {
+	var letters []int
+	for i, b := range data {
+		if b >= 'a' && b <= 'z' {
+			letters = append(letters, i)
+		}
+	}
+	edited := append([]byte(nil), data...)
+	if len(letters) == 0 {
+		return append(edited, '\n')
+	}
+	for i := 0; i < 1+g.rng.Intn(5); i++ {
+		pos := letters[g.rng.Intn(len(letters))]
+		edited[pos] = 'a' + byte((int(edited[pos]-'a')+1+g.rng.Intn(25))%26)
+	}
+	return edited
+}
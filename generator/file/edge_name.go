@@ -0,0 +1,90 @@
+package file
+
+import (
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFilenameBytes is the usual filesystem limit on one path component
+const maxFilenameBytes = 255
+
+// windowsReservedNames are device names Windows refuses as file names, with any extension
+var windowsReservedNames = []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM9", "LPT1", "LPT9", "con", "nul"}
+
+// edgeCaseEmoji mixes single code points, skin tone modifiers, flags and ZWJ sequences
+var edgeCaseEmoji = []string{"😀", "🚀", "👍🏽", "🇻🇳", "👩‍💻", "👨‍👩‍👧‍👦", "🏳️‍🌈", "❤️"}
+
+// edgeCaseInvisibles are zero-width and byte-order characters that render as nothing
+var edgeCaseInvisibles = []string{"\u200b", "\u200c", "\u200d", "\u2060", "\ufeff"}
+
+// edgeCaseBidi are bidirectional controls and right-to-left text
+var edgeCaseBidi = []string{"\u202e", "\u200f", "\u202b", "\u2067", "שלום", "مرحبا"}
+
+// edgeCaseReservedChars are characters rejected by Windows and many APIs (the path
+// separators and NUL are excluded since names must stay a single path component)
+var edgeCaseReservedChars = []string{"<", ">", ":", "\"", "|", "?", "*", "%", "#", "&", "~"}
+
+// insertAt inserts s at a random rune boundary of name
+func insertAt(rng *rand.Rand, name, s string) string {
+	runes := []rune(name)
+	pos := rng.Intn(len(runes) + 1)
+	return string(runes[:pos]) + s + string(runes[pos:])
+}
+
+// GenerateEdgeCaseFilename turns name into a pathological variant: emoji, zero-width
+// characters, right-to-left marks, Windows-reserved names or characters, decomposed
+// accents, leading/trailing spaces and dots, or a name filling the full 255 bytes
+// together with ext.
+func GenerateEdgeCaseFilename(rng *rand.Rand, name, ext string) string {
+	switch rng.Intn(8) {
+	case 0:
+		return insertAt(rng, name, edgeCaseEmoji[rng.Intn(len(edgeCaseEmoji))])
+	case 1:
+		return insertAt(rng, name, edgeCaseInvisibles[rng.Intn(len(edgeCaseInvisibles))])
+	case 2:
+		return insertAt(rng, name, edgeCaseBidi[rng.Intn(len(edgeCaseBidi))])
+	case 3:
+		return windowsReservedNames[rng.Intn(len(windowsReservedNames))]
+	case 4:
+		return insertAt(rng, name, edgeCaseReservedChars[rng.Intn(len(edgeCaseReservedChars))])
+	case 5:
+		// NFD accents look identical to their precomposed form but compare differently
+		return insertAt(rng, name, "e\u0301")
+	case 6:
+		affixes := []string{" ", "  ", ".", "..", " . "}
+		if rng.Intn(2) == 0 {
+			return affixes[rng.Intn(len(affixes))] + name
+		}
+		// A trailing dot or space before the extension, e.g. "name .txt" or "name..txt"
+		return name + affixes[rng.Intn(len(affixes))]
+	default:
+		return longFilename(name, ext)
+	}
+}
+
+// longFilename repeats name until name plus "."+ext fills maxFilenameBytes exactly,
+// padding with ASCII when a multi-byte rune does not fit
+func longFilename(name, ext string) string {
+	limit := maxFilenameBytes - len(ext) - 1
+	if name == "" {
+		name = "x"
+	}
+	var b strings.Builder
+	for b.Len() < limit {
+		for _, r := range name {
+			if b.Len()+utf8.RuneLen(r) > limit {
+				break
+			}
+			b.WriteRune(r)
+		}
+		if b.Len()+1 > limit {
+			break
+		}
+		b.WriteByte('_')
+	}
+	for b.Len() < limit {
+		b.WriteByte('_')
+	}
+	return b.String()
+}
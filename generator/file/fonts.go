@@ -0,0 +1,101 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"unicode"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/songvi/robo/generator/fonts"
+)
+
+// Scripts PDF fonts are configured for. Latin also covers Greek and Cyrillic.
+const (
+	ScriptLatin      = "latin"
+	ScriptCJK        = "cjk"
+	ScriptArabic     = "arabic"
+	ScriptHebrew     = "hebrew"
+	ScriptThai       = "thai"
+	ScriptDevanagari = "devanagari"
+)
+
+// scriptTables maps the non-Latin scripts to the Unicode ranges detected for them
+var scriptTables = map[string][]*unicode.RangeTable{
+	ScriptCJK:        {unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul},
+	ScriptArabic:     {unicode.Arabic},
+	ScriptHebrew:     {unicode.Hebrew},
+	ScriptThai:       {unicode.Thai},
+	ScriptDevanagari: {unicode.Devanagari},
+}
+
+// scriptOf returns the script text needs a font for: its most frequent non-Latin
+// script, or latin when it has none. Fonts for other scripts carry Latin glyphs too.
+func scriptOf(text string) string {
+	counts := make(map[string]int)
+	for _, r := range text {
+		for script, tables := range scriptTables {
+			if unicode.In(r, tables...) {
+				counts[script]++
+				break
+			}
+		}
+	}
+	best := ScriptLatin
+	for script, n := range counts {
+		if n > counts[best] || n == counts[best] && script < best {
+			best = script
+		}
+	}
+	return best
+}
+
+// FontSet holds the TrueType fonts used to write PDF text, one per script
+type FontSet struct {
+	fonts map[string][]byte
+}
+
+// LoadFonts reads the configured script -> TrueType font file paths. Latin text falls
+// back to the embedded Noto Sans unless a latin font is configured.
+func LoadFonts(paths map[string]string) (*FontSet, error) {
+	set := &FontSet{fonts: map[string][]byte{ScriptLatin: fonts.NotoSansRegular}}
+	for script, path := range paths {
+		if _, ok := scriptTables[script]; !ok && script != ScriptLatin {
+			return nil, fmt.Errorf("unknown font script %q", script)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s font: %v", script, err)
+		}
+		set.fonts[script] = data
+	}
+	return set, nil
+}
+
+// defaultFonts only knows the embedded Latin font
+var defaultFonts = &FontSet{fonts: map[string][]byte{ScriptLatin: fonts.NotoSansRegular}}
+
+// pdfFonts registers the fonts of a FontSet with one document as they are needed
+type pdfFonts struct {
+	set   *FontSet
+	pdf   *gofpdf.Fpdf
+	added map[string]bool
+}
+
+// use selects a font able to render text, failing when its script has no font
+func (f *pdfFonts) use(text string, size float64) error {
+	script := scriptOf(text)
+	data, ok := f.set.fonts[script]
+	if !ok {
+		return fmt.Errorf("no font configured for %s text; add a %q entry to the generator fonts", script, script)
+	}
+	if !f.added[script] {
+		f.pdf.AddUTF8FontFromBytes(script, "", data)
+		f.added[script] = true
+	}
+	f.pdf.SetFont(script, "", size)
+	if f.pdf.Err() {
+		return fmt.Errorf("failed to set %s font: %v", script, f.pdf.Error())
+	}
+	return nil
+}
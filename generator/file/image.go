@@ -0,0 +1,241 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// exifCameras are the camera make/model pairs written to JPEG EXIF metadata
+var exifCameras = [][2]string{
+	{"Canon", "Canon EOS 5D Mark IV"},
+	{"NIKON CORPORATION", "NIKON D850"},
+	{"SONY", "ILCE-7M3"},
+	{"Apple", "iPhone 14 Pro"},
+	{"samsung", "SM-S918B"},
+	{"FUJIFILM", "X-T4"},
+}
+
+// drawImage renders a gradient background with random shapes and per-pixel noise
+func drawImage(rng *rand.Rand, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	from := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+	to := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+	lerp := func(a, b uint8, t float64) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t) }
+
+	// Diagonal gradient background
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			t := (float64(x)/float64(width) + float64(y)/float64(height)) / 2
+			img.SetRGBA(x, y, color.RGBA{lerp(from.R, to.R, t), lerp(from.G, to.G, t), lerp(from.B, to.B, t), 255})
+		}
+	}
+
+	// Rectangles and circles
+	for i := 0; i < 3+rng.Intn(10); i++ {
+		c := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+		cx, cy := rng.Intn(width), rng.Intn(height)
+		size := 1 + rng.Intn(max(width, height)/4+1)
+		circle := rng.Intn(2) == 0
+		for y := max(0, cy-size); y < min(height, cy+size); y++ {
+			for x := max(0, cx-size); x < min(width, cx+size); x++ {
+				if circle && (x-cx)*(x-cx)+(y-cy)*(y-cy) > size*size {
+					continue
+				}
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+
+	// Sensor-like noise
+	const noise = 24
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := int(img.Pix[i+c]) + rng.Intn(2*noise+1) - noise
+			img.Pix[i+c] = uint8(min(255, max(0, v)))
+		}
+	}
+	return img
+}
+
+// encodeImage encodes img as jpeg or png
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	}
+	return buf.Bytes(), err
+}
+
+// generateImage produces a valid jpeg or png whose encoded size approaches targetSize,
+// adjusting the dimensions over a few encode passes
+func generateImage(rng *rand.Rand, format string, targetSize int) ([]byte, error) {
+	bytesPerPixel := 0.6
+	if format == "png" {
+		bytesPerPixel = 2.0
+	}
+	pixels := float64(targetSize) / bytesPerPixel
+	width := int(math.Sqrt(pixels * 4 / 3))
+	seed := rng.Int63()
+
+	var data []byte
+	for attempt := 0; attempt < 4; attempt++ {
+		width = min(8000, max(16, width))
+		height := max(12, width*3/4)
+		// Redraw the same scene at each size so retries stay deterministic
+		img := drawImage(rand.New(rand.NewSource(seed)), width, height)
+		var err error
+		if data, err = encodeImage(img, format); err != nil {
+			return nil, err
+		}
+		ratio := float64(targetSize) / float64(len(data))
+		if ratio > 0.9 && ratio < 1.1 {
+			break
+		}
+		width = int(float64(width) * math.Sqrt(ratio))
+	}
+
+	if format != "png" {
+		data = insertEXIF(data, buildEXIF(rng))
+	}
+	return data, nil
+}
+
+// tiffEntry is one IFD entry of the EXIF TIFF structure
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16 // 2 = ASCII, 4 = LONG, 5 = RATIONAL
+	count uint32
+	data  []byte // Value bytes; stored inline when 4 bytes or fewer
+}
+
+// asciiEntry builds a NUL-terminated ASCII entry
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), data: append([]byte(s), 0)}
+}
+
+// longEntry builds a LONG entry
+func longEntry(tag uint16, v uint32) tiffEntry {
+	return tiffEntry{tag: tag, typ: 4, count: 1, data: binary.LittleEndian.AppendUint32(nil, v)}
+}
+
+// degreesEntry builds a GPS coordinate entry as degrees, minutes, seconds rationals
+func degreesEntry(tag uint16, deg float64) tiffEntry {
+	deg = math.Abs(deg)
+	d := math.Floor(deg)
+	m := math.Floor((deg - d) * 60)
+	sec := ((deg-d)*60 - m) * 60
+	var data []byte
+	for _, r := range [][2]uint32{{uint32(d), 1}, {uint32(m), 1}, {uint32(sec * 100), 100}} {
+		data = binary.LittleEndian.AppendUint32(data, r[0])
+		data = binary.LittleEndian.AppendUint32(data, r[1])
+	}
+	return tiffEntry{tag: tag, typ: 5, count: 3, data: data}
+}
+
+// ifdSize returns the encoded size of an IFD including its out-of-line values
+func ifdSize(entries []tiffEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, e := range entries {
+		if len(e.data) > 4 {
+			size += len(e.data)
+		}
+	}
+	return size
+}
+
+// writeIFD encodes an IFD placed at offset (relative to the TIFF header)
+func writeIFD(buf *bytes.Buffer, entries []tiffEntry, offset int) {
+	le := binary.LittleEndian
+	valueOffset := offset + 2 + 12*len(entries) + 4
+	var values []byte
+	buf.Write(le.AppendUint16(nil, uint16(len(entries))))
+	for _, e := range entries {
+		buf.Write(le.AppendUint16(nil, e.tag))
+		buf.Write(le.AppendUint16(nil, e.typ))
+		buf.Write(le.AppendUint32(nil, e.count))
+		if len(e.data) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, e.data)
+			buf.Write(inline)
+		} else {
+			buf.Write(le.AppendUint32(nil, uint32(valueOffset+len(values))))
+			values = append(values, e.data...)
+		}
+	}
+	buf.Write(le.AppendUint32(nil, 0)) // No next IFD
+	buf.Write(values)
+}
+
+// buildEXIF builds an APP1 EXIF payload with camera, capture date and GPS position
+func buildEXIF(rng *rand.Rand) []byte {
+	camera := exifCameras[rng.Intn(len(exifCameras))]
+	taken := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(rng.Int63n(int64(10 * 365 * 24 * time.Hour))))
+	dateTime := taken.Format("2006:01:02 15:04:05")
+	lat, lon := rng.Float64()*180-90, rng.Float64()*360-180
+	latRef, lonRef := "N", "E"
+	if lat < 0 {
+		latRef = "S"
+	}
+	if lon < 0 {
+		lonRef = "W"
+	}
+
+	exifIFD := []tiffEntry{asciiEntry(0x9003, dateTime)}
+	gpsIFD := []tiffEntry{
+		asciiEntry(0x0001, latRef),
+		degreesEntry(0x0002, lat),
+		asciiEntry(0x0003, lonRef),
+		degreesEntry(0x0004, lon),
+	}
+	ifd0 := []tiffEntry{
+		asciiEntry(0x010F, camera[0]),
+		asciiEntry(0x0110, camera[1]),
+		asciiEntry(0x0132, dateTime),
+		longEntry(0x8769, 0), // Exif IFD pointer, patched below
+		longEntry(0x8825, 0), // GPS IFD pointer, patched below
+	}
+
+	const headerSize = 8
+	ifd0Offset := headerSize
+	exifOffset := ifd0Offset + ifdSize(ifd0)
+	gpsOffset := exifOffset + ifdSize(exifIFD)
+	ifd0[3] = longEntry(0x8769, uint32(exifOffset))
+	ifd0[4] = longEntry(0x8825, uint32(gpsOffset))
+
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	tiff.Write(binary.LittleEndian.AppendUint16(nil, 42))
+	tiff.Write(binary.LittleEndian.AppendUint32(nil, uint32(ifd0Offset)))
+	writeIFD(&tiff, ifd0, ifd0Offset)
+	writeIFD(&tiff, exifIFD, exifOffset)
+	writeIFD(&tiff, gpsIFD, gpsOffset)
+
+	return append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+}
+
+// insertEXIF inserts an APP1 segment holding payload right after the JPEG SOI marker
+func insertEXIF(jpegData, payload []byte) []byte {
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := make([]byte, 0, len(jpegData)+len(segment))
+	out = append(out, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+// GenerateAvatar renders a size x size PNG suitable as a profile picture
+func GenerateAvatar(rng *rand.Rand, size int) ([]byte, error) {
+	return encodeImage(drawImage(rng, size, size), "png")
+}
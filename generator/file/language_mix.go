@@ -0,0 +1,89 @@
+package file
+
+import (
+	"math/rand"
+)
+
+// Language is the language of generated content: a single language code, or a
+// weighted mix each sentence, name and run of words draws its language from
+type Language struct {
+	code string
+	mix  *languageMix
+}
+
+// languageMix is a weighted mix of languages
+type languageMix struct {
+	langs      []string
+	weights    []float64 // Normalized to sum to 1
+	cumulative []float64
+}
+
+// NewLanguage returns the content language of a single language code
+func NewLanguage(code string) Language {
+	return Language{code: code}
+}
+
+// MixLanguages returns a content language mixing langs by weight; languages without
+// a positive weight are left out, and a mix without any is English
+func MixLanguages(langs []string, weights []float64) Language {
+	m := &languageMix{}
+	total := 0.0
+	for i, lang := range langs {
+		if i >= len(weights) || weights[i] <= 0 {
+			continue
+		}
+		total += weights[i]
+		m.langs = append(m.langs, lang)
+		m.weights = append(m.weights, weights[i])
+		m.cumulative = append(m.cumulative, total)
+	}
+	if len(m.langs) == 0 {
+		return NewLanguage("en")
+	}
+	for i := range m.weights {
+		m.weights[i] /= total
+		m.cumulative[i] /= total
+	}
+	return Language{mix: m}
+}
+
+// draw returns the language code of the next sentence, name or run of words; a single
+// language returns its code without drawing from rng
+func (l Language) draw(rng *rand.Rand) string {
+	if l.mix == nil {
+		return l.code
+	}
+	r := rng.Float64()
+	for i, c := range l.mix.cumulative {
+		if r < c {
+			return l.mix.langs[i]
+		}
+	}
+	return l.mix.langs[len(l.mix.langs)-1]
+}
+
+// Primary returns the most weighted language of a mix, or the single language code
+func (l Language) Primary() string {
+	if l.mix == nil {
+		return l.code
+	}
+	best := 0
+	for i, w := range l.mix.weights {
+		if w > l.mix.weights[best] {
+			best = i
+		}
+	}
+	return l.mix.langs[best]
+}
+
+// Breakdown returns the share of each language of a mix; a single language yields nil
+func (l Language) Breakdown() map[string]float64 {
+	if l.mix == nil {
+		return nil
+	}
+	breakdown := make(map[string]float64, len(l.mix.langs))
+	for i, lang := range l.mix.langs {
+		breakdown[lang] += l.mix.weights[i]
+	}
+	return breakdown
+}
@@ -0,0 +1,157 @@
+package file
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// mailDomains are the domains used for generated email addresses
+var mailDomains = []string{"example.com", "example.org", "example.net", "corp.example", "mail.example"}
+
+// generateMailAddress generates an address with an ASCII local part and a display name in lang
+func generateMailAddress(rng *rand.Rand, lang string) *mail.Address {
+	local := generateEnglishWord(rng) + "." + generateEnglishWord(rng)
+	return &mail.Address{
+		Name:    strings.Join(generateWords(rng, lang, 2), " "),
+		Address: local + "@" + mailDomains[rng.Intn(len(mailDomains))],
+	}
+}
+
+// wrapBase64 base64-encodes data in 76-character lines as required by RFC 2045
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.String()
+}
+
+// generateEML produces an RFC 5322 multipart message with a multilingual body and attachments
+func generateEML(rng *rand.Rand, lang Language, targetSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	from := generateMailAddress(rng, lang.draw(rng))
+	var to []string
+	for i := 0; i < 1+rng.Intn(3); i++ {
+		to = append(to, generateMailAddress(rng, lang.draw(rng)).String())
+	}
+	date := randomDate(rng)
+
+	mw := multipart.NewWriter(&buf)
+	headers := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", GenerateFolderName(rng, lang.draw(rng)))},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%d.%d@%s>", date.Unix(), rng.Int63(), mailDomains[rng.Intn(len(mailDomains))])},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/mixed; boundary=\"" + mw.Boundary() + "\""},
+	}
+	var header bytes.Buffer
+	for _, h := range headers {
+		header.WriteString(h.key + ": " + h.value + "\r\n")
+	}
+	header.WriteString("\r\n")
+
+	// Body: about a third of the target size
+	var body strings.Builder
+	for body.Len() < targetSize/3 {
+		body.WriteString(generateSentence(rng, lang.draw(rng)) + "\r\n")
+	}
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(wrapBase64([]byte(body.String())))); err != nil {
+		return nil, err
+	}
+
+	// Attachments fill the remaining size
+	for buf.Len()+header.Len() < targetSize {
+		data, err := generateCSV(rng, lang, clampSize(targetSize/3, 512, 1024*1024))
+		if err != nil {
+			return nil, err
+		}
+		name := mime.QEncoding.Encode("utf-8", GenerateFilename(rng, []string{lang.draw(rng)})+".csv")
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"text/csv; charset=utf-8; name=\"" + name + "\""},
+			"Content-Disposition":       {"attachment; filename=\"" + name + "\""},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write([]byte(wrapBase64(data))); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return append(header.Bytes(), buf.Bytes()...), nil
+}
+
+// escapeICS escapes TEXT property values per RFC 5545
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICS folds a content line to 75 octets without splitting UTF-8 sequences
+func foldICS(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+// generateICS produces an RFC 5545 calendar with events until targetSize is reached
+func generateICS(rng *rand.Rand, lang Language, targetSize int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//songvi//robo//EN\r\nCALSCALE:GREGORIAN\r\n")
+	const stamp = "20060102T150405Z"
+	for buf.Len() < targetSize {
+		start := randomDate(rng).Truncate(30 * time.Minute)
+		end := start.Add(time.Duration(1+rng.Intn(6)) * 30 * time.Minute)
+		organizer := generateMailAddress(rng, lang.draw(rng))
+
+		buf.WriteString("BEGIN:VEVENT\r\n")
+		buf.WriteString(foldICS(fmt.Sprintf("UID:%d-%d@robo.example", start.Unix(), rng.Int63())))
+		buf.WriteString(foldICS("DTSTAMP:" + start.Add(-24*time.Hour).Format(stamp)))
+		buf.WriteString(foldICS("DTSTART:" + start.Format(stamp)))
+		buf.WriteString(foldICS("DTEND:" + end.Format(stamp)))
+		buf.WriteString(foldICS("SUMMARY:" + escapeICS(GenerateFolderName(rng, lang.draw(rng)))))
+		buf.WriteString(foldICS("DESCRIPTION:" + escapeICS(generateSentence(rng, lang.draw(rng))+"\n"+generateSentence(rng, lang.draw(rng)))))
+		buf.WriteString(foldICS("LOCATION:" + escapeICS(GenerateFolderName(rng, lang.draw(rng)))))
+		buf.WriteString(foldICS(fmt.Sprintf("ORGANIZER;CN=%q:mailto:%s", organizer.Name, organizer.Address)))
+		for i := 0; i < 1+rng.Intn(4); i++ {
+			attendee := generateMailAddress(rng, lang.draw(rng))
+			buf.WriteString(foldICS(fmt.Sprintf("ATTENDEE;CN=%q;ROLE=REQ-PARTICIPANT:mailto:%s", attendee.Name, attendee.Address)))
+		}
+		buf.WriteString("END:VEVENT\r\n")
+	}
+	buf.WriteString("END:VCALENDAR\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,161 @@
+package file
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// defaultMarkovOrder is the number of preceding tokens a chain state is built from
+const defaultMarkovOrder = 2
+
+// maxMarkovTokens caps the length of a generated sentence
+const maxMarkovTokens = 60
+
+// markovEnd marks the end of a sentence in the transition table
+const markovEnd = "\x00end"
+
+// MarkovModel is a token-level Markov chain trained on a text corpus. Corpora written
+// without spaces between words (Chinese, Japanese, Thai) are tokenized per character.
+type MarkovModel struct {
+	order       int
+	separator   string
+	starts      [][]string
+	transitions map[string][]string
+}
+
+// NewMarkovModel trains a Markov chain of the given order on text
+func NewMarkovModel(text string, order int) (*MarkovModel, error) {
+	if order <= 0 {
+		order = defaultMarkovOrder
+	}
+	sentences := splitSentences(text)
+	spaced := 0
+	for _, sentence := range sentences {
+		if strings.ContainsRune(sentence, ' ') {
+			spaced++
+		}
+	}
+
+	m := &MarkovModel{order: order, transitions: make(map[string][]string)}
+	if spaced*2 >= len(sentences) {
+		m.separator = " "
+	}
+	for _, sentence := range sentences {
+		var tokens []string
+		if m.separator == "" {
+			for _, r := range sentence {
+				tokens = append(tokens, string(r))
+			}
+		} else {
+			tokens = strings.Fields(sentence)
+		}
+		if len(tokens) <= order {
+			continue
+		}
+		m.starts = append(m.starts, tokens[:order])
+		for i := order; i <= len(tokens); i++ {
+			next := markovEnd
+			if i < len(tokens) {
+				next = tokens[i]
+			}
+			key := strings.Join(tokens[i-order:i], "\x00")
+			m.transitions[key] = append(m.transitions[key], next)
+		}
+	}
+	if len(m.starts) == 0 {
+		return nil, fmt.Errorf("corpus has no sentence longer than %d tokens", order)
+	}
+	return m, nil
+}
+
+// splitSentences splits text on sentence-ending punctuation and line breaks
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			sentences = append(sentences, s)
+		}
+		current.Reset()
+	}
+	for _, r := range text {
+		switch r {
+		case '\n', '\r':
+			flush()
+			continue
+		}
+		if unicode.IsSpace(r) {
+			r = ' '
+		}
+		current.WriteRune(r)
+		switch r {
+		case '.', '!', '?', '。', '！', '？', '؟', '।':
+			flush()
+		}
+	}
+	flush()
+	return sentences
+}
+
+// Sentence walks the chain from a random start state until the end of a sentence
+func (m *MarkovModel) Sentence(rng *rand.Rand) string {
+	tokens := append([]string(nil), m.starts[rng.Intn(len(m.starts))]...)
+	for len(tokens) < maxMarkovTokens {
+		candidates := m.transitions[strings.Join(tokens[len(tokens)-m.order:], "\x00")]
+		if len(candidates) == 0 {
+			break
+		}
+		next := candidates[rng.Intn(len(candidates))]
+		if next == markovEnd {
+			break
+		}
+		tokens = append(tokens, next)
+	}
+	return strings.Join(tokens, m.separator)
+}
+
+// markovModels holds the chains registered per language; generateSentence prefers
+// them over the built-in sentence templates
+var (
+	markovMu     sync.RWMutex
+	markovModels = make(map[string]*MarkovModel)
+)
+
+// RegisterMarkovModel makes generateSentence draw sentences for lang from model.
+// A nil model restores the built-in templates.
+func RegisterMarkovModel(lang string, model *MarkovModel) {
+	markovMu.Lock()
+	defer markovMu.Unlock()
+	if model == nil {
+		delete(markovModels, lang)
+		return
+	}
+	markovModels[lang] = model
+}
+
+// markovModel returns the chain registered for lang, if any
+func markovModel(lang string) *MarkovModel {
+	markovMu.RLock()
+	defer markovMu.RUnlock()
+	return markovModels[lang]
+}
+
+// LoadCorpora trains a Markov model for every language -> corpus file path and registers it
+func LoadCorpora(paths map[string]string, order int) error {
+	for lang, path := range paths {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s corpus: %v", lang, err)
+		}
+		model, err := NewMarkovModel(string(text), order)
+		if err != nil {
+			return fmt.Errorf("failed to train %s corpus %s: %v", lang, path, err)
+		}
+		RegisterMarkovModel(lang, model)
+	}
+	return nil
+}
@@ -3,31 +3,30 @@ package file
 import (
 	"math/rand"
 	"strings"
-	"time"
 )
 
-// Configuration: Set to true for romanized Chinese, Thai, Japanese, and Arabic, false for native scripts
-const useRomanized = false // Affects Chinese, Thai, Japanese, Arabic; Korean always uses Hangul
+// Configuration: Set to true for romanized or ASCII-folded words, false for native scripts
+const useRomanized = false // Affects every language but Korean, which always uses Hangul
 
 // Generate an English-like word (Latin-based)
-func generateEnglishWord() string {
+func generateEnglishWord(rng *rand.Rand) string {
 	vowels := []string{"a", "e", "i", "o", "u", "ai", "ea", "ou"}
 	consonants := []string{"b", "c", "d", "f", "g", "h", "j", "k", "l", "m", "n", "p", "r", "s", "t", "v", "w", "y", "sh", "ch", "th"}
-	length := 3 + rand.Intn(5) // 3 to 7 letters
+	length := 3 + rng.Intn(5) // 3 to 7 letters
 
 	word := ""
 	for i := 0; i < length; i++ {
 		if i%2 == 0 {
-			word += consonants[rand.Intn(len(consonants))]
+			word += consonants[rng.Intn(len(consonants))]
 		} else {
-			word += vowels[rand.Intn(len(vowels))]
+			word += vowels[rng.Intn(len(vowels))]
 		}
 	}
 	return word
 }
 
 // Generate a non-English word based on specified language
-func generateNonEnglishWord(lang string) string {
+func generateNonEnglishWord(rng *rand.Rand, lang string) string {
 	langPatterns := map[string]struct {
 		native        []string // Native script characters or syllables
 		romanized     []string // Romanized equivalents (used for Chinese, Thai, Japanese, Arabic if useRomanized = true)
@@ -38,43 +37,79 @@ func generateNonEnglishWord(lang string) string {
 			native:        []string{"nâm", "hỏa", "lân", "thư", "mình", "ngọc", "tâm", "việt", "phố", "sông", "hà", "nội", "đà", "nẵng", "huế", "cần"},
 			romanized:     []string{"nam", "hoa", "lan", "thu", "minh", "ngoc", "tam", "viet", "pho", "song", "ha", "noi", "da", "nang", "hue", "can"},
 			suffixes:      []string{"", "", ""}, // No suffixes
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
 		},
 		"ge": { // German-like (e.g., mü, schön, with umlauts and ß)
 			native:        []string{"mü", "schön", "wald", "stern", "bau", "feld", "himmel", "licht", "tag", "nacht", "straße", "berg", "fluss", "baum", "grün", "weiß"},
 			romanized:     []string{"mue", "schoen", "wald", "stern", "bau", "feld", "himmel", "licht", "tag", "nacht", "strasse", "berg", "fluss", "baum", "gruen", "weiss"},
 			suffixes:      []string{"en", "er", "d", "e", "in"},
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
 		},
 		"cn": { // Chinese-like (e.g., 好, 星, Pinyin: hao, xing)
 			native:        []string{"好", "星", "美", "兰", "君", "伟", "青", "书", "天", "花", "月", "山", "水", "风", "云", "龙", "凤", "春", "秋"},
 			romanized:     []string{"hao", "xing", "mei", "lan", "jun", "wei", "qing", "shu", "tian", "hua", "yue", "shan", "shui", "feng", "yun", "long", "feng", "chun", "qiu"},
 			suffixes:      []string{"", "", ""}, // No suffixes
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
 		},
 		"kn": { // Korean-like (e.g., 하, 나, 별, always native Hangul)
 			native:        []string{"하", "나", "별", "미", "지", "라", "고", "타", "영", "수", "강", "산", "바", "람", "꽃", "하늘", "달", "빛", "소리"},
 			romanized:     []string{"하", "나", "별", "미", "지", "라", "고", "타", "영", "수", "강", "산", "바", "람", "꽃", "하늘", "달", "빛", "소리"}, // Ignored, always native
 			suffixes:      []string{"", "ㄴ", "ㅁ", "이"},                                                                               // Native Hangul suffixes
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
 		},
-		"tl": { // Thai-like (e.g., ชัย, สุข, Romanized: chai, suk)
+		"tl": { // Tagalog-like (e.g., mahal, bayan, Latin script)
+			native:        []string{"ma", "ga", "la", "sa", "ka", "ba", "ta", "na", "pa", "li", "wa", "ngi", "lu", "bi", "ha", "yan"},
+			romanized:     []string{"ma", "ga", "la", "sa", "ka", "ba", "ta", "na", "pa", "li", "wa", "ngi", "lu", "bi", "ha", "yan"},
+			suffixes:      []string{"", "an", "in", "ng"},
+			syllableCount: 2 + rng.Intn(2),
+		},
+		"th": { // Thai-like (e.g., ชัย, สุข, Romanized: chai, suk)
 			native:        []string{"ชัย", "สุข", "รถ", "ผัด", "ใหม่", "น้ำ", "ขาว", "ลม", "ดิน", "ไฟ", "ฟ้า", "ต้น", "ใบ", "หิน", "แสง", "เงา"},
 			romanized:     []string{"chai", "suk", "rot", "phat", "mai", "nam", "khao", "lom", "din", "fai", "fa", "ton", "bai", "hin", "saeng", "ngao"},
 			suffixes:      []string{"", "ต", "น", "ม"}, // Native suffixes (romanized: t, n, m)
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
+		},
+		"ru": { // Russian-like (e.g., лес, свет, Romanized: les, svet)
+			native:        []string{"мир", "лес", "дом", "свет", "ра", "ко", "ни", "ва", "ло", "ми", "сне", "го", "ба", "ту", "зор", "река"},
+			romanized:     []string{"mir", "les", "dom", "svet", "ra", "ko", "ni", "va", "lo", "mi", "sne", "go", "ba", "tu", "zor", "reka"},
+			suffixes:      []string{"ка", "ов", "ий", "ня"}, // Romanized: ka, ov, iy, nya
+			syllableCount: 1 + rng.Intn(2),
+		},
+		"hi": { // Hindi-like (e.g., राम, दीप, Romanized: ram, deep)
+			native:        []string{"राम", "सूर", "धन", "मन", "जल", "कम", "नव", "प्रि", "देव", "सा", "रा", "गी", "वन", "सुख", "दीप", "तारा"},
+			romanized:     []string{"ram", "sur", "dhan", "man", "jal", "kam", "nav", "pri", "dev", "sa", "ra", "gi", "van", "sukh", "deep", "tara"},
+			suffixes:      []string{"का", "जी", "वान", "पुर"}, // Romanized: ka, ji, van, pur
+			syllableCount: 1 + rng.Intn(2),
+		},
+		"he": { // Hebrew-like (e.g., אור, שיר, Romanized: or, shir)
+			native:        []string{"של", "אור", "מים", "שמש", "ים", "דר", "גל", "בית", "לב", "עיר", "טל", "רון", "נוף", "שיר", "חן", "עץ"},
+			romanized:     []string{"shal", "or", "mayim", "shemesh", "yam", "dar", "gal", "beit", "lev", "ir", "tal", "ron", "nof", "shir", "chen", "etz"},
+			suffixes:      []string{"ים", "ות", "ה", "י"}, // Romanized: im, ot, a, i
+			syllableCount: 1 + rng.Intn(2),
+		},
+		"es": { // Spanish-like (e.g., sol, río, with accents and ñ)
+			native:        []string{"sol", "mar", "luz", "ca", "ra", "mi", "lo", "ta", "río", "flor", "cie", "no", "ña", "bo", "ve", "pá"},
+			romanized:     []string{"sol", "mar", "luz", "ca", "ra", "mi", "lo", "ta", "rio", "flor", "cie", "no", "na", "bo", "ve", "pa"},
+			suffixes:      []string{"o", "a", "os", "ción"},
+			syllableCount: 1 + rng.Intn(2),
+		},
+		"pt": { // Portuguese-like (e.g., céu, pão, with accents and ç)
+			native:        []string{"sol", "mar", "luz", "ção", "ra", "mi", "lo", "ta", "rio", "flor", "céu", "nho", "lha", "bo", "ve", "pão"},
+			romanized:     []string{"sol", "mar", "luz", "cao", "ra", "mi", "lo", "ta", "rio", "flor", "ceu", "nho", "lha", "bo", "ve", "pao"},
+			suffixes:      []string{"o", "a", "ões", "inho"},
+			syllableCount: 1 + rng.Intn(2),
 		},
 		"jp": { // Japanese-like (e.g., さ, く, Hiragana, Romanized: sa, ku)
 			native:        []string{"さ", "く", "ら", "み", "な", "き", "ゆ", "め", "ひ", "ろ", "か", "ぜ", "そ", "ら", "つ", "き", "や", "ま", "は", "な"},
 			romanized:     []string{"sa", "ku", "ra", "mi", "na", "ki", "yu", "me", "hi", "ro", "ka", "ze", "so", "ra", "tsu", "ki", "ya", "ma", "ha", "na"},
 			suffixes:      []string{"", "ん", "い", "う"}, // Native suffixes (romanized: n, i, u)
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
 		},
 		"ar": { // Arabic-like (e.g., نور, سلا, Transliterated: nur, sala)
 			native:        []string{"نور", "سلا", "رح", "مح", "زي", "حل", "جم", "فر", "قمر", "شمس", "نجم", "سماء", "بحر", "رمل", "ضوء", "هواء"},
 			romanized:     []string{"nur", "sala", "rah", "mah", "zi", "hal", "jam", "far", "qamar", "shams", "najm", "sama", "bahr", "raml", "daw", "hawa"},
 			suffixes:      []string{"", "ة", "ي", "ات"}, // Native suffixes (romanized: a, i, at)
-			syllableCount: 1 + rand.Intn(2),
+			syllableCount: 1 + rng.Intn(2),
 		},
 	}
 
@@ -88,18 +123,18 @@ func generateNonEnglishWord(lang string) string {
 	// Generate syllables
 	for i := 0; i < pattern.syllableCount; i++ {
 		if lang == "kn" || !useRomanized {
-			word += pattern.native[rand.Intn(len(pattern.native))]
+			word += pattern.native[rng.Intn(len(pattern.native))]
 		} else {
-			word += pattern.romanized[rand.Intn(len(pattern.romanized))]
+			word += pattern.romanized[rng.Intn(len(pattern.romanized))]
 		}
 	}
 
 	// Add suffix with 50% probability
-	if rand.Float32() < 0.5 && len(pattern.suffixes) > 0 && pattern.suffixes[0] != "" {
+	if rng.Float32() < 0.5 && len(pattern.suffixes) > 0 && pattern.suffixes[0] != "" {
 		if lang == "kn" || !useRomanized {
-			word += pattern.suffixes[rand.Intn(len(pattern.suffixes))]
+			word += pattern.suffixes[rng.Intn(len(pattern.suffixes))]
 		} else {
-			word += pattern.suffixes[rand.Intn(len(pattern.suffixes))]
+			word += pattern.suffixes[rng.Intn(len(pattern.suffixes))]
 		}
 	}
 
@@ -116,38 +151,49 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// Generate a filename with 2 to 5 words, all from one randomly chosen language
-func GenerateFilename(langs []string) string {
-	rand.Seed(time.Now().UnixNano())
-
-	// Randomly choose one language from the provided list
-	lang := langs[rand.Intn(len(langs))]
-
-	// Randomly choose number of words (2 to 5)
-	numWords := 2 + rand.Intn(4)
+// generateWord generates a single word in the given language
+func generateWord(rng *rand.Rand, lang string) string {
+	if lang == "en" {
+		return generateEnglishWord(rng)
+	}
+	return generateNonEnglishWord(rng, lang)
+}
 
-	// Collect words
+// generateWords generates numWords distinct words in the given language
+func generateWords(rng *rand.Rand, lang string, numWords int) []string {
 	var selectedWords []string
-
-	// Generate words in the chosen language
 	for i := 0; i < numWords; i++ {
-		var word string
-		if lang == "en" {
-			word = generateEnglishWord()
-		} else {
-			word = generateNonEnglishWord(lang)
-		}
+		word := generateWord(rng, lang)
 		// Avoid duplicates
 		for contains(selectedWords, word) {
-			if lang == "en" {
-				word = generateEnglishWord()
-			} else {
-				word = generateNonEnglishWord(lang)
-			}
+			word = generateWord(rng, lang)
 		}
 		selectedWords = append(selectedWords, word)
 	}
+	return selectedWords
+}
+
+// Generate a filename with 2 to 5 words, all from one randomly chosen language
+func GenerateFilename(rng *rand.Rand, langs []string) string {
+	// Randomly choose one language from the provided list
+	lang := langs[rng.Intn(len(langs))]
 
-	// Join words with hyphens
-	return strings.Join(selectedWords, " ")
+	// Randomly choose number of words (2 to 5)
+	numWords := 2 + rng.Intn(4)
+
+	// Join words with spaces
+	return strings.Join(generateWords(rng, lang, numWords), " ")
+}
+
+// GenerateFolderName generates a folder or workspace name with 1 to 3 words in the given language
+func GenerateFolderName(rng *rand.Rand, lang string) string {
+	numWords := 1 + rng.Intn(3)
+	words := generateWords(rng, lang, numWords)
+	// Latin-script folder names read more naturally capitalized
+	if lang == "en" {
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
 }
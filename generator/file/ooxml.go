@@ -0,0 +1,59 @@
+package file
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// ooxmlPackage writes the parts of an Office Open XML package into a zip container
+type ooxmlPackage struct {
+	cw *countingWriter
+	zw *zip.Writer
+}
+
+// newOOXMLPackage starts an OOXML package written to w
+func newOOXMLPackage(w io.Writer) *ooxmlPackage {
+	cw := &countingWriter{w: w}
+	return &ooxmlPackage{cw: cw, zw: zip.NewWriter(cw)}
+}
+
+// add writes a deflated part at name
+func (p *ooxmlPackage) add(name, content string) error {
+	part, err := p.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
+
+// size returns the number of compressed bytes flushed so far
+func (p *ooxmlPackage) size() int {
+	return p.cw.n
+}
+
+// close finishes the zip container
+func (p *ooxmlPackage) close() error {
+	return p.zw.Close()
+}
+
+// xmlEscape escapes text for use in XML character data and attribute values
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
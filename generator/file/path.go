@@ -0,0 +1,103 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/songvi/robo/models"
+)
+
+// nameRegistry remembers the file names handed out so far. Names compare without case
+// since Windows and macOS shares would store two names differing only in case as one.
+type nameRegistry struct {
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// SanitizeFilename replaces the path separators and NUL bytes a file name must not
+// contain, and prefixes names that would denote the current or parent directory
+func SanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', 0:
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+	return name
+}
+
+// CycleNamespace is the directory or key prefix the files of a cycle are stored
+// under, so removing it cleans the cycle up; files outside any cycle have none
+func CycleNamespace(cycleID string) string {
+	if cycleID == "" {
+		return ""
+	}
+	return path.Join("cycles", SanitizeFilename(cycleID))
+}
+
+// StoreName is the slash-separated name file is stored under: its LocalName inside
+// the namespace of its cycle
+func StoreName(file *models.File) string {
+	return path.Join(CycleNamespace(file.CycleID), LocalName(file))
+}
+
+// LocalPath is the path file is staged at in the repository
+func (g *FileContentGenerator) LocalPath(file *models.File) string {
+	return filepath.Join(g.RepositoryPath, filepath.FromSlash(StoreName(file)))
+}
+
+// UniqueName sanitizes name and, when name.ext was handed out before or already exists
+// in the namespace of CycleID, appends a short random suffix until it is unique. Names
+// at the length limit are shortened to make room for the suffix, and for the version
+// marker of the last of versions revisions the file may get.
+func (g *FileContentGenerator) UniqueName(name, ext string, versions int) string {
+	limit := maxFilenameBytes - len(versionMarker(versions)) - len(ext) - 1
+	name = trimName(SanitizeFilename(name), limit)
+	r := g.names
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidate := name
+	for g.nameTaken(candidate, ext) {
+		suffix := fmt.Sprintf("-%04x", g.rng.Intn(1<<16))
+		candidate = trimName(name, limit-len(suffix)) + suffix
+	}
+	r.used[g.nameKey(candidate, ext)] = true
+	return candidate
+}
+
+// trimName drops the last runes of name until it takes at most limit bytes
+func trimName(name string, limit int) string {
+	for len(name) > limit && name != "" {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// nameKey identifies name.ext within the namespace of CycleID in the registry
+func (g *FileContentGenerator) nameKey(name, ext string) string {
+	return path.Join(CycleNamespace(g.CycleID), strings.ToLower(name+"."+ext))
+}
+
+// nameTaken tells whether name.ext was handed out or exists on disk; the caller holds
+// the registry lock
+func (g *FileContentGenerator) nameTaken(name, ext string) bool {
+	if g.names.used[g.nameKey(name, ext)] {
+		return true
+	}
+	if g.DryRun {
+		return false
+	}
+	_, err := os.Lstat(g.LocalPath(&models.File{Name: name, FileExtension: ext, CycleID: g.CycleID}))
+	return err == nil
+}
@@ -0,0 +1,165 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfSectionBytes is the initial guess of the output size of one PDF section
+const pdfSectionBytes = 6000
+
+// writePDFSection adds a bookmarked heading followed by paragraphs and, now and
+// then, a table or an embedded image
+func writePDFSection(pdf *gofpdf.Fpdf, fonts *pdfFonts, rng *rand.Rand, lang Language, number int) error {
+	title := fmt.Sprintf("%d. %s", number, GenerateFolderName(rng, lang.draw(rng)))
+	if err := fonts.use(title, 16); err != nil {
+		return err
+	}
+	if number > 1 && rng.Intn(3) == 0 {
+		pdf.AddPage()
+	}
+	pdf.Ln(4)
+	pdf.Bookmark(title, 0, -1)
+	pdf.MultiCell(0, 8, title, "", "L", false)
+	pdf.Ln(2)
+
+	for i := 0; i < 2+rng.Intn(4); i++ {
+		var paragraph string
+		for j := 0; j < 2+rng.Intn(5); j++ {
+			paragraph += generateSentence(rng, lang.draw(rng)) + " "
+		}
+		if err := fonts.use(paragraph, 11); err != nil {
+			return err
+		}
+		pdf.MultiCell(0, 5, paragraph, "", "L", false)
+		pdf.Ln(2)
+	}
+
+	switch rng.Intn(10) {
+	case 0, 1, 2, 3:
+		return writePDFTable(pdf, fonts, rng, lang)
+	case 4, 5, 6:
+		return writePDFImage(pdf, rng, number)
+	}
+	return nil
+}
+
+// writePDFTable adds a bordered table with a shaded header row
+func writePDFTable(pdf *gofpdf.Fpdf, fonts *pdfFonts, rng *rand.Rand, lang Language) error {
+	widths := []float64{70, 40, 40, 30}
+	header := []string{GenerateFolderName(rng, lang.draw(rng)), GenerateFolderName(rng, lang.draw(rng)), GenerateFolderName(rng, lang.draw(rng)), "#"}
+	cell := func(i int, text, align string, fill bool) error {
+		if err := fonts.use(text, 10); err != nil {
+			return err
+		}
+		pdf.CellFormat(widths[i], 7, text, "1", 0, align, fill, 0, "")
+		return nil
+	}
+
+	pdf.SetFillColor(220, 226, 235)
+	for i, text := range header {
+		if err := cell(i, text, "C", true); err != nil {
+			return err
+		}
+	}
+	pdf.Ln(-1)
+	for row := 0; row < 3+rng.Intn(10); row++ {
+		values := []string{
+			generateWord(rng, lang.draw(rng)),
+			randomDate(rng).Format("2006-01-02"),
+			strconv.FormatFloat(rng.Float64()*10000, 'f', 2, 64),
+			strconv.Itoa(rng.Intn(1000)),
+		}
+		for i, text := range values {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			if err := cell(i, text, align, false); err != nil {
+				return err
+			}
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+	return nil
+}
+
+// writePDFImage embeds a generated JPEG picture scaled to the page width
+func writePDFImage(pdf *gofpdf.Fpdf, rng *rand.Rand, number int) error {
+	width := 240 + rng.Intn(400)
+	data, err := encodeImage(drawImage(rng, width, width*3/4), "jpeg")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("figure%d", number)
+	options := gofpdf.ImageOptions{ImageType: "JPG"}
+	pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(data))
+	pdf.ImageOptions(name, -1, -1, 120, 0, true, options, 0, "")
+	pdf.Ln(4)
+	return pdf.Error()
+}
+
+// renderPDF lays out sections until there are numSections of them
+func renderPDF(rng *rand.Rand, lang Language, fontSet *FontSet, numSections int) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	fonts := &pdfFonts{set: fontSet, pdf: pdf, added: make(map[string]bool)}
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		if fonts.use("0", 8) == nil {
+			pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+		}
+	})
+	pdf.AddPage()
+
+	for i := 1; i <= numSections; i++ {
+		if err := writePDFSection(pdf, fonts, rng, lang, i); err != nil {
+			return nil, err
+		}
+		if pdf.Err() {
+			return nil, pdf.Error()
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// generatePDF writes a multi-page document with an outline, headings, tables and
+// images whose size approaches targetSize, adjusting the number of sections over a
+// few render passes
+func generatePDF(w io.Writer, rng *rand.Rand, lang Language, fontSet *FontSet, targetSize int) error {
+	seed := rng.Int63()
+	numSections := max(1, targetSize/pdfSectionBytes)
+
+	var data []byte
+	for attempt := 0; attempt < 4; attempt++ {
+		// Render the same document at each length so retries stay deterministic
+		var err error
+		if data, err = renderPDF(rand.New(rand.NewSource(seed)), lang, fontSet, numSections); err != nil {
+			return err
+		}
+		ratio := float64(targetSize) / float64(len(data))
+		if ratio > 0.9 && ratio < 1.1 {
+			break
+		}
+		next := max(1, int(math.Round(float64(numSections)*ratio)))
+		if next == numSections {
+			break
+		}
+		numSections = next
+	}
+	_, err := w.Write(data)
+	return err
+}
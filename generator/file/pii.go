@@ -0,0 +1,243 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/songvi/robo/models"
+)
+
+// PII types InjectPII embeds
+const (
+	PIICreditCard = "credit_card"
+	PIIIBAN       = "iban"
+	PIISSN        = "ssn"
+	PIIEmail      = "email"
+)
+
+// PIITypes lists every PII type InjectPII supports
+var PIITypes = []string{PIICreditCard, PIIIBAN, PIISSN, PIIEmail}
+
+// piiExtensions are the text formats PII is embedded into. Values go between two
+// words, so they land in prose, string values or text nodes without breaking the format.
+var piiExtensions = map[string]bool{"txt": true, "md": true, "csv": true, "json": true, "xml": true}
+
+// cardPrefixes are issuer prefixes with the card number length they use
+var cardPrefixes = []struct {
+	prefix string
+	length int
+}{
+	{"4", 16}, {"51", 16}, {"52", 16}, {"53", 16}, {"54", 16}, {"55", 16},
+	{"34", 15}, {"37", 15}, {"6011", 16},
+}
+
+// ibanFormats are BBAN layouts per country: 'a' is an upper-case letter, 'n' a digit
+var ibanFormats = []struct{ country, bban string }{
+	{"DE", "nnnnnnnnnnnnnnnnnn"},
+	{"FR", "nnnnnnnnnnnnnnnnnnnnnnn"},
+	{"GB", "aaaannnnnnnnnnnnnn"},
+	{"ES", "nnnnnnnnnnnnnnnnnnnn"},
+	{"NL", "aaaannnnnnnnnn"},
+	{"IT", "annnnnnnnnnnnnnnnnnnnnn"},
+}
+
+// luhnCheckDigit returns the digit completing digits into a Luhn-valid number
+func luhnCheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		// Double every second digit counting from the check digit
+		if (len(digits)-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// generateCardNumber returns a Luhn-valid card number grouped like printed cards
+func generateCardNumber(rng *rand.Rand) string {
+	card := cardPrefixes[rng.Intn(len(cardPrefixes))]
+	digits := []byte(card.prefix)
+	for len(digits) < card.length-1 {
+		digits = append(digits, byte('0'+rng.Intn(10)))
+	}
+	digits = append(digits, luhnCheckDigit(string(digits)))
+
+	groups := []int{4, 4, 4, 4}
+	if card.length == 15 {
+		groups = []int{4, 6, 5}
+	}
+	separator := []string{" ", "-", ""}[rng.Intn(3)]
+	var parts []string
+	for _, n := range groups {
+		parts = append(parts, string(digits[:n]))
+		digits = digits[n:]
+	}
+	return strings.Join(parts, separator)
+}
+
+// ibanCheckDigits computes the ISO 13616 check digits of an IBAN without them
+func ibanCheckDigits(country, bban string) string {
+	var numeric strings.Builder
+	for _, r := range bban + country + "00" {
+		if r >= 'A' && r <= 'Z' {
+			numeric.WriteString(fmt.Sprint(r - 'A' + 10))
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	n, _ := new(big.Int).SetString(numeric.String(), 10)
+	mod := new(big.Int).Mod(n, big.NewInt(97)).Int64()
+	return fmt.Sprintf("%02d", 98-mod)
+}
+
+// generateIBAN returns an IBAN with valid check digits, in the printed format of
+// space-separated groups of four half of the time
+func generateIBAN(rng *rand.Rand) string {
+	format := ibanFormats[rng.Intn(len(ibanFormats))]
+	bban := make([]byte, len(format.bban))
+	for i, c := range format.bban {
+		if c == 'a' {
+			bban[i] = byte('A' + rng.Intn(26))
+		} else {
+			bban[i] = byte('0' + rng.Intn(10))
+		}
+	}
+	iban := format.country + ibanCheckDigits(format.country, string(bban)) + string(bban)
+	if rng.Intn(2) == 0 {
+		return iban
+	}
+	var groups []string
+	for len(iban) > 4 {
+		groups = append(groups, iban[:4])
+		iban = iban[4:]
+	}
+	return strings.Join(append(groups, iban), " ")
+}
+
+// generateSSN returns a token shaped like a US social security number, avoiding the
+// area numbers never issued (000, 666 and 900-999)
+func generateSSN(rng *rand.Rand) string {
+	area := 1 + rng.Intn(899)
+	if area == 666 {
+		area = 665
+	}
+	return fmt.Sprintf("%03d-%02d-%04d", area, 1+rng.Intn(99), 1+rng.Intn(9999))
+}
+
+// GeneratePII returns a synthetic value of the given PII type
+func GeneratePII(rng *rand.Rand, piiType string) (string, error) {
+	switch piiType {
+	case PIICreditCard:
+		return generateCardNumber(rng), nil
+	case PIIIBAN:
+		return generateIBAN(rng), nil
+	case PIISSN:
+		return generateSSN(rng), nil
+	case PIIEmail:
+		return generateMailAddress(rng, "en").Address, nil
+	}
+	return "", fmt.Errorf("unknown PII type %q", piiType)
+}
+
+// wordGaps returns the offsets just after every space separating two words, leaving
+// out XML markup
+func wordGaps(data []byte, xml bool) []int {
+	var gaps []int
+	inTag := false
+	for i := 1; i < len(data)-1; i++ {
+		switch data[i] {
+		case '<':
+			inTag = xml
+		case '>':
+			inTag = false
+		case ' ':
+			if inTag {
+				continue
+			}
+			before, _ := utf8.DecodeLastRune(data[:i])
+			after, _ := utf8.DecodeRune(data[i+1:])
+			if unicode.IsLetter(before) && unicode.IsLetter(after) {
+				gaps = append(gaps, i+1)
+			}
+		}
+	}
+	return gaps
+}
+
+// InjectPII embeds count synthetic values of the given types (all types when empty)
+// between words of the local copy of file and records where they went in file.PII.
+// Formats values cannot be embedded in safely, and dry runs, are left unchanged.
+func (g *FileContentGenerator) InjectPII(file *models.File, types []string, count int) error {
+	ext := strings.ToLower(file.FileExtension)
+	if !piiExtensions[ext] || count <= 0 || g.DryRun {
+		return nil
+	}
+	if len(types) == 0 {
+		types = PIITypes
+	}
+	fullPath := g.LocalPath(file)
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file for PII: %v", err)
+	}
+	gaps := wordGaps(data, ext == "xml")
+	if len(gaps) == 0 {
+		return nil
+	}
+
+	// Pick distinct gaps and fill them front to back
+	picked := g.rng.Perm(len(gaps))[:min(count, len(gaps))]
+	sort.Ints(picked)
+	var out bytes.Buffer
+	last := 0
+	for _, p := range picked {
+		piiType := types[g.rng.Intn(len(types))]
+		value, err := GeneratePII(g.rng, piiType)
+		if err != nil {
+			return err
+		}
+		out.Write(data[last:gaps[p]])
+		file.PII = append(file.PII, models.PIILocation{Type: piiType, Value: value, Offset: out.Len()})
+		out.WriteString(value + " ")
+		last = gaps[p]
+	}
+	out.Write(data[last:])
+
+	if err := os.WriteFile(fullPath, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file with PII: %v", err)
+	}
+	file.FileSize = out.Len()
+	return nil
+}
+
+// RelocatePII records in next the PII of prev still found in its content, in order,
+// dropping values a revision edited away
+func (g *FileContentGenerator) RelocatePII(prev, next *models.File) error {
+	data, err := os.ReadFile(g.LocalPath(next))
+	if err != nil {
+		return fmt.Errorf("failed to read file for PII: %v", err)
+	}
+	next.PII = nil
+	from := 0
+	for _, pii := range prev.PII {
+		i := bytes.Index(data[from:], []byte(pii.Value))
+		if i < 0 {
+			continue
+		}
+		pii.Offset = from + i
+		next.PII = append(next.PII, pii)
+		from = pii.Offset + len(pii.Value)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+const pptxNamespaces = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+
+const pptxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="ppt/presentation.xml"/>` +
+	`</Relationships>`
+
+const pptxEmptySpTree = `<p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/></p:spTree>`
+
+const pptxSlideMaster = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sldMaster ` + pptxNamespaces + `><p:cSld>` + pptxEmptySpTree + `</p:cSld>` +
+	`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+	`<p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst></p:sldMaster>`
+
+const pptxSlideMasterRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="../theme/theme1.xml"/>` +
+	`</Relationships>`
+
+const pptxSlideLayout = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sldLayout ` + pptxNamespaces + ` type="blank"><p:cSld name="Blank">` + pptxEmptySpTree + `</p:cSld>` +
+	`<p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sldLayout>`
+
+const pptxSlideLayoutRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster" Target="../slideMasters/slideMaster1.xml"/>` +
+	`</Relationships>`
+
+const pptxSlideRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/>` +
+	`</Relationships>`
+
+const pptxTheme = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="Robo"><a:themeElements>` +
+	`<a:clrScheme name="Robo">` +
+	`<a:dk1><a:sysClr val="windowText" lastClr="000000"/></a:dk1><a:lt1><a:sysClr val="window" lastClr="FFFFFF"/></a:lt1>` +
+	`<a:dk2><a:srgbClr val="1F497D"/></a:dk2><a:lt2><a:srgbClr val="EEECE1"/></a:lt2>` +
+	`<a:accent1><a:srgbClr val="4F81BD"/></a:accent1><a:accent2><a:srgbClr val="C0504D"/></a:accent2>` +
+	`<a:accent3><a:srgbClr val="9BBB59"/></a:accent3><a:accent4><a:srgbClr val="8064A2"/></a:accent4>` +
+	`<a:accent5><a:srgbClr val="4BACC6"/></a:accent5><a:accent6><a:srgbClr val="F79646"/></a:accent6>` +
+	`<a:hlink><a:srgbClr val="0000FF"/></a:hlink><a:folHlink><a:srgbClr val="800080"/></a:folHlink></a:clrScheme>` +
+	`<a:fontScheme name="Robo"><a:majorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:majorFont>` +
+	`<a:minorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:minorFont></a:fontScheme>` +
+	`<a:fmtScheme name="Robo">` +
+	`<a:fillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:fillStyleLst>` +
+	`<a:lnStyleLst><a:ln w="9525"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="25400"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="38100"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln></a:lnStyleLst>` +
+	`<a:effectStyleLst><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle></a:effectStyleLst>` +
+	`<a:bgFillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:bgFillStyleLst>` +
+	`</a:fmtScheme></a:themeElements></a:theme>`
+
+// pptxTextBox renders a text box shape with the given paragraphs at the given position (EMU)
+func pptxTextBox(id int, name string, x, y, cx, cy int, size int, bullets bool, paragraphs []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`, id, name)
+	fmt.Fprintf(&b, `<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr>`, x, y, cx, cy)
+	b.WriteString(`<p:txBody><a:bodyPr wrap="square"><a:normAutofit/></a:bodyPr><a:lstStyle/>`)
+	for _, text := range paragraphs {
+		b.WriteString("<a:p>")
+		if bullets {
+			b.WriteString(`<a:pPr marL="342900" indent="-342900"><a:buChar char="•"/></a:pPr>`)
+		}
+		fmt.Fprintf(&b, `<a:r><a:rPr lang="en-US" sz="%d"/><a:t>%s</a:t></a:r></a:p>`, size, xmlEscape(text))
+	}
+	b.WriteString("</p:txBody></p:sp>")
+	return b.String()
+}
+
+// generatePPTXSlide renders one slide with a title and 3 to 7 bullet points
+func generatePPTXSlide(rng *rand.Rand, lang Language) string {
+	var bullets []string
+	for i := 0; i < 3+rng.Intn(5); i++ {
+		bullets = append(bullets, generateSentence(rng, lang.draw(rng)))
+	}
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sld ` + pptxNamespaces + `><p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/>` +
+		pptxTextBox(2, "Title", 457200, 274638, 8229600, 1143000, 3600, false, []string{GenerateFolderName(rng, lang.draw(rng))}) +
+		pptxTextBox(3, "Content", 457200, 1600200, 8229600, 4525963, 2000, true, bullets) +
+		`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
+}
+
+// generatePPTX writes a presentation with generated multilingual slides until the
+// compressed package approaches targetSize
+func generatePPTX(w io.Writer, rng *rand.Rand, lang Language, targetSize int) error {
+	pkg := newOOXMLPackage(w)
+	fixed := []struct{ name, content string }{
+		{"_rels/.rels", pptxRootRels},
+		{"ppt/slideMasters/slideMaster1.xml", pptxSlideMaster},
+		{"ppt/slideMasters/_rels/slideMaster1.xml.rels", pptxSlideMasterRels},
+		{"ppt/slideLayouts/slideLayout1.xml", pptxSlideLayout},
+		{"ppt/slideLayouts/_rels/slideLayout1.xml.rels", pptxSlideLayoutRels},
+		{"ppt/theme/theme1.xml", pptxTheme},
+	}
+	for _, part := range fixed {
+		if err := pkg.add(part.name, part.content); err != nil {
+			return err
+		}
+	}
+
+	numSlides := 0
+	for numSlides == 0 || pkg.size() < targetSize {
+		numSlides++
+		if err := pkg.add(fmt.Sprintf("ppt/slides/slide%d.xml", numSlides), generatePPTXSlide(rng, lang)); err != nil {
+			return err
+		}
+		if err := pkg.add(fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", numSlides), pptxSlideRels); err != nil {
+			return err
+		}
+	}
+
+	var contentTypes, presentationRels, slideIDs strings.Builder
+	contentTypes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/ppt/presentation.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"/>` +
+		`<Override PartName="/ppt/slideMasters/slideMaster1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"/>` +
+		`<Override PartName="/ppt/slideLayouts/slideLayout1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"/>` +
+		`<Override PartName="/ppt/theme/theme1.xml" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/>`)
+	presentationRels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster" Target="slideMasters/slideMaster1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="theme/theme1.xml"/>`)
+	for i := 1; i <= numSlides; i++ {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/ppt/slides/slide%d.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/>`, i)
+		fmt.Fprintf(&presentationRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide%d.xml"/>`, i+2, i)
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="rId%d"/>`, 255+i, i+2)
+	}
+	contentTypes.WriteString("</Types>")
+	presentationRels.WriteString("</Relationships>")
+
+	presentation := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:presentation ` + pptxNamespaces + `>` +
+		`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>` +
+		`<p:sldIdLst>` + slideIDs.String() + `</p:sldIdLst>` +
+		`<p:sldSz cx="9144000" cy="6858000" type="screen4x3"/><p:notesSz cx="6858000" cy="9144000"/></p:presentation>`
+
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"ppt/presentation.xml", presentation},
+		{"ppt/_rels/presentation.xml.rels", presentationRels.String()},
+	} {
+		if err := pkg.add(part.name, part.content); err != nil {
+			return err
+		}
+	}
+	return pkg.close()
+}
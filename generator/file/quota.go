@@ -0,0 +1,126 @@
+package file
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// quotaEntry is one file tracked by a RepositoryQuota
+type quotaEntry struct {
+	path      string
+	size      int64
+	cycle     string
+	createdAt time.Time
+}
+
+// RepositoryQuota bounds the disk used by generated files. When a limit is exceeded
+// the oldest files are deleted; files older than the retention are deleted as well.
+type RepositoryQuota struct {
+	MaxBytes      int64         // Global limit; zero means unlimited
+	MaxCycleBytes int64         // Limit per cycle; zero means unlimited
+	Retention     time.Duration // Maximum file age; zero keeps files forever
+
+	mu       sync.Mutex
+	entries  []quotaEntry // Oldest first
+	total    int64
+	perCycle map[string]int64
+	now      func() time.Time
+}
+
+// NewRepositoryQuota creates a RepositoryQuota with the given limits
+func NewRepositoryQuota(maxBytes, maxCycleBytes int64, retention time.Duration) *RepositoryQuota {
+	return &RepositoryQuota{
+		MaxBytes:      maxBytes,
+		MaxCycleBytes: maxCycleBytes,
+		Retention:     retention,
+		perCycle:      make(map[string]int64),
+		now:           time.Now,
+	}
+}
+
+// Scan tracks the files already present under dir, oldest first, so limits also
+// cover what previous runs left behind
+func (q *RepositoryQuota) Scan(dir string) error {
+	var found []quotaEntry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		found = append(found, quotaEntry{path: path, size: info.Size(), createdAt: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].createdAt.Before(found[j].createdAt) })
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, e := range found {
+		q.track(e)
+	}
+	return nil
+}
+
+// track appends e to the accounting
+func (q *RepositoryQuota) track(e quotaEntry) {
+	q.entries = append(q.entries, e)
+	q.total += e.size
+	q.perCycle[e.cycle] += e.size
+}
+
+// Add tracks a newly written file and enforces the limits, returning the paths of
+// the files it deleted. The file just added is never deleted.
+func (q *RepositoryQuota) Add(path string, size int64, cycle string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.track(quotaEntry{path: path, size: size, cycle: cycle, createdAt: q.now()})
+
+	var removed []string
+	kept := q.entries[:0]
+	last := len(q.entries) - 1
+	for i, e := range q.entries {
+		expired := q.Retention > 0 && q.now().Sub(e.createdAt) > q.Retention
+		overGlobal := q.MaxBytes > 0 && q.total > q.MaxBytes
+		overCycle := q.MaxCycleBytes > 0 && e.cycle == cycle && cycle != "" && q.perCycle[cycle] > q.MaxCycleBytes
+		if i == last || !(expired || overGlobal || overCycle) {
+			kept = append(kept, e)
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove %s over quota: %v", e.path, err)
+			kept = append(kept, e)
+			continue
+		}
+		q.total -= e.size
+		q.perCycle[e.cycle] -= e.size
+		if q.perCycle[e.cycle] <= 0 {
+			delete(q.perCycle, e.cycle)
+		}
+		removed = append(removed, e.path)
+	}
+	q.entries = kept
+	return removed
+}
+
+// Usage returns the bytes tracked globally and for cycle
+func (q *RepositoryQuota) Usage(cycle string) (total, cycleBytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.total, q.perCycle[cycle]
+}
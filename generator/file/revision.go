@@ -0,0 +1,82 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/songvi/robo/models"
+)
+
+// LocalName is the file name a generated file is staged and stored under. Revisions
+// after the first get a version marker so they do not overwrite their predecessors;
+// UniqueName leaves room for it.
+func LocalName(file *models.File) string {
+	return file.Name + versionMarker(file.Version) + "." + file.FileExtension
+}
+
+// versionMarker is what LocalName adds to the name of revision version
+func versionMarker(version int) string {
+	if version > 1 {
+		return fmt.Sprintf(".v%d", version)
+	}
+	return ""
+}
+
+// Revise writes next as a revision of prev, which must still be on local disk. Prose
+// formats get a few lines rewritten, inserted or deleted, CSV files a few changed
+// letters and formats that tolerate trailing bytes a short appended tail. Any other
+// format is regenerated in full, as happens when such a document is saved again.
+func (g *FileContentGenerator) Revise(prev, next *models.File, lang Language) error {
+	if g.DryRun {
+		g.planRevision(prev, next)
+		return nil
+	}
+	ext := strings.ToLower(next.FileExtension)
+	if !letterMutableExtensions[ext] && !tailTolerantExtensions[ext] {
+		next.FileSize = prev.FileSize
+		return g.GenerateContent(next, lang)
+	}
+
+	data, err := os.ReadFile(g.LocalPath(prev))
+	if err != nil {
+		return fmt.Errorf("failed to read previous revision: %v", err)
+	}
+	switch ext {
+	case "txt", "md":
+		data = g.editLines(data, lang)
+	case "csv":
+		data = g.mutateLetters(data)
+	default:
+		tail := make([]byte, 16+g.rng.Intn(241))
+		g.rng.Read(tail)
+		data = append(data, tail...)
+	}
+
+	fullPath := g.LocalPath(next)
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write revision: %v", err)
+	}
+	next.FileSize = len(data)
+	g.remember(ext, fullPath)
+	return nil
+}
+
+// editLines applies one to three line edits: a line is replaced by a new sentence, a
+// new sentence is inserted, or a line is deleted
+func (g *FileContentGenerator) editLines(data []byte, lang Language) []byte {
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	for i := 0; i < 1+g.rng.Intn(3); i++ {
+		pos := g.rng.Intn(len(lines))
+		switch op := g.rng.Intn(3); {
+		case op == 0:
+			lines[pos] = []byte(generateSentence(g.rng, lang.draw(g.rng)))
+		case op == 1 || len(lines) == 1:
+			lines = append(lines[:pos+1], append([][]byte{[]byte(generateSentence(g.rng, lang.draw(g.rng)))}, lines[pos+1:]...)...)
+		default:
+			lines = append(lines[:pos], lines[pos+1:]...)
+		}
+	}
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
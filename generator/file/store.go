@@ -0,0 +1,297 @@
+package file
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// FileStore is where generated files end up. Content is always rendered into the
+// generator's local RepositoryPath first and then handed to the store.
+type FileStore interface {
+	// Put stores the file at localPath under name and returns its location
+	Put(ctx context.Context, localPath, name string) (string, error)
+}
+
+// LocalStore keeps files on local disk under Dir
+type LocalStore struct {
+	Dir string
+}
+
+// NewLocalStore creates a LocalStore rooted at dir
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{Dir: dir}
+}
+
+// Put moves the file into Dir, leaving it in place when it is already there
+func (s *LocalStore) Put(ctx context.Context, localPath, name string) (string, error) {
+	dest := filepath.Join(s.Dir, filepath.FromSlash(name))
+	if filepath.Clean(localPath) == dest {
+		return dest, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := os.Rename(localPath, dest); err == nil {
+		return dest, nil
+	}
+	// Rename fails across devices; fall back to copying
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", dest, err)
+	}
+	defer out.Close()
+	if err := copyFile(out, localPath); err != nil {
+		return "", fmt.Errorf("failed to copy file to %s: %v", dest, err)
+	}
+	return dest, os.Remove(localPath)
+}
+
+// S3Config configures an S3 or MinIO bucket, addressed path-style
+type S3Config struct {
+	Endpoint  string `json:"endpoint" yaml:"endpoint"` // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Region    string `json:"region" yaml:"region"`
+	Bucket    string `json:"bucket" yaml:"bucket"`
+	Prefix    string `json:"prefix" yaml:"prefix"` // Key prefix for every object
+	AccessKey string `json:"access_key" yaml:"access_key"`
+	SecretKey string `json:"secret_key" yaml:"secret_key"`
+}
+
+// S3Store uploads files as objects with SigV4-signed PUT requests
+type S3Store struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Store creates an S3Store for cfg
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 file store requires an endpoint and a bucket")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3Store{config: cfg, client: &http.Client{}, now: time.Now}, nil
+}
+
+// Put uploads the file as object Prefix+name
+func (s *S3Store) Put(ctx context.Context, localPath, name string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %v", err)
+	}
+
+	key := path.Join(s.config.Prefix, filepath.ToSlash(name))
+	escapedPath := "/" + uriEncode(s.config.Bucket) + "/" + uriEncode(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimRight(s.config.Endpoint, "/")+escapedPath, f)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = info.Size()
+	s.sign(req, escapedPath)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to s3: %v", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to upload %s to s3: unexpected status %s: %s", key, resp.Status, body)
+	}
+	return fmt.Sprintf("s3://%s/%s", s.config.Bucket, key), nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. The payload is left unsigned so
+// large files can be streamed without hashing them first.
+func (s *S3Store) sign(req *http.Request, escapedPath string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, payloadHash, amzDate)
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{req.Method, escapedPath, "", canonicalHeaders, signedHeaders, payloadHash}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.config.Region)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + s.config.SecretKey)
+	for _, part := range []string{date, s.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but RFC 3986 unreserved characters and '/'
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// WebDAVConfig configures a WebDAV collection
+type WebDAVConfig struct {
+	URL      string `json:"url" yaml:"url"` // Base collection URL
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// WebDAVStore uploads files with PUT, creating parent collections with MKCOL
+type WebDAVStore struct {
+	config WebDAVConfig
+	client *http.Client
+}
+
+// NewWebDAVStore creates a WebDAVStore for cfg
+func NewWebDAVStore(cfg WebDAVConfig) (*WebDAVStore, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webdav file store requires a url")
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return &WebDAVStore{config: cfg, client: &http.Client{}}, nil
+}
+
+// do sends a request with basic auth when credentials are configured
+func (s *WebDAVStore) do(ctx context.Context, method, url string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+	return s.client.Do(req)
+}
+
+// Put creates the parent collections of name and uploads the file
+func (s *WebDAVStore) Put(ctx context.Context, localPath, name string) (string, error) {
+	name = filepath.ToSlash(name)
+	// MKCOL answers 405 when the collection already exists
+	dirs := strings.Split(path.Dir(name), "/")
+	for i := range dirs {
+		if dirs[i] == "." {
+			break
+		}
+		resp, err := s.do(ctx, "MKCOL", s.config.URL+"/"+uriEncode(strings.Join(dirs[:i+1], "/"))+"/", nil, 0)
+		if err != nil {
+			return "", fmt.Errorf("failed to create webdav collection: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+			return "", fmt.Errorf("failed to create webdav collection: unexpected status %s", resp.Status)
+		}
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %v", err)
+	}
+	url := s.config.URL + "/" + uriEncode(name)
+	resp, err := s.do(ctx, http.MethodPut, url, f, info.Size())
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to webdav: %v", name, err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return url, nil
+	}
+	return "", fmt.Errorf("failed to upload %s to webdav: unexpected status %s", name, resp.Status)
+}
+
+// Publish records the SHA-256 of the generated file in ContentID, hands the file to
+// the configured Store and records its location in FileContent. Unless KeepLocal is set, remote stores get the only copy and the
+// local file stops being a duplicate source. Files kept on local disk count against
+// the repository Quota. Dry runs have nothing to publish.
+func (g *FileContentGenerator) Publish(ctx context.Context, file *models.File) error {
+	if g.DryRun {
+		return nil
+	}
+	name := StoreName(file)
+	localPath := g.LocalPath(file)
+	keptPath := localPath
+
+	id, err := contentID(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash generated file: %v", err)
+	}
+	file.ContentID = id
+
+	if g.Store != nil {
+		location, err := g.Store.Put(ctx, localPath, name)
+		if err != nil {
+			return err
+		}
+		file.FileContent = location
+
+		if _, local := g.Store.(*LocalStore); local {
+			// The file may have moved to another directory
+			if location != localPath {
+				g.forget(localPath)
+				g.remember(strings.ToLower(file.FileExtension), location)
+			}
+			keptPath = location
+		} else if !g.KeepLocal {
+			g.forget(localPath)
+			if err := os.Remove(localPath); err != nil {
+				return fmt.Errorf("failed to remove staged file: %v", err)
+			}
+			keptPath = ""
+		}
+	}
+
+	if g.Quota != nil && keptPath != "" {
+		info, err := os.Stat(keptPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat stored file: %v", err)
+		}
+		for _, removed := range g.Quota.Add(keptPath, info.Size(), file.CycleID) {
+			g.forget(removed)
+		}
+	}
+	return nil
+}
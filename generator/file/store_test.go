@@ -0,0 +1,186 @@
+package file
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+// recordingServer stores every PUT body by escaped path and answers MKCOL
+type recordingServer struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   map[string]string
+}
+
+func newRecordingServer(t *testing.T) (*recordingServer, *httptest.Server) {
+	rs := &recordingServer{bodies: make(map[string]string)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		rs.requests = append(rs.requests, r)
+		switch r.Method {
+		case "MKCOL":
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPut:
+			rs.bodies[r.URL.EscapedPath()] = string(body)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return rs, srv
+}
+
+func writeTempFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "staged")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestS3Store(t *testing.T) {
+	rs, srv := newRecordingServer(t)
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "robo", Prefix: "cycle-1", AccessKey: "AKID", SecretKey: "secret"})
+	require.NoError(t, err)
+
+	location, err := store.Put(context.Background(), writeTempFile(t, "hello"), "report 👍.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "s3://robo/cycle-1/report 👍.txt", location)
+	assert.Equal(t, "hello", rs.bodies["/robo/cycle-1/report%20%F0%9F%91%8D.txt"])
+
+	auth := rs.requests[0].Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"), auth)
+	assert.Contains(t, auth, "/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=")
+	assert.Equal(t, "UNSIGNED-PAYLOAD", rs.requests[0].Header.Get("x-amz-content-sha256"))
+}
+
+func TestS3StoreSignature(t *testing.T) {
+	store, err := NewS3Store(S3Config{Endpoint: "http://minio:9000", Region: "eu-west-1", Bucket: "robo", AccessKey: "AKID", SecretKey: "secret"})
+	require.NoError(t, err)
+	store.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	req, err := http.NewRequest(http.MethodPut, "http://minio:9000/robo/a%20b.txt", nil)
+	require.NoError(t, err)
+	store.sign(req, "/robo/a%20b.txt")
+	// Reference signature computed independently from the SigV4 specification
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKID/20240501/eu-west-1/s3/aws4_request, "+
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, "+
+		"Signature=4b88e05850e4d16c70ddc2295690c5ca7e0c675bec401ccef0e775ea36b4e4a1", req.Header.Get("Authorization"))
+}
+
+func TestWebDAVStore(t *testing.T) {
+	rs, srv := newRecordingServer(t)
+	store, err := NewWebDAVStore(WebDAVConfig{URL: srv.URL + "/dav/", Username: "robo", Password: "pw"})
+	require.NoError(t, err)
+
+	location, err := store.Put(context.Background(), writeTempFile(t, "hello"), "team/docs/plan.txt")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/dav/team/docs/plan.txt", location)
+	assert.Equal(t, "hello", rs.bodies["/dav/team/docs/plan.txt"])
+
+	require.Len(t, rs.requests, 3)
+	assert.Equal(t, "MKCOL", rs.requests[0].Method)
+	assert.Equal(t, "/dav/team/", rs.requests[0].URL.Path)
+	assert.Equal(t, "/dav/team/docs/", rs.requests[1].URL.Path)
+	user, pass, ok := rs.requests[2].BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "robo", user)
+	assert.Equal(t, "pw", pass)
+}
+
+func TestPublish(t *testing.T) {
+	rs, srv := newRecordingServer(t)
+	dir := t.TempDir()
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(1)))
+	g.Store, _ = NewWebDAVStore(WebDAVConfig{URL: srv.URL})
+
+	f := &models.File{Name: "notes", FileExtension: "txt", FileSize: 2048}
+	require.NoError(t, g.GenerateContent(f, NewLanguage("en")))
+	require.NoError(t, g.Publish(context.Background(), f))
+	assert.Equal(t, srv.URL+"/notes.txt", f.FileContent)
+	assert.NotEmpty(t, rs.bodies["/notes.txt"])
+
+	_, err := os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.True(t, os.IsNotExist(err), "the staged copy is removed")
+	assert.Empty(t, g.sources.byExt["txt"], "removed files are no longer duplicate sources")
+
+	// A local store moves the file into its directory
+	dest := t.TempDir()
+	g.Store = NewLocalStore(dest)
+	require.NoError(t, g.GenerateContent(f, NewLanguage("en")))
+	require.NoError(t, g.Publish(context.Background(), f))
+	assert.Equal(t, filepath.Join(dest, "notes.txt"), f.FileContent)
+	assert.Equal(t, []string{f.FileContent}, g.sources.byExt["txt"])
+
+	// Files of a cycle are staged and stored under its namespace, where names are unique
+	// on their own
+	g.CycleID = "c1"
+	assert.Equal(t, "notes", g.UniqueName("notes", "txt", 1))
+	f = &models.File{Name: "notes", FileExtension: "txt", FileSize: 2048, CycleID: "c1"}
+	assert.Equal(t, filepath.Join(dir, "cycles", "c1", "notes.txt"), g.LocalPath(f))
+	require.NoError(t, g.GenerateContent(f, NewLanguage("en")))
+	require.NoError(t, g.Publish(context.Background(), f))
+	assert.Equal(t, filepath.Join(dest, "cycles", "c1", "notes.txt"), f.FileContent)
+	assert.FileExists(t, f.FileContent)
+}
+
+func TestRepositoryQuota(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+		return path
+	}
+
+	// Files from a previous run are accounted for
+	old := write("old.bin", 400)
+	quota := NewRepositoryQuota(1000, 500, time.Hour)
+	require.NoError(t, quota.Scan(dir))
+	now := time.Now()
+	quota.now = func() time.Time { return now }
+
+	a := write("a.bin", 300)
+	assert.Empty(t, quota.Add(a, 300, "cycle-1"))
+	b := write("b.bin", 400)
+	assert.Equal(t, []string{old}, quota.Add(b, 400, "cycle-2"), "the oldest file goes first when the global quota is exceeded")
+
+	c := write("c.bin", 300)
+	assert.Equal(t, []string{a}, quota.Add(c, 300, "cycle-1"), "the per-cycle quota evicts within the cycle")
+	total, cycleBytes := quota.Usage("cycle-1")
+	assert.Equal(t, int64(700), total)
+	assert.Equal(t, int64(300), cycleBytes)
+
+	// Retention removes files past their age, but never the file just added
+	now = now.Add(2 * time.Hour)
+	d := write("d.bin", 10)
+	assert.ElementsMatch(t, []string{b, c}, quota.Add(d, 10, ""))
+	for _, path := range []string{old, a, b, c} {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err), path)
+	}
+
+	// Publish forgets evicted files as duplicate sources
+	g := NewFileContentGenerator(dir, rand.New(rand.NewSource(1)))
+	g.Quota = NewRepositoryQuota(1, 0, 0)
+	for _, name := range []string{"first", "second"} {
+		f := &models.File{Name: name, FileExtension: "txt", FileSize: 1024}
+		require.NoError(t, g.GenerateContent(f, NewLanguage("en")))
+		require.NoError(t, g.Publish(context.Background(), f))
+	}
+	assert.Equal(t, []string{filepath.Join(dir, "second.txt")}, g.sources.byExt["txt"])
+}
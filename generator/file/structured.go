@@ -0,0 +1,190 @@
+package file
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clampSize bounds a requested file size to [minSize, maxSize]
+func clampSize(size, minSize, maxSize int) int {
+	if size < minSize {
+		return minSize
+	}
+	if size > maxSize {
+		return maxSize
+	}
+	return size
+}
+
+// randomDate returns a random date within the last five years
+func randomDate(rng *rand.Rand) time.Time {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	return base.Add(time.Duration(rng.Int63n(int64(5 * 365 * 24 * time.Hour))))
+}
+
+// generateCSV produces rows of typed columns until targetSize is reached
+func generateCSV(rng *rand.Rand, lang Language, targetSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"id", "name", "created_at", "amount", "quantity", "active", "note"}); err != nil {
+		return nil, err
+	}
+	for id := 1; buf.Len() < targetSize; id++ {
+		record := []string{
+			strconv.Itoa(id),
+			strings.Join(generateWords(rng, lang.draw(rng), 1+rng.Intn(2)), " "),
+			randomDate(rng).Format("2006-01-02"),
+			strconv.FormatFloat(rng.Float64()*10000, 'f', 2, 64),
+			strconv.Itoa(rng.Intn(1000)),
+			strconv.FormatBool(rng.Float32() < 0.5),
+			generateSentence(rng, lang.draw(rng)),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+		w.Flush()
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// jsonAuthor is the nested author object of a generated JSON document
+type jsonAuthor struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+}
+
+// jsonMetrics is the nested metrics object of a generated JSON document
+type jsonMetrics struct {
+	Views  int     `json:"views"`
+	Rating float64 `json:"rating"`
+}
+
+// jsonDocument is a single random record in a generated JSON file
+type jsonDocument struct {
+	ID        int         `json:"id"`
+	Title     string      `json:"title"`
+	Author    jsonAuthor  `json:"author"`
+	Tags      []string    `json:"tags"`
+	CreatedAt time.Time   `json:"created_at"`
+	Published bool        `json:"published"`
+	Metrics   jsonMetrics `json:"metrics"`
+	Body      []string    `json:"body"`
+}
+
+// generateJSON produces an array of nested documents until targetSize is reached
+func generateJSON(rng *rand.Rand, lang Language, targetSize int) ([]byte, error) {
+	var docs []jsonDocument
+	size := 0
+	for id := 1; size < targetSize; id++ {
+		doc := jsonDocument{
+			ID:        id,
+			Title:     GenerateFolderName(rng, lang.draw(rng)),
+			Author:    jsonAuthor{Name: strings.Join(generateWords(rng, lang.draw(rng), 2), " "), Language: lang.Primary()},
+			Tags:      generateWords(rng, lang.draw(rng), 1+rng.Intn(4)),
+			CreatedAt: randomDate(rng),
+			Published: rng.Float32() < 0.7,
+			Metrics:   jsonMetrics{Views: rng.Intn(100000), Rating: float64(rng.Intn(50)) / 10},
+		}
+		for i := 0; i < 1+rng.Intn(5); i++ {
+			doc.Body = append(doc.Body, generateSentence(rng, lang.draw(rng)))
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		size += len(encoded)
+		docs = append(docs, doc)
+	}
+	return json.MarshalIndent(docs, "", "  ")
+}
+
+// xmlDocument is a single random record in a generated XML file
+type xmlDocument struct {
+	XMLName   xml.Name `xml:"document"`
+	ID        int      `xml:"id,attr"`
+	Language  string   `xml:"lang,attr"`
+	Title     string   `xml:"title"`
+	Author    string   `xml:"author"`
+	CreatedAt string   `xml:"created_at"`
+	Keywords  []string `xml:"keywords>keyword"`
+	Body      []string `xml:"body>paragraph"`
+}
+
+// xmlDocuments is the root element of a generated XML file
+type xmlDocuments struct {
+	XMLName   xml.Name      `xml:"documents"`
+	Documents []xmlDocument `xml:"document"`
+}
+
+// generateXML produces a well-formed XML document collection until targetSize is reached
+func generateXML(rng *rand.Rand, lang Language, targetSize int) ([]byte, error) {
+	var root xmlDocuments
+	size := 0
+	for id := 1; size < targetSize; id++ {
+		doc := xmlDocument{
+			ID:        id,
+			Language:  lang.Primary(),
+			Title:     GenerateFolderName(rng, lang.draw(rng)),
+			Author:    strings.Join(generateWords(rng, lang.draw(rng), 2), " "),
+			CreatedAt: randomDate(rng).Format(time.RFC3339),
+			Keywords:  generateWords(rng, lang.draw(rng), 1+rng.Intn(4)),
+		}
+		for i := 0; i < 1+rng.Intn(5); i++ {
+			doc.Body = append(doc.Body, generateSentence(rng, lang.draw(rng)))
+		}
+		encoded, err := xml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		size += len(encoded)
+		root.Documents = append(root.Documents, doc)
+	}
+	data, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// generateMarkdown produces sections with headings, paragraphs, lists and tables until targetSize is reached
+func generateMarkdown(rng *rand.Rand, lang Language, targetSize int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", GenerateFolderName(rng, lang.draw(rng)))
+	for buf.Len() < targetSize {
+		fmt.Fprintf(&buf, "## %s\n\n", GenerateFolderName(rng, lang.draw(rng)))
+
+		// Paragraph
+		for i := 0; i < 2+rng.Intn(4); i++ {
+			buf.WriteString(generateSentence(rng, lang.draw(rng)) + " ")
+		}
+		buf.WriteString("\n\n")
+
+		switch rng.Intn(3) {
+		case 0:
+			// Bullet list
+			for i := 0; i < 2+rng.Intn(4); i++ {
+				fmt.Fprintf(&buf, "- %s\n", generateSentence(rng, lang.draw(rng)))
+			}
+		case 1:
+			// Table
+			buf.WriteString("| Name | Date | Amount |\n|------|------|-------:|\n")
+			for i := 0; i < 2+rng.Intn(5); i++ {
+				fmt.Fprintf(&buf, "| %s | %s | %.2f |\n",
+					generateWord(rng, lang.draw(rng)), randomDate(rng).Format("2006-01-02"), rng.Float64()*1000)
+			}
+		case 2:
+			// Quote
+			fmt.Fprintf(&buf, "> %s\n", generateSentence(rng, lang.draw(rng)))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
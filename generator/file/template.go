@@ -0,0 +1,178 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/songvi/robo/models"
+)
+
+// templateExtensions are the formats a rendered template can be written as. Text formats
+// take the output verbatim; docx gets one paragraph per line.
+var templateExtensions = map[string]bool{
+	"txt": true, "md": true, "csv": true, "json": true, "xml": true,
+	"eml": true, "ics": true, "docx": true,
+}
+
+// ContentTemplate is a parsed text/template used as the body of generated documents
+type ContentTemplate struct {
+	name string
+	tmpl *template.Template
+}
+
+// templateData is the value a content template is executed with
+type templateData struct {
+	Name      string // File name without extension
+	Extension string
+	Lang      string
+}
+
+// templateFuncs returns the generators available to templates, drawing from rng in lang
+func templateFuncs(rng *rand.Rand, lang Language) template.FuncMap {
+	return template.FuncMap{
+		"word":     func() string { return generateWord(rng, lang.draw(rng)) },
+		"words":    func(n int) string { return strings.Join(generateWords(rng, lang.draw(rng), n), " ") },
+		"sentence": func() string { return generateSentence(rng, lang.draw(rng)) },
+		"paragraph": func(n int) string {
+			sentences := make([]string, n)
+			for i := range sentences {
+				sentences[i] = generateSentence(rng, lang.draw(rng))
+			}
+			return strings.Join(sentences, " ")
+		},
+		"name":   func() string { return GenerateFolderName(rng, lang.Primary()) },
+		"person": func() string { return strings.Join(generateWords(rng, lang.draw(rng), 2), " ") },
+		"email":  func() string { return generateMailAddress(rng, lang.draw(rng)).Address },
+		"company": func() string {
+			return GenerateFolderName(rng, lang.Primary()) + " " + companySuffixes[rng.Intn(len(companySuffixes))]
+		},
+		"date": func(layout ...string) string {
+			if len(layout) == 0 {
+				return randomDate(rng).Format("2006-01-02")
+			}
+			return randomDate(rng).Format(layout[0])
+		},
+		"int":    func(lo, hi int) int { return lo + rng.Intn(hi-lo+1) },
+		"amount": func(lo, hi float64) string { return strconv.FormatFloat(lo+rng.Float64()*(hi-lo), 'f', 2, 64) },
+		"digits": func(n int) string {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = byte('0' + rng.Intn(10))
+			}
+			return string(b)
+		},
+		"pick": func(options ...string) string { return options[rng.Intn(len(options))] },
+		"seq": func(n int) []int {
+			s := make([]int, n)
+			for i := range s {
+				s[i] = i + 1
+			}
+			return s
+		},
+	}
+}
+
+// companySuffixes are appended to generated company names
+var companySuffixes = []string{"Ltd", "Inc.", "GmbH", "SA", "LLC", "Co."}
+
+// ParseContentTemplate parses the template file at path. Besides the template data
+// (.Name, .Extension, .Lang), templates can call word, words n, sentence, paragraph n,
+// name, person, email, company, date [layout], int min max, amount min max, digits n,
+// pick options... and seq n.
+func ParseContentTemplate(path string) (*ContentTemplate, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %v", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs(nil, Language{})).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %v", path, err)
+	}
+	return &ContentTemplate{name: filepath.Base(path), tmpl: tmpl}, nil
+}
+
+// LoadContentTemplates parses the template files configured per extension
+func LoadContentTemplates(paths map[string][]string) (map[string][]*ContentTemplate, error) {
+	templates := make(map[string][]*ContentTemplate)
+	for ext, files := range paths {
+		ext = strings.ToLower(ext)
+		if !templateExtensions[ext] {
+			return nil, fmt.Errorf("content templates are not supported for %s files", ext)
+		}
+		for _, path := range files {
+			t, err := ParseContentTemplate(path)
+			if err != nil {
+				return nil, err
+			}
+			templates[ext] = append(templates[ext], t)
+		}
+	}
+	return templates, nil
+}
+
+// render executes the template with generators drawing from rng in lang
+func (t *ContentTemplate) render(rng *rand.Rand, lang Language, data templateData) ([]byte, error) {
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Funcs(templateFuncs(rng, lang)).Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %v", t.name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// template picks one of the templates configured for ext, or nil when the file
+// should be generated from prose
+func (g *FileContentGenerator) template(ext string) *ContentTemplate {
+	templates := g.Templates[ext]
+	if len(templates) == 0 {
+		return nil
+	}
+	if g.TemplateRatio > 0 && g.rng.Float64() >= g.TemplateRatio {
+		return nil
+	}
+	return templates[g.rng.Intn(len(templates))]
+}
+
+// generateFromTemplate renders t into the file at fullPath; the document is as long as
+// the template makes it, so FileSize is updated to the written size
+func (g *FileContentGenerator) generateFromTemplate(file *models.File, t *ContentTemplate, lang Language, fullPath string) error {
+	ext := strings.ToLower(file.FileExtension)
+	data, err := t.render(g.rng, lang, templateData{Name: file.Name, Extension: ext, Lang: lang.Primary()})
+	if err != nil {
+		return err
+	}
+
+	if ext == "docx" {
+		f, err := os.Create(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to create docx file: %v", err)
+		}
+		err = writeDOCX(f, strings.Split(strings.TrimRight(string(data), "\n"), "\n"), lang)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write docx file: %v", err)
+		}
+	} else if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s file: %v", ext, err)
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s file: %v", ext, err)
+	}
+	file.FileSize = int(info.Size())
+	file.FileContent = fmt.Sprintf("Generated %s content from template %s", strings.ToUpper(ext), t.name)
+	g.remember(ext, fullPath)
+	return nil
+}
@@ -0,0 +1,191 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxRowBytes is the initial guess of the compressed size of one data row
+const xlsxRowBytes = 60
+
+// xlsxSheetName makes name a valid, unique worksheet name: at most 31 characters
+// (28 leave room for a numeric suffix) and none of : \ / ? * [ ] '. The apostrophe
+// is dropped as well so names can be quoted in formulas.
+func xlsxSheetName(name string, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]'`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	for utf8.RuneCountInString(name) > 28 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Sheet"
+	}
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s %d", name, i)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+// writeXLSXDataSheet streams rows of typed columns into sheet: text, date, integer
+// and currency values, a per-row formula and a closing row of SUM formulas
+func writeXLSXDataSheet(f *excelize.File, sheet string, rng *rand.Rand, lang Language, rows int, dateStyle, moneyStyle int) error {
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(1, 1, 30); err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(6, 6, 60); err != nil {
+		return err
+	}
+	header := []interface{}{
+		GenerateFolderName(rng, lang.draw(rng)), "Date", "Quantity", "Unit price", "Total", generateWord(rng, lang.draw(rng)),
+	}
+	if err := sw.SetRow("A1", header, excelize.RowOpts{Height: 20}); err != nil {
+		return err
+	}
+	for r := 2; r < rows+2; r++ {
+		row := []interface{}{
+			strings.Join(generateWords(rng, lang.draw(rng), 1+rng.Intn(3)), " "),
+			excelize.Cell{StyleID: dateStyle, Value: randomDate(rng)},
+			1 + rng.Intn(500),
+			excelize.Cell{StyleID: moneyStyle, Value: math.Round(rng.Float64()*100000) / 100},
+			excelize.Cell{StyleID: moneyStyle, Formula: fmt.Sprintf("C%d*D%d", r, r)},
+			generateSentence(rng, lang.draw(rng)),
+		}
+		if err := sw.SetRow(fmt.Sprintf("A%d", r), row); err != nil {
+			return err
+		}
+	}
+	last := rows + 1
+	totals := []interface{}{
+		"Total", nil,
+		excelize.Cell{Formula: fmt.Sprintf("SUM(C2:C%d)", last)},
+		excelize.Cell{StyleID: moneyStyle, Formula: fmt.Sprintf("AVERAGE(D2:D%d)", last)},
+		excelize.Cell{StyleID: moneyStyle, Formula: fmt.Sprintf("SUM(E2:E%d)", last)},
+	}
+	if err := sw.SetRow(fmt.Sprintf("A%d", rows+2), totals); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
+
+// renderXLSX builds a workbook of 2 to 4 data sheets sharing rowsTotal rows, plus a
+// summary sheet whose formulas reference the data sheets and feed a chart
+func renderXLSX(rng *rand.Rand, lang Language, rowsTotal int) (*excelize.File, error) {
+	f := excelize.NewFile()
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		return nil, err
+	}
+	moneyStyle, err := f.NewStyle(&excelize.Style{NumFmt: 4})
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	summary := xlsxSheetName("Summary", used)
+	if err := f.SetSheetName("Sheet1", summary); err != nil {
+		return nil, err
+	}
+
+	numSheets := 2 + rng.Intn(3)
+	type dataSheet struct {
+		name     string
+		totalRow int
+	}
+	var sheets []dataSheet
+	for i := 0; i < numSheets; i++ {
+		name := xlsxSheetName(GenerateFolderName(rng, lang.draw(rng)), used)
+		if _, err := f.NewSheet(name); err != nil {
+			return nil, err
+		}
+		rows := max(1, rowsTotal/numSheets)
+		if err := writeXLSXDataSheet(f, name, rng, lang, rows, dateStyle, moneyStyle); err != nil {
+			return nil, fmt.Errorf("failed to write sheet %s: %v", name, err)
+		}
+		sheets = append(sheets, dataSheet{name: name, totalRow: rows + 2})
+	}
+
+	// One summary row per data sheet, linking to its grand total
+	if err := f.SetSheetRow(summary, "A1", &[]interface{}{"Sheet", "Total"}); err != nil {
+		return nil, err
+	}
+	for i, sheet := range sheets {
+		if err := f.SetCellValue(summary, fmt.Sprintf("A%d", i+2), sheet.name); err != nil {
+			return nil, err
+		}
+		ref := fmt.Sprintf("'%s'!E%d", sheet.name, sheet.totalRow)
+		if err := f.SetCellFormula(summary, fmt.Sprintf("B%d", i+2), ref); err != nil {
+			return nil, err
+		}
+		if err := f.SetCellStyle(summary, fmt.Sprintf("B%d", i+2), fmt.Sprintf("B%d", i+2), moneyStyle); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.SetColWidth(summary, "A", "B", 30); err != nil {
+		return nil, err
+	}
+	last := len(sheets) + 1
+	chart := &excelize.Chart{
+		Type: excelize.Col,
+		Series: []excelize.ChartSeries{{
+			Name:       fmt.Sprintf("'%s'!$B$1", summary),
+			Categories: fmt.Sprintf("'%s'!$A$2:$A$%d", summary, last),
+			Values:     fmt.Sprintf("'%s'!$B$2:$B$%d", summary, last),
+		}},
+		Title: []excelize.RichTextRun{{Text: GenerateFolderName(rng, lang.draw(rng))}},
+	}
+	if err := f.AddChart(summary, "D2", chart); err != nil {
+		return nil, fmt.Errorf("failed to add chart: %v", err)
+	}
+	return f, nil
+}
+
+// generateXLSX writes a workbook whose size approaches targetSize, scaling the row
+// count over a few render passes
+func generateXLSX(w io.Writer, rng *rand.Rand, lang Language, targetSize int) error {
+	seed := rng.Int63()
+	rows := max(10, targetSize/xlsxRowBytes)
+
+	var data []byte
+	for attempt := 0; attempt < 4; attempt++ {
+		// Render the same workbook at each length so retries stay deterministic
+		f, err := renderXLSX(rand.New(rand.NewSource(seed)), lang, rows)
+		if err != nil {
+			return err
+		}
+		buf, err := f.WriteToBuffer()
+		f.Close()
+		if err != nil {
+			return err
+		}
+		data = buf.Bytes()
+		ratio := float64(targetSize) / float64(len(data))
+		if ratio > 0.9 && ratio < 1.1 {
+			break
+		}
+		next := max(10, int(float64(rows)*ratio))
+		if next == rows {
+			break
+		}
+		rows = next
+	}
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/songvi/robo/models"
+)
+
+// maxZipfRank bounds zipf ranks when the distribution has no maximum size
+const maxZipfRank = 1 << 20
+
+// validateSizeDistribution checks that the parameters of dist are usable
+func validateSizeDistribution(dist *models.SizeDistribution) error {
+	switch dist.Type {
+	case "lognormal":
+		if dist.Sigma < 0 {
+			return fmt.Errorf("invalid lognormal size distribution: sigma must not be negative")
+		}
+	case "pareto":
+		if dist.Scale <= 0 || dist.Alpha <= 0 {
+			return fmt.Errorf("invalid pareto size distribution: scale and alpha must be positive")
+		}
+	case "zipf":
+		if dist.Scale <= 0 || dist.Exponent <= 1 {
+			return fmt.Errorf("invalid zipf size distribution: scale must be positive and exponent greater than 1")
+		}
+	default:
+		return fmt.Errorf("unsupported size distribution: %s", dist.Type)
+	}
+	if dist.Max > 0 && dist.Max < dist.Min {
+		return fmt.Errorf("invalid size distribution: max is lower than min")
+	}
+	return nil
+}
+
+// sampleFileSize draws a file size in bytes from dist, clamped to its bounds
+func sampleFileSize(rng *rand.Rand, dist *models.SizeDistribution) int {
+	var size float64
+	switch dist.Type {
+	case "lognormal":
+		size = math.Exp(dist.Mu + dist.Sigma*rng.NormFloat64())
+	case "pareto":
+		// Inverse transform sampling; 1-U keeps the base away from zero
+		size = dist.Scale / math.Pow(1-rng.Float64(), 1/dist.Alpha)
+	case "zipf":
+		maxRank := uint64(maxZipfRank)
+		if dist.Max > 0 {
+			maxRank = uint64(math.Max(1, float64(dist.Max)/dist.Scale))
+		}
+		size = dist.Scale * float64(rand.NewZipf(rng, dist.Exponent, 1, maxRank-1).Uint64()+1)
+	}
+
+	if size > float64(math.MaxInt32) {
+		size = math.MaxInt32
+	}
+	result := int(math.Max(size, math.Max(float64(dist.Min), 1)))
+	if dist.Max > 0 && result > dist.Max {
+		result = dist.Max
+	}
+	return result
+}
+
+// sizeSamples is the number of draws MeanFileSize averages a size distribution over
+const sizeSamples = 10000
+
+// MeanFileSize returns the expected size in bytes of the files of strategy: the
+// probability-weighted mean of its discrete sizes or, as clamping makes closed forms
+// inexact, the mean of a fixed sample of its size distribution
+func MeanFileSize(strategy models.FileStrategy) float64 {
+	if dist := strategy.FileSizeDistribution; dist != nil {
+		if validateSizeDistribution(dist) != nil {
+			return 0
+		}
+		rng := rand.New(rand.NewSource(1))
+		total := 0.0
+		for i := 0; i < sizeSamples; i++ {
+			total += float64(sampleFileSize(rng, dist))
+		}
+		return total / sizeSamples
+	}
+	if len(strategy.FileSize) != len(strategy.FileSizeProbability) {
+		return 0
+	}
+	mean := 0.0
+	for i, size := range strategy.FileSize {
+		mean += float64(size) * strategy.FileSizeProbability[i]
+	}
+	return mean
+}
+
+// MaxFileSize returns the size in bytes of the largest file strategy may generate
+func MaxFileSize(strategy models.FileStrategy) int64 {
+	if dist := strategy.FileSizeDistribution; dist != nil {
+		if dist.Max > 0 {
+			return int64(dist.Max)
+		}
+		return math.MaxInt32
+	}
+	largest := 0
+	for i, size := range strategy.FileSize {
+		if i < len(strategy.FileSizeProbability) && strategy.FileSizeProbability[i] > 0 && size > largest {
+			largest = size
+		}
+	}
+	return int64(largest)
+}
@@ -0,0 +1,9 @@
+// Package fonts embeds the fallback font used when no font is configured for a script
+package fonts
+
+import _ "embed"
+
+// NotoSansRegular covers Latin, Greek and Cyrillic text, including Vietnamese
+//
+//go:embed NotoSans-Regular.ttf
+var NotoSansRegular []byte
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"path/filepath"
@@ -10,39 +11,121 @@ import (
 	"github.com/songvi/robo/models"
 )
 
-// GenerateFile creates It creates a new file based on the FileStrategy configuration
-func GenerateFile(strategy models.FileStrategy, repositoryPath string) (models.File, error) {
-	rand.Seed(time.Now().UnixNano())
+// GenerateFile creates It creates a new file based on the FileStrategy configuration,
+// writing its content through contentGenerator
+func GenerateFile(rng *rand.Rand, strategy models.FileStrategy, contentGenerator *file.FileContentGenerator) (models.File, error) {
+	generatedFile, _, err := stageFile(rng, strategy, contentGenerator, 1)
+	if err != nil {
+		return models.File{}, err
+	}
+
+	// Hand the file to the configured store
+	if err := contentGenerator.Publish(context.Background(), &generatedFile); err != nil {
+		return models.File{}, fmt.Errorf("failed to store file: %v", err)
+	}
+
+	return generatedFile, nil
+}
+
+// GenerateFileVersions creates a file like GenerateFile and, for the share of files
+// selected by the Versioning strategy, its successive revisions in order. Every
+// revision is derived from the previous one before any of them is published.
+func GenerateFileVersions(rng *rand.Rand, strategy models.FileStrategy, contentGenerator *file.FileContentGenerator) ([]models.File, error) {
+	// The number of versions comes first, so the name leaves room for their markers
+	versions := 1
+	if v := strategy.Versioning; v != nil && v.MaxVersions > 1 && rng.Float64() < v.Probability {
+		versions = v.MinVersions + rng.Intn(v.MaxVersions-v.MinVersions+1)
+	}
+	first, contentLang, err := stageFile(rng, strategy, contentGenerator, versions)
+	if err != nil {
+		return nil, err
+	}
+	files := []models.File{first}
 
+	if versions > 1 {
+		files[0].Version = 1
+		files[0].VersionGroup = fmt.Sprintf("%016x", rng.Uint64())
+		for k := 2; k <= versions; k++ {
+			prev := &files[len(files)-1]
+			revision := models.File{
+				Name:          prev.Name,
+				Description:   fmt.Sprintf("Revision %d of %s", k, prev.Name),
+				FileExtension: prev.FileExtension,
+				FileSize:      prev.FileSize,
+				LanguageMix:   prev.LanguageMix,
+				Version:       k,
+				VersionGroup:  prev.VersionGroup,
+				CycleID:       prev.CycleID,
+			}
+			revision.FileContent = contentGenerator.LocalPath(&revision)
+			start := time.Now()
+			if err := contentGenerator.Revise(prev, &revision, contentLang); err != nil {
+				return nil, fmt.Errorf("failed to generate revision %d: %v", k, err)
+			}
+			if len(prev.PII) > 0 {
+				if err := contentGenerator.RelocatePII(prev, &revision); err != nil {
+					return nil, err
+				}
+			}
+			revision.GenerationTime = time.Since(start)
+			files = append(files, revision)
+		}
+	}
+
+	for i := range files {
+		if err := contentGenerator.Publish(context.Background(), &files[i]); err != nil {
+			return nil, fmt.Errorf("failed to store file: %v", err)
+		}
+	}
+	return files, nil
+}
+
+// stageFile picks the attributes of a new file and writes its content to the local
+// repository, returning the file and the language its content was written in; its name
+// leaves room for the version markers of versions revisions
+func stageFile(rng *rand.Rand, strategy models.FileStrategy, contentGenerator *file.FileContentGenerator, versions int) (models.File, file.Language, error) {
 	// Validate strategy
 	if len(strategy.FileExtension) == 0 || len(strategy.FileExtensionProbability) == 0 ||
-		len(strategy.FileSize) == 0 || len(strategy.FileSizeProbability) == 0 ||
 		len(strategy.FileLang) == 0 || len(strategy.FileLangNameProbability) == 0 {
-		return models.File{}, fmt.Errorf("invalid FileStrategy: one or more required fields are empty")
+		return models.File{}, file.Language{}, fmt.Errorf("invalid FileStrategy: one or more required fields are empty")
+	}
+	if strategy.FileSizeDistribution != nil {
+		if err := validateSizeDistribution(strategy.FileSizeDistribution); err != nil {
+			return models.File{}, file.Language{}, fmt.Errorf("invalid FileStrategy: %v", err)
+		}
+	} else if len(strategy.FileSize) == 0 || len(strategy.FileSizeProbability) == 0 {
+		return models.File{}, file.Language{}, fmt.Errorf("invalid FileStrategy: one or more required fields are empty")
 	}
 	if len(strategy.FileExtension) != len(strategy.FileExtensionProbability) ||
 		len(strategy.FileSize) != len(strategy.FileSizeProbability) ||
 		len(strategy.FileLang) != len(strategy.FileLangNameProbability) {
-		return models.File{}, fmt.Errorf("invalid FileStrategy: field lengths do not match their corresponding probabilities")
+		return models.File{}, file.Language{}, fmt.Errorf("invalid FileStrategy: field lengths do not match their corresponding probabilities")
 	}
 
 	// Select file extension based on probability
-	extIndex := selectFileIndexByProbability(strategy.FileExtensionProbability)
+	extIndex := selectFileIndexByProbability(rng, strategy.FileExtensionProbability)
 	fileExtension := strategy.FileExtension[extIndex]
 
-	// Select file size based on probability
-	sizeIndex := selectFileIndexByProbability(strategy.FileSizeProbability)
-	fileSize := strategy.FileSize[sizeIndex]
+	// Select file size from the distribution, or the discrete list by probability
+	var fileSize int
+	if strategy.FileSizeDistribution != nil {
+		fileSize = sampleFileSize(rng, strategy.FileSizeDistribution)
+	} else {
+		sizeIndex := selectFileIndexByProbability(rng, strategy.FileSizeProbability)
+		fileSize = strategy.FileSize[sizeIndex]
+	}
 
 	// Select file name language based on probability
-	langIndex := selectFileIndexByProbability(strategy.FileLangNameProbability)
+	langIndex := selectFileIndexByProbability(rng, strategy.FileLangNameProbability)
 	fileLang := strategy.FileLang[langIndex]
 
 	// Generate file name
-	fileName := file.GenerateFilename([]string{fileLang})
-
-	// Create file path
-	// filePath := filepath.Join("files", fmt.Sprintf("%s.%s", fileName, fileExtension))
+	fileName := file.GenerateFilename(rng, []string{fileLang})
+	if strategy.EdgeCaseNameRatio > 0 && rng.Float64() < strategy.EdgeCaseNameRatio {
+		fileName = file.GenerateEdgeCaseFilename(rng, fileName, fileExtension)
+	}
+	// Names repeat now and then; later files must not overwrite earlier ones
+	fileName = contentGenerator.UniqueName(fileName, fileExtension, versions)
 
 	// Create file struct
 	generatedFile := models.File{
@@ -50,21 +133,47 @@ func GenerateFile(strategy models.FileStrategy, repositoryPath string) (models.F
 		Description:   fmt.Sprintf("Generated %s file in %s", fileExtension, fileLang),
 		FileExtension: fileExtension,
 		FileSize:      fileSize,
-		FileContent:   filepath.Join(repositoryPath, fmt.Sprintf("%s.%s", fileName, fileExtension)),
+		CycleID:       contentGenerator.CycleID,
 	}
+	generatedFile.FileContent = contentGenerator.LocalPath(&generatedFile)
 
-	// Generate file content
-	contentGenerator := file.NewFileContentGenerator(repositoryPath)
-	if err := contentGenerator.GenerateContent(&generatedFile, fileLang); err != nil {
-		return models.File{}, fmt.Errorf("failed to generate file content: %v", err)
+	// Mixed documents draw each sentence's language from the configured mix
+	contentLang := file.NewLanguage(fileLang)
+	if mix := strategy.ContentLanguageMix; mix != nil && len(mix.Languages) > 0 && rng.Float64() < mix.Probability {
+		contentLang = file.MixLanguages(mix.Languages, mix.Weights)
+		generatedFile.LanguageMix = contentLang.Breakdown()
 	}
 
-	return generatedFile, nil
+	// Copy an earlier file for a share of duplicates and near-duplicates
+	start := time.Now()
+	var source string
+	if dupRatio := strategy.DedupRatio + strategy.NearDuplicateRatio; dupRatio > 0 {
+		if r := rng.Float64(); r < dupRatio {
+			var err error
+			if source, err = contentGenerator.Duplicate(&generatedFile, r >= strategy.DedupRatio); err != nil {
+				return models.File{}, file.Language{}, err
+			}
+		}
+	}
+	if source != "" {
+		generatedFile.Description = fmt.Sprintf("Duplicate of %s", filepath.Base(source))
+		generatedFile.LanguageMix = nil
+	} else if err := contentGenerator.GenerateContent(&generatedFile, contentLang); err != nil {
+		return models.File{}, file.Language{}, fmt.Errorf("failed to generate file content: %v", err)
+	} else if p := strategy.PII; p != nil && rng.Float64() < p.Probability {
+		count := p.MinItems + rng.Intn(p.MaxItems-p.MinItems+1)
+		if err := contentGenerator.InjectPII(&generatedFile, p.Types, count); err != nil {
+			return models.File{}, file.Language{}, err
+		}
+	}
+	generatedFile.GenerationTime = time.Since(start)
+
+	return generatedFile, contentLang, nil
 }
 
 // selectFileIndexByProbability selects an index based on a probability distribution
-func selectFileIndexByProbability(probabilities []float64) int {
-	r := rand.Float64()
+func selectFileIndexByProbability(rng *rand.Rand, probabilities []float64) int {
+	r := rng.Float64()
 	sum := 0.0
 	for i, p := range probabilities {
 		sum += p
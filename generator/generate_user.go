@@ -9,7 +9,7 @@ import (
 )
 
 // GenerateUser creates a new user based on the UserStrategy configuration
-func GenerateUser(strategy models.UserStrategy) (models.User, error) {
+func GenerateUser(rng *rand.Rand, strategy models.UserStrategy) (models.User, error) {
 	if len(strategy.UserLang) == 0 || len(strategy.LangProbability) == 0 {
 		return models.User{}, fmt.Errorf("invalid UserStrategy: user_lang or lang_probability is empty")
 	}
@@ -18,23 +18,48 @@ func GenerateUser(strategy models.UserStrategy) (models.User, error) {
 	}
 
 	// Select language based on probability distribution
-	langIndex := selectIndexByProbability(strategy.LangProbability)
+	langIndex := selectIndexByProbability(rng, strategy.LangProbability)
 	language := strategy.UserLang[langIndex]
 
 	// Generate random display name and username
-	displayName := user.GenerateDisplayName(strategy)
-	username := generateRandomUserName(6, 12)
+	displayName := user.GenerateDisplayName(rng, strategy)
+	username := generateRandomUserName(rng, 6, 12)
 
-	return models.User{
+	u := models.User{
 		DisplayName: displayName,
 		UserName:    username,
 		Language:    language,
-	}, nil
+	}
+	if p := strategy.Profile; p != nil {
+		if p.Email {
+			u.Email = user.GenerateEmail(rng, language, displayName, username, p.EmailDomains)
+		}
+		if p.Phone {
+			u.Phone = user.GeneratePhone(rng, language)
+		}
+		if p.Address {
+			u.Address = user.GenerateAddress(rng, language)
+		}
+		if p.JobTitle {
+			u.JobTitle = user.GenerateJobTitle(rng, language)
+		}
+		if p.AvatarDir != "" {
+			path, err := user.WriteAvatar(rng, p.AvatarDir, username, p.AvatarSize)
+			if err != nil {
+				return models.User{}, err
+			}
+			u.AvatarPath = path
+		}
+	}
+	if len(strategy.Personas) > 0 && len(strategy.Personas) == len(strategy.PersonaProbability) {
+		u.Persona = strategy.Personas[selectIndexByProbability(rng, strategy.PersonaProbability)]
+	}
+	return u, nil
 }
 
 // selectIndexByProbability selects an index based on a probability distribution
-func selectIndexByProbability(probabilities []float64) int {
-	r := rand.Float64()
+func selectIndexByProbability(rng *rand.Rand, probabilities []float64) int {
+	r := rng.Float64()
 	sum := 0.0
 	for i, p := range probabilities {
 		sum += p
@@ -46,12 +71,12 @@ func selectIndexByProbability(probabilities []float64) int {
 }
 
 // generateRandomUserName generates a random string of specified length range
-func generateRandomUserName(minLen, maxLen int) string {
-	length := minLen + rand.Intn(maxLen-minLen+1)
+func generateRandomUserName(rng *rand.Rand, minLen, maxLen int) string {
+	length := minLen + rng.Intn(maxLen-minLen+1)
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+		b[i] = charset[rng.Intn(len(charset))]
 	}
 	return string(b)
 }
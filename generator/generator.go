@@ -2,14 +2,19 @@ package generator
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"sync"
+	"time"
 
 	"go.uber.org/fx"
-	"gorm.io/driver/sqlite" // Example driver; replace with your database driver
-	"gorm.io/gorm"
 
+	"github.com/songvi/robo/generator/file"
+	useraction "github.com/songvi/robo/generator/user_action"
 	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
 )
 
 // Generator defines the interface for the generator service
@@ -17,60 +22,170 @@ type Generator interface {
 	Users(ctx context.Context) <-chan models.User
 	Files(ctx context.Context) <-chan models.File
 	Workspaces(ctx context.Context) <-chan models.Workspace
+	// GenerateUsers, GenerateFiles and GenerateWorkspaces generate exactly n items on
+	// demand, independently of the channel workers and from the sources seeded on ctx
+	// by WithSeed, if any
+	GenerateUsers(ctx context.Context, n int) ([]models.User, error)
+	GenerateFiles(ctx context.Context, n int) ([]models.File, error)
+	GenerateWorkspaces(ctx context.Context, n int) ([]models.Workspace, error)
+	// GenerateActions generates the action stream of one session from the
+	// ActionStrategy, with the action mix of persona when it has a profile; it returns
+	// no actions when no action types are configured
+	GenerateActions(ctx context.Context, sessionID, persona string) ([]useraction.UserAction, error)
+	// FileStats reports how long generating file content took so far
+	FileStats() FileStats
+	// WriteMetrics writes counters and gauges in the Prometheus text format
+	WriteMetrics(w io.Writer) error
 }
 
 // generatorImpl is the implementation of the Generator interface
 type generatorImpl struct {
 	config        GeneratorConfig
-	db            *gorm.DB
+	store         store.Store // Users workspaces are made of; nil when none is provided
 	userCh        chan models.User
 	fileCh        chan models.File
 	workspaceCh   chan models.Workspace
 	wg            sync.WaitGroup
+	workerCtx     context.Context
 	cancelWorkers context.CancelFunc
+	seed          int64
+	fileStore     file.FileStore
+
+	// Each kind owns its own source since *rand.Rand is not safe for concurrent use;
+	// the mutexes let workers and on-demand calls share them, and guard the sources
+	// seeded by WithSeed as well
+	sources          *sources
+	userMu           sync.Mutex
+	fileMu           sync.Mutex
+	contentGenerator *file.FileContentGenerator
+	pendingFiles     map[string][]models.File // Revisions waiting to follow their previous version, by cycle
+	stats            fileStats
+	metrics          generatorMetrics
+	metricsServer    *http.Server
+	workspaceMu      sync.Mutex
+	actionMu         sync.Mutex
+
+	// Background workers are paced by these throttles; nil ones never wait
+	userThrottle      *throttle
+	fileThrottle      *throttle
+	byteThrottle      *throttle
+	workspaceThrottle *throttle
+	growth            *growthSchedule // Paces the file worker in growth mode
+
+	// Lazy mode starts each worker on the first call to its channel accessor
+	startMu sync.Mutex
+	started map[string]bool
+	stopped bool
+}
+
+// GeneratorParams are the dependencies of NewGenerator
+type GeneratorParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Config    GeneratorConfig
+	// Store holds the users workspaces are drawn from; without one no workspaces can
+	// be generated
+	Store store.Store `optional:"true"`
 }
 
 // NewGenerator creates a new Generator instance with the provided config
-func NewGenerator(lc fx.Lifecycle, config GeneratorConfig) (Generator, error) {
-	// Initialize GORM database
-	db, err := gorm.Open(sqlite.Open(config.DBConfig.DSN), &gorm.Config{})
+func NewGenerator(p GeneratorParams) (Generator, error) {
+	lc, config := p.Lifecycle, p.Config
+
+	config.SetDefaults()
+	userBuffer, fileBuffer, workspaceBuffer := config.UserBuffer, config.FileBuffer, config.WorkspaceBuffer
+	// Lazy workers hand items over unbuffered so nothing piles up unconsumed
+	if config.Lazy {
+		userBuffer, fileBuffer, workspaceBuffer = 0, 0, 0
+	}
+
+	// Train the configured text corpora before any worker generates sentences
+	if err := file.LoadCorpora(config.Corpora.Paths, config.Corpora.Order); err != nil {
+		return nil, err
+	}
+
+	fileStore, err := newFileStore(config.FileStore)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set default buffer sizes if not specified
-	userBuffer := config.UserBuffer
-	if userBuffer <= 0 {
-		userBuffer = 10 // Default buffer for users
+	// Use a time-based seed unless a fixed one is configured
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	g := &generatorImpl{
+		config:       config,
+		store:        p.Store,
+		userCh:       make(chan models.User, userBuffer),
+		fileCh:       make(chan models.File, fileBuffer),
+		workspaceCh:  make(chan models.Workspace, workspaceBuffer),
+		seed:         seed,
+		fileStore:    fileStore,
+		sources:      newSources(seed),
+		started:      make(map[string]bool),
+		pendingFiles: make(map[string][]models.File),
+
+		userThrottle:      newThrottle(config.Throughput.UsersPerSecond),
+		fileThrottle:      newThrottle(config.Throughput.FilesPerSecond),
+		byteThrottle:      newThrottle(float64(config.Throughput.MaxBytesPerSecond)),
+		workspaceThrottle: newThrottle(config.Throughput.WorkspacesPerSecond),
+	}
+
+	if g.growth, err = newGrowthSchedule(config.Growth, seed+4); err != nil {
+		return nil, err
+	}
+
+	g.contentGenerator = file.NewFileContentGenerator(config.FileStore.FilePath, g.sources.file)
+	if config.CorpusCache.Dir != "" {
+		g.contentGenerator.Cache = file.NewCorpusCache(config.CorpusCache.Dir, config.CorpusCache.Uniqueness)
 	}
-	fileBuffer := config.FileBuffer
-	if fileBuffer <= 0 {
-		fileBuffer = 5 // Default buffer for files (smaller due to potential large size)
+	g.contentGenerator.Store = fileStore
+	if g.contentGenerator.Templates, err = file.LoadContentTemplates(config.Templates.Paths); err != nil {
+		return nil, err
 	}
-	workspaceBuffer := config.WorkspaceBuffer
-	if workspaceBuffer <= 0 {
-		workspaceBuffer = 10 // Default buffer for workspaces
+	g.contentGenerator.TemplateRatio = config.Templates.Ratio
+	if g.contentGenerator.Fonts, err = file.LoadFonts(config.Fonts); err != nil {
+		return nil, err
 	}
+	g.contentGenerator.KeepLocal = config.FileStore.KeepLocal
+	g.contentGenerator.DryRun = config.DryRun
 
-	g := &generatorImpl{
-		config:      config,
-		db:          db,
-		userCh:      make(chan models.User, userBuffer),
-		fileCh:      make(chan models.File, fileBuffer),
-		workspaceCh: make(chan models.Workspace, workspaceBuffer),
+	if q := config.Quota; q.MaxBytes > 0 || q.MaxCycleBytes > 0 || q.RetentionSeconds > 0 {
+		quota := file.NewRepositoryQuota(q.MaxBytes, q.MaxCycleBytes, time.Duration(q.RetentionSeconds)*time.Second)
+		// Account for files left behind by previous runs
+		repository := config.FileStore.FilePath
+		if local, ok := fileStore.(*file.LocalStore); ok {
+			repository = local.Dir
+		}
+		if err := quota.Scan(repository); err != nil {
+			return nil, fmt.Errorf("failed to scan file repository: %v", err)
+		}
+		g.contentGenerator.Quota = quota
 	}
 
 	// Create a context for worker cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	g.cancelWorkers = cancel
+	g.workerCtx, g.cancelWorkers = context.WithCancel(context.Background())
 
-	// Start workers on Fx lifecycle start
+	// Start workers on Fx lifecycle start, unless they only start on demand
 	lc.Append(fx.Hook{
 		OnStart: func(context.Context) error {
-			g.startWorkers(ctx)
+			if config.Metrics.Addr != "" {
+				if g.metricsServer, err = serveMetrics(config.Metrics.Addr, g); err != nil {
+					return err
+				}
+			}
+			if !config.Lazy {
+				g.startWorkers()
+			}
 			return nil
 		},
-		OnStop: func(context.Context) error {
+		OnStop: func(ctx context.Context) error {
+			if g.metricsServer != nil {
+				g.metricsServer.Shutdown(ctx)
+			}
 			g.stopWorkers()
 			return nil
 		},
@@ -79,92 +194,195 @@ func NewGenerator(lc fx.Lifecycle, config GeneratorConfig) (Generator, error) {
 	return g, nil
 }
 
-// startWorkers starts the background workers for generating users, files, and workspaces
-func (g *generatorImpl) startWorkers(ctx context.Context) {
-	// User worker
-	g.wg.Add(1)
-	go func() {
-		defer g.wg.Done()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				user, err := GenerateUser(g.config.Strategy.UserStrategy)
-				if err != nil {
-					log.Printf("Error generating user: %v", err)
-					continue // Log error in production
-				}
-				select {
-				case g.userCh <- user:
-				case <-ctx.Done():
-					return
-				}
-			}
+// newFileStore creates the FileStore selected by cfg; nil keeps files in FilePath
+func newFileStore(cfg FileStore) (file.FileStore, error) {
+	switch cfg.Type {
+	case "", "local":
+		if cfg.Dir == "" {
+			return nil, nil
 		}
-	}()
+		return file.NewLocalStore(cfg.Dir), nil
+	case "s3":
+		return file.NewS3Store(cfg.S3)
+	case "webdav":
+		return file.NewWebDAVStore(cfg.WebDAV)
+	default:
+		return nil, fmt.Errorf("unsupported file store type: %s", cfg.Type)
+	}
+}
 
-	// File worker
-	g.wg.Add(1)
-	go func() {
-		defer g.wg.Done()
-		for {
+// sourcesFor returns the sources seeded on ctx by WithSeed, or those of the generator
+func (g *generatorImpl) sourcesFor(ctx context.Context) *sources {
+	if src, ok := ctx.Value(seedKey{}).(*sources); ok {
+		return src
+	}
+	return g.sources
+}
+
+// nextUser generates one user
+func (g *generatorImpl) nextUser(ctx context.Context) (models.User, error) {
+	g.userMu.Lock()
+	defer g.userMu.Unlock()
+	user, err := GenerateUser(g.sourcesFor(ctx).user, g.config.Strategy.UserStrategy)
+	g.metrics.record("user", 1, err)
+	return user, err
+}
+
+// nextFile generates one file for the cycle set on ctx by WithCycle ("" outside any
+// cycle); the revisions of a versioned file are handed out by the following calls for
+// the same cycle
+func (g *generatorImpl) nextFile(ctx context.Context) (models.File, error) {
+	cycleID := CycleFromContext(ctx)
+	g.fileMu.Lock()
+	defer g.fileMu.Unlock()
+	if len(g.pendingFiles[cycleID]) == 0 {
+		rng := g.sourcesFor(ctx).file
+		contentGenerator := g.contentGenerator
+		if cycleID != "" {
+			contentGenerator = contentGenerator.Fork(rng)
+			contentGenerator.CycleID = cycleID
+		}
+		files, err := GenerateFileVersions(rng, g.config.Strategy.FileStrategy, contentGenerator)
+		g.metrics.record("file", len(files), err)
+		if err != nil {
+			return models.File{}, err
+		}
+		g.stats.record(files)
+		g.pendingFiles[cycleID] = files
+	}
+	f := g.pendingFiles[cycleID][0]
+	g.pendingFiles[cycleID] = g.pendingFiles[cycleID][1:]
+	return f, nil
+}
+
+// nextWorkspace generates one workspace from the users set on ctx by WithUsers, or from
+// users of the application store outside a cycle
+func (g *generatorImpl) nextWorkspace(ctx context.Context) (models.Workspace, error) {
+	users, ok := usersFromContext(ctx)
+	if !ok && g.store != nil {
+		// Get the maximum number of users needed based on WorkspaceStrategy
+		var err error
+		users, err = g.store.ListUsers(ctx, max(g.config.Strategy.WorkspaceStrategy.NumberOfUsers))
+		if err != nil {
+			err = fmt.Errorf("failed to list users: %v", err)
+			g.metrics.record("workspace", 0, err)
+			return models.Workspace{}, err
+		}
+	}
+	if len(users) == 0 {
+		err := fmt.Errorf("no users available")
+		g.metrics.record("workspace", 0, err)
+		return models.Workspace{}, err
+	}
+
+	g.workspaceMu.Lock()
+	defer g.workspaceMu.Unlock()
+	workspace, err := GenerateWorkspace(g.sourcesFor(ctx).workspace, g.config.Strategy.WorkspaceStrategy, users)
+	g.metrics.record("workspace", 1, err)
+	return workspace, err
+}
+
+// runWorker generates items with next and sends them on ch until the workers stop
+func runWorker[T any](g *generatorImpl, ch chan<- T, next func() (T, error), logErrors bool) {
+	ctx := g.workerCtx
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			item, err := next()
+			if err != nil {
+				if logErrors && ctx.Err() == nil {
+					log.Printf("Error generating item: %v", err)
+				}
+				continue // Log error in production
+			}
 			select {
+			case ch <- item:
 			case <-ctx.Done():
 				return
-			default:
-				file, err := GenerateFile(g.config.Strategy.FileStrategy, g.config.FileStore.FilePath)
-				if err != nil {
-					continue // Log error in production
-				}
-				select {
-				case g.fileCh <- file:
-				case <-ctx.Done():
-					return
-				}
 			}
 		}
-	}()
+	}
+}
 
-	// Workspace worker
+// startWorker starts the named background worker once, unless the generator stopped
+func (g *generatorImpl) startWorker(name string, run func()) {
+	g.startMu.Lock()
+	defer g.startMu.Unlock()
+	if g.stopped || g.started[name] {
+		return
+	}
+	g.started[name] = true
 	g.wg.Add(1)
 	go func() {
 		defer g.wg.Done()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				// Fetch UUIDs from database
-				var users []models.User
-				// Get the maximum number of users needed based on WorkspaceStrategy
-				maxUsers := max(g.config.Strategy.WorkspaceStrategy.NumberOfUsers)
-				if err := g.db.Limit(maxUsers).Find(&models.User{}).Error; err != nil {
-					continue // Log error in production
-				}
-				if len(users) == 0 {
-					continue // No users available; retry
-				}
+		run()
+	}()
+}
 
-				// Extract UUIDs
-				uuids := make([]string, len(users))
-				for i, u := range users {
-					uuids[i] = u.UUID
-				}
+// throttled paces next with the throttle for its kind, charging cost for every item
+func throttled[T any](ctx context.Context, items *throttle, next func() (T, error)) func() (T, error) {
+	return func() (T, error) {
+		if err := items.Wait(ctx, 1); err != nil {
+			var zero T
+			return zero, err
+		}
+		return next()
+	}
+}
 
-				// Generate workspace
-				workspace, err := GenerateWorkspace(g.config.Strategy.WorkspaceStrategy, uuids)
-				if err != nil {
-					continue // Log error in production
-				}
-				select {
-				case g.workspaceCh <- workspace:
-				case <-ctx.Done():
-					return
-				}
+// runUserWorker generates users at the configured rate
+func (g *generatorImpl) runUserWorker() {
+	runWorker(g, g.userCh, throttled(g.workerCtx, g.userThrottle, func() (models.User, error) {
+		return g.nextUser(g.workerCtx)
+	}), true)
+}
+
+// runFileWorker generates files at the configured file and byte rates, or on the
+// growth schedule in growth mode
+func (g *generatorImpl) runFileWorker() {
+	if g.growth != nil {
+		g.runGrowthWorker()
+		return
+	}
+	if g.usePool() {
+		g.runFilePool(g.workerCtx, -1, false, func(f models.File) bool {
+			if g.fileThrottle.Wait(g.workerCtx, 1) != nil {
+				return false
+			}
+			select {
+			case g.fileCh <- f:
+			case <-g.workerCtx.Done():
+				return false
 			}
+			return g.byteThrottle.Wait(g.workerCtx, float64(f.FileSize)) == nil
+		})
+		return
+	}
+	next := throttled(g.workerCtx, g.fileThrottle, func() (models.File, error) {
+		f, err := g.nextFile(g.workerCtx)
+		if err == nil {
+			// Pay for the bytes written before the next file is generated
+			err = g.byteThrottle.Wait(g.workerCtx, float64(f.FileSize))
 		}
-	}()
+		return f, err
+	})
+	runWorker(g, g.fileCh, next, false)
+}
+
+// runWorkspaceWorker generates workspaces at the configured rate
+func (g *generatorImpl) runWorkspaceWorker() {
+	runWorker(g, g.workspaceCh, throttled(g.workerCtx, g.workspaceThrottle, func() (models.Workspace, error) {
+		return g.nextWorkspace(g.workerCtx)
+	}), false)
+}
+
+// startWorkers starts the background workers for generating users, files, and workspaces
+func (g *generatorImpl) startWorkers() {
+	g.startWorker("users", g.runUserWorker)
+	g.startWorker("files", g.runFileWorker)
+	g.startWorker("workspaces", g.runWorkspaceWorker)
 }
 
 // max returns the maximum value in a slice of integers
@@ -183,31 +401,102 @@ func max(numbers []int) int {
 
 // stopWorkers stops all background workers and closes channels
 func (g *generatorImpl) stopWorkers() {
+	g.startMu.Lock()
+	g.stopped = true
+	g.startMu.Unlock()
 	g.cancelWorkers()
 	g.wg.Wait()
 	close(g.userCh)
 	close(g.fileCh)
 	close(g.workspaceCh)
-	// Close database connection
-	sqlDB, _ := g.db.DB()
-	sqlDB.Close()
 }
 
 // Users returns a channel of generated users
 func (g *generatorImpl) Users(ctx context.Context) <-chan models.User {
+	g.startWorker("users", g.runUserWorker)
 	return g.userCh
 }
 
 // Files returns a channel of generated files
 func (g *generatorImpl) Files(ctx context.Context) <-chan models.File {
+	g.startWorker("files", g.runFileWorker)
 	return g.fileCh
 }
 
 // Workspaces returns a channel of generated workspaces
 func (g *generatorImpl) Workspaces(ctx context.Context) <-chan models.Workspace {
+	g.startWorker("workspaces", g.runWorkspaceWorker)
 	return g.workspaceCh
 }
 
+// generateN calls next n times, stopping early when ctx is done
+func generateN[T any](ctx context.Context, n int, next func() (T, error)) ([]T, error) {
+	items := make([]T, 0, n)
+	for len(items) < n {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+		item, err := next()
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GenerateUsers generates n users on demand
+func (g *generatorImpl) GenerateUsers(ctx context.Context, n int) ([]models.User, error) {
+	return generateN(ctx, n, func() (models.User, error) { return g.nextUser(ctx) })
+}
+
+// GenerateFiles generates n files on demand, for the cycle set on ctx by WithCycle
+func (g *generatorImpl) GenerateFiles(ctx context.Context, n int) ([]models.File, error) {
+	cycleID := CycleFromContext(ctx)
+	if !g.usePool() {
+		return generateN(ctx, n, func() (models.File, error) { return g.nextFile(ctx) })
+	}
+	// Revisions left over by an earlier call come first
+	g.fileMu.Lock()
+	pending := g.pendingFiles[cycleID]
+	k := min(n, len(pending))
+	files := append(make([]models.File, 0, n), pending[:k]...)
+	g.pendingFiles[cycleID] = pending[k:]
+	g.fileMu.Unlock()
+
+	err := g.runFilePool(ctx, n-k, true, func(f models.File) bool {
+		files = append(files, f)
+		return true
+	})
+	return files, err
+}
+
+// GenerateWorkspaces generates n workspaces on demand
+func (g *generatorImpl) GenerateWorkspaces(ctx context.Context, n int) ([]models.Workspace, error) {
+	return generateN(ctx, n, func() (models.Workspace, error) { return g.nextWorkspace(ctx) })
+}
+
+// GenerateActions generates the actions of one session on demand
+func (g *generatorImpl) GenerateActions(ctx context.Context, sessionID, persona string) ([]useraction.UserAction, error) {
+	if len(g.config.Strategy.ActionStrategy.ActionTypes) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	g.actionMu.Lock()
+	defer g.actionMu.Unlock()
+	strategy := useraction.ForPersona(g.config.Strategy.ActionStrategy, persona)
+	actions, err := useraction.GenerateSession(g.sourcesFor(ctx).action, strategy, sessionID)
+	g.metrics.record("action", len(actions), err)
+	return actions, err
+}
+
+// FileStats reports the file generation timings accumulated so far
+func (g *generatorImpl) FileStats() FileStats {
+	return g.stats.snapshot()
+}
+
 // Module defines the Fx module for the Generator service
 var Module = fx.Module(
 	"generator",
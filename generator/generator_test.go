@@ -2,9 +2,21 @@ package generator
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
 	"log"
+	"math"
+	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,17 +26,22 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
+	"github.com/songvi/robo/generator/file"
+	useraction "github.com/songvi/robo/generator/user_action"
 	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
 )
 
 func TestGenerator(t *testing.T) {
 	// Setup test database
+	require.NoError(t, os.MkdirAll(".test", 0755), "failed to create test directory")
 	db, err := gorm.Open(sqlite.Open("file:.test/test.db?cache=shared&mode=rwc"), &gorm.Config{})
 	require.NoError(t, err, "failed to open test database")
 
-	// Create users table and insert test UUIDs
+	// Create users table and insert test UUIDs through the application store
 	err = db.AutoMigrate(&models.User{})
 	require.NoError(t, err, "failed to migrate user table")
+	userStore := store.NewGORMStore(db)
 	testUUIDs := []string{
 		"550e8400-e29b-41d4-a716-446655440000",
 		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
@@ -32,8 +49,8 @@ func TestGenerator(t *testing.T) {
 		"8f8b8f8b-8f8b-8f8b-8f8b-8f8b8f8b8f8b",
 		"9e107d9d-372b-4b1b-a8f7-0c7e2f0b1c2d",
 	}
-	for _, uuid := range testUUIDs {
-		db.Create(&models.User{UUID: uuid})
+	for i, uuid := range testUUIDs {
+		require.NoError(t, userStore.CreateUser(context.Background(), &models.User{UUID: uuid, UserName: fmt.Sprintf("user%d", i)}))
 	}
 
 	// Cleanup database after test
@@ -72,10 +89,7 @@ func TestGenerator(t *testing.T) {
 		FileStore: FileStore{
 			FilePath: fileDir,
 		},
-		DBStore: DBStore{},
-		DBConfig: DBConfig{
-			DSN: "file:.test/test.db?cache=shared&mode=rwc",
-		},
+		DBStore:         DBStore{},
 		FileBuffer:      2,
 		UserBuffer:      3,
 		WorkspaceBuffer: 2,
@@ -85,6 +99,7 @@ func TestGenerator(t *testing.T) {
 	var generator Generator
 	app := fx.New(
 		fx.Provide(func() GeneratorConfig { return config }),
+		fx.Provide(func() store.Store { return userStore }),
 		Module,
 		fx.Populate(&generator),
 	)
@@ -165,5 +180,860 @@ func TestGenerator(t *testing.T) {
 				t.Fatalf("timed out waiting for workspaces; received %d/%d", workspacesReceived, config.WorkspaceBuffer)
 			}
 		}
+
+		// The workspaces of a cycle are made of its users only
+		cycleUsers := []models.User{{UUID: "u1", Language: "en"}, {UUID: "u2", Language: "fr"}}
+		workspaces, err := generator.GenerateWorkspaces(WithUsers(ctx, cycleUsers), 3)
+		require.NoError(t, err)
+		for _, w := range workspaces {
+			assert.ElementsMatch(t, []string{"u1", "u2"}, w.Users)
+		}
 	})
 }
+
+func TestGenerateDeterministic(t *testing.T) {
+	userStrategy := models.UserStrategy{
+		UserLang:        []string{"en", "vi", "jp"},
+		LangProbability: []float64{0.4, 0.3, 0.3},
+	}
+	fileStrategy := models.FileStrategy{
+		FileExtension:            []string{"txt"},
+		FileExtensionProbability: []float64{1},
+		FileSize:                 []int{1024, 4096},
+		FileSizeProbability:      []float64{0.5, 0.5},
+		FileLang:                 []string{"en", "cn"},
+		FileLangNameProbability:  []float64{0.5, 0.5},
+	}
+
+	generate := func(dir string) ([]models.User, []models.File) {
+		rng := rand.New(rand.NewSource(42))
+		var users []models.User
+		var files []models.File
+		for i := 0; i < 5; i++ {
+			u, err := GenerateUser(rng, userStrategy)
+			require.NoError(t, err)
+			users = append(users, u)
+			f, err := GenerateFile(rng, fileStrategy, file.NewFileContentGenerator(dir, rng))
+			require.NoError(t, err)
+			files = append(files, f)
+		}
+		return users, files
+	}
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	usersA, filesA := generate(dirA)
+	usersB, filesB := generate(dirB)
+
+	assert.Equal(t, usersA, usersB, "same seed should produce identical users")
+	require.Len(t, filesB, len(filesA))
+	for i := range filesA {
+		assert.Equal(t, filesA[i].Name, filesB[i].Name, "same seed should produce identical file names")
+		assert.Equal(t, filesA[i].FileSize, filesB[i].FileSize, "same seed should produce identical file sizes")
+		contentA, err := os.ReadFile(filepath.Join(dirA, filesA[i].Name+"."+filesA[i].FileExtension))
+		require.NoError(t, err)
+		contentB, err := os.ReadFile(filepath.Join(dirB, filesB[i].Name+"."+filesB[i].FileExtension))
+		require.NoError(t, err)
+		assert.Equal(t, contentA, contentB, "same seed should produce identical content")
+	}
+}
+
+func TestSampleFileSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	median := func(dist *models.SizeDistribution) int {
+		require.NoError(t, validateSizeDistribution(dist))
+		sizes := make([]int, 2001)
+		for i := range sizes {
+			sizes[i] = sampleFileSize(rng, dist)
+			require.GreaterOrEqual(t, sizes[i], dist.Min)
+			if dist.Max > 0 {
+				require.LessOrEqual(t, sizes[i], dist.Max)
+			}
+		}
+		sort.Ints(sizes)
+		return sizes[len(sizes)/2]
+	}
+
+	// Lognormal median is exp(mu)
+	assert.InDelta(t, 8103, median(&models.SizeDistribution{Type: "lognormal", Mu: 9, Sigma: 1.5}), 1500)
+	// Pareto median is scale * 2^(1/alpha)
+	assert.InDelta(t, 2828, median(&models.SizeDistribution{Type: "pareto", Scale: 1000, Alpha: 0.667, Max: 10 << 20}), 400)
+	// Zipf with a steep exponent mostly yields the first rank
+	assert.Equal(t, 4096, median(&models.SizeDistribution{Type: "zipf", Scale: 4096, Exponent: 2, Min: 1024, Max: 1 << 20}))
+
+	assert.Error(t, validateSizeDistribution(&models.SizeDistribution{Type: "pareto"}))
+	assert.Error(t, validateSizeDistribution(&models.SizeDistribution{Type: "zipf", Scale: 1, Exponent: 1}))
+	assert.Error(t, validateSizeDistribution(&models.SizeDistribution{Type: "uniform"}))
+
+	// Lognormal mean is exp(mu + sigma^2/2)
+	lognormal := models.FileStrategy{FileSizeDistribution: &models.SizeDistribution{Type: "lognormal", Mu: 9, Sigma: 0.5}}
+	assert.InDelta(t, 9182, MeanFileSize(lognormal), 300)
+	assert.Equal(t, int64(math.MaxInt32), MaxFileSize(lognormal), "unbounded distributions are clamped")
+	discrete := models.FileStrategy{FileSize: []int{1000, 3000, 9000}, FileSizeProbability: []float64{0.5, 0.5, 0}}
+	assert.Equal(t, 2000.0, MeanFileSize(discrete))
+	assert.Equal(t, int64(3000), MaxFileSize(discrete))
+}
+
+func TestGenerateDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(7))
+	contentGenerator := file.NewFileContentGenerator(dir, rng)
+	strategy := models.FileStrategy{
+		FileExtension:            []string{"txt"},
+		FileExtensionProbability: []float64{1},
+		FileSize:                 []int{2048},
+		FileSizeProbability:      []float64{1},
+		FileLang:                 []string{"en"},
+		FileLangNameProbability:  []float64{1},
+	}
+	read := func(f models.File) []byte {
+		data, err := os.ReadFile(filepath.Join(dir, f.Name+"."+f.FileExtension))
+		require.NoError(t, err)
+		return data
+	}
+
+	original, err := GenerateFile(rng, strategy, contentGenerator)
+	require.NoError(t, err)
+
+	strategy.DedupRatio = 1
+	duplicate, err := GenerateFile(rng, strategy, contentGenerator)
+	require.NoError(t, err)
+	assert.NotEqual(t, original.Name, duplicate.Name)
+	assert.Equal(t, read(original), read(duplicate), "duplicates are byte-identical")
+
+	strategy.DedupRatio, strategy.NearDuplicateRatio = 0, 1
+	near, err := GenerateFile(rng, strategy, contentGenerator)
+	require.NoError(t, err)
+	nearData, originalData := read(near), read(original)
+	require.Len(t, nearData, len(originalData))
+	diff := 0
+	for i := range nearData {
+		if nearData[i] != originalData[i] {
+			diff++
+		}
+	}
+	assert.True(t, diff > 0 && diff <= 5, "near-duplicates differ by a few letters, got %d", diff)
+}
+
+func TestGenerateOnDemand(t *testing.T) {
+	fileDir := t.TempDir()
+	config := GeneratorConfig{
+		Strategy: Strategy{
+			UserStrategy: models.UserStrategy{
+				UserLang:        []string{"en"},
+				LangProbability: []float64{1},
+			},
+			FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"txt"},
+				FileExtensionProbability: []float64{1},
+				FileSize:                 []int{1024},
+				FileSizeProbability:      []float64{1},
+				FileLang:                 []string{"en"},
+				FileLangNameProbability:  []float64{1},
+			},
+		},
+		FileStore: FileStore{FilePath: fileDir},
+		Lazy:      true,
+		Seed:      1,
+	}
+
+	var generator Generator
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(func() GeneratorConfig { return config }),
+		Module,
+		fx.Populate(&generator),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, app.Start(ctx))
+	defer app.Stop(context.Background())
+
+	// Lazy workers generate nothing until a consumer asks
+	time.Sleep(50 * time.Millisecond)
+	entries, err := os.ReadDir(fileDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "lazy mode should not generate files ahead of demand")
+
+	users, err := generator.GenerateUsers(ctx, 3)
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+
+	// The same seed replays the same users, whatever was generated in between
+	replayed, err := generator.GenerateUsers(WithSeed(ctx, 42), 3)
+	require.NoError(t, err)
+	again, err := generator.GenerateUsers(WithSeed(ctx, 42), 3)
+	require.NoError(t, err)
+	assert.Equal(t, replayed, again)
+	assert.NotEqual(t, users, replayed)
+
+	files, err := generator.GenerateFiles(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(fileDir, f.Name+"."+f.FileExtension))
+		assert.NoError(t, err, "on-demand files are written to the repository")
+	}
+
+	select {
+	case u := <-generator.Users(ctx):
+		assert.NotEmpty(t, u.UserName)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for a lazily started user worker")
+	}
+
+	cancelled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	_, err = generator.GenerateUsers(cancelled, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, newThrottle(0).Wait(ctx, 1e9), "a zero rate never waits")
+
+	th := newThrottle(20)
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, th.Wait(ctx, 1))
+	}
+	assert.Less(t, time.Since(start), 40*time.Millisecond, "the burst is available immediately")
+
+	// A cost larger than the burst puts the bucket in debt: 10 units at 20/s is 500ms
+	start = time.Now()
+	require.NoError(t, th.Wait(ctx, 10))
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, th.Wait(cancelled, 100), context.Canceled)
+}
+
+func TestStrategyValidate(t *testing.T) {
+	strategy := func() Strategy {
+		return Strategy{
+			UserStrategy: models.UserStrategy{
+				UserLang:        []string{"en", "fr"},
+				LangProbability: []float64{0.5, 0.3},
+			},
+			WorkspaceStrategy: models.WorkspaceStrategy{
+				NumberOfUsers:            []int{2, 3},
+				NumberOfUsersProbability: []float64{0.6, 0.4},
+			},
+		}
+	}
+
+	s := strategy()
+	err := s.Validate(false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user_strategy.user_lang: probabilities sum to 0.8")
+
+	s = strategy()
+	require.NoError(t, s.Validate(true))
+	assert.InDeltaSlice(t, []float64{0.625, 0.375}, s.UserStrategy.LangProbability, 1e-9)
+
+	s = strategy()
+	s.WorkspaceStrategy.NumberOfUsersProbability = []float64{1.2, -0.2}
+	assert.ErrorContains(t, s.Validate(true), "must be a non-negative number")
+
+	s = strategy()
+	s.UserStrategy.UserLang = []string{"en"}
+	assert.ErrorContains(t, s.Validate(true), "2 probabilities for 1 values")
+
+	s = strategy()
+	s.FileStrategy = models.FileStrategy{
+		FileExtension:            []string{"txt"},
+		FileExtensionProbability: []float64{1},
+		FileSizeDistribution:     &models.SizeDistribution{Type: "lognormal", Mu: 9, Sigma: 1},
+		FileLang:                 []string{"en"},
+		FileLangNameProbability:  []float64{1},
+		DedupRatio:               0.8,
+		NearDuplicateRatio:       0.3,
+	}
+	assert.ErrorContains(t, s.Validate(true), "together exceed 1")
+}
+
+func TestGenerateMixedLanguageFile(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	strategy := models.FileStrategy{
+		FileExtension:            []string{"txt"},
+		FileExtensionProbability: []float64{1},
+		FileSize:                 []int{4096},
+		FileSizeProbability:      []float64{1},
+		FileLang:                 []string{"en"},
+		FileLangNameProbability:  []float64{1},
+		ContentLanguageMix: &models.LanguageMixStrategy{
+			Probability: 1,
+			Languages:   []string{"en", "es"},
+			Weights:     []float64{0.6, 0.4},
+		},
+	}
+	f, err := GenerateFile(rng, strategy, file.NewFileContentGenerator(t.TempDir(), rng))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"en": 0.6, "es": 0.4}, f.LanguageMix, "the language breakdown is recorded on the file")
+}
+
+func TestGenerateUserProfile(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	avatars := t.TempDir()
+	strategy := models.UserStrategy{
+		UserLang:        []string{"jp"},
+		LangProbability: []float64{1},
+		Profile: &models.ProfileStrategy{
+			Email:        true,
+			EmailDomains: []string{"corp.example"},
+			Phone:        true,
+			Address:      true,
+			JobTitle:     true,
+			AvatarDir:    avatars,
+			AvatarSize:   32,
+		},
+	}
+	u, err := GenerateUser(rng, strategy)
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.ToLower(u.UserName)+"@corp.example", u.Email, "non-Latin names fall back to the username")
+	assert.True(t, strings.HasPrefix(u.Phone, "+81 "), "phone uses the country calling code, got %q", u.Phone)
+	assert.True(t, strings.HasPrefix(u.Address, "日本 〒"), "Japanese addresses start with the country, got %q", u.Address)
+	assert.NotEmpty(t, u.JobTitle)
+
+	f, err := os.Open(u.AvatarPath)
+	require.NoError(t, err)
+	defer f.Close()
+	cfg, err := png.DecodeConfig(f)
+	require.NoError(t, err)
+	assert.Equal(t, 32, cfg.Width)
+	assert.Equal(t, 32, cfg.Height)
+
+	strategy.Profile = nil
+	u, err = GenerateUser(rng, strategy)
+	require.NoError(t, err)
+	assert.Empty(t, u.Email+u.Phone+u.Address+u.JobTitle+u.AvatarPath, "profile fields are opt-in")
+}
+
+func TestGenerateSessionActions(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	strategy := models.ActionStrategy{
+		ActionTypes:   []string{"upload_file", "consult_file"},
+		ActionWeights: []float64{0.8, 0.2},
+		MinActions:    20,
+		MaxActions:    30,
+		StartAction:   "login",
+		EndAction:     "logout",
+		ThinkTime:     &models.ThinkTimeDistribution{Type: "uniform", MinMs: 500, MaxMs: 3000},
+	}
+	actions, err := useraction.GenerateSession(rng, strategy, "session-1")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(actions), 22)
+	require.LessOrEqual(t, len(actions), 32)
+
+	assert.Equal(t, "login", actions[0].ActionType)
+	assert.Zero(t, actions[0].ThinkTimeMs, "the first action does not wait")
+	assert.Equal(t, "logout", actions[len(actions)-1].ActionType)
+	uploads := 0
+	for i, a := range actions {
+		assert.Equal(t, i, a.Sequence)
+		assert.Equal(t, "session-1", a.SessionID)
+		if i > 0 {
+			assert.GreaterOrEqual(t, a.ThinkTimeMs, int64(500))
+			assert.LessOrEqual(t, a.ThinkTimeMs, int64(3000))
+		}
+		if a.ActionType == "upload_file" {
+			uploads++
+		}
+	}
+	assert.Greater(t, uploads, (len(actions)-2)/2, "heavier actions dominate the stream")
+
+	strategy.ThinkTime = &models.ThinkTimeDistribution{Type: "gamma"}
+	assert.Error(t, (&Strategy{ActionStrategy: strategy}).Validate(false))
+}
+
+func TestPersonas(t *testing.T) {
+	rng := rand.New(rand.NewSource(31))
+	s := Strategy{
+		UserStrategy: models.UserStrategy{
+			UserLang:           []string{"en"},
+			LangProbability:    []float64{1},
+			Personas:           []string{"heavy_uploader", "reader"},
+			PersonaProbability: []float64{0.3, 0.7},
+		},
+		ActionStrategy: models.ActionStrategy{
+			ActionTypes:   []string{"upload_file", "download_file"},
+			ActionWeights: []float64{0.5, 0.5},
+			MinActions:    5,
+			MaxActions:    10,
+			Personas: map[string]models.PersonaActions{
+				"heavy_uploader": {ActionWeights: []float64{1, 0}, MinActions: 40, MaxActions: 50},
+				"reader":         {ActionWeights: []float64{0, 1}},
+			},
+		},
+	}
+	require.NoError(t, s.Validate(false))
+
+	personas := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		u, err := GenerateUser(rng, s.UserStrategy)
+		require.NoError(t, err)
+		personas[u.Persona]++
+	}
+	assert.Equal(t, 200, personas["heavy_uploader"]+personas["reader"])
+	assert.Greater(t, personas["reader"], personas["heavy_uploader"])
+
+	actions, err := useraction.GenerateSession(rng, useraction.ForPersona(s.ActionStrategy, "heavy_uploader"), "s1")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(actions), 40)
+	for _, a := range actions {
+		assert.Equal(t, "upload_file", a.ActionType)
+	}
+	actions, err = useraction.GenerateSession(rng, useraction.ForPersona(s.ActionStrategy, "reader"), "s2")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(actions), 10, "the default session length applies")
+	for _, a := range actions {
+		assert.Equal(t, "download_file", a.ActionType)
+	}
+	assert.Equal(t, s.ActionStrategy.ActionWeights, useraction.ForPersona(s.ActionStrategy, "").ActionWeights)
+
+	// Every assigned persona needs an action profile
+	s.UserStrategy.Personas[1] = "admin"
+	assert.Error(t, s.Validate(false))
+}
+
+func TestGenerateFileVersions(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	dir := t.TempDir()
+	strategy := models.FileStrategy{
+		FileExtension:            []string{"txt"},
+		FileExtensionProbability: []float64{1},
+		FileSize:                 []int{2048},
+		FileSizeProbability:      []float64{1},
+		FileLang:                 []string{"en"},
+		FileLangNameProbability:  []float64{1},
+		Versioning:               &models.VersioningStrategy{Probability: 1, MinVersions: 4, MaxVersions: 4},
+	}
+	files, err := GenerateFileVersions(rng, strategy, file.NewFileContentGenerator(dir, rng))
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	var previous []byte
+	for i, f := range files {
+		assert.Equal(t, i+1, f.Version)
+		assert.Equal(t, files[0].Name, f.Name, "revisions keep the document name")
+		assert.Equal(t, files[0].VersionGroup, f.VersionGroup)
+		data, err := os.ReadFile(filepath.Join(dir, file.LocalName(&f)))
+		require.NoError(t, err)
+		assert.Equal(t, f.FileSize, len(data))
+		assert.NotEqual(t, previous, data, "every revision changes the content")
+		previous = data
+	}
+	assert.NotEmpty(t, files[0].VersionGroup)
+
+	// Edge-case names filling the name limit leave room for the version marker
+	strategy.EdgeCaseNameRatio = 1
+	strategy.Versioning = &models.VersioningStrategy{Probability: 1, MinVersions: 12, MaxVersions: 12}
+	longNames := 0
+	for i := 0; i < 32; i++ {
+		files, err := GenerateFileVersions(rng, strategy, file.NewFileContentGenerator(dir, rng))
+		require.NoError(t, err)
+		require.Len(t, files, 12)
+		if len(file.LocalName(&files[0])) < 240 {
+			continue
+		}
+		longNames++
+		for _, f := range files {
+			assert.LessOrEqual(t, len(file.LocalName(&f)), 255)
+			_, err := os.Stat(filepath.Join(dir, file.LocalName(&f)))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NotZero(t, longNames)
+	strategy.EdgeCaseNameRatio = 0
+
+	strategy.Versioning = nil
+	files, err = GenerateFileVersions(rng, strategy, file.NewFileContentGenerator(dir, rng))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Zero(t, files[0].Version, "files are unversioned by default")
+}
+
+func TestFilePool(t *testing.T) {
+	start := func(pool PoolConfig, lazy bool) (Generator, string) {
+		dir := t.TempDir()
+		config := GeneratorConfig{
+			Strategy: Strategy{FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"txt", "csv"},
+				FileExtensionProbability: []float64{0.5, 0.5},
+				FileSize:                 []int{2048, 8192},
+				FileSizeProbability:      []float64{0.5, 0.5},
+				FileLang:                 []string{"en", "fr"},
+				FileLangNameProbability:  []float64{0.5, 0.5},
+				Versioning:               &models.VersioningStrategy{Probability: 0.5, MinVersions: 2, MaxVersions: 3},
+			}},
+			FileStore: FileStore{FilePath: dir},
+			Pool:      pool,
+			Lazy:      lazy,
+			Seed:      7,
+		}
+		var generator Generator
+		app := fx.New(
+			fx.NopLogger,
+			fx.Provide(func() GeneratorConfig { return config }),
+			Module,
+			fx.Populate(&generator),
+		)
+		require.NoError(t, app.Start(context.Background()))
+		t.Cleanup(func() { app.Stop(context.Background()) })
+		return generator, dir
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Files come out in dispatch order, so the worker count does not change them
+	wide, dir := start(PoolConfig{Workers: 4}, true)
+	narrow, _ := start(PoolConfig{Workers: 2, QueueSize: 1}, true)
+	var wideFiles, narrowFiles []models.File
+	for _, n := range []int{5, 6} {
+		files, err := wide.GenerateFiles(ctx, n)
+		require.NoError(t, err)
+		require.Len(t, files, n)
+		wideFiles = append(wideFiles, files...)
+		files, err = narrow.GenerateFiles(ctx, n)
+		require.NoError(t, err)
+		narrowFiles = append(narrowFiles, files...)
+	}
+	for i := range wideFiles {
+		assert.Equal(t, wideFiles[i].Name, narrowFiles[i].Name)
+		assert.Equal(t, wideFiles[i].Version, narrowFiles[i].Version)
+		assert.Equal(t, wideFiles[i].FileSize, narrowFiles[i].FileSize)
+		_, err := os.Stat(filepath.Join(dir, file.LocalName(&wideFiles[i])))
+		assert.NoError(t, err)
+		if wideFiles[i].Version > 1 {
+			assert.Equal(t, wideFiles[i-1].VersionGroup, wideFiles[i].VersionGroup, "revisions follow their previous version")
+		}
+	}
+
+	stats := wide.FileStats()
+	assert.GreaterOrEqual(t, stats.Files, int64(len(wideFiles)))
+	assert.Positive(t, stats.Duration)
+	assert.GreaterOrEqual(t, stats.Duration, stats.MaxDuration)
+	assert.Equal(t, stats.Files, stats.ByExtension["txt"].Files+stats.ByExtension["csv"].Files)
+
+	// Background workers keep producing while the pending bytes are bounded
+	background, _ := start(PoolConfig{Workers: 3, MaxPendingBytes: 4096}, false)
+	files := background.Files(ctx)
+	for i := 0; i < 10; i++ {
+		select {
+		case f := <-files:
+			assert.NotEmpty(t, f.Name)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for pooled files")
+		}
+	}
+}
+
+func TestGrowthSchedule(t *testing.T) {
+	activity := make([]float64, 24)
+	activity[10] = 1
+	noWeekend := 0.0
+	s, err := newGrowthSchedule(GrowthConfig{FilesPerDay: 240, HourlyActivity: activity, WeekendActivity: &noWeekend, Location: "UTC"}, 1)
+	require.NoError(t, err)
+
+	monday := time.Date(2024, time.June, 3, 10, 30, 0, 0, time.UTC)
+	assert.InDelta(t, 240.0/3600, s.rate(monday), 1e-9, "the whole day's files arrive in the only active hour")
+	assert.Zero(t, s.rate(monday.Add(time.Hour)))
+	assert.Zero(t, s.rate(monday.AddDate(0, 0, 5)), "weekends can be switched off")
+
+	// From an idle hour the next file lands in the next active hour
+	from := monday.Add(30 * time.Minute)
+	for i := 0; i < 20; i++ {
+		at := from.Add(s.next(from))
+		assert.Equal(t, 4, at.Day())
+		assert.Equal(t, 10, at.Hour())
+	}
+	// From Friday evening it skips the weekend
+	friday := time.Date(2024, time.June, 7, 18, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Monday, friday.Add(s.next(friday)).Weekday())
+
+	// Within the active hour gaps average 3600/240 seconds
+	var total time.Duration
+	start := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 200; i++ {
+		total += s.next(start)
+	}
+	assert.InDelta(t, 15.0, (total / 200).Seconds(), 3)
+
+	disabled, err := newGrowthSchedule(GrowthConfig{}, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, disabled)
+	_, err = newGrowthSchedule(GrowthConfig{FilesPerDay: 10, HourlyActivity: []float64{1, 2}}, 1)
+	assert.Error(t, err)
+	_, err = newGrowthSchedule(GrowthConfig{FilesPerDay: 10, Location: "Nowhere/Else"}, 1)
+	assert.Error(t, err)
+}
+
+func TestGrowthMode(t *testing.T) {
+	weekend := 1.0
+	config := GeneratorConfig{
+		Strategy: Strategy{FileStrategy: models.FileStrategy{
+			FileExtension:            []string{"txt"},
+			FileExtensionProbability: []float64{1},
+			FileSize:                 []int{1024},
+			FileSizeProbability:      []float64{1},
+			FileLang:                 []string{"en"},
+			FileLangNameProbability:  []float64{1},
+		}},
+		FileStore: FileStore{FilePath: t.TempDir()},
+		// Twenty files per second around the clock
+		Growth: GrowthConfig{FilesPerDay: 20 * 86400, HourlyActivity: []float64{
+			1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		}, WeekendActivity: &weekend},
+		Lazy: true,
+		Seed: 3,
+	}
+
+	var generator Generator
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(func() GeneratorConfig { return config }),
+		Module,
+		fx.Populate(&generator),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, app.Start(ctx))
+	defer app.Stop(context.Background())
+
+	files := generator.Files(ctx)
+	for i := 0; i < 3; i++ {
+		select {
+		case f := <-files:
+			assert.NotEmpty(t, f.Name)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for growth files")
+		}
+	}
+	assert.GreaterOrEqual(t, generator.FileStats().Files, int64(3))
+}
+
+func TestGeneratePIIFiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+	dir := t.TempDir()
+	strategy := models.FileStrategy{
+		FileExtension:            []string{"txt"},
+		FileExtensionProbability: []float64{1},
+		FileSize:                 []int{4096},
+		FileSizeProbability:      []float64{1},
+		FileLang:                 []string{"en"},
+		FileLangNameProbability:  []float64{1},
+		Versioning:               &models.VersioningStrategy{Probability: 1, MinVersions: 3, MaxVersions: 3},
+		PII:                      &models.PIIStrategy{Probability: 1, Types: []string{file.PIICreditCard, file.PIIIBAN}, MinItems: 4, MaxItems: 8},
+	}
+	s := Strategy{FileStrategy: strategy}
+	require.NoError(t, s.Validate(false))
+
+	files, err := GenerateFileVersions(rng, strategy, file.NewFileContentGenerator(dir, rng))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.GreaterOrEqual(t, len(files[0].PII), 4)
+	assert.LessOrEqual(t, len(files[0].PII), 8)
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file.LocalName(&f)))
+		require.NoError(t, err)
+		assert.NotEmpty(t, f.PII, "revisions keep the PII they were not edited out of")
+		for _, pii := range f.PII {
+			assert.Contains(t, []string{file.PIICreditCard, file.PIIIBAN}, pii.Type)
+			assert.Equal(t, pii.Value, string(data[pii.Offset:pii.Offset+len(pii.Value)]))
+		}
+	}
+
+	s.FileStrategy.PII = &models.PIIStrategy{Probability: 0.5, Types: []string{"passport"}, MinItems: 1, MaxItems: 1}
+	assert.ErrorContains(t, s.Validate(false), `unknown type "passport"`)
+	s.FileStrategy.PII = &models.PIIStrategy{Probability: 0.5, MinItems: 3, MaxItems: 1}
+	assert.ErrorContains(t, s.Validate(false), "min_items <= max_items")
+}
+
+func TestGenerateDryRun(t *testing.T) {
+	rng := rand.New(rand.NewSource(23))
+	dir := t.TempDir()
+	strategy := models.FileStrategy{
+		FileExtension:            []string{"txt", "pdf", "png"},
+		FileExtensionProbability: []float64{0.4, 0.3, 0.3},
+		FileSize:                 []int{4096, 1 << 30},
+		FileSizeProbability:      []float64{0.5, 0.5},
+		FileLang:                 []string{"en"},
+		FileLangNameProbability:  []float64{1},
+		DedupRatio:               0.4,
+		Versioning:               &models.VersioningStrategy{Probability: 0.3, MinVersions: 2, MaxVersions: 3},
+		PII:                      &models.PIIStrategy{Probability: 1, MinItems: 1, MaxItems: 2},
+	}
+	cg := file.NewFileContentGenerator(dir, rng)
+	cg.DryRun = true
+
+	seen := make(map[string]bool)
+	duplicates := 0
+	for i := 0; i < 50; i++ {
+		files, err := GenerateFileVersions(rng, strategy, cg)
+		require.NoError(t, err)
+		for _, f := range files {
+			assert.Regexp(t, "^[0-9a-f]{64}$", f.ContentID)
+			assert.Positive(t, f.FileSize)
+			assert.Empty(t, f.PII, "dry runs have no content to embed PII in")
+			if strings.HasPrefix(f.Description, "Duplicate of") {
+				duplicates++
+				assert.True(t, seen[f.ContentID], "exact duplicates share the ContentID of their source")
+			}
+			seen[f.ContentID] = true
+		}
+	}
+	assert.Positive(t, duplicates)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "dry runs write nothing")
+
+	// Real files carry the SHA-256 of their content
+	strategy.FileSize = []int{4096, 8192}
+	f, err := GenerateFile(rng, strategy, file.NewFileContentGenerator(dir, rng))
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, file.LocalName(&f)))
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.ContentID)
+}
+
+func TestGeneratorMetrics(t *testing.T) {
+	config := GeneratorConfig{
+		Strategy: Strategy{
+			UserStrategy: models.UserStrategy{UserLang: []string{"en"}, LangProbability: []float64{1}},
+			FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"txt"},
+				FileExtensionProbability: []float64{1},
+				FileSize:                 []int{2048},
+				FileSizeProbability:      []float64{1},
+				FileLang:                 []string{"en"},
+				FileLangNameProbability:  []float64{1},
+			},
+		},
+		FileStore: FileStore{FilePath: t.TempDir()},
+		Metrics:   MetricsConfig{Addr: "127.0.0.1:0"},
+		Lazy:      true,
+		Seed:      5,
+	}
+	var generator Generator
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(func() GeneratorConfig { return config }),
+		Module,
+		fx.Populate(&generator),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, app.Start(ctx))
+	defer app.Stop(context.Background())
+
+	_, err := generator.GenerateUsers(ctx, 3)
+	require.NoError(t, err)
+	_, err = generator.GenerateFiles(ctx, 2)
+	require.NoError(t, err)
+	_, err = generator.GenerateWorkspaces(ctx, 1)
+	assert.Error(t, err, "no users are stored yet")
+
+	// Scrape the endpoint the generator serves
+	server := generator.(*generatorImpl).metricsServer
+	require.NotNil(t, server)
+	resp, err := http.Get("http://" + server.Addr + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	metrics := string(body)
+
+	assert.Contains(t, metrics, "# TYPE robo_generator_generated_total counter")
+	assert.Contains(t, metrics, `robo_generator_generated_total{kind="user"} 3`)
+	assert.Contains(t, metrics, `robo_generator_generated_total{kind="file"} 2`)
+	assert.Contains(t, metrics, `robo_generator_errors_total{kind="workspace"} 1`)
+	assert.Contains(t, metrics, `robo_generator_file_seconds_total{extension="txt"}`)
+	assert.Contains(t, metrics, `robo_generator_channel_capacity{kind="file"} 0`, "lazy channels are unbuffered")
+	assert.Regexp(t, `robo_generator_file_bytes_total \d+`, metrics)
+}
+
+func TestGenerateCycleFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := GeneratorConfig{
+		Strategy: Strategy{
+			FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"txt"},
+				FileExtensionProbability: []float64{1},
+				FileSize:                 []int{2048},
+				FileSizeProbability:      []float64{1},
+				FileLang:                 []string{"en"},
+				FileLangNameProbability:  []float64{1},
+				Versioning:               &models.VersioningStrategy{Probability: 0.5, MinVersions: 2, MaxVersions: 3},
+			},
+		},
+		FileStore: FileStore{FilePath: dir},
+		Lazy:      true,
+		Seed:      9,
+	}
+	for _, workers := range []int{1, 3} {
+		config.Pool = PoolConfig{Workers: workers}
+		var generator Generator
+		app := fx.New(
+			fx.NopLogger,
+			fx.Provide(func() GeneratorConfig { return config }),
+			Module,
+			fx.Populate(&generator),
+		)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		require.NoError(t, app.Start(ctx))
+
+		cycleID := "cycle-" + strconv.Itoa(workers)
+		files, err := generator.GenerateFiles(WithCycle(ctx, cycleID), 5)
+		require.NoError(t, err)
+		require.Len(t, files, 5)
+		for _, f := range files {
+			assert.Equal(t, cycleID, f.CycleID)
+			assert.FileExists(t, filepath.Join(dir, "cycles", cycleID, file.LocalName(&f)))
+		}
+
+		// Revisions left over for the cycle are not handed out outside it
+		files, err = generator.GenerateFiles(ctx, 2)
+		require.NoError(t, err)
+		for _, f := range files {
+			assert.Empty(t, f.CycleID)
+			assert.FileExists(t, filepath.Join(dir, file.LocalName(&f)))
+		}
+		require.NoError(t, app.Stop(context.Background()))
+		cancel()
+	}
+}
+
+func TestStrategyPresets(t *testing.T) {
+	for _, name := range StrategyPresets() {
+		s, err := StrategyPreset(name)
+		require.NoError(t, err)
+		assert.NoError(t, s.Validate(false), name)
+	}
+	_, err := StrategyPreset("nope")
+	assert.Error(t, err)
+
+	var config GeneratorConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"strategy": {
+		"preset": "small-office",
+		"file_strategy": {"dedup_ratio": 0.3, "file_name_lang": ["vi"], "file_name_probability": [1]}
+	}}`), &config))
+	s := config.Strategy
+	assert.Equal(t, "small-office", s.Preset)
+	assert.Equal(t, 0.3, s.FileStrategy.DedupRatio)
+	assert.Equal(t, []string{"vi"}, s.FileStrategy.FileLang)
+	assert.Contains(t, s.FileStrategy.FileExtension, "docx", "fields left out come from the preset")
+	assert.Equal(t, []string{"en"}, s.UserStrategy.UserLang)
+	require.NoError(t, s.Validate(false))
+
+	// Overrides do not leak into later uses of the preset
+	require.NoError(t, json.Unmarshal([]byte(`{"preset": "small-office", "file_strategy": {"versioning": {"max_versions": 9}}}`), &s))
+	assert.Equal(t, 9, s.FileStrategy.Versioning.MaxVersions)
+	fresh, err := StrategyPreset("small-office")
+	require.NoError(t, err)
+	assert.Equal(t, 4, fresh.FileStrategy.Versioning.MaxVersions)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"preset": "nope"}`), &s))
+}
@@ -3,15 +3,14 @@ package generator
 import (
 	"fmt"
 	"math/rand"
-	"time"
 
+	"github.com/songvi/robo/generator/file"
 	"github.com/songvi/robo/models"
 )
 
-// GenerateWorkspace creates a new workspace with a randomly selected list of user UUIDs based on the WorkspaceStrategy
-func GenerateWorkspace(wsStrategy models.WorkspaceStrategy, availableUserUUIDs []string) (models.Workspace, error) {
-	rand.Seed(time.Now().UnixNano())
-
+// GenerateWorkspace creates a new workspace with a randomly selected list of users based on the WorkspaceStrategy.
+// The workspace name is generated in a language drawn from the selected members' languages.
+func GenerateWorkspace(rng *rand.Rand, wsStrategy models.WorkspaceStrategy, availableUsers []models.User) (models.Workspace, error) {
 	// Validate WorkspaceStrategy
 	if len(wsStrategy.NumberOfUsers) == 0 || len(wsStrategy.NumberOfUsersProbability) == 0 {
 		return models.Workspace{}, fmt.Errorf("invalid WorkspaceStrategy: number_of_users or number_of_users_probability is empty")
@@ -20,32 +19,36 @@ func GenerateWorkspace(wsStrategy models.WorkspaceStrategy, availableUserUUIDs [
 		return models.Workspace{}, fmt.Errorf("invalid WorkspaceStrategy: number_of_users and number_of_users_probability lengths do not match")
 	}
 
-	// Validate availableUserUUIDs
-	if len(availableUserUUIDs) == 0 {
-		return models.Workspace{}, fmt.Errorf("no available user UUIDs provided")
+	// Validate availableUsers
+	if len(availableUsers) == 0 {
+		return models.Workspace{}, fmt.Errorf("no available users provided")
 	}
 
 	// Select number of users based on probability
-	numUsersIndex := selectWorkspaceIndexByProbability(wsStrategy.NumberOfUsersProbability)
+	numUsersIndex := selectWorkspaceIndexByProbability(rng, wsStrategy.NumberOfUsersProbability)
 	numUsers := wsStrategy.NumberOfUsers[numUsersIndex]
 
 	// Ensure we don't select more users than available
-	if numUsers > len(availableUserUUIDs) {
-		numUsers = len(availableUserUUIDs)
+	if numUsers > len(availableUsers) {
+		numUsers = len(availableUsers)
 	}
 
-	// Shuffle available UUIDs to select random users
-	uuids := make([]string, len(availableUserUUIDs))
-	copy(uuids, availableUserUUIDs)
-	rand.Shuffle(len(uuids), func(i, j int) {
-		uuids[i], uuids[j] = uuids[j], uuids[i]
+	// Shuffle available users to select random members
+	users := make([]models.User, len(availableUsers))
+	copy(users, availableUsers)
+	rng.Shuffle(len(users), func(i, j int) {
+		users[i], users[j] = users[j], users[i]
 	})
 
-	// Select UUIDs for the workspace
-	selectedUUIDs := uuids[:numUsers]
+	// Select members for the workspace
+	members := users[:numUsers]
+	selectedUUIDs := make([]string, len(members))
+	for i, u := range members {
+		selectedUUIDs[i] = u.UUID
+	}
 
-	// Generate workspace name
-	workspaceName := generateWspRandomName(8, 16)
+	// Generate workspace name in the members' languages
+	workspaceName := file.GenerateFolderName(rng, selectMemberLanguage(rng, members))
 
 	return models.Workspace{
 		Name:  workspaceName,
@@ -54,8 +57,8 @@ func GenerateWorkspace(wsStrategy models.WorkspaceStrategy, availableUserUUIDs [
 }
 
 // selectWorkspaceIndexByProbability selects an index based on a probability distribution
-func selectWorkspaceIndexByProbability(probabilities []float64) int {
-	r := rand.Float64()
+func selectWorkspaceIndexByProbability(rng *rand.Rand, probabilities []float64) int {
+	r := rng.Float64()
 	sum := 0.0
 	for i, p := range probabilities {
 		sum += p
@@ -66,13 +69,16 @@ func selectWorkspaceIndexByProbability(probabilities []float64) int {
 	return len(probabilities) - 1
 }
 
-// generateWspRandomName generates a random string of specified length range
-func generateWspRandomName(minLen, maxLen int) string {
-	length := minLen + rand.Intn(maxLen-minLen+1)
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+// selectMemberLanguage picks a language weighted by how many members use it, defaulting to English
+func selectMemberLanguage(rng *rand.Rand, members []models.User) string {
+	var langs []string
+	for _, u := range members {
+		if u.Language != "" {
+			langs = append(langs, u.Language)
+		}
+	}
+	if len(langs) == 0 {
+		return "en"
 	}
-	return string(b)
+	return langs[rng.Intn(len(langs))]
 }
@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// defaultHourlyActivity is an office-hours curve: quiet nights, a morning ramp, a
+// lunch dip and an evening tail
+var defaultHourlyActivity = []float64{
+	0.1, 0.05, 0.05, 0.05, 0.05, 0.1, 0.3, 0.8, 1.6, 2.0, 2.0, 1.8,
+	1.2, 1.6, 1.9, 1.9, 1.7, 1.3, 0.8, 0.5, 0.4, 0.3, 0.2, 0.15,
+}
+
+// defaultWeekendActivity is the Saturday and Sunday activity relative to weekdays
+const defaultWeekendActivity = 0.3
+
+// growthSchedule spreads FilesPerDay over the day following the activity curve, as a
+// Poisson process whose rate changes every hour
+type growthSchedule struct {
+	perHour  [24]float64 // Files per hour of a weekday
+	weekend  float64
+	location *time.Location
+	rng      *rand.Rand
+	now      func() time.Time
+}
+
+// newGrowthSchedule validates cfg and builds its schedule; it returns nil when growth
+// mode is disabled
+func newGrowthSchedule(cfg GrowthConfig, seed int64) (*growthSchedule, error) {
+	if cfg.FilesPerDay <= 0 {
+		return nil, nil
+	}
+	activity := cfg.HourlyActivity
+	if len(activity) == 0 {
+		activity = defaultHourlyActivity
+	}
+	if len(activity) != 24 {
+		return nil, fmt.Errorf("growth hourly_activity must have 24 values, got %d", len(activity))
+	}
+	total := 0.0
+	for _, a := range activity {
+		if a < 0 {
+			return nil, fmt.Errorf("growth hourly_activity must not be negative")
+		}
+		total += a
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("growth hourly_activity must not be all zero")
+	}
+	weekend := defaultWeekendActivity
+	if cfg.WeekendActivity != nil {
+		if weekend = *cfg.WeekendActivity; weekend < 0 {
+			return nil, fmt.Errorf("growth weekend_activity must not be negative")
+		}
+	}
+	location := time.Local
+	if cfg.Location != "" {
+		var err error
+		if location, err = time.LoadLocation(cfg.Location); err != nil {
+			return nil, fmt.Errorf("invalid growth location: %v", err)
+		}
+	}
+
+	s := &growthSchedule{weekend: weekend, location: location, rng: rand.New(rand.NewSource(seed)), now: time.Now}
+	for h, a := range activity {
+		s.perHour[h] = cfg.FilesPerDay * a / total
+	}
+	return s, nil
+}
+
+// rate returns the expected files per second at t
+func (s *growthSchedule) rate(t time.Time) float64 {
+	t = t.In(s.location)
+	perHour := s.perHour[t.Hour()]
+	if day := t.Weekday(); day == time.Saturday || day == time.Sunday {
+		perHour *= s.weekend
+	}
+	return perHour / 3600
+}
+
+// next returns how long to wait from now until the next file. The rate is constant
+// within an hour, so a gap crossing the hour is drawn again from the new rate; the
+// process being memoryless keeps that exact.
+func (s *growthSchedule) next(now time.Time) time.Duration {
+	var wait time.Duration
+	for {
+		t := now.Add(wait).In(s.location)
+		untilHour := time.Hour - time.Duration(t.Minute())*time.Minute -
+			time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond())
+		if rate := s.rate(t); rate > 0 {
+			gap := time.Duration(s.rng.ExpFloat64() / rate * float64(time.Second))
+			if gap < untilHour {
+				return wait + gap
+			}
+		}
+		wait += untilHour
+	}
+}
+
+// runGrowthWorker adds files to the corpus on the growth schedule. Files are in the
+// repository or store once generated, so consumers falling behind on the file
+// channel miss them rather than holding the growth back.
+func (g *generatorImpl) runGrowthWorker() {
+	ctx := g.workerCtx
+	for {
+		timer := time.NewTimer(g.growth.next(g.growth.now()))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		files, err := g.GenerateFiles(ctx, 1)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error growing file corpus: %v", err)
+			continue
+		}
+		select {
+		case g.fileCh <- files[0]:
+		default:
+		}
+	}
+}
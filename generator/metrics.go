@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// generatorKinds are the kinds of items metrics are reported for
+var generatorKinds = []string{"user", "file", "workspace", "action"}
+
+// generatorMetrics counts generated items and generation errors per kind
+type generatorMetrics struct {
+	mu        sync.Mutex
+	generated map[string]int64
+	errors    map[string]int64
+}
+
+// record accounts for one generation attempt of kind that produced n items or failed
+func (m *generatorMetrics) record(kind string, n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.generated == nil {
+		m.generated = make(map[string]int64)
+		m.errors = make(map[string]int64)
+	}
+	if err != nil {
+		m.errors[kind]++
+		return
+	}
+	m.generated[kind] += int64(n)
+}
+
+// counts returns a copy of the generated and error counters
+func (m *generatorMetrics) counts() (generated, failed map[string]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	generated, failed = make(map[string]int64), make(map[string]int64)
+	for _, kind := range generatorKinds {
+		generated[kind] = m.generated[kind]
+		failed[kind] = m.errors[kind]
+	}
+	return generated, failed
+}
+
+// metricFamily is one metric in the Prometheus text exposition format
+type metricFamily struct {
+	name, help, kind string
+	label            string
+	values           map[string]float64 // Label value -> sample; "" when unlabelled
+}
+
+// writeMetricFamilies writes families in the Prometheus text exposition format
+func writeMetricFamilies(w io.Writer, families []metricFamily) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		labels := make([]string, 0, len(f.values))
+		for label := range f.values {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			if f.label == "" {
+				fmt.Fprintf(bw, "%s %g\n", f.name, f.values[label])
+			} else {
+				fmt.Fprintf(bw, "%s{%s=%q} %g\n", f.name, f.label, label, f.values[label])
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMetrics writes the generator metrics in the Prometheus text exposition format
+func (g *generatorImpl) WriteMetrics(w io.Writer) error {
+	generated, failed := g.metrics.counts()
+	stats := g.stats.snapshot()
+	generated["file"] = stats.Files
+
+	toFloat := func(counts map[string]int64) map[string]float64 {
+		values := make(map[string]float64, len(counts))
+		for k, v := range counts {
+			values[k] = float64(v)
+		}
+		return values
+	}
+	fileSeconds := make(map[string]float64)
+	for ext, s := range stats.ByExtension {
+		fileSeconds[ext] = s.Duration.Seconds()
+	}
+
+	return writeMetricFamilies(w, []metricFamily{
+		{name: "robo_generator_generated_total", help: "Items generated, by kind.", kind: "counter",
+			label: "kind", values: toFloat(generated)},
+		{name: "robo_generator_errors_total", help: "Failed generation attempts, by kind.", kind: "counter",
+			label: "kind", values: toFloat(failed)},
+		{name: "robo_generator_file_bytes_total", help: "File content bytes generated.", kind: "counter",
+			values: map[string]float64{"": float64(stats.Bytes)}},
+		{name: "robo_generator_file_seconds_total", help: "Time spent writing file content, by extension.", kind: "counter",
+			label: "extension", values: fileSeconds},
+		{name: "robo_generator_channel_items", help: "Items generated ahead of consumers, by channel.", kind: "gauge",
+			label: "kind", values: map[string]float64{
+				"user": float64(len(g.userCh)), "file": float64(len(g.fileCh)), "workspace": float64(len(g.workspaceCh)),
+			}},
+		{name: "robo_generator_channel_capacity", help: "Capacity of the output channels, by channel.", kind: "gauge",
+			label: "kind", values: map[string]float64{
+				"user": float64(cap(g.userCh)), "file": float64(cap(g.fileCh)), "workspace": float64(cap(g.workspaceCh)),
+			}},
+	})
+}
+
+// MetricsHandler serves the metrics of g to Prometheus scrapes
+func MetricsHandler(g Generator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := g.WriteMetrics(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// serveMetrics starts serving the metrics of g on addr under /metrics
+func serveMetrics(addr string, g Generator) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics on %s: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler(g))
+	server := &http.Server{Addr: listener.Addr().String(), Handler: mux}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+	return server, nil
+}
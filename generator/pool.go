@@ -0,0 +1,225 @@
+package generator
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// GenerationStats aggregates the time spent writing file content
+type GenerationStats struct {
+	Files       int64
+	Bytes       int64
+	Duration    time.Duration // Summed over files, so it exceeds wall time with several workers
+	MaxDuration time.Duration // Slowest single file
+}
+
+// FileStats reports file generation timings overall and per file extension
+type FileStats struct {
+	GenerationStats
+	ByExtension map[string]GenerationStats
+}
+
+// fileStats accumulates the GenerationTime of generated files
+type fileStats struct {
+	mu    sync.Mutex
+	total GenerationStats
+	byExt map[string]GenerationStats
+}
+
+// add accounts for one generated file
+func (s *GenerationStats) add(f models.File) {
+	s.Files++
+	s.Bytes += int64(f.FileSize)
+	s.Duration += f.GenerationTime
+	if f.GenerationTime > s.MaxDuration {
+		s.MaxDuration = f.GenerationTime
+	}
+}
+
+// record accounts for generated files
+func (s *fileStats) record(files []models.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byExt == nil {
+		s.byExt = make(map[string]GenerationStats)
+	}
+	for _, f := range files {
+		s.total.add(f)
+		ext := strings.ToLower(f.FileExtension)
+		stats := s.byExt[ext]
+		stats.add(f)
+		s.byExt[ext] = stats
+	}
+}
+
+// snapshot returns a copy of the accumulated stats
+func (s *fileStats) snapshot() FileStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := FileStats{GenerationStats: s.total, ByExtension: make(map[string]GenerationStats, len(s.byExt))}
+	for ext, st := range s.byExt {
+		stats.ByExtension[ext] = st
+	}
+	return stats
+}
+
+// fileJob asks a pool worker for one file and its revisions
+type fileJob struct {
+	seq     int64
+	seed    int64
+	cycleID string
+}
+
+// fileResult is what a pool worker generated for a fileJob
+type fileResult struct {
+	seq   int64
+	files []models.File
+	err   error
+}
+
+// usePool tells whether files are generated by the worker pool
+func (g *generatorImpl) usePool() bool {
+	return g.config.Pool.Workers > 1
+}
+
+// generateJob generates the files of job with a source seeded for it and a fork of
+// the content generator, so it can run alongside other jobs
+func (g *generatorImpl) generateJob(job fileJob) fileResult {
+	rng := rand.New(rand.NewSource(job.seed))
+	contentGenerator := g.contentGenerator.Fork(rng)
+	contentGenerator.CycleID = job.cycleID
+	files, err := GenerateFileVersions(rng, g.config.Strategy.FileStrategy, contentGenerator)
+	g.metrics.record("file", len(files), err)
+	if err == nil {
+		g.stats.record(files)
+	}
+	return fileResult{seq: job.seq, files: files, err: err}
+}
+
+// runFilePool generates files on the pool workers and passes them to emit in the order
+// their jobs were dispatched, until emit returns false, limit files were emitted (a
+// negative limit never stops) or ctx is done. Each job is seeded from the file source,
+// so a fixed seed yields the same files whatever the number of workers; only
+// duplicates depend on which earlier files had completed. Failed jobs stop the pool
+// when stopOnError is set and are skipped otherwise. Files belong to the cycle set on
+// ctx by WithCycle.
+//
+// Backpressure keeps at most Workers+QueueSize jobs between dispatch and emit, and
+// holds back new jobs while the files awaiting emit exceed MaxPendingBytes.
+func (g *generatorImpl) runFilePool(ctx context.Context, limit int, stopOnError bool, emit func(models.File) bool) error {
+	cfg := g.config.Pool
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = cfg.Workers
+	}
+	capacity := int64(cfg.Workers + queueSize)
+	cycleID, src := CycleFromContext(ctx), g.sourcesFor(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	jobs := make(chan fileJob, capacity)
+	results := make(chan fileResult, capacity)
+	var pendingBytes atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				result := g.generateJob(job)
+				for _, f := range result.files {
+					pendingBytes.Add(int64(f.FileSize))
+				}
+				results <- result // Never blocks: at most capacity jobs are in flight
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		close(jobs)
+		wg.Wait()
+	}()
+
+	var dispatched, next int64
+	emitted := 0
+	ready := make(map[int64]fileResult)
+	for {
+		if limit >= 0 && emitted >= limit {
+			break
+		}
+		// Every job yields at least one file, so never dispatch more than the limit needs
+		for inFlight := dispatched - next; inFlight < capacity &&
+			(limit < 0 || int64(emitted)+inFlight < int64(limit)) &&
+			(inFlight == 0 || cfg.MaxPendingBytes <= 0 || pendingBytes.Load() < cfg.MaxPendingBytes); inFlight++ {
+			g.fileMu.Lock()
+			seed := src.file.Int63()
+			g.fileMu.Unlock()
+			jobs <- fileJob{seq: dispatched, seed: seed, cycleID: cycleID}
+			dispatched++
+		}
+
+		select {
+		case result := <-results:
+			ready[result.seq] = result
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// Emit completed jobs in dispatch order
+		for result, ok := ready[next]; ok && (limit < 0 || emitted < limit); result, ok = ready[next] {
+			delete(ready, next)
+			next++
+			if result.err != nil {
+				if stopOnError {
+					return result.err
+				}
+				continue
+			}
+			for i, f := range result.files {
+				if limit >= 0 && emitted >= limit {
+					// Put the revisions past the limit back in line
+					next--
+					ready[next] = fileResult{seq: next, files: result.files[i:]}
+					break
+				}
+				pendingBytes.Add(-int64(f.FileSize))
+				if !emit(f) {
+					return ctx.Err()
+				}
+				emitted++
+			}
+		}
+	}
+
+	// Keep the revisions and jobs past the limit for the next call, in order
+	var leftover []models.File
+	for next < dispatched {
+		result, ok := ready[next]
+		if !ok {
+			select {
+			case result := <-results:
+				ready[result.seq] = result
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		delete(ready, next)
+		next++
+		if result.err == nil {
+			leftover = append(leftover, result.files...)
+		}
+	}
+	g.fileMu.Lock()
+	g.pendingFiles[cycleID] = append(g.pendingFiles[cycleID], leftover...)
+	g.fileMu.Unlock()
+	return nil
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/songvi/robo/models"
+)
+
+// strategyPresets are the named strategies shipped with robo. Each call builds a fresh
+// strategy, so overrides decoded into it never leak into the next use of the preset.
+var strategyPresets = map[string]func() Strategy{
+	// Office documents of modest size shared in small teams
+	"small-office": func() Strategy {
+		return Strategy{
+			FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"docx", "xlsx", "pptx", "pdf", "txt", "csv", "eml"},
+				FileExtensionProbability: []float64{0.25, 0.2, 0.1, 0.2, 0.1, 0.1, 0.05},
+				FileSize:                 []int{8 << 10, 64 << 10, 512 << 10, 4 << 20},
+				FileSizeProbability:      []float64{0.3, 0.4, 0.25, 0.05},
+				FileLang:                 []string{"en"},
+				FileLangNameProbability:  []float64{1},
+				DedupRatio:               0.05,
+				NearDuplicateRatio:       0.05,
+				Versioning:               &models.VersioningStrategy{Probability: 0.2, MinVersions: 2, MaxVersions: 4},
+			},
+			UserStrategy: models.UserStrategy{
+				UserLang:        []string{"en"},
+				LangProbability: []float64{1},
+				Profile:         &models.ProfileStrategy{Email: true, Phone: true, JobTitle: true},
+			},
+			WorkspaceStrategy: models.WorkspaceStrategy{
+				NumberOfUsers:            []int{2, 5, 10},
+				NumberOfUsersProbability: []float64{0.3, 0.5, 0.2},
+			},
+		}
+	},
+	// Large images, audio, video and archives with a long tail of sizes
+	"media-heavy": func() Strategy {
+		return Strategy{
+			FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"jpg", "png", "mp4", "mp3", "zip", "pdf"},
+				FileExtensionProbability: []float64{0.3, 0.2, 0.2, 0.1, 0.1, 0.1},
+				FileSizeDistribution: &models.SizeDistribution{
+					Type: "lognormal", Mu: 15, Sigma: 1.5, Min: 64 << 10, Max: 2 << 30,
+				},
+				FileLang:                []string{"en"},
+				FileLangNameProbability: []float64{1},
+				DedupRatio:              0.1,
+			},
+			UserStrategy: models.UserStrategy{
+				UserLang:        []string{"en"},
+				LangProbability: []float64{1},
+			},
+			WorkspaceStrategy: models.WorkspaceStrategy{
+				NumberOfUsers:            []int{2, 5},
+				NumberOfUsersProbability: []float64{0.6, 0.4},
+			},
+		}
+	},
+	// Japanese, Chinese and Korean names and content, partly mixed with English
+	"cjk-heavy": func() Strategy {
+		return Strategy{
+			FileStrategy: models.FileStrategy{
+				FileExtension:            []string{"txt", "docx", "pdf", "xlsx", "md"},
+				FileExtensionProbability: []float64{0.3, 0.25, 0.2, 0.15, 0.1},
+				FileSize:                 []int{4 << 10, 64 << 10, 1 << 20},
+				FileSizeProbability:      []float64{0.4, 0.45, 0.15},
+				FileLang:                 []string{"jp", "cn", "kn", "en"},
+				FileLangNameProbability:  []float64{0.3, 0.3, 0.3, 0.1},
+				ContentLanguageMix: &models.LanguageMixStrategy{
+					Probability: 0.2,
+					Languages:   []string{"jp", "cn", "kn", "en"},
+					Weights:     []float64{1, 1, 1, 1},
+				},
+			},
+			UserStrategy: models.UserStrategy{
+				UserLang:        []string{"jp", "cn", "kn", "en"},
+				LangProbability: []float64{0.3, 0.3, 0.3, 0.1},
+			},
+			WorkspaceStrategy: models.WorkspaceStrategy{
+				NumberOfUsers:            []int{2, 5, 10},
+				NumberOfUsersProbability: []float64{0.3, 0.5, 0.2},
+			},
+		}
+	},
+}
+
+// StrategyPresets returns the names of the shipped strategy presets, sorted
+func StrategyPresets() []string {
+	names := make([]string, 0, len(strategyPresets))
+	for name := range strategyPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StrategyPreset returns the shipped strategy of the given name
+func StrategyPreset(name string) (Strategy, error) {
+	preset, ok := strategyPresets[name]
+	if !ok {
+		return Strategy{}, fmt.Errorf("unknown strategy preset %q, expected one of %v", name, StrategyPresets())
+	}
+	s := preset()
+	s.Preset = name
+	return s, nil
+}
+
+// UnmarshalJSON decodes a strategy over the preset it names, if any: fields present in
+// the JSON override the preset, nested objects field by field and lists as a whole
+func (s *Strategy) UnmarshalJSON(data []byte) error {
+	var head struct {
+		Preset string `json:"preset"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	var base Strategy
+	if head.Preset != "" {
+		var err error
+		if base, err = StrategyPreset(head.Preset); err != nil {
+			return err
+		}
+	}
+	type plain Strategy // Drops this method to avoid recursing
+	if err := json.Unmarshal(data, (*plain)(&base)); err != nil {
+		return err
+	}
+	*s = base
+	return nil
+}
@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/songvi/robo/generator/file"
+	useraction "github.com/songvi/robo/generator/user_action"
+)
+
+// probabilityTolerance is how far a probability list may sum from 1 and still be accepted
+const probabilityTolerance = 1e-6
+
+// checkProbabilities validates a probability list for the n values it weights. When the
+// list does not sum to 1 it is rescaled if normalize is set and rejected otherwise.
+func checkProbabilities(field string, probabilities []float64, n int, normalize bool) error {
+	if len(probabilities) == 0 || n == 0 {
+		return fmt.Errorf("%s: values and probabilities must not be empty", field)
+	}
+	if len(probabilities) != n {
+		return fmt.Errorf("%s: %d probabilities for %d values", field, len(probabilities), n)
+	}
+	sum := 0.0
+	for i, p := range probabilities {
+		if math.IsNaN(p) || math.IsInf(p, 0) || p < 0 {
+			return fmt.Errorf("%s: probability at index %d is %v, must be a non-negative number", field, i, p)
+		}
+		sum += p
+	}
+	if sum == 0 {
+		return fmt.Errorf("%s: probabilities sum to 0", field)
+	}
+	if math.Abs(sum-1) <= probabilityTolerance {
+		return nil
+	}
+	if !normalize {
+		return fmt.Errorf("%s: probabilities sum to %.4g instead of 1 (enable normalize_probabilities to rescale them)", field, sum)
+	}
+	for i := range probabilities {
+		probabilities[i] /= sum
+	}
+	return nil
+}
+
+// Validate checks every probability list of the strategies that are configured,
+// rescaling lists that do not sum to 1 in place when normalize is set
+func (s *Strategy) Validate(normalize bool) error {
+	fs := &s.FileStrategy
+	if len(fs.FileExtension) > 0 || len(fs.FileExtensionProbability) > 0 {
+		if err := checkProbabilities("file_strategy.file_extension", fs.FileExtensionProbability, len(fs.FileExtension), normalize); err != nil {
+			return err
+		}
+		if fs.FileSizeDistribution != nil {
+			if err := validateSizeDistribution(fs.FileSizeDistribution); err != nil {
+				return fmt.Errorf("file_strategy.file_size_distribution: %v", err)
+			}
+		} else if err := checkProbabilities("file_strategy.file_size", fs.FileSizeProbability, len(fs.FileSize), normalize); err != nil {
+			return err
+		}
+		if err := checkProbabilities("file_strategy.file_name_lang", fs.FileLangNameProbability, len(fs.FileLang), normalize); err != nil {
+			return err
+		}
+		for name, ratio := range map[string]float64{
+			"dedup_ratio":          fs.DedupRatio,
+			"near_duplicate_ratio": fs.NearDuplicateRatio,
+			"edge_case_name_ratio": fs.EdgeCaseNameRatio,
+		} {
+			if ratio < 0 || ratio > 1 {
+				return fmt.Errorf("file_strategy.%s: %v is outside [0, 1]", name, ratio)
+			}
+		}
+		if fs.DedupRatio+fs.NearDuplicateRatio > 1 {
+			return fmt.Errorf("file_strategy: dedup_ratio and near_duplicate_ratio together exceed 1")
+		}
+		if mix := fs.ContentLanguageMix; mix != nil {
+			if mix.Probability < 0 || mix.Probability > 1 {
+				return fmt.Errorf("file_strategy.content_language_mix.probability: %v is outside [0, 1]", mix.Probability)
+			}
+			// Weights are relative shares, so they are always rescaled
+			if err := checkProbabilities("file_strategy.content_language_mix", mix.Weights, len(mix.Languages), true); err != nil {
+				return err
+			}
+		}
+		if v := fs.Versioning; v != nil {
+			if v.Probability < 0 || v.Probability > 1 {
+				return fmt.Errorf("file_strategy.versioning.probability: %v is outside [0, 1]", v.Probability)
+			}
+			if v.MinVersions < 1 || v.MaxVersions < v.MinVersions {
+				return fmt.Errorf("file_strategy.versioning: versions must satisfy 1 <= min_versions <= max_versions")
+			}
+		}
+		if p := fs.PII; p != nil {
+			if p.Probability < 0 || p.Probability > 1 {
+				return fmt.Errorf("file_strategy.pii.probability: %v is outside [0, 1]", p.Probability)
+			}
+			if p.MinItems < 1 || p.MaxItems < p.MinItems {
+				return fmt.Errorf("file_strategy.pii: items must satisfy 1 <= min_items <= max_items")
+			}
+			for _, t := range p.Types {
+				if !slices.Contains(file.PIITypes, t) {
+					return fmt.Errorf("file_strategy.pii.types: unknown type %q", t)
+				}
+			}
+		}
+	}
+
+	us := &s.UserStrategy
+	if len(us.UserLang) > 0 || len(us.LangProbability) > 0 {
+		if err := checkProbabilities("user_strategy.user_lang", us.LangProbability, len(us.UserLang), normalize); err != nil {
+			return err
+		}
+	}
+	if len(us.Personas) > 0 || len(us.PersonaProbability) > 0 {
+		if err := checkProbabilities("user_strategy.personas", us.PersonaProbability, len(us.Personas), normalize); err != nil {
+			return err
+		}
+		for _, persona := range us.Personas {
+			if _, ok := s.ActionStrategy.Personas[persona]; !ok {
+				return fmt.Errorf("user_strategy.personas: %q has no profile in action_strategy.personas", persona)
+			}
+		}
+	}
+
+	ws := &s.WorkspaceStrategy
+	if len(ws.NumberOfUsers) > 0 || len(ws.NumberOfUsersProbability) > 0 {
+		if err := checkProbabilities("workspace_strategy.number_of_users", ws.NumberOfUsersProbability, len(ws.NumberOfUsers), normalize); err != nil {
+			return err
+		}
+	}
+
+	as := &s.ActionStrategy
+	if len(as.ActionTypes) > 0 || len(as.ActionWeights) > 0 {
+		if err := checkProbabilities("action_strategy.action_types", as.ActionWeights, len(as.ActionTypes), normalize); err != nil {
+			return err
+		}
+		if as.MinActions < 0 || as.MaxActions < as.MinActions {
+			return fmt.Errorf("action_strategy: actions bounds must satisfy 0 <= min_actions <= max_actions")
+		}
+		if as.ThinkTime != nil {
+			if err := useraction.ValidateThinkTime(as.ThinkTime); err != nil {
+				return fmt.Errorf("action_strategy.think_time: %v", err)
+			}
+		}
+	}
+	for name, persona := range as.Personas {
+		if len(persona.ActionWeights) > 0 {
+			field := fmt.Sprintf("action_strategy.personas.%s.action_weights", name)
+			if err := checkProbabilities(field, persona.ActionWeights, len(as.ActionTypes), normalize); err != nil {
+				return err
+			}
+		}
+		if persona.MaxActions > 0 && (persona.MinActions < 0 || persona.MaxActions < persona.MinActions) {
+			return fmt.Errorf("action_strategy.personas.%s: actions bounds must satisfy 0 <= min_actions <= max_actions", name)
+		}
+	}
+	return nil
+}
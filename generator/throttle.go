@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// throttle paces work to a rate per second. Costs larger than the burst are allowed
+// and paid back by waiting, so a single large file never blocks forever.
+type throttle struct {
+	rate   float64
+	burst  float64
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newThrottle creates a throttle for rate units per second; a zero rate returns nil,
+// which never waits
+func newThrottle(rate float64) *throttle {
+	if rate <= 0 {
+		return nil
+	}
+	burst := math.Max(rate, 1)
+	return &throttle{rate: rate, burst: burst, tokens: burst, now: time.Now}
+}
+
+// Wait takes cost units and blocks until the bucket is no longer in debt
+func (t *throttle) Wait(ctx context.Context, cost float64) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	now := t.now()
+	if !t.last.IsZero() {
+		t.tokens = math.Min(t.burst, t.tokens+now.Sub(t.last).Seconds()*t.rate)
+	}
+	t.last = now
+	t.tokens -= cost
+	debt := -t.tokens
+	t.mu.Unlock()
+
+	if debt <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(debt / t.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
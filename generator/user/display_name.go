@@ -1,10 +1,12 @@
 package user
 
 import (
+	"math/rand"
+
 	"github.com/songvi/robo/generator/file"
 	"github.com/songvi/robo/models"
 )
 
-func GenerateDisplayName(strategy models.UserStrategy) string {
-	return file.GenerateFilename(strategy.UserLang)
+func GenerateDisplayName(rng *rand.Rand, strategy models.UserStrategy) string {
+	return file.GenerateFilename(rng, strategy.UserLang)
 }
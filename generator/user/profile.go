@@ -0,0 +1,250 @@
+package user
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/songvi/robo/generator/file"
+)
+
+// locale holds the country-specific formats used to enrich a user profile
+type locale struct {
+	tld         string   // Country code top-level domain for default email domains
+	country     string   // Country name in the local language
+	phone       string   // Phone number layout; '#' is replaced by a random digit
+	postalCode  string   // Postal code layout; '#' is replaced by a random digit
+	cities      []string // Cities in the local script
+	streetTypes []string
+	// address lays out the parts in the local order
+	address func(number, street, streetType, city, postal, country string) string
+	titles  []string // Job titles in the local language
+}
+
+// westernAddress formats "12 Main Street, City 12345, Country"
+func westernAddress(number, street, streetType, city, postal, country string) string {
+	return fmt.Sprintf("%s %s %s, %s %s, %s", number, street, streetType, city, postal, country)
+}
+
+// continentalAddress formats "Street Type 12, 12345 City, Country"
+func continentalAddress(number, street, streetType, city, postal, country string) string {
+	return fmt.Sprintf("%s %s %s, %s %s, %s", streetType, street, number, postal, city, country)
+}
+
+// eastAsianAddress formats "Country postal City Street 12", largest unit first
+func eastAsianAddress(number, street, streetType, city, postal, country string) string {
+	return fmt.Sprintf("%s %s %s %s%s %s", country, postal, city, street, streetType, number)
+}
+
+// locales maps the generator's language codes to their country formats
+var locales = map[string]locale{
+	"en": {
+		tld: "com", country: "United States", phone: "+1 (###) ###-####", postalCode: "#####",
+		cities:      []string{"Springfield", "Riverside", "Franklin", "Greenville", "Fairview", "Madison"},
+		streetTypes: []string{"Street", "Avenue", "Road", "Lane", "Boulevard"},
+		address:     westernAddress,
+		titles:      []string{"Software Engineer", "Product Manager", "Sales Director", "Accountant", "HR Specialist", "Data Analyst", "Legal Counsel", "Support Agent"},
+	},
+	"vi": {
+		tld: "vn", country: "Việt Nam", phone: "+84 9# ### ####", postalCode: "######",
+		cities:      []string{"Hà Nội", "Hồ Chí Minh", "Đà Nẵng", "Huế", "Cần Thơ", "Hải Phòng"},
+		streetTypes: []string{"Đường", "Phố"},
+		address: func(number, street, streetType, city, postal, country string) string {
+			return fmt.Sprintf("Số %s %s %s, %s %s, %s", number, streetType, street, city, postal, country)
+		},
+		titles: []string{"Kỹ sư phần mềm", "Quản lý sản phẩm", "Giám đốc kinh doanh", "Kế toán", "Chuyên viên nhân sự", "Nhà phân tích dữ liệu"},
+	},
+	"ge": {
+		tld: "de", country: "Deutschland", phone: "+49 15# ########", postalCode: "#####",
+		cities:      []string{"Berlin", "München", "Hamburg", "Köln", "Frankfurt", "Leipzig"},
+		streetTypes: []string{"Straße", "Weg", "Allee", "Platz"},
+		address: func(number, street, streetType, city, postal, country string) string {
+			return fmt.Sprintf("%s %s %s, %s %s, %s", street, streetType, number, postal, city, country)
+		},
+		titles: []string{"Softwareentwickler", "Produktmanager", "Vertriebsleiter", "Buchhalter", "Personalreferent", "Datenanalyst"},
+	},
+	"fr": {
+		tld: "fr", country: "France", phone: "+33 6 ## ## ## ##", postalCode: "#####",
+		cities:      []string{"Paris", "Lyon", "Marseille", "Toulouse", "Nantes", "Lille"},
+		streetTypes: []string{"rue", "avenue", "boulevard", "place"},
+		address: func(number, street, streetType, city, postal, country string) string {
+			return fmt.Sprintf("%s %s %s, %s %s, %s", number, streetType, street, postal, city, country)
+		},
+		titles: []string{"Ingénieur logiciel", "Chef de produit", "Directeur commercial", "Comptable", "Chargé RH", "Analyste de données"},
+	},
+	"es": {
+		tld: "es", country: "España", phone: "+34 6## ### ###", postalCode: "#####",
+		cities:      []string{"Madrid", "Barcelona", "Valencia", "Sevilla", "Bilbao", "Málaga"},
+		streetTypes: []string{"Calle", "Avenida", "Paseo", "Plaza"},
+		address:     continentalAddress,
+		titles:      []string{"Ingeniero de software", "Jefe de producto", "Director comercial", "Contable", "Técnico de RR. HH.", "Analista de datos"},
+	},
+	"pt": {
+		tld: "pt", country: "Portugal", phone: "+351 9## ### ###", postalCode: "####-###",
+		cities:      []string{"Lisboa", "Porto", "Braga", "Coimbra", "Faro", "Aveiro"},
+		streetTypes: []string{"Rua", "Avenida", "Travessa", "Praça"},
+		address:     continentalAddress,
+		titles:      []string{"Engenheiro de software", "Gestor de produto", "Diretor comercial", "Contabilista", "Técnico de RH", "Analista de dados"},
+	},
+	"ru": {
+		tld: "ru", country: "Россия", phone: "+7 9## ###-##-##", postalCode: "######",
+		cities:      []string{"Москва", "Санкт-Петербург", "Казань", "Новосибирск", "Екатеринбург", "Самара"},
+		streetTypes: []string{"ул.", "пр-т", "пер."},
+		address: func(number, street, streetType, city, postal, country string) string {
+			return fmt.Sprintf("%s %s, д. %s, %s, %s, %s", streetType, street, number, city, country, postal)
+		},
+		titles: []string{"Инженер-программист", "Менеджер продукта", "Директор по продажам", "Бухгалтер", "HR-специалист", "Аналитик данных"},
+	},
+	"hi": {
+		tld: "in", country: "भारत", phone: "+91 9#### #####", postalCode: "######",
+		cities:      []string{"दिल्ली", "मुंबई", "बेंगलुरु", "जयपुर", "लखनऊ", "पुणे"},
+		streetTypes: []string{"मार्ग", "रोड", "नगर"},
+		address:     westernAddress,
+		titles:      []string{"सॉफ्टवेयर इंजीनियर", "प्रोडक्ट मैनेजर", "बिक्री निदेशक", "लेखाकार", "एचआर विशेषज्ञ", "डेटा विश्लेषक"},
+	},
+	"he": {
+		tld: "il", country: "ישראל", phone: "+972 5#-###-####", postalCode: "#######",
+		cities:      []string{"תל אביב", "ירושלים", "חיפה", "באר שבע", "נתניה", "אילת"},
+		streetTypes: []string{"רחוב", "שדרות"},
+		address:     continentalAddress,
+		titles:      []string{"מהנדס תוכנה", "מנהל מוצר", "מנהל מכירות", "רואה חשבון", "מומחה משאבי אנוש", "אנליסט נתונים"},
+	},
+	"th": {
+		tld: "th", country: "ประเทศไทย", phone: "+66 8# ### ####", postalCode: "#####",
+		cities:      []string{"กรุงเทพมหานคร", "เชียงใหม่", "ภูเก็ต", "ขอนแก่น", "หาดใหญ่", "พัทยา"},
+		streetTypes: []string{"ถนน", "ซอย"},
+		address: func(number, street, streetType, city, postal, country string) string {
+			return fmt.Sprintf("%s %s%s %s %s %s", number, streetType, street, city, postal, country)
+		},
+		titles: []string{"วิศวกรซอฟต์แวร์", "ผู้จัดการผลิตภัณฑ์", "ผู้อำนวยการฝ่ายขาย", "นักบัญชี", "เจ้าหน้าที่ฝ่ายบุคคล", "นักวิเคราะห์ข้อมูล"},
+	},
+	"tl": {
+		tld: "ph", country: "Pilipinas", phone: "+63 9## ### ####", postalCode: "####",
+		cities:      []string{"Maynila", "Lungsod Quezon", "Cebu", "Davao", "Iloilo", "Baguio"},
+		streetTypes: []string{"Kalye", "Abenida"},
+		address:     westernAddress,
+		titles:      []string{"Inhinyero ng software", "Tagapamahala ng produkto", "Direktor ng benta", "Accountant", "Espesyalista sa HR", "Analista ng datos"},
+	},
+	"cn": {
+		tld: "cn", country: "中国", phone: "+86 13# #### ####", postalCode: "######",
+		cities:      []string{"北京市", "上海市", "广州市", "深圳市", "成都市", "杭州市"},
+		streetTypes: []string{"路", "街", "大道"},
+		address:     eastAsianAddress,
+		titles:      []string{"软件工程师", "产品经理", "销售总监", "会计", "人力资源专员", "数据分析师"},
+	},
+	"jp": {
+		tld: "jp", country: "日本", phone: "+81 90-####-####", postalCode: "〒###-####",
+		cities:      []string{"東京都", "大阪市", "京都市", "札幌市", "福岡市", "名古屋市"},
+		streetTypes: []string{"町", "通り"},
+		address:     eastAsianAddress,
+		titles:      []string{"ソフトウェアエンジニア", "プロダクトマネージャー", "営業部長", "会計士", "人事担当", "データアナリスト"},
+	},
+	"kn": {
+		tld: "kr", country: "대한민국", phone: "+82 10-####-####", postalCode: "#####",
+		cities:      []string{"서울특별시", "부산광역시", "인천광역시", "대구광역시", "광주광역시", "대전광역시"},
+		streetTypes: []string{"로", "길"},
+		address:     eastAsianAddress,
+		titles:      []string{"소프트웨어 엔지니어", "제품 관리자", "영업 이사", "회계사", "인사 담당자", "데이터 분석가"},
+	},
+	"ar": {
+		tld: "sa", country: "المملكة العربية السعودية", phone: "+966 5# ### ####", postalCode: "#####",
+		cities:      []string{"الرياض", "جدة", "مكة", "المدينة", "الدمام", "الطائف"},
+		streetTypes: []string{"شارع", "طريق"},
+		address:     continentalAddress,
+		titles:      []string{"مهندس برمجيات", "مدير منتج", "مدير مبيعات", "محاسب", "أخصائي موارد بشرية", "محلل بيانات"},
+	},
+}
+
+// localeFor returns the locale of lang, defaulting to English
+func localeFor(lang string) locale {
+	if l, ok := locales[lang]; ok {
+		return l
+	}
+	return locales["en"]
+}
+
+// fillDigits replaces every '#' in layout with a random digit
+func fillDigits(rng *rand.Rand, layout string) string {
+	var b strings.Builder
+	for _, r := range layout {
+		if r == '#' {
+			b.WriteByte(byte('0' + rng.Intn(10)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// GenerateEmail builds an address from the display name when it is written in ASCII
+// letters, falling back to the username for other scripts
+func GenerateEmail(rng *rand.Rand, lang, displayName, username string, domains []string) string {
+	local := strings.ToLower(username)
+	words := strings.Fields(displayName)
+	ascii := len(words) > 0
+	for _, r := range displayName {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || r == ' ') {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		local = strings.ToLower(strings.Join(words, "."))
+		// Disambiguate common names the way directories do
+		if rng.Intn(2) == 0 {
+			local += fmt.Sprintf("%d", rng.Intn(100))
+		}
+	}
+
+	domain := "example." + localeFor(lang).tld
+	if len(domains) > 0 {
+		domain = domains[rng.Intn(len(domains))]
+	}
+	return local + "@" + domain
+}
+
+// GeneratePhone generates a phone number in the international format of lang's country
+func GeneratePhone(rng *rand.Rand, lang string) string {
+	return fillDigits(rng, localeFor(lang).phone)
+}
+
+// GenerateAddress generates a postal address laid out the way lang's country writes it
+func GenerateAddress(rng *rand.Rand, lang string) string {
+	l := localeFor(lang)
+	number := fmt.Sprintf("%d", 1+rng.Intn(250))
+	street := file.GenerateFolderName(rng, lang)
+	streetType := l.streetTypes[rng.Intn(len(l.streetTypes))]
+	city := l.cities[rng.Intn(len(l.cities))]
+	return l.address(number, street, streetType, city, fillDigits(rng, l.postalCode), l.country)
+}
+
+// GenerateJobTitle picks a job title in lang
+func GenerateJobTitle(rng *rand.Rand, lang string) string {
+	titles := localeFor(lang).titles
+	return titles[rng.Intn(len(titles))]
+}
+
+// defaultAvatarSize is the avatar edge length in pixels when none is configured
+const defaultAvatarSize = 128
+
+// WriteAvatar renders an avatar for username into dir and returns its path
+func WriteAvatar(rng *rand.Rand, dir, username string, size int) (string, error) {
+	if size <= 0 {
+		size = defaultAvatarSize
+	}
+	data, err := file.GenerateAvatar(rng, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to render avatar: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create avatar directory: %v", err)
+	}
+	path := filepath.Join(dir, username+".png")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write avatar: %v", err)
+	}
+	return path, nil
+}
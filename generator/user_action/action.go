@@ -5,6 +5,10 @@ type UserAction struct {
 	Description string `json:"description" yaml:"description"`
 	// The action type
 	ActionType string `json:"action_type" yaml:"action_type"`
+	// Position of the action in its session, starting at 0
+	Sequence int `json:"sequence" yaml:"sequence"`
+	// ThinkTimeMs is how long the user pauses before performing the action
+	ThinkTimeMs int64 `json:"think_time_ms" yaml:"think_time_ms"`
 
 	CycleID   string `json:"cycle_id" yaml:"cycle_id"`
 	SessionID string `json:"session_id" yaml:"session_id"`
@@ -0,0 +1,122 @@
+package useraction
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/songvi/robo/models"
+)
+
+// ValidateThinkTime checks that the parameters of dist are usable
+func ValidateThinkTime(dist *models.ThinkTimeDistribution) error {
+	switch dist.Type {
+	case "constant", "exponential", "lognormal":
+		if dist.MeanMs < 0 || dist.Sigma < 0 {
+			return fmt.Errorf("invalid %s think time: mean_ms and sigma must not be negative", dist.Type)
+		}
+	case "uniform":
+		if dist.MaxMs <= 0 {
+			return fmt.Errorf("invalid uniform think time: max_ms must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported think time distribution: %s", dist.Type)
+	}
+	if dist.MinMs < 0 || dist.MaxMs > 0 && dist.MaxMs < dist.MinMs {
+		return fmt.Errorf("invalid think time: bounds must satisfy 0 <= min_ms <= max_ms")
+	}
+	return nil
+}
+
+// sampleThinkTime draws a pause in milliseconds from dist, clamped to its bounds
+func sampleThinkTime(rng *rand.Rand, dist *models.ThinkTimeDistribution) int64 {
+	if dist == nil {
+		return 0
+	}
+	var ms float64
+	switch dist.Type {
+	case "constant":
+		ms = float64(dist.MeanMs)
+	case "uniform":
+		ms = float64(dist.MinMs) + rng.Float64()*float64(dist.MaxMs-dist.MinMs)
+	case "exponential":
+		ms = rng.ExpFloat64() * float64(dist.MeanMs)
+	case "lognormal":
+		ms = math.Exp(math.Log(math.Max(1, float64(dist.MeanMs))) + dist.Sigma*rng.NormFloat64())
+	}
+	result := int64(math.Max(ms, float64(dist.MinMs)))
+	if dist.MaxMs > 0 && result > dist.MaxMs {
+		result = dist.MaxMs
+	}
+	return result
+}
+
+// pickAction selects an action type according to its weight
+func pickAction(rng *rand.Rand, types []string, weights []float64) string {
+	r := rng.Float64()
+	sum := 0.0
+	for i, w := range weights {
+		sum += w
+		if r <= sum {
+			return types[i]
+		}
+	}
+	return types[len(types)-1]
+}
+
+// ForPersona returns strategy with the action weights and session length of persona
+// applied; strategy is returned unchanged for users without a known persona
+func ForPersona(strategy models.ActionStrategy, persona string) models.ActionStrategy {
+	profile, ok := strategy.Personas[persona]
+	if !ok {
+		return strategy
+	}
+	if len(profile.ActionWeights) > 0 {
+		strategy.ActionWeights = profile.ActionWeights
+	}
+	if profile.MaxActions > 0 {
+		strategy.MinActions, strategy.MaxActions = profile.MinActions, profile.MaxActions
+	}
+	return strategy
+}
+
+// GenerateSession produces the ordered actions of one session: the optional start
+// action, MinActions to MaxActions weighted actions and the optional end action, each
+// preceded by a think time. The first action of a session never waits.
+func GenerateSession(rng *rand.Rand, strategy models.ActionStrategy, sessionID string) ([]UserAction, error) {
+	if len(strategy.ActionTypes) == 0 || len(strategy.ActionTypes) != len(strategy.ActionWeights) {
+		return nil, fmt.Errorf("invalid ActionStrategy: action_types and action_weights must be non-empty and of equal length")
+	}
+	if strategy.MinActions < 0 || strategy.MaxActions < strategy.MinActions {
+		return nil, fmt.Errorf("invalid ActionStrategy: actions bounds must satisfy 0 <= min_actions <= max_actions")
+	}
+
+	var types []string
+	if strategy.StartAction != "" {
+		types = append(types, strategy.StartAction)
+	}
+	n := strategy.MinActions + rng.Intn(strategy.MaxActions-strategy.MinActions+1)
+	for i := 0; i < n; i++ {
+		types = append(types, pickAction(rng, strategy.ActionTypes, strategy.ActionWeights))
+	}
+	if strategy.EndAction != "" {
+		types = append(types, strategy.EndAction)
+	}
+
+	actions := make([]UserAction, len(types))
+	for i, actionType := range types {
+		var thinkTime int64
+		if i > 0 {
+			thinkTime = sampleThinkTime(rng, strategy.ThinkTime)
+		}
+		actions[i] = UserAction{
+			Name:        actionType,
+			Description: fmt.Sprintf("Step %d of session %s", i+1, sessionID),
+			ActionType:  actionType,
+			Sequence:    i,
+			ThinkTimeMs: thinkTime,
+			SessionID:   sessionID,
+		}
+	}
+	return actions, nil
+}
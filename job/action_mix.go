@@ -0,0 +1,91 @@
+package job
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/songvi/robo/models"
+)
+
+// validateActionMix checks the action mix of a strategy
+func validateActionMix(mix []models.ActionMix) error {
+	seen := make(map[string]bool, len(mix))
+	for i, m := range mix {
+		if m.Action == "" {
+			return fmt.Errorf("action %d has no name", i)
+		}
+		if seen[m.Action] {
+			return fmt.Errorf("action %s is listed twice", m.Action)
+		}
+		seen[m.Action] = true
+		if m.Weight < 0 || m.Count < 0 || math.IsNaN(m.Weight) || math.IsInf(m.Weight, 0) {
+			return fmt.Errorf("action %s: weight and count must not be negative", m.Action)
+		}
+		if (m.Weight > 0) == (m.Count > 0) {
+			return fmt.Errorf("action %s: set either a weight or a count", m.Action)
+		}
+	}
+	return nil
+}
+
+// actionQuotas returns how many jobs of each action of mix a session gets: Count jobs
+// for counted actions, and total jobs shared by weight, rounded by largest remainder
+// so they add up to total exactly
+func actionQuotas(mix []models.ActionMix, total int) []int {
+	quotas := make([]int, len(mix))
+	weights := 0.0
+	for i, m := range mix {
+		quotas[i] = m.Count
+		weights += m.Weight
+	}
+	if weights == 0 || total <= 0 {
+		return quotas
+	}
+
+	remainders := make([]int, 0, len(mix))
+	assigned := 0
+	for i, m := range mix {
+		if m.Weight == 0 {
+			continue
+		}
+		share := float64(total) * m.Weight / weights
+		quotas[i] = int(share)
+		assigned += quotas[i]
+		remainders = append(remainders, i)
+	}
+	sort.SliceStable(remainders, func(a, b int) bool {
+		ra := float64(total)*mix[remainders[a]].Weight/weights - float64(quotas[remainders[a]])
+		rb := float64(total)*mix[remainders[b]].Weight/weights - float64(quotas[remainders[b]])
+		return ra > rb
+	})
+	for i := 0; assigned < total; i++ {
+		quotas[remainders[i%len(remainders)]]++
+		assigned++
+	}
+	return quotas
+}
+
+// mixActions returns the action of every job of a session in order, interleaving the
+// actions by smooth weighted round-robin so each is spread evenly over the session
+func mixActions(mix []models.ActionMix, total int) []string {
+	quotas := actionQuotas(mix, total)
+	n := 0
+	for _, q := range quotas {
+		n += q
+	}
+	actions := make([]string, 0, n)
+	current := make([]int, len(quotas))
+	for len(actions) < n {
+		best := -1
+		for i, q := range quotas {
+			current[i] += q
+			if best < 0 || current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= n
+		actions = append(actions, mix[best].Action)
+	}
+	return actions
+}
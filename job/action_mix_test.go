@@ -0,0 +1,35 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestMixActions(t *testing.T) {
+	mix := []models.ActionMix{
+		{Action: "upload_file", Weight: 70},
+		{Action: "consult_file", Weight: 20},
+		{Action: "delete_file", Weight: 10},
+		{Action: "create_workspace", Count: 1},
+	}
+	assert.NoError(t, validateActionMix(mix))
+	assert.Equal(t, []int{7, 2, 1, 1}, actionQuotas(mix, 10))
+	assert.Equal(t, []int{2, 1, 0, 1}, actionQuotas(mix, 3), "largest remainders get the rounding")
+
+	actions := mixActions(mix, 10)
+	counts := make(map[string]int)
+	for _, a := range actions {
+		counts[a]++
+	}
+	assert.Equal(t, map[string]int{"upload_file": 7, "consult_file": 2, "delete_file": 1, "create_workspace": 1}, counts)
+	assert.Equal(t, "upload_file", actions[0])
+	assert.NotEqual(t, actions[0], actions[1], "actions are interleaved")
+
+	assert.Error(t, validateActionMix([]models.ActionMix{{Action: "upload_file"}}))
+	assert.Error(t, validateActionMix([]models.ActionMix{{Action: "upload_file", Weight: 1, Count: 2}}))
+	assert.Error(t, validateActionMix([]models.ActionMix{{Action: "a", Weight: 1}, {Action: "a", Weight: 2}}))
+	assert.Error(t, validateActionMix([]models.ActionMix{{Action: "a", Weight: -1}}))
+}
@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+type batchStore struct {
+	store.Store
+	pending []models.Job
+	writes  int
+}
+
+func (s *batchStore) GetJobsByStatus(ctx context.Context, status string, jobs *[]models.Job) error {
+	*jobs = s.pending
+	return nil
+}
+
+func (s *batchStore) GetCycle(ctx context.Context, id string) (*models.Cycle, error) {
+	return &models.Cycle{UUID: id, Status: "running", Phase: "load", Strategy: &models.Strategy{BatchSize: 2}}, nil
+}
+
+func (s *batchStore) ListCyclesByStatus(ctx context.Context, statuses ...string) ([]models.Cycle, error) {
+	return nil, nil
+}
+
+func (s *batchStore) UpdateJob(ctx context.Context, job *models.Job) error {
+	s.writes++
+	return nil
+}
+
+func (s *batchStore) UpdateJobs(ctx context.Context, jobs []models.Job) error {
+	s.writes++
+	return nil
+}
+
+func (s *batchStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	return &models.Job{UUID: id, Status: "completed"}, nil
+}
+
+type batchDispatcher struct {
+	dispatcher.Dispatcher
+	sent [][]string
+}
+
+func (d *batchDispatcher) DispatchJobToPool(ctx context.Context, job *models.Job, labels map[string]string) error {
+	d.sent = append(d.sent, []string{job.UUID})
+	return nil
+}
+
+func (d *batchDispatcher) DispatchBatchToPool(ctx context.Context, jobs []models.Job, labels map[string]string) error {
+	var uuids []string
+	for _, job := range jobs {
+		uuids = append(uuids, job.UUID)
+	}
+	d.sent = append(d.sent, uuids)
+	return nil
+}
+
+func TestDispatchPendingBatches(t *testing.T) {
+	st := &batchStore{pending: []models.Job{
+		{UUID: "a1", CycleUUID: "c1", SessionID: "s1"},
+		{UUID: "b1", CycleUUID: "c1", SessionID: "s2"},
+		{UUID: "a2", CycleUUID: "c1", SessionID: "s1"},
+		{UUID: "a3", CycleUUID: "c1", SessionID: "s1"},
+		{UUID: "a4", CycleUUID: "c1", SessionID: "s1", DependsOn: "a3"},
+	}}
+	d := &batchDispatcher{}
+	s := &jobServiceImpl{clock: NewSystemClock(), store: st, dispatcher: d, logger: logger.NewSlogLogger()}
+
+	s.dispatchPending(context.Background())
+	assert.Equal(t, [][]string{{"a1", "a2"}, {"a4"}, {"a3"}, {"b1"}}, d.sent,
+		"full batches go at once, dependent jobs alone and the rest at the end")
+	assert.Equal(t, 4, st.writes, "a batch is marked dispatched in one write")
+}
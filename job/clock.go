@@ -0,0 +1,46 @@
+package job
+
+import "time"
+
+// Clock tells the time and the JobService waits on it; fx provides the system clock,
+// which tests replace to drive dispatching, pacing and schedules deterministically
+type Clock interface {
+	Now() time.Time
+	// NewTicker sends the time on its channel every d
+	NewTicker(d time.Duration) Ticker
+	// NewTimer sends the time on its channel once d passed
+	NewTimer(d time.Duration) Timer
+}
+
+// Ticker is a time.Ticker of a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is a time.Timer of a Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// NewSystemClock returns the Clock of the time package
+func NewSystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
@@ -0,0 +1,153 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+// fakeClock only moves when Advance is called
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a ticker when period is set and a timer otherwise
+type fakeWaiter struct {
+	clock  *fakeClock
+	c      chan time.Time
+	at     time.Time
+	period time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) wait(d, period time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d), period: period}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker { return fakeTicker{c.wait(d, d)} }
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer { return c.wait(d, 0) }
+
+// Advance moves the clock by d and fires the tickers and timers due; like those of the
+// time package, they drop ticks their reader is not ready for
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		for !w.at.After(c.now) {
+			select {
+			case w.c <- w.at:
+			default:
+			}
+			if w.period == 0 {
+				break
+			}
+			w.at = w.at.Add(w.period)
+		}
+		if w.period > 0 || w.at.After(c.now) {
+			waiters = append(waiters, w)
+		}
+	}
+	c.waiters = waiters
+}
+
+// Waiters counts the tickers and timers not stopped nor fired
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (w *fakeWaiter) C() <-chan time.Time { return w.c }
+
+func (w *fakeWaiter) Stop() bool {
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTicker struct{ *fakeWaiter }
+
+func (t fakeTicker) Stop() { t.fakeWaiter.Stop() }
+
+// clockDispatcher signals every batch it dispatches
+type clockDispatcher struct {
+	batchDispatcher
+	results chan *nats.Msg
+	batches chan []models.Job
+}
+
+func (d *clockDispatcher) Subscribe(ctx context.Context, subject string) (<-chan *nats.Msg, error) {
+	return d.results, nil
+}
+
+func (d *clockDispatcher) DispatchBatchToPool(ctx context.Context, jobs []models.Job, labels map[string]string) error {
+	d.batches <- jobs
+	return nil
+}
+
+func TestProcessJobsOnClock(t *testing.T) {
+	st := &batchStore{pending: []models.Job{
+		{UUID: "a1", CycleUUID: "c1", SessionID: "s1"},
+		{UUID: "a2", CycleUUID: "c1", SessionID: "s1"},
+	}}
+	d := &clockDispatcher{results: make(chan *nats.Msg), batches: make(chan []models.Job, 1)}
+	clock := newFakeClock()
+	s := &jobServiceImpl{clock: clock, store: st, dispatcher: d, logger: logger.NewSlogLogger()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.ProcessJobs(ctx) }()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+
+	clock.Advance(dispatchInterval - time.Second)
+	select {
+	case <-d.batches:
+		t.Fatal("pending jobs were dispatched before the dispatch interval")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	select {
+	case batch := <-d.batches:
+		assert.Len(t, batch, 2)
+	case <-time.After(time.Second):
+		t.Fatal("pending jobs were not dispatched once the dispatch interval passed")
+	}
+
+	cancel()
+	close(d.results)
+	require.NoError(t, <-done)
+	assert.Zero(t, clock.Waiters(), "the ticker is stopped")
+}
@@ -0,0 +1,136 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// Defaults of the error budget settings left unset
+const (
+	defaultBudgetWindow     = 60 * time.Second
+	defaultBudgetSustain    = 30 * time.Second
+	defaultBudgetMinSamples = 20
+)
+
+// validateErrorBudget checks the error budget of a strategy
+func validateErrorBudget(budget *models.ErrorBudget) error {
+	if budget.MaxErrorRate <= 0 || budget.MaxErrorRate > 1 {
+		return fmt.Errorf("max_error_rate %v is outside (0, 1]", budget.MaxErrorRate)
+	}
+	if budget.WindowSeconds < 0 || budget.SustainSeconds < 0 || budget.MinSamples < 0 {
+		return fmt.Errorf("window, sustain and sample count must not be negative")
+	}
+	return nil
+}
+
+// budgetBucket counts the results of one second
+type budgetBucket struct {
+	second        int64
+	total, failed int
+}
+
+// cycleBudget is the rolling window of results of one cycle
+type cycleBudget struct {
+	buckets       []budgetBucket // Oldest first
+	exceededSince time.Time      // Zero while the rate is within budget
+}
+
+// budgetTracker keeps the recent job results of running cycles to evaluate their error
+// budget. Like SLO samples, they are kept in memory only.
+type budgetTracker struct {
+	mu     sync.Mutex
+	cycles map[string]*cycleBudget
+}
+
+func newBudgetTracker() *budgetTracker {
+	return &budgetTracker{cycles: make(map[string]*cycleBudget)}
+}
+
+// record adds the result of a finished job at now and returns the error rate of the
+// window, and whether it exceeded the budget for the sustained window
+func (t *budgetTracker) record(job *models.Job, budget *models.ErrorBudget, now time.Time) (float64, bool) {
+	window, sustain, minSamples := defaultBudgetWindow, defaultBudgetSustain, defaultBudgetMinSamples
+	if budget.WindowSeconds > 0 {
+		window = time.Duration(budget.WindowSeconds) * time.Second
+	}
+	if budget.SustainSeconds > 0 {
+		sustain = time.Duration(budget.SustainSeconds) * time.Second
+	}
+	if budget.MinSamples > 0 {
+		minSamples = budget.MinSamples
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.cycles[job.CycleUUID]
+	if !ok {
+		c = &cycleBudget{}
+		t.cycles[job.CycleUUID] = c
+	}
+	second := now.Unix()
+	if n := len(c.buckets); n == 0 || c.buckets[n-1].second != second {
+		c.buckets = append(c.buckets, budgetBucket{second: second})
+	}
+	last := &c.buckets[len(c.buckets)-1]
+	last.total++
+	if job.Status == "failed" {
+		last.failed++
+	}
+
+	// Drop the seconds that left the window
+	oldest := now.Add(-window).Unix()
+	i := 0
+	for i < len(c.buckets) && c.buckets[i].second <= oldest {
+		i++
+	}
+	c.buckets = c.buckets[i:]
+
+	total, failed := 0, 0
+	for _, b := range c.buckets {
+		total += b.total
+		failed += b.failed
+	}
+	rate := float64(failed) / float64(total)
+	if total < minSamples || rate <= budget.MaxErrorRate {
+		c.exceededSince = time.Time{}
+		return rate, false
+	}
+	if c.exceededSince.IsZero() {
+		c.exceededSince = now
+	}
+	return rate, now.Sub(c.exceededSince) >= sustain
+}
+
+// forget drops the results of a cycle that is over
+func (t *budgetTracker) forget(cycleUUID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.cycles, cycleUUID)
+}
+
+// evaluateErrorBudget records the result of a finished job against the error budget of
+// its cycle and aborts the cycle once the budget is exhausted
+func (s *jobServiceImpl) evaluateErrorBudget(ctx context.Context, job *models.Job) error {
+	if job.Status != "completed" && job.Status != "failed" {
+		return nil
+	}
+	cycle, err := s.store.GetCycle(ctx, job.CycleUUID)
+	if err != nil {
+		return err
+	}
+	if cycle.Strategy == nil || cycle.Strategy.ErrorBudget == nil || cycle.Status != "running" {
+		return nil
+	}
+	rate, exhausted := s.budgets.record(job, cycle.Strategy.ErrorBudget, s.clock.Now())
+	if !exhausted {
+		return nil
+	}
+	s.logger.Error(ctx, "Cycle error budget exhausted, aborting", "cycle_uuid", cycle.UUID,
+		"error_rate", rate, "max_error_rate", cycle.Strategy.ErrorBudget.MaxErrorRate)
+	return s.stopCycle(ctx, cycle.UUID, "failed_errors",
+		fmt.Sprintf("error rate %.4g exhausted the error budget of %.4g", rate, cycle.Strategy.ErrorBudget.MaxErrorRate))
+}
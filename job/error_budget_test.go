@@ -0,0 +1,43 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestBudgetTracker(t *testing.T) {
+	budget := &models.ErrorBudget{MaxErrorRate: 0.5, WindowSeconds: 10, SustainSeconds: 5, MinSamples: 4}
+	require.NoError(t, validateErrorBudget(budget))
+	assert.Error(t, validateErrorBudget(&models.ErrorBudget{}))
+	tracker := newBudgetTracker()
+	start := time.Unix(1000, 0)
+	record := func(status string, after time.Duration) (float64, bool) {
+		return tracker.record(&models.Job{CycleUUID: "c1", Status: status}, budget, start.Add(after))
+	}
+
+	_, exhausted := record("failed", 0)
+	assert.False(t, exhausted, "too few samples")
+	record("failed", 0)
+	record("failed", 0)
+	rate, exhausted := record("failed", time.Second)
+	assert.Equal(t, 1.0, rate)
+	assert.False(t, exhausted, "the rate exceeds the budget from now on")
+	_, exhausted = record("failed", 3*time.Second)
+	assert.False(t, exhausted)
+	_, exhausted = record("failed", 6*time.Second)
+	assert.True(t, exhausted, "the rate exceeded the budget for the sustain window")
+
+	// A recovered target resets the sustain window, and old failures leave the window
+	for i := 0; i < 8; i++ {
+		record("completed", 7*time.Second)
+	}
+	_, exhausted = record("failed", 8*time.Second)
+	assert.False(t, exhausted)
+	rate, _ = record("completed", 20*time.Second)
+	assert.Equal(t, 0.0, rate, "results older than the window are dropped")
+}
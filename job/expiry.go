@@ -0,0 +1,97 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// defaultDrain is how long an expired cycle lets its jobs in flight finish when the
+// strategy leaves DrainSeconds unset
+const defaultDrain = 60 * time.Second
+
+// validateExpiry checks the duration settings of strategy
+func validateExpiry(strategy *models.Strategy) error {
+	if strategy.CycleDuration < 0 || strategy.DrainSeconds < 0 {
+		return fmt.Errorf("cycle_duration and drain_seconds must not be negative")
+	}
+	switch strategy.OnExpiry {
+	case "", "cancel", "drain":
+		return nil
+	}
+	return fmt.Errorf("unknown on_expiry policy %q", strategy.OnExpiry)
+}
+
+// expireCycles stops the cycles that ran out of their duration, and closes the expired
+// cycles whose jobs in flight had their time to drain
+func (s *jobServiceImpl) expireCycles(ctx context.Context) {
+	cycles, err := s.store.ListCyclesByStatus(ctx, "running", "paused", "expired")
+	if err != nil {
+		s.logger.Error(ctx, "Failed to list cycles to expire", "error", err)
+		return
+	}
+	now := s.clock.Now()
+	for i := range cycles {
+		cycle := &cycles[i]
+		if cycle.Strategy == nil || cycle.Strategy.CycleDuration <= 0 || cycle.DoneAt != 0 {
+			continue
+		}
+		deadline := time.Unix(cycle.StartedAt, 0).Add(time.Duration(cycle.Strategy.CycleDuration) * time.Second)
+		drain := defaultDrain
+		if cycle.Strategy.DrainSeconds > 0 {
+			drain = time.Duration(cycle.Strategy.DrainSeconds) * time.Second
+		}
+		switch {
+		case now.Before(deadline):
+		case cycle.Status != "expired":
+			if err := s.expireCycle(ctx, cycle); err != nil {
+				s.logger.Error(ctx, "Failed to expire cycle", "cycle_uuid", cycle.UUID, "error", err)
+			}
+		case !now.Before(deadline.Add(drain)):
+			if err := s.finishDrain(ctx, cycle, true); err != nil {
+				s.logger.Error(ctx, "Failed to close drained cycle", "cycle_uuid", cycle.UUID, "error", err)
+			}
+		}
+	}
+}
+
+// expireCycle stops a cycle that ran out of its duration. Under the drain policy the
+// jobs in flight may still finish: only the pending ones and open sessions are
+// cancelled, and the cycle closes once its last result arrived.
+func (s *jobServiceImpl) expireCycle(ctx context.Context, cycle *models.Cycle) error {
+	reason := fmt.Sprintf("ran out of its cycle duration of %ds", cycle.Strategy.CycleDuration)
+	s.logger.Info(ctx, "Cycle expired", "cycle_uuid", cycle.UUID, "on_expiry", cycle.Strategy.OnExpiry)
+	if cycle.Strategy.OnExpiry != "drain" {
+		return s.stopCycle(ctx, cycle.UUID, "expired", reason)
+	}
+	cycle, err := s.setCycleStatus(ctx, cycle.UUID, "expired", "running", "paused")
+	if err != nil {
+		return err
+	}
+	cycle.Reason = reason
+	// Nothing is dispatched for an expired cycle anymore
+	if _, err := s.store.UpdateCycleJobsStatus(ctx, cycle.UUID, []string{"pending"}, "cancelled"); err != nil {
+		return err
+	}
+	if _, err := s.store.UpdateCycleSessionsStatus(ctx, cycle.UUID, []string{"planned", "active"}, "cancelled"); err != nil {
+		return err
+	}
+	return s.finishDrain(ctx, cycle, false)
+}
+
+// finishDrain records the progress of an expired cycle draining its jobs in flight and
+// closes it once none remain, or at once with force, cancelling those left
+func (s *jobServiceImpl) finishDrain(ctx context.Context, cycle *models.Cycle, force bool) error {
+	progress, err := s.cycleProgress(ctx, cycle)
+	if err != nil {
+		return err
+	}
+	cycle.Progress = progress
+	if progress.Remaining() > 0 && !force {
+		return s.store.UpdateCycleColumns(ctx, cycle, store.CycleProgressColumns...)
+	}
+	return s.cancelCycle(ctx, cycle)
+}
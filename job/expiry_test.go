@@ -0,0 +1,108 @@
+package job
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+type expiryStore struct {
+	*liveStore
+}
+
+func (s expiryStore) ListCyclesByStatus(ctx context.Context, statuses ...string) ([]models.Cycle, error) {
+	if slices.Contains(statuses, s.cycle.Status) {
+		return []models.Cycle{s.cycle}, nil
+	}
+	return nil, nil
+}
+
+func (s expiryStore) UpdateCycleJobsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error) {
+	var n int64
+	for _, job := range s.jobs {
+		if slices.Contains(from, job.Status) {
+			job.Status = to
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s expiryStore) UpdateCycleSessionsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error) {
+	if slices.Contains(from, s.session.Status) {
+		s.session.Status = to
+		return 1, nil
+	}
+	return 0, nil
+}
+
+type expiryDispatcher struct {
+	dispatcher.Dispatcher
+	cancelled int
+}
+
+func (d *expiryDispatcher) CancelCycle(ctx context.Context, cycleUUID string) error {
+	d.cancelled++
+	return nil
+}
+
+func TestExpireCycles(t *testing.T) {
+	require.Error(t, validateExpiry(&models.Strategy{OnExpiry: "wait"}))
+
+	for _, policy := range []string{"cancel", "drain"} {
+		t.Run(policy, func(t *testing.T) {
+			clock := newFakeClock()
+			st := expiryStore{&liveStore{
+				cycle: models.Cycle{UUID: "c1", Status: "running", StartedAt: clock.Now().Unix(),
+					Strategy: &models.Strategy{CycleDuration: 60, OnExpiry: policy, DrainSeconds: 30}},
+				session: models.Session{UUID: "s1", CycleUUID: "c1", Status: "active"},
+				jobs: map[string]*models.Job{
+					"j1": {UUID: "j1", Status: "completed"},
+					"j2": {UUID: "j2", Status: "processing"},
+					"j3": {UUID: "j3", Status: "pending"},
+				},
+			}}
+			d := &expiryDispatcher{}
+			s := &jobServiceImpl{clock: clock, store: st, dispatcher: d, logger: logger.NewSlogLogger(),
+				slos: newSLOTracker(), budgets: newBudgetTracker(), ledger: nopLedger{}, notifier: nopNotifier{}}
+			ctx := context.Background()
+
+			clock.Advance(59 * time.Second)
+			s.expireCycles(ctx)
+			assert.Equal(t, "running", st.cycle.Status)
+
+			clock.Advance(time.Second)
+			s.expireCycles(ctx)
+			assert.Equal(t, "expired", st.cycle.Status)
+			assert.Equal(t, "ran out of its cycle duration of 60s", st.cycle.Reason)
+			assert.Equal(t, "cancelled", st.jobs["j3"].Status)
+			assert.Equal(t, "cancelled", st.session.Status)
+			if policy == "cancel" {
+				assert.Equal(t, "cancelled", st.jobs["j2"].Status)
+				assert.Equal(t, 1, d.cancelled)
+				assert.Equal(t, clock.Now().Unix(), st.cycle.DoneAt)
+				return
+			}
+
+			assert.Equal(t, "processing", st.jobs["j2"].Status, "jobs in flight drain")
+			assert.Zero(t, st.cycle.DoneAt)
+			clock.Advance(10 * time.Second)
+			s.expireCycles(ctx)
+			assert.Zero(t, st.cycle.DoneAt, "the drain lasts drain_seconds")
+
+			st.jobs["j2"].Status = "completed"
+			require.NoError(t, s.checkCycleCompletion(ctx, "c1"))
+			assert.Equal(t, "expired", st.cycle.Status)
+			assert.Equal(t, clock.Now().Unix(), st.cycle.DoneAt, "the cycle closes with its last result")
+			assert.Equal(t, 2, st.cycle.Progress.CompletedJobs)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// sessionRunners are the schedulers of the live sessions in progress, woken when a
+// result of their session arrives
+type sessionRunners struct {
+	mu   sync.Mutex
+	wake map[string]chan struct{} // Session UUID -> wake-up signal of its scheduler
+}
+
+func newSessionRunners() *sessionRunners {
+	return &sessionRunners{wake: make(map[string]chan struct{})}
+}
+
+// add registers the scheduler of a session; it returns false when one already runs
+func (r *sessionRunners) add(sessionID string) (<-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.wake[sessionID]; ok {
+		return nil, false
+	}
+	wake := make(chan struct{}, 1)
+	r.wake[sessionID] = wake
+	return wake, true
+}
+
+func (r *sessionRunners) remove(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.wake, sessionID)
+}
+
+// notify wakes the scheduler of a session, if it has one
+func (r *sessionRunners) notify(sessionID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case r.wake[sessionID] <- struct{}{}:
+	default:
+	}
+}
+
+// sessionJob returns the login or logout job of a session, carrying its credentials
+func sessionJob(ctx context.Context, session models.Session, user models.User, action string) models.Job {
+	inputJSON, _ := json.Marshal(map[string]interface{}{
+		"user_id":     session.UserName,
+		"session_id":  session.UUID,
+		"action":      action,
+		"credentials": map[string]string{"username": user.UserName, "password": user.Password},
+	})
+	return models.Job{
+		UUID:      newID(ctx),
+		Name:      action,
+		InputData: json.RawMessage(inputJSON),
+		Status:    "pending",
+	}
+}
+
+// sessionPlan turns the planned jobs of a live session into its steps, with the think
+// time of the generated actions
+func sessionPlan(jobs []models.Job) []models.SessionStep {
+	plan := make([]models.SessionStep, len(jobs))
+	for i, job := range jobs {
+		var input struct {
+			ThinkTimeMs int64 `json:"think_time_ms"`
+		}
+		json.Unmarshal(job.InputData, &input)
+		plan[i] = models.SessionStep{
+			JobUUID:     job.UUID,
+			Action:      job.Name,
+			Input:       job.InputData,
+			ThinkTimeMs: input.ThinkTimeMs,
+		}
+	}
+	return plan
+}
+
+// sessionState is what the scheduler of a live session does after a step
+type sessionState int
+
+const (
+	sessionWait sessionState = iota // Until a result arrives or the next poll
+	sessionNext                     // Take the next step at once
+	sessionOver                     // Stop: the session or its cycle is over
+)
+
+// runSession schedules the steps of a live session until it is over or ctx is done.
+// Results wake the scheduler up; it also polls every dispatchInterval so pauses, aborts
+// and lost results do not stall it.
+func (s *jobServiceImpl) runSession(ctx context.Context, sessionID string) {
+	wake, ok := s.runners.add(sessionID)
+	if !ok {
+		return
+	}
+	defer s.runners.remove(sessionID)
+	ticker := s.clock.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	thought := -1 // Step whose think time is over
+	for ctx.Err() == nil {
+		state, err := s.stepSession(ctx, sessionID, &thought)
+		if err != nil {
+			s.logger.Error(ctx, "Failed to schedule session step", "session_id", sessionID, "error", err)
+		}
+		switch state {
+		case sessionOver:
+			return
+		case sessionNext:
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-ticker.C():
+		}
+	}
+}
+
+// stepSession moves a live session along: once the job of its current step finished and
+// the think time of the next step is over, it creates the job of the next step and
+// dispatches it, unless the cycle is paced. It ends the session after its last step.
+func (s *jobServiceImpl) stepSession(ctx context.Context, sessionID string, thought *int) (sessionState, error) {
+	session, err := s.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return sessionWait, err
+	}
+	if session.Status == "ended" || session.Status == "cancelled" {
+		return sessionOver, nil
+	}
+	var last *models.Job
+	if session.CurrentJob != "" {
+		if last, err = s.store.GetJob(ctx, session.CurrentJob); err != nil {
+			return sessionWait, err
+		}
+		if last.Status != "completed" && last.Status != "failed" && last.Status != "cancelled" {
+			return sessionWait, nil
+		}
+		if session.StartedAt == 0 {
+			session.StartedAt = last.StartAt
+		}
+	}
+
+	if session.Step >= len(session.Plan) {
+		session.Status = "ended"
+		session.EndedAt = s.clock.Now().Unix()
+		if last != nil && last.DoneAt > 0 {
+			session.EndedAt = last.DoneAt
+		}
+		if err := s.store.UpdateSession(ctx, session); err != nil {
+			return sessionWait, err
+		}
+		return sessionOver, s.checkCycleCompletion(ctx, session.CycleUUID)
+	}
+
+	cycle, err := s.store.GetCycle(ctx, session.CycleUUID)
+	if err != nil {
+		return sessionWait, err
+	}
+	switch {
+	case cycle.Status == "paused", cycle.Phase == "warmup":
+		return sessionWait, nil
+	case cycle.Status != "running":
+		return sessionOver, nil
+	}
+
+	step := session.Plan[session.Step]
+	if *thought != session.Step && step.ThinkTimeMs > 0 {
+		timer := s.clock.NewTimer(time.Duration(step.ThinkTimeMs) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return sessionOver, nil
+		case <-timer.C():
+		}
+		// The cycle may have changed in the meantime
+		*thought = session.Step
+		return sessionNext, nil
+	}
+
+	job := models.Job{
+		UUID:      step.JobUUID,
+		Name:      step.Action,
+		InputData: step.Input,
+		Status:    "pending",
+		CycleUUID: session.CycleUUID,
+		SessionID: session.UUID,
+		Target:    session.Target,
+	}
+	// Paced cycles leave the job to their pacer; otherwise it is saved as dispatched so
+	// dispatchPending does not pick it up as well
+	direct := cycle.Strategy == nil || !cycle.Strategy.Paced()
+	if direct {
+		job.Status = "dispatched"
+	}
+	if err := s.store.CreateJob(ctx, &job); err != nil {
+		return sessionWait, err
+	}
+	session.Status = "active"
+	session.Step++
+	session.CurrentJob = job.UUID
+	if err := s.store.UpdateSession(ctx, session); err != nil {
+		return sessionWait, err
+	}
+	if direct {
+		if err := s.dispatcher.DispatchJobToPool(ctx, &job, poolLabels(cycle.Strategy, job.Target)); err != nil {
+			// Back in line for dispatchPending
+			job.Status = "pending"
+			if updateErr := s.store.UpdateJob(ctx, &job); updateErr != nil {
+				s.logger.Error(ctx, "Failed to update job status", "job_uuid", job.UUID, "error", updateErr)
+			}
+			return sessionWait, err
+		}
+	}
+	return sessionWait, nil
+}
+
+// startSessions runs the scheduler of every live session of a cycle that is not over
+func (s *jobServiceImpl) startSessions(ctx context.Context, cycleUUID string) error {
+	sessions, err := s.store.GetCycleSessionsByStatus(ctx, cycleUUID, "planned", "active")
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		go s.runSession(s.ctx, session.UUID)
+	}
+	return nil
+}
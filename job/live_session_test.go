@@ -0,0 +1,141 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/ledger"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/notify"
+	"github.com/songvi/robo/store"
+)
+
+type liveStore struct {
+	store.Store
+	cycle   models.Cycle
+	session models.Session
+	jobs    map[string]*models.Job
+}
+
+func (s *liveStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	session := s.session
+	return &session, nil
+}
+
+func (s *liveStore) UpdateSession(ctx context.Context, session *models.Session) error {
+	s.session = *session
+	return nil
+}
+
+func (s *liveStore) GetCycleSessionsByStatus(ctx context.Context, cycleUUID string, statuses ...string) ([]models.Session, error) {
+	for _, status := range statuses {
+		if s.session.Status == status {
+			return []models.Session{s.session}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *liveStore) GetCycle(ctx context.Context, id string) (*models.Cycle, error) {
+	cycle := s.cycle
+	return &cycle, nil
+}
+
+func (s *liveStore) UpdateCycleColumns(ctx context.Context, cycle *models.Cycle, columns ...string) error {
+	s.cycle = *cycle
+	return nil
+}
+
+func (s *liveStore) UpdateCycleStatus(ctx context.Context, cycle *models.Cycle, from []string, columns ...string) error {
+	if !slices.Contains(from, s.cycle.Status) {
+		return store.ErrConflict
+	}
+	s.cycle = *cycle
+	return nil
+}
+
+func (s *liveStore) CreateJob(ctx context.Context, job *models.Job) error {
+	j := *job
+	s.jobs[job.UUID] = &j
+	return nil
+}
+
+func (s *liveStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	j := *s.jobs[id]
+	return &j, nil
+}
+
+func (s *liveStore) CountJobsByStatus(ctx context.Context, cycleUUID string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, job := range s.jobs {
+		counts[job.Status]++
+	}
+	return counts, nil
+}
+
+type liveDispatcher struct {
+	dispatcher.Dispatcher
+	dispatched []string
+}
+
+func (d *liveDispatcher) DispatchJobToPool(ctx context.Context, job *models.Job, labels map[string]string) error {
+	d.dispatched = append(d.dispatched, job.Name)
+	return nil
+}
+
+type nopLedger struct{ ledger.Ledger }
+
+func (nopLedger) Append(ctx context.Context, record ledger.Record) error { return nil }
+
+type nopNotifier struct{ notify.Notifier }
+
+func (nopNotifier) Notify(ctx context.Context, event notify.Event) {}
+
+func TestStepSession(t *testing.T) {
+	plan := sessionPlan([]models.Job{
+		{UUID: "j1", Name: "login", InputData: json.RawMessage(`{}`)},
+		{UUID: "j2", Name: "upload_file", InputData: json.RawMessage(`{"think_time_ms":1}`)},
+	})
+	assert.Equal(t, int64(1), plan[1].ThinkTimeMs)
+
+	st := &liveStore{
+		cycle:   models.Cycle{UUID: "c1", Status: "running", Phase: "load", Strategy: &models.Strategy{LiveSessions: true}},
+		session: models.Session{UUID: "s1", CycleUUID: "c1", Status: "planned", Plan: plan},
+		jobs:    make(map[string]*models.Job),
+	}
+	d := &liveDispatcher{}
+	s := &jobServiceImpl{clock: NewSystemClock(), store: st, dispatcher: d, logger: logger.NewSlogLogger(), slos: newSLOTracker(), budgets: newBudgetTracker(), ledger: nopLedger{}, notifier: nopNotifier{}}
+	ctx := context.Background()
+	thought := -1
+	step := func() sessionState {
+		state, err := s.stepSession(ctx, "s1", &thought)
+		require.NoError(t, err)
+		return state
+	}
+
+	assert.Equal(t, sessionWait, step())
+	assert.Equal(t, []string{"login"}, d.dispatched)
+	assert.Equal(t, "dispatched", st.jobs["j1"].Status)
+	assert.Equal(t, "active", st.session.Status)
+	assert.Equal(t, sessionWait, step(), "the next job waits for the result of the login")
+	assert.Len(t, st.jobs, 1)
+
+	st.jobs["j1"].Status = "completed"
+	assert.Equal(t, sessionNext, step(), "the think time of the upload passes first")
+	assert.Equal(t, sessionWait, step())
+	assert.Equal(t, []string{"login", "upload_file"}, d.dispatched)
+
+	st.jobs["j2"].Status = "completed"
+	require.NoError(t, s.checkCycleCompletion(ctx, "c1"))
+	assert.Equal(t, "running", st.cycle.Status, "the cycle waits for its live sessions")
+	assert.Equal(t, sessionOver, step())
+	assert.Equal(t, "ended", st.session.Status)
+	assert.Equal(t, "completed", st.cycle.Status)
+}
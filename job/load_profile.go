@@ -0,0 +1,162 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// loadTarget is what a load profile allows at one point of a cycle
+type loadTarget struct {
+	concurrency      int
+	rate             float64
+	limitConcurrency bool
+	limitRate        bool
+}
+
+// validateLoadProfile checks the stages of profile
+func validateLoadProfile(profile *models.LoadProfile) error {
+	if len(profile.Stages) == 0 {
+		return fmt.Errorf("load profile has no stages")
+	}
+	for i, stage := range profile.Stages {
+		if stage.DurationSeconds <= 0 {
+			return fmt.Errorf("load stage %d (%s): duration must be positive", i, stage.Name)
+		}
+		if stage.Concurrency < 0 || stage.Rate < 0 {
+			return fmt.Errorf("load stage %d (%s): negative target", i, stage.Name)
+		}
+	}
+	if target, _ := profileTarget(profile, 0); !target.limitConcurrency && !target.limitRate {
+		return fmt.Errorf("load profile sets neither concurrency nor rate")
+	}
+	return nil
+}
+
+// profileTarget returns the target of profile after elapsed running time, or false once
+// its last stage is over
+func profileTarget(profile *models.LoadProfile, elapsed time.Duration) (loadTarget, bool) {
+	var target loadTarget
+	for _, stage := range profile.Stages {
+		target.limitConcurrency = target.limitConcurrency || stage.Concurrency > 0
+		target.limitRate = target.limitRate || stage.Rate > 0
+	}
+
+	var prevConcurrency, prevRate float64
+	for _, stage := range profile.Stages {
+		duration := time.Duration(stage.DurationSeconds) * time.Second
+		if elapsed < duration {
+			concurrency, rate := float64(stage.Concurrency), stage.Rate
+			if stage.Ramp {
+				f := float64(elapsed) / float64(duration)
+				concurrency = prevConcurrency + (concurrency-prevConcurrency)*f
+				rate = prevRate + (rate-prevRate)*f
+			}
+			target.concurrency = int(math.Round(concurrency))
+			target.rate = rate
+			return target, true
+		}
+		elapsed -= duration
+		prevConcurrency, prevRate = float64(stage.Concurrency), stage.Rate
+	}
+	return target, false
+}
+
+// cycleTarget returns what strategy allows after elapsed running time: the target of
+// its load profile, capped by MaxRate and MaxInFlight
+func cycleTarget(strategy *models.Strategy, elapsed time.Duration) (loadTarget, bool) {
+	var target loadTarget
+	ok := false
+	if strategy.LoadProfile != nil {
+		if target, ok = profileTarget(strategy.LoadProfile, elapsed); !ok {
+			target = loadTarget{}
+		}
+	}
+	if strategy.MaxRate > 0 {
+		if !target.limitRate || target.rate > strategy.MaxRate {
+			target.rate = strategy.MaxRate
+		}
+		target.limitRate = true
+	}
+	if strategy.MaxInFlight > 0 {
+		if !target.limitConcurrency || target.concurrency > strategy.MaxInFlight {
+			target.concurrency = strategy.MaxInFlight
+		}
+		target.limitConcurrency = true
+	}
+	return target, ok || strategy.MaxRate > 0 || strategy.MaxInFlight > 0
+}
+
+// paceCycle dispatches the jobs of a paced cycle, every second as many as its load
+// profile and caps allow, until the cycle is over or ctx is done. The profile resumes
+// after elapsed running time; paused and warm-up time do not count toward it.
+func (s *jobServiceImpl) paceCycle(ctx context.Context, cycleUUID string, strategy *models.Strategy, elapsed time.Duration) {
+	ticker := s.clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var tokens float64 // Dispatches the rate allows so far
+	last := s.clock.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			dt := now.Sub(last)
+			last = now
+
+			cycle, err := s.store.GetCycle(ctx, cycleUUID)
+			if err != nil {
+				s.logger.Error(ctx, "Failed to fetch paced cycle", "cycle_uuid", cycleUUID, "error", err)
+				continue
+			}
+			switch cycle.Status {
+			case "running":
+			case "paused":
+				continue
+			default:
+				return
+			}
+			// The profile starts with the load phase
+			if cycle.Phase == "warmup" {
+				continue
+			}
+			elapsed += dt
+
+			allowed := math.MaxInt
+			if target, ok := cycleTarget(strategy, elapsed); ok {
+				if target.limitRate {
+					// Let at most a second's worth of dispatches build up
+					tokens = math.Min(tokens+target.rate*dt.Seconds(), math.Max(target.rate, 1))
+					allowed = int(tokens)
+				}
+				if target.limitConcurrency {
+					counts, err := s.store.CountJobsByStatus(ctx, cycleUUID)
+					if err != nil {
+						s.logger.Error(ctx, "Failed to count jobs of paced cycle", "cycle_uuid", cycleUUID, "error", err)
+						continue
+					}
+					allowed = min(allowed, target.concurrency-counts["dispatched"]-counts["processing"])
+				}
+			}
+			if allowed <= 0 {
+				continue
+			}
+
+			jobs, err := s.store.GetCycleJobsByStatus(ctx, cycleUUID, "pending")
+			if err != nil {
+				s.logger.Error(ctx, "Failed to fetch pending jobs of paced cycle", "cycle_uuid", cycleUUID, "error", err)
+				continue
+			}
+			dispatched := 0
+			for i := 0; i < len(jobs) && dispatched < allowed; i++ {
+				if s.dispatchJob(ctx, &jobs[i], strategy) {
+					dispatched++
+				}
+			}
+			tokens = math.Max(tokens-float64(dispatched), 0)
+		}
+	}
+}
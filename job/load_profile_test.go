@@ -0,0 +1,62 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestProfileTarget(t *testing.T) {
+	profile := &models.LoadProfile{Stages: []models.LoadStage{
+		{Name: "ramp-up", DurationSeconds: 100, Concurrency: 40, Ramp: true},
+		{Name: "steady", DurationSeconds: 200, Concurrency: 40},
+		{Name: "spike", DurationSeconds: 10, Concurrency: 200},
+		{Name: "ramp-down", DurationSeconds: 50, Concurrency: 0, Ramp: true},
+	}}
+	assert.NoError(t, validateLoadProfile(profile))
+
+	for _, tc := range []struct {
+		at   time.Duration
+		want int
+	}{
+		{0, 0},
+		{25 * time.Second, 10},
+		{150 * time.Second, 40},
+		{305 * time.Second, 200},
+		{335 * time.Second, 100},
+	} {
+		target, ok := profileTarget(profile, tc.at)
+		assert.True(t, ok, tc.at)
+		assert.True(t, target.limitConcurrency)
+		assert.False(t, target.limitRate)
+		assert.Equal(t, tc.want, target.concurrency, tc.at)
+	}
+	_, ok := profileTarget(profile, 360*time.Second)
+	assert.False(t, ok, "profile is over after its last stage")
+
+	assert.Error(t, validateLoadProfile(&models.LoadProfile{}))
+	assert.Error(t, validateLoadProfile(&models.LoadProfile{Stages: []models.LoadStage{{DurationSeconds: 10}}}))
+	assert.Error(t, validateLoadProfile(&models.LoadProfile{Stages: []models.LoadStage{{Rate: 5}}}))
+}
+
+func TestCycleTarget(t *testing.T) {
+	strategy := &models.Strategy{
+		LoadProfile: &models.LoadProfile{Stages: []models.LoadStage{{DurationSeconds: 60, Rate: 50, Concurrency: 8}}},
+		MaxRate:     20,
+	}
+	target, ok := cycleTarget(strategy, 10*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 20.0, target.rate, "the cap lowers the profile rate")
+	assert.Equal(t, 8, target.concurrency)
+
+	target, ok = cycleTarget(strategy, 90*time.Second)
+	assert.True(t, ok, "the cap outlives the profile")
+	assert.Equal(t, 20.0, target.rate)
+	assert.False(t, target.limitConcurrency)
+
+	_, ok = cycleTarget(&models.Strategy{LoadProfile: strategy.LoadProfile}, 90*time.Second)
+	assert.False(t, ok)
+}
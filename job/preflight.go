@@ -0,0 +1,97 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/songvi/robo/generator"
+	"github.com/songvi/robo/models"
+)
+
+// preflightTimeout bounds how long StartCycle waits for a worker's preflight report
+const preflightTimeout = 10 * time.Second
+
+// preflightSubject prefixes the subjects workers answer preflight requests on, one per
+// worker ID
+const preflightSubject = "dispatcher.worker.preflight"
+
+// buildPreflightRequest derives the distinct actions and the upload volume from the planned jobs
+func (s *jobServiceImpl) buildPreflightRequest(cycle models.Cycle, jobs []models.Job) models.PreflightRequest {
+	req := models.PreflightRequest{CycleUUID: cycle.UUID}
+	seen := make(map[string]bool)
+	uploads := 0
+	for _, job := range jobs {
+		if !seen[job.Name] {
+			seen[job.Name] = true
+			req.Actions = append(req.Actions, job.Name)
+		}
+		if job.Name == "upload_file" {
+			uploads++
+		}
+	}
+	if cycle.Strategy != nil && cycle.Strategy.Teardown {
+		for _, kind := range teardownKinds {
+			if seen[kind.create] && !seen[kind.delete] {
+				seen[kind.delete] = true
+				req.Actions = append(req.Actions, kind.delete)
+			}
+		}
+	}
+	req.RequiredBytes = int64(float64(uploads) * generator.MeanFileSize(s.fileStrategy))
+	if uploads > 0 {
+		req.MaxFileBytes = generator.MaxFileSize(s.fileStrategy)
+	}
+	return req
+}
+
+// preflight asks a worker of the pool of each target of the cycle to verify the target
+// against the jobs it would run there, before any job of the cycle is saved
+func (s *jobServiceImpl) preflight(ctx context.Context, cycle models.Cycle, jobs []models.Job) error {
+	if len(cycle.Strategy.Targets) == 0 {
+		return s.preflightPool(ctx, cycle.Strategy.WorkerLabels, s.buildPreflightRequest(cycle, jobs))
+	}
+	for _, target := range cycle.Strategy.Targets {
+		targetJobs := slices.DeleteFunc(slices.Clone(jobs), func(job models.Job) bool { return job.Target != target.Name })
+		req := s.buildPreflightRequest(cycle, targetJobs)
+		req.Target = target.Name
+		if err := s.preflightPool(ctx, poolLabels(cycle.Strategy, target.Name), req); err != nil {
+			return fmt.Errorf("target %s: %w", target.Name, err)
+		}
+	}
+	return nil
+}
+
+// preflightPool sends req to a worker of the pool of labels and checks its report
+func (s *jobServiceImpl) preflightPool(ctx context.Context, labels map[string]string, req models.PreflightRequest) error {
+	pool := s.workerPool(labels)
+	if len(pool) == 0 {
+		return fmt.Errorf("preflight failed: %s", strings.Join(poolProblems(pool, labels, nil), "; "))
+	}
+	worker := pool[0]
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal preflight request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	msg, err := s.dispatcher.Request(ctx, preflightSubject+"."+worker.UUID, data)
+	if err != nil {
+		return fmt.Errorf("preflight failed: worker %s did not answer: %w", worker.UUID, err)
+	}
+
+	var report models.PreflightReport
+	if err := json.Unmarshal(msg.Data, &report); err != nil {
+		return fmt.Errorf("preflight failed: invalid report: %w", err)
+	}
+
+	if failures := report.Failures(req); len(failures) > 0 {
+		return fmt.Errorf("preflight failed on worker %s: %s", report.WorkerID, strings.Join(failures, "; "))
+	}
+	s.logger.Info(ctx, "Preflight passed", "cycle_uuid", req.CycleUUID, "target", req.Target, "worker_id", report.WorkerID, "actions", req.Actions)
+	return nil
+}
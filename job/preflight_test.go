@@ -0,0 +1,76 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+// preflightDispatcher answers preflight requests with the report of the worker asked
+type preflightDispatcher struct {
+	poolDispatcher
+	reports  map[string]models.PreflightReport // Subject -> report
+	requests map[string]models.PreflightRequest
+}
+
+func (d *preflightDispatcher) Request(ctx context.Context, subject string, data []byte) (*nats.Msg, error) {
+	var req models.PreflightRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	d.requests[subject] = req
+	report, err := json.Marshal(d.reports[subject])
+	return &nats.Msg{Data: report}, err
+}
+
+func TestPreflight(t *testing.T) {
+	ok := func(worker string) models.PreflightReport {
+		return models.PreflightReport{WorkerID: worker, CredentialsOK: true, AvailableBytes: -1,
+			Permissions: map[string]bool{"upload_file": true}}
+	}
+	d := &preflightDispatcher{
+		poolDispatcher: poolDispatcher{workers: []models.Worker{
+			{UUID: "w-eu", Labels: map[string]string{"pool": "eu"}},
+			{UUID: "w-us", Labels: map[string]string{"pool": "us"}},
+		}},
+		reports: map[string]models.PreflightReport{
+			preflightSubject + ".w-eu": ok("w-eu"),
+			preflightSubject + ".w-us": ok("w-us"),
+		},
+		requests: make(map[string]models.PreflightRequest),
+	}
+	s := &jobServiceImpl{dispatcher: d, logger: logger.NewSlogLogger(),
+		fileStrategy: models.FileStrategy{FileSizeDistribution: &models.SizeDistribution{Type: "zipf", Scale: 1000, Exponent: 50, Max: 5000}}}
+	cycle := models.Cycle{UUID: "c1", Strategy: &models.Strategy{Targets: []models.Target{
+		{Name: "eu", Percent: 50, WorkerLabels: map[string]string{"pool": "eu"}},
+		{Name: "us", Percent: 50, WorkerLabels: map[string]string{"pool": "us"}},
+	}}}
+	jobs := []models.Job{
+		{Name: "upload_file", Target: "eu"},
+		{Name: "upload_file", Target: "eu"},
+		{Name: "upload_file", Target: "us"},
+	}
+
+	// Each target is checked by a worker of its own pool, for its own jobs
+	require.NoError(t, s.preflight(context.Background(), cycle, jobs))
+	eu, us := d.requests[preflightSubject+".w-eu"], d.requests[preflightSubject+".w-us"]
+	assert.Equal(t, "eu", eu.Target)
+	assert.InDelta(t, 2000, eu.RequiredBytes, 10, "sizes drawn from a distribution are counted")
+	assert.InDelta(t, 1000, us.RequiredBytes, 10)
+	assert.Equal(t, int64(5000), us.MaxFileBytes)
+
+	report := ok("w-us")
+	report.CredentialsOK = false
+	d.reports[preflightSubject+".w-us"] = report
+	assert.ErrorContains(t, s.preflight(context.Background(), cycle, jobs), "target us: preflight failed on worker w-us: target credentials were rejected")
+
+	d.workers = d.workers[:1]
+	assert.ErrorContains(t, s.preflight(context.Background(), cycle, jobs), "target us: preflight failed: no active worker carries the labels map[pool:us]")
+}
@@ -0,0 +1,170 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/aggregator"
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+type resultDispatcher struct {
+	dispatcher.Dispatcher
+	mu            sync.Mutex
+	subscriptions int
+	results       chan *nats.Msg
+}
+
+func (d *resultDispatcher) Subscribe(ctx context.Context, subject string) (<-chan *nats.Msg, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscriptions++
+	go func() {
+		<-ctx.Done()
+		close(d.results)
+	}()
+	return d.results, nil
+}
+
+type resultStore struct {
+	store.Store
+	mu      sync.Mutex
+	polls   int
+	saved   []string
+	actions []models.UserAction
+}
+
+func (s *resultStore) GetJobsByStatus(ctx context.Context, status string, jobs *[]models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.polls++
+	return nil
+}
+
+func (s *resultStore) UpdateJob(ctx context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, job.UUID)
+	return nil
+}
+
+func (s *resultStore) CreateUserActions(ctx context.Context, actions []models.UserAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = append(s.actions, actions...)
+	return nil
+}
+
+func (s *resultStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	return &models.Job{UUID: id, Status: "dispatched"}, nil
+}
+
+func (s *resultStore) ListCyclesByStatus(ctx context.Context, statuses ...string) ([]models.Cycle, error) {
+	return nil, nil
+}
+
+func (s *resultStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	return nil, errors.New("no session")
+}
+
+func (s *resultStore) GetCycle(ctx context.Context, id string) (*models.Cycle, error) {
+	return nil, errors.New("no cycle")
+}
+
+func TestProcessJobsSubscribesOnce(t *testing.T) {
+	defer func(interval time.Duration) { dispatchInterval = interval }(dispatchInterval)
+	dispatchInterval = 5 * time.Millisecond
+
+	d := &resultDispatcher{results: make(chan *nats.Msg, 4)}
+	st := &resultStore{}
+	s := &jobServiceImpl{clock: NewSystemClock(), store: st, dispatcher: d, logger: logger.NewSlogLogger(), results: aggregator.NewAggregator(), slos: newSLOTracker()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.ProcessJobs(ctx) }()
+
+	data, _ := json.Marshal(models.Job{UUID: "j1", Status: "completed"})
+	d.results <- &nats.Msg{Data: data}
+	require.Eventually(t, func() bool {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		return st.polls >= 3 && len(st.saved) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ProcessJobs did not stop")
+	}
+	assert.Equal(t, 1, d.subscriptions, "results are consumed on one subscription")
+	assert.Equal(t, []string{"j1"}, st.saved)
+	require.Len(t, st.actions, 1, "results are recorded in the audit trail of their session")
+	assert.Equal(t, "j1", st.actions[0].JobUUID)
+	assert.Equal(t, "completed", st.actions[0].Status)
+}
+
+// versionedStore rejects stale updates like the GORM store; raced jobs change once
+// between their read and their update
+type versionedStore struct {
+	store.Store
+	jobs  map[string]models.Job
+	raced map[string]bool
+	reads int
+}
+
+func (s *versionedStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	s.reads++
+	job := s.jobs[id]
+	if s.raced[id] {
+		delete(s.raced, id)
+		saved := s.jobs[id]
+		saved.Version++
+		s.jobs[id] = saved
+	}
+	return &job, nil
+}
+
+func (s *versionedStore) UpdateJob(ctx context.Context, job *models.Job) error {
+	if s.jobs[job.UUID].Version != job.Version {
+		return store.ErrConflict
+	}
+	job.Version++
+	s.jobs[job.UUID] = *job
+	return nil
+}
+
+func TestSaveResults(t *testing.T) {
+	st := &versionedStore{
+		jobs: map[string]models.Job{
+			"j1": {UUID: "j1", Status: "dispatched", Target: "blue", Version: 1},
+			"j2": {UUID: "j2", Status: "cancelled", Version: 2},
+		},
+		raced: map[string]bool{"j1": true},
+	}
+	s := &jobServiceImpl{clock: NewSystemClock(), store: st, logger: logger.NewSlogLogger()}
+
+	saved, err := s.saveResults(context.Background(), []models.Job{{UUID: "j1", Status: "completed", WorkerID: "w1", DurationMs: 12}})
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, 2, st.reads, "a conflicting save reads the job again")
+	assert.Equal(t, models.Job{UUID: "j1", Status: "completed", WorkerID: "w1", DurationMs: 12, Target: "blue", Version: 3}, st.jobs["j1"],
+		"the result is merged onto the saved job")
+
+	saved, err = s.saveResults(context.Background(), []models.Job{{UUID: "j2", Status: "completed"}})
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+	assert.Equal(t, "cancelled", st.jobs["j2"].Status, "results of cancelled jobs are dropped")
+}
@@ -0,0 +1,107 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/songvi/robo/store"
+)
+
+// recoverCycles picks up the cycles a previous run of the service left running, paused
+// or in teardown: their jobs in flight are requeued or expired per the recovery policy,
+// paced cycles get their pacer back, live sessions their scheduler and cycles with
+// nothing left are completed
+func (s *jobServiceImpl) recoverCycles(ctx context.Context) {
+	cycles, err := s.store.ListCyclesByStatus(ctx, "running", "paused")
+	if err != nil {
+		s.logger.Error(ctx, "Failed to list unfinished cycles", "error", err)
+		return
+	}
+	for i := range cycles {
+		cycle := &cycles[i]
+		if err := s.reconcileJobs(ctx, cycle.UUID); err != nil {
+			s.logger.Error(ctx, "Failed to reconcile jobs of unfinished cycle", "cycle_uuid", cycle.UUID, "error", err)
+			continue
+		}
+		if cycle.Strategy != nil && cycle.Strategy.Paced() {
+			// Paused time is not known anymore, so the profile resumes as if there was none
+			elapsed := time.Duration(0)
+			if cycle.LoadStartedAt > 0 {
+				elapsed = s.clock.Now().Sub(time.Unix(cycle.LoadStartedAt, 0))
+			}
+			go s.paceCycle(s.ctx, cycle.UUID, cycle.Strategy, elapsed)
+		}
+		if cycle.Strategy != nil && cycle.Strategy.LiveSessions {
+			if err := s.startSessions(ctx, cycle.UUID); err != nil {
+				s.logger.Error(ctx, "Failed to resume live sessions", "cycle_uuid", cycle.UUID, "error", err)
+			}
+		}
+		if err := s.checkCycleCompletion(ctx, cycle.UUID); err != nil {
+			s.logger.Error(ctx, "Failed to check cycle completion", "cycle_uuid", cycle.UUID, "error", err)
+		}
+		s.logger.Info(ctx, "Recovered unfinished cycle", "cycle_uuid", cycle.UUID, "status", cycle.Status)
+	}
+
+	// Cycles over but not cleaned up yet go on with their teardown
+	cycles, err = s.store.ListCyclesByPhase(ctx, "teardown")
+	if err != nil {
+		s.logger.Error(ctx, "Failed to list cycles in teardown", "error", err)
+		return
+	}
+	for _, cycle := range cycles {
+		if cycle.Status == "running" || cycle.Status == "paused" {
+			continue
+		}
+		if err := s.reconcileJobs(ctx, cycle.UUID); err != nil {
+			s.logger.Error(ctx, "Failed to reconcile teardown jobs of cycle", "cycle_uuid", cycle.UUID, "error", err)
+			continue
+		}
+		if err := s.finishTeardown(ctx, cycle.UUID); err != nil {
+			s.logger.Error(ctx, "Failed to check cycle teardown", "cycle_uuid", cycle.UUID, "error", err)
+		}
+		s.logger.Info(ctx, "Recovered cycle teardown", "cycle_uuid", cycle.UUID, "status", cycle.Status)
+	}
+}
+
+// reconcileJobs settles the jobs a cycle had dispatched before the restart. Workers
+// first drop them, so requeued jobs do not run twice.
+func (s *jobServiceImpl) reconcileJobs(ctx context.Context, cycleUUID string) error {
+	if err := s.dispatcher.CancelCycle(ctx, cycleUUID); err != nil {
+		return err
+	}
+	inFlight := []string{"dispatched", "processing"}
+
+	if s.config.Recovery != "expire" {
+		requeued, err := s.store.UpdateCycleJobsStatus(ctx, cycleUUID, inFlight, "pending")
+		if err != nil {
+			return err
+		}
+		s.logger.Info(ctx, "Requeued jobs left in flight", "cycle_uuid", cycleUUID, "jobs", requeued)
+		return nil
+	}
+
+	now := s.clock.Now().Unix()
+	jobs, err := s.store.GetJobsByCycle(ctx, cycleUUID, inFlight...)
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		job.Status = "failed"
+		job.Error = "expired: the job service restarted while it was in flight"
+		job.DoneAt = now
+		if err := s.store.UpdateJob(ctx, job); err != nil {
+			// Its result arrived in the meantime
+			if errors.Is(err, store.ErrConflict) {
+				continue
+			}
+			return err
+		}
+		if err := s.trackSession(ctx, job); err != nil {
+			s.logger.Error(ctx, "Failed to update session", "session_id", job.SessionID, "error", err)
+		}
+	}
+	s.logger.Info(ctx, "Expired jobs left in flight", "cycle_uuid", cycleUUID, "jobs", len(jobs))
+	return nil
+}
@@ -0,0 +1,146 @@
+package job
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// recoveryStore is an expiryStore whose raced jobs get their result while they expire
+type recoveryStore struct {
+	expiryStore
+	raced map[string]bool
+}
+
+func (s recoveryStore) ListCyclesByPhase(ctx context.Context, phase string) ([]models.Cycle, error) {
+	if s.cycle.Phase == phase {
+		return []models.Cycle{s.cycle}, nil
+	}
+	return nil, nil
+}
+
+func (s recoveryStore) GetJobsByCycle(ctx context.Context, cycleUUID string, statuses ...string) ([]models.Job, error) {
+	var jobs []models.Job
+	for _, job := range s.jobs {
+		if slices.Contains(statuses, job.Status) {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].UUID < jobs[j].UUID })
+	return jobs, nil
+}
+
+func (s recoveryStore) UpdateJob(ctx context.Context, job *models.Job) error {
+	if s.raced[job.UUID] {
+		s.jobs[job.UUID].Status = "completed"
+		return store.ErrConflict
+	}
+	j := *job
+	s.jobs[job.UUID] = &j
+	return nil
+}
+
+func (s recoveryStore) CountSessionJobsByStatus(ctx context.Context, sessionUUID string) (map[string]int, error) {
+	return s.CountJobsByStatus(ctx, s.cycle.UUID)
+}
+
+func (s recoveryStore) CountPhaseJobsByStatus(ctx context.Context, cycleUUID, phase string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, job := range s.jobs {
+		if job.Phase == phase {
+			counts[job.Status]++
+		}
+	}
+	return counts, nil
+}
+
+func TestRecoverCycles(t *testing.T) {
+	newService := func(cycle models.Cycle, jobs map[string]*models.Job, recovery string) (*jobServiceImpl, recoveryStore, *expiryDispatcher) {
+		st := recoveryStore{
+			expiryStore: expiryStore{&liveStore{
+				cycle:   cycle,
+				session: models.Session{UUID: "s1", CycleUUID: "c1", Status: "active"},
+				jobs:    jobs,
+			}},
+			raced: make(map[string]bool),
+		}
+		d := &expiryDispatcher{}
+		s := &jobServiceImpl{clock: newFakeClock(), store: st, dispatcher: d, logger: logger.NewSlogLogger(),
+			config: JobServiceConfig{Recovery: recovery}, ctx: context.Background(),
+			slos: newSLOTracker(), budgets: newBudgetTracker(), ledger: nopLedger{}, notifier: nopNotifier{}}
+		return s, st, d
+	}
+	inFlight := func() map[string]*models.Job {
+		return map[string]*models.Job{
+			"j1": {UUID: "j1", SessionID: "s1", Status: "completed"},
+			"j2": {UUID: "j2", SessionID: "s1", Status: "dispatched"},
+			"j3": {UUID: "j3", SessionID: "s1", Status: "processing"},
+		}
+	}
+	running := models.Cycle{UUID: "c1", Status: "running", Phase: "load", Strategy: &models.Strategy{}}
+
+	t.Run("requeue", func(t *testing.T) {
+		jobs := inFlight()
+		jobs["j4"] = &models.Job{UUID: "j4", SessionID: "s1", Status: "pending"}
+		s, st, d := newService(running, jobs, "")
+		s.recoverCycles(context.Background())
+
+		assert.Equal(t, 1, d.cancelled, "workers drop the jobs before they are requeued")
+		assert.Equal(t, "completed", st.jobs["j1"].Status)
+		assert.Equal(t, "pending", st.jobs["j2"].Status)
+		assert.Equal(t, "pending", st.jobs["j3"].Status)
+		assert.Equal(t, "running", st.cycle.Status)
+		assert.Equal(t, 3, st.cycle.Progress.Remaining(), "the progress is saved")
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		s, st, d := newService(running, inFlight(), "expire")
+		st.raced["j2"] = true
+		s.recoverCycles(context.Background())
+
+		assert.Equal(t, 1, d.cancelled)
+		assert.Equal(t, "completed", st.jobs["j2"].Status, "a result that raced the expiry is kept")
+		assert.Equal(t, "failed", st.jobs["j3"].Status)
+		assert.Equal(t, "expired: the job service restarted while it was in flight", st.jobs["j3"].Error)
+		assert.Equal(t, s.clock.Now().Unix(), st.jobs["j3"].DoneAt)
+		assert.Equal(t, "ended", st.session.Status, "the session of the expired jobs is tracked")
+		assert.Equal(t, "completed", st.cycle.Status, "a cycle with nothing left is completed")
+		assert.Equal(t, 2, st.cycle.Progress.CompletedJobs)
+		assert.Equal(t, 1, st.cycle.Progress.FailedJobs)
+	})
+
+	t.Run("teardown", func(t *testing.T) {
+		cycle := models.Cycle{UUID: "c1", Status: "completed", Phase: "teardown", Strategy: &models.Strategy{Teardown: true}}
+		jobs := map[string]*models.Job{
+			"j1": {UUID: "j1", SessionID: "s1", Status: "completed"},
+			"t1": {UUID: "t1", SessionID: "s1", Status: "completed", Phase: "teardown"},
+			"t2": {UUID: "t2", SessionID: "s1", Status: "processing", Phase: "teardown"},
+		}
+		s, st, d := newService(cycle, jobs, "")
+		s.recoverCycles(context.Background())
+		assert.Equal(t, 1, d.cancelled, "cycles in teardown are recovered too")
+		assert.Equal(t, "pending", st.jobs["t2"].Status)
+		assert.Equal(t, "teardown", st.cycle.Phase, "the teardown goes on")
+
+		st.jobs["t2"].Status = "completed"
+		s.recoverCycles(context.Background())
+		assert.Equal(t, "done", st.cycle.Phase, "a teardown with nothing left is finished")
+		assert.Equal(t, "completed", st.cycle.Status)
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		paused := running
+		paused.Status = "paused"
+		s, st, _ := newService(paused, inFlight(), "")
+		s.recoverCycles(context.Background())
+		assert.Equal(t, "pending", st.jobs["j3"].Status, "paused cycles are reconciled")
+		assert.Equal(t, "paused", st.cycle.Status)
+	})
+}
@@ -0,0 +1,196 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/songvi/robo/generator"
+	"github.com/songvi/robo/generator/file"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// sessionResources are the generated entities the jobs of one session operate on
+type sessionResources struct {
+	user       models.User
+	workspaces []models.Workspace
+	files      []models.File
+}
+
+// fileRef is how a job refers to a generated file
+type fileRef struct {
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	Extension   string `json:"extension"`
+	Size        int    `json:"size"`
+	Path        string `json:"path"`      // Where the generator staged the content
+	StoreKey    string `json:"store_key"` // Name of the content in the file store
+	WorkspaceID string `json:"workspace_id,omitempty"`
+}
+
+// newID returns the UUID of a row of the cycle of ctx. It is drawn from the seed of the
+// cycle but named within the cycle, so a replay of the seed makes the rows of the
+// original in the same order under UUIDs of its own.
+func newID(ctx context.Context) string {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(generator.IDReader(ctx), b); err != nil {
+		return uuid.New().String()
+	}
+	if cycleID := generator.CycleFromContext(ctx); cycleID != "" {
+		return uuid.NewSHA1(uuid.NameSpaceOID, append([]byte(cycleID+"/"), b...)).String()
+	}
+	return uuid.Must(uuid.NewRandomFromReader(bytes.NewReader(b))).String()
+}
+
+// newPassword returns the password of a generated user of the cycle of ctx, drawn from
+// its seed when it has one
+func newPassword(ctx context.Context) string {
+	b := make([]byte, 12)
+	if _, err := io.ReadFull(generator.IDReader(ctx), b); err != nil {
+		return uuid.New().String()
+	}
+	return hex.EncodeToString(b)
+}
+
+// planResources generates the workspaces and files of a cycle and shares them among
+// its sessions: a workspace goes to the session of one of its members when there is
+// one, and files go round-robin with a workspace of their session. Generation failures
+// are logged and leave the jobs without the resources; saveResources saves them.
+func (s *jobServiceImpl) planResources(ctx context.Context, cycle models.Cycle, sessions []models.Session, users []models.User) []sessionResources {
+	resources := make([]sessionResources, len(sessions))
+	bySessionUser := make(map[string]int, len(users))
+	for i := range sessions {
+		resources[i].user = users[i]
+		bySessionUser[users[i].UUID] = i
+	}
+	if len(sessions) == 0 {
+		return resources
+	}
+
+	if n := cycle.Strategy.MaxWorkspaces; n > 0 {
+		// Workspaces are made of the users of the cycle, which are not saved yet
+		workspaces, err := s.generator.GenerateWorkspaces(generator.WithUsers(ctx, users), n)
+		if err != nil {
+			s.logger.Error(ctx, "Failed to generate workspaces for cycle", "cycle_uuid", cycle.UUID, "error", err)
+		}
+		for i, ws := range workspaces {
+			owner := i % len(sessions)
+			for _, member := range ws.Users {
+				if j, ok := bySessionUser[member]; ok {
+					owner = j
+					break
+				}
+			}
+			ws.UUID = newID(ctx)
+			ws.CycleID, ws.SessionID = cycle.UUID, sessions[owner].UUID
+			resources[owner].workspaces = append(resources[owner].workspaces, ws)
+		}
+	}
+
+	if n := cycle.Strategy.MaxFiles; n > 0 {
+		files, err := s.generator.GenerateFiles(ctx, n)
+		if err != nil {
+			s.logger.Error(ctx, "Failed to generate files for cycle", "cycle_uuid", cycle.UUID, "error", err)
+		}
+		// Files of a session go to its workspaces in turn
+		owned := make([]int, len(sessions))
+		for i := range files {
+			owner := i % len(sessions)
+			files[i].UUID = newID(ctx)
+			files[i].CycleID, files[i].SessionID = cycle.UUID, sessions[owner].UUID
+			if workspaces := resources[owner].workspaces; len(workspaces) > 0 {
+				files[i].WorkspaceID = workspaces[owned[owner]%len(workspaces)].UUID
+			}
+			owned[owner]++
+		}
+		for i, f := range files {
+			resources[i%len(sessions)].files = append(resources[i%len(sessions)].files, f)
+		}
+	}
+	return resources
+}
+
+// saveResources saves the workspaces and files planResources shared among sessions
+func saveResources(ctx context.Context, st store.Store, resources []sessionResources) error {
+	var files []models.File
+	for _, r := range resources {
+		for i := range r.workspaces {
+			if err := st.CreateWorkspace(ctx, &r.workspaces[i]); err != nil {
+				return fmt.Errorf("failed to save workspace: %v", err)
+			}
+		}
+		files = append(files, r.files...)
+	}
+	if err := st.CreateFilesBatch(ctx, files); err != nil {
+		return fmt.Errorf("failed to save files: %v", err)
+	}
+	return nil
+}
+
+// attach adds the resources each job of the session operates on to its input data:
+// the credentials of the session user to every job, the user to create_user, a
+// workspace to workspace actions and a file to file actions. Uploads take the files of
+// the session in turn; other file actions work on a file uploaded before them.
+func (r *sessionResources) attach(jobs []models.Job) error {
+	credentials := map[string]string{"username": r.user.UserName, "password": r.user.Password}
+	uploaded := 0
+	nextWorkspace := 0
+	for i := range jobs {
+		var input map[string]interface{}
+		if err := json.Unmarshal(jobs[i].InputData, &input); err != nil {
+			return err
+		}
+		if input == nil {
+			input = make(map[string]interface{})
+		}
+		input["credentials"] = credentials
+
+		action := jobs[i].Name
+		switch {
+		case action == "create_user":
+			input["user"] = map[string]string{
+				"username":     r.user.UserName,
+				"display_name": r.user.DisplayName,
+				"email":        r.user.Email,
+				"language":     r.user.Language,
+			}
+		case strings.HasSuffix(action, "_workspace") && len(r.workspaces) > 0:
+			ws := r.workspaces[nextWorkspace%len(r.workspaces)]
+			nextWorkspace++
+			input["workspace"] = map[string]interface{}{"uuid": ws.UUID, "name": ws.Name, "users": ws.Users}
+		case strings.HasSuffix(action, "_file") && len(r.files) > 0:
+			var f models.File
+			if action == "upload_file" {
+				f = r.files[uploaded%len(r.files)]
+				uploaded++
+			} else {
+				// Work on the latest upload, or on the first file before any
+				f = r.files[max(uploaded-1, 0)%len(r.files)]
+			}
+			input["file"] = fileRef{
+				UUID:        f.UUID,
+				Name:        f.Name,
+				Extension:   f.FileExtension,
+				Size:        f.FileSize,
+				Path:        f.FileContent,
+				StoreKey:    file.StoreName(&f),
+				WorkspaceID: f.WorkspaceID,
+			}
+			input["file_path"] = f.FileContent
+		}
+
+		data, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		jobs[i].InputData = data
+	}
+	return nil
+}
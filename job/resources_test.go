@@ -0,0 +1,114 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/songvi/robo/generator"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+func TestAttachResources(t *testing.T) {
+	r := &sessionResources{
+		user:       models.User{UUID: "u1", UserName: "alice", Password: "secret"},
+		workspaces: []models.Workspace{{UUID: "w1", Name: "Projects"}},
+		files: []models.File{
+			{UUID: "f1", Name: "plan", FileExtension: "md", FileContent: "/data/cycles/c1/plan.md", CycleID: "c1"},
+			{UUID: "f2", Name: "budget", FileExtension: "xlsx", FileContent: "/data/cycles/c1/budget.xlsx", CycleID: "c1"},
+		},
+	}
+	var jobs []models.Job
+	for _, action := range []string{"create_user", "create_workspace", "consult_file", "upload_file", "upload_file", "download_file"} {
+		jobs = append(jobs, models.Job{Name: action, InputData: json.RawMessage(`{"action":"` + action + `"}`)})
+	}
+	require.NoError(t, r.attach(jobs))
+
+	input := func(i int) map[string]interface{} {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(jobs[i].InputData, &m))
+		return m
+	}
+	fileOf := func(i int) string { return input(i)["file"].(map[string]interface{})["uuid"].(string) }
+
+	assert.Equal(t, map[string]interface{}{"username": "alice", "password": "secret"}, input(0)["credentials"])
+	assert.Equal(t, "alice", input(0)["user"].(map[string]interface{})["username"])
+	assert.Equal(t, "w1", input(1)["workspace"].(map[string]interface{})["uuid"])
+	assert.Equal(t, "f1", fileOf(2), "file actions before any upload use the first file")
+	assert.Equal(t, "f1", fileOf(3))
+	assert.Equal(t, "/data/cycles/c1/plan.md", input(3)["file_path"])
+	assert.Equal(t, "cycles/c1/plan.md", input(3)["file"].(map[string]interface{})["store_key"])
+	assert.Equal(t, "f2", fileOf(4))
+	assert.Equal(t, "f2", fileOf(5), "downloads use the latest upload")
+	assert.Equal(t, "upload_file", input(3)["action"], "existing input is kept")
+}
+
+// replayGenerator generates the same users and files on every call, as the generator
+// does under the same seed
+type replayGenerator struct {
+	generator.Generator
+}
+
+func (replayGenerator) GenerateUsers(ctx context.Context, n int) ([]models.User, error) {
+	users := make([]models.User, n)
+	for i := range users {
+		users[i] = models.User{UserName: fmt.Sprintf("user%d", i), DisplayName: fmt.Sprintf("User %d", i), Language: "en"}
+	}
+	return users, nil
+}
+
+func (replayGenerator) GenerateFiles(ctx context.Context, n int) ([]models.File, error) {
+	files := make([]models.File, n)
+	for i := range files {
+		files[i] = models.File{Name: fmt.Sprintf("file%d", i), FileExtension: "txt"}
+	}
+	return files, nil
+}
+
+func TestReplaySeed(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "store.db")), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(db))
+	require.NoError(t, store.ScopeProjects(db))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	st := store.NewGORMStore(db)
+	s := &jobServiceImpl{clock: NewSystemClock(), store: st, generator: replayGenerator{}, logger: logger.NewSlogLogger(),
+		slos: newSLOTracker(), budgets: newBudgetTracker(), ledger: nopLedger{}, notifier: nopNotifier{}}
+	start := func(seed int64) (models.Cycle, []models.User) {
+		cycle, err := s.StartCycle(context.Background(), models.Cycle{Seed: seed, Strategy: &models.Strategy{
+			MaxUsers: 2, MaxFiles: 2, Actions: []models.ActionMix{{Action: "upload_file", Weight: 1}},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "running", cycle.Status)
+		var users []models.User
+		require.NoError(t, db.Where("cycle_id = ?", cycle.UUID).Order("username").Find(&users).Error)
+		require.Len(t, users, 2)
+		return cycle, users
+	}
+
+	original, originalUsers := start(42)
+	replay, replayUsers := start(42)
+	assert.Equal(t, original.Seed, replay.Seed)
+	for i := range replayUsers {
+		assert.Equal(t, originalUsers[i].UserName, replayUsers[i].UserName, "a replay generates the same users")
+		assert.Equal(t, originalUsers[i].Password, replayUsers[i].Password)
+		assert.NotEqual(t, originalUsers[i].UUID, replayUsers[i].UUID, "the rows of a replay are its own")
+	}
+	_, otherUsers := start(43)
+	assert.NotEqual(t, originalUsers[0].Password, otherUsers[0].Password)
+
+	assert.NotEqual(t, newID(context.Background()), newID(context.Background()), "unseeded IDs are random")
+}
@@ -0,0 +1,93 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// retentionBatch is the number of old cycles archived and deleted per query
+const retentionBatch = 20
+
+// runRetention archives and deletes the cycles older than the retention policy at
+// every interval until ctx is done
+func (s *jobServiceImpl) runRetention(ctx context.Context) {
+	policy := s.config.Retention
+	interval := time.Duration(policy.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	s.logger.Info(ctx, "Cycle retention armed", "max_age_days", policy.MaxAgeDays, "archive_dir", policy.ArchiveDir)
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.applyRetention(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// applyRetention archives and deletes the cycles that finished more than MaxAgeDays
+// ago; a cycle that fails to archive is kept for the next run
+func (s *jobServiceImpl) applyRetention(ctx context.Context) {
+	policy := s.config.Retention
+	cutoff := s.clock.Now().AddDate(0, 0, -policy.MaxAgeDays).Unix()
+	deleted := 0
+	for ctx.Err() == nil {
+		cycles, err := s.store.ListCyclesDoneBefore(ctx, cutoff, retentionBatch)
+		if err != nil {
+			s.logger.Error(ctx, "Failed to list cycles past retention", "error", err)
+			return
+		}
+		kept := 0
+		for _, cycle := range cycles {
+			if policy.ArchiveDir != "" {
+				if err := s.archiveCycle(ctx, cycle.UUID); err != nil {
+					s.logger.Error(ctx, "Failed to archive cycle", "cycle_uuid", cycle.UUID, "error", err)
+					kept++
+					continue
+				}
+			}
+			if err := s.store.DeleteCycleData(ctx, cycle.UUID); err != nil {
+				s.logger.Error(ctx, "Failed to delete cycle past retention", "cycle_uuid", cycle.UUID, "error", err)
+				kept++
+				continue
+			}
+			deleted++
+		}
+		// Cycles kept come back first in the next query, so stop rather than loop on them
+		if len(cycles) < retentionBatch || kept > 0 {
+			break
+		}
+	}
+	if deleted > 0 {
+		s.logger.Info(ctx, "Deleted cycles past retention", "cycles", deleted, "max_age_days", policy.MaxAgeDays)
+	}
+}
+
+// archiveCycle writes the archive of a cycle to the archive directory, replacing the
+// file only once the archive is complete
+func (s *jobServiceImpl) archiveCycle(ctx context.Context, cycleUUID string) error {
+	dir := s.config.Retention.ArchiveDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(dir, cycleUUID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := s.store.ArchiveCycle(ctx, cycleUUID, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %v", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, cycleUUID+".jsonl.gz"))
+}
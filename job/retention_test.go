@@ -0,0 +1,80 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// retentionStore keeps finished cycles by UUID and fails to archive those in broken
+type retentionStore struct {
+	store.Store
+	cycles map[string]models.Cycle
+	broken map[string]bool
+}
+
+func (s *retentionStore) ListCyclesDoneBefore(ctx context.Context, before int64, limit int) ([]models.Cycle, error) {
+	var cycles []models.Cycle
+	for _, cycle := range s.cycles {
+		if cycle.DoneAt > 0 && cycle.DoneAt < before && len(cycles) < limit {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles, nil
+}
+
+func (s *retentionStore) ArchiveCycle(ctx context.Context, cycleUUID string, w io.Writer) error {
+	if s.broken[cycleUUID] {
+		return errors.New("disk full")
+	}
+	_, err := io.WriteString(w, cycleUUID)
+	return err
+}
+
+func (s *retentionStore) DeleteCycleData(ctx context.Context, cycleUUID string) error {
+	delete(s.cycles, cycleUUID)
+	return nil
+}
+
+func TestApplyRetention(t *testing.T) {
+	clock := newFakeClock()
+	clock.Advance(30 * 24 * time.Hour)
+	day := int64(24 * 60 * 60)
+	now := clock.Now().Unix()
+	st := &retentionStore{
+		cycles: map[string]models.Cycle{
+			"old":     {UUID: "old", DoneAt: now - 10*day},
+			"broken":  {UUID: "broken", DoneAt: now - 10*day},
+			"recent":  {UUID: "recent", DoneAt: now - day},
+			"running": {UUID: "running"},
+		},
+		broken: map[string]bool{"broken": true},
+	}
+	dir := filepath.Join(t.TempDir(), "archive")
+	s := &jobServiceImpl{clock: clock, store: st, logger: logger.NewSlogLogger(),
+		config: JobServiceConfig{Retention: store.RetentionConfig{MaxAgeDays: 7, ArchiveDir: dir}}}
+
+	s.applyRetention(context.Background())
+	assert.NotContains(t, st.cycles, "old")
+	assert.Contains(t, st.cycles, "broken", "cycles that fail to archive are kept")
+	assert.Contains(t, st.cycles, "recent")
+	assert.Contains(t, st.cycles, "running")
+
+	archive, err := os.ReadFile(filepath.Join(dir, "old.jsonl.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(archive))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "failed archives leave no file behind")
+}
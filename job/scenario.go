@@ -0,0 +1,283 @@
+package job
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/songvi/robo/generator/file"
+	"github.com/songvi/robo/models"
+)
+
+// maxScenarioJobs bounds the jobs one session compiles to, against runaway loops
+const maxScenarioJobs = 10000
+
+// loadScenario returns the scenario of strategy, reading ScenarioFile when no inline
+// Scenario is set, or nil when it has none
+func loadScenario(strategy *models.Strategy) (*models.Scenario, error) {
+	if strategy.Scenario != nil || strategy.ScenarioFile == "" {
+		return strategy.Scenario, nil
+	}
+	data, err := os.ReadFile(strategy.ScenarioFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %v", err)
+	}
+	// JSON is a subset of YAML, so one decoder reads both
+	var scenario models.Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file %s: %v", strategy.ScenarioFile, err)
+	}
+	return &scenario, nil
+}
+
+// validateScenario checks the steps of scenario and parses every payload template
+func validateScenario(scenario *models.Scenario) error {
+	if len(scenario.Steps) == 0 {
+		return fmt.Errorf("scenario %q has no steps", scenario.Name)
+	}
+	return validateSteps(scenario.Steps, "steps")
+}
+
+func validateSteps(steps []models.ScenarioStep, path string) error {
+	for i, step := range steps {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		if step.Action == "" && len(step.Steps) == 0 && len(step.Branches) == 0 {
+			return fmt.Errorf("%s: needs an action, steps or branches", at)
+		}
+		if step.Probability < 0 || step.Probability > 1 {
+			return fmt.Errorf("%s: probability %v is outside [0, 1]", at, step.Probability)
+		}
+		if r := step.Repeat; r != nil && (r.Min < 0 || r.Max < r.Min || r.Max == 0) {
+			return fmt.Errorf("%s: repeat needs 0 <= min <= max and max > 0", at)
+		}
+		if len(step.Payload) > 0 && step.Action == "" {
+			return fmt.Errorf("%s: payload needs an action", at)
+		}
+		for key, text := range step.Payload {
+			if _, err := parsePayload(key, text, nil); err != nil {
+				return fmt.Errorf("%s: payload %s: %v", at, key, err)
+			}
+		}
+		if err := validateSteps(step.Steps, at+".steps"); err != nil {
+			return err
+		}
+		total := 0.0
+		for j, branch := range step.Branches {
+			if branch.Weight < 0 {
+				return fmt.Errorf("%s.branches[%d]: negative weight", at, j)
+			}
+			total += branch.Weight
+			if err := validateSteps(branch.Steps, fmt.Sprintf("%s.branches[%d].steps", at, j)); err != nil {
+				return err
+			}
+		}
+		if len(step.Branches) > 0 && total == 0 {
+			return fmt.Errorf("%s: branch weights sum to zero", at)
+		}
+	}
+	return nil
+}
+
+// parsePayload parses a payload template; funcs may be nil when only checking syntax
+func parsePayload(key, text string, funcs template.FuncMap) (*template.Template, error) {
+	if funcs == nil {
+		funcs = template.FuncMap{
+			"file": func() string { return "" },
+			"user": func() string { return "" },
+			"job":  func(string) string { return "" },
+		}
+	}
+	return template.New(key).Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// scenarioCompiler compiles a scenario into the jobs of one session, each depending on
+// the one before it
+type scenarioCompiler struct {
+	scenario *models.Scenario
+	session  models.Session
+	users    []string // User names of the cycle, for {{user}}
+	// generateFile generates a file of the cycle and returns its name in the FileStore
+	generateFile func() (string, error)
+	rng          *rand.Rand
+
+	jobs    []models.Job
+	stepJob map[string]string // Step ID -> UUID of its last job
+}
+
+// newScenarioCompiler prepares the compilation of scenario for session; the choices of a
+// session depend only on the seed of its cycle and its user, so a replayed cycle makes
+// the same ones, and its job UUIDs are named after the session
+func newScenarioCompiler(scenario *models.Scenario, seed int64, session models.Session, users []string, generateFile func() (string, error)) *scenarioCompiler {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(session.UserName))
+	return &scenarioCompiler{
+		scenario:     scenario,
+		session:      session,
+		users:        users,
+		generateFile: generateFile,
+		rng:          rand.New(rand.NewSource(int64(h.Sum64()))),
+		stepJob:      make(map[string]string),
+	}
+}
+
+// compile returns the jobs of the session in order
+func (c *scenarioCompiler) compile() ([]models.Job, error) {
+	if err := c.compileSteps(c.scenario.Steps); err != nil {
+		return nil, err
+	}
+	return c.jobs, nil
+}
+
+func (c *scenarioCompiler) compileSteps(steps []models.ScenarioStep) error {
+	for _, step := range steps {
+		if step.Probability > 0 && c.rng.Float64() >= step.Probability {
+			continue
+		}
+		times := 1
+		if r := step.Repeat; r != nil {
+			times = r.Min + c.rng.Intn(r.Max-r.Min+1)
+		}
+		for i := 0; i < times; i++ {
+			if step.Action != "" {
+				if err := c.addJob(step, i); err != nil {
+					return err
+				}
+			}
+			if err := c.compileSteps(step.Steps); err != nil {
+				return err
+			}
+			if len(step.Branches) > 0 {
+				if err := c.compileSteps(c.pickBranch(step.Branches).Steps); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pickBranch draws a branch with a chance proportional to its weight
+func (c *scenarioCompiler) pickBranch(branches []models.ScenarioBranch) models.ScenarioBranch {
+	total := 0.0
+	for _, b := range branches {
+		total += b.Weight
+	}
+	r := c.rng.Float64() * total
+	for _, b := range branches {
+		if r < b.Weight {
+			return b
+		}
+		r -= b.Weight
+	}
+	return branches[len(branches)-1]
+}
+
+// addJob appends the job of one run of step, rendering its payload
+func (c *scenarioCompiler) addJob(step models.ScenarioStep, iteration int) error {
+	if len(c.jobs) >= maxScenarioJobs {
+		return fmt.Errorf("scenario %q compiles to more than %d jobs per session", c.scenario.Name, maxScenarioJobs)
+	}
+	inputData := map[string]interface{}{
+		"user_id":    c.session.UserName,
+		"session_id": c.session.UUID,
+		"persona":    c.session.Persona,
+		"action":     step.Action,
+		"scenario":   c.scenario.Name,
+		"sequence":   len(c.jobs),
+		"iteration":  iteration,
+	}
+	if step.ID != "" {
+		inputData["step"] = step.ID
+	}
+	vars := map[string]interface{}{
+		"User":      c.session.UserName,
+		"Session":   c.session.UUID,
+		"Persona":   c.session.Persona,
+		"Iteration": iteration,
+	}
+	// Render in key order so the choices of {{user}} are reproducible
+	for _, key := range slices.Sorted(maps.Keys(step.Payload)) {
+		tmpl, err := parsePayload(key, step.Payload[key], c.funcs())
+		if err != nil {
+			return err
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, vars); err != nil {
+			return fmt.Errorf("failed to render payload %s of step %q: %v", key, step.ID, err)
+		}
+		inputData[key] = out.String()
+	}
+	inputJSON, err := json.Marshal(inputData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job input data: %v", err)
+	}
+
+	job := models.Job{
+		UUID:      uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%d", c.session.UUID, len(c.jobs)))).String(),
+		Name:      step.Action,
+		InputData: json.RawMessage(inputJSON),
+		Status:    "pending",
+	}
+	if len(c.jobs) > 0 {
+		job.DependsOn = c.jobs[len(c.jobs)-1].UUID
+	}
+	c.jobs = append(c.jobs, job)
+	if step.ID != "" {
+		c.stepJob[step.ID] = job.UUID
+	}
+	return nil
+}
+
+// funcs are the data references payload templates may call
+func (c *scenarioCompiler) funcs() template.FuncMap {
+	return template.FuncMap{
+		"file": c.generateFile,
+		"user": func() string {
+			var others []string
+			for _, name := range c.users {
+				if name != c.session.UserName {
+					others = append(others, name)
+				}
+			}
+			if len(others) == 0 {
+				return c.session.UserName
+			}
+			return others[c.rng.Intn(len(others))]
+		},
+		"job": func(id string) (string, error) {
+			jobUUID, ok := c.stepJob[id]
+			if !ok {
+				return "", fmt.Errorf("no job of step %q so far", id)
+			}
+			return jobUUID, nil
+		},
+	}
+}
+
+// scenarioJobs compiles scenario into the jobs of session, generating the files its
+// payloads reference within the cycle
+func (s *jobServiceImpl) scenarioJobs(ctx context.Context, scenario *models.Scenario, seed int64, session models.Session, users []string) ([]models.Job, error) {
+	generateFile := func() (string, error) {
+		files, err := s.generator.GenerateFiles(ctx, 1)
+		if err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "", fmt.Errorf("no file generated")
+		}
+		return file.StoreName(&files[0]), nil
+	}
+	return newScenarioCompiler(scenario, seed, session, users, generateFile).compile()
+}
@@ -0,0 +1,89 @@
+package job
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+const testScenario = `
+name: share-flow
+steps:
+  - id: login
+    action: login
+  - action: upload_file
+    repeat: {min: 2, max: 2}
+    payload:
+      file_path: "{{file}}"
+      note: "{{.User}} upload {{.Iteration}}"
+  - branches:
+      - weight: 1
+        steps:
+          - action: share_file
+            payload:
+              with: "{{user}}"
+              after: '{{job "login"}}'
+      - weight: 0
+        steps:
+          - action: delete_file
+  - action: never
+    probability: 0.000001
+  - action: logout
+`
+
+func TestScenarioCompile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testScenario), 0o644))
+	scenario, err := loadScenario(&models.Strategy{ScenarioFile: path})
+	require.NoError(t, err)
+	require.NoError(t, validateScenario(scenario))
+
+	session := models.Session{UUID: "7b0c3a52-0000-4000-8000-000000000001", UserName: "alice"}
+	files := 0
+	generateFile := func() (string, error) {
+		files++
+		return "cycles/c1/report.pdf", nil
+	}
+	jobs, err := newScenarioCompiler(scenario, 1, session, []string{"alice", "bob"}, generateFile).compile()
+	require.NoError(t, err)
+
+	var names []string
+	for i, job := range jobs {
+		names = append(names, job.Name)
+		if i == 0 {
+			assert.Empty(t, job.DependsOn)
+		} else {
+			assert.Equal(t, jobs[i-1].UUID, job.DependsOn, "jobs of a session run in order")
+		}
+	}
+	assert.Equal(t, []string{"login", "upload_file", "upload_file", "share_file", "logout"}, names)
+	assert.Equal(t, 2, files)
+
+	var upload, share map[string]interface{}
+	require.NoError(t, json.Unmarshal(jobs[2].InputData, &upload))
+	assert.Equal(t, "cycles/c1/report.pdf", upload["file_path"])
+	assert.Equal(t, "alice upload 1", upload["note"])
+	require.NoError(t, json.Unmarshal(jobs[3].InputData, &share))
+	assert.Equal(t, "bob", share["with"])
+	assert.Equal(t, jobs[0].UUID, share["after"])
+}
+
+func TestScenarioValidation(t *testing.T) {
+	for _, steps := range [][]models.ScenarioStep{
+		{{}},
+		{{Action: "a", Probability: 1.5}},
+		{{Action: "a", Repeat: &models.ScenarioRepeat{Min: 3, Max: 1}}},
+		{{Branches: []models.ScenarioBranch{{Weight: 0, Steps: []models.ScenarioStep{{Action: "a"}}}}}},
+		{{Action: "a", Payload: map[string]string{"x": "{{.User"}}},
+		{{Steps: []models.ScenarioStep{{Payload: map[string]string{"x": "y"}}}}},
+	} {
+		assert.Error(t, validateScenario(&models.Scenario{Steps: steps}))
+	}
+	assert.Error(t, validateScenario(&models.Scenario{}))
+}
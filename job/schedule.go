@@ -0,0 +1,259 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// cronDescriptors are the shorthands accepted in place of five cron fields
+var cronDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// cronExpr is a parsed five-field cron expression; each field is the set of values it
+// matches
+type cronExpr struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool // A day matches either restricted day field, as in Vixie cron
+}
+
+// parseCronField parses one comma-separated cron field of values, ranges and steps
+// within [lo, hi]
+func parseCronField(field string, lo, hi int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+		from, to := lo, hi
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", part)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range in %q", part)
+				}
+			} else if step > 1 {
+				to = hi // "5/15" means from 5 on, every 15
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q is outside [%d, %d]", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// parseCron parses a five-field cron expression or descriptor
+func parseCron(spec string) (*cronExpr, error) {
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q needs 5 fields", spec)
+	}
+	var e cronExpr
+	var err error
+	if e.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if e.dow[7] {
+		e.dow[0] = true // Both 0 and 7 are Sunday
+	}
+	e.domAny, e.dowAny = fields[2] == "*", fields[4] == "*"
+	return &e, nil
+}
+
+// dayMatches tells whether the day of t is selected by the day-of-month and
+// day-of-week fields
+func (e *cronExpr) dayMatches(t time.Time) bool {
+	dom, dow := e.dom[t.Day()], e.dow[int(t.Weekday())]
+	switch {
+	case e.domAny && e.dowAny:
+		return true
+	case e.domAny:
+		return dow
+	case e.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first time after t the expression matches, or the zero time when
+// it matches none within five years
+func (e *cronExpr) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !e.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !e.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !e.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !e.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// cycleSchedule computes the run times of a CycleSchedule
+type cycleSchedule struct {
+	models.CycleSchedule
+	cron     *cronExpr
+	location *time.Location
+}
+
+// newCycleSchedule validates cfg and prepares its run times
+func newCycleSchedule(cfg models.CycleSchedule) (*cycleSchedule, error) {
+	s := &cycleSchedule{CycleSchedule: cfg, location: time.Local}
+	switch cfg.Overlap {
+	case "":
+		s.Overlap = "skip"
+	case "skip", "queue", "parallel":
+	default:
+		return nil, fmt.Errorf("schedule %q: unsupported overlap policy %q", cfg.Name, cfg.Overlap)
+	}
+	if cfg.Location != "" {
+		var err error
+		if s.location, err = time.LoadLocation(cfg.Location); err != nil {
+			return nil, fmt.Errorf("schedule %q: invalid location: %v", cfg.Name, err)
+		}
+	}
+	switch {
+	case cfg.Cron != "":
+		var err error
+		if s.cron, err = parseCron(cfg.Cron); err != nil {
+			return nil, fmt.Errorf("schedule %q: %v", cfg.Name, err)
+		}
+	case cfg.IntervalSeconds <= 0:
+		return nil, fmt.Errorf("schedule %q: needs a cron expression or a positive interval", cfg.Name)
+	}
+	return s, nil
+}
+
+// next returns the first run time after t
+func (s *cycleSchedule) next(t time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.next(t.In(s.location))
+	}
+	return t.Add(time.Duration(s.IntervalSeconds) * time.Second)
+}
+
+// runSchedules starts the cycles of every configured schedule until ctx is done
+func (s *jobServiceImpl) runSchedules(ctx context.Context) {
+	for _, cfg := range s.config.Schedules {
+		schedule, err := newCycleSchedule(cfg)
+		if err != nil {
+			s.logger.Error(ctx, "Ignoring invalid cycle schedule", "schedule", cfg.Name, "error", err)
+			continue
+		}
+		go s.runSchedule(ctx, schedule)
+	}
+}
+
+// runSchedule starts a cycle at every run time of schedule, applying its overlap
+// policy while the previous cycle is still running
+func (s *jobServiceImpl) runSchedule(ctx context.Context, schedule *cycleSchedule) {
+	var previous string // UUID of the last cycle started
+	queued := false
+	poll := s.clock.NewTicker(10 * time.Second)
+	defer poll.Stop()
+
+	nextRun := schedule.next(s.clock.Now())
+	s.logger.Info(ctx, "Cycle schedule armed", "schedule", schedule.Name, "next_run", nextRun)
+	for !nextRun.IsZero() {
+		timer := s.clock.NewTimer(nextRun.Sub(s.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-poll.C():
+			timer.Stop()
+			if queued && !s.cycleActive(ctx, previous) {
+				queued = false
+				previous = s.startScheduledCycle(ctx, schedule)
+			}
+			continue
+		case <-timer.C():
+		}
+		nextRun = schedule.next(nextRun)
+
+		if s.cycleActive(ctx, previous) {
+			switch schedule.Overlap {
+			case "skip":
+				s.logger.Info(ctx, "Skipping scheduled cycle, previous one still running", "schedule", schedule.Name, "cycle_uuid", previous)
+				continue
+			case "queue":
+				queued = true
+				s.logger.Info(ctx, "Queueing scheduled cycle behind the running one", "schedule", schedule.Name, "cycle_uuid", previous)
+				continue
+			}
+		}
+		previous = s.startScheduledCycle(ctx, schedule)
+	}
+}
+
+// cycleActive tells whether the cycle is still running or paused
+func (s *jobServiceImpl) cycleActive(ctx context.Context, cycleUUID string) bool {
+	if cycleUUID == "" {
+		return false
+	}
+	cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if err != nil {
+		s.logger.Error(ctx, "Failed to fetch scheduled cycle", "cycle_uuid", cycleUUID, "error", err)
+		return false
+	}
+	return cycle.Status == "running" || cycle.Status == "paused"
+}
+
+// startScheduledCycle starts one cycle of schedule and returns its UUID, or "" when it
+// failed to start
+func (s *jobServiceImpl) startScheduledCycle(ctx context.Context, schedule *cycleSchedule) string {
+	cycle, err := s.StartCycle(ctx, models.Cycle{
+		Name:     fmt.Sprintf("%s %s", schedule.Name, s.clock.Now().In(schedule.location).Format(time.RFC3339)),
+		Strategy: schedule.Strategy,
+	})
+	if err != nil {
+		s.logger.Error(ctx, "Failed to start scheduled cycle", "schedule", schedule.Name, "error", err)
+		return ""
+	}
+	return cycle.UUID
+}
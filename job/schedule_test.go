@@ -0,0 +1,58 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2025, 3, 14, 10, 7, 30, 0, time.UTC) // A Friday
+	for _, tc := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 15, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2025, 3, 17, 2, 30, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2025, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 13,20 * 5", time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		e, err := parseCron(tc.spec)
+		require.NoError(t, err, tc.spec)
+		assert.Equal(t, tc.want, e.next(base), tc.spec)
+	}
+
+	e, err := parseCron("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, e.next(base).IsZero(), "February 31st never comes")
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@yearly"} {
+		_, err := parseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCycleSchedule(t *testing.T) {
+	s, err := newCycleSchedule(models.CycleSchedule{Name: "ramp", IntervalSeconds: 3600})
+	require.NoError(t, err)
+	assert.Equal(t, "skip", s.Overlap)
+	now := time.Now()
+	assert.Equal(t, now.Add(time.Hour), s.next(now))
+
+	s, err = newCycleSchedule(models.CycleSchedule{Name: "nightly", Cron: "0 2 * * *", Location: "Asia/Tokyo", Overlap: "queue"})
+	require.NoError(t, err)
+	next := s.next(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)) // 09:00 in Tokyo
+	assert.Equal(t, time.Date(2025, 3, 14, 17, 0, 0, 0, time.UTC), next.UTC())
+
+	_, err = newCycleSchedule(models.CycleSchedule{Name: "none"})
+	assert.Error(t, err)
+	_, err = newCycleSchedule(models.CycleSchedule{Name: "x", IntervalSeconds: 60, Overlap: "stack"})
+	assert.Error(t, err)
+}
@@ -3,28 +3,70 @@ package job
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
 	"go.uber.org/fx"
 
+	"github.com/songvi/robo/aggregator"
 	"github.com/songvi/robo/config"
 	"github.com/songvi/robo/dispatcher"
 	"github.com/songvi/robo/generator"
+	"github.com/songvi/robo/generator/file"
+	useraction "github.com/songvi/robo/generator/user_action"
+	"github.com/songvi/robo/ledger"
 	"github.com/songvi/robo/logger"
 	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/notify"
 	"github.com/songvi/robo/store"
 )
 
 // JobServiceConfig defines the configuration for JobService
 type JobServiceConfig struct {
-	Strategy *models.Strategy `json:"strategy" yaml:"strategy"`
+	Strategy  *models.Strategy       `json:"strategy" yaml:"strategy"`
+	Schedules []models.CycleSchedule `json:"schedules" yaml:"schedules"`
+	Recovery  string                 `json:"recovery" yaml:"recovery"`
+	Retention store.RetentionConfig  `json:"retention" yaml:"retention"`
 }
 
+var (
+	// ErrInvalidStrategy is returned when a cycle cannot start with its strategy
+	ErrInvalidStrategy = errors.New("invalid strategy")
+	// ErrCycleState is returned when a cycle is not in a status the operation applies to
+	ErrCycleState = errors.New("cycle status does not allow the operation")
+	// ErrJobState is returned when a job is not in a status the operation applies to
+	ErrJobState = errors.New("job status does not allow the operation")
+)
+
 // JobService defines the interface for job management
 type JobService interface {
-	StartCycle(ctx context.Context, cycle models.Cycle) error
+	// StartCycle plans and saves the sessions and jobs of a new cycle and returns it
+	StartCycle(ctx context.Context, cycle models.Cycle) (models.Cycle, error)
+	// ValidateCycle checks a cycle as StartCycle would and returns the plan of its
+	// sessions, jobs, disk use and workers without creating anything
+	ValidateCycle(ctx context.Context, cycle models.Cycle) (models.CyclePlan, error)
+	// StartTemplateCycle starts a cycle from a stored template, with overrides merged
+	// into its strategy
+	StartTemplateCycle(ctx context.Context, template string, cycle models.Cycle, overrides json.RawMessage) (models.Cycle, error)
 	ProcessJobs(ctx context.Context) error
+	// CycleProgress reports the job counts and estimated finish time of a cycle
+	CycleProgress(ctx context.Context, cycleUUID string) (models.CycleProgress, error)
+	// CyclesProgress refreshes the progress of the unfinished cycles among cycles at once
+	CyclesProgress(ctx context.Context, cycles []models.Cycle) error
+	// PauseCycle stops dispatching the jobs of a running cycle and cancels those in
+	// flight, which ResumeCycle dispatches again
+	PauseCycle(ctx context.Context, cycleUUID string) error
+	ResumeCycle(ctx context.Context, cycleUUID string) error
+	// AbortCycle cancels every job of a running or paused cycle for good
+	AbortCycle(ctx context.Context, cycleUUID string) error
+	// RetryJob puts a failed or cancelled job of a running or paused cycle back in line
+	RetryJob(ctx context.Context, jobUUID string) error
 }
 
 // jobServiceImpl implements the JobService interface
@@ -34,6 +76,17 @@ type jobServiceImpl struct {
 	logger     logger.Logger
 	config     JobServiceConfig
 	generator  generator.Generator
+	ledger     ledger.Ledger
+	results    aggregator.Aggregator
+	notifier   notify.Notifier
+	// fileStrategy is used to estimate the storage a cycle needs on the target
+	fileStrategy models.FileStrategy
+	clock        Clock
+	// ctx lasts until the service stops; the dispatch of paced cycles runs under it
+	ctx     context.Context
+	slos    *sloTracker
+	budgets *budgetTracker
+	runners *sessionRunners
 }
 
 // NewJobService creates a new JobService instance
@@ -44,6 +97,10 @@ func NewJobService(
 	store store.Store,
 	dispatcher dispatcher.Dispatcher,
 	generator generator.Generator,
+	ledger ledger.Ledger,
+	results aggregator.Aggregator,
+	notifier notify.Notifier,
+	clock Clock,
 ) JobService {
 	// Load config
 	cfg := configSvc.GetConfig()
@@ -74,24 +131,54 @@ func NewJobService(
 		}
 	}
 
+	jobConfig.Schedules = cfg.Schedules
+	jobConfig.Retention = cfg.Store.Retention
+	jobConfig.Recovery = cfg.Recovery
+
 	s := &jobServiceImpl{
 		store:      store,
 		dispatcher: dispatcher,
 		logger:     logger,
 		config:     jobConfig,
 		generator:  generator,
+		ledger:     ledger,
+		results:    results,
+		notifier:   notifier,
+
+		fileStrategy: cfg.Generator.Strategy.FileStrategy,
+		clock:        clock,
+		slos:         newSLOTracker(),
+		budgets:      newBudgetTracker(),
+		runners:      newSessionRunners(),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	processed := make(chan struct{})
 	lc.Append(fx.Hook{
 		OnStart: func(context.Context) error {
 			logger.Info(ctx, "Starting JobService")
-			go s.ProcessJobs(ctx)
+			s.recoverCycles(ctx)
+			go func() {
+				defer close(processed)
+				if err := s.ProcessJobs(ctx); err != nil {
+					logger.Error(ctx, "Job processing stopped", "error", err)
+				}
+			}()
+			s.runSchedules(ctx)
+			if jobConfig.Retention.MaxAgeDays > 0 {
+				go s.runRetention(ctx)
+			}
 			return nil
 		},
-		OnStop: func(context.Context) error {
+		OnStop: func(stopCtx context.Context) error {
 			logger.Info(ctx, "Stopping JobService")
 			cancel()
+			// Let the result consumer finish the result in hand
+			select {
+			case <-processed:
+			case <-stopCtx.Done():
+			}
 			return nil
 		},
 	})
@@ -100,76 +187,278 @@ func NewJobService(
 }
 
 // StartCycle initiates a new cycle and generates sessions and jobs
-func (s *jobServiceImpl) StartCycle(ctx context.Context, cycle models.Cycle) error {
+func (s *jobServiceImpl) StartCycle(ctx context.Context, cycle models.Cycle) (models.Cycle, error) {
 	cycle.UUID = uuid.New().String()
-	cycle.StartedAt = time.Now().Unix()
+	cycle.StartedAt = s.clock.Now().Unix()
 	cycle.Status = "running"
+	cycle.Namespace = file.CycleNamespace(cycle.UUID)
+	// Everything the cycle generates derives from its seed, so a cycle started again
+	// with the seed of another replays its data
+	if cycle.Seed == 0 {
+		cycle.Seed = rand.Int63()
+	}
+	ctx = generator.WithSeed(generator.WithCycle(ctx, cycle.UUID), cycle.Seed)
 	// Use strategy from config if not provided
 	if cycle.Strategy == nil {
 		cycle.Strategy = s.config.Strategy
 	}
+	strategy, err := prepareStrategy(cycle.Strategy)
+	if err != nil {
+		return cycle, err
+	}
+	cycle.Strategy = strategy
+	// Load jobs wait for the warm-up, if any
+	if cycle.Strategy.WarmUp != nil {
+		cycle.Phase = "warmup"
+	} else {
+		cycle.Phase, cycle.LoadStartedAt = "load", cycle.StartedAt
+	}
 
-	// Save cycle to database
-	if err := s.store.CreateCycle(ctx, &cycle); err != nil {
-		s.logger.Error(ctx, "Failed to save cycle to database", "cycle_uuid", cycle.UUID, "error", err)
-		return err
+	// Generate the users under the seed of the cycle, before saving anything
+	users, err := s.generator.GenerateUsers(ctx, cycle.Strategy.MaxUsers)
+	if err != nil {
+		s.logger.Error(ctx, "Failed to generate users", "cycle_uuid", cycle.UUID, "error", err)
+		return cycle, fmt.Errorf("failed to generate users: %v", err)
 	}
 
-	// Fetch users from generator
-	users := []models.User{}
-	userCh := s.generator.Users(ctx)
-	for i := 0; i < cycle.Strategy.MaxUsers; i++ {
-		select {
-		case user, ok := <-userCh:
-			if !ok {
-				break
-			}
-			users = append(users, user)
-		case <-ctx.Done():
-			return ctx.Err()
+	// Give every user a session of the cycle; the users are saved with the sessions
+	sessions := make([]models.Session, len(users))
+	targets := sessionTargets(cycle.Strategy.Targets, len(users))
+	for i := range users {
+		sessions[i] = models.Session{
+			UUID:      newID(ctx),
+			UserName:  users[i].UserName,
+			Persona:   users[i].Persona,
+			CycleUUID: cycle.UUID,
+			Status:    "planned",
+		}
+		if targets != nil {
+			sessions[i].Target = targets[i]
 		}
+		users[i].UUID = newID(ctx)
+		users[i].CycleID = cycle.UUID
+		users[i].SessionID = sessions[i].UUID
+		if users[i].Password == "" {
+			users[i].Password = newPassword(ctx)
+		}
+		sessions[i].UserID = users[i].UUID
 	}
 
-	for _, user := range users {
-		session := models.Session{UserID: user.UserName}
+	// Plan jobs for every session before saving anything
+	userNames := make([]string, len(users))
+	for i, user := range users {
+		userNames[i] = user.UserName
+	}
+	// Scenarios reference the data they need in their payloads; other sessions, and
+	// the warm-up of every session, get the workspaces and files of the cycle
+	warmUp := cycle.Strategy.WarmUp
+	var resources []sessionResources
+	if cycle.Strategy.Scenario == nil || warmUp != nil {
+		resources = s.planResources(ctx, cycle, sessions, users)
+	}
+	var jobs []models.Job
+	warmUpCount := 0
+	for i, session := range sessions {
 		// Generate jobs for the session
-		jobs, err := s.generateSessionJobs(ctx, cycle, session)
+		sessionJobs, err := s.generateSessionJobs(ctx, cycle, session, userNames)
 		if err != nil {
-			s.logger.Error(ctx, "Failed to generate jobs for session", "cycle_uuid", cycle.UUID, "user_id", session.UserID, "error", err)
+			s.logger.Error(ctx, "Failed to generate jobs for session", "cycle_uuid", cycle.UUID, "session_id", session.UUID, "error", err)
 			continue
 		}
-
-		// Save jobs to database
-		for _, job := range jobs {
+		// Live sessions run one job at a time between a login and a logout
+		if cycle.Strategy.LiveSessions {
+			for j := range sessionJobs {
+				sessionJobs[j].DependsOn = ""
+			}
+			sessionJobs = append(append([]models.Job{sessionJob(ctx, session, users[i], "login")}, sessionJobs...),
+				sessionJob(ctx, session, users[i], "logout"))
+		}
+		seeds := 0
+		if warmUp != nil {
+			seedJobs := warmUpJobs(ctx, warmUp, session, len(resources[i].workspaces))
+			seeds = len(seedJobs)
+			warmUpCount += seeds
+			sessionJobs = append(seedJobs, sessionJobs...)
+		}
+		if resources != nil {
+			// Load jobs work on the files the warm-up uploaded, but scenario jobs carry
+			// their own data
+			attached := sessionJobs
+			if cycle.Strategy.Scenario != nil {
+				attached = sessionJobs[:seeds]
+			}
+			if err := resources[i].attach(attached); err != nil {
+				s.logger.Error(ctx, "Failed to attach resources to session jobs", "cycle_uuid", cycle.UUID, "session_id", session.UUID, "error", err)
+			}
+		}
+		if cycle.Strategy.LiveSessions {
+			sessions[i].Plan = sessionPlan(sessionJobs[seeds:])
+		}
+		for _, job := range sessionJobs {
 			job.CycleUUID = cycle.UUID
-			job.SessionID = session.UserID
-			if err := s.store.CreateJob(ctx, &job); err != nil {
-				s.logger.Error(ctx, "Failed to save job to database", "job_uuid", job.UUID, "error", err)
-				continue
+			job.SessionID = session.UUID
+			job.Target = session.Target
+			jobs = append(jobs, job)
+		}
+	}
+
+	// Verify the target accepts the planned actions before dispatching thousands of jobs
+	if cycle.Strategy.Preflight {
+		if err := s.preflight(ctx, cycle, jobs); err != nil {
+			s.logger.Error(ctx, "Cycle preflight failed", "cycle_uuid", cycle.UUID, "error", err)
+			cycle.Status = "preflight_failed"
+			cycle.DoneAt = s.clock.Now().Unix()
+			if createErr := s.store.CreateCycle(ctx, &cycle); createErr != nil {
+				s.logger.Error(ctx, "Failed to save cycle to database", "cycle_uuid", cycle.UUID, "error", createErr)
 			}
+			return cycle, err
+		}
+	}
+
+	// The load jobs of live sessions are created by their scheduler
+	if cycle.Strategy.LiveSessions {
+		jobs = slices.DeleteFunc(jobs, func(job models.Job) bool { return job.Phase == "" })
+	}
+	cycle.Progress.TotalJobs = len(jobs)
+	if cycle.Phase == "warmup" && warmUpCount == 0 {
+		cycle.Phase, cycle.LoadStartedAt = "load", s.clock.Now().Unix()
+	}
+	// The cycle is saved with its users, sessions, resources and jobs or not at all
+	err = s.store.WithTx(ctx, func(tx store.Store) error {
+		if err := tx.CreateCycle(ctx, &cycle); err != nil {
+			return fmt.Errorf("failed to save cycle: %w", err)
+		}
+		for i := range users {
+			if err := tx.CreateUser(ctx, &users[i]); err != nil {
+				return fmt.Errorf("failed to save user %s: %w", users[i].UserName, err)
+			}
+		}
+		for i := range sessions {
+			if err := tx.CreateSession(ctx, &sessions[i]); err != nil {
+				return fmt.Errorf("failed to save session: %v", err)
+			}
+		}
+		if err := saveResources(ctx, tx, resources); err != nil {
+			return err
+		}
+		if err := tx.CreateJobsBatch(ctx, jobs); err != nil {
+			return fmt.Errorf("failed to save jobs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error(ctx, "Failed to save cycle to database", "cycle_uuid", cycle.UUID, "jobs", len(jobs), "error", err)
+		// Record the attempt without any of what it planned
+		cycle.Status, cycle.Reason = "aborted", "failed to save its users, sessions and jobs"
+		cycle.Progress.TotalJobs = 0
+		cycle.DoneAt = s.clock.Now().Unix()
+		if createErr := s.store.CreateCycle(ctx, &cycle); createErr != nil {
+			s.logger.Error(ctx, "Failed to save cycle to database", "cycle_uuid", cycle.UUID, "error", createErr)
+		}
+		return cycle, err
+	}
+
+	if cycle.Strategy.Paced() {
+		go s.paceCycle(s.ctx, cycle.UUID, cycle.Strategy, 0)
+	}
+	if cycle.Strategy.LiveSessions {
+		for _, session := range sessions {
+			go s.runSession(s.ctx, session.UUID)
 		}
 	}
 
 	s.logger.Info(ctx, "Cycle started", "cycle_uuid", cycle.UUID, "name", cycle.Name)
-	return nil
+	s.notifyCycle(ctx, notify.CycleStarted, &cycle, nil)
+	return cycle, nil
 }
 
-// generateSessionJobs creates jobs for a session
-func (s *jobServiceImpl) generateSessionJobs(ctx context.Context, cycle models.Cycle, session models.Session) ([]models.Job, error) {
-	var jobs []models.Job
-	actions := []string{
-		"create_user",
-		"create_workspace",
-		"upload_file", "download_file", "consult_file",
+// prepareStrategy validates strategy and returns the copy a cycle keeps, with its
+// scenario read from its file once
+func prepareStrategy(strategy *models.Strategy) (*models.Strategy, error) {
+	scenario, err := loadScenario(strategy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStrategy, err)
 	}
+	if scenario != nil {
+		if err := validateScenario(scenario); err != nil {
+			return nil, fmt.Errorf("%w: invalid scenario: %v", ErrInvalidStrategy, err)
+		}
+		copied := *strategy
+		copied.Scenario, copied.ScenarioFile = scenario, ""
+		strategy = &copied
+	}
+	if profile := strategy.LoadProfile; profile != nil {
+		if err := validateLoadProfile(profile); err != nil {
+			return nil, fmt.Errorf("%w: invalid load profile: %v", ErrInvalidStrategy, err)
+		}
+	}
+	if strategy.MaxRate < 0 || strategy.MaxInFlight < 0 {
+		return nil, fmt.Errorf("%w: max_rate and max_in_flight must not be negative", ErrInvalidStrategy)
+	}
+	if err := validateSLOs(strategy.SLOs); err != nil {
+		return nil, fmt.Errorf("%w: invalid SLO: %v", ErrInvalidStrategy, err)
+	}
+	if strategy.BatchSize < 0 {
+		return nil, fmt.Errorf("%w: batch_size must not be negative", ErrInvalidStrategy)
+	}
+	if err := validateExpiry(strategy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStrategy, err)
+	}
+	if strategy.ErrorBudget != nil {
+		if err := validateErrorBudget(strategy.ErrorBudget); err != nil {
+			return nil, fmt.Errorf("%w: invalid error budget: %v", ErrInvalidStrategy, err)
+		}
+	}
+	if err := validateActionMix(strategy.Actions); err != nil {
+		return nil, fmt.Errorf("%w: invalid action mix: %v", ErrInvalidStrategy, err)
+	}
+	if err := validateTargets(strategy.Targets); err != nil {
+		return nil, fmt.Errorf("%w: invalid targets: %v", ErrInvalidStrategy, err)
+	}
+	if strategy.WarmUp != nil {
+		if err := validateWarmUp(strategy.WarmUp); err != nil {
+			return nil, fmt.Errorf("%w: invalid warm-up: %v", ErrInvalidStrategy, err)
+		}
+	}
+	return strategy, nil
+}
 
+// generateSessionJobs creates jobs for a session. Sessions follow the cycle's scenario,
+// or else the action mix of the strategy, or else the action stream of the generator's
+// ActionStrategy when one is configured, and otherwise cycle through the default actions.
+func (s *jobServiceImpl) generateSessionJobs(ctx context.Context, cycle models.Cycle, session models.Session, users []string) ([]models.Job, error) {
+	if cycle.Strategy.Scenario != nil {
+		return s.scenarioJobs(ctx, cycle.Strategy.Scenario, cycle.Seed, session, users)
+	}
 	// Generate jobs based on strategy limits
 	totalJobs := cycle.Strategy.MaxFiles + cycle.Strategy.MaxWorkspaces
-	for i := 0; i < totalJobs; i++ {
-		action := actions[i%len(actions)]
+	var sessionActions []string
+	if len(cycle.Strategy.Actions) > 0 {
+		sessionActions = mixActions(cycle.Strategy.Actions, totalJobs)
+	} else {
+		actions, err := s.generator.GenerateActions(ctx, session.UUID, session.Persona)
+		if err != nil {
+			return nil, err
+		}
+		if len(actions) > 0 {
+			return s.actionJobs(ctx, session, actions), nil
+		}
+		defaultActions := []string{
+			"create_user",
+			"create_workspace",
+			"upload_file", "download_file", "consult_file",
+		}
+		for i := 0; i < totalJobs; i++ {
+			sessionActions = append(sessionActions, defaultActions[i%len(defaultActions)])
+		}
+	}
+
+	var jobs []models.Job
+	for _, action := range sessionActions {
 		inputData := map[string]string{
-			"user_id": session.UserID,
-			"action":  action,
+			"user_id":    session.UserName,
+			"session_id": session.UUID,
+			"action":     action,
 		}
 		inputJSON, err := json.Marshal(inputData)
 		if err != nil {
@@ -178,7 +467,7 @@ func (s *jobServiceImpl) generateSessionJobs(ctx context.Context, cycle models.C
 		}
 
 		job := models.Job{
-			UUID:      uuid.New().String(),
+			UUID:      newID(ctx),
 			Name:      action,
 			InputData: json.RawMessage(inputJSON),
 			Status:    "pending",
@@ -189,101 +478,663 @@ func (s *jobServiceImpl) generateSessionJobs(ctx context.Context, cycle models.C
 	return jobs, nil
 }
 
-// ProcessJobs dispatches pending jobs and processes results
+// actionJobs turns a generated action stream into jobs, keeping the order and think
+// time of every action in its input data
+func (s *jobServiceImpl) actionJobs(ctx context.Context, session models.Session, actions []useraction.UserAction) []models.Job {
+	var jobs []models.Job
+	for _, action := range actions {
+		inputData := map[string]interface{}{
+			"user_id":       session.UserName,
+			"session_id":    session.UUID,
+			"persona":       session.Persona,
+			"action":        action.ActionType,
+			"sequence":      action.Sequence,
+			"think_time_ms": action.ThinkTimeMs,
+		}
+		inputJSON, err := json.Marshal(inputData)
+		if err != nil {
+			s.logger.Error(ctx, "Failed to marshal job input data", "action", action.ActionType, "error", err)
+			continue
+		}
+		jobs = append(jobs, models.Job{
+			UUID:      newID(ctx),
+			Name:      action.ActionType,
+			InputData: json.RawMessage(inputJSON),
+			Status:    "pending",
+		})
+	}
+	return jobs
+}
+
+// dispatchInterval is how often ProcessJobs dispatches pending jobs
+var dispatchInterval = 10 * time.Second
+
+// ProcessJobs consumes job results on one subscription while it expires cycles out of
+// time and dispatches pending jobs every dispatchInterval, until ctx is cancelled and
+// the consumer has drained
 func (s *jobServiceImpl) ProcessJobs(ctx context.Context) error {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	resultCh, err := s.dispatcher.Subscribe(ctx, "dispatcher.job.result")
+	if err != nil {
+		s.logger.Error(ctx, "Failed to subscribe to job results", "error", err)
+		return err
+	}
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		s.consumeResults(ctx, resultCh)
+	}()
+	defer func() { <-consumed }()
 
+	ticker := s.clock.NewTicker(dispatchInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
-			// Fetch pending jobs
-			var jobs []models.Job
-			if err := s.store.GetJobsByStatus(ctx, "pending", &jobs); err != nil {
-				s.logger.Error(ctx, "Failed to fetch pending jobs", "error", err)
-				continue
-			}
+		case <-ticker.C():
+			s.expireCycles(ctx)
+			s.dispatchPending(ctx)
+		}
+	}
+}
 
-			for _, job := range jobs {
-				// Dispatch job
-				if err := s.dispatcher.DispatchJob(ctx, &job); err != nil {
-					s.logger.Error(ctx, "Failed to dispatch job", "job_uuid", job.UUID, "error", err)
-					continue
-				}
+// dispatchPending dispatches the pending jobs of running cycles that are not paced
+func (s *jobServiceImpl) dispatchPending(ctx context.Context) {
+	var jobs []models.Job
+	if err := s.store.GetJobsByStatus(ctx, "pending", &jobs); err != nil {
+		s.logger.Error(ctx, "Failed to fetch pending jobs", "error", err)
+		return
+	}
 
-				// Update job status
-				job.Status = "dispatched"
-				if err := s.store.UpdateJob(ctx, &job); err != nil {
-					s.logger.Error(ctx, "Failed to update job status", "job_uuid", job.UUID, "error", err)
-					continue
+	// Running cycles by UUID; nil for the others
+	running := make(map[string]*models.Cycle)
+	// Jobs waiting for their batch to fill up, by session in order of appearance
+	batches := make(map[string][]models.Job)
+	var batched []string
+	dispatch := func(job models.Job, strategy *models.Strategy) {
+		if strategy == nil || strategy.BatchSize <= 1 || job.DependsOn != "" {
+			s.dispatchJob(ctx, &job, strategy)
+			return
+		}
+		batch, ok := batches[job.SessionID]
+		if !ok {
+			batched = append(batched, job.SessionID)
+		}
+		batch = append(batch, job)
+		if len(batch) == strategy.BatchSize {
+			s.dispatchBatch(ctx, batch, strategy)
+			batch = nil
+		}
+		batches[job.SessionID] = batch
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		cycle, known := running[job.CycleUUID]
+		if !known {
+			var err error
+			if cycle, err = s.store.GetCycle(ctx, job.CycleUUID); err != nil {
+				s.logger.Error(ctx, "Failed to fetch cycle of job", "job_uuid", job.UUID, "cycle_uuid", job.CycleUUID, "error", err)
+				continue
+			}
+			if cycle.Status != "running" && cycle.Phase != "teardown" {
+				cycle = nil
+			} else if cycle.Phase == "warmup" {
+				// Catch up on a warm-up whose last result raced the start of the cycle
+				if loaded, err := s.finishWarmUp(ctx, cycle.UUID); err != nil {
+					s.logger.Error(ctx, "Failed to check cycle warm-up", "cycle_uuid", cycle.UUID, "error", err)
+				} else if loaded {
+					cycle.Phase = "load"
 				}
 			}
+			running[job.CycleUUID] = cycle
+		}
+		// Hold back the jobs of paused cycles and load jobs during the warm-up, and
+		// leave the load of paced cycles to their pacer; teardown jobs run once the
+		// cycle is over
+		switch {
+		case cycle == nil:
+		case job.Phase == "warmup", job.Phase == "teardown":
+			dispatch(job, cycle.Strategy)
+		case cycle.Status != "running", cycle.Phase == "warmup", cycle.Strategy != nil && cycle.Strategy.Paced():
+		default:
+			dispatch(job, cycle.Strategy)
+		}
+	}
+	// Dispatch the batches that did not fill up
+	for _, sessionID := range batched {
+		if batch := batches[sessionID]; len(batch) > 0 && ctx.Err() == nil {
+			s.dispatchBatch(ctx, batch, running[batch[0].CycleUUID].Strategy)
+		}
+	}
+}
+
+// consumeResults saves the job and batch results received on resultCh until it is
+// closed
+func (s *jobServiceImpl) consumeResults(ctx context.Context, resultCh <-chan *nats.Msg) {
+	for msg := range resultCh {
+		var batch models.JobBatch
+		if err := json.Unmarshal(msg.Data, &batch); err == nil && len(batch.Jobs) > 0 {
+			s.handleBatchResult(ctx, batch.Jobs)
+			continue
+		}
+		var job models.Job
+		if err := json.Unmarshal(msg.Data, &job); err != nil {
+			s.logger.Error(ctx, "Failed to unmarshal job result", "error", err)
+			continue
+		}
+		s.handleResult(ctx, &job)
+	}
+}
+
+// handleResult saves the result of a job and updates its session and cycle
+func (s *jobServiceImpl) handleResult(ctx context.Context, job *models.Job) {
+	// Pausing or aborting the cycle already settled the status of cancelled jobs
+	if job.Status == "cancelled" {
+		return
+	}
 
-			// Process job results
-			resultCh, err := s.dispatcher.Subscribe(ctx, "dispatcher.job.result")
+	saved, err := s.saveResults(ctx, []models.Job{*job})
+	if err != nil {
+		s.logger.Error(ctx, "Failed to save job result", "job_uuid", job.UUID, "error", err)
+		return
+	}
+	if len(saved) == 0 {
+		return
+	}
+	job = &saved[0]
+	s.recordActions(ctx, saved)
+	s.settleResult(ctx, job)
+
+	// Check if cycle is complete
+	if err := s.checkCycleCompletion(ctx, job.CycleUUID); err != nil {
+		s.logger.Error(ctx, "Failed to check cycle completion", "cycle_uuid", job.CycleUUID, "error", err)
+	}
+}
+
+// handleBatchResult saves the results of a batch in one transaction, then updates the
+// session and cycle of its jobs like handleResult
+func (s *jobServiceImpl) handleBatchResult(ctx context.Context, jobs []models.Job) {
+	// Pausing or aborting the cycle already settled the status of cancelled jobs
+	jobs = slices.DeleteFunc(jobs, func(job models.Job) bool { return job.Status == "cancelled" })
+	if len(jobs) == 0 {
+		return
+	}
+	saved, err := s.saveResults(ctx, jobs)
+	if err != nil {
+		s.logger.Error(ctx, "Failed to save job batch results", "jobs", len(jobs), "session_id", jobs[0].SessionID, "error", err)
+		return
+	}
+	if len(saved) == 0 {
+		return
+	}
+	s.recordActions(ctx, saved)
+	for i := range saved {
+		s.settleResult(ctx, &saved[i])
+	}
+	if err := s.checkCycleCompletion(ctx, saved[0].CycleUUID); err != nil {
+		s.logger.Error(ctx, "Failed to check cycle completion", "cycle_uuid", saved[0].CycleUUID, "error", err)
+	}
+}
+
+// maxSaveAttempts bounds the attempts to save results of jobs that keep changing
+const maxSaveAttempts = 3
+
+// saveResults copies the results workers sent onto the saved jobs and saves them in one
+// transaction, reading the jobs again when one changed meanwhile. Results of jobs that
+// are no longer in flight, e.g. cancelled with their cycle, are dropped. It returns the
+// jobs saved.
+func (s *jobServiceImpl) saveResults(ctx context.Context, results []models.Job) ([]models.Job, error) {
+	for attempt := 1; ; attempt++ {
+		jobs := make([]models.Job, 0, len(results))
+		for _, result := range results {
+			job, err := s.store.GetJob(ctx, result.UUID)
 			if err != nil {
-				s.logger.Error(ctx, "Failed to subscribe to job results", "error", err)
+				return nil, err
+			}
+			// The result may beat the dispatch of the job to the store
+			if job.Status != "pending" && job.Status != "dispatched" && job.Status != "processing" {
+				s.logger.Info(ctx, "Dropped result of job no longer in flight", "job_uuid", job.UUID, "status", job.Status, "result", result.Status)
 				continue
 			}
-			go func() {
-				for msg := range resultCh {
-					var job models.Job
-					if err := json.Unmarshal(msg.Data, &job); err != nil {
-						s.logger.Error(ctx, "Failed to unmarshal job result", "error", err)
-						continue
-					}
-
-					// Update job result in database
-					if err := s.store.UpdateJob(ctx, &job); err != nil {
-						s.logger.Error(ctx, "Failed to save job result", "job_uuid", job.UUID, "error", err)
-						continue
-					}
-
-					s.logger.Info(ctx, "Job result processed", "job_uuid", job.UUID, "status", job.Status)
-
-					// Check if cycle is complete
-					if err := s.checkCycleCompletion(ctx, job.CycleUUID); err != nil {
-						s.logger.Error(ctx, "Failed to check cycle completion", "cycle_uuid", job.CycleUUID, "error", err)
-					}
-				}
-			}()
+			job.Status, job.WorkerID, job.OutputData, job.Error = result.Status, result.WorkerID, result.OutputData, result.Error
+			job.StartAt, job.DoneAt, job.DurationMs = result.StartAt, result.DoneAt, result.DurationMs
+			jobs = append(jobs, *job)
 		}
+		var err error
+		switch len(jobs) {
+		case 0:
+			return nil, nil
+		case 1:
+			err = s.store.UpdateJob(ctx, &jobs[0])
+		default:
+			err = s.store.UpdateJobs(ctx, jobs)
+		}
+		if errors.Is(err, store.ErrConflict) && attempt < maxSaveAttempts {
+			continue
+		}
+		return jobs, err
+	}
+}
+
+// recordActions adds the saved results of jobs to the audit trail of their sessions
+func (s *jobServiceImpl) recordActions(ctx context.Context, jobs []models.Job) {
+	actions := make([]models.UserAction, len(jobs))
+	for i, job := range jobs {
+		actions[i] = models.NewUserAction(job)
+	}
+	if err := s.store.CreateUserActions(ctx, actions); err != nil {
+		s.logger.Error(ctx, "Failed to record user actions", "session_id", jobs[0].SessionID, "actions", len(actions), "error", err)
+	}
+}
+
+// settleResult updates the session, phase and statistics of the cycle of a saved job
+// result
+func (s *jobServiceImpl) settleResult(ctx context.Context, job *models.Job) {
+	s.logger.Info(ctx, "Job result processed", "job_uuid", job.UUID, "status", job.Status)
+
+	if err := s.trackSession(ctx, job); err != nil {
+		s.logger.Error(ctx, "Failed to update session", "session_id", job.SessionID, "error", err)
+	}
+	s.runners.notify(job.SessionID)
+	// Warm-up and teardown results are left out of the statistics of the cycle
+	switch job.Phase {
+	case "warmup":
+		if _, err := s.finishWarmUp(ctx, job.CycleUUID); err != nil {
+			s.logger.Error(ctx, "Failed to check cycle warm-up", "cycle_uuid", job.CycleUUID, "error", err)
+		}
+	case "teardown":
+		if err := s.finishTeardown(ctx, job.CycleUUID); err != nil {
+			s.logger.Error(ctx, "Failed to check cycle teardown", "cycle_uuid", job.CycleUUID, "error", err)
+		}
+	default:
+		s.results.Record(job)
+		if err := s.evaluateSLOs(ctx, job); err != nil {
+			s.logger.Error(ctx, "Failed to evaluate cycle SLOs", "cycle_uuid", job.CycleUUID, "error", err)
+		}
+		if err := s.evaluateErrorBudget(ctx, job); err != nil {
+			s.logger.Error(ctx, "Failed to evaluate cycle error budget", "cycle_uuid", job.CycleUUID, "error", err)
+		}
+	}
+}
+
+// dispatchJob dispatches a pending job to the worker pool of its target in its cycle's
+// strategy and marks it dispatched, unless it depends on a job that has not finished yet; it tells
+// whether the job was dispatched
+func (s *jobServiceImpl) dispatchJob(ctx context.Context, job *models.Job, strategy *models.Strategy) bool {
+	// Keep scenario jobs in order within their session
+	if job.DependsOn != "" && !s.jobFinished(ctx, job.DependsOn) {
+		return false
+	}
+
+	// Dispatch job
+	if err := s.dispatcher.DispatchJobToPool(ctx, job, poolLabels(strategy, job.Target)); err != nil {
+		s.logger.Error(ctx, "Failed to dispatch job", "job_uuid", job.UUID, "error", err)
+		return false
+	}
+
+	// Update job status, unless its result or the cancellation of its cycle came first
+	job.Status = "dispatched"
+	if err := s.store.UpdateJob(ctx, job); err != nil && !errors.Is(err, store.ErrConflict) {
+		s.logger.Error(ctx, "Failed to update job status", "job_uuid", job.UUID, "error", err)
+	}
+	return true
+}
+
+// dispatchBatch dispatches pending independent jobs of a session as one batch to the
+// worker pool of its target and marks them dispatched in one transaction
+func (s *jobServiceImpl) dispatchBatch(ctx context.Context, jobs []models.Job, strategy *models.Strategy) {
+	if len(jobs) == 1 {
+		s.dispatchJob(ctx, &jobs[0], strategy)
+		return
+	}
+	if err := s.dispatcher.DispatchBatchToPool(ctx, jobs, poolLabels(strategy, jobs[0].Target)); err != nil {
+		s.logger.Error(ctx, "Failed to dispatch job batch", "session_id", jobs[0].SessionID, "jobs", len(jobs), "error", err)
+		return
+	}
+	for i := range jobs {
+		jobs[i].Status = "dispatched"
+	}
+	err := s.store.UpdateJobs(ctx, jobs)
+	if errors.Is(err, store.ErrConflict) {
+		// Some results came first: mark the others one by one
+		for i := range jobs {
+			if err = s.store.UpdateJob(ctx, &jobs[i]); err != nil && !errors.Is(err, store.ErrConflict) {
+				break
+			}
+			err = nil
+		}
+	}
+	if err != nil {
+		s.logger.Error(ctx, "Failed to update job batch status", "session_id", jobs[0].SessionID, "error", err)
+	}
+}
+
+// jobFinished tells whether the job completed, failed or was cancelled
+func (s *jobServiceImpl) jobFinished(ctx context.Context, jobUUID string) bool {
+	job, err := s.store.GetJob(ctx, jobUUID)
+	if err != nil {
+		s.logger.Error(ctx, "Failed to fetch job dependency", "job_uuid", jobUUID, "error", err)
+		return false
+	}
+	return job.Status == "completed" || job.Status == "failed" || job.Status == "cancelled"
+}
+
+// cycleProgress counts the jobs of cycle by status and extrapolates when the last one
+// finishes from the pace of those finished so far
+func (s *jobServiceImpl) cycleProgress(ctx context.Context, cycle *models.Cycle) (models.CycleProgress, error) {
+	counts, err := s.store.CountJobsByStatus(ctx, cycle.UUID)
+	if err != nil {
+		return models.CycleProgress{}, err
+	}
+	return progressOf(cycle, counts, s.clock.Now().Unix()), nil
+}
+
+// progressOf turns the job counts of cycle per status into its progress at now
+func progressOf(cycle *models.Cycle, counts map[string]int, now int64) models.CycleProgress {
+	var progress models.CycleProgress
+	for _, n := range counts {
+		progress.TotalJobs += n
+	}
+	progress.CompletedJobs = counts["completed"]
+	progress.FailedJobs = counts["failed"]
+	progress.CancelledJobs = counts["cancelled"]
+
+	finished := progress.CompletedJobs + progress.FailedJobs
+	if finished > 0 && progress.Remaining() > 0 {
+		elapsed := now - cycle.StartedAt
+		progress.EstimatedDoneAt = now + elapsed*int64(progress.Remaining())/int64(finished)
+	}
+	return progress
+}
+
+// CycleProgress reports the job counts and estimated finish time of a cycle
+func (s *jobServiceImpl) CycleProgress(ctx context.Context, cycleUUID string) (models.CycleProgress, error) {
+	cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if err != nil {
+		return models.CycleProgress{}, err
+	}
+	if cycle.Status != "running" && cycle.Status != "paused" {
+		return cycle.Progress, nil
+	}
+	return s.cycleProgress(ctx, cycle)
+}
+
+// CyclesProgress refreshes the progress of the running and paused cycles among cycles
+// with one count of their jobs
+func (s *jobServiceImpl) CyclesProgress(ctx context.Context, cycles []models.Cycle) error {
+	var unfinished []string
+	for _, cycle := range cycles {
+		if cycle.Status == "running" || cycle.Status == "paused" {
+			unfinished = append(unfinished, cycle.UUID)
+		}
+	}
+	if len(unfinished) == 0 {
+		return nil
+	}
+	counts, err := s.store.CountJobsByCycle(ctx, unfinished)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now().Unix()
+	for i := range cycles {
+		if cycle := &cycles[i]; cycle.Status == "running" || cycle.Status == "paused" {
+			cycle.Progress = progressOf(cycle, counts[cycle.UUID], now)
+		}
+	}
+	return nil
+}
+
+// setCycleStatus moves a cycle from one of the from statuses to status
+func (s *jobServiceImpl) setCycleStatus(ctx context.Context, cycleUUID, status string, from ...string) (*models.Cycle, error) {
+	cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, cycle.Status) {
+		return nil, fmt.Errorf("%w: cycle %s is %s, expected %s", ErrCycleState, cycleUUID, cycle.Status, strings.Join(from, " or "))
+	}
+	cycle.Status = status
+	if err := s.store.UpdateCycleStatus(ctx, cycle, from); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return nil, fmt.Errorf("%w: cycle %s changed while moving to %s", ErrCycleState, cycleUUID, status)
+		}
+		return nil, err
+	}
+	return cycle, nil
+}
+
+// PauseCycle stops dispatching the jobs of a running cycle and cancels those in
+// flight, putting them back in line for ResumeCycle
+func (s *jobServiceImpl) PauseCycle(ctx context.Context, cycleUUID string) error {
+	if _, err := s.setCycleStatus(ctx, cycleUUID, "paused", "running"); err != nil {
+		return err
+	}
+	if err := s.dispatcher.CancelCycle(ctx, cycleUUID); err != nil {
+		return err
+	}
+	requeued, err := s.store.UpdateCycleJobsStatus(ctx, cycleUUID, []string{"dispatched", "processing"}, "pending")
+	if err != nil {
+		return err
+	}
+	s.logger.Info(ctx, "Cycle paused", "cycle_uuid", cycleUUID, "requeued_jobs", requeued)
+	return nil
+}
+
+// ResumeCycle dispatches the jobs of a paused cycle again
+func (s *jobServiceImpl) ResumeCycle(ctx context.Context, cycleUUID string) error {
+	if _, err := s.setCycleStatus(ctx, cycleUUID, "running", "paused"); err != nil {
+		return err
+	}
+	s.logger.Info(ctx, "Cycle resumed", "cycle_uuid", cycleUUID)
+	return nil
+}
+
+// RetryJob resets a failed or cancelled job to pending so it is dispatched again
+func (s *jobServiceImpl) RetryJob(ctx context.Context, jobUUID string) error {
+	job, err := s.store.GetJob(ctx, jobUUID)
+	if err != nil {
+		return err
+	}
+	if job.Status != "failed" && job.Status != "cancelled" {
+		return fmt.Errorf("%w: job %s is %s, expected failed or cancelled", ErrJobState, jobUUID, job.Status)
+	}
+	cycle, err := s.store.GetCycle(ctx, job.CycleUUID)
+	if err != nil {
+		return err
+	}
+	if cycle.Status != "running" && cycle.Status != "paused" {
+		return fmt.Errorf("%w: cycle %s is %s, expected running or paused", ErrCycleState, cycle.UUID, cycle.Status)
+	}
+
+	job.Status = "pending"
+	job.WorkerID, job.Error, job.OutputData = "", "", nil
+	job.StartAt, job.DoneAt, job.DurationMs, job.DispatchedAt = 0, 0, 0, 0
+	if err := s.store.UpdateJob(ctx, job); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return fmt.Errorf("%w: job %s changed while being retried", ErrJobState, jobUUID)
+		}
+		return err
 	}
+	s.logger.Info(ctx, "Job retried", "job_uuid", jobUUID, "cycle_uuid", cycle.UUID)
+	return nil
 }
 
-// checkCycleCompletion checks if all jobs in a cycle are complete
+// AbortCycle cancels every unfinished job of a running or paused cycle and closes it
+func (s *jobServiceImpl) AbortCycle(ctx context.Context, cycleUUID string) error {
+	return s.stopCycle(ctx, cycleUUID, "aborted", "aborted on request")
+}
+
+// stopCycle cancels every unfinished job of a running or paused cycle and closes it
+// with status, for reason
+func (s *jobServiceImpl) stopCycle(ctx context.Context, cycleUUID, status, reason string) error {
+	cycle, err := s.setCycleStatus(ctx, cycleUUID, status, "running", "paused")
+	if err != nil {
+		return err
+	}
+	cycle.Reason = reason
+	return s.cancelCycle(ctx, cycle)
+}
+
+// cancelCycle cancels every unfinished job and open session of a stopped cycle and
+// closes it
+func (s *jobServiceImpl) cancelCycle(ctx context.Context, cycle *models.Cycle) error {
+	cycleUUID := cycle.UUID
+	if err := s.dispatcher.CancelCycle(ctx, cycleUUID); err != nil {
+		return err
+	}
+	if _, err := s.store.UpdateCycleJobsStatus(ctx, cycleUUID, []string{"pending", "dispatched", "processing"}, "cancelled"); err != nil {
+		return err
+	}
+	if _, err := s.store.UpdateCycleSessionsStatus(ctx, cycleUUID, []string{"planned", "active"}, "cancelled"); err != nil {
+		return err
+	}
+
+	cycle.DoneAt = s.clock.Now().Unix()
+	progress, err := s.cycleProgress(ctx, cycle)
+	if err != nil {
+		return err
+	}
+	cycle.Progress = progress
+	if err := s.store.UpdateCycleColumns(ctx, cycle, append([]string{"reason", "done_at"}, store.CycleProgressColumns...)...); err != nil {
+		return err
+	}
+	s.slos.forget(cycleUUID)
+	s.budgets.forget(cycleUUID)
+	s.logger.Info(ctx, "Cycle stopped", "cycle_uuid", cycleUUID, "status", cycle.Status, "reason", cycle.Reason,
+		"cancelled_jobs", cycle.Progress.CancelledJobs)
+	s.notifyCycle(ctx, notify.CycleFinished, cycle, nil)
+
+	if err := s.recordLedger(ctx, cycle); err != nil {
+		s.logger.Error(ctx, "Failed to append cycle to run ledger", "cycle_uuid", cycleUUID, "error", err)
+	}
+	s.teardown(ctx, cycle)
+	return nil
+}
+
+// trackSession moves the session of a finished job along its lifecycle: it is active
+// from the start of its earliest job and ends once none of its jobs remain. Live
+// sessions are left to their scheduler.
+func (s *jobServiceImpl) trackSession(ctx context.Context, job *models.Job) error {
+	session, err := s.store.GetSession(ctx, job.SessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status == "ended" || session.Status == "cancelled" || len(session.Plan) > 0 {
+		return nil
+	}
+	session.Status = "active"
+	if session.StartedAt == 0 || job.StartAt < session.StartedAt {
+		session.StartedAt = job.StartAt
+	}
+	counts, err := s.store.CountSessionJobsByStatus(ctx, session.UUID)
+	if err != nil {
+		return err
+	}
+	if counts["pending"]+counts["dispatched"]+counts["processing"] == 0 {
+		session.Status = "ended"
+		session.EndedAt = job.DoneAt
+	}
+	return s.store.UpdateSession(ctx, session)
+}
+
+// checkCycleCompletion records the progress of a running cycle and completes it once
+// none of its jobs remain and its live sessions, if any, ended
 func (s *jobServiceImpl) checkCycleCompletion(ctx context.Context, cycleUUID string) error {
-	var pendingJobs []models.Job
-	if err := s.store.GetJobsByStatus(ctx, "pending", &pendingJobs); err != nil {
+	cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if err != nil {
 		return err
 	}
-	var dispatchedJobs []models.Job
-	if err := s.store.GetJobsByStatus(ctx, "dispatched", &dispatchedJobs); err != nil {
+	if cycle.Status == "expired" && cycle.DoneAt == 0 {
+		return s.finishDrain(ctx, cycle, false)
+	}
+	if cycle.Status != "running" {
+		return nil
+	}
+	if cycle.Progress, err = s.cycleProgress(ctx, cycle); err != nil {
 		return err
 	}
 
-	if len(pendingJobs)+len(dispatchedJobs) == 0 {
-		cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if cycle.Progress.Remaining() > 0 {
+		return s.saveRunningProgress(ctx, cycle)
+	}
+	if cycle.Strategy != nil && cycle.Strategy.LiveSessions {
+		open, err := s.store.GetCycleSessionsByStatus(ctx, cycleUUID, "planned", "active")
 		if err != nil {
 			return err
 		}
-		cycle.Status = "completed"
-		cycle.DoneAt = time.Now().Unix()
-		if err := s.store.UpdateCycle(ctx, cycle); err != nil {
-			return err
+		if len(open) > 0 {
+			return s.saveRunningProgress(ctx, cycle)
 		}
-		s.logger.Info(ctx, "Cycle completed", "cycle_uuid", cycleUUID)
 	}
+	cycle.Status = "completed"
+	if len(cycle.SLOBreaches) > 0 {
+		cycle.Status = "failed_slo"
+	}
+	cycle.DoneAt = s.clock.Now().Unix()
+	cycle.Progress.EstimatedDoneAt = cycle.DoneAt
+	if err := s.store.UpdateCycleStatus(ctx, cycle, []string{"running"}, append([]string{"done_at"}, store.CycleProgressColumns...)...); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			// Aborted, paused or expired since it was read, which closes it otherwise
+			return nil
+		}
+		return err
+	}
+	s.slos.forget(cycleUUID)
+	s.budgets.forget(cycleUUID)
+	s.logger.Info(ctx, "Cycle completed", "cycle_uuid", cycleUUID, "status", cycle.Status,
+		"completed_jobs", cycle.Progress.CompletedJobs, "failed_jobs", cycle.Progress.FailedJobs)
+	s.notifyCycle(ctx, notify.CycleFinished, cycle, nil)
 
+	if err := s.recordLedger(ctx, cycle); err != nil {
+		s.logger.Error(ctx, "Failed to append cycle to run ledger", "cycle_uuid", cycleUUID, "error", err)
+	}
+	s.teardown(ctx, cycle)
 	return nil
 }
 
+// saveRunningProgress saves the progress of a cycle as long as it is still running, so
+// it does not overwrite the progress a pause or an abort saved since it was counted
+func (s *jobServiceImpl) saveRunningProgress(ctx context.Context, cycle *models.Cycle) error {
+	err := s.store.UpdateCycleStatus(ctx, cycle, []string{"running"}, store.CycleProgressColumns...)
+	if errors.Is(err, store.ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+// notifyCycle sends a cycle event; the notification gets its own copy of the cycle
+func (s *jobServiceImpl) notifyCycle(ctx context.Context, eventType string, cycle *models.Cycle, breaches []models.SLOBreach) {
+	c := *cycle
+	s.notifier.Notify(ctx, notify.Event{Type: eventType, Cycle: &c, Breaches: breaches})
+}
+
+// recordLedger appends the cycle summary and its job KPIs to the run ledger
+func (s *jobServiceImpl) recordLedger(ctx context.Context, cycle *models.Cycle) error {
+	record := ledger.Record{
+		CycleUUID: cycle.UUID,
+		CycleName: cycle.Name,
+		Template:  cycle.Template,
+		Verdict:   cycle.Status,
+		Reason:    cycle.Reason,
+		StartedAt: cycle.StartedAt,
+		DoneAt:    cycle.DoneAt,
+
+		TotalJobs:     cycle.Progress.TotalJobs,
+		CompletedJobs: cycle.Progress.CompletedJobs,
+		FailedJobs:    cycle.Progress.FailedJobs,
+	}
+	if elapsed := cycle.DoneAt - cycle.StartedAt; elapsed > 0 {
+		record.Throughput = float64(record.TotalJobs) / float64(elapsed)
+	}
+	return s.ledger.Append(ctx, record)
+}
+
 // Module defines the Fx module for the JobService
 var Module = fx.Module(
 	"job",
-	fx.Provide(NewJobService),
+	fx.Provide(NewJobService, NewSystemClock),
 	fx.Invoke(func(s JobService) {
 		// Ensure JobService is instantiated
 		//logger.Logger.Info(context.Background(), "JobService module initialized")
@@ -0,0 +1,165 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/songvi/robo/aggregator"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/notify"
+)
+
+// defaultSLOPercentile and defaultSLOMinSamples apply when an SLO leaves them unset
+const (
+	defaultSLOPercentile = 95
+	defaultSLOMinSamples = 10
+)
+
+// validateSLOs checks the SLOs of a strategy
+func validateSLOs(slos []models.SLO) error {
+	for i, slo := range slos {
+		if slo.MaxLatencyMs <= 0 && slo.MaxErrorRate <= 0 {
+			return fmt.Errorf("slo %d (%s): needs max_latency_ms or max_error_rate", i, slo.Action)
+		}
+		if slo.Percentile < 0 || slo.Percentile > 100 {
+			return fmt.Errorf("slo %d (%s): percentile %v is outside [0, 100]", i, slo.Action, slo.Percentile)
+		}
+		if slo.MaxErrorRate < 0 || slo.MaxErrorRate > 1 || slo.MinSamples < 0 {
+			return fmt.Errorf("slo %d (%s): invalid error rate or sample count", i, slo.Action)
+		}
+	}
+	return nil
+}
+
+// actionSamples are the results of the finished jobs of one action
+type actionSamples struct {
+	durations aggregator.Histogram // Milliseconds
+	failed    int
+}
+
+// cycleSamples are the job results of one cycle
+type cycleSamples struct {
+	actions  map[string]*actionSamples
+	breached map[int]bool // Indexes of the SLOs already breached
+}
+
+// sloTracker keeps the job results of running cycles to evaluate their SLOs. Results
+// are kept in memory, so a restarted service evaluates from the results it sees.
+type sloTracker struct {
+	mu     sync.Mutex
+	cycles map[string]*cycleSamples
+}
+
+func newSLOTracker() *sloTracker {
+	return &sloTracker{cycles: make(map[string]*cycleSamples)}
+}
+
+// record adds the result of a finished job at now and returns the SLOs it newly breaches
+func (t *sloTracker) record(job *models.Job, slos []models.SLO, now time.Time) []models.SLOBreach {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.cycles[job.CycleUUID]
+	if !ok {
+		c = &cycleSamples{actions: make(map[string]*actionSamples), breached: make(map[int]bool)}
+		t.cycles[job.CycleUUID] = c
+	}
+	a, ok := c.actions[job.Name]
+	if !ok {
+		a = &actionSamples{}
+		c.actions[job.Name] = a
+	}
+	a.durations.Record(job.DurationMs)
+	if job.Status == "failed" {
+		a.failed++
+	}
+
+	var breaches []models.SLOBreach
+	for i, slo := range slos {
+		if c.breached[i] || (slo.Action != "" && slo.Action != job.Name) {
+			continue
+		}
+		if breach, ok := c.evaluate(slo); ok {
+			c.breached[i] = true
+			breach.At = now.Unix()
+			breaches = append(breaches, breach)
+		}
+	}
+	return breaches
+}
+
+// evaluate reports whether slo is breached by the results so far
+func (c *cycleSamples) evaluate(slo models.SLO) (models.SLOBreach, bool) {
+	var durations aggregator.Histogram
+	failed := 0
+	for action, a := range c.actions {
+		if slo.Action == "" || slo.Action == action {
+			durations.Merge(&a.durations)
+			failed += a.failed
+		}
+	}
+	minSamples := slo.MinSamples
+	if minSamples == 0 {
+		minSamples = defaultSLOMinSamples
+	}
+	samples := int(durations.Count())
+	if samples < minSamples {
+		return models.SLOBreach{}, false
+	}
+
+	if slo.MaxErrorRate > 0 {
+		if rate := float64(failed) / float64(samples); rate > slo.MaxErrorRate {
+			return models.SLOBreach{SLO: slo, Metric: "error_rate", Value: rate, Threshold: slo.MaxErrorRate, Samples: samples}, true
+		}
+	}
+	if slo.MaxLatencyMs > 0 {
+		p := slo.Percentile
+		if p == 0 {
+			p = defaultSLOPercentile
+		}
+		if latency := durations.Quantile(p / 100); latency > slo.MaxLatencyMs {
+			return models.SLOBreach{SLO: slo, Metric: "latency", Value: float64(latency), Threshold: float64(slo.MaxLatencyMs), Samples: samples}, true
+		}
+	}
+	return models.SLOBreach{}, false
+}
+
+// forget drops the results of a cycle that is over
+func (t *sloTracker) forget(cycleUUID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.cycles, cycleUUID)
+}
+
+// evaluateSLOs records the result of a finished job against the SLOs of its cycle; a
+// breach is saved with the cycle and stops it when the strategy says so
+func (s *jobServiceImpl) evaluateSLOs(ctx context.Context, job *models.Job) error {
+	if job.Status != "completed" && job.Status != "failed" {
+		return nil
+	}
+	cycle, err := s.store.GetCycle(ctx, job.CycleUUID)
+	if err != nil {
+		return err
+	}
+	if cycle.Strategy == nil || len(cycle.Strategy.SLOs) == 0 || (cycle.Status != "running" && cycle.Status != "paused") {
+		return nil
+	}
+	breaches := s.slos.record(job, cycle.Strategy.SLOs, s.clock.Now())
+	if len(breaches) == 0 {
+		return nil
+	}
+	for _, b := range breaches {
+		s.logger.Error(ctx, "Cycle SLO breached", "cycle_uuid", cycle.UUID, "action", b.SLO.Action,
+			"metric", b.Metric, "value", b.Value, "threshold", b.Threshold)
+	}
+	cycle.SLOBreaches = append(cycle.SLOBreaches, breaches...)
+	if err := s.store.UpdateCycleColumns(ctx, cycle, "slo_breaches"); err != nil {
+		return err
+	}
+	s.notifyCycle(ctx, notify.SLOBreached, cycle, breaches)
+	if cycle.Strategy.StopOnSLOBreach {
+		return s.stopCycle(ctx, cycle.UUID, "failed_slo", "stopped on SLO breach")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestSLOTracker(t *testing.T) {
+	slos := []models.SLO{
+		{Action: "upload_file", MaxLatencyMs: 2000},
+		{MaxErrorRate: 0.1, MinSamples: 20},
+	}
+	require.NoError(t, validateSLOs(slos))
+	tracker := newSLOTracker()
+
+	// 19 fast uploads then a slow one keep the p95 under 2s
+	for i := 0; i < 20; i++ {
+		job := &models.Job{CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: 300}
+		if i == 19 {
+			job.DurationMs = 9000
+		}
+		assert.Empty(t, tracker.record(job, slos, time.Now()))
+	}
+	// A second slow upload moves the p95 to 9s
+	breaches := tracker.record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: 9000}, slos, time.Now())
+	require.Len(t, breaches, 1)
+	assert.Equal(t, "latency", breaches[0].Metric)
+	assert.InEpsilon(t, 9000.0, breaches[0].Value, 0.01)
+	assert.Empty(t, tracker.record(&models.Job{CycleUUID: "c1", Name: "upload_file", Status: "completed", DurationMs: 9000}, slos, time.Now()),
+		"an SLO is reported once")
+
+	// Failures of any action count toward the error rate
+	var errorBreaches []models.SLOBreach
+	for i := 0; i < 3 && len(errorBreaches) == 0; i++ {
+		errorBreaches = tracker.record(&models.Job{CycleUUID: "c1", Name: "download_file", Status: "failed"}, slos, time.Now())
+	}
+	require.Len(t, errorBreaches, 1)
+	assert.Equal(t, "error_rate", errorBreaches[0].Metric)
+
+	tracker.forget("c1")
+	assert.Empty(t, tracker.cycles)
+
+	assert.Error(t, validateSLOs([]models.SLO{{Action: "upload_file"}}))
+	assert.Error(t, validateSLOs([]models.SLO{{MaxLatencyMs: 10, Percentile: 120}}))
+}
@@ -0,0 +1,69 @@
+package job
+
+import (
+	"fmt"
+	"maps"
+	"math"
+
+	"github.com/songvi/robo/models"
+)
+
+// validateTargets checks the targets of a strategy: named once each, with percents
+// adding up to 100
+func validateTargets(targets []models.Target) error {
+	seen := make(map[string]bool, len(targets))
+	total := 0.0
+	for i, t := range targets {
+		if t.Name == "" {
+			return fmt.Errorf("target %d has no name", i)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("target %s is listed twice", t.Name)
+		}
+		seen[t.Name] = true
+		if !(t.Percent > 0) || math.IsInf(t.Percent, 0) {
+			return fmt.Errorf("target %s: percent must be positive", t.Name)
+		}
+		if len(t.WorkerLabels) == 0 {
+			return fmt.Errorf("target %s: worker_labels bind the target to its workers and must be set", t.Name)
+		}
+		total += t.Percent
+	}
+	if len(targets) > 0 && math.Abs(total-100) > 1e-6 {
+		return fmt.Errorf("percents add up to %g instead of 100", total)
+	}
+	return nil
+}
+
+// sessionTargets returns the target of each of n sessions, sharing them by percent and
+// interleaving the targets so each gets users of every part of the cycle; nil without
+// targets
+func sessionTargets(targets []models.Target, n int) []string {
+	if len(targets) == 0 {
+		return nil
+	}
+	mix := make([]models.ActionMix, len(targets))
+	for i, t := range targets {
+		mix[i] = models.ActionMix{Action: t.Name, Weight: t.Percent}
+	}
+	return mixActions(mix, n)
+}
+
+// poolLabels returns the labels of the workers running the jobs of target: those of
+// strategy, and those of target when it is one of strategy's
+func poolLabels(strategy *models.Strategy, target string) map[string]string {
+	if strategy == nil {
+		return nil
+	}
+	for _, t := range strategy.Targets {
+		if t.Name == target {
+			labels := maps.Clone(strategy.WorkerLabels)
+			if labels == nil {
+				labels = make(map[string]string, len(t.WorkerLabels))
+			}
+			maps.Copy(labels, t.WorkerLabels)
+			return labels
+		}
+	}
+	return strategy.WorkerLabels
+}
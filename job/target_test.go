@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+type targetDispatcher struct {
+	dispatcher.Dispatcher
+	labels map[string]map[string]string // Job UUID -> labels
+}
+
+func (d *targetDispatcher) DispatchJobToPool(ctx context.Context, job *models.Job, labels map[string]string) error {
+	d.labels[job.UUID] = labels
+	return nil
+}
+
+func TestTargets(t *testing.T) {
+	a := models.Target{Name: "a", Percent: 70, WorkerLabels: map[string]string{"env": "blue"}}
+	b := models.Target{Name: "b", Percent: 30, WorkerLabels: map[string]string{"env": "green"}}
+	assert.NoError(t, validateTargets([]models.Target{a, b}))
+	assert.NoError(t, validateTargets(nil))
+	assert.Error(t, validateTargets([]models.Target{a, a}), "targets are named once")
+	assert.Error(t, validateTargets([]models.Target{a}), "percents add up to 100")
+	assert.Error(t, validateTargets([]models.Target{{Name: "a", Percent: 100}}), "targets are bound to workers")
+
+	targets := sessionTargets([]models.Target{a, b}, 10)
+	assert.Equal(t, []string{"a", "b", "a", "a", "a", "b", "a", "a", "b", "a"}, targets)
+	assert.Nil(t, sessionTargets(nil, 10))
+
+	strategy := &models.Strategy{WorkerLabels: map[string]string{"region": "eu"}, Targets: []models.Target{a, b}}
+	d := &targetDispatcher{labels: make(map[string]map[string]string)}
+	s := &jobServiceImpl{clock: NewSystemClock(), store: &batchStore{}, dispatcher: d, logger: logger.NewSlogLogger()}
+	s.dispatchJob(context.Background(), &models.Job{UUID: "j1", Target: "b"}, strategy)
+	s.dispatchJob(context.Background(), &models.Job{UUID: "j2"}, strategy)
+	assert.Equal(t, map[string]string{"region": "eu", "env": "green"}, d.labels["j1"])
+	assert.Equal(t, map[string]string{"region": "eu"}, d.labels["j2"])
+	assert.Equal(t, map[string]string{"region": "eu"}, strategy.WorkerLabels, "the strategy labels are left untouched")
+}
@@ -0,0 +1,154 @@
+package job
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/songvi/robo/models"
+)
+
+// teardownKinds pairs the actions creating entities on the target with the actions
+// deleting them and the input key the entity travels under, in teardown order: files
+// go before the workspaces holding them and workspaces before their users
+var teardownKinds = []struct {
+	create, delete, key string
+}{
+	{"upload_file", "delete_file", "file"},
+	{"create_workspace", "delete_workspace", "workspace"},
+	{"create_user", "delete_user", "user"},
+}
+
+// teardownJobs returns, per session in session order, the jobs deleting what the
+// created jobs created: files, then workspaces, then users, each kind latest first
+func teardownJobs(created []models.Job) [][]models.Job {
+	created = slices.Clone(created)
+	slices.SortStableFunc(created, func(a, b models.Job) int {
+		return cmp.Or(cmp.Compare(a.SessionID, b.SessionID), cmp.Compare(b.DoneAt, a.DoneAt))
+	})
+
+	var sessions [][]models.Job
+	for start := 0; start < len(created); {
+		end := start
+		for end < len(created) && created[end].SessionID == created[start].SessionID {
+			end++
+		}
+		var jobs []models.Job
+		for _, kind := range teardownKinds {
+			for _, job := range created[start:end] {
+				if job.Name != kind.create {
+					continue
+				}
+				jobs = append(jobs, deleteJob(job, kind.delete, kind.key))
+			}
+		}
+		if len(jobs) > 0 {
+			sessions = append(sessions, jobs)
+		}
+		start = end
+	}
+	return sessions
+}
+
+// deleteJob returns the job deleting what job created, with the entity and the
+// credentials of its input. Its UUID derives from that of job, so the teardown of a
+// replayed cycle has the same ones.
+func deleteJob(job models.Job, action, key string) models.Job {
+	var created map[string]interface{}
+	json.Unmarshal(job.InputData, &created)
+	input := map[string]interface{}{
+		"action":     action,
+		"phase":      "teardown",
+		"created_by": job.UUID,
+	}
+	for _, k := range []string{key, "credentials", "user_id", "session_id"} {
+		if v, ok := created[k]; ok {
+			input[k] = v
+		}
+	}
+	inputJSON, _ := json.Marshal(input)
+	return models.Job{
+		UUID:      uuid.NewSHA1(uuid.NameSpaceOID, []byte(job.UUID+"/"+action)).String(),
+		Name:      action,
+		InputData: json.RawMessage(inputJSON),
+		Status:    "pending",
+		CycleUUID: job.CycleUUID,
+		SessionID: job.SessionID,
+		Phase:     "teardown",
+		Target:    job.Target,
+	}
+}
+
+// teardown starts the teardown of a cycle that is over when its strategy asks for one
+func (s *jobServiceImpl) teardown(ctx context.Context, cycle *models.Cycle) {
+	if cycle.Strategy == nil || !cycle.Strategy.Teardown {
+		return
+	}
+	if err := s.startTeardown(ctx, cycle); err != nil {
+		s.logger.Error(ctx, "Failed to start cycle teardown", "cycle_uuid", cycle.UUID, "error", err)
+	}
+}
+
+// startTeardown saves the jobs deleting what the completed jobs of the cycle created on
+// the target. The jobs of a session run one after the other; those of different
+// sessions run side by side.
+func (s *jobServiceImpl) startTeardown(ctx context.Context, cycle *models.Cycle) error {
+	created, err := s.store.GetCycleJobsByStatus(ctx, cycle.UUID, "completed")
+	if err != nil {
+		return err
+	}
+	sessions := teardownJobs(created)
+	if len(sessions) == 0 {
+		return nil
+	}
+	// The cycle enters its teardown before its jobs are saved, so none is held back
+	cycle.Phase = "teardown"
+	if err := s.store.UpdateCycleColumns(ctx, cycle, "phase"); err != nil {
+		return err
+	}
+
+	var jobs []models.Job
+	for _, sessionJobs := range sessions {
+		previous := ""
+		for _, job := range sessionJobs {
+			job.DependsOn = previous
+			previous = job.UUID
+			jobs = append(jobs, job)
+		}
+	}
+	if err := s.store.CreateJobsBatch(ctx, jobs); err != nil {
+		s.logger.Error(ctx, "Failed to save teardown jobs to database", "cycle_uuid", cycle.UUID, "jobs", len(jobs), "error", err)
+		return s.finishTeardown(ctx, cycle.UUID)
+	}
+	s.logger.Info(ctx, "Cycle teardown started", "cycle_uuid", cycle.UUID, "jobs", len(jobs))
+	return nil
+}
+
+// finishTeardown closes the teardown of a cycle once every teardown job finished,
+// successfully or not
+func (s *jobServiceImpl) finishTeardown(ctx context.Context, cycleUUID string) error {
+	counts, err := s.store.CountPhaseJobsByStatus(ctx, cycleUUID, "teardown")
+	if err != nil {
+		return err
+	}
+	if counts["pending"]+counts["dispatched"]+counts["processing"] > 0 {
+		return nil
+	}
+	cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if err != nil {
+		return err
+	}
+	if cycle.Phase != "teardown" {
+		return nil
+	}
+	cycle.Phase = "done"
+	if err := s.store.UpdateCycleColumns(ctx, cycle, "phase"); err != nil {
+		return err
+	}
+	s.logger.Info(ctx, "Cycle teardown finished", "cycle_uuid", cycle.UUID,
+		"completed", counts["completed"], "failed", counts["failed"])
+	return nil
+}
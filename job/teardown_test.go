@@ -0,0 +1,44 @@
+package job
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestTeardownJobs(t *testing.T) {
+	created := []models.Job{
+		{UUID: "u1", Name: "create_user", SessionID: "s1", DoneAt: 1, InputData: json.RawMessage(`{"user":{"username":"alice"},"credentials":{"username":"alice"}}`)},
+		{UUID: "w1", Name: "create_workspace", SessionID: "s1", DoneAt: 2, InputData: json.RawMessage(`{"workspace":{"uuid":"ws1"}}`)},
+		{UUID: "f1", Name: "upload_file", SessionID: "s1", DoneAt: 3, InputData: json.RawMessage(`{"file":{"uuid":"file1"}}`)},
+		{UUID: "c1", Name: "consult_file", SessionID: "s1", DoneAt: 4},
+		{UUID: "f2", Name: "upload_file", SessionID: "s1", DoneAt: 5, InputData: json.RawMessage(`{"file":{"uuid":"file2"}}`)},
+		{UUID: "u2", Name: "create_user", SessionID: "s2", DoneAt: 1},
+		{UUID: "c2", Name: "consult_file", SessionID: "s3", DoneAt: 1},
+	}
+	sessions := teardownJobs(created)
+	require.Len(t, sessions, 2, "sessions that created nothing have no teardown")
+
+	var actions []string
+	for _, job := range sessions[0] {
+		actions = append(actions, job.Name)
+		assert.Equal(t, "teardown", job.Phase)
+		assert.Equal(t, "s1", job.SessionID)
+	}
+	assert.Equal(t, []string{"delete_file", "delete_file", "delete_workspace", "delete_user"}, actions)
+
+	input := func(job models.Job) map[string]interface{} {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(job.InputData, &m))
+		return m
+	}
+	assert.Equal(t, "f2", input(sessions[0][0])["created_by"], "the latest upload is deleted first")
+	assert.Equal(t, map[string]interface{}{"uuid": "file2"}, input(sessions[0][0])["file"])
+	assert.Equal(t, map[string]interface{}{"uuid": "ws1"}, input(sessions[0][2])["workspace"])
+	assert.Equal(t, map[string]interface{}{"username": "alice"}, input(sessions[0][3])["credentials"])
+	assert.Equal(t, "delete_user", sessions[1][0].Name)
+}
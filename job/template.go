@@ -0,0 +1,93 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/songvi/robo/models"
+)
+
+// ValidateStrategy checks that a cycle can start with strategy
+func ValidateStrategy(strategy *models.Strategy) error {
+	if strategy == nil {
+		return fmt.Errorf("%w: no strategy", ErrInvalidStrategy)
+	}
+	_, err := prepareStrategy(strategy)
+	return err
+}
+
+// StartTemplateCycle starts cycle with the strategy of a template, with overrides merged
+// into it as a JSON merge patch (RFC 7386); cycle sets the name, description and labels
+func (s *jobServiceImpl) StartTemplateCycle(ctx context.Context, template string, cycle models.Cycle, overrides json.RawMessage) (models.Cycle, error) {
+	t, err := s.store.GetCycleTemplate(ctx, template)
+	if err != nil {
+		return models.Cycle{}, err
+	}
+	strategy, err := applyOverrides(t.Strategy, overrides)
+	if err != nil {
+		return models.Cycle{}, err
+	}
+	if cycle.Name == "" {
+		cycle.Name = t.Name
+	}
+	cycle.Template, cycle.Strategy = t.Name, strategy
+	return s.StartCycle(ctx, cycle)
+}
+
+// applyOverrides returns a copy of strategy with overrides merged into its JSON form;
+// null removes a field and objects merge field by field
+func applyOverrides(strategy *models.Strategy, overrides json.RawMessage) (*models.Strategy, error) {
+	if strategy == nil {
+		strategy = &models.Strategy{}
+	}
+	base, err := json.Marshal(strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal strategy: %v", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(base, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal strategy: %v", err)
+	}
+	if len(bytes.TrimSpace(overrides)) > 0 {
+		var patch interface{}
+		if err := json.Unmarshal(overrides, &patch); err != nil {
+			return nil, fmt.Errorf("%w: invalid overrides: %v", ErrInvalidStrategy, err)
+		}
+		doc = mergePatch(doc, patch)
+	}
+
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal strategy: %v", err)
+	}
+	// Reject misspelled fields rather than dropping them
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	var out models.Strategy
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("%w: invalid overrides: %v", ErrInvalidStrategy, err)
+	}
+	return &out, nil
+}
+
+// mergePatch applies a JSON merge patch to doc
+func mergePatch(doc, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		docObj = make(map[string]interface{})
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(docObj, k)
+		} else {
+			docObj[k] = mergePatch(docObj[k], v)
+		}
+	}
+	return docObj
+}
@@ -0,0 +1,36 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/models"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	template := &models.Strategy{
+		MaxUsers:     10,
+		MaxRate:      5,
+		WorkerLabels: map[string]string{"pool": "eu", "tier": "gold"},
+		LoadProfile:  &models.LoadProfile{Stages: []models.LoadStage{{Name: "steady", DurationSeconds: 60, Concurrency: 4}}},
+	}
+
+	got, err := applyOverrides(template, json.RawMessage(`{"max_users": 50, "max_rate": null, "worker_labels": {"tier": null, "zone": "a"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 50, got.MaxUsers)
+	assert.Zero(t, got.MaxRate)
+	assert.Equal(t, map[string]string{"pool": "eu", "zone": "a"}, got.WorkerLabels)
+	assert.Equal(t, 4, got.LoadProfile.Stages[0].Concurrency, "untouched fields are kept")
+	assert.Equal(t, 10, template.MaxUsers, "the template is not modified")
+
+	got, err = applyOverrides(template, nil)
+	require.NoError(t, err)
+	assert.Equal(t, template, got)
+
+	_, err = applyOverrides(template, json.RawMessage(`{"max_user": 50}`))
+	assert.True(t, errors.Is(err, ErrInvalidStrategy), "misspelled fields are rejected")
+}
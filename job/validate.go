@@ -0,0 +1,149 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/songvi/robo/generator"
+	"github.com/songvi/robo/models"
+)
+
+// ValidateCycle checks cycle as StartCycle would and plans its sessions and jobs
+// without generating users or files and without saving anything. Sessions get
+// placeholder users, so persona-specific action mixes are not reflected.
+func (s *jobServiceImpl) ValidateCycle(ctx context.Context, cycle models.Cycle) (models.CyclePlan, error) {
+	if cycle.Strategy == nil {
+		cycle.Strategy = s.config.Strategy
+	}
+	strategy, err := prepareStrategy(cycle.Strategy)
+	if err != nil {
+		return models.CyclePlan{}, err
+	}
+	cycle.Strategy = strategy
+	// Plans of a cycle without a seed are repeatable all the same
+	if cycle.Seed == 0 {
+		cycle.Seed = 1
+	}
+	// Generated action streams draw from a source of their own, leaving those of the
+	// cycles that run untouched
+	ctx = generator.WithSeed(ctx, cycle.Seed)
+
+	plan := models.CyclePlan{Sessions: strategy.MaxUsers, Jobs: make(map[string]int)}
+	userNames := make([]string, strategy.MaxUsers)
+	for i := range userNames {
+		userNames[i] = fmt.Sprintf("user-%d", i+1)
+	}
+	targets := sessionTargets(strategy.Targets, len(userNames))
+	var jobs []models.Job
+	for i, userName := range userNames {
+		session := models.Session{UUID: uuid.New().String(), UserName: userName}
+		if targets != nil {
+			session.Target = targets[i]
+			if plan.Targets == nil {
+				plan.Targets = make(map[string]int)
+			}
+			plan.Targets[session.Target]++
+		}
+		var sessionJobs []models.Job
+		if strategy.Scenario != nil {
+			// Count the files scenario payloads would generate instead of generating them
+			generateFile := func() (string, error) {
+				plan.GeneratedFiles++
+				return "", nil
+			}
+			sessionJobs, err = newScenarioCompiler(strategy.Scenario, cycle.Seed, session, userNames, generateFile).compile()
+		} else {
+			sessionJobs, err = s.generateSessionJobs(ctx, cycle, session, userNames)
+		}
+		if err != nil {
+			return plan, fmt.Errorf("%w: failed to plan the jobs of a session: %v", ErrInvalidStrategy, err)
+		}
+		if strategy.LiveSessions {
+			sessionJobs = append(append([]models.Job{{Name: "login"}}, sessionJobs...), models.Job{Name: "logout"})
+		}
+		if strategy.WarmUp != nil {
+			// Workspaces go round-robin to the sessions
+			workspaces := strategy.MaxWorkspaces / len(userNames)
+			if i < strategy.MaxWorkspaces%len(userNames) {
+				workspaces++
+			}
+			warmUp := warmUpJobs(ctx, strategy.WarmUp, session, workspaces)
+			plan.WarmUpJobs += len(warmUp)
+			sessionJobs = append(warmUp, sessionJobs...)
+		}
+		jobs = append(jobs, sessionJobs...)
+	}
+	if strategy.Scenario == nil || strategy.WarmUp != nil {
+		plan.GeneratedFiles += strategy.MaxFiles
+	}
+
+	for _, job := range jobs {
+		plan.Jobs[job.Name]++
+	}
+	if strategy.Teardown {
+		for _, kind := range teardownKinds {
+			if n := plan.Jobs[kind.create]; n > 0 {
+				plan.Jobs[kind.delete] += n
+				plan.TeardownJobs += n
+			}
+		}
+	}
+	plan.TotalJobs = len(jobs) + plan.TeardownJobs
+
+	preflight := s.buildPreflightRequest(cycle, jobs)
+	plan.RequiredBytes = preflight.RequiredBytes
+	plan.GeneratedBytes = int64(float64(plan.GeneratedFiles) * generator.MeanFileSize(s.fileStrategy))
+	if len(strategy.Targets) == 0 {
+		pool := s.workerPool(strategy.WorkerLabels)
+		plan.Workers = len(pool)
+		plan.Problems = poolProblems(pool, strategy.WorkerLabels, preflight.Actions)
+	}
+	for _, target := range strategy.Targets {
+		labels := poolLabels(strategy, target.Name)
+		pool := s.workerPool(labels)
+		plan.Workers += len(pool)
+		for _, problem := range poolProblems(pool, labels, preflight.Actions) {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("target %s: %s", target.Name, problem))
+		}
+	}
+	if plan.Sessions == 0 {
+		plan.Problems = append(plan.Problems, "max_users is 0, so the cycle has no sessions")
+	}
+	return plan, nil
+}
+
+// workerPool returns the active workers carrying labels that are not draining
+func (s *jobServiceImpl) workerPool(labels map[string]string) []models.Worker {
+	var pool []models.Worker
+	for _, w := range s.dispatcher.GetActiveWorkers() {
+		if !w.Draining && w.HasLabels(labels) {
+			pool = append(pool, w)
+		}
+	}
+	return pool
+}
+
+// poolProblems lists what keeps the worker pool of labels from running actions: having
+// no worker, or no worker executing one of them. Workers that do not report their
+// capabilities are assumed to execute every action.
+func poolProblems(pool []models.Worker, labels map[string]string, actions []string) []string {
+	if len(pool) == 0 {
+		if len(labels) == 0 {
+			return []string{"no active worker"}
+		}
+		return []string{fmt.Sprintf("no active worker carries the labels %v", labels)}
+	}
+	var problems []string
+	for _, action := range actions {
+		supported := slices.ContainsFunc(pool, func(w models.Worker) bool {
+			return len(w.Capabilities) == 0 || slices.Contains(w.Capabilities, action)
+		})
+		if !supported {
+			problems = append(problems, fmt.Sprintf("no worker of the pool executes %q", action))
+		}
+	}
+	return problems
+}
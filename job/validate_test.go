@@ -0,0 +1,61 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/dispatcher"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+type poolDispatcher struct {
+	dispatcher.Dispatcher
+	workers []models.Worker
+}
+
+func (d *poolDispatcher) GetActiveWorkers() []models.Worker {
+	return d.workers
+}
+
+func TestValidateCycle(t *testing.T) {
+	d := &poolDispatcher{workers: []models.Worker{
+		{UUID: "w1", Capabilities: []string{"create_user", "create_workspace", "upload_file", "delete_file", "delete_workspace"}},
+		{UUID: "w2", Labels: map[string]string{"pool": "eu"}, Draining: true},
+	}}
+	s := &jobServiceImpl{clock: NewSystemClock(), dispatcher: d, logger: logger.NewSlogLogger(),
+		fileStrategy: models.FileStrategy{FileSize: []int{1000}, FileSizeProbability: []float64{1}}}
+	strategy := &models.Strategy{
+		MaxUsers: 2, MaxFiles: 3, MaxWorkspaces: 1,
+		Actions:  []models.ActionMix{{Action: "upload_file", Weight: 1}, {Action: "create_workspace", Count: 1}},
+		WarmUp:   &models.WarmUp{Users: true},
+		Teardown: true,
+	}
+
+	plan, err := s.ValidateCycle(context.Background(), models.Cycle{Strategy: strategy})
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.Sessions)
+	assert.Equal(t, map[string]int{
+		"upload_file": 8, "create_workspace": 2, "create_user": 2,
+		"delete_file": 8, "delete_workspace": 2, "delete_user": 2,
+	}, plan.Jobs)
+	assert.Equal(t, 2, plan.WarmUpJobs)
+	assert.Equal(t, 12, plan.TeardownJobs)
+	assert.Equal(t, 24, plan.TotalJobs)
+	assert.Equal(t, 3, plan.GeneratedFiles)
+	assert.Equal(t, int64(3000), plan.GeneratedBytes)
+	assert.Equal(t, int64(8000), plan.RequiredBytes)
+	assert.Equal(t, 1, plan.Workers)
+	assert.Equal(t, []string{`no worker of the pool executes "delete_user"`}, plan.Problems)
+
+	strategy.WorkerLabels = map[string]string{"pool": "eu"}
+	plan, err = s.ValidateCycle(context.Background(), models.Cycle{Strategy: strategy})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"no active worker carries the labels map[pool:eu]"}, plan.Problems, "draining workers do not count")
+
+	_, err = s.ValidateCycle(context.Background(), models.Cycle{Strategy: &models.Strategy{BatchSize: -1}})
+	assert.ErrorIs(t, err, ErrInvalidStrategy)
+}
@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/songvi/robo/models"
+)
+
+// validateWarmUp checks the warm-up of a strategy
+func validateWarmUp(warmUp *models.WarmUp) error {
+	if warmUp.Files < 0 {
+		return fmt.Errorf("files must not be negative")
+	}
+	if !warmUp.Users && !warmUp.Workspaces && warmUp.Files == 0 {
+		return fmt.Errorf("warm-up seeds neither users, workspaces nor files")
+	}
+	return nil
+}
+
+// warmUpJobs returns the jobs seeding the target for session: creating its user, its
+// workspaces workspaces and uploading the initial corpus, in that order
+func warmUpJobs(ctx context.Context, warmUp *models.WarmUp, session models.Session, workspaces int) []models.Job {
+	var actions []string
+	if warmUp.Users {
+		actions = append(actions, "create_user")
+	}
+	if warmUp.Workspaces {
+		for i := 0; i < workspaces; i++ {
+			actions = append(actions, "create_workspace")
+		}
+	}
+	for i := 0; i < warmUp.Files; i++ {
+		actions = append(actions, "upload_file")
+	}
+
+	jobs := make([]models.Job, 0, len(actions))
+	for _, action := range actions {
+		inputJSON, _ := json.Marshal(map[string]string{
+			"user_id":    session.UserName,
+			"session_id": session.UUID,
+			"action":     action,
+			"phase":      "warmup",
+		})
+		jobs = append(jobs, models.Job{
+			UUID:      newID(ctx),
+			Name:      action,
+			InputData: json.RawMessage(inputJSON),
+			Status:    "pending",
+			Phase:     "warmup",
+		})
+	}
+	return jobs
+}
+
+// finishWarmUp starts the load phase of a cycle once every warm-up job of the cycle
+// finished, successfully or not, and tells whether the cycle is in its load phase
+func (s *jobServiceImpl) finishWarmUp(ctx context.Context, cycleUUID string) (bool, error) {
+	counts, err := s.store.CountPhaseJobsByStatus(ctx, cycleUUID, "warmup")
+	if err != nil {
+		return false, err
+	}
+	if counts["pending"]+counts["dispatched"]+counts["processing"] > 0 {
+		return false, nil
+	}
+	cycle, err := s.store.GetCycle(ctx, cycleUUID)
+	if err != nil {
+		return false, err
+	}
+	if cycle.Phase != "warmup" {
+		return true, nil
+	}
+	cycle.Phase = "load"
+	cycle.LoadStartedAt = s.clock.Now().Unix()
+	if err := s.store.UpdateCycleColumns(ctx, cycle, "phase", "load_started_at"); err != nil {
+		return false, err
+	}
+	s.logger.Info(ctx, "Cycle warm-up finished", "cycle_uuid", cycle.UUID,
+		"completed", counts["completed"], "failed", counts["failed"], "seconds", cycle.LoadStartedAt-cycle.StartedAt)
+	return true, nil
+}
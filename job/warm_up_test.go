@@ -0,0 +1,68 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+type warmUpStore struct {
+	store.Store
+	counts map[string]int
+	cycle  models.Cycle
+}
+
+func (s *warmUpStore) CountPhaseJobsByStatus(ctx context.Context, cycleUUID, phase string) (map[string]int, error) {
+	return s.counts, nil
+}
+
+func (s *warmUpStore) GetCycle(ctx context.Context, uuid string) (*models.Cycle, error) {
+	cycle := s.cycle
+	return &cycle, nil
+}
+
+func (s *warmUpStore) UpdateCycleColumns(ctx context.Context, cycle *models.Cycle, columns ...string) error {
+	s.cycle = *cycle
+	return nil
+}
+
+func TestWarmUpJobs(t *testing.T) {
+	warmUp := &models.WarmUp{Users: true, Workspaces: true, Files: 2}
+	require.NoError(t, validateWarmUp(warmUp))
+	assert.Error(t, validateWarmUp(&models.WarmUp{}))
+	assert.Error(t, validateWarmUp(&models.WarmUp{Users: true, Files: -1}))
+
+	jobs := warmUpJobs(context.Background(), warmUp, models.Session{UUID: "s1", UserName: "alice"}, 1)
+	var actions []string
+	for _, job := range jobs {
+		actions = append(actions, job.Name)
+		assert.Equal(t, "warmup", job.Phase)
+	}
+	assert.Equal(t, []string{"create_user", "create_workspace", "upload_file", "upload_file"}, actions)
+}
+
+func TestFinishWarmUp(t *testing.T) {
+	st := &warmUpStore{
+		counts: map[string]int{"completed": 3, "processing": 1},
+		cycle:  models.Cycle{UUID: "c1", Phase: "warmup", StartedAt: 100},
+	}
+	s := &jobServiceImpl{clock: NewSystemClock(), store: st, logger: logger.NewSlogLogger()}
+
+	loaded, err := s.finishWarmUp(context.Background(), "c1")
+	require.NoError(t, err)
+	assert.False(t, loaded, "a warm-up job is still running")
+	assert.Equal(t, "warmup", st.cycle.Phase)
+
+	st.counts = map[string]int{"completed": 3, "failed": 1}
+	loaded, err = s.finishWarmUp(context.Background(), "c1")
+	require.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Equal(t, "load", st.cycle.Phase)
+	assert.NotZero(t, st.cycle.LoadStartedAt)
+}
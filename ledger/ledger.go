@@ -0,0 +1,154 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/logger"
+)
+
+// Record is the summary appended to the ledger for every completed cycle
+type Record struct {
+	CycleUUID     string  `json:"cycle_uuid"`
+	CycleName     string  `json:"cycle_name"`
+	Template      string  `json:"template,omitempty"`
+	Deployment    string  `json:"deployment"`
+	TargetVersion string  `json:"target_version,omitempty"`
+	Verdict       string  `json:"verdict"`
+	Reason        string  `json:"reason,omitempty"` // Why the cycle was stopped, if it was
+	StartedAt     int64   `json:"started_at"`
+	DoneAt        int64   `json:"done_at"`
+	TotalJobs     int     `json:"total_jobs"`
+	CompletedJobs int     `json:"completed_jobs"`
+	FailedJobs    int     `json:"failed_jobs"`
+	Throughput    float64 `json:"throughput"` // Finished jobs per second
+	RecordedAt    int64   `json:"recorded_at"`
+}
+
+// Ledger appends cycle summaries to a central history shared by robo deployments
+type Ledger interface {
+	Append(ctx context.Context, record Record) error
+}
+
+// noopLedger is used when no ledger is configured
+type noopLedger struct{}
+
+func (noopLedger) Append(context.Context, Record) error { return nil }
+
+// stampedLedger fills in the deployment-wide fields of every record
+type stampedLedger struct {
+	Ledger
+	deployment    string
+	targetVersion string
+}
+
+// Append stamps the record with the deployment name and target version
+func (l stampedLedger) Append(ctx context.Context, record Record) error {
+	record.Deployment = l.deployment
+	if record.TargetVersion == "" {
+		record.TargetVersion = l.targetVersion
+	}
+	record.RecordedAt = time.Now().Unix()
+	return l.Ledger.Append(ctx, record)
+}
+
+// fileLedger appends records as JSON lines to a file, typically on shared storage
+type fileLedger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Append writes the record as one JSON line
+func (l *fileLedger) Append(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger file: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// webhookLedger posts each record as JSON to a URL
+type webhookLedger struct {
+	url    string
+	client *http.Client
+}
+
+// Append posts the record and expects a 2xx response
+func (l *webhookLedger) Append(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger record: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post ledger record: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post ledger record: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// NewLedger creates the Ledger selected by the ledger config section
+func NewLedger(configSvc config.ConfigService, logger logger.Logger) (Ledger, error) {
+	cfg := configSvc.GetConfig().Ledger
+	ctx := context.Background()
+	var backend Ledger
+	switch cfg.Type {
+	case "":
+		return noopLedger{}, nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("ledger type file requires a path")
+		}
+		backend = &fileLedger{path: cfg.Path}
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("ledger type webhook requires a url")
+		}
+		backend = &webhookLedger{url: cfg.URL, client: &http.Client{Timeout: 10 * time.Second}}
+	default:
+		return nil, fmt.Errorf("unsupported ledger type: %s", cfg.Type)
+	}
+
+	deployment := cfg.Deployment
+	if deployment == "" {
+		deployment, _ = os.Hostname()
+	}
+	logger.Info(ctx, "Run ledger enabled", "type", cfg.Type, "deployment", deployment)
+	return stampedLedger{Ledger: backend, deployment: deployment, targetVersion: cfg.TargetVersion}, nil
+}
+
+// Module defines the Fx module for the run ledger
+var Module = fx.Module(
+	"ledger",
+	fx.Provide(NewLedger),
+)
@@ -0,0 +1,104 @@
+package ledger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/logger"
+)
+
+type staticConfig config.Config
+
+func (c staticConfig) GetConfig() config.Config { return config.Config(c) }
+
+func TestFileLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared", "ledger.jsonl")
+	l, err := NewLedger(staticConfig{Ledger: config.LedgerConfig{
+		Type: "file", Path: path, Deployment: "perf-eu", TargetVersion: "2.4.0",
+	}}, logger.NewSlogLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, l.Append(ctx, Record{
+		CycleUUID: "c1", CycleName: "nightly", Template: "smoke", Verdict: "completed",
+		StartedAt: 100, DoneAt: 200, TotalJobs: 10, CompletedJobs: 9, FailedJobs: 1, Throughput: 0.1,
+	}))
+	require.NoError(t, l.Append(ctx, Record{CycleUUID: "c2", Verdict: "aborted", TargetVersion: "2.5.0-rc1"}))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "one JSON record per line")
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, records, 2)
+
+	first := records[0]
+	assert.Equal(t, "c1", first.CycleUUID)
+	assert.Equal(t, "nightly", first.CycleName)
+	assert.Equal(t, "smoke", first.Template)
+	assert.Equal(t, "completed", first.Verdict)
+	assert.Equal(t, 9, first.CompletedJobs)
+	assert.Equal(t, 0.1, first.Throughput)
+	assert.Equal(t, "perf-eu", first.Deployment)
+	assert.Equal(t, "2.4.0", first.TargetVersion, "records take the configured target version by default")
+	assert.NotZero(t, first.RecordedAt)
+	assert.Equal(t, "2.5.0-rc1", records[1].TargetVersion, "a record keeps its own target version")
+
+	_, err = NewLedger(staticConfig{Ledger: config.LedgerConfig{Type: "file"}}, logger.NewSlogLogger())
+	assert.Error(t, err)
+}
+
+func TestWebhookLedger(t *testing.T) {
+	records := make(chan Record, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var record Record
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+		records <- record
+		if record.CycleUUID == "unwanted" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	l, err := NewLedger(staticConfig{Ledger: config.LedgerConfig{
+		Type: "webhook", URL: server.URL, Deployment: "perf-us", TargetVersion: "2.4.0",
+	}}, logger.NewSlogLogger())
+	require.NoError(t, err)
+
+	require.NoError(t, l.Append(context.Background(), Record{CycleUUID: "c1", Template: "smoke", Verdict: "failed_slo", FailedJobs: 3}))
+	record := <-records
+	assert.Equal(t, "c1", record.CycleUUID)
+	assert.Equal(t, "smoke", record.Template)
+	assert.Equal(t, "failed_slo", record.Verdict)
+	assert.Equal(t, 3, record.FailedJobs)
+	assert.Equal(t, "perf-us", record.Deployment)
+	assert.Equal(t, "2.4.0", record.TargetVersion)
+
+	assert.ErrorContains(t, l.Append(context.Background(), Record{CycleUUID: "unwanted"}), "unexpected status 502")
+	<-records
+
+	_, err = NewLedger(staticConfig{Ledger: config.LedgerConfig{Type: "webhook"}}, logger.NewSlogLogger())
+	assert.Error(t, err)
+	_, err = NewLedger(staticConfig{Ledger: config.LedgerConfig{Type: "postgres"}}, logger.NewSlogLogger())
+	assert.Error(t, err)
+}
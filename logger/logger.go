@@ -2,8 +2,12 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sync"
+	"sync/atomic"
 
 	"go.uber.org/fx"
 )
@@ -17,31 +21,141 @@ type Logger interface {
 
 // SlogLogger is an implementation of Logger using slog
 type SlogLogger struct {
-	logger *slog.Logger
+	logger atomic.Pointer[slog.Logger]
+	mu     sync.Mutex
+	output io.Closer // Log file of the current handler, if any
 }
 
-// NewSlogLogger creates a new SlogLogger
+// Environment variables selecting what is logged and where, until the config is loaded,
+// whose settings they then take over
+const (
+	LevelEnv  = "ROBO_LOG_LEVEL"  // Lowest level logged: debug (default), info, warn or error
+	FormatEnv = "ROBO_LOG_FORMAT" // json (default) or text
+	OutputEnv = "ROBO_LOG_OUTPUT" // stdout (default), stderr or a file logs are appended to
+)
+
+// Config selects the lowest level logged, the format of the records and their output
+type Config struct {
+	Level  string `json:"level" yaml:"level"`   // debug (default), info, warn or error
+	Format string `json:"format" yaml:"format"` // json (default) or text
+	Output string `json:"output" yaml:"output"` // stdout (default), stderr or the path of a file logs are appended to
+}
+
+// ConfigFromEnv returns the Config the environment variables select
+func ConfigFromEnv() Config {
+	return Config{
+		Level:  os.Getenv(LevelEnv),
+		Format: os.Getenv(FormatEnv),
+		Output: os.Getenv(OutputEnv),
+	}
+}
+
+// Validate checks the level and the format; the output is only checked once opened
+func (c Config) Validate() error {
+	if _, err := c.level(); err != nil {
+		return fmt.Errorf("level: unknown %q, use debug, info, warn or error", c.Level)
+	}
+	switch c.Format {
+	case "", "json", "text":
+	default:
+		return fmt.Errorf("format: unknown %q, use json or text", c.Format)
+	}
+	return nil
+}
+
+func (c Config) level() (slog.Level, error) {
+	level := slog.LevelDebug
+	if c.Level == "" {
+		return level, nil
+	}
+	err := level.UnmarshalText([]byte(c.Level))
+	return level, err
+}
+
+// newLogger returns the slog logger of c, and the file it writes to, if any
+func newLogger(c Config) (*slog.Logger, io.Closer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	level, _ := c.level()
+	var (
+		out  io.Writer
+		file *os.File
+	)
+	switch c.Output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		var err error
+		if file, err = os.OpenFile(c.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file %s: %v", c.Output, err)
+		}
+		out = file
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewJSONHandler(out, opts)
+	if c.Format == "text" {
+		handler = slog.NewTextHandler(out, opts)
+	}
+	if file == nil {
+		return slog.New(handler), nil, nil
+	}
+	return slog.New(handler), file, nil
+}
+
+// NewSlogLogger creates a new SlogLogger configured by the environment variables; those
+// that are invalid are logged and left out
 func NewSlogLogger() Logger {
-	return &SlogLogger{
-		logger: slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug, // Ensure DEBUG level
-		})),
+	l := &SlogLogger{}
+	if err := l.Configure(ConfigFromEnv()); err != nil {
+		l.logger.Store(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		l.Error(context.Background(), "Invalid log settings, logging everything as JSON to stdout", "error", err)
+	}
+	return l
+}
+
+// Configure replaces the level, format and output of the logger with those of c, closing
+// the log file it wrote to, if any. The logger is left unchanged when c is invalid.
+func (l *SlogLogger) Configure(c Config) error {
+	logger, output, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logger.Store(logger)
+	previous := l.output
+	l.output = output
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// Configure applies c to l when it can be reconfigured, as SlogLogger can, and does
+// nothing otherwise
+func Configure(l Logger, c Config) error {
+	if configurable, ok := l.(interface{ Configure(Config) error }); ok {
+		return configurable.Configure(c)
 	}
+	return nil
 }
 
 // Info logs an info message
 func (l *SlogLogger) Info(ctx context.Context, msg string, args ...any) {
-	l.logger.InfoContext(ctx, msg, args...)
+	l.logger.Load().InfoContext(ctx, msg, args...)
 }
 
 // Error logs an error message
 func (l *SlogLogger) Error(ctx context.Context, msg string, args ...any) {
-	l.logger.ErrorContext(ctx, msg, args...)
+	l.logger.Load().ErrorContext(ctx, msg, args...)
 }
 
 // Debug logs a debug message
 func (l *SlogLogger) Debug(ctx context.Context, msg string, args ...any) {
-	l.logger.DebugContext(ctx, msg, args...)
+	l.logger.Load().DebugContext(ctx, msg, args...)
 }
 
 // ProvideLogger is an fx-compatible constructor for Logger
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robo.log")
+	l := &SlogLogger{}
+	require.NoError(t, l.Configure(Config{Level: "info", Format: "text", Output: path}))
+
+	ctx := context.Background()
+	l.Debug(ctx, "left out")
+	l.Info(ctx, "kept", "key", "value")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "left out")
+	assert.Contains(t, string(data), `level=INFO msg=kept key=value`)
+
+	assert.EqualError(t, l.Configure(Config{Level: "loud"}), `level: unknown "loud", use debug, info, warn or error`)
+	l.Info(ctx, "still kept")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "still kept", "invalid settings leave the logger unchanged")
+
+	require.NoError(t, l.Configure(Config{Output: "stderr"}))
+	assert.Nil(t, l.output, "the log file is closed")
+}
@@ -0,0 +1,38 @@
+package models
+
+// ActionStrategy shapes the stream of actions each session performs
+type ActionStrategy struct {
+	ActionTypes   []string  `json:"action_types" yaml:"action_types"`
+	ActionWeights []float64 `json:"action_weights" yaml:"action_weights"`
+	// Each session performs between MinActions and MaxActions weighted actions
+	MinActions int `json:"min_actions" yaml:"min_actions"`
+	MaxActions int `json:"max_actions" yaml:"max_actions"`
+	// StartAction and EndAction, when set, open and close every session (e.g. login/logout)
+	StartAction string                 `json:"start_action,omitempty" yaml:"start_action,omitempty"`
+	EndAction   string                 `json:"end_action,omitempty" yaml:"end_action,omitempty"`
+	ThinkTime   *ThinkTimeDistribution `json:"think_time,omitempty" yaml:"think_time,omitempty"`
+	// Personas give the sessions of users with a persona their own action mix
+	Personas map[string]PersonaActions `json:"personas,omitempty" yaml:"personas,omitempty"`
+}
+
+// PersonaActions is the action profile of a persona such as "heavy_uploader", "reader"
+// or "admin"
+type PersonaActions struct {
+	// ActionWeights weight ActionTypes for the persona; empty keeps the default weights
+	ActionWeights []float64 `json:"action_weights,omitempty" yaml:"action_weights,omitempty"`
+	// MinActions and MaxActions replace the session length when MaxActions is set
+	MinActions int `json:"min_actions,omitempty" yaml:"min_actions,omitempty"`
+	MaxActions int `json:"max_actions,omitempty" yaml:"max_actions,omitempty"`
+}
+
+// ThinkTimeDistribution describes the pause before each action, in milliseconds
+type ThinkTimeDistribution struct {
+	Type string `json:"type" yaml:"type"` // constant, uniform, exponential or lognormal
+	// Constant and exponential use MeanMs; uniform draws from [MinMs, MaxMs].
+	// Lognormal: exp(ln(MeanMs) + Sigma*N(0,1)), so MeanMs is the median.
+	MeanMs int64   `json:"mean_ms,omitempty" yaml:"mean_ms,omitempty"`
+	Sigma  float64 `json:"sigma,omitempty" yaml:"sigma,omitempty"`
+	// Samples are clamped to [MinMs, MaxMs]; a zero MaxMs means no upper bound
+	MinMs int64 `json:"min_ms,omitempty" yaml:"min_ms,omitempty"`
+	MaxMs int64 `json:"max_ms,omitempty" yaml:"max_ms,omitempty"`
+}
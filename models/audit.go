@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Audit records when a row was created and last updated, in Unix seconds, who created
+// it, and when it was soft deleted. Queries leave soft-deleted rows out until they are
+// purged.
+type Audit struct {
+	CreatedAt int64          `json:"created_at" yaml:"created_at" gorm:"column:created_at;type:bigint;autoCreateTime"`
+	UpdatedAt int64          `json:"updated_at" yaml:"updated_at" gorm:"column:updated_at;type:bigint;autoUpdateTime"`
+	CreatedBy string         `json:"created_by,omitempty" yaml:"created_by,omitempty" gorm:"column:created_by;type:text"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" yaml:"-" gorm:"column:deleted_at;index"`
+}
+
+// SystemCreator is the creator of the rows robo creates on its own
+const SystemCreator = "system"
+
+type creatorKey struct{}
+
+// WithCreator returns a context whose rows are created by creator, e.g. the caller of
+// the API request creating them
+func WithCreator(ctx context.Context, creator string) context.Context {
+	return context.WithValue(ctx, creatorKey{}, creator)
+}
+
+// CreatorFrom returns the creator set on ctx by WithCreator, or SystemCreator
+func CreatorFrom(ctx context.Context) string {
+	if creator, ok := ctx.Value(creatorKey{}).(string); ok && creator != "" {
+		return creator
+	}
+	return SystemCreator
+}
+
+// BeforeCreate sets the creator of a row from the context of its statement
+func (a *Audit) BeforeCreate(tx *gorm.DB) error {
+	if a.CreatedBy == "" {
+		a.CreatedBy = CreatorFrom(tx.Statement.Context)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package models
+
+// CycleTemplate is a reusable, named strategy (with its scenario and load profile)
+// that cycles are started from with parameter overrides
+type CycleTemplate struct {
+	Name        string    `json:"name" yaml:"name" gorm:"primaryKey;type:text"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty" gorm:"column:description;type:text"`
+	Strategy    *Strategy `json:"strategy" yaml:"strategy" gorm:"column:strategy;type:json;serializer:json"`
+	Audit       `yaml:",inline"`
+}
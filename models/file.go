@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type File struct {
 	UUID          string `json:"uuid" yaml:"uuid" gorm:"primaryKey;type:uuid;"`
 	Name          string `json:"name" yaml:"name" gorm:"column:name;type:text;not null"`
@@ -10,7 +12,30 @@ type File struct {
 	FileSize      int    `json:"file_size" yaml:"file_size" gorm:"column:file_size;type:integer;not null"`
 	FileContent   string `json:"file_content" yaml:"file_content" gorm:"column:file_content;type:text"`
 	WorkspaceID   string `json:"workspace_id" yaml:"workspace_id" gorm:"column:workspace_id;type:uuid;not null"`
+	// LanguageMix is the share of each content language for multi-language documents
+	LanguageMix map[string]float64 `json:"language_mix,omitempty" yaml:"language_mix,omitempty" gorm:"column:language_mix;type:text;serializer:json"`
+	// Version numbers successive revisions of one document from 1; 0 means unversioned.
+	// All revisions of a document share its Name and VersionGroup.
+	Version      int    `json:"version,omitempty" yaml:"version,omitempty" gorm:"column:version;type:integer"`
+	VersionGroup string `json:"version_group,omitempty" yaml:"version_group,omitempty" gorm:"column:version_group;type:text"`
+	// ContentID is the hex SHA-256 of the content; dry runs make up a digest of the same
+	// shape, shared by exact duplicates, for content they never write
+	ContentID string `json:"content_id,omitempty" yaml:"content_id,omitempty" gorm:"column:content_id;type:text"`
+	// PII lists the synthetic sensitive values embedded in the content
+	PII []PIILocation `json:"pii,omitempty" yaml:"pii,omitempty" gorm:"column:pii;type:text;serializer:json"`
+	// GenerationTime is how long writing the content took; it is not persisted
+	GenerationTime time.Duration `json:"generation_time,omitempty" yaml:"generation_time,omitempty" gorm:"-"`
+	// ProjectID is the project the file belongs to
+	ProjectID string `json:"project_id" yaml:"project_id" gorm:"column:project_id;type:text;index"`
+	Audit     `yaml:",inline"`
 	// Foreign key relationships
 	Cycle     Cycle     `gorm:"foreignKey:CycleID;references:UUID"`
 	Workspace Workspace `gorm:"foreignKey:WorkspaceID;references:UUID"`
 }
+
+// PIILocation is one synthetic sensitive value and where it sits in the file content
+type PIILocation struct {
+	Type   string `json:"type" yaml:"type"`
+	Value  string `json:"value" yaml:"value"`
+	Offset int    `json:"offset" yaml:"offset"` // Byte offset of Value in the file
+}
@@ -5,6 +5,62 @@ type FileStrategy struct {
 	FileExtensionProbability []float64 `json:"file_extension_probability" yaml:"file_extension_probability"`
 	FileSize                 []int     `json:"file_size" yaml:"file_size"`
 	FileSizeProbability      []float64 `json:"file_size_probability" yaml:"file_size_probability"`
-	FileLang                 []string  `json:"file_name_lang" yaml:"file_name_lang"`
-	FileLangNameProbability  []float64 `json:"file_name_probability" yaml:"file_name_probability"`
+	// FileSizeDistribution, when set, replaces the discrete FileSize list
+	FileSizeDistribution    *SizeDistribution `json:"file_size_distribution,omitempty" yaml:"file_size_distribution,omitempty"`
+	FileLang                []string          `json:"file_name_lang" yaml:"file_name_lang"`
+	FileLangNameProbability []float64         `json:"file_name_probability" yaml:"file_name_probability"`
+	// DedupRatio is the fraction of files written byte-identical to an earlier file
+	DedupRatio float64 `json:"dedup_ratio,omitempty" yaml:"dedup_ratio,omitempty"`
+	// NearDuplicateRatio is the fraction of files copied from an earlier file with small edits
+	NearDuplicateRatio float64 `json:"near_duplicate_ratio,omitempty" yaml:"near_duplicate_ratio,omitempty"`
+	// EdgeCaseNameRatio is the fraction of files given pathological names (emoji,
+	// right-to-left marks, reserved names, 255-byte names, ...)
+	EdgeCaseNameRatio float64 `json:"edge_case_name_ratio,omitempty" yaml:"edge_case_name_ratio,omitempty"`
+	// ContentLanguageMix makes a share of documents mix sentences from several languages
+	ContentLanguageMix *LanguageMixStrategy `json:"content_language_mix,omitempty" yaml:"content_language_mix,omitempty"`
+	// Versioning makes a share of files come out as several successive revisions
+	Versioning *VersioningStrategy `json:"versioning,omitempty" yaml:"versioning,omitempty"`
+	// PII embeds synthetic sensitive data into a share of text documents
+	PII *PIIStrategy `json:"pii,omitempty" yaml:"pii,omitempty"`
+}
+
+// PIIStrategy describes synthetic sensitive data embedded into text documents so DLP
+// and classification engines can be validated against the recorded locations
+type PIIStrategy struct {
+	Probability float64  `json:"probability" yaml:"probability"` // Share of text documents receiving PII
+	Types       []string `json:"types" yaml:"types"`             // credit_card, iban, ssn, email; empty means all
+	// Each selected document receives between MinItems and MaxItems values
+	MinItems int `json:"min_items" yaml:"min_items"`
+	MaxItems int `json:"max_items" yaml:"max_items"`
+}
+
+// VersioningStrategy describes documents emitted as a series of revisions
+type VersioningStrategy struct {
+	Probability float64 `json:"probability" yaml:"probability"` // Share of files that get revisions
+	// Each versioned document has between MinVersions and MaxVersions revisions, the original included
+	MinVersions int `json:"min_versions" yaml:"min_versions"`
+	MaxVersions int `json:"max_versions" yaml:"max_versions"`
+}
+
+// LanguageMixStrategy describes multi-language documents
+type LanguageMixStrategy struct {
+	Probability float64   `json:"probability" yaml:"probability"` // Share of files with mixed content
+	Languages   []string  `json:"languages" yaml:"languages"`
+	Weights     []float64 `json:"weights" yaml:"weights"` // Relative share of sentences per language
+}
+
+// SizeDistribution describes a continuous file-size distribution in bytes
+type SizeDistribution struct {
+	Type string `json:"type" yaml:"type"` // lognormal, pareto or zipf
+	// Lognormal: size = exp(Mu + Sigma*N(0,1))
+	Mu    float64 `json:"mu,omitempty" yaml:"mu,omitempty"`
+	Sigma float64 `json:"sigma,omitempty" yaml:"sigma,omitempty"`
+	// Pareto: Scale is the minimum size x_m and Alpha the shape.
+	// Zipf: size = Scale * rank with P(rank) proportional to rank^-Exponent.
+	Scale    float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Alpha    float64 `json:"alpha,omitempty" yaml:"alpha,omitempty"`
+	Exponent float64 `json:"exponent,omitempty" yaml:"exponent,omitempty"`
+	// Samples are clamped to [Min, Max]; a zero Max means no upper bound
+	Min int `json:"min,omitempty" yaml:"min,omitempty"`
+	Max int `json:"max,omitempty" yaml:"max,omitempty"`
 }
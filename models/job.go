@@ -11,10 +11,42 @@ type Job struct {
 	Error      string          `json:"error" yaml:"error" gorm:"column:error;type:text"`
 	StartAt    int64           `json:"start_at" yaml:"start_at" gorm:"column:start_at;type:bigint"`
 	DoneAt     int64           `json:"done_at" yaml:"done_at" gorm:"column:done_at;type:bigint"`
-	Status     string          `json:"status" yaml:"status" gorm:"column:status;type:text;not null"`
-	CycleUUID  string          `json:"cycle_uuid" yaml:"cycle_uuid" gorm:"column:cycle_uuid;type:uuid;not null"`
-	SessionID  string          `json:"session_id" yaml:"session_id" gorm:"column:session_id;type:text;not null"`
+	// DurationMs is how long the worker took to execute the job
+	DurationMs int64 `json:"duration_ms" yaml:"duration_ms" gorm:"column:duration_ms;type:bigint"`
+	// DispatchedAt is when the job was last dispatched, in Unix nanoseconds on the
+	// dispatching host; cycle cancellations drop the jobs dispatched before them
+	DispatchedAt int64  `json:"dispatched_at" yaml:"dispatched_at" gorm:"column:dispatched_at;type:bigint"`
+	Status       string `json:"status" yaml:"status" gorm:"column:status;type:text;not null"`
+	// Version counts the updates of the job; an update of a job that changed since it
+	// was read is rejected
+	Version   int    `json:"version" yaml:"version" gorm:"column:version;type:integer;not null;default:0"`
+	CycleUUID string `json:"cycle_uuid" yaml:"cycle_uuid" gorm:"column:cycle_uuid;type:uuid;not null"`
+	SessionID string `json:"session_id" yaml:"session_id" gorm:"column:session_id;type:text;not null"`
+	// DependsOn is the UUID of the job that must finish before this one is dispatched
+	DependsOn string `json:"depends_on,omitempty" yaml:"depends_on,omitempty" gorm:"column:depends_on;type:uuid"`
+	// Phase is "warmup" for the jobs seeding the target before the measured load and
+	// "teardown" for those cleaning it up after the cycle; their results are left out of
+	// statistics and SLOs
+	Phase string `json:"phase,omitempty" yaml:"phase,omitempty" gorm:"column:phase;type:text"`
+	// Target is the strategy target of the job's session, whose workers run the job
+	Target string `json:"target,omitempty" yaml:"target,omitempty" gorm:"column:target;type:text"`
+	// ProjectID is the project the job belongs to
+	ProjectID string `json:"project_id" yaml:"project_id" gorm:"column:project_id;type:text;index"`
+	Audit     `yaml:",inline"`
 	// Foreign key relationships
 	Cycle  Cycle  `gorm:"foreignKey:CycleUUID;references:UUID"`
 	Worker Worker `gorm:"foreignKey:WorkerID;references:UUID"`
 }
+
+// JobBatch carries consecutive independent jobs of one session to one worker, which
+// runs them in order and sends their results back together
+type JobBatch struct {
+	Jobs []Job `json:"jobs" yaml:"jobs"`
+}
+
+// CycleCancellation tells workers to stop the jobs of a cycle dispatched up to At, in
+// Unix nanoseconds on the dispatching host
+type CycleCancellation struct {
+	CycleUUID string `json:"cycle_uuid" yaml:"cycle_uuid"`
+	At        int64  `json:"at" yaml:"at"`
+}
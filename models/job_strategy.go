@@ -1,23 +1,205 @@
 package models
 
 type Strategy struct {
+	// CycleDuration bounds the running time of the cycle in seconds from its start; a
+	// cycle still running or paused then is stopped with status "expired". Zero means
+	// no bound.
 	CycleDuration int `json:"cycle_duration" yaml:"cycle_duration"`
-	MaxUsers      int `json:"max_users" yaml:"max_users"`
-	MaxFiles      int `json:"max_files" yaml:"max_files"`
-	MaxWorkspaces int `json:"max_workspace" yaml:"max_workspace"`
+	// OnExpiry decides what becomes of the jobs in flight once CycleDuration is over:
+	// cancel (default) cancels them with the pending ones, drain lets them finish for up
+	// to DrainSeconds (60 by default) and cancels those left then
+	OnExpiry      string `json:"on_expiry,omitempty" yaml:"on_expiry,omitempty"`
+	DrainSeconds  int    `json:"drain_seconds,omitempty" yaml:"drain_seconds,omitempty"`
+	MaxUsers      int    `json:"max_users" yaml:"max_users"`
+	MaxFiles      int    `json:"max_files" yaml:"max_files"`
+	MaxWorkspaces int    `json:"max_workspace" yaml:"max_workspace"`
+	// Actions sets the action mix of every session in place of the configured action
+	// stream; weighted actions share MaxFiles + MaxWorkspaces jobs per session
+	Actions []ActionMix `json:"actions,omitempty" yaml:"actions,omitempty"`
+	// Preflight asks a worker to verify credentials, permissions and quota before any job is saved
+	Preflight bool `json:"preflight" yaml:"preflight"`
+	// Scenario, or the YAML or JSON file ScenarioFile points to, replaces the action
+	// stream of every session
+	Scenario     *Scenario `json:"scenario,omitempty" yaml:"scenario,omitempty"`
+	ScenarioFile string    `json:"scenario_file,omitempty" yaml:"scenario_file,omitempty"`
+	// LiveSessions runs every session as a state machine: login, its actions one at a
+	// time with their think time, then logout. The job of an action is created and
+	// dispatched once the result of the previous one arrived, instead of all up front.
+	LiveSessions bool `json:"live_sessions,omitempty" yaml:"live_sessions,omitempty"`
+	// WarmUp seeds the target before the measured load begins
+	WarmUp *WarmUp `json:"warm_up,omitempty" yaml:"warm_up,omitempty"`
+	// Teardown deletes the users, workspaces and files the cycle created on the target
+	// once it completed or was stopped
+	Teardown bool `json:"teardown,omitempty" yaml:"teardown,omitempty"`
+	// LoadProfile paces the dispatch of the cycle's jobs; without one every pending job
+	// is dispatched as soon as possible
+	LoadProfile *LoadProfile `json:"load_profile,omitempty" yaml:"load_profile,omitempty"`
+	// MaxRate (jobs dispatched per second) and MaxInFlight (jobs dispatched and not
+	// finished) cap the dispatch of the cycle at all times, on top of its load profile;
+	// zero means no cap
+	MaxRate     float64 `json:"max_rate,omitempty" yaml:"max_rate,omitempty"`
+	MaxInFlight int     `json:"max_in_flight,omitempty" yaml:"max_in_flight,omitempty"`
+	// BatchSize groups up to BatchSize consecutive independent pending jobs of a session
+	// into one batch handled by one worker when the cycle is not paced; zero or one
+	// dispatches jobs one by one
+	BatchSize int `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	// WorkerLabels restricts the cycle to the pool of workers carrying these labels
+	WorkerLabels map[string]string `json:"worker_labels,omitempty" yaml:"worker_labels,omitempty"`
+	// Targets splits the sessions of the cycle between several environments under test,
+	// e.g. two builds to compare under the same load; the jobs of a session go to the
+	// workers bound to its target
+	Targets []Target `json:"targets,omitempty" yaml:"targets,omitempty"`
+	// SLOs are evaluated from job results while the cycle runs; a breached SLO fails the
+	// cycle with status "failed_slo", at once when StopOnSLOBreach is set
+	SLOs            []SLO `json:"slos,omitempty" yaml:"slos,omitempty"`
+	StopOnSLOBreach bool  `json:"stop_on_slo_breach,omitempty" yaml:"stop_on_slo_breach,omitempty"`
+	// ErrorBudget aborts the cycle with status "failed_errors" when its rolling error
+	// rate stays too high
+	ErrorBudget *ErrorBudget `json:"error_budget,omitempty" yaml:"error_budget,omitempty"`
+}
+
+// Target is an environment under test taking Percent of the sessions of a cycle; its
+// jobs go to the workers carrying WorkerLabels on top of those of the strategy
+type Target struct {
+	Name         string            `json:"name" yaml:"name"`
+	Percent      float64           `json:"percent" yaml:"percent"`
+	WorkerLabels map[string]string `json:"worker_labels" yaml:"worker_labels"`
+}
+
+// ErrorBudget bounds the error rate of a cycle over the last WindowSeconds (60 by
+// default); the cycle is aborted once the rate exceeded MaxErrorRate for SustainSeconds
+// (30 by default), so a target that is down is not pounded any longer. The rate is
+// evaluated once the window holds MinSamples results (20 by default).
+type ErrorBudget struct {
+	MaxErrorRate   float64 `json:"max_error_rate" yaml:"max_error_rate"`
+	WindowSeconds  int     `json:"window_seconds,omitempty" yaml:"window_seconds,omitempty"`
+	SustainSeconds int     `json:"sustain_seconds,omitempty" yaml:"sustain_seconds,omitempty"`
+	MinSamples     int     `json:"min_samples,omitempty" yaml:"min_samples,omitempty"`
+}
+
+// WarmUp selects the seed data every session creates on the target before the load
+// phase: its user, its workspaces and an initial corpus of Files uploads. Warm-up jobs
+// are dispatched as soon as possible and the load phase waits for all of them.
+type WarmUp struct {
+	Users      bool `json:"users,omitempty" yaml:"users,omitempty"`
+	Workspaces bool `json:"workspaces,omitempty" yaml:"workspaces,omitempty"`
+	Files      int  `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// ActionMix is the share of an action in a session: exactly Count jobs, or a Weight
+// relative to the other weighted actions, e.g. 70 upload_file, 20 consult_file
+type ActionMix struct {
+	Action string  `json:"action" yaml:"action"`
+	Weight float64 `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Count  int     `json:"count,omitempty" yaml:"count,omitempty"`
+}
+
+// SLO asserts the latency and/or error rate of an action, e.g. p95 upload_file < 2s
+type SLO struct {
+	Action string `json:"action,omitempty" yaml:"action,omitempty"` // Empty covers every action
+	// MaxLatencyMs bounds the Percentile (95 by default) of job durations
+	Percentile   float64 `json:"percentile,omitempty" yaml:"percentile,omitempty"`
+	MaxLatencyMs int64   `json:"max_latency_ms,omitempty" yaml:"max_latency_ms,omitempty"`
+	// MaxErrorRate bounds the share of failed jobs, e.g. 0.01 for 1%
+	MaxErrorRate float64 `json:"max_error_rate,omitempty" yaml:"max_error_rate,omitempty"`
+	// MinSamples is the number of finished jobs before the SLO is evaluated; 10 by default
+	MinSamples int `json:"min_samples,omitempty" yaml:"min_samples,omitempty"`
+}
+
+// SLOBreach records the first time an SLO of a cycle was breached
+type SLOBreach struct {
+	SLO       SLO     `json:"slo" yaml:"slo"`
+	Metric    string  `json:"metric" yaml:"metric"` // "latency" or "error_rate"
+	Value     float64 `json:"value" yaml:"value"`   // Milliseconds or ratio
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	Samples   int     `json:"samples" yaml:"samples"`
+	At        int64   `json:"at" yaml:"at"`
+}
+
+// Paced tells whether the dispatch of the cycle follows a load profile or caps
+func (s *Strategy) Paced() bool {
+	return s.LoadProfile != nil || s.MaxRate > 0 || s.MaxInFlight > 0
+}
+
+// LoadProfile shapes the load of a cycle over time as successive stages, e.g. a ramp-up,
+// a steady state, a spike and a ramp-down. Concurrency is limited when any stage sets
+// it, and likewise the rate. Once the last stage is over, the remaining jobs are
+// dispatched without limit.
+type LoadProfile struct {
+	Stages []LoadStage `json:"stages" yaml:"stages"`
+}
+
+// LoadStage holds its targets for DurationSeconds of running time, or moves to them
+// linearly from those of the previous stage (zero before the first) when Ramp is set
+type LoadStage struct {
+	Name            string  `json:"name,omitempty" yaml:"name,omitempty"`
+	DurationSeconds int     `json:"duration_seconds" yaml:"duration_seconds"`
+	Concurrency     int     `json:"concurrency,omitempty" yaml:"concurrency,omitempty"` // Jobs dispatched and not finished
+	Rate            float64 `json:"rate,omitempty" yaml:"rate,omitempty"`               // Jobs dispatched per second
+	Ramp            bool    `json:"ramp,omitempty" yaml:"ramp,omitempty"`
 }
 
 type Cycle struct {
 	UUID      string    `json:"uuid" yaml:"uuid" gorm:"primaryKey;type:uuid;"`
 	Name      string    `json:"name" yaml:"name" gorm:"column:name;type:text;not null"`
-	Strategy  *Strategy `json:"strategy" yaml:"strategy" gorm:"column:strategy;type:json"`
+	Strategy  *Strategy `json:"strategy" yaml:"strategy" gorm:"column:strategy;type:json;serializer:json"`
 	StartedAt int64     `json:"started_at" yaml:"started_at" gorm:"column:started_at;type:bigint;not null"`
 	DoneAt    int64     `json:"done_at" yaml:"done_at" gorm:"column:done_at;type:bigint"`
 	Status    string    `json:"status" yaml:"status" gorm:"column:status;type:text;not null"`
+	// Reason tells why the cycle was stopped before its jobs were done
+	Reason    string `json:"reason,omitempty" yaml:"reason,omitempty" gorm:"column:reason;type:text"`
+	Namespace string `json:"namespace" yaml:"namespace" gorm:"column:namespace;type:text"`                  // FileStore prefix of the cycle's files
+	Template  string `json:"template,omitempty" yaml:"template,omitempty" gorm:"column:template;type:text"` // Template the cycle started from
+	// Description and Labels record what the cycle tested, e.g. the target environment,
+	// the build version under test and the owner, to correlate its results with
+	Description string            `json:"description,omitempty" yaml:"description,omitempty" gorm:"column:description;type:text"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" gorm:"column:labels;type:text;serializer:json"`
+	// Seed of everything the cycle generated; starting a cycle with the seed of another
+	// replays its users, files, workspaces and actions
+	Seed int64 `json:"seed" yaml:"seed" gorm:"column:seed;type:bigint"`
+	// Phase is "warmup" while warm-up jobs remain and "load" afterwards, from
+	// LoadStartedAt; once the cycle is over it is "teardown" while teardown jobs remain
+	// and "done" afterwards
+	Phase         string        `json:"phase,omitempty" yaml:"phase,omitempty" gorm:"column:phase;type:text"`
+	LoadStartedAt int64         `json:"load_started_at,omitempty" yaml:"load_started_at,omitempty" gorm:"column:load_started_at;type:bigint"`
+	Progress      CycleProgress `json:"progress" yaml:"progress" gorm:"embedded"`
+	// SLOBreaches lists the SLOs of the strategy breached so far
+	SLOBreaches []SLOBreach `json:"slo_breaches,omitempty" yaml:"slo_breaches,omitempty" gorm:"column:slo_breaches;type:text;serializer:json"`
+	// ProjectID is the project the cycle belongs to
+	ProjectID string `json:"project_id" yaml:"project_id" gorm:"column:project_id;type:text;index"`
+	Audit     `yaml:",inline"`
+}
+
+// CycleProgress counts the jobs of a cycle by outcome
+type CycleProgress struct {
+	TotalJobs     int `json:"total_jobs" yaml:"total_jobs" gorm:"column:total_jobs;type:integer"`
+	CompletedJobs int `json:"completed_jobs" yaml:"completed_jobs" gorm:"column:completed_jobs;type:integer"`
+	FailedJobs    int `json:"failed_jobs" yaml:"failed_jobs" gorm:"column:failed_jobs;type:integer"`
+	CancelledJobs int `json:"cancelled_jobs" yaml:"cancelled_jobs" gorm:"column:cancelled_jobs;type:integer"`
+	// EstimatedDoneAt extrapolates when the last job finishes from the pace so far;
+	// zero until a job finished
+	EstimatedDoneAt int64 `json:"estimated_done_at" yaml:"estimated_done_at" gorm:"column:estimated_done_at;type:bigint"`
+}
+
+// Remaining is the number of jobs still pending, dispatched or processing
+func (p CycleProgress) Remaining() int {
+	return p.TotalJobs - p.CompletedJobs - p.FailedJobs - p.CancelledJobs
 }
 
-type Session struct {
-	UserID string `json:"user_id" yaml:"user_id"`
+// CycleSchedule starts cycles unattended, on a cron expression or a fixed interval
+type CycleSchedule struct {
+	Name string `json:"name" yaml:"name"`
+	// Cron is a five-field expression (minute hour day-of-month month day-of-week) or
+	// one of @hourly, @daily, @weekly and @monthly
+	Cron            string `json:"cron,omitempty" yaml:"cron,omitempty"`
+	IntervalSeconds int    `json:"interval_seconds,omitempty" yaml:"interval_seconds,omitempty"` // Used when Cron is empty
+	Location        string `json:"location,omitempty" yaml:"location,omitempty"`                 // IANA time zone of Cron; defaults to local time
+	// Overlap decides what happens when the previous cycle of the schedule is still
+	// running: skip (default) drops the run, queue starts it once the previous cycle
+	// ends and parallel starts it anyway
+	Overlap string `json:"overlap,omitempty" yaml:"overlap,omitempty"`
+	// Strategy replaces the JobService strategy for the scheduled cycles
+	Strategy *Strategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
 }
 
 type JobServiceConfig struct {
@@ -0,0 +1,84 @@
+package models
+
+// RollupBucketsMs are the upper bounds of the latency buckets of a MetricRollup; one
+// more bucket counts the longer jobs
+var RollupBucketsMs = []int64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// MetricRollup counts the finished jobs of one action of a cycle within one minute, so
+// time series are read without scanning the jobs
+type MetricRollup struct {
+	CycleUUID string `json:"cycle_uuid" yaml:"cycle_uuid" gorm:"primaryKey;column:cycle_uuid;type:uuid"`
+	Action    string `json:"action" yaml:"action" gorm:"primaryKey;column:action;type:text"`
+	// Minute is the Unix time of the start of the minute
+	Minute        int64 `json:"minute" yaml:"minute" gorm:"primaryKey;column:minute;type:bigint;autoIncrement:false"`
+	Jobs          int64 `json:"jobs" yaml:"jobs" gorm:"column:jobs;type:bigint"`
+	Failed        int64 `json:"failed" yaml:"failed" gorm:"column:failed;type:bigint"`
+	DurationSumMs int64 `json:"duration_sum_ms" yaml:"duration_sum_ms" gorm:"column:duration_sum_ms;type:bigint"`
+	DurationMinMs int64 `json:"duration_min_ms" yaml:"duration_min_ms" gorm:"column:duration_min_ms;type:bigint"`
+	DurationMaxMs int64 `json:"duration_max_ms" yaml:"duration_max_ms" gorm:"column:duration_max_ms;type:bigint"`
+	// Buckets counts the jobs per bucket of RollupBucketsMs, the longer ones last
+	Buckets []int64 `json:"buckets" yaml:"buckets" gorm:"column:buckets;type:text;serializer:json"`
+}
+
+// Record counts one job of the action that took durationMs
+func (r *MetricRollup) Record(durationMs int64, failed bool) {
+	if len(r.Buckets) == 0 {
+		r.Buckets = make([]int64, len(RollupBucketsMs)+1)
+	}
+	bucket := len(RollupBucketsMs)
+	for i, bound := range RollupBucketsMs {
+		if durationMs <= bound {
+			bucket = i
+			break
+		}
+	}
+	r.Buckets[bucket]++
+	if r.Jobs == 0 || durationMs < r.DurationMinMs {
+		r.DurationMinMs = durationMs
+	}
+	r.DurationMaxMs = max(r.DurationMaxMs, durationMs)
+	r.DurationSumMs += durationMs
+	r.Jobs++
+	if failed {
+		r.Failed++
+	}
+}
+
+// Merge adds the jobs of other, a rollup of the same action and minute
+func (r *MetricRollup) Merge(other MetricRollup) {
+	if other.Jobs == 0 {
+		return
+	}
+	if len(r.Buckets) == 0 {
+		r.Buckets = make([]int64, len(RollupBucketsMs)+1)
+	}
+	for i, n := range other.Buckets {
+		if i < len(r.Buckets) {
+			r.Buckets[i] += n
+		}
+	}
+	if r.Jobs == 0 || other.DurationMinMs < r.DurationMinMs {
+		r.DurationMinMs = other.DurationMinMs
+	}
+	r.DurationMaxMs = max(r.DurationMaxMs, other.DurationMaxMs)
+	r.DurationSumMs += other.DurationSumMs
+	r.Jobs += other.Jobs
+	r.Failed += other.Failed
+}
+
+// QuantileMs returns the upper bound of the bucket holding the q quantile of the job
+// durations, or their maximum for the last bucket
+func (r MetricRollup) QuantileMs(q float64) int64 {
+	rank := int64(q * float64(r.Jobs))
+	var seen int64
+	for i, n := range r.Buckets {
+		seen += n
+		if seen > rank {
+			if i < len(RollupBucketsMs) {
+				return min(RollupBucketsMs[i], r.DurationMaxMs)
+			}
+			return r.DurationMaxMs
+		}
+	}
+	return r.DurationMaxMs
+}
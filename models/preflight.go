@@ -0,0 +1,69 @@
+package models
+
+import "fmt"
+
+// PreflightRequest asks a worker to verify it can run a cycle's planned actions against the target
+type PreflightRequest struct {
+	CycleUUID string `json:"cycle_uuid" yaml:"cycle_uuid"`
+	// Target is the strategy target the jobs run against; empty without targets
+	Target        string   `json:"target,omitempty" yaml:"target,omitempty"`
+	Actions       []string `json:"actions" yaml:"actions"`
+	RequiredBytes int64    `json:"required_bytes" yaml:"required_bytes"`
+	// MaxFileBytes is the size of the largest file the cycle may upload
+	MaxFileBytes int64 `json:"max_file_bytes,omitempty" yaml:"max_file_bytes,omitempty"`
+}
+
+// PreflightReport is a worker's answer to a PreflightRequest
+type PreflightReport struct {
+	WorkerID      string          `json:"worker_id" yaml:"worker_id"`
+	CredentialsOK bool            `json:"credentials_ok" yaml:"credentials_ok"`
+	Permissions   map[string]bool `json:"permissions" yaml:"permissions"`
+	// AvailableBytes is the free quota on the target, or -1 when unknown
+	AvailableBytes int64 `json:"available_bytes" yaml:"available_bytes"`
+	// MaxUploadBytes is the size of the largest upload the target accepts, or 0 when
+	// it sets no limit
+	MaxUploadBytes int64    `json:"max_upload_bytes,omitempty" yaml:"max_upload_bytes,omitempty"`
+	Errors         []string `json:"errors" yaml:"errors"`
+}
+
+// Failures lists every reason the report does not satisfy the request; empty means the cycle may start
+func (r PreflightReport) Failures(req PreflightRequest) []string {
+	failures := append([]string{}, r.Errors...)
+	if !r.CredentialsOK {
+		failures = append(failures, "target credentials were rejected")
+	}
+	for _, action := range req.Actions {
+		if !r.Permissions[action] {
+			failures = append(failures, fmt.Sprintf("missing permission for action %q", action))
+		}
+	}
+	if r.AvailableBytes >= 0 && r.AvailableBytes < req.RequiredBytes {
+		failures = append(failures, fmt.Sprintf("insufficient target quota: %d bytes available, %d required", r.AvailableBytes, req.RequiredBytes))
+	}
+	if r.MaxUploadBytes > 0 && r.MaxUploadBytes < req.MaxFileBytes {
+		failures = append(failures, fmt.Sprintf("target accepts uploads of up to %d bytes, files of up to %d are planned", r.MaxUploadBytes, req.MaxFileBytes))
+	}
+	return failures
+}
+
+// CyclePlan summarizes what a cycle would do if it started, without creating anything.
+// Job counts are estimates where the strategy draws actions at random.
+type CyclePlan struct {
+	Sessions     int            `json:"sessions" yaml:"sessions"`
+	Targets      map[string]int `json:"targets,omitempty" yaml:"targets,omitempty"` // Sessions by strategy target
+	TotalJobs    int            `json:"total_jobs" yaml:"total_jobs"`
+	WarmUpJobs   int            `json:"warm_up_jobs" yaml:"warm_up_jobs"`
+	TeardownJobs int            `json:"teardown_jobs" yaml:"teardown_jobs"`
+	Jobs         map[string]int `json:"jobs" yaml:"jobs"` // By action, warm-up and teardown included
+	// GeneratedFiles and GeneratedBytes are staged by the generator before the cycle
+	// starts; RequiredBytes is what the uploads need on the target
+	GeneratedFiles int   `json:"generated_files" yaml:"generated_files"`
+	GeneratedBytes int64 `json:"generated_bytes" yaml:"generated_bytes"`
+	RequiredBytes  int64 `json:"required_bytes" yaml:"required_bytes"`
+	// Workers counts the active workers of the cycle's pool, those of its targets
+	// included, that are not draining
+	Workers int `json:"workers" yaml:"workers"`
+	// Problems would keep the cycle from running as planned, e.g. actions no worker of
+	// the pool executes
+	Problems []string `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "context"
+
+// DefaultProject is the project of the rows created outside of any project, including
+// every row of the databases created before projects
+const DefaultProject = "default"
+
+// Project is a tenant of a robo installation: the cycles it starts, with their jobs,
+// users, files and workspaces, are only visible to its callers
+type Project struct {
+	ID          string       `json:"id" yaml:"id" gorm:"primaryKey;type:text"`
+	Description string       `json:"description,omitempty" yaml:"description,omitempty" gorm:"column:description;type:text"`
+	Quota       ProjectQuota `json:"quota" yaml:"quota" gorm:"embedded;embeddedPrefix:quota_"`
+	Audit       `yaml:",inline"`
+}
+
+// ProjectQuota bounds what a project uses; zero fields are unlimited
+type ProjectQuota struct {
+	// MaxRunningCycles is the number of cycles of the project not done yet
+	MaxRunningCycles int `json:"max_running_cycles,omitempty" yaml:"max_running_cycles,omitempty" gorm:"column:max_running_cycles;type:integer"`
+	// MaxCycleJobs is the number of jobs of one cycle, warm-up and teardown included
+	MaxCycleJobs int `json:"max_cycle_jobs,omitempty" yaml:"max_cycle_jobs,omitempty" gorm:"column:max_cycle_jobs;type:integer"`
+}
+
+type projectKey struct{}
+
+// WithProject returns a context whose Store queries only see the rows of project, and
+// whose rows are created in it
+func WithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// ProjectFrom returns the project set on ctx by WithProject, or "" when the context is
+// not scoped to a project and sees every row
+func ProjectFrom(ctx context.Context) string {
+	project, _ := ctx.Value(projectKey{}).(string)
+	return project
+}
@@ -0,0 +1,41 @@
+package models
+
+// Scenario declares what each session of a cycle does, as ordered steps the JobService
+// compiles into a chain of jobs per session
+type Scenario struct {
+	Name  string         `json:"name" yaml:"name"`
+	Steps []ScenarioStep `json:"steps" yaml:"steps"`
+}
+
+// ScenarioStep runs an action, a block of nested steps, or picks one of its branches.
+// A step may combine them: each repetition runs the action, then the nested steps,
+// then one branch.
+type ScenarioStep struct {
+	// ID names the step so later payloads can reference its job with {{job "id"}}
+	ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Probability is the chance the step runs at all; zero means always
+	Probability float64 `json:"probability,omitempty" yaml:"probability,omitempty"`
+	// Repeat loops the step between Min and Max times; nil runs it once
+	Repeat *ScenarioRepeat `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+	// Payload adds text/template values to the input data of the action's job. They
+	// see .User, .Session, .Persona and .Iteration and may call {{file}} for a newly
+	// generated file of the cycle, {{user}} for another user of the cycle and
+	// {{job "id"}} for the UUID of the last job of an earlier step.
+	Payload  map[string]string `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Steps    []ScenarioStep    `json:"steps,omitempty" yaml:"steps,omitempty"`
+	Branches []ScenarioBranch  `json:"branches,omitempty" yaml:"branches,omitempty"`
+}
+
+// ScenarioRepeat bounds the number of times a step runs
+type ScenarioRepeat struct {
+	Min int `json:"min" yaml:"min"`
+	Max int `json:"max" yaml:"max"`
+}
+
+// ScenarioBranch is one alternative of a step, taken with a chance proportional to its
+// Weight
+type ScenarioBranch struct {
+	Weight float64        `json:"weight" yaml:"weight"`
+	Steps  []ScenarioStep `json:"steps" yaml:"steps"`
+}
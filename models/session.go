@@ -0,0 +1,36 @@
+package models
+
+import "encoding/json"
+
+// Session is one user's run of actions within a cycle. It is planned when the cycle
+// starts, active once its first job ran and ended when none of its jobs remain, or
+// cancelled with its cycle. A live session ends once the last step of its plan ran.
+type Session struct {
+	UUID      string          `json:"uuid" yaml:"uuid" gorm:"primaryKey;type:uuid;"`
+	UserID    string          `json:"user_id" yaml:"user_id" gorm:"column:user_id;type:uuid"`
+	UserName  string          `json:"username" yaml:"username" gorm:"column:username;type:text;not null"`
+	Persona   string          `json:"persona,omitempty" yaml:"persona,omitempty" gorm:"column:persona;type:text"`
+	Target    string          `json:"target,omitempty" yaml:"target,omitempty" gorm:"column:target;type:text"` // Strategy target the session runs against
+	CycleUUID string          `json:"cycle_uuid" yaml:"cycle_uuid" gorm:"column:cycle_uuid;type:uuid;not null"`
+	Status    string          `json:"status" yaml:"status" gorm:"column:status;type:text;not null"`
+	StartedAt int64           `json:"started_at" yaml:"started_at" gorm:"column:started_at;type:bigint"`
+	EndedAt   int64           `json:"ended_at" yaml:"ended_at" gorm:"column:ended_at;type:bigint"`
+	State     json.RawMessage `json:"state,omitempty" yaml:"state,omitempty" gorm:"column:state;type:json"` // Carried between the session's actions
+	// Plan lists the steps of a live session, whose jobs are created one at a time:
+	// Step is the index of the next one and CurrentJob the UUID of the latest
+	Plan       []SessionStep `json:"plan,omitempty" yaml:"plan,omitempty" gorm:"column:plan;type:json;serializer:json"`
+	Step       int           `json:"step" yaml:"step" gorm:"column:step;type:integer"`
+	CurrentJob string        `json:"current_job,omitempty" yaml:"current_job,omitempty" gorm:"column:current_job;type:uuid"`
+	Audit      `yaml:",inline"`
+	// Foreign key relationships
+	Cycle Cycle `gorm:"foreignKey:CycleUUID;references:UUID"`
+}
+
+// SessionStep is a job of a live session before it is created; the job runs ThinkTimeMs
+// after the result of the previous one
+type SessionStep struct {
+	JobUUID     string          `json:"job_uuid" yaml:"job_uuid"`
+	Action      string          `json:"action" yaml:"action"`
+	Input       json.RawMessage `json:"input" yaml:"input"`
+	ThinkTimeMs int64           `json:"think_time_ms,omitempty" yaml:"think_time_ms,omitempty"`
+}
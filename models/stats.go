@@ -0,0 +1,60 @@
+package models
+
+// ActionStats summarizes the finished jobs of one action
+type ActionStats struct {
+	Jobs   int     `json:"jobs" yaml:"jobs"`
+	Failed int     `json:"failed" yaml:"failed"`
+	MinMs  int64   `json:"min_ms" yaml:"min_ms"`
+	MaxMs  int64   `json:"max_ms" yaml:"max_ms"`
+	MeanMs float64 `json:"mean_ms" yaml:"mean_ms"`
+	// Percentiles of the job durations, within 1%
+	P50Ms int64 `json:"p50_ms" yaml:"p50_ms"`
+	P90Ms int64 `json:"p90_ms" yaml:"p90_ms"`
+	P95Ms int64 `json:"p95_ms" yaml:"p95_ms"`
+	P99Ms int64 `json:"p99_ms" yaml:"p99_ms"`
+}
+
+// MinuteStats summarizes the jobs of each action that finished within one minute
+type MinuteStats struct {
+	Minute  int64                  `json:"minute" yaml:"minute"` // Unix time of the start of the minute
+	Actions map[string]ActionStats `json:"actions" yaml:"actions"`
+}
+
+// CycleStats summarizes the finished jobs of a cycle per action, overall and minute
+// by minute, and per strategy target when the cycle has several
+type CycleStats struct {
+	CycleUUID string                            `json:"cycle_uuid" yaml:"cycle_uuid"`
+	Actions   map[string]ActionStats            `json:"actions" yaml:"actions"`
+	Minutes   []MinuteStats                     `json:"minutes" yaml:"minutes"`
+	Targets   map[string]map[string]ActionStats `json:"targets,omitempty" yaml:"targets,omitempty"` // Target -> action
+}
+
+// ActionComparison compares the results of one action in a baseline and a candidate
+// cycle. Deltas are relative to the baseline, in percent; throughput is jobs per second
+// of load phase.
+type ActionComparison struct {
+	Baseline            *ActionStats `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	Candidate           *ActionStats `json:"candidate,omitempty" yaml:"candidate,omitempty"`
+	BaselineThroughput  float64      `json:"baseline_throughput" yaml:"baseline_throughput"`
+	CandidateThroughput float64      `json:"candidate_throughput" yaml:"candidate_throughput"`
+	P50DeltaPct         float64      `json:"p50_delta_pct" yaml:"p50_delta_pct"`
+	P95DeltaPct         float64      `json:"p95_delta_pct" yaml:"p95_delta_pct"`
+	P99DeltaPct         float64      `json:"p99_delta_pct" yaml:"p99_delta_pct"`
+	ThroughputDeltaPct  float64      `json:"throughput_delta_pct" yaml:"throughput_delta_pct"`
+	ErrorRateDelta      float64      `json:"error_rate_delta" yaml:"error_rate_delta"` // Candidate minus baseline error rate
+	Regressed           bool         `json:"regressed" yaml:"regressed"`
+}
+
+// CycleComparison compares two finished cycles of a template action by action;
+// Regressed is set when any action regressed beyond the thresholds
+type CycleComparison struct {
+	Template  string `json:"template" yaml:"template"`
+	Baseline  string `json:"baseline" yaml:"baseline"`
+	Candidate string `json:"candidate" yaml:"candidate"`
+	// MaxLatencyRegressionPct bounds the p95 delta and MaxThroughputRegressionPct the
+	// throughput drop of every action
+	MaxLatencyRegressionPct    float64                     `json:"max_latency_regression_pct" yaml:"max_latency_regression_pct"`
+	MaxThroughputRegressionPct float64                     `json:"max_throughput_regression_pct" yaml:"max_throughput_regression_pct"`
+	Actions                    map[string]ActionComparison `json:"actions" yaml:"actions"`
+	Regressed                  bool                        `json:"regressed" yaml:"regressed"`
+}
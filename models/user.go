@@ -3,10 +3,22 @@ package models
 type User struct {
 	UUID        string `json:"uuid" yaml:"uuid" gorm:"primaryKey;type:uuid;"`
 	DisplayName string `json:"display_name" yaml:"display_name" gorm:"column:display_name;type:text;not null"`
-	UserName    string `json:"username" yaml:"username" gorm:"column:username;type:text;unique;not null"`
-	Language    string `json:"language" yaml:"language" gorm:"column:language;type:text;not null"`
-	CycleID     string `json:"cycle_id" yaml:"cycle_id" gorm:"column:cycle_id;type:uuid;not null"`
-	SessionID   string `json:"session_id" yaml:"session_id" gorm:"column:session_id;type:text;not null"`
+	// UserName is unique within the cycle, as a replay of its seed generates the same ones
+	UserName   string `json:"username" yaml:"username" gorm:"column:username;type:text;not null;uniqueIndex:idx_users_cycle_username"`
+	Language   string `json:"language" yaml:"language" gorm:"column:language;type:text;not null"`
+	Email      string `json:"email,omitempty" yaml:"email,omitempty" gorm:"column:email;type:text"`
+	Phone      string `json:"phone,omitempty" yaml:"phone,omitempty" gorm:"column:phone;type:text"`
+	Address    string `json:"address,omitempty" yaml:"address,omitempty" gorm:"column:address;type:text"`
+	JobTitle   string `json:"job_title,omitempty" yaml:"job_title,omitempty" gorm:"column:job_title;type:text"`
+	AvatarPath string `json:"avatar_path,omitempty" yaml:"avatar_path,omitempty" gorm:"column:avatar_path;type:text"`
+	Persona    string `json:"persona,omitempty" yaml:"persona,omitempty" gorm:"column:persona;type:text"`
+	// Password is the credential the session of the user signs in to the target with
+	Password  string `json:"password,omitempty" yaml:"password,omitempty" gorm:"column:password;type:text"`
+	CycleID   string `json:"cycle_id" yaml:"cycle_id" gorm:"column:cycle_id;type:uuid;not null;uniqueIndex:idx_users_cycle_username"`
+	SessionID string `json:"session_id" yaml:"session_id" gorm:"column:session_id;type:text;not null"`
+	// ProjectID is the project the user belongs to
+	ProjectID string `json:"project_id" yaml:"project_id" gorm:"column:project_id;type:text;index"`
+	Audit     `yaml:",inline"`
 	// Foreign key relationships
 	Cycle Cycle `gorm:"foreignKey:CycleID;references:UUID"`
 }
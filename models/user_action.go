@@ -0,0 +1,31 @@
+package models
+
+// UserAction is the audit record of an action the user of a session performed: a job
+// result as it was received, kept when the job is retried or the session goes on
+type UserAction struct {
+	UUID       string `json:"uuid" yaml:"uuid" gorm:"primaryKey;type:uuid;"`
+	CycleUUID  string `json:"cycle_uuid" yaml:"cycle_uuid" gorm:"column:cycle_uuid;type:uuid;not null;index"`
+	SessionID  string `json:"session_id" yaml:"session_id" gorm:"column:session_id;type:uuid;not null;index"`
+	JobUUID    string `json:"job_uuid" yaml:"job_uuid" gorm:"column:job_uuid;type:uuid;not null"`
+	Action     string `json:"action" yaml:"action" gorm:"column:action;type:text;not null"`
+	Phase      string `json:"phase,omitempty" yaml:"phase,omitempty" gorm:"column:phase;type:text"`
+	Status     string `json:"status" yaml:"status" gorm:"column:status;type:text;not null"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty" gorm:"column:error;type:text"`
+	WorkerID   string `json:"worker_id" yaml:"worker_id" gorm:"column:worker_id;type:uuid"`
+	StartAt    int64  `json:"start_at" yaml:"start_at" gorm:"column:start_at;type:bigint"`
+	DoneAt     int64  `json:"done_at" yaml:"done_at" gorm:"column:done_at;type:bigint"`
+	DurationMs int64  `json:"duration_ms" yaml:"duration_ms" gorm:"column:duration_ms;type:bigint"`
+	Audit      `yaml:",inline"`
+	// Foreign key relationships
+	Cycle   Cycle   `json:"-" yaml:"-" gorm:"foreignKey:CycleUUID;references:UUID"`
+	Session Session `json:"-" yaml:"-" gorm:"foreignKey:SessionID;references:UUID"`
+}
+
+// NewUserAction returns the audit record of the result of job
+func NewUserAction(job Job) UserAction {
+	return UserAction{
+		CycleUUID: job.CycleUUID, SessionID: job.SessionID, JobUUID: job.UUID, Action: job.Name, Phase: job.Phase,
+		Status: job.Status, Error: job.Error, WorkerID: job.WorkerID,
+		StartAt: job.StartAt, DoneAt: job.DoneAt, DurationMs: job.DurationMs,
+	}
+}
@@ -3,4 +3,23 @@ package models
 type UserStrategy struct {
 	UserLang        []string  `json:"user_lang" yaml:"user_lang"`
 	LangProbability []float64 `json:"lang_probability" yaml:"lang_probability"`
+	// Profile, when set, fills in contact details and an avatar in the user's locale
+	Profile *ProfileStrategy `json:"profile,omitempty" yaml:"profile,omitempty"`
+	// Personas are assigned to users with PersonaProbability; each names an action
+	// profile in ActionStrategy.Personas
+	Personas           []string  `json:"personas,omitempty" yaml:"personas,omitempty"`
+	PersonaProbability []float64 `json:"persona_probability,omitempty" yaml:"persona_probability,omitempty"`
+}
+
+// ProfileStrategy selects which profile fields are generated for each user
+type ProfileStrategy struct {
+	Email bool `json:"email" yaml:"email"`
+	// EmailDomains are picked from at random; empty means example.<country tld>
+	EmailDomains []string `json:"email_domains,omitempty" yaml:"email_domains,omitempty"`
+	Phone        bool     `json:"phone" yaml:"phone"`
+	Address      bool     `json:"address" yaml:"address"`
+	JobTitle     bool     `json:"job_title" yaml:"job_title"`
+	// AvatarDir, when set, receives a <username>.png avatar for every user
+	AvatarDir  string `json:"avatar_dir,omitempty" yaml:"avatar_dir,omitempty"`
+	AvatarSize int    `json:"avatar_size,omitempty" yaml:"avatar_size,omitempty"` // Edge length in pixels, 128 by default
 }
@@ -1,6 +1,39 @@
 package models
 
+// Worker statuses
+const (
+	WorkerActive       = "active"       // Registered and sending heartbeats
+	WorkerInactive     = "inactive"     // Stopped sending heartbeats
+	WorkerDeregistered = "deregistered" // Shut down cleanly
+)
+
+// Worker is a worker as the dispatcher last heard of it
 type Worker struct {
 	UUID string `json:"uuid" yaml:"uuid" gorm:"primaryKey;type:uuid;"`
 	Name string `json:"name" yaml:"name" gorm:"column:name;type:text;not null"`
+	// Labels place the worker in pools, e.g. {"pool": "eu-west"}; cycles select pools
+	// with Strategy.WorkerLabels
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" gorm:"column:labels;type:text;serializer:json"`
+	// Capabilities are the actions the worker executes; empty for workers that do not
+	// tell
+	Capabilities []string `json:"capabilities,omitempty" yaml:"capabilities,omitempty" gorm:"column:capabilities;type:text;serializer:json"`
+	// Draining workers finish the jobs they have but are dispatched no new ones
+	Draining bool `json:"draining,omitempty" yaml:"draining,omitempty" gorm:"column:draining"`
+	// Status is WorkerActive, WorkerInactive or WorkerDeregistered
+	Status string `json:"status,omitempty" yaml:"status,omitempty" gorm:"column:status;type:text"`
+	// LastSeenAt is when the worker last registered or sent a heartbeat, in Unix seconds
+	LastSeenAt int64 `json:"last_seen_at,omitempty" yaml:"last_seen_at,omitempty" gorm:"column:last_seen_at;type:bigint;index"`
+	// Version is the release of robo the worker runs, when it tells
+	Version string `json:"version,omitempty" yaml:"version,omitempty" gorm:"column:version;type:text"`
+	Audit   `yaml:",inline"`
+}
+
+// HasLabels tells whether the worker carries every label of selector
+func (w Worker) HasLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if w.Labels[k] != v {
+			return false
+		}
+	}
+	return true
 }
@@ -6,6 +6,9 @@ type Workspace struct {
 	Users     []string `json:"users" yaml:"users" gorm:"column:users;type:text;serializer:json;default:'[]'"`
 	CycleID   string   `json:"cycle_id" yaml:"cycle_id" gorm:"column:cycle_id;type:uuid;not null"`
 	SessionID string   `json:"session_id" yaml:"session_id" gorm:"column:session_id;type:text;not null"`
+	// ProjectID is the project the workspace belongs to
+	ProjectID string `json:"project_id" yaml:"project_id" gorm:"column:project_id;type:text;index"`
+	Audit     `yaml:",inline"`
 	// Foreign key relationships
 	Cycle Cycle `gorm:"foreignKey:CycleID;references:UUID"`
 }
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+// Event types
+const (
+	CycleStarted    = "cycle_started"
+	CycleFinished   = "cycle_finished" // Completed, failed its SLOs or error budget, expired, or aborted
+	SLOBreached     = "slo_breached"
+	WorkersDegraded = "workers_degraded"
+	// Events fed by the changes the store saves, which are frequent: they are sent only
+	// to the targets that list them
+	JobFailed          = "job_failed"
+	CycleStatusChanged = "cycle_status_changed"
+)
+
+// changeEvents are the event types sent only to the targets that list them
+var changeEvents = []string{JobFailed, CycleStatusChanged}
+
+// Event is what targets are notified of
+type Event struct {
+	Type     string             `json:"type"`
+	At       int64              `json:"at"`
+	Cycle    *models.Cycle      `json:"cycle,omitempty"`
+	Breaches []models.SLOBreach `json:"breaches,omitempty"`
+	Workers  int                `json:"workers,omitempty"` // Active workers left, for workers_degraded
+	Change   *store.Change      `json:"change,omitempty"`  // For job_failed and cycle_status_changed
+}
+
+// Summary describes the event in one line
+func (e Event) Summary() string {
+	switch e.Type {
+	case CycleStarted:
+		return fmt.Sprintf("robo: cycle %q started (%s)", e.Cycle.Name, e.Cycle.UUID)
+	case CycleFinished:
+		summary := fmt.Sprintf("robo: cycle %q finished %s: %d completed, %d failed, %d cancelled of %d jobs",
+			e.Cycle.Name, e.Cycle.Status, e.Cycle.Progress.CompletedJobs, e.Cycle.Progress.FailedJobs,
+			e.Cycle.Progress.CancelledJobs, e.Cycle.Progress.TotalJobs)
+		if e.Cycle.Reason != "" {
+			summary += " (" + e.Cycle.Reason + ")"
+		}
+		return summary
+	case SLOBreached:
+		var parts []string
+		for _, b := range e.Breaches {
+			parts = append(parts, fmt.Sprintf("%s %s %.4g > %.4g", b.SLO.Action, b.Metric, b.Value, b.Threshold))
+		}
+		return fmt.Sprintf("robo: cycle %q breached its SLOs: %s", e.Cycle.Name, strings.Join(parts, "; "))
+	case WorkersDegraded:
+		return fmt.Sprintf("robo: worker fleet degraded to %d active workers", e.Workers)
+	case JobFailed:
+		return fmt.Sprintf("robo: job %s of cycle %s failed", e.Change.UUID, e.Change.CycleUUID)
+	case CycleStatusChanged:
+		return fmt.Sprintf("robo: cycle %s is %s", e.Change.CycleUUID, e.Change.Status)
+	}
+	return "robo: " + e.Type
+}
+
+// Notifier sends events to the configured targets
+type Notifier interface {
+	// Notify sends event in the background; delivery failures are logged
+	Notify(ctx context.Context, event Event)
+}
+
+// sender delivers a rendered event to one target
+type sender interface {
+	send(ctx context.Context, event Event) error
+}
+
+// target is a sender and the events it wants
+type target struct {
+	sender
+	kind   string
+	events []string
+}
+
+// notifierImpl is the implementation of the Notifier interface
+type notifierImpl struct {
+	logger  logger.Logger
+	targets []target
+}
+
+// NewNotifier creates a Notifier for the targets of the notify config section, which
+// subscribes to events when a target wants the changes of jobs or cycles
+func NewNotifier(configSvc config.ConfigService, logger logger.Logger, events *store.Events) (Notifier, error) {
+	n := &notifierImpl{logger: logger}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for i, cfg := range configSvc.GetConfig().Notify.Targets {
+		var tmpl *template.Template
+		if cfg.Template != "" {
+			var err error
+			if tmpl, err = template.New(cfg.Type).Parse(cfg.Template); err != nil {
+				return nil, fmt.Errorf("notify target %d: invalid template: %v", i, err)
+			}
+		}
+		var s sender
+		switch cfg.Type {
+		case "webhook", "slack":
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("notify target %d: type %s requires a url", i, cfg.Type)
+			}
+			s = &webhookSender{url: cfg.URL, headers: cfg.Headers, slack: cfg.Type == "slack", template: tmpl, client: client}
+		case "email":
+			if cfg.SMTPAddr == "" || cfg.From == "" || len(cfg.To) == 0 {
+				return nil, fmt.Errorf("notify target %d: type email requires smtp_addr, from and to", i)
+			}
+			s = &emailSender{addr: cfg.SMTPAddr, from: cfg.From, to: cfg.To, template: tmpl}
+		default:
+			return nil, fmt.Errorf("notify target %d: unsupported type: %s", i, cfg.Type)
+		}
+		n.targets = append(n.targets, target{sender: s, kind: cfg.Type, events: cfg.Events})
+	}
+	if len(n.targets) > 0 {
+		logger.Info(context.Background(), "Notifications enabled", "targets", len(n.targets))
+	}
+	if events != nil && n.wantsChanges() {
+		events.Subscribe(n.notifyChange)
+	}
+	return n, nil
+}
+
+// wantsChanges tells whether a target lists one of changeEvents
+func (n *notifierImpl) wantsChanges() bool {
+	for _, t := range n.targets {
+		for _, event := range changeEvents {
+			if slices.Contains(t.events, event) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notifyChange notifies the failures of jobs and the status changes of cycles
+func (n *notifierImpl) notifyChange(ctx context.Context, change store.Change) {
+	switch {
+	case change.Kind == store.JobChanged && change.Status == "failed":
+		n.Notify(ctx, Event{Type: JobFailed, At: change.At, Change: &change})
+	case change.Kind == store.CycleChanged:
+		n.Notify(ctx, Event{Type: CycleStatusChanged, At: change.At, Change: &change})
+	}
+}
+
+// Notify sends event to every target that wants it, each in its own goroutine
+func (n *notifierImpl) Notify(ctx context.Context, event Event) {
+	if event.At == 0 {
+		event.At = time.Now().Unix()
+	}
+	for _, t := range n.targets {
+		if len(t.events) > 0 && !slices.Contains(t.events, event.Type) ||
+			len(t.events) == 0 && slices.Contains(changeEvents, event.Type) {
+			continue
+		}
+		go func(t target) {
+			// The event outlives the caller's request
+			sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+			defer cancel()
+			if err := t.send(sendCtx, event); err != nil {
+				n.logger.Error(ctx, "Failed to send notification", "type", t.kind, "event", event.Type, "error", err)
+			}
+		}(t)
+	}
+}
+
+// render executes tmpl over event, or returns fallback when there is no template
+func render(tmpl *template.Template, event Event, fallback func() ([]byte, error)) ([]byte, error) {
+	if tmpl == nil {
+		return fallback()
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("failed to render notification: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// webhookSender posts events to a URL, as Slack messages for Slack incoming webhooks
+type webhookSender struct {
+	url      string
+	headers  map[string]string
+	slack    bool
+	template *template.Template
+	client   *http.Client
+}
+
+// send posts the event and expects a 2xx response
+func (w *webhookSender) send(ctx context.Context, event Event) error {
+	var body []byte
+	var err error
+	if w.slack {
+		var text []byte
+		if text, err = render(w.template, event, func() ([]byte, error) { return []byte(event.Summary()), nil }); err == nil {
+			body, err = json.Marshal(map[string]string{"text": string(text)})
+		}
+	} else {
+		body, err = render(w.template, event, func() ([]byte, error) { return json.Marshal(event) })
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post notification: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// emailSender mails events through an SMTP relay that accepts unauthenticated mail
+type emailSender struct {
+	addr     string
+	from     string
+	to       []string
+	template *template.Template
+}
+
+// send mails the event with its summary as subject
+func (e *emailSender) send(ctx context.Context, event Event) error {
+	body, err := render(e.template, event, func() ([]byte, error) { return []byte(event.Summary()), nil })
+	if err != nil {
+		return err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n", e.from, strings.Join(e.to, ", "), event.Summary())
+	msg.Write(body)
+	if err := smtp.SendMail(e.addr, nil, e.from, e.to, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to mail notification: %w", err)
+	}
+	return nil
+}
+
+// Module defines the Fx module for notifications
+var Module = fx.Module(
+	"notify",
+	fx.Provide(NewNotifier),
+)
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+	"github.com/songvi/robo/store"
+)
+
+type staticConfig config.Config
+
+func (c staticConfig) GetConfig() config.Config { return config.Config(c) }
+
+func TestNotifyWebhooks(t *testing.T) {
+	bodies := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies <- r.URL.Path + " " + r.Header.Get("X-Token") + " " + string(data)
+	}))
+	defer server.Close()
+
+	cfg := staticConfig{Notify: config.NotifyConfig{Targets: []config.NotifyTarget{
+		{Type: "webhook", URL: server.URL + "/hook", Headers: map[string]string{"X-Token": "t"}, Events: []string{CycleFinished}},
+		{Type: "slack", URL: server.URL + "/slack", Template: "{{.Cycle.Name}} is {{.Cycle.Status}}"},
+	}}}
+	n, err := NewNotifier(cfg, logger.NewSlogLogger(), nil)
+	require.NoError(t, err)
+
+	cycle := &models.Cycle{UUID: "c1", Name: "nightly", Status: "completed"}
+	n.Notify(context.Background(), Event{Type: CycleStarted, Cycle: cycle})
+	assert.Equal(t, `/slack  {"text":"nightly is completed"}`, receive(t, bodies))
+
+	n.Notify(context.Background(), Event{Type: CycleFinished, Cycle: cycle})
+	got := []string{receive(t, bodies), receive(t, bodies)}
+	var hook string
+	for _, b := range got {
+		if b[:5] == "/hook" {
+			hook = b
+		}
+	}
+	require.NotEmpty(t, hook, "the webhook wants cycle_finished")
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(hook[len("/hook t "):]), &event))
+	assert.Equal(t, CycleFinished, event.Type)
+	assert.Equal(t, "nightly", event.Cycle.Name)
+	assert.NotZero(t, event.At)
+
+	_, err = NewNotifier(staticConfig{Notify: config.NotifyConfig{Targets: []config.NotifyTarget{{Type: "email"}}}}, logger.NewSlogLogger(), nil)
+	assert.Error(t, err)
+}
+
+// jobStore saves jobs without a database
+type jobStore struct {
+	store.Store
+}
+
+func (jobStore) UpdateJob(ctx context.Context, job *models.Job) error { return nil }
+
+func TestNotifyChanges(t *testing.T) {
+	bodies := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies <- r.URL.Path + " " + string(data)
+	}))
+	defer server.Close()
+
+	cfg := staticConfig{Notify: config.NotifyConfig{Targets: []config.NotifyTarget{
+		{Type: "slack", URL: server.URL + "/failures", Events: []string{JobFailed}},
+		{Type: "slack", URL: server.URL + "/all"},
+	}}}
+	events := store.NewEvents()
+	_, err := NewNotifier(cfg, logger.NewSlogLogger(), events)
+	require.NoError(t, err)
+
+	s := store.NewObservedStore(jobStore{}, events)
+	require.NoError(t, s.UpdateJob(context.Background(), &models.Job{UUID: "j1", CycleUUID: "c1", Status: "completed"}))
+	require.NoError(t, s.UpdateJob(context.Background(), &models.Job{UUID: "j2", CycleUUID: "c1", Status: "failed"}))
+	assert.Equal(t, `/failures {"text":"robo: job j2 of cycle c1 failed"}`, receive(t, bodies))
+	select {
+	case b := <-bodies:
+		t.Fatalf("unexpected notification %s: targets without events are not sent changes", b)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func receive(t *testing.T, bodies <-chan string) string {
+	select {
+	case b := <-bodies:
+		return b
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+		return ""
+	}
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/models"
+)
+
+// RetentionConfig decides how long finished cycles stay in the database
+type RetentionConfig struct {
+	// MaxAgeDays is how long after it finished a cycle is archived and deleted with its
+	// sessions, jobs, user actions, users, workspaces and files; zero keeps cycles forever
+	MaxAgeDays int `json:"max_age_days" yaml:"max_age_days"`
+	// ArchiveDir receives a <cycle uuid>.jsonl.gz archive of every cycle before it is
+	// deleted; empty deletes cycles without archiving them
+	ArchiveDir string `json:"archive_dir" yaml:"archive_dir"`
+	// IntervalMinutes is how often old cycles are looked for; zero means hourly
+	IntervalMinutes int `json:"interval_minutes" yaml:"interval_minutes"`
+}
+
+// ArchiveRecord is one line of a cycle archive: Kind is "cycle", "session", "user",
+// "workspace", "file", "user_action" or "job", and Data the row
+type ArchiveRecord struct {
+	Kind string      `json:"kind"`
+	Data interface{} `json:"data"`
+}
+
+// ListCyclesDoneBefore returns up to limit cycles that finished before the Unix time
+// before, oldest first
+func (s *GORMStore) ListCyclesDoneBefore(ctx context.Context, before int64, limit int) ([]models.Cycle, error) {
+	var cycles []models.Cycle
+	if err := s.db.WithContext(ctx).Where("done_at > 0 AND done_at < ?", before).
+		Order("done_at").Limit(limit).Find(&cycles).Error; err != nil {
+		return nil, err
+	}
+	return cycles, nil
+}
+
+// ArchiveCycle writes the cycle and everything it created to w as gzipped JSON lines,
+// the cycle first; jobs are read in batches so large cycles are not held in memory
+func (s *GORMStore) ArchiveCycle(ctx context.Context, cycleUUID string, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	write := func(kind string, rows interface{}) error {
+		return enc.Encode(ArchiveRecord{Kind: kind, Data: rows})
+	}
+	db := s.db.WithContext(ctx)
+
+	var cycle models.Cycle
+	if err := db.First(&cycle, "uuid = ?", cycleUUID).Error; err != nil {
+		return err
+	}
+	if err := write("cycle", cycle); err != nil {
+		return err
+	}
+	var sessions []models.Session
+	if err := db.Where("cycle_uuid = ?", cycleUUID).Find(&sessions).Error; err != nil {
+		return fmt.Errorf("failed to read sessions: %v", err)
+	}
+	for _, session := range sessions {
+		if err := write("session", session); err != nil {
+			return err
+		}
+	}
+	var users []models.User
+	if err := db.Where("cycle_id = ?", cycleUUID).Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to read users: %v", err)
+	}
+	for _, user := range users {
+		if err := write("user", user); err != nil {
+			return err
+		}
+	}
+	var workspaces []models.Workspace
+	if err := db.Where("cycle_id = ?", cycleUUID).Find(&workspaces).Error; err != nil {
+		return fmt.Errorf("failed to read workspaces: %v", err)
+	}
+	for _, workspace := range workspaces {
+		if err := write("workspace", workspace); err != nil {
+			return err
+		}
+	}
+	var files []models.File
+	if err := db.Where("cycle_id = ?", cycleUUID).Find(&files).Error; err != nil {
+		return fmt.Errorf("failed to read files: %v", err)
+	}
+	for _, file := range files {
+		if err := write("file", file); err != nil {
+			return err
+		}
+	}
+	var actions []models.UserAction
+	err := db.Where("cycle_uuid = ?", cycleUUID).FindInBatches(&actions, s.batchSize, func(tx *gorm.DB, batch int) error {
+		for _, action := range actions {
+			if err := write("user_action", action); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to read user actions: %v", err)
+	}
+	err = s.ScanCycleJobs(ctx, cycleUUID, func(jobs []models.Job) error {
+		for _, job := range jobs {
+			if err := write("job", job); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read jobs: %v", err)
+	}
+	return zw.Close()
+}
+
+// DeleteCycleData deletes a cycle with its sessions, jobs, user actions, metric
+// rollups, users, workspaces and files for good, soft-deleted rows included, all or none
+func (s *GORMStore) DeleteCycleData(ctx context.Context, cycleUUID string) error {
+	return s.db.WithContext(ctx).Unscoped().Transaction(func(tx *gorm.DB) error {
+		deletes := []struct {
+			model  interface{}
+			column string
+		}{
+			{&models.UserAction{}, "cycle_uuid"},
+			{&models.MetricRollup{}, "cycle_uuid"},
+			{&models.Job{}, "cycle_uuid"},
+			{&models.Session{}, "cycle_uuid"},
+			{&models.File{}, "cycle_id"},
+			{&models.Workspace{}, "cycle_id"},
+			{&models.User{}, "cycle_id"},
+			{&models.Cycle{}, "uuid"},
+		}
+		for _, d := range deletes {
+			if err := tx.Where(d.column+" = ?", cycleUUID).Delete(d.model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,294 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/models"
+)
+
+// CacheConfig sizes the read-through cache of the cycles, workers and users the Store
+// looks up by ID
+type CacheConfig struct {
+	// Size is the number of rows of each model cached; zero disables the cache
+	Size int `json:"size" yaml:"size"`
+	// TTLSeconds is how long a cached row is served before it is read again, which
+	// bounds how stale rows written by another robo process can be; zero means a minute
+	TTLSeconds int `json:"ttl_seconds" yaml:"ttl_seconds"`
+}
+
+// lruEntry is a cached row with when it expires
+type lruEntry[T any] struct {
+	key     string
+	value   T
+	expires time.Time
+}
+
+// lruCache keeps up to size rows by key for ttl, evicting the least recently used
+type lruCache[T any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	order   *list.List // Most recently used first
+	entries map[string]*list.Element
+}
+
+func newLRUCache[T any](size int, ttl time.Duration) *lruCache[T] {
+	return &lruCache[T]{size: size, ttl: ttl, now: time.Now, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the row cached for key unless it expired
+func (c *lruCache[T]) get(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero T
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[T])
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// put caches value for key, evicting the least recently used row when full
+func (c *lruCache[T]) put(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry[T]{key: key, value: value, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[T]).key)
+	}
+}
+
+func (c *lruCache[T]) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+func (c *lruCache[T]) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// CachedStore is a Store that serves GetCycle, GetWorker and GetUser from an in-memory
+// cache, which its writes invalidate; contexts scoped to a project only get its rows. The rows it returns are shallow copies: callers
+// save the changes they make to them rather than sharing them.
+type CachedStore struct {
+	Store
+	cycles  *lruCache[models.Cycle]
+	workers *lruCache[models.Worker]
+	users   *lruCache[models.User]
+	// invalidated is set on the Store of a transaction, whose reads bypass the cache
+	// as its writes are not committed yet; it collects the writes to invalidate again
+	// once the transaction ends, in case a concurrent read cached the old rows
+	invalidated *[]func()
+}
+
+// NewCachedStore caches the lookups of store as cfg sizes them
+func NewCachedStore(store Store, cfg CacheConfig) *CachedStore {
+	ttl := time.Duration(cfg.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &CachedStore{
+		Store:   store,
+		cycles:  newLRUCache[models.Cycle](cfg.Size, ttl),
+		workers: newLRUCache[models.Worker](cfg.Size, ttl),
+		users:   newLRUCache[models.User](cfg.Size, ttl),
+	}
+}
+
+// invalidate applies fn now and, in a transaction, again when it ends
+func (c *CachedStore) invalidate(fn func()) {
+	fn()
+	if c.invalidated != nil {
+		*c.invalidated = append(*c.invalidated, fn)
+	}
+}
+
+// cachedGet returns the row of id from cache, or reads it with get and caches it
+func cachedGet[T any](c *CachedStore, cache *lruCache[T], id string, get func() (*T, error)) (*T, error) {
+	if c.invalidated == nil {
+		if row, ok := cache.get(id); ok {
+			return &row, nil
+		}
+	}
+	row, err := get()
+	if err != nil {
+		return nil, err
+	}
+	if c.invalidated == nil {
+		cache.put(id, *row)
+	}
+	return row, nil
+}
+
+// inProject reports whether a row of project is visible to the context, as the cache
+// serves the rows of every project
+func inProject(ctx context.Context, project string) bool {
+	scoped := models.ProjectFrom(ctx)
+	return scoped == "" || scoped == project
+}
+
+func (c *CachedStore) GetCycle(ctx context.Context, id string) (*models.Cycle, error) {
+	cycle, err := cachedGet(c, c.cycles, id, func() (*models.Cycle, error) { return c.Store.GetCycle(ctx, id) })
+	if err == nil && !inProject(ctx, cycle.ProjectID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return cycle, err
+}
+
+func (c *CachedStore) CreateCycle(ctx context.Context, cycle *models.Cycle) error {
+	err := c.Store.CreateCycle(ctx, cycle)
+	c.invalidate(func() { c.cycles.remove(cycle.UUID) })
+	return err
+}
+
+func (c *CachedStore) UpdateCycle(ctx context.Context, cycle *models.Cycle) error {
+	err := c.Store.UpdateCycle(ctx, cycle)
+	c.invalidate(func() { c.cycles.remove(cycle.UUID) })
+	return err
+}
+
+func (c *CachedStore) UpdateCycleColumns(ctx context.Context, cycle *models.Cycle, columns ...string) error {
+	err := c.Store.UpdateCycleColumns(ctx, cycle, columns...)
+	c.invalidate(func() { c.cycles.remove(cycle.UUID) })
+	return err
+}
+
+func (c *CachedStore) UpdateCycleStatus(ctx context.Context, cycle *models.Cycle, from []string, columns ...string) error {
+	err := c.Store.UpdateCycleStatus(ctx, cycle, from, columns...)
+	c.invalidate(func() { c.cycles.remove(cycle.UUID) })
+	return err
+}
+
+func (c *CachedStore) DeleteCycle(ctx context.Context, id string) error {
+	err := c.Store.DeleteCycle(ctx, id)
+	c.invalidate(func() { c.cycles.remove(id) })
+	return err
+}
+
+// DeleteCycleData also deletes the users of the cycle, so every user is invalidated
+func (c *CachedStore) DeleteCycleData(ctx context.Context, cycleUUID string) error {
+	err := c.Store.DeleteCycleData(ctx, cycleUUID)
+	c.invalidate(func() {
+		c.cycles.remove(cycleUUID)
+		c.users.clear()
+	})
+	return err
+}
+
+func (c *CachedStore) GetWorker(ctx context.Context, id string) (*models.Worker, error) {
+	return cachedGet(c, c.workers, id, func() (*models.Worker, error) { return c.Store.GetWorker(ctx, id) })
+}
+
+func (c *CachedStore) CreateWorker(ctx context.Context, worker *models.Worker) error {
+	err := c.Store.CreateWorker(ctx, worker)
+	c.invalidate(func() { c.workers.remove(worker.UUID) })
+	return err
+}
+
+func (c *CachedStore) UpdateWorker(ctx context.Context, worker *models.Worker) error {
+	err := c.Store.UpdateWorker(ctx, worker)
+	c.invalidate(func() { c.workers.remove(worker.UUID) })
+	return err
+}
+
+func (c *CachedStore) DeleteWorker(ctx context.Context, id string) error {
+	err := c.Store.DeleteWorker(ctx, id)
+	c.invalidate(func() { c.workers.remove(id) })
+	return err
+}
+
+func (c *CachedStore) UpsertWorker(ctx context.Context, worker *models.Worker) error {
+	err := c.Store.UpsertWorker(ctx, worker)
+	c.invalidate(func() { c.workers.remove(worker.UUID) })
+	return err
+}
+
+func (c *CachedStore) TouchWorker(ctx context.Context, id string, seenAt int64) error {
+	err := c.Store.TouchWorker(ctx, id, seenAt)
+	c.invalidate(func() { c.workers.remove(id) })
+	return err
+}
+
+func (c *CachedStore) UpdateWorkerStatus(ctx context.Context, id, status string) error {
+	err := c.Store.UpdateWorkerStatus(ctx, id, status)
+	c.invalidate(func() { c.workers.remove(id) })
+	return err
+}
+
+func (c *CachedStore) GetUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := cachedGet(c, c.users, id, func() (*models.User, error) { return c.Store.GetUser(ctx, id) })
+	if err == nil && !inProject(ctx, user.ProjectID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, err
+}
+
+func (c *CachedStore) CreateUser(ctx context.Context, user *models.User) error {
+	err := c.Store.CreateUser(ctx, user)
+	c.invalidate(func() { c.users.remove(user.UUID) })
+	return err
+}
+
+func (c *CachedStore) UpdateUser(ctx context.Context, user *models.User) error {
+	err := c.Store.UpdateUser(ctx, user)
+	c.invalidate(func() { c.users.remove(user.UUID) })
+	return err
+}
+
+func (c *CachedStore) DeleteUser(ctx context.Context, id string) error {
+	err := c.Store.DeleteUser(ctx, id)
+	c.invalidate(func() { c.users.remove(id) })
+	return err
+}
+
+// Purge deletes soft-deleted rows of every table, so the whole cache is invalidated
+func (c *CachedStore) Purge(ctx context.Context, deletedBefore int64) (map[string]int64, error) {
+	purged, err := c.Store.Purge(ctx, deletedBefore)
+	c.invalidate(func() {
+		c.cycles.clear()
+		c.workers.clear()
+		c.users.clear()
+	})
+	return purged, err
+}
+
+// WithTx calls fn with a Store of the transaction that reads around the cache and
+// invalidates its writes again once the transaction commits or rolls back
+func (c *CachedStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	var invalidated []func()
+	err := c.Store.WithTx(ctx, func(tx Store) error {
+		return fn(&CachedStore{Store: tx, cycles: c.cycles, workers: c.workers, users: c.users, invalidated: &invalidated})
+	})
+	for _, invalidate := range invalidated {
+		c.invalidate(invalidate)
+	}
+	return err
+}
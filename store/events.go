@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/songvi/robo/models"
+)
+
+// Kinds of changes
+const (
+	JobChanged   = "job"   // A job moved to Status
+	JobsChanged  = "jobs"  // Count jobs, of a cycle unless CycleUUID is empty, moved to Status at once
+	CycleChanged = "cycle" // A cycle was created or moved to Status
+)
+
+// Change is a status change the Store saved
+type Change struct {
+	Kind      string `json:"kind"`
+	UUID      string `json:"uuid,omitempty"` // The job or cycle; empty for JobsChanged
+	CycleUUID string `json:"cycle_uuid"`
+	// ProjectID is the project of the job or cycle, or of the context of the write when
+	// the Store does not read it; empty when the write was not scoped to a project
+	ProjectID string `json:"project_id,omitempty"`
+	Status    string `json:"status"`
+	Phase     string `json:"phase,omitempty"`
+	Count     int64  `json:"count,omitempty"` // Jobs changed, for JobsChanged
+	At        int64  `json:"at"`
+}
+
+// Observer is called with every change the Store saves, once it is committed. It runs
+// in the goroutine of the write, so it must not block: observers hand changes off to
+// their own goroutine, and drop them when they cannot keep up.
+type Observer func(ctx context.Context, change Change)
+
+// Events lets observers subscribe to the status changes of jobs and cycles
+type Events struct {
+	mu        sync.RWMutex
+	next      int
+	observers map[int]Observer
+}
+
+// NewEvents returns Events without observers
+func NewEvents() *Events {
+	return &Events{observers: make(map[int]Observer)}
+}
+
+// Subscribe calls observer with every change from now on, until unsubscribe is called
+func (e *Events) Subscribe(observer Observer) (unsubscribe func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	id := e.next
+	e.next++
+	e.observers[id] = observer
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.observers, id)
+	}
+}
+
+func (e *Events) publish(ctx context.Context, change Change) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, observer := range e.observers {
+		observer(ctx, change)
+	}
+}
+
+// ObservedStore is a Store that publishes the status changes of the jobs and cycles it
+// saves to Events. The status of a cycle is published when it differs from the one
+// last published, as cycles are saved for their progress as well.
+type ObservedStore struct {
+	Store
+	events *Events
+	// cycles holds the status last published per cycle
+	cycles *sync.Map
+	// pending is set on the Store of a transaction: it collects the changes to publish
+	// once the transaction commits
+	pending *[]Change
+}
+
+// NewObservedStore publishes the changes store saves to events
+func NewObservedStore(store Store, events *Events) *ObservedStore {
+	return &ObservedStore{Store: store, events: events, cycles: &sync.Map{}}
+}
+
+// publish publishes changes, or holds them until the transaction commits
+func (o *ObservedStore) publish(ctx context.Context, changes ...Change) {
+	if o.pending != nil {
+		*o.pending = append(*o.pending, changes...)
+		return
+	}
+	now := time.Now().Unix()
+	for _, change := range changes {
+		if change.Kind == CycleChanged {
+			if last, ok := o.cycles.Swap(change.UUID, change.Status); ok && last == change.Status {
+				continue
+			}
+		}
+		if change.At == 0 {
+			change.At = now
+		}
+		if change.ProjectID == "" {
+			change.ProjectID = models.ProjectFrom(ctx)
+		}
+		o.events.publish(ctx, change)
+	}
+}
+
+func jobChange(job *models.Job) Change {
+	return Change{Kind: JobChanged, UUID: job.UUID, CycleUUID: job.CycleUUID, ProjectID: job.ProjectID, Status: job.Status, Phase: job.Phase}
+}
+
+func cycleChange(cycle *models.Cycle) Change {
+	return Change{Kind: CycleChanged, UUID: cycle.UUID, CycleUUID: cycle.UUID, ProjectID: cycle.ProjectID, Status: cycle.Status, Phase: cycle.Phase}
+}
+
+func (o *ObservedStore) UpdateJob(ctx context.Context, job *models.Job) error {
+	if err := o.Store.UpdateJob(ctx, job); err != nil {
+		return err
+	}
+	o.publish(ctx, jobChange(job))
+	return nil
+}
+
+func (o *ObservedStore) UpdateJobs(ctx context.Context, jobs []models.Job) error {
+	if err := o.Store.UpdateJobs(ctx, jobs); err != nil {
+		return err
+	}
+	changes := make([]Change, len(jobs))
+	for i := range jobs {
+		changes[i] = jobChange(&jobs[i])
+	}
+	o.publish(ctx, changes...)
+	return nil
+}
+
+// UpdateJobStatus publishes the change without the cycle of the job, which it does
+// not read
+func (o *ObservedStore) UpdateJobStatus(ctx context.Context, id string, from []string, to string) error {
+	if err := o.Store.UpdateJobStatus(ctx, id, from, to); err != nil {
+		return err
+	}
+	o.publish(ctx, Change{Kind: JobChanged, UUID: id, Status: to})
+	return nil
+}
+
+func (o *ObservedStore) UpdateCycleJobsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error) {
+	n, err := o.Store.UpdateCycleJobsStatus(ctx, cycleUUID, from, to)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	o.publish(ctx, Change{Kind: JobsChanged, CycleUUID: cycleUUID, Status: to, Count: n})
+	return n, nil
+}
+
+// UpdateJobsStatus publishes one change for the jobs it moved, which may belong to
+// several cycles
+func (o *ObservedStore) UpdateJobsStatus(ctx context.Context, uuids []string, status string) (int64, error) {
+	n, err := o.Store.UpdateJobsStatus(ctx, uuids, status)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	o.publish(ctx, Change{Kind: JobsChanged, Status: status, Count: n})
+	return n, nil
+}
+
+func (o *ObservedStore) CreateCycle(ctx context.Context, cycle *models.Cycle) error {
+	if err := o.Store.CreateCycle(ctx, cycle); err != nil {
+		return err
+	}
+	o.publish(ctx, cycleChange(cycle))
+	return nil
+}
+
+func (o *ObservedStore) UpdateCycle(ctx context.Context, cycle *models.Cycle) error {
+	if err := o.Store.UpdateCycle(ctx, cycle); err != nil {
+		return err
+	}
+	o.publish(ctx, cycleChange(cycle))
+	return nil
+}
+
+// UpdateCycleStatus publishes the status it saved; UpdateCycleColumns publishes nothing,
+// as the status of the cycle it is given may be stale
+func (o *ObservedStore) UpdateCycleStatus(ctx context.Context, cycle *models.Cycle, from []string, columns ...string) error {
+	if err := o.Store.UpdateCycleStatus(ctx, cycle, from, columns...); err != nil {
+		return err
+	}
+	o.publish(ctx, cycleChange(cycle))
+	return nil
+}
+
+func (o *ObservedStore) DeleteCycleData(ctx context.Context, cycleUUID string) error {
+	if err := o.Store.DeleteCycleData(ctx, cycleUUID); err != nil {
+		return err
+	}
+	o.cycles.Delete(cycleUUID)
+	return nil
+}
+
+// WithTx calls fn with a Store of the transaction whose changes are published once it
+// commits, and dropped when it rolls back
+func (o *ObservedStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	var pending []Change
+	err := o.Store.WithTx(ctx, func(tx Store) error {
+		return fn(&ObservedStore{Store: tx, events: o.events, cycles: o.cycles, pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+	o.publish(ctx, pending...)
+	return nil
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/logger"
+)
+
+// queryBuckets are the upper bounds, in seconds, of the query duration histogram
+var queryBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5}
+
+// operationStats accumulates the queries of one GORM operation
+type operationStats struct {
+	queries int64
+	errors  int64
+	rows    int64
+	seconds float64
+	buckets []int64 // Queries no longer than each of queryBuckets
+}
+
+// QueryMetrics counts the queries of the store per operation: "create", "query",
+// "update", "delete", "row" or "raw"
+type QueryMetrics struct {
+	mu         sync.Mutex
+	operations map[string]*operationStats
+}
+
+// NewQueryMetrics returns empty QueryMetrics
+func NewQueryMetrics() *QueryMetrics {
+	return &QueryMetrics{operations: make(map[string]*operationStats)}
+}
+
+// record accounts for one query of operation that took elapsed and affected rows
+func (m *QueryMetrics) record(operation string, elapsed time.Duration, rows int64, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op, ok := m.operations[operation]
+	if !ok {
+		op = &operationStats{buckets: make([]int64, len(queryBuckets))}
+		m.operations[operation] = op
+	}
+	op.queries++
+	if failed {
+		op.errors++
+	}
+	if rows > 0 {
+		op.rows += rows
+	}
+	op.seconds += elapsed.Seconds()
+	for i, bound := range queryBuckets {
+		if elapsed.Seconds() <= bound {
+			op.buckets[i]++
+		}
+	}
+}
+
+// WriteMetrics writes the query metrics in the Prometheus text exposition format
+func (m *QueryMetrics) WriteMetrics(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	operations := make([]string, 0, len(m.operations))
+	for operation := range m.operations {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	bw := bufio.NewWriter(w)
+	counters := []struct {
+		name, help string
+		value      func(op *operationStats) int64
+	}{
+		{"robo_store_queries_total", "Database queries, by operation.", func(op *operationStats) int64 { return op.queries }},
+		{"robo_store_query_errors_total", "Failed database queries, by operation.", func(op *operationStats) int64 { return op.errors }},
+		{"robo_store_rows_affected_total", "Rows affected by database queries, by operation.", func(op *operationStats) int64 { return op.rows }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for _, operation := range operations {
+			fmt.Fprintf(bw, "%s{operation=%q} %d\n", c.name, operation, c.value(m.operations[operation]))
+		}
+	}
+	const histogram = "robo_store_query_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Duration of database queries, by operation.\n# TYPE %s histogram\n", histogram, histogram)
+	for _, operation := range operations {
+		op := m.operations[operation]
+		for i, bound := range queryBuckets {
+			fmt.Fprintf(bw, "%s_bucket{operation=%q,le=\"%g\"} %d\n", histogram, operation, bound, op.buckets[i])
+		}
+		fmt.Fprintf(bw, "%s_bucket{operation=%q,le=\"+Inf\"} %d\n", histogram, operation, op.queries)
+		fmt.Fprintf(bw, "%s_sum{operation=%q} %g\n", histogram, operation, op.seconds)
+		fmt.Fprintf(bw, "%s_count{operation=%q} %d\n", histogram, operation, op.queries)
+	}
+	return bw.Flush()
+}
+
+// startedKey is the statement setting holding when a query started
+const startedKey = "robo:started"
+
+// Instrument records the duration, outcome and rows affected of every query of db in
+// metrics, and logs the queries that take slowQuery or longer when slowQuery is set.
+// Missing records are not counted as errors; slow queries are logged without their
+// values, which may hold credentials.
+func Instrument(db *gorm.DB, metrics *QueryMetrics, log logger.Logger, slowQuery time.Duration) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(startedKey, time.Now())
+	}
+	after := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			value, ok := tx.InstanceGet(startedKey)
+			if !ok {
+				return
+			}
+			elapsed := time.Since(value.(time.Time))
+			failed := tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound)
+			metrics.record(operation, elapsed, tx.Statement.RowsAffected, failed)
+			if slowQuery > 0 && elapsed >= slowQuery {
+				log.Info(tx.Statement.Context, "Slow database query", "operation", operation, "table", tx.Statement.Table,
+					"duration_ms", elapsed.Milliseconds(), "rows", tx.Statement.RowsAffected, "sql", tx.Statement.SQL.String())
+			}
+		}
+	}
+
+	// Method values, as GORM does not export the types of its callback processors
+	type register func(name string, fn func(*gorm.DB)) error
+	callbacks := db.Callback()
+	processors := []struct {
+		operation     string
+		before, after register
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, p := range processors {
+		if err := p.before("robo:before_"+p.operation, before); err != nil {
+			return fmt.Errorf("failed to instrument %s queries: %v", p.operation, err)
+		}
+		if err := p.after("robo:after_"+p.operation, after(p.operation)); err != nil {
+			return fmt.Errorf("failed to instrument %s queries: %v", p.operation, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/models"
+)
+
+// ErrCycleNotFound is returned when rows are created for a cycle that does not exist
+var ErrCycleNotFound = errors.New("cycle not found")
+
+// ErrDuplicateUUID is returned when a row is created with the UUID of another one
+var ErrDuplicateUUID = errors.New("duplicate UUID")
+
+// checkCycles returns ErrCycleNotFound unless every cycle of cycleUUIDs exists and is
+// visible to the context of db
+func checkCycles(db *gorm.DB, cycleUUIDs ...string) error {
+	wanted := make(map[string]bool, len(cycleUUIDs))
+	for _, id := range cycleUUIDs {
+		wanted[id] = true
+	}
+	ids := make([]string, 0, len(wanted))
+	for id := range wanted {
+		ids = append(ids, id)
+	}
+	var found []string
+	if err := db.Model(&models.Cycle{}).Where("uuid IN ?", ids).Pluck("uuid", &found).Error; err != nil {
+		return fmt.Errorf("failed to read cycles: %v", err)
+	}
+	for _, id := range found {
+		delete(wanted, id)
+	}
+	for id := range wanted {
+		return fmt.Errorf("%w: %q", ErrCycleNotFound, id)
+	}
+	return nil
+}
+
+// checkUUIDs returns ErrDuplicateUUID when one of uuids repeats or is taken by a row of
+// model, soft deleted or not, reading them in batches of batchSize. Empty UUIDs are left
+// out: the Store generates random ones, which are not checked.
+func checkUUIDs(db *gorm.DB, model interface{}, batchSize int, uuids ...string) error {
+	// UUIDs are unique across projects
+	db = db.WithContext(models.WithProject(db.Statement.Context, ""))
+	seen := make(map[string]bool, len(uuids))
+	var given []string
+	for _, id := range uuids {
+		if id == "" {
+			continue
+		}
+		if seen[id] {
+			return fmt.Errorf("%w: %s given twice", ErrDuplicateUUID, id)
+		}
+		seen[id] = true
+		given = append(given, id)
+	}
+	for start := 0; start < len(given); start += batchSize {
+		var taken []string
+		batch := given[start:min(start+batchSize, len(given))]
+		if err := db.Unscoped().Model(model).Where("uuid IN ?", batch).Limit(1).Pluck("uuid", &taken).Error; err != nil {
+			return fmt.Errorf("failed to read UUIDs: %v", err)
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("%w: %s", ErrDuplicateUUID, taken[0])
+		}
+	}
+	return nil
+}
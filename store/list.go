@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/songvi/robo/models"
+)
+
+// ErrInvalidPage is returned for a page with an unknown sort or a malformed cursor
+var ErrInvalidPage = errors.New("invalid page")
+
+// DefaultPageLimit and MaxPageLimit bound the number of items of a page
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// jobSorts are the job columns a page can be sorted by
+var jobSorts = map[string]bool{"start_at": true, "done_at": true, "duration_ms": true, "dispatched_at": true}
+
+// JobFilter selects jobs; empty fields match every job
+type JobFilter struct {
+	CycleUUID string
+	SessionID string
+	Statuses  []string
+	WorkerID  string
+	// Since and Until bound StartAt in Unix seconds, Since included and Until excluded;
+	// zero leaves the bound open
+	Since int64
+	Until int64
+}
+
+// Page selects a page of a list sorted by Sort, then by UUID to break ties
+type Page struct {
+	Sort   string // Column to sort by; start_at by default
+	Desc   bool
+	Limit  int    // DefaultPageLimit when zero, at most MaxPageLimit
+	Cursor string // NextCursor of the previous page; empty for the first page
+}
+
+// JobPage is a page of jobs; NextCursor is empty on the last page
+type JobPage struct {
+	Jobs       []models.Job `json:"jobs"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// cursor is the position after the last item of a page
+type cursor struct {
+	Value int64  `json:"v"`
+	UUID  string `json:"id"`
+}
+
+// ListJobs returns a page of the jobs matching filter. Pages follow one another from
+// the last item of the previous one, so jobs saved meanwhile do not shift them.
+func (s *GORMStore) ListJobs(ctx context.Context, filter JobFilter, page Page) (JobPage, error) {
+	if page.Sort == "" {
+		page.Sort = "start_at"
+	}
+	if !jobSorts[page.Sort] {
+		return JobPage{}, fmt.Errorf("%w: cannot sort jobs by %s", ErrInvalidPage, page.Sort)
+	}
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageLimit
+	}
+	page.Limit = min(page.Limit, MaxPageLimit)
+
+	query := s.db.WithContext(ctx)
+	if filter.CycleUUID != "" {
+		query = query.Where("cycle_uuid = ?", filter.CycleUUID)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.WorkerID != "" {
+		query = query.Where("worker_id = ?", filter.WorkerID)
+	}
+	if filter.Since > 0 {
+		query = query.Where("start_at >= ?", filter.Since)
+	}
+	if filter.Until > 0 {
+		query = query.Where("start_at < ?", filter.Until)
+	}
+
+	order, after := "ASC", ">"
+	if page.Desc {
+		order, after = "DESC", "<"
+	}
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return JobPage{}, err
+		}
+		query = query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND uuid %[2]s ?))", page.Sort, after), c.Value, c.Value, c.UUID)
+	}
+
+	// One more job than the page holds tells whether another page follows
+	var jobs []models.Job
+	err := query.Order(fmt.Sprintf("%s %s, uuid %s", page.Sort, order, order)).Limit(page.Limit + 1).Find(&jobs).Error
+	if err != nil {
+		return JobPage{}, err
+	}
+	result := JobPage{Jobs: jobs}
+	if len(jobs) > page.Limit {
+		result.Jobs = jobs[:page.Limit]
+		last := result.Jobs[page.Limit-1]
+		result.NextCursor = encodeCursor(cursor{Value: jobSortValue(last, page.Sort), UUID: last.UUID})
+	}
+	return result, nil
+}
+
+// jobSortValue returns the value of the sort column of job
+func jobSortValue(job models.Job, sort string) int64 {
+	switch sort {
+	case "done_at":
+		return job.DoneAt
+	case "duration_ms":
+		return job.DurationMs
+	case "dispatched_at":
+		return job.DispatchedAt
+	default:
+		return job.StartAt
+	}
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.UUID == "" {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+	return c, nil
+}
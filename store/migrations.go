@@ -0,0 +1,392 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/models"
+)
+
+// migrationsTable records the IDs of the migrations applied to a database
+const migrationsTable = "migrations"
+
+// migrations upgrade the schema from one robo release to the next, in order. A
+// migration is never edited once released: changes to the models come with a new one,
+// which adds what it needs only when missing so databases created by the initial
+// migration of a later release upgrade as well.
+var migrations = []*gormigrate.Migration{
+	{
+		// The schema robo created with AutoMigrate before migrations were versioned;
+		// databases created that way adopt it as their first migration
+		ID: "202610150001_initial",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&models.Job{},
+				&models.Worker{},
+				&models.User{},
+				&models.File{},
+				&models.Workspace{},
+				&models.Cycle{},
+				&models.Session{},
+				&models.CycleTemplate{},
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&models.Job{},
+				&models.Worker{},
+				&models.User{},
+				&models.File{},
+				&models.Workspace{},
+				&models.Cycle{},
+				&models.Session{},
+				&models.CycleTemplate{},
+			)
+		},
+	},
+	{
+		// The audit trail of the actions of sessions
+		ID: "202610150002_user_actions",
+		Migrate: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable("user_actions") {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&userActionV2{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_actions")
+		},
+	},
+	{
+		// Soft deletes and audit columns on every table
+		ID: "202610150003_audit_columns",
+		Migrate: func(tx *gorm.DB) error {
+			for _, table := range auditTables {
+				m := tx.Table(table).Migrator()
+				for _, column := range auditColumns {
+					if m.HasColumn(&auditV3{}, column) {
+						continue
+					}
+					if err := m.AddColumn(&auditV3{}, column); err != nil {
+						return fmt.Errorf("failed to add %s to %s: %v", column, table, err)
+					}
+				}
+				index := "idx_" + table + "_deleted_at"
+				if !m.HasIndex(&auditV3{}, index) {
+					if err := tx.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (deleted_at)", index, table)).Error; err != nil {
+						return fmt.Errorf("failed to index %s: %v", table, err)
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, table := range auditTables {
+				m := tx.Table(table).Migrator()
+				index := "idx_" + table + "_deleted_at"
+				if m.HasIndex(&auditV3{}, index) {
+					if err := m.DropIndex(&auditV3{}, index); err != nil {
+						return fmt.Errorf("failed to drop the index of %s: %v", table, err)
+					}
+				}
+				for _, column := range auditColumns {
+					if !m.HasColumn(&auditV3{}, column) || table == "cycle_templates" && column != "created_by" && column != "deleted_at" {
+						continue // The initial schema has the timestamps of templates
+					}
+					if err := m.DropColumn(&auditV3{}, column); err != nil {
+						return fmt.Errorf("failed to drop %s from %s: %v", column, table, err)
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// The registry of workers: their status, when they were last seen and their version
+		ID: "202610150004_worker_registry",
+		Migrate: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, column := range workerRegistryColumns {
+				if m.HasColumn(&workerV4{}, column) {
+					continue
+				}
+				if err := m.AddColumn(&workerV4{}, column); err != nil {
+					return fmt.Errorf("failed to add %s to workers: %v", column, err)
+				}
+			}
+			if m.HasIndex(&workerV4{}, "LastSeenAt") {
+				return nil
+			}
+			return m.CreateIndex(&workerV4{}, "LastSeenAt")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&workerV4{}, "LastSeenAt") {
+				if err := m.DropIndex(&workerV4{}, "LastSeenAt"); err != nil {
+					return fmt.Errorf("failed to drop the index of workers: %v", err)
+				}
+			}
+			for _, column := range workerRegistryColumns {
+				if !m.HasColumn(&workerV4{}, column) {
+					continue
+				}
+				if err := m.DropColumn(&workerV4{}, column); err != nil {
+					return fmt.Errorf("failed to drop %s from workers: %v", column, err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Per-minute rollups of job results, for time series
+		ID: "202610150005_metric_rollups",
+		Migrate: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable("metric_rollups") {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&metricRollupV5{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("metric_rollups")
+		},
+	},
+	{
+		// Projects, with the rows of the tables scoped to them in the default project
+		ID: "202610150006_projects",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasTable("projects") {
+				if err := tx.Migrator().CreateTable(&projectV6{}); err != nil {
+					return fmt.Errorf("failed to create projects: %v", err)
+				}
+				if err := tx.Exec("CREATE INDEX idx_projects_deleted_at ON projects (deleted_at)").Error; err != nil {
+					return fmt.Errorf("failed to index projects: %v", err)
+				}
+			}
+			if err := tx.Where(projectV6{ID: models.DefaultProject}).FirstOrCreate(&projectV6{ID: models.DefaultProject}).Error; err != nil {
+				return fmt.Errorf("failed to create the default project: %v", err)
+			}
+			for _, table := range projectTables {
+				m := tx.Table(table).Migrator()
+				if !m.HasColumn(&projectIDV6{}, "project_id") {
+					if err := m.AddColumn(&projectIDV6{}, "project_id"); err != nil {
+						return fmt.Errorf("failed to add project_id to %s: %v", table, err)
+					}
+				}
+				if err := tx.Table(table).Where("project_id IS NULL OR project_id = ''").
+					Update("project_id", models.DefaultProject).Error; err != nil {
+					return fmt.Errorf("failed to move %s to the default project: %v", table, err)
+				}
+				index := "idx_" + table + "_project_id"
+				if !m.HasIndex(&projectIDV6{}, index) {
+					if err := tx.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (project_id)", index, table)).Error; err != nil {
+						return fmt.Errorf("failed to index %s: %v", table, err)
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, table := range projectTables {
+				m := tx.Table(table).Migrator()
+				index := "idx_" + table + "_project_id"
+				if m.HasIndex(&projectIDV6{}, index) {
+					if err := m.DropIndex(&projectIDV6{}, index); err != nil {
+						return fmt.Errorf("failed to drop the index of %s: %v", table, err)
+					}
+				}
+				if m.HasColumn(&projectIDV6{}, "project_id") {
+					if err := m.DropColumn(&projectIDV6{}, "project_id"); err != nil {
+						return fmt.Errorf("failed to drop project_id from %s: %v", table, err)
+					}
+				}
+			}
+			return tx.Migrator().DropTable("projects")
+		},
+	},
+	{
+		// Usernames unique within their cycle only, so a cycle can replay the seed of another
+		ID: "202610150007_users_unique_per_cycle",
+		Migrate: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasConstraint(&userV7{}, "uni_users_username") {
+				if err := m.DropConstraint(&userV7{}, "uni_users_username"); err != nil {
+					return fmt.Errorf("failed to drop the unique usernames of users: %v", err)
+				}
+			}
+			if m.HasIndex(&userV7{}, "idx_users_cycle_username") {
+				return nil
+			}
+			return m.CreateIndex(&userV7{}, "idx_users_cycle_username")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&userV7{}, "idx_users_cycle_username") {
+				if err := m.DropIndex(&userV7{}, "idx_users_cycle_username"); err != nil {
+					return fmt.Errorf("failed to drop the index of users: %v", err)
+				}
+			}
+			return tx.Exec("CREATE UNIQUE INDEX uni_users_username ON users (username)").Error
+		},
+	},
+}
+
+// userActionV2 is the user_actions table as migration 202610150002 creates it
+type userActionV2 struct {
+	UUID       string `gorm:"primaryKey;type:uuid"`
+	CycleUUID  string `gorm:"column:cycle_uuid;type:uuid;not null;index"`
+	SessionID  string `gorm:"column:session_id;type:uuid;not null;index"`
+	JobUUID    string `gorm:"column:job_uuid;type:uuid;not null"`
+	Action     string `gorm:"column:action;type:text;not null"`
+	Phase      string `gorm:"column:phase;type:text"`
+	Status     string `gorm:"column:status;type:text;not null"`
+	Error      string `gorm:"column:error;type:text"`
+	WorkerID   string `gorm:"column:worker_id;type:uuid"`
+	StartAt    int64  `gorm:"column:start_at;type:bigint"`
+	DoneAt     int64  `gorm:"column:done_at;type:bigint"`
+	DurationMs int64  `gorm:"column:duration_ms;type:bigint"`
+	// Foreign keys to the cycle and session of the action
+	Cycle   models.Cycle   `gorm:"foreignKey:CycleUUID;references:UUID"`
+	Session models.Session `gorm:"foreignKey:SessionID;references:UUID"`
+}
+
+func (userActionV2) TableName() string { return "user_actions" }
+
+// auditTables are the tables migration 202610150003 adds the audit columns to
+var auditTables = []string{
+	"jobs", "workers", "users", "files", "workspaces", "cycles", "sessions", "cycle_templates", "user_actions",
+}
+
+// auditColumns are the columns of auditV3, which cycle_templates already partly has
+var auditColumns = []string{"created_at", "updated_at", "created_by", "deleted_at"}
+
+// auditV3 is the audit columns as migration 202610150003 adds them
+type auditV3 struct {
+	CreatedAt int64          `gorm:"column:created_at;type:bigint"`
+	UpdatedAt int64          `gorm:"column:updated_at;type:bigint"`
+	CreatedBy string         `gorm:"column:created_by;type:text"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at"`
+}
+
+// workerRegistryColumns are the columns migration 202610150004 adds to workers
+var workerRegistryColumns = []string{"status", "last_seen_at", "version"}
+
+// workerV4 is the registry columns of workers as migration 202610150004 adds them
+type workerV4 struct {
+	Status     string `gorm:"column:status;type:text"`
+	LastSeenAt int64  `gorm:"column:last_seen_at;type:bigint;index"`
+	Version    string `gorm:"column:version;type:text"`
+}
+
+func (workerV4) TableName() string { return "workers" }
+
+// metricRollupV5 is the metric_rollups table as migration 202610150005 creates it
+type metricRollupV5 struct {
+	CycleUUID     string `gorm:"primaryKey;column:cycle_uuid;type:uuid"`
+	Action        string `gorm:"primaryKey;column:action;type:text"`
+	Minute        int64  `gorm:"primaryKey;column:minute;type:bigint;autoIncrement:false"`
+	Jobs          int64  `gorm:"column:jobs;type:bigint"`
+	Failed        int64  `gorm:"column:failed;type:bigint"`
+	DurationSumMs int64  `gorm:"column:duration_sum_ms;type:bigint"`
+	DurationMinMs int64  `gorm:"column:duration_min_ms;type:bigint"`
+	DurationMaxMs int64  `gorm:"column:duration_max_ms;type:bigint"`
+	Buckets       string `gorm:"column:buckets;type:text"`
+}
+
+func (metricRollupV5) TableName() string { return "metric_rollups" }
+
+// projectTables are the tables migration 202610150006 scopes to projects
+var projectTables = []string{"jobs", "users", "files", "workspaces", "cycles"}
+
+// projectIDV6 is the column migration 202610150006 adds to projectTables
+type projectIDV6 struct {
+	ProjectID string `gorm:"column:project_id;type:text"`
+}
+
+// projectV6 is the projects table as migration 202610150006 creates it
+type projectV6 struct {
+	ID                    string         `gorm:"primaryKey;type:text"`
+	Description           string         `gorm:"column:description;type:text"`
+	QuotaMaxRunningCycles int            `gorm:"column:quota_max_running_cycles;type:integer"`
+	QuotaMaxCycleJobs     int            `gorm:"column:quota_max_cycle_jobs;type:integer"`
+	CreatedAt             int64          `gorm:"column:created_at;type:bigint"`
+	UpdatedAt             int64          `gorm:"column:updated_at;type:bigint"`
+	CreatedBy             string         `gorm:"column:created_by;type:text"`
+	DeletedAt             gorm.DeletedAt `gorm:"column:deleted_at"`
+}
+
+func (projectV6) TableName() string { return "projects" }
+
+// userV7 is the columns of users migration 202610150007 indexes
+type userV7 struct {
+	UserName string `gorm:"column:username;type:text;not null;uniqueIndex:idx_users_cycle_username"`
+	CycleID  string `gorm:"column:cycle_id;type:uuid;not null;uniqueIndex:idx_users_cycle_username"`
+}
+
+func (userV7) TableName() string { return "users" }
+
+// MigrationStatus tells whether a migration is applied to a database
+type MigrationStatus struct {
+	ID      string `json:"id"`
+	Applied bool   `json:"applied"`
+}
+
+func newMigrator(db *gorm.DB) *gormigrate.Gormigrate {
+	options := *gormigrate.DefaultOptions
+	options.TableName = migrationsTable
+	options.UseTransaction = db.Dialector.Name() != "mysql" // MySQL commits DDL implicitly
+	return gormigrate.New(db, &options, migrations)
+}
+
+// Migrate applies the migrations db is missing
+func Migrate(db *gorm.DB) error {
+	if err := newMigrator(db).Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate the database: %v", err)
+	}
+	return nil
+}
+
+// RollbackMigrations reverts the last steps migrations applied to db and returns the
+// IDs of those it reverted, stopping early when none is left
+func RollbackMigrations(db *gorm.DB, steps int) ([]string, error) {
+	status, err := Migrations(db)
+	if err != nil {
+		return nil, err
+	}
+	m := newMigrator(db)
+	var reverted []string
+	for i := len(status) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if !status[i].Applied {
+			continue
+		}
+		if err := m.RollbackLast(); err != nil {
+			if errors.Is(err, gormigrate.ErrNoRunMigration) {
+				break
+			}
+			return reverted, fmt.Errorf("failed to roll back migration %s: %v", status[i].ID, err)
+		}
+		reverted = append(reverted, status[i].ID)
+	}
+	return reverted, nil
+}
+
+// Migrations lists the migrations robo knows, oldest first, with whether db has them
+func Migrations(db *gorm.DB) ([]MigrationStatus, error) {
+	applied := make(map[string]bool)
+	if db.Migrator().HasTable(migrationsTable) {
+		var ids []string
+		if err := db.Table(migrationsTable).Pluck("id", &ids).Error; err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %v", err)
+		}
+		for _, id := range ids {
+			applied[id] = true
+		}
+	}
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		status[i] = MigrationStatus{ID: m.ID, Applied: applied[m.ID]}
+	}
+	return status, nil
+}
@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/songvi/robo/models"
+)
+
+// ErrUnknownProject is returned when rows are created in a project that does not exist
+var ErrUnknownProject = errors.New("unknown project")
+
+// ErrQuotaExceeded is returned when rows are created beyond the quota of their project
+var ErrQuotaExceeded = errors.New("project quota exceeded")
+
+// CRUD methods for Project
+func (s *GORMStore) CreateProject(ctx context.Context, project *models.Project) error {
+	return s.db.WithContext(ctx).Create(project).Error
+}
+
+func (s *GORMStore) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListProjects returns every project by ID
+func (s *GORMStore) ListProjects(ctx context.Context) ([]models.Project, error) {
+	var projects []models.Project
+	if err := s.db.WithContext(ctx).Order("id").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// UpdateProject saves the description and quota of an existing project; it fails when
+// there is none by the ID
+func (s *GORMStore) UpdateProject(ctx context.Context, project *models.Project) error {
+	result := s.db.WithContext(ctx).Model(project).
+		Select("description", "quota_max_running_cycles", "quota_max_cycle_jobs", "updated_at").Updates(project)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// DeleteProject deletes a project without cycles; the default project is never deleted
+func (s *GORMStore) DeleteProject(ctx context.Context, id string) error {
+	if id == models.DefaultProject {
+		return fmt.Errorf("%w: the default project is not deleted", ErrConflict)
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cycles int64
+		if err := tx.Model(&models.Cycle{}).Where("project_id = ?", id).Count(&cycles).Error; err != nil {
+			return err
+		}
+		if cycles > 0 {
+			return fmt.Errorf("%w: project %s has %d cycles", ErrConflict, id, cycles)
+		}
+		return tx.Delete(&models.Project{}, "id = ?", id).Error
+	})
+}
+
+// ScopeProjects registers the callbacks scoping the rows of db with a ProjectID to the
+// project of the context of their statements, as models.WithProject sets it: queries,
+// updates and deletes only see the rows of the project, and rows are created in it.
+// Rows created outside of a project join the project of their cycle, or the default
+// one. Creates fail with ErrUnknownProject and ErrQuotaExceeded.
+func ScopeProjects(db *gorm.DB) error {
+	// Method values, as GORM does not export the types of its callback processors
+	type register func(name string, fn func(*gorm.DB)) error
+	callbacks := db.Callback()
+	filters := []struct {
+		operation string
+		before    register
+	}{
+		{"query", callbacks.Query().Before("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register},
+	}
+	for _, f := range filters {
+		if err := f.before("robo:project_"+f.operation, filterProject); err != nil {
+			return fmt.Errorf("failed to scope %s queries to projects: %v", f.operation, err)
+		}
+	}
+	if err := callbacks.Create().Before("gorm:create").Register("robo:project_create", assignProject); err != nil {
+		return fmt.Errorf("failed to scope create queries to projects: %v", err)
+	}
+	return nil
+}
+
+// filterProject restricts a statement to the rows of the project of its context
+func filterProject(tx *gorm.DB) {
+	project := models.ProjectFrom(tx.Statement.Context)
+	if project == "" || tx.Statement.Schema == nil || tx.Statement.Schema.LookUpField("ProjectID") == nil {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "project_id"}, Value: project},
+	}})
+}
+
+// assignProject sets the project of the rows a statement creates and checks it exists
+// and has room for them
+func assignProject(tx *gorm.DB) {
+	stmt := tx.Statement
+	if tx.Error != nil || stmt.Schema == nil {
+		return
+	}
+	field := stmt.Schema.LookUpField("ProjectID")
+	if field == nil {
+		return
+	}
+	ctx := stmt.Context
+	scoped := models.ProjectFrom(ctx)
+	rows := statementRows(stmt.ReflectValue)
+	if len(rows) == 0 {
+		return
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	cycleField := stmt.Schema.LookUpField("CycleUUID")
+	if cycleField == nil {
+		cycleField = stmt.Schema.LookUpField("CycleID")
+	}
+	var cycleProjects map[string]string
+	if scoped == "" && cycleField != nil {
+		var cycles []string
+		for _, row := range rows {
+			if fieldString(ctx, field, row) == "" {
+				cycles = append(cycles, fieldString(ctx, cycleField, row))
+			}
+		}
+		var err error
+		if cycleProjects, err = projectsOfCycles(db, cycles); err != nil {
+			tx.AddError(fmt.Errorf("failed to read the projects of cycles: %v", err))
+			return
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, row := range rows {
+		project := fieldString(ctx, field, row)
+		switch {
+		case scoped != "" && project != "" && project != scoped:
+			// Saving a row of another project must not move it to this one
+			tx.AddError(fmt.Errorf("%w: %s row of project %s", gorm.ErrRecordNotFound, stmt.Schema.Table, project))
+			return
+		case scoped != "":
+			project = scoped
+		case project != "":
+		case cycleField != nil && cycleProjects[fieldString(ctx, cycleField, row)] != "":
+			project = cycleProjects[fieldString(ctx, cycleField, row)]
+		default:
+			project = models.DefaultProject
+		}
+		if err := field.Set(ctx, row, project); err != nil {
+			tx.AddError(err)
+			return
+		}
+		used[project] = true
+	}
+	if err := checkQuotas(db, stmt.Schema, rows, used); err != nil {
+		tx.AddError(err)
+	}
+}
+
+// statementRows returns the structs a statement creates
+func statementRows(value reflect.Value) []reflect.Value {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		rows := make([]reflect.Value, value.Len())
+		for i := range rows {
+			rows[i] = reflect.Indirect(value.Index(i))
+		}
+		return rows
+	case reflect.Struct:
+		return []reflect.Value{value}
+	}
+	return nil
+}
+
+func fieldString(ctx context.Context, field *schema.Field, row reflect.Value) string {
+	value, _ := field.ValueOf(ctx, row)
+	s, _ := value.(string)
+	return s
+}
+
+// projectsOfCycles returns the project of each of cycles that exists
+func projectsOfCycles(db *gorm.DB, cycles []string) (map[string]string, error) {
+	projects := make(map[string]string, len(cycles))
+	if len(cycles) == 0 {
+		return projects, nil
+	}
+	var rows []struct {
+		UUID      string
+		ProjectID string
+	}
+	if err := db.Model(&models.Cycle{}).Select("uuid, project_id").Where("uuid IN ?", cycles).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		projects[row.UUID] = row.ProjectID
+	}
+	return projects, nil
+}
+
+// checkQuotas fails when a project of used is missing, or when the rows created would
+// take it over its quota of running cycles or of jobs per cycle
+func checkQuotas(db *gorm.DB, s *schema.Schema, rows []reflect.Value, used map[string]bool) error {
+	ids := make([]string, 0, len(used))
+	for id := range used {
+		ids = append(ids, id)
+	}
+	var projects []models.Project
+	if err := db.Where("id IN ?", ids).Find(&projects).Error; err != nil {
+		return fmt.Errorf("failed to read projects: %v", err)
+	}
+	if len(projects) < len(ids) {
+		for _, project := range projects {
+			delete(used, project.ID)
+		}
+		for id := range used {
+			return fmt.Errorf("%w: %s", ErrUnknownProject, id)
+		}
+	}
+
+	ctx := db.Statement.Context
+	projectField := s.LookUpField("ProjectID")
+	for _, project := range projects {
+		switch {
+		case s.Table == "cycles" && project.Quota.MaxRunningCycles > 0:
+			// Cycles saved once done, e.g. those that failed their preflight, do not run
+			doneField := s.LookUpField("DoneAt")
+			var started int64
+			for _, row := range rows {
+				if done, _ := doneField.ValueOf(ctx, row); fieldString(ctx, projectField, row) == project.ID && done.(int64) == 0 {
+					started++
+				}
+			}
+			if started == 0 {
+				continue
+			}
+			var running int64
+			if err := db.Model(&models.Cycle{}).Where("project_id = ? AND (done_at = 0 OR done_at IS NULL)", project.ID).
+				Count(&running).Error; err != nil {
+				return fmt.Errorf("failed to count the running cycles of project %s: %v", project.ID, err)
+			}
+			if running+started > int64(project.Quota.MaxRunningCycles) {
+				return fmt.Errorf("%w: project %s runs %d cycles of at most %d", ErrQuotaExceeded, project.ID, running, project.Quota.MaxRunningCycles)
+			}
+		case s.Table == "jobs" && project.Quota.MaxCycleJobs > 0:
+			cycleField := s.LookUpField("CycleUUID")
+			created := make(map[string]int64)
+			for _, row := range rows {
+				if fieldString(ctx, projectField, row) == project.ID {
+					created[fieldString(ctx, cycleField, row)]++
+				}
+			}
+			for cycle, n := range created {
+				var saved int64
+				if err := db.Model(&models.Job{}).Where("cycle_uuid = ?", cycle).Count(&saved).Error; err != nil {
+					return fmt.Errorf("failed to count the jobs of cycle %s: %v", cycle, err)
+				}
+				if saved+n > int64(project.Quota.MaxCycleJobs) {
+					return fmt.Errorf("%w: cycle %s would have %d jobs of at most %d in project %s",
+						ErrQuotaExceeded, cycle, saved+n, project.Quota.MaxCycleJobs, project.ID)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/models"
+)
+
+// purgeModels are the models with soft deletes, children before their parents
+var purgeModels = []interface{}{
+	&models.UserAction{},
+	&models.Job{},
+	&models.Session{},
+	&models.File{},
+	&models.Workspace{},
+	&models.User{},
+	&models.Worker{},
+	&models.Cycle{},
+	&models.CycleTemplate{},
+	&models.Project{},
+}
+
+// Purge deletes for good the rows of every table soft deleted before the Unix time
+// deletedBefore, all or none, and returns how many it deleted per table
+func (s *GORMStore) Purge(ctx context.Context, deletedBefore int64) (map[string]int64, error) {
+	before := time.Unix(deletedBefore, 0)
+	purged := make(map[string]int64, len(purgeModels))
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range purgeModels {
+			stmt := &gorm.Statement{DB: tx}
+			if err := stmt.Parse(model); err != nil {
+				return fmt.Errorf("failed to parse %T: %v", model, err)
+			}
+			result := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(model)
+			if result.Error != nil {
+				return fmt.Errorf("failed to purge %s: %v", stmt.Schema.Table, result.Error)
+			}
+			purged[stmt.Schema.Table] = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
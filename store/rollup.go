@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/songvi/robo/models"
+)
+
+// MergeMetricRollups adds rollups to those saved for the same cycle, action and minute,
+// all or none
+func (s *GORMStore) MergeMetricRollups(ctx context.Context, rollups []models.MetricRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, rollup := range rollups {
+			var saved models.MetricRollup
+			err := tx.Where("cycle_uuid = ? AND action = ? AND minute = ?", rollup.CycleUUID, rollup.Action, rollup.Minute).
+				Take(&saved).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				saved = models.MetricRollup{CycleUUID: rollup.CycleUUID, Action: rollup.Action, Minute: rollup.Minute}
+			case err != nil:
+				return fmt.Errorf("failed to read rollup: %v", err)
+			}
+			saved.Merge(rollup)
+			if err := tx.Save(&saved).Error; err != nil {
+				return fmt.Errorf("failed to save rollup: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ListMetricRollups returns the rollups of a cycle from the minute of the Unix time from
+// until to, both included, by minute and action; zero leaves a bound open
+func (s *GORMStore) ListMetricRollups(ctx context.Context, cycleUUID string, from, to int64) ([]models.MetricRollup, error) {
+	query := s.db.WithContext(ctx).Where("cycle_uuid = ?", cycleUUID).Order("minute, action")
+	if from > 0 {
+		query = query.Where("minute >= ?", from-from%60)
+	}
+	if to > 0 {
+		query = query.Where("minute <= ?", to)
+	}
+	var rollups []models.MetricRollup
+	if err := query.Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
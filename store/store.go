@@ -2,33 +2,69 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/fx"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/songvi/robo/logger"
 	"github.com/songvi/robo/models"
 )
 
 // Store defines the CRUD interface for all models
 type Store interface {
 	CreateJob(ctx context.Context, job *models.Job) error
+	// CreateJobsBatch inserts jobs in batches of the configured size, all or none
+	CreateJobsBatch(ctx context.Context, jobs []models.Job) error
 	GetJob(ctx context.Context, id string) (*models.Job, error)
 	UpdateJob(ctx context.Context, job *models.Job) error
+	UpdateJobs(ctx context.Context, jobs []models.Job) error
+	UpdateJobStatus(ctx context.Context, id string, from []string, to string) error
+	// UpdateJobsStatus moves the unfinished jobs of uuids to a status at once and returns
+	// how many it moved
+	UpdateJobsStatus(ctx context.Context, uuids []string, status string) (int64, error)
 	DeleteJob(ctx context.Context, id string) error
 	GetJobsByStatus(ctx context.Context, status string, jobs *[]models.Job) error
+	// ListJobs returns a page of the jobs matching filter
+	ListJobs(ctx context.Context, filter JobFilter, page Page) (JobPage, error)
+	GetCycleJobsByStatus(ctx context.Context, cycleUUID, status string) ([]models.Job, error)
+	GetJobsByCycle(ctx context.Context, cycleUUID string, statuses ...string) ([]models.Job, error)
+	// ScanCycleJobs calls fn with the jobs of a cycle in batches
+	ScanCycleJobs(ctx context.Context, cycleUUID string, fn func(jobs []models.Job) error) error
+	GetCycleResults(ctx context.Context, cycleUUID string) ([]models.Job, error)
+	CountJobsByStatus(ctx context.Context, cycleUUID string) (map[string]int, error)
+	// CountJobsByCycle counts the jobs of several cycles per cycle and status
+	CountJobsByCycle(ctx context.Context, cycleUUIDs []string) (map[string]map[string]int, error)
+	CountPhaseJobsByStatus(ctx context.Context, cycleUUID, phase string) (map[string]int, error)
+	UpdateCycleJobsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error)
 
 	CreateWorker(ctx context.Context, worker *models.Worker) error
 	GetWorker(ctx context.Context, id string) (*models.Worker, error)
 	UpdateWorker(ctx context.Context, worker *models.Worker) error
 	DeleteWorker(ctx context.Context, id string) error
+	// UpsertWorker creates a worker or replaces the one of the same UUID
+	UpsertWorker(ctx context.Context, worker *models.Worker) error
+	// TouchWorker records that a worker was seen at a Unix time
+	TouchWorker(ctx context.Context, id string, seenAt int64) error
+	UpdateWorkerStatus(ctx context.Context, id, status string) error
+	// ListActiveWorkers returns the active workers seen since a Unix time
+	ListActiveWorkers(ctx context.Context, since int64) ([]models.Worker, error)
 
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUser(ctx context.Context, id string) (*models.User, error)
+	ListUsers(ctx context.Context, limit int) ([]models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id string) error
 
 	CreateFile(ctx context.Context, file *models.File) error
+	// CreateFilesBatch inserts files in batches of the configured size, all or none
+	CreateFilesBatch(ctx context.Context, files []models.File) error
 	GetFile(ctx context.Context, id string) (*models.File, error)
 	UpdateFile(ctx context.Context, file *models.File) error
 	DeleteFile(ctx context.Context, id string) error
@@ -38,28 +74,141 @@ type Store interface {
 	UpdateWorkspace(ctx context.Context, workspace *models.Workspace) error
 	DeleteWorkspace(ctx context.Context, id string) error
 
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	UpdateSession(ctx context.Context, session *models.Session) error
+	UpdateCycleSessionsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error)
+	GetCycleSessionsByStatus(ctx context.Context, cycleUUID string, statuses ...string) ([]models.Session, error)
+	CountSessionJobsByStatus(ctx context.Context, sessionID string) (map[string]int, error)
+	// ListCycleSessions returns the sessions of a cycle in any status
+	ListCycleSessions(ctx context.Context, cycleUUID string) ([]models.Session, error)
+	DeleteSession(ctx context.Context, id string) error
+
+	// CreateUserActions records actions in batches of the configured size, all or none
+	CreateUserActions(ctx context.Context, actions []models.UserAction) error
+	GetUserAction(ctx context.Context, id string) (*models.UserAction, error)
+	// ListSessionActions returns the actions of a session in the order they were done
+	ListSessionActions(ctx context.Context, sessionID string) ([]models.UserAction, error)
+	DeleteUserAction(ctx context.Context, id string) error
+
 	CreateCycle(ctx context.Context, cycle *models.Cycle) error
 	GetCycle(ctx context.Context, id string) (*models.Cycle, error)
+	ListCyclesByStatus(ctx context.Context, statuses ...string) ([]models.Cycle, error)
+	ListCyclesByPhase(ctx context.Context, phase string) ([]models.Cycle, error)
+	// ListCycles returns the cycles matching filter, most recently started first
+	ListCycles(ctx context.Context, filter CycleFilter) ([]models.Cycle, error)
 	UpdateCycle(ctx context.Context, cycle *models.Cycle) error
+	// UpdateCycleColumns saves the named columns of a cycle and leaves the others as they
+	// are in the database
+	UpdateCycleColumns(ctx context.Context, cycle *models.Cycle, columns ...string) error
+	// UpdateCycleStatus saves the status of a cycle, and the named columns with it, when
+	// the cycle is in one of the from statuses; ErrConflict otherwise
+	UpdateCycleStatus(ctx context.Context, cycle *models.Cycle, from []string, columns ...string) error
 	DeleteCycle(ctx context.Context, id string) error
+	// ListCyclesDoneBefore returns up to limit cycles that finished before a Unix time
+	ListCyclesDoneBefore(ctx context.Context, before int64, limit int) ([]models.Cycle, error)
+	// ArchiveCycle writes a cycle and everything it created to w as gzipped JSON lines
+	ArchiveCycle(ctx context.Context, cycleUUID string, w io.Writer) error
+	// MergeMetricRollups adds per-minute rollups of job results to the saved ones
+	MergeMetricRollups(ctx context.Context, rollups []models.MetricRollup) error
+	// ListMetricRollups returns the per-minute rollups of a cycle between Unix times
+	ListMetricRollups(ctx context.Context, cycleUUID string, from, to int64) ([]models.MetricRollup, error)
+	// DeleteCycleData deletes a cycle and everything it created for good
+	DeleteCycleData(ctx context.Context, cycleUUID string) error
+	// Purge deletes for good the rows soft deleted before a Unix time and returns how
+	// many it deleted per table
+	Purge(ctx context.Context, deletedBefore int64) (map[string]int64, error)
+
+	CreateCycleTemplate(ctx context.Context, template *models.CycleTemplate) error
+	GetCycleTemplate(ctx context.Context, name string) (*models.CycleTemplate, error)
+	ListCycleTemplates(ctx context.Context) ([]models.CycleTemplate, error)
+	UpdateCycleTemplate(ctx context.Context, template *models.CycleTemplate) error
+	DeleteCycleTemplate(ctx context.Context, name string) error
+
+	CreateProject(ctx context.Context, project *models.Project) error
+	GetProject(ctx context.Context, id string) (*models.Project, error)
+	ListProjects(ctx context.Context) ([]models.Project, error)
+	UpdateProject(ctx context.Context, project *models.Project) error
+	// DeleteProject deletes a project without cycles
+	DeleteProject(ctx context.Context, id string) error
+
+	// WithTx calls fn with a Store whose writes commit together when fn returns nil and
+	// roll back when it returns an error
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+// ErrConflict is returned when a row changed since it was read, or is not in the state
+// an update expects
+var ErrConflict = errors.New("conflicting update")
+
+// DefaultBatchSize is the number of rows per insert statement of batch inserts unless
+// configured otherwise
+const DefaultBatchSize = 500
+
+// StoreConfig tunes the Store
+type StoreConfig struct {
+	// BatchSize is the number of rows per insert statement of batch inserts; zero uses
+	// DefaultBatchSize
+	BatchSize int `json:"batch_size" yaml:"batch_size"`
+	// SlowQueryMs is the duration from which queries are logged; zero logs none
+	SlowQueryMs int             `json:"slow_query_ms" yaml:"slow_query_ms"`
+	Retention   RetentionConfig `json:"retention" yaml:"retention"`
+	Cache       CacheConfig     `json:"cache" yaml:"cache"`
 }
 
 // GORMStore is the implementation of Store using GORM
 type GORMStore struct {
-	db *gorm.DB
+	db        *gorm.DB
+	batchSize int
 }
 
 // NewGORMStore initializes a new GORMStore
 func NewGORMStore(db *gorm.DB) *GORMStore {
-	return &GORMStore{db: db}
+	return &GORMStore{db: db, batchSize: DefaultBatchSize}
 }
 
 // CRUD methods for Job
+
+// CreateJob creates a job of an existing cycle; it returns ErrCycleNotFound and
+// ErrDuplicateUUID
 func (s *GORMStore) CreateJob(ctx context.Context, job *models.Job) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.Job{}, s.batchSize, job.UUID); err != nil {
+		return err
+	}
+	if err := checkCycles(db, job.CycleUUID); err != nil {
+		return err
+	}
 	if job.UUID == "" {
 		job.UUID = uuid.New().String()
 	}
-	return s.db.WithContext(ctx).Create(job).Error
+	return db.Create(job).Error
+}
+
+// CreateJobsBatch inserts jobs of existing cycles in one transaction, giving a UUID to
+// those without one; it returns ErrCycleNotFound and ErrDuplicateUUID
+func (s *GORMStore) CreateJobsBatch(ctx context.Context, jobs []models.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	uuids, cycles := make([]string, len(jobs)), make([]string, len(jobs))
+	for i := range jobs {
+		uuids[i], cycles[i] = jobs[i].UUID, jobs[i].CycleUUID
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkUUIDs(tx, &models.Job{}, s.batchSize, uuids...); err != nil {
+			return err
+		}
+		if err := checkCycles(tx, cycles...); err != nil {
+			return err
+		}
+		for i := range jobs {
+			if jobs[i].UUID == "" {
+				jobs[i].UUID = uuid.New().String()
+			}
+		}
+		return tx.CreateInBatches(jobs, s.batchSize).Error
+	})
 }
 
 func (s *GORMStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
@@ -70,8 +219,85 @@ func (s *GORMStore) GetJob(ctx context.Context, id string) (*models.Job, error)
 	return &job, nil
 }
 
+// UpdateJob saves job unless it changed since it was read, in which case it returns
+// ErrConflict and the caller reads it again
 func (s *GORMStore) UpdateJob(ctx context.Context, job *models.Job) error {
-	return s.db.WithContext(ctx).Save(job).Error
+	return updateJob(s.db.WithContext(ctx), job)
+}
+
+// UpdateJobs saves jobs in one transaction, or none of them with ErrConflict when one
+// changed since it was read
+func (s *GORMStore) UpdateJobs(ctx context.Context, jobs []models.Job) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range jobs {
+			if err := updateJob(tx, &jobs[i]); err != nil {
+				for j := range jobs[:i] {
+					jobs[j].Version--
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateJob saves job if its version is still the saved one and moves it to the next
+// version
+func updateJob(db *gorm.DB, job *models.Job) error {
+	version := job.Version
+	job.Version++
+	result := db.Model(job).Where("version = ?", version).Select("*").Omit(clause.Associations).Updates(job)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = fmt.Errorf("%w: job %s changed since version %d", ErrConflict, job.UUID, version)
+	}
+	if result.Error != nil {
+		job.Version = version
+	}
+	return result.Error
+}
+
+// UpdateJobStatus moves a job from one of the from statuses to the to status in one
+// statement; it returns ErrConflict when the job is in another status
+func (s *GORMStore) UpdateJobStatus(ctx context.Context, id string, from []string, to string) error {
+	result := s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("uuid = ? AND status IN ?", id, from).
+		Updates(map[string]interface{}{"status": to, "version": gorm.Expr("version + 1")})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: job %s is %s, expected one of %v", ErrConflict, id, job.Status, from)
+}
+
+// unfinishedJobStatuses are the statuses of the jobs that did not complete, fail or get
+// cancelled yet
+var unfinishedJobStatuses = []string{"pending", "dispatched", "processing"}
+
+// UpdateJobsStatus moves the jobs of uuids to status in one statement per batch of the
+// configured size, all or none. Jobs already completed, failed or cancelled are left
+// alone, so a result that came first is not overwritten.
+func (s *GORMStore) UpdateJobsStatus(ctx context.Context, uuids []string, status string) (int64, error) {
+	if len(uuids) == 0 {
+		return 0, nil
+	}
+	var updated int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(uuids); start += s.batchSize {
+			batch := uuids[start:min(start+s.batchSize, len(uuids))]
+			result := tx.Model(&models.Job{}).
+				Where("uuid IN ? AND status IN ?", batch, unfinishedJobStatuses).
+				Updates(map[string]interface{}{"status": status, "version": gorm.Expr("version + 1")})
+			if result.Error != nil {
+				return result.Error
+			}
+			updated += result.RowsAffected
+		}
+		return nil
+	})
+	return updated, err
 }
 
 func (s *GORMStore) DeleteJob(ctx context.Context, id string) error {
@@ -82,12 +308,131 @@ func (s *GORMStore) GetJobsByStatus(ctx context.Context, status string, jobs *[]
 	return s.db.WithContext(ctx).Where("status = ?", status).Find(jobs).Error
 }
 
+// GetCycleJobsByStatus returns the jobs of a cycle in status
+func (s *GORMStore) GetCycleJobsByStatus(ctx context.Context, cycleUUID, status string) ([]models.Job, error) {
+	return s.GetJobsByCycle(ctx, cycleUUID, status)
+}
+
+// GetJobsByCycle returns the jobs of a cycle in one of statuses, or all of them without
+// statuses
+func (s *GORMStore) GetJobsByCycle(ctx context.Context, cycleUUID string, statuses ...string) ([]models.Job, error) {
+	query := s.db.WithContext(ctx).Where("cycle_uuid = ?", cycleUUID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	var jobs []models.Job
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ScanCycleJobs calls fn with the jobs of a cycle in batches of the configured size,
+// stopping at the first error fn returns
+func (s *GORMStore) ScanCycleJobs(ctx context.Context, cycleUUID string, fn func(jobs []models.Job) error) error {
+	var jobs []models.Job
+	return s.db.WithContext(ctx).Where("cycle_uuid = ?", cycleUUID).FindInBatches(&jobs, s.batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(jobs)
+	}).Error
+}
+
+// GetCycleResults returns the completed and failed jobs of the load phase of a cycle,
+// without those of its warm-up and teardown
+func (s *GORMStore) GetCycleResults(ctx context.Context, cycleUUID string) ([]models.Job, error) {
+	var jobs []models.Job
+	if err := s.db.WithContext(ctx).
+		Where("cycle_uuid = ? AND status IN ? AND (phase = '' OR phase IS NULL)", cycleUUID, []string{"completed", "failed"}).
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// UpdateCycleJobsStatus moves the jobs of a cycle in one of the from statuses to the to
+// status and returns how many it moved
+func (s *GORMStore) UpdateCycleJobsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("cycle_uuid = ? AND status IN ?", cycleUUID, from).
+		Updates(map[string]interface{}{"status": to, "version": gorm.Expr("version + 1")})
+	return result.RowsAffected, result.Error
+}
+
+// CountJobsByStatus counts the jobs of a cycle per status
+func (s *GORMStore) CountJobsByStatus(ctx context.Context, cycleUUID string) (map[string]int, error) {
+	return s.countJobsByStatus(ctx, "cycle_uuid = ?", cycleUUID)
+}
+
+// CountJobsByCycle counts the jobs of each of cycleUUIDs per status in one query;
+// cycles without jobs are left out
+func (s *GORMStore) CountJobsByCycle(ctx context.Context, cycleUUIDs []string) (map[string]map[string]int, error) {
+	counts := make(map[string]map[string]int, len(cycleUUIDs))
+	if len(cycleUUIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		CycleUUID string
+		Status    string
+		Count     int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Job{}).
+		Select("cycle_uuid, status, count(*) as count").
+		Where("cycle_uuid IN ?", cycleUUIDs).
+		Group("cycle_uuid, status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if counts[row.CycleUUID] == nil {
+			counts[row.CycleUUID] = make(map[string]int)
+		}
+		counts[row.CycleUUID][row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CountPhaseJobsByStatus counts the jobs of one phase of a cycle per status
+func (s *GORMStore) CountPhaseJobsByStatus(ctx context.Context, cycleUUID, phase string) (map[string]int, error) {
+	return s.countJobsByStatus(ctx, "cycle_uuid = ? AND phase = ?", cycleUUID, phase)
+}
+
+// CountSessionJobsByStatus counts the jobs of a session per status
+func (s *GORMStore) CountSessionJobsByStatus(ctx context.Context, sessionID string) (map[string]int, error) {
+	return s.countJobsByStatus(ctx, "session_id = ?", sessionID)
+}
+
+// countJobsByStatus counts the jobs matching query per status
+func (s *GORMStore) countJobsByStatus(ctx context.Context, query string, args ...interface{}) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Job{}).
+		Select("status, count(*) as count").
+		Where(query, args...).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
 // CRUD methods for Worker
+
+// CreateWorker creates a worker; it returns ErrDuplicateUUID for a worker that exists
+// already, which UpsertWorker replaces instead
 func (s *GORMStore) CreateWorker(ctx context.Context, worker *models.Worker) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.Worker{}, s.batchSize, worker.UUID); err != nil {
+		return err
+	}
 	if worker.UUID == "" {
 		worker.UUID = uuid.New().String()
 	}
-	return s.db.WithContext(ctx).Create(worker).Error
+	return db.Create(worker).Error
 }
 
 func (s *GORMStore) GetWorker(ctx context.Context, id string) (*models.Worker, error) {
@@ -106,12 +451,64 @@ func (s *GORMStore) DeleteWorker(ctx context.Context, id string) error {
 	return s.db.WithContext(ctx).Delete(&models.Worker{}, "uuid = ?", id).Error
 }
 
+// UpsertWorker saves a worker as it registers, whether or not it registered before
+func (s *GORMStore) UpsertWorker(ctx context.Context, worker *models.Worker) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "uuid"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"name", "labels", "capabilities", "draining", "status", "last_seen_at", "version", "updated_at", "deleted_at",
+		}),
+	}).Create(worker).Error
+}
+
+// TouchWorker records a heartbeat of a worker, which is active again if it was not; it
+// returns gorm.ErrRecordNotFound for a worker that never registered
+func (s *GORMStore) TouchWorker(ctx context.Context, id string, seenAt int64) error {
+	db := s.db.WithContext(ctx)
+	result := db.Model(&models.Worker{}).Where("uuid = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "status": models.WorkerActive})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	// MySQL counts the rows changed rather than matched, so an unchanged worker, e.g.
+	// seen twice in the same second, affects none
+	var n int64
+	if err := db.Model(&models.Worker{}).Where("uuid = ?", id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *GORMStore) UpdateWorkerStatus(ctx context.Context, id, status string) error {
+	return s.db.WithContext(ctx).Model(&models.Worker{}).Where("uuid = ?", id).Update("status", status).Error
+}
+
+// ListActiveWorkers returns the active workers seen since a Unix time, by name
+func (s *GORMStore) ListActiveWorkers(ctx context.Context, since int64) ([]models.Worker, error) {
+	var workers []models.Worker
+	if err := s.db.WithContext(ctx).Where("status = ? AND last_seen_at >= ?", models.WorkerActive, since).
+		Order("name").Find(&workers).Error; err != nil {
+		return nil, err
+	}
+	return workers, nil
+}
+
 // CRUD methods for User
+
+// CreateUser creates a user; it returns ErrDuplicateUUID. Its cycle is not checked, as
+// the users of a cycle are saved while it is planned, before the cycle.
 func (s *GORMStore) CreateUser(ctx context.Context, user *models.User) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.User{}, s.batchSize, user.UUID); err != nil {
+		return err
+	}
 	if user.UUID == "" {
 		user.UUID = uuid.New().String()
 	}
-	return s.db.WithContext(ctx).Create(user).Error
+	return db.Create(user).Error
 }
 
 func (s *GORMStore) GetUser(ctx context.Context, id string) (*models.User, error) {
@@ -122,6 +519,19 @@ func (s *GORMStore) GetUser(ctx context.Context, id string) (*models.User, error
 	return &user, nil
 }
 
+// ListUsers returns up to limit users; a non-positive limit returns them all
+func (s *GORMStore) ListUsers(ctx context.Context, limit int) ([]models.User, error) {
+	var users []models.User
+	query := s.db.WithContext(ctx)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (s *GORMStore) UpdateUser(ctx context.Context, user *models.User) error {
 	return s.db.WithContext(ctx).Save(user).Error
 }
@@ -131,11 +541,47 @@ func (s *GORMStore) DeleteUser(ctx context.Context, id string) error {
 }
 
 // CRUD methods for File
+
+// CreateFile creates a file of an existing cycle; it returns ErrCycleNotFound and
+// ErrDuplicateUUID
 func (s *GORMStore) CreateFile(ctx context.Context, file *models.File) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.File{}, s.batchSize, file.UUID); err != nil {
+		return err
+	}
+	if err := checkCycles(db, file.CycleID); err != nil {
+		return err
+	}
 	if file.UUID == "" {
 		file.UUID = uuid.New().String()
 	}
-	return s.db.WithContext(ctx).Create(file).Error
+	return db.Create(file).Error
+}
+
+// CreateFilesBatch inserts files of existing cycles in one transaction, giving a UUID
+// to those without one; it returns ErrCycleNotFound and ErrDuplicateUUID
+func (s *GORMStore) CreateFilesBatch(ctx context.Context, files []models.File) error {
+	if len(files) == 0 {
+		return nil
+	}
+	uuids, cycles := make([]string, len(files)), make([]string, len(files))
+	for i := range files {
+		uuids[i], cycles[i] = files[i].UUID, files[i].CycleID
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkUUIDs(tx, &models.File{}, s.batchSize, uuids...); err != nil {
+			return err
+		}
+		if err := checkCycles(tx, cycles...); err != nil {
+			return err
+		}
+		for i := range files {
+			if files[i].UUID == "" {
+				files[i].UUID = uuid.New().String()
+			}
+		}
+		return tx.CreateInBatches(files, s.batchSize).Error
+	})
 }
 
 func (s *GORMStore) GetFile(ctx context.Context, id string) (*models.File, error) {
@@ -155,14 +601,24 @@ func (s *GORMStore) DeleteFile(ctx context.Context, id string) error {
 }
 
 // CRUD methods for Workspace
+
+// CreateWorkspace creates a workspace of an existing cycle; it returns ErrCycleNotFound
+// and ErrDuplicateUUID
 func (s *GORMStore) CreateWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.Workspace{}, s.batchSize, workspace.UUID); err != nil {
+		return err
+	}
+	if err := checkCycles(db, workspace.CycleID); err != nil {
+		return err
+	}
 	if workspace.UUID == "" {
 		workspace.UUID = uuid.New().String()
 	}
 	if workspace.Users == nil {
 		workspace.Users = []string{}
 	}
-	return s.db.WithContext(ctx).Create(workspace).Error
+	return db.Create(workspace).Error
 }
 
 func (s *GORMStore) GetWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
@@ -181,12 +637,128 @@ func (s *GORMStore) DeleteWorkspace(ctx context.Context, id string) error {
 	return s.db.WithContext(ctx).Delete(&models.Workspace{}, "uuid = ?", id).Error
 }
 
+// CRUD methods for Session
+
+// CreateSession creates a session of an existing cycle; it returns ErrCycleNotFound and
+// ErrDuplicateUUID
+func (s *GORMStore) CreateSession(ctx context.Context, session *models.Session) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.Session{}, s.batchSize, session.UUID); err != nil {
+		return err
+	}
+	if err := checkCycles(db, session.CycleUUID); err != nil {
+		return err
+	}
+	if session.UUID == "" {
+		session.UUID = uuid.New().String()
+	}
+	return db.Create(session).Error
+}
+
+func (s *GORMStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.WithContext(ctx).First(&session, "uuid = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *GORMStore) UpdateSession(ctx context.Context, session *models.Session) error {
+	return s.db.WithContext(ctx).Save(session).Error
+}
+
+// UpdateCycleSessionsStatus moves the sessions of a cycle in one of the from statuses
+// to the to status and returns how many it moved
+func (s *GORMStore) UpdateCycleSessionsStatus(ctx context.Context, cycleUUID string, from []string, to string) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("cycle_uuid = ? AND status IN ?", cycleUUID, from).
+		Update("status", to)
+	return result.RowsAffected, result.Error
+}
+
+// GetCycleSessionsByStatus returns the sessions of a cycle in one of statuses
+func (s *GORMStore) GetCycleSessionsByStatus(ctx context.Context, cycleUUID string, statuses ...string) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := s.db.WithContext(ctx).Where("cycle_uuid = ? AND status IN ?", cycleUUID, statuses).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListCycleSessions returns the sessions of a cycle in any status
+func (s *GORMStore) ListCycleSessions(ctx context.Context, cycleUUID string) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := s.db.WithContext(ctx).Where("cycle_uuid = ?", cycleUUID).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *GORMStore) DeleteSession(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&models.Session{}, "uuid = ?", id).Error
+}
+
+// CRUD methods for UserAction
+
+// CreateUserActions inserts actions of existing cycles in one transaction, giving a
+// UUID to those without one; it returns ErrCycleNotFound and ErrDuplicateUUID
+func (s *GORMStore) CreateUserActions(ctx context.Context, actions []models.UserAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+	uuids, cycles := make([]string, len(actions)), make([]string, len(actions))
+	for i := range actions {
+		uuids[i], cycles[i] = actions[i].UUID, actions[i].CycleUUID
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkUUIDs(tx, &models.UserAction{}, s.batchSize, uuids...); err != nil {
+			return err
+		}
+		if err := checkCycles(tx, cycles...); err != nil {
+			return err
+		}
+		for i := range actions {
+			if actions[i].UUID == "" {
+				actions[i].UUID = uuid.New().String()
+			}
+		}
+		return tx.Omit(clause.Associations).CreateInBatches(actions, s.batchSize).Error
+	})
+}
+
+func (s *GORMStore) GetUserAction(ctx context.Context, id string) (*models.UserAction, error) {
+	var action models.UserAction
+	if err := s.db.WithContext(ctx).First(&action, "uuid = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// ListSessionActions returns the actions of a session by the time they were done
+func (s *GORMStore) ListSessionActions(ctx context.Context, sessionID string) ([]models.UserAction, error) {
+	var actions []models.UserAction
+	if err := s.db.WithContext(ctx).Where("session_id = ?", sessionID).Order("done_at, start_at").Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func (s *GORMStore) DeleteUserAction(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&models.UserAction{}, "uuid = ?", id).Error
+}
+
 // CRUD methods for Cycle
+
+// CreateCycle creates a cycle; it returns ErrDuplicateUUID
 func (s *GORMStore) CreateCycle(ctx context.Context, cycle *models.Cycle) error {
+	db := s.db.WithContext(ctx)
+	if err := checkUUIDs(db, &models.Cycle{}, s.batchSize, cycle.UUID); err != nil {
+		return err
+	}
 	if cycle.UUID == "" {
 		cycle.UUID = uuid.New().String()
 	}
-	return s.db.WithContext(ctx).Create(cycle).Error
+	return db.Create(cycle).Error
 }
 
 func (s *GORMStore) GetCycle(ctx context.Context, id string) (*models.Cycle, error) {
@@ -197,30 +769,176 @@ func (s *GORMStore) GetCycle(ctx context.Context, id string) (*models.Cycle, err
 	return &cycle, nil
 }
 
+// ListCyclesByStatus returns the cycles in one of statuses
+func (s *GORMStore) ListCyclesByStatus(ctx context.Context, statuses ...string) ([]models.Cycle, error) {
+	var cycles []models.Cycle
+	if err := s.db.WithContext(ctx).Where("status IN ?", statuses).Find(&cycles).Error; err != nil {
+		return nil, err
+	}
+	return cycles, nil
+}
+
+// ListCyclesByPhase returns the cycles in phase
+func (s *GORMStore) ListCyclesByPhase(ctx context.Context, phase string) ([]models.Cycle, error) {
+	var cycles []models.Cycle
+	if err := s.db.WithContext(ctx).Where("phase = ?", phase).Find(&cycles).Error; err != nil {
+		return nil, err
+	}
+	return cycles, nil
+}
+
+// CycleFilter selects cycles; empty fields match every cycle
+type CycleFilter struct {
+	Statuses []string
+	Template string
+	Labels   map[string]string // Every label must be set to the value
+}
+
+// ListCycles returns the cycles matching filter, most recently started first. Labels
+// are serialized, so they are matched after the query.
+func (s *GORMStore) ListCycles(ctx context.Context, filter CycleFilter) ([]models.Cycle, error) {
+	query := s.db.WithContext(ctx).Order("started_at DESC")
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Template != "" {
+		query = query.Where("template = ?", filter.Template)
+	}
+	var cycles []models.Cycle
+	if err := query.Find(&cycles).Error; err != nil {
+		return nil, err
+	}
+	matched := cycles[:0]
+	for _, cycle := range cycles {
+		if hasLabels(cycle.Labels, filter.Labels) {
+			matched = append(matched, cycle)
+		}
+	}
+	return matched, nil
+}
+
+// hasLabels reports whether labels has every label of want
+func hasLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *GORMStore) UpdateCycle(ctx context.Context, cycle *models.Cycle) error {
 	return s.db.WithContext(ctx).Save(cycle).Error
 }
 
+// CycleProgressColumns are the columns of the progress of a cycle
+var CycleProgressColumns = []string{"total_jobs", "completed_jobs", "failed_jobs", "cancelled_jobs", "estimated_done_at"}
+
+// UpdateCycleColumns saves the named columns of a cycle only, so the changes others
+// made to the rest of the row since it was read are kept
+func (s *GORMStore) UpdateCycleColumns(ctx context.Context, cycle *models.Cycle, columns ...string) error {
+	result := s.db.WithContext(ctx).Model(cycle).Select(slices.Concat(columns, []string{"updated_at"})).Updates(cycle)
+	if result.Error == nil && result.RowsAffected == 0 {
+		// MySQL counts the rows changed rather than those matched
+		_, err := s.GetCycle(ctx, cycle.UUID)
+		return err
+	}
+	return result.Error
+}
+
+// UpdateCycleStatus saves the status of a cycle and the named columns in one statement
+// conditional on the status in the database, so a cycle read before another update
+// moved it does not overwrite that update
+func (s *GORMStore) UpdateCycleStatus(ctx context.Context, cycle *models.Cycle, from []string, columns ...string) error {
+	result := s.db.WithContext(ctx).Model(cycle).Where("status IN ?", from).
+		Select(slices.Concat([]string{"status", "updated_at"}, columns)).Updates(cycle)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	current, err := s.GetCycle(ctx, cycle.UUID)
+	if err != nil {
+		return err
+	}
+	// MySQL counts the rows changed rather than those matched
+	if slices.Contains(from, current.Status) {
+		return nil
+	}
+	return fmt.Errorf("%w: cycle %s is %s, expected one of %v", ErrConflict, cycle.UUID, current.Status, from)
+}
+
 func (s *GORMStore) DeleteCycle(ctx context.Context, id string) error {
 	return s.db.WithContext(ctx).Delete(&models.Cycle{}, "uuid = ?", id).Error
 }
 
+// CRUD methods for CycleTemplate
+
+// CreateCycleTemplate creates a template, replacing a soft-deleted one by the same name
+func (s *GORMStore) CreateCycleTemplate(ctx context.Context, template *models.CycleTemplate) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("name = ? AND deleted_at IS NOT NULL", template.Name).
+			Delete(&models.CycleTemplate{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(template).Error
+	})
+}
+
+func (s *GORMStore) GetCycleTemplate(ctx context.Context, name string) (*models.CycleTemplate, error) {
+	var template models.CycleTemplate
+	if err := s.db.WithContext(ctx).First(&template, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ListCycleTemplates returns every cycle template by name
+func (s *GORMStore) ListCycleTemplates(ctx context.Context) ([]models.CycleTemplate, error) {
+	var templates []models.CycleTemplate
+	if err := s.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// UpdateCycleTemplate saves an existing template; it fails when there is none by the name
+func (s *GORMStore) UpdateCycleTemplate(ctx context.Context, template *models.CycleTemplate) error {
+	result := s.db.WithContext(ctx).Model(template).Select("description", "strategy", "updated_at").Updates(template)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (s *GORMStore) DeleteCycleTemplate(ctx context.Context, name string) error {
+	return s.db.WithContext(ctx).Delete(&models.CycleTemplate{}, "name = ?", name).Error
+}
+
+// WithTx runs fn in a database transaction; the batch inserts and updates fn makes
+// run in nested transactions, which commit or roll back with it
+func (s *GORMStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&GORMStore{db: tx, batchSize: s.batchSize})
+	})
+}
+
 // ProvideStore is an fx-compatible constructor
-func ProvideStore(lc fx.Lifecycle, db *gorm.DB) Store {
+func ProvideStore(lc fx.Lifecycle, db *gorm.DB, cfg StoreConfig, log logger.Logger, metrics *QueryMetrics, events *Events) (Store, error) {
+	if err := Instrument(db, metrics, log, time.Duration(cfg.SlowQueryMs)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if err := ScopeProjects(db); err != nil {
+		return nil, err
+	}
 	store := NewGORMStore(db)
+	if cfg.BatchSize > 0 {
+		store.batchSize = cfg.BatchSize
+	}
 
 	// Add lifecycle hooks for migrations
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			// Perform migrations for database tables
-			return db.AutoMigrate(
-				&models.Job{},
-				&models.Worker{},
-				&models.User{},
-				&models.File{},
-				&models.Workspace{},
-				&models.Cycle{},
-			)
+			// Upgrade the database to the schema of this release
+			return Migrate(db.WithContext(ctx))
 		},
 		OnStop: func(ctx context.Context) error {
 			// Cleanup tasks if needed
@@ -232,8 +950,13 @@ func ProvideStore(lc fx.Lifecycle, db *gorm.DB) Store {
 		},
 	})
 
-	return store
+	var cached Store = store
+	if cfg.Cache.Size > 0 {
+		cached = NewCachedStore(store, cfg.Cache)
+	}
+	return NewObservedStore(cached, events), nil
 }
 
-// Module exports the Store for fx
-var Module = fx.Provide(ProvideStore)
+// Module exports the Store, the metrics of its queries and the events of its changes
+// for fx
+var Module = fx.Provide(ProvideStore, NewQueryMetrics, NewEvents)
@@ -0,0 +1,788 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+// newTestStore opens a GORMStore on a sqlite database of its own
+func newTestStore(tb testing.TB) *GORMStore {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tb.TempDir(), "store.db")), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(tb, err)
+	require.NoError(tb, Migrate(db))
+	require.NoError(tb, ScopeProjects(db))
+	tb.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	return NewGORMStore(db)
+}
+
+// createCycles creates running cycles, which jobs need to be created for
+func createCycles(tb testing.TB, s Store, uuids ...string) {
+	for _, id := range uuids {
+		require.NoError(tb, s.CreateCycle(context.Background(), &models.Cycle{UUID: id, Status: "running"}))
+	}
+}
+
+func newJobs(n int) []models.Job {
+	jobs := make([]models.Job, n)
+	for i := range jobs {
+		jobs[i] = models.Job{Name: "upload_file", Status: "pending", CycleUUID: "c1", SessionID: fmt.Sprintf("s%d", i%10)}
+	}
+	return jobs
+}
+
+func TestCreateBatch(t *testing.T) {
+	s := newTestStore(t)
+	s.batchSize = 100
+	ctx := context.Background()
+	createCycles(t, s, "c1")
+
+	jobs := newJobs(250)
+	require.NoError(t, s.CreateJobsBatch(ctx, jobs))
+	for _, job := range jobs {
+		assert.NotEmpty(t, job.UUID)
+	}
+	counts, err := s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"pending": 250}, counts)
+
+	duplicated := newJobs(150)
+	duplicated[149].UUID = jobs[0].UUID
+	assert.ErrorIs(t, s.CreateJobsBatch(ctx, duplicated), ErrDuplicateUUID)
+	counts, err = s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, 250, counts["pending"], "a failed batch insert saves nothing")
+
+	files := []models.File{{Name: "a.txt", CycleID: "c1"}, {Name: "b.txt", CycleID: "c1"}}
+	require.NoError(t, s.CreateFilesBatch(ctx, files))
+	saved, err := s.GetFile(ctx, files[1].UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt", saved.Name)
+	assert.NoError(t, s.CreateJobsBatch(ctx, nil))
+}
+
+func TestListJobs(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	createCycles(t, s, "c1", "c2")
+	jobs := []models.Job{
+		{UUID: "j1", Status: "completed", CycleUUID: "c1", SessionID: "s1", WorkerID: "w1", StartAt: 100},
+		{UUID: "j2", Status: "failed", CycleUUID: "c1", SessionID: "s1", WorkerID: "w2", StartAt: 200},
+		{UUID: "j3", Status: "completed", CycleUUID: "c1", SessionID: "s2", WorkerID: "w1", StartAt: 200},
+		{UUID: "j4", Status: "completed", CycleUUID: "c1", SessionID: "s2", WorkerID: "w1", StartAt: 300},
+		{UUID: "j5", Status: "completed", CycleUUID: "c2", SessionID: "s3", WorkerID: "w1", StartAt: 400},
+	}
+	require.NoError(t, s.CreateJobsBatch(ctx, jobs))
+
+	// Walk the pages of the jobs of c1, latest first, two at a time
+	var uuids []string
+	page := Page{Desc: true, Limit: 2}
+	for {
+		result, err := s.ListJobs(ctx, JobFilter{CycleUUID: "c1"}, page)
+		require.NoError(t, err)
+		for _, job := range result.Jobs {
+			uuids = append(uuids, job.UUID)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	assert.Equal(t, []string{"j4", "j3", "j2", "j1"}, uuids, "ties are broken by UUID")
+
+	result, err := s.ListJobs(ctx, JobFilter{Statuses: []string{"completed"}, WorkerID: "w1", Since: 200, Until: 400}, Page{})
+	require.NoError(t, err)
+	require.Len(t, result.Jobs, 2)
+	assert.Equal(t, "j3", result.Jobs[0].UUID)
+	assert.Equal(t, "j4", result.Jobs[1].UUID)
+	assert.Empty(t, result.NextCursor)
+
+	_, err = s.ListJobs(ctx, JobFilter{}, Page{Sort: "name"})
+	assert.ErrorIs(t, err, ErrInvalidPage)
+	_, err = s.ListJobs(ctx, JobFilter{}, Page{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidPage)
+}
+
+func TestJobVersions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	createCycles(t, s, "c1")
+	require.NoError(t, s.CreateJob(ctx, &models.Job{UUID: "j1", Status: "pending", CycleUUID: "c1", SessionID: "s1"}))
+
+	dispatching, err := s.GetJob(ctx, "j1")
+	require.NoError(t, err)
+	completing, err := s.GetJob(ctx, "j1")
+	require.NoError(t, err)
+
+	completing.Status = "completed"
+	require.NoError(t, s.UpdateJob(ctx, completing))
+	assert.Equal(t, 1, completing.Version)
+	dispatching.Status = "dispatched"
+	assert.ErrorIs(t, s.UpdateJob(ctx, dispatching), ErrConflict, "a stale job does not overwrite the result")
+	assert.Equal(t, 0, dispatching.Version)
+
+	saved, err := s.GetJob(ctx, "j1")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", saved.Status)
+
+	assert.ErrorIs(t, s.UpdateJobStatus(ctx, "j1", []string{"pending"}, "dispatched"), ErrConflict)
+	require.NoError(t, s.UpdateJobStatus(ctx, "j1", []string{"completed", "failed"}, "pending"))
+	_, err = s.UpdateCycleJobsStatus(ctx, "c1", []string{"pending"}, "cancelled")
+	require.NoError(t, err)
+	saved, err = s.GetJob(ctx, "j1")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", saved.Status)
+	assert.Equal(t, 3, saved.Version, "every transition moves the version")
+	assert.ErrorIs(t, s.UpdateJobStatus(ctx, "j2", []string{"pending"}, "dispatched"), gorm.ErrRecordNotFound)
+
+	require.NoError(t, s.CreateJobsBatch(ctx, []models.Job{{UUID: "j2", CycleUUID: "c1", SessionID: "s1", Status: "pending"}}))
+	stale := []models.Job{{UUID: "j2", CycleUUID: "c1", SessionID: "s1", Status: "dispatched"}, *saved}
+	stale[1].Version--
+	assert.ErrorIs(t, s.UpdateJobs(ctx, stale), ErrConflict)
+	assert.Equal(t, 0, stale[0].Version)
+	saved, err = s.GetJob(ctx, "j2")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", saved.Status, "a conflicting batch saves nothing")
+}
+
+func TestUpdateJobsStatus(t *testing.T) {
+	s := newTestStore(t)
+	s.batchSize = 2
+	ctx := context.Background()
+	createCycles(t, s, "c1")
+	jobs := newJobs(5)
+	jobs[4].Status = "completed"
+	require.NoError(t, s.CreateJobsBatch(ctx, jobs))
+	uuids := make([]string, len(jobs))
+	for i, job := range jobs {
+		uuids[i] = job.UUID
+	}
+
+	n, err := s.UpdateJobsStatus(ctx, uuids, "dispatched")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n, "finished jobs are left alone")
+	saved, err := s.GetJobsByCycle(ctx, "c1", "dispatched")
+	require.NoError(t, err)
+	require.Len(t, saved, 4)
+	assert.Equal(t, 1, saved[0].Version)
+	done, err := s.GetJob(ctx, jobs[4].UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", done.Status)
+
+	n, err = s.UpdateJobsStatus(ctx, nil, "pending")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestUpdateCycleStatus(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	createCycles(t, s, "c1")
+
+	// A pause lands between the read and the write of the progress of the cycle
+	stale, err := s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	paused := *stale
+	paused.Status = "paused"
+	require.NoError(t, s.UpdateCycleStatus(ctx, &paused, []string{"running"}))
+
+	stale.Progress.CompletedJobs = 3
+	require.NoError(t, s.UpdateCycleColumns(ctx, stale, CycleProgressColumns...))
+	stale.Status = "completed"
+	err = s.UpdateCycleStatus(ctx, stale, []string{"running"}, "done_at")
+	assert.ErrorIs(t, err, ErrConflict)
+
+	cycle, err := s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "paused", cycle.Status, "the pause is kept")
+	assert.Equal(t, 3, cycle.Progress.CompletedJobs)
+
+	require.NoError(t, s.UpdateCycleStatus(ctx, cycle, []string{"paused"}), "an unchanged row is no conflict")
+	assert.ErrorIs(t, s.UpdateCycleColumns(ctx, &models.Cycle{UUID: "c9"}, "reason"), gorm.ErrRecordNotFound)
+}
+
+func TestCountJobsByCycle(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	createCycles(t, s, "c1", "c2", "c3")
+	require.NoError(t, s.CreateJobsBatch(ctx, []models.Job{
+		{CycleUUID: "c1", SessionID: "s1", Status: "completed"},
+		{CycleUUID: "c1", SessionID: "s1", Status: "completed"},
+		{CycleUUID: "c1", SessionID: "s1", Status: "pending"},
+		{CycleUUID: "c2", SessionID: "s2", Status: "failed"},
+		{CycleUUID: "c3", SessionID: "s3", Status: "pending"},
+	}))
+
+	counts, err := s.CountJobsByCycle(ctx, []string{"c1", "c2", "c4"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]int{
+		"c1": {"completed": 2, "pending": 1},
+		"c2": {"failed": 1},
+	}, counts)
+
+	jobs, err := s.GetJobsByCycle(ctx, "c1", "pending", "processing")
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	jobs, err = s.GetJobsByCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Len(t, jobs, 3)
+}
+
+func TestWithTx(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	err := s.WithTx(ctx, func(tx Store) error {
+		require.NoError(t, tx.CreateCycle(ctx, &models.Cycle{UUID: "c1", Status: "running"}))
+		require.NoError(t, tx.CreateJobsBatch(ctx, newJobs(3)))
+		return errors.New("crash")
+	})
+	require.EqualError(t, err, "crash")
+	_, err = s.GetCycle(ctx, "c1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "the cycle rolls back")
+	counts, err := s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Empty(t, counts, "its jobs roll back with it")
+
+	require.NoError(t, s.WithTx(ctx, func(tx Store) error {
+		if err := tx.CreateCycle(ctx, &models.Cycle{UUID: "c1", Status: "running"}); err != nil {
+			return err
+		}
+		return tx.CreateJobsBatch(ctx, newJobs(3))
+	}))
+	_, err = s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	counts, err = s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, counts["pending"])
+}
+
+func TestMigrations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "store.db")), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	// A database robo created with AutoMigrate adopts the initial migration
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+
+	status, err := Migrations(db)
+	require.NoError(t, err)
+	require.NotEmpty(t, status)
+	for _, m := range status {
+		assert.False(t, m.Applied, m.ID)
+	}
+
+	require.NoError(t, Migrate(db))
+	require.NoError(t, Migrate(db), "migrating is idempotent")
+	status, err = Migrations(db)
+	require.NoError(t, err)
+	for _, m := range status {
+		assert.True(t, m.Applied, m.ID)
+	}
+	assert.True(t, db.Migrator().HasTable(&models.Cycle{}))
+
+	reverted, err := RollbackMigrations(db, len(status)+1)
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(status), "rolling back stops with the first migration")
+	assert.Equal(t, status[len(status)-1].ID, reverted[0], "the last migration is reverted first")
+	assert.False(t, db.Migrator().HasTable(&models.Cycle{}))
+	status, err = Migrations(db)
+	require.NoError(t, err)
+	for _, m := range status {
+		assert.False(t, m.Applied, m.ID)
+	}
+}
+
+func TestUsernamesUniquePerCycle(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "store.db")), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	// The users table of the releases whose usernames were unique across cycles
+	type legacyUser struct {
+		UUID     string `gorm:"primaryKey;type:uuid"`
+		UserName string `gorm:"column:username;type:text;unique;not null"`
+		CycleID  string `gorm:"column:cycle_id;type:uuid;not null"`
+	}
+	require.NoError(t, db.Table("users").AutoMigrate(&legacyUser{}))
+	require.True(t, db.Migrator().HasConstraint("users", "uni_users_username"))
+	require.NoError(t, Migrate(db))
+	require.NoError(t, ScopeProjects(db))
+
+	s := NewGORMStore(db)
+	ctx := context.Background()
+	createCycles(t, s, "c1", "c2")
+	require.NoError(t, s.CreateUser(ctx, &models.User{UUID: "u1", UserName: "alice", CycleID: "c1"}))
+	require.NoError(t, s.CreateUser(ctx, &models.User{UUID: "u2", UserName: "alice", CycleID: "c2"}), "a replay reuses the usernames of the cycle it replays")
+	assert.Error(t, s.CreateUser(ctx, &models.User{UUID: "u3", UserName: "alice", CycleID: "c1"}))
+}
+
+func TestArchiveCycle(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	for _, cycle := range []models.Cycle{
+		{UUID: "c1", Status: "completed", DoneAt: 100},
+		{UUID: "c2", Status: "completed", DoneAt: 200},
+		{UUID: "c3", Status: "running"},
+	} {
+		require.NoError(t, s.CreateCycle(ctx, &cycle))
+	}
+	require.NoError(t, s.CreateSession(ctx, &models.Session{UUID: "s1", CycleUUID: "c1"}))
+	require.NoError(t, s.CreateFilesBatch(ctx, []models.File{{CycleID: "c1", SessionID: "s1", Name: "a.txt"}}))
+	require.NoError(t, s.CreateJobsBatch(ctx, newJobs(3)))
+
+	cycles, err := s.ListCyclesDoneBefore(ctx, 150, 10)
+	require.NoError(t, err)
+	require.Len(t, cycles, 1)
+	assert.Equal(t, "c1", cycles[0].UUID, "running cycles and those done since are kept")
+
+	var buf bytes.Buffer
+	require.NoError(t, s.ArchiveCycle(ctx, "c1", &buf))
+	zr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	kinds := make(map[string]int)
+	dec := json.NewDecoder(zr)
+	for dec.More() {
+		var record ArchiveRecord
+		require.NoError(t, dec.Decode(&record))
+		kinds[record.Kind]++
+	}
+	assert.Equal(t, map[string]int{"cycle": 1, "session": 1, "file": 1, "job": 3}, kinds)
+
+	require.NoError(t, s.DeleteCycleData(ctx, "c1"))
+	_, err = s.GetCycle(ctx, "c1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	counts, err := s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+	_, err = s.GetCycle(ctx, "c2")
+	assert.NoError(t, err, "other cycles are kept")
+}
+
+func TestUserActions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	require.NoError(t, s.CreateCycle(ctx, &models.Cycle{UUID: "c1", Status: "running"}))
+	require.NoError(t, s.CreateSession(ctx, &models.Session{UUID: "s1", CycleUUID: "c1", Status: "active"}))
+	require.NoError(t, s.CreateSession(ctx, &models.Session{UUID: "s2", CycleUUID: "c1", Status: "planned"}))
+
+	actions := []models.UserAction{
+		models.NewUserAction(models.Job{UUID: "j2", CycleUUID: "c1", SessionID: "s1", Name: "consult_file", Status: "failed", DoneAt: 20}),
+		models.NewUserAction(models.Job{UUID: "j1", CycleUUID: "c1", SessionID: "s1", Name: "upload_file", Status: "completed", DoneAt: 10}),
+		models.NewUserAction(models.Job{UUID: "j2", CycleUUID: "c1", SessionID: "s1", Name: "consult_file", Status: "completed", DoneAt: 30}),
+	}
+	require.NoError(t, s.CreateUserActions(ctx, actions))
+	listed, err := s.ListSessionActions(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, listed, 3, "every result of a retried job is kept")
+	assert.Equal(t, []string{"j1", "j2", "j2"}, []string{listed[0].JobUUID, listed[1].JobUUID, listed[2].JobUUID})
+
+	action, err := s.GetUserAction(ctx, actions[0].UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", action.Status)
+	require.NoError(t, s.DeleteUserAction(ctx, action.UUID))
+	_, err = s.GetUserAction(ctx, action.UUID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	sessions, err := s.ListCycleSessions(ctx, "c1")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	require.NoError(t, s.DeleteSession(ctx, "s2"))
+	sessions, err = s.ListCycleSessions(ctx, "c1")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestSoftDeleteAndPurge(t *testing.T) {
+	s := newTestStore(t)
+	ctx := models.WithCreator(context.Background(), "key:abc")
+	require.NoError(t, s.CreateCycle(ctx, &models.Cycle{UUID: "c1", Status: "completed"}))
+	require.NoError(t, s.CreateJobsBatch(context.Background(), newJobs(2)))
+	require.NoError(t, s.CreateCycleTemplate(ctx, &models.CycleTemplate{Name: "nightly"}))
+
+	cycle, err := s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "key:abc", cycle.CreatedBy)
+	assert.NotZero(t, cycle.CreatedAt)
+	var jobs []models.Job
+	require.NoError(t, s.GetJobsByStatus(ctx, "pending", &jobs))
+	require.Len(t, jobs, 2)
+	assert.Equal(t, models.SystemCreator, jobs[0].CreatedBy)
+
+	require.NoError(t, s.DeleteCycle(ctx, "c1"))
+	require.NoError(t, s.DeleteJob(ctx, jobs[0].UUID))
+	require.NoError(t, s.DeleteCycleTemplate(ctx, "nightly"))
+	_, err = s.GetCycle(ctx, "c1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "soft-deleted rows are not found")
+	counts, err := s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"pending": 1}, counts)
+	require.NoError(t, s.CreateCycleTemplate(ctx, &models.CycleTemplate{Name: "nightly"}),
+		"a soft-deleted template is replaced")
+
+	purged, err := s.Purge(ctx, time.Now().Add(-time.Hour).Unix())
+	require.NoError(t, err)
+	assert.Zero(t, purged["cycles"], "rows deleted after the purge time are kept")
+	purged, err = s.Purge(ctx, time.Now().Add(time.Hour).Unix())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged["cycles"])
+	assert.Equal(t, int64(1), purged["jobs"])
+	assert.Zero(t, purged["cycle_templates"])
+	var remaining int64
+	require.NoError(t, s.db.Unscoped().Model(&models.Job{}).Count(&remaining).Error)
+	assert.Equal(t, int64(1), remaining)
+}
+
+// slowLog records the messages logged about slow queries
+type slowLog struct {
+	logger.Logger
+	messages []string
+}
+
+func (l *slowLog) Info(ctx context.Context, msg string, args ...any) {
+	l.messages = append(l.messages, msg)
+}
+
+func TestInstrument(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	createCycles(t, s, "c1")
+	metrics, log := NewQueryMetrics(), &slowLog{}
+	require.NoError(t, Instrument(s.db, metrics, log, time.Nanosecond))
+
+	require.NoError(t, s.CreateJobsBatch(ctx, newJobs(3)))
+	_, err := s.GetJob(ctx, "missing")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.Error(t, s.db.Exec("SELECT * FROM missing_table").Error)
+	assert.NotEmpty(t, log.messages, "queries over the threshold are logged")
+
+	var buf bytes.Buffer
+	require.NoError(t, metrics.WriteMetrics(&buf))
+	out := buf.String()
+	assert.Contains(t, out, `robo_store_queries_total{operation="create"} 1`)
+	assert.Contains(t, out, `robo_store_rows_affected_total{operation="create"} 3`)
+	assert.Contains(t, out, `robo_store_query_errors_total{operation="query"} 0`, "missing records are not errors")
+	assert.Contains(t, out, `robo_store_query_errors_total{operation="raw"} 1`)
+	// Creating the jobs reads their cycle and the quota of their project
+	assert.Contains(t, out, `robo_store_query_duration_seconds_count{operation="query"} 3`)
+}
+
+func BenchmarkCreateJobs(b *testing.B) {
+	s := newTestStore(b)
+	ctx := context.Background()
+	createCycles(b, s, "c1")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, job := range newJobs(1000) {
+			if err := s.CreateJob(ctx, &job); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCreateJobsBatch(b *testing.B) {
+	for _, size := range []int{100, DefaultBatchSize} {
+		b.Run(fmt.Sprintf("batch_size=%d", size), func(b *testing.B) {
+			s := newTestStore(b)
+			s.batchSize = size
+			ctx := context.Background()
+			createCycles(b, s, "c1")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := s.CreateJobsBatch(ctx, newJobs(1000)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// countingStore counts the lookups reaching the database
+type countingStore struct {
+	Store
+	gets int
+}
+
+func (s *countingStore) GetCycle(ctx context.Context, id string) (*models.Cycle, error) {
+	s.gets++
+	return s.Store.GetCycle(ctx, id)
+}
+
+func TestCachedStore(t *testing.T) {
+	db := &countingStore{Store: newTestStore(t)}
+	s := NewCachedStore(db, CacheConfig{Size: 2, TTLSeconds: 60})
+	ctx := context.Background()
+	for _, id := range []string{"c1", "c2", "c3"} {
+		require.NoError(t, s.CreateCycle(ctx, &models.Cycle{UUID: id, Status: "running"}))
+	}
+
+	cycle, err := s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	cycle.Status = "changed"
+	cycle, err = s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, db.gets, "the second lookup is served from cache")
+	assert.Equal(t, "running", cycle.Status, "callers get copies")
+
+	cycle.Status = "completed"
+	require.NoError(t, s.UpdateCycle(ctx, cycle))
+	cycle, err = s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", cycle.Status, "writes invalidate the cache")
+	assert.Equal(t, 2, db.gets)
+
+	_, err = s.GetCycle(ctx, "c9")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = s.GetCycle(ctx, "c2")
+	require.NoError(t, err)
+	_, err = s.GetCycle(ctx, "c3")
+	require.NoError(t, err)
+	_, err = s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, 6, db.gets, "the least recently used cycle is evicted")
+
+	require.NoError(t, s.WithTx(ctx, func(tx Store) error {
+		require.NoError(t, tx.UpdateCycle(ctx, &models.Cycle{UUID: "c1", Status: "aborted"}))
+		cycle, err := tx.GetCycle(ctx, "c1")
+		require.NoError(t, err)
+		assert.Equal(t, "aborted", cycle.Status)
+		return nil
+	}))
+	cycle, err = s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "aborted", cycle.Status, "transactions invalidate what they write")
+
+	s.cycles.now = func() time.Time { return time.Now().Add(time.Hour) }
+	gets := db.gets
+	_, err = s.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, gets+1, db.gets, "expired rows are read again")
+}
+
+func TestObservedStore(t *testing.T) {
+	events := NewEvents()
+	var changes []Change
+	unsubscribe := events.Subscribe(func(ctx context.Context, change Change) {
+		changes = append(changes, change)
+	})
+	s := NewObservedStore(newTestStore(t), events)
+	ctx := context.Background()
+
+	cycle := &models.Cycle{UUID: "c1", Status: "running"}
+	require.NoError(t, s.CreateCycle(ctx, cycle))
+	cycle.Progress.CompletedJobs = 1
+	require.NoError(t, s.UpdateCycle(ctx, cycle))
+	assert.Len(t, changes, 1, "saving a cycle in the same status is not a change")
+
+	err := s.WithTx(ctx, func(tx Store) error {
+		require.NoError(t, tx.CreateJobsBatch(ctx, newJobs(3)))
+		_, err := tx.UpdateCycleJobsStatus(ctx, "c1", []string{"pending"}, "cancelled")
+		require.NoError(t, err)
+		assert.Len(t, changes, 1, "changes wait for the transaction to commit")
+		return errors.New("crash")
+	})
+	require.Error(t, err)
+	assert.Len(t, changes, 1, "changes rolled back are not published")
+
+	jobs := newJobs(2)
+	require.NoError(t, s.CreateJobsBatch(ctx, jobs))
+	require.NoError(t, s.WithTx(ctx, func(tx Store) error {
+		jobs[0].Status = "completed"
+		if err := tx.UpdateJob(ctx, &jobs[0]); err != nil {
+			return err
+		}
+		cycle.Status = "paused"
+		return tx.UpdateCycle(ctx, cycle)
+	}))
+	_, err = s.UpdateCycleJobsStatus(ctx, "c1", []string{"pending"}, "cancelled")
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	assert.Equal(t, Change{Kind: CycleChanged, UUID: "c1", CycleUUID: "c1", ProjectID: models.DefaultProject, Status: "running", At: changes[0].At}, changes[0])
+	assert.Equal(t, JobChanged, changes[1].Kind)
+	assert.Equal(t, jobs[0].UUID, changes[1].UUID)
+	assert.Equal(t, "completed", changes[1].Status)
+	assert.Equal(t, "paused", changes[2].Status)
+	assert.Equal(t, Change{Kind: JobsChanged, CycleUUID: "c1", Status: "cancelled", Count: 1, At: changes[3].At}, changes[3])
+
+	unsubscribe()
+	cycle.Status = "running"
+	require.NoError(t, s.UpdateCycle(ctx, cycle))
+	assert.Len(t, changes, 4)
+}
+
+func TestWorkerRegistry(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	worker := &models.Worker{UUID: "w1", Name: "worker-1", Capabilities: []string{"login"}, Status: models.WorkerActive, LastSeenAt: 100, Version: "1.0"}
+	require.NoError(t, s.UpsertWorker(ctx, worker))
+	require.NoError(t, s.UpsertWorker(ctx, &models.Worker{UUID: "w2", Name: "worker-2", Status: models.WorkerActive, LastSeenAt: 100}))
+	assert.ErrorIs(t, s.TouchWorker(ctx, "w9", 200), gorm.ErrRecordNotFound)
+
+	worker.Version, worker.LastSeenAt = "1.1", 150
+	require.NoError(t, s.UpsertWorker(ctx, worker), "a worker registers again")
+	require.NoError(t, s.TouchWorker(ctx, "w2", 200))
+	require.NoError(t, s.TouchWorker(ctx, "w2", 200), "a heartbeat that changes nothing still finds the worker")
+	saved, err := s.GetWorker(ctx, "w1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", saved.Version)
+	assert.Equal(t, []string{"login"}, saved.Capabilities)
+
+	active, err := s.ListActiveWorkers(ctx, 150)
+	require.NoError(t, err)
+	require.Len(t, active, 2)
+	assert.Equal(t, "worker-1", active[0].Name)
+	active, err = s.ListActiveWorkers(ctx, 160)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "w2", active[0].UUID)
+
+	require.NoError(t, s.UpdateWorkerStatus(ctx, "w2", models.WorkerInactive))
+	active, err = s.ListActiveWorkers(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.NoError(t, s.TouchWorker(ctx, "w2", 300))
+	active, err = s.ListActiveWorkers(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, active, 2, "a heartbeat makes a worker active again")
+}
+
+func TestMetricRollups(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	rollup := func(action string, minute, durationMs int64) models.MetricRollup {
+		r := models.MetricRollup{CycleUUID: "c1", Action: action, Minute: minute}
+		r.Record(durationMs, false)
+		return r
+	}
+	require.NoError(t, s.MergeMetricRollups(ctx, []models.MetricRollup{rollup("login", 60, 30), rollup("upload_file", 60, 400)}))
+	require.NoError(t, s.MergeMetricRollups(ctx, []models.MetricRollup{rollup("login", 60, 5), rollup("login", 120, 30)}))
+
+	rollups, err := s.ListMetricRollups(ctx, "c1", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, rollups, 3)
+	assert.Equal(t, "login", rollups[0].Action)
+	assert.Equal(t, int64(2), rollups[0].Jobs, "rollups of the same minute add up")
+	assert.Equal(t, int64(5), rollups[0].DurationMinMs)
+	assert.Equal(t, int64(35), rollups[0].DurationSumMs)
+	assert.Equal(t, int64(1), rollups[0].Buckets[0])
+	assert.Equal(t, "upload_file", rollups[1].Action)
+
+	rollups, err = s.ListMetricRollups(ctx, "c1", 150, 0)
+	require.NoError(t, err)
+	require.Len(t, rollups, 1, "from covers its whole minute")
+	assert.Equal(t, int64(120), rollups[0].Minute)
+	rollups, err = s.ListMetricRollups(ctx, "c1", 0, 60)
+	require.NoError(t, err)
+	assert.Len(t, rollups, 2)
+
+	require.NoError(t, s.DeleteCycleData(ctx, "c1"))
+	rollups, err = s.ListMetricRollups(ctx, "c1", 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, rollups)
+}
+
+func TestProjects(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	require.NoError(t, s.CreateProject(ctx, &models.Project{ID: "team-a", Quota: models.ProjectQuota{MaxRunningCycles: 1, MaxCycleJobs: 3}}))
+	teamA, other := models.WithProject(ctx, "team-a"), models.WithProject(ctx, models.DefaultProject)
+
+	c1 := &models.Cycle{UUID: "c1", Status: "running"}
+	require.NoError(t, s.CreateCycle(teamA, c1))
+	assert.Equal(t, "team-a", c1.ProjectID)
+	require.NoError(t, s.CreateCycle(ctx, &models.Cycle{UUID: "c2", Status: "running"}))
+	// Jobs created outside of a project, e.g. by the scheduler, join the project of their cycle
+	jobs := newJobs(2)
+	require.NoError(t, s.CreateJobsBatch(ctx, jobs))
+	assert.Equal(t, "team-a", jobs[0].ProjectID)
+
+	cycles, err := s.ListCycles(teamA, CycleFilter{})
+	require.NoError(t, err)
+	require.Len(t, cycles, 1)
+	assert.Equal(t, "c1", cycles[0].UUID)
+	cycles, err = s.ListCycles(ctx, CycleFilter{})
+	require.NoError(t, err)
+	assert.Len(t, cycles, 2, "contexts without a project see every project")
+	_, err = s.GetCycle(teamA, "c2")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	found, err := s.GetJobsByCycle(other, "c1")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	n, err := s.UpdateCycleJobsStatus(other, "c1", []string{"pending"}, "cancelled")
+	require.NoError(t, err)
+	assert.Zero(t, n, "updates leave the rows of other projects alone")
+
+	c2, err := s.GetCycle(ctx, "c2")
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.UpdateCycle(teamA, c2), gorm.ErrRecordNotFound, "saving a row of another project does not move it")
+	c2, err = s.GetCycle(ctx, "c2")
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultProject, c2.ProjectID)
+
+	assert.ErrorIs(t, s.CreateCycle(teamA, &models.Cycle{UUID: "c3", Status: "running"}), ErrQuotaExceeded)
+	require.NoError(t, s.CreateCycle(teamA, &models.Cycle{UUID: "c4", Status: "aborted", DoneAt: 100}), "done cycles do not run")
+	assert.ErrorIs(t, s.CreateJobsBatch(teamA, newJobs(2)), ErrQuotaExceeded)
+	require.NoError(t, s.CreateJob(teamA, &newJobs(1)[0]))
+	assert.ErrorIs(t, s.CreateCycle(models.WithProject(ctx, "team-b"), &models.Cycle{UUID: "c5"}), ErrUnknownProject)
+
+	cached := NewCachedStore(s, CacheConfig{Size: 10})
+	_, err = cached.GetCycle(ctx, "c1")
+	require.NoError(t, err)
+	_, err = cached.GetCycle(other, "c1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "cached rows of other projects are hidden")
+
+	assert.ErrorIs(t, s.DeleteProject(ctx, "team-a"), ErrConflict, "projects with cycles are kept")
+	assert.ErrorIs(t, s.DeleteProject(ctx, models.DefaultProject), ErrConflict)
+	require.NoError(t, s.DeleteCycleData(ctx, "c1"))
+	require.NoError(t, s.DeleteCycleData(ctx, "c4"))
+	require.NoError(t, s.DeleteProject(ctx, "team-a"))
+	projects, err := s.ListProjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, models.DefaultProject, projects[0].ID)
+}
+
+func TestIntegrity(t *testing.T) {
+	s := newTestStore(t)
+	s.batchSize = 2
+	ctx := context.Background()
+	createCycles(t, s, "c1")
+
+	assert.ErrorIs(t, s.CreateJob(ctx, &models.Job{CycleUUID: "c9", SessionID: "s1"}), ErrCycleNotFound)
+	assert.ErrorIs(t, s.CreateJobsBatch(ctx, []models.Job{{CycleUUID: "c1"}, {CycleUUID: "c9"}}), ErrCycleNotFound)
+	assert.ErrorIs(t, s.CreateSession(ctx, &models.Session{CycleUUID: "c9"}), ErrCycleNotFound)
+	assert.ErrorIs(t, s.CreateFile(ctx, &models.File{CycleID: "c9", Name: "a.txt"}), ErrCycleNotFound)
+	assert.ErrorIs(t, s.CreateWorkspace(ctx, &models.Workspace{CycleID: "c9"}), ErrCycleNotFound)
+	counts, err := s.CountJobsByStatus(ctx, "c1")
+	require.NoError(t, err)
+	assert.Empty(t, counts, "a batch with a missing cycle saves nothing")
+	require.NoError(t, s.CreateProject(ctx, &models.Project{ID: "team-a"}))
+	assert.ErrorIs(t, s.CreateJob(models.WithProject(ctx, "team-a"), &models.Job{CycleUUID: "c1"}), ErrCycleNotFound,
+		"cycles of other projects are missing")
+
+	require.NoError(t, s.CreateJobsBatch(ctx, []models.Job{{UUID: "j1", CycleUUID: "c1"}, {UUID: "j2", CycleUUID: "c1"}, {UUID: "j3", CycleUUID: "c1"}}))
+	assert.ErrorIs(t, s.CreateJob(ctx, &models.Job{UUID: "j1", CycleUUID: "c1"}), ErrDuplicateUUID)
+	assert.ErrorIs(t, s.CreateJobsBatch(ctx, []models.Job{{UUID: "j4", CycleUUID: "c1"}, {UUID: "j4", CycleUUID: "c1"}}), ErrDuplicateUUID)
+	assert.ErrorIs(t, s.CreateJobsBatch(ctx, []models.Job{{UUID: "j4", CycleUUID: "c1"}, {UUID: "j5", CycleUUID: "c1"}, {UUID: "j3", CycleUUID: "c1"}}),
+		ErrDuplicateUUID, "UUIDs are checked past the first batch")
+	assert.ErrorIs(t, s.CreateCycle(models.WithProject(ctx, "team-a"), &models.Cycle{UUID: "c1"}), ErrDuplicateUUID,
+		"UUIDs are unique across projects")
+	require.NoError(t, s.DeleteJob(ctx, "j2"))
+	assert.ErrorIs(t, s.CreateJob(ctx, &models.Job{UUID: "j2", CycleUUID: "c1"}), ErrDuplicateUUID, "deleted rows keep their UUID")
+	require.NoError(t, s.CreateJob(ctx, &models.Job{CycleUUID: "c1"}))
+}
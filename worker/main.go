@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"time"
 
@@ -19,12 +20,7 @@ func ProvideNATS(lc fx.Lifecycle, configService config.ConfigService, logger log
 	logger.Debug(ctx, "Initializing NATS connection")
 	config := configService.GetConfig()
 	broker := config.Broker
-	if broker == "" {
-		broker = "nats://localhost:4222"
-		logger.Info(ctx, "Using default NATS broker", "broker", broker)
-	} else {
-		logger.Info(ctx, "Using configured NATS broker", "broker", broker)
-	}
+	logger.Info(ctx, "Using NATS broker", "broker", broker)
 
 	// Connect with timeout and retry
 	nc, err := nats.Connect(broker,
@@ -96,11 +92,16 @@ func (l *CustomFxLogger) LogEvent(event fxevent.Event) {
 }
 
 func main() {
+	configFile := flag.String("config", "", "config file; ROBO_CONFIG or the first of config.SearchPaths by default")
+	profile := flag.String("profile", "", "profile of the config file, e.g. dev, staging or perf; ROBO_PROFILE by default")
+	flag.Parse()
+
 	app := fx.New(
 		fx.WithLogger(func(logger logger.Logger) fxevent.Logger {
 			return &CustomFxLogger{logger: logger}
 		}),
 		logger.ProvideLogger(),
+		fx.Supply(config.File(*configFile), config.Profile(*profile)),
 		config.Module,
 		fx.Provide(ProvideNATS),
 		fx.Provide(NewWorker),
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/songvi/robo/config"
+)
+
+// tusVersion is the tus resumable upload protocol version spoken by the uploader
+const tusVersion = "1.0.0"
+
+// defaultChunkSize is used when the upload config leaves chunk_size unset
+const defaultChunkSize = 5 * 1024 * 1024
+
+// maxOffsetConflicts bounds consecutive offset re-syncs before an upload is abandoned
+const maxOffsetConflicts = 3
+
+// errInterrupted marks a chunk deliberately cut short to exercise the resume path
+var errInterrupted = errors.New("upload deliberately interrupted")
+
+// errOffsetConflict is returned when the server's offset differs from the client's
+var errOffsetConflict = errors.New("upload offset conflict")
+
+// errUnauthorized is returned when the target rejects the worker's requests
+var errUnauthorized = errors.New("target rejected the worker's credentials")
+
+// ChunkReport records the outcome of one PATCH request
+type ChunkReport struct {
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// UploadReport is stored in the job output after a chunked upload
+type UploadReport struct {
+	URL           string        `json:"url"`
+	Size          int64         `json:"size"`
+	Interruptions int           `json:"interruptions"`
+	Chunks        []ChunkReport `json:"chunks"`
+}
+
+// tusUploader uploads files to a tus endpoint in chunks and resumes after interruptions
+type tusUploader struct {
+	client *http.Client
+	config config.UploadConfig
+}
+
+// newTusUploader creates a tusUploader from the worker's upload config
+func newTusUploader(cfg config.UploadConfig) *tusUploader {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = defaultChunkSize
+	}
+	return &tusUploader{client: &http.Client{}, config: cfg}
+}
+
+// Upload creates an upload for the file at path and sends it chunk by chunk
+func (u *tusUploader) Upload(ctx context.Context, path string) (*UploadReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat upload file: %w", err)
+	}
+
+	uploadURL, err := u.create(ctx, filepath.Base(path), info.Size())
+	if err != nil {
+		return nil, err
+	}
+	report := &UploadReport{URL: uploadURL, Size: info.Size()}
+
+	var offset int64
+	lastInterrupted := int64(-1)
+	conflicts := 0
+	for offset < info.Size() {
+		size := min(int64(u.config.ChunkSize), info.Size()-offset)
+		// Never interrupt twice at the same offset so the upload always makes progress
+		interrupt := offset != lastInterrupted && rand.Float64() < u.config.InterruptProbability
+		if interrupt {
+			lastInterrupted = offset
+		}
+
+		start := time.Now()
+		newOffset, err := u.patch(ctx, uploadURL, io.NewSectionReader(f, offset, size), offset, size, interrupt)
+		chunk := ChunkReport{Offset: offset, Size: size, LatencyMs: time.Since(start).Milliseconds()}
+		if err != nil {
+			chunk.Error = err.Error()
+			report.Chunks = append(report.Chunks, chunk)
+			switch {
+			case interrupt:
+				report.Interruptions++
+			case errors.Is(err, errOffsetConflict) && conflicts < maxOffsetConflicts:
+				// The server may still have been persisting an aborted chunk
+				conflicts++
+			default:
+				return report, err
+			}
+			// Resume from whatever the server persisted
+			if offset, err = u.offset(ctx, uploadURL); err != nil {
+				return report, err
+			}
+			continue
+		}
+		report.Chunks = append(report.Chunks, chunk)
+		offset = newOffset
+		conflicts = 0
+	}
+	return report, nil
+}
+
+// Probe asks the tus endpoint which uploads it accepts and returns the size of the
+// largest one, or 0 when it sets no limit
+func (u *tusUploader) Probe(ctx context.Context) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, u.config.Endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach the target: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return 0, errUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, fmt.Errorf("failed to probe the target: unexpected status %s", resp.Status)
+	}
+	maxSize := resp.Header.Get("Tus-Max-Size")
+	if maxSize == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseInt(maxSize, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Tus-Max-Size %q", maxSize)
+	}
+	return size, nil
+}
+
+// create issues the tus creation request and returns the absolute upload URL
+func (u *tusUploader) create(ctx context.Context, name string, size int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.config.Endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte(name)))
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to create upload: unexpected status %s", resp.Status)
+	}
+
+	base, err := url.Parse(u.config.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("invalid upload location: %w", err)
+	}
+	return base.ResolveReference(location).String(), nil
+}
+
+// patch sends one chunk at offset and returns the new offset reported by the server.
+// When interrupt is set only half of the chunk is sent before the request is aborted.
+func (u *tusUploader) patch(ctx context.Context, uploadURL string, body io.Reader, offset, size int64, interrupt bool) (int64, error) {
+	if interrupt {
+		body = io.MultiReader(io.LimitReader(body, size/2), errorReader{errInterrupted})
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, uploadURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload chunk at offset %d: %w", offset, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return 0, fmt.Errorf("failed to upload chunk at offset %d: %w", offset, errOffsetConflict)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return 0, fmt.Errorf("failed to upload chunk at offset %d: unexpected status %s", offset, resp.Status)
+	}
+	return strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+}
+
+// offset asks the server how many bytes of the upload it has persisted
+func (u *tusUploader) offset(ctx context.Context, uploadURL string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uploadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query upload offset: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, fmt.Errorf("failed to query upload offset: unexpected status %s", resp.Status)
+	}
+	return strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+}
+
+// errorReader always fails with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
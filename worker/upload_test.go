@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/config"
+)
+
+// fakeTusServer is a minimal tus server keeping a single upload in memory
+type fakeTusServer struct {
+	mu   sync.Mutex
+	data bytes.Buffer
+}
+
+func (s *fakeTusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Tus-Resumable", tusVersion)
+	switch r.Method {
+	case http.MethodPost:
+		w.Header().Set("Location", "/files/1")
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPatch:
+		if r.Header.Get("Upload-Offset") != strconv.Itoa(s.data.Len()) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		// Persist whatever arrived, even when the client aborts mid-chunk
+		io.Copy(&s.data, r.Body)
+		w.Header().Set("Upload-Offset", strconv.Itoa(s.data.Len()))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		w.Header().Set("Upload-Offset", strconv.Itoa(s.data.Len()))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestTusUploaderResumesAfterInterruption(t *testing.T) {
+	server := &fakeTusServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	content := bytes.Repeat([]byte("robo upload "), 10000)
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	uploader := newTusUploader(config.UploadConfig{
+		Endpoint:             ts.URL + "/files",
+		ChunkSize:            16 * 1024,
+		InterruptProbability: 1,
+	})
+	report, err := uploader.Upload(context.Background(), path)
+	require.NoError(t, err)
+
+	assert.Equal(t, content, server.data.Bytes(), "server should hold the complete file")
+	assert.Equal(t, ts.URL+"/files/1", report.URL)
+	assert.Greater(t, report.Interruptions, 0, "interruptions should be exercised")
+	assert.NotEmpty(t, report.Chunks)
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -11,8 +16,27 @@ import (
 
 	"github.com/songvi/robo/config"
 	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
 )
 
+// supportedActions lists the job names this worker knows how to execute
+var supportedActions = map[string]bool{
+	"login":            true,
+	"logout":           true,
+	"create_user":      true,
+	"create_workspace": true,
+	"upload_file":      true,
+	"download_file":    true,
+	"consult_file":     true,
+	"delete_file":      true,
+	"delete_workspace": true,
+	"delete_user":      true,
+}
+
+// version is the release of the worker, reported when it registers; set it at build time
+// with -ldflags "-X main.version=..."
+var version = "dev"
+
 // Job defines the structure of a job (same as dispatcher)
 type Job struct {
 	UUID       string          `json:"uuid" yaml:"uuid"`
@@ -23,7 +47,13 @@ type Job struct {
 	Error      string          `json:"error" yaml:"error"`
 	StartAt    int64           `json:"start_at" yaml:"start_at"`
 	DoneAt     int64           `json:"done_at" yaml:"done_at"`
+	DurationMs int64           `json:"duration_ms" yaml:"duration_ms"`
 	Status     string          `json:"status" yaml:"status"`
+	// DispatchedAt, CycleUUID, SessionID and Phase travel back with the result unchanged
+	DispatchedAt int64  `json:"dispatched_at" yaml:"dispatched_at"`
+	CycleUUID    string `json:"cycle_uuid" yaml:"cycle_uuid"`
+	SessionID    string `json:"session_id" yaml:"session_id"`
+	Phase        string `json:"phase,omitempty" yaml:"phase,omitempty"`
 }
 
 // Worker defines the worker service
@@ -38,6 +68,18 @@ type workerImpl struct {
 	config   config.ConfigService
 	workerID string
 	name     string
+
+	// settingsMu guards the settings the control plane may publish while the worker runs
+	settingsMu sync.RWMutex
+	uploader   *tusUploader
+	labels     map[string]string
+	registered atomic.Bool
+
+	// jobMu guards the running job and the cycle cancellations received so far
+	jobMu         sync.Mutex
+	runningCycle  string
+	cancelRunning context.CancelFunc
+	cancelledAt   map[string]int64 // Cycle UUID -> dispatcher time of its last cancellation
 }
 
 // NewWorker creates a new Worker instance
@@ -48,7 +90,10 @@ func NewWorker(lc fx.Lifecycle, config config.ConfigService, logger logger.Logge
 		config:   config,
 		workerID: "worker-1", // Should be unique, e.g., generated UUID
 		name:     "Worker1",
+
+		cancelledAt: make(map[string]int64),
 	}
+	w.setSettings(config.GetConfig().WorkerSettings())
 
 	ctx, cancel := context.WithCancel(context.Background())
 	lc.Append(fx.Hook{
@@ -68,16 +113,71 @@ func NewWorker(lc fx.Lifecycle, config config.ConfigService, logger logger.Logge
 
 // Start begins worker operations
 func (w *workerImpl) Start(ctx context.Context) error {
-	// Register worker
+	// Take the settings the control plane published, if any, before registering
+	if remote := w.config.GetConfig().Remote; remote.Bucket != "" {
+		err := config.WatchWorkerSettings(ctx, w.nc, remote, w.logger, func(settings config.WorkerSettings) {
+			// The dispatcher learns new labels from a new registration
+			if w.applySettings(ctx, settings) && w.registered.Load() {
+				if err := w.register(ctx); err != nil {
+					w.logger.Error(ctx, "Failed to register again with new labels", "worker_id", w.workerID, "error", err)
+				}
+			}
+		})
+		if err != nil {
+			w.logger.Error(ctx, "Failed to watch remote worker settings, keeping the local ones", "bucket", remote.Bucket, "error", err)
+		}
+	}
+	if err := w.register(ctx); err != nil {
+		return err
+	}
+	w.registered.Store(true)
+
+	// Subscribe to jobs
+	jobSubject := fmt.Sprintf("dispatcher.job.%s", w.workerID)
+	jobCh, err := w.subscribe(ctx, jobSubject)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to jobs: %w", err)
+	}
+	go w.handleJobs(ctx, jobCh)
+
+	// Stop the jobs of paused and aborted cycles
+	cancelCh, err := w.subscribe(ctx, "dispatcher.job.cancel")
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to job cancellations: %w", err)
+	}
+	go w.handleCancellations(ctx, cancelCh)
+
+	// Answer preflight checks before cycle start
+	preflightCh, err := w.subscribe(ctx, fmt.Sprintf("dispatcher.worker.preflight.%s", w.workerID))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to preflight requests: %w", err)
+	}
+	go w.handlePreflight(ctx, preflightCh)
+
+	// Start heartbeat
+	go w.sendHeartbeats(ctx)
+
+	return nil
+}
+
+// register announces the worker, with its capabilities and labels, to the dispatcher
+func (w *workerImpl) register(ctx context.Context) error {
+	w.settingsMu.RLock()
+	labels := w.labels
+	w.settingsMu.RUnlock()
 	regMsg := struct {
-		WorkerID     string   `json:"worker_id"`
-		Name         string   `json:"name"`
-		Capabilities []string `json:"capabilities"`
-		Status       string   `json:"status"`
+		WorkerID     string            `json:"worker_id"`
+		Name         string            `json:"name"`
+		Capabilities []string          `json:"capabilities"`
+		Labels       map[string]string `json:"labels,omitempty"`
+		Version      string            `json:"version,omitempty"`
+		Status       string            `json:"status"`
 	}{
 		WorkerID:     w.workerID,
 		Name:         w.name,
-		Capabilities: []string{"file_processing", "task_execution"},
+		Capabilities: slices.Sorted(maps.Keys(supportedActions)),
+		Labels:       labels,
+		Version:      version,
 		Status:       "registered",
 	}
 	data, err := json.Marshal(regMsg)
@@ -87,20 +187,34 @@ func (w *workerImpl) Start(ctx context.Context) error {
 	if err := w.nc.Publish("dispatcher.worker.register", data); err != nil {
 		return fmt.Errorf("failed to publish registration: %w", err)
 	}
-	w.logger.Info(ctx, "Worker registered", "worker_id", w.workerID, "name", w.name)
+	w.logger.Info(ctx, "Worker registered", "worker_id", w.workerID, "name", w.name, "labels", regMsg.Labels, "version", version)
+	return nil
+}
 
-	// Subscribe to jobs
-	jobSubject := fmt.Sprintf("dispatcher.job.%s", w.workerID)
-	jobCh, err := w.subscribe(ctx, jobSubject)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to jobs: %w", err)
+// setSettings takes the upload and worker settings
+func (w *workerImpl) setSettings(settings config.WorkerSettings) {
+	w.settingsMu.Lock()
+	defer w.settingsMu.Unlock()
+	w.uploader = nil
+	if settings.Upload.Endpoint != "" {
+		w.uploader = newTusUploader(settings.Upload)
 	}
-	go w.handleJobs(ctx, jobCh)
-
-	// Start heartbeat
-	go w.sendHeartbeats(ctx)
+	w.labels = settings.Worker.Labels
+}
 
-	return nil
+// applySettings takes the settings the control plane published when they are valid,
+// and tells whether they changed the labels of the worker
+func (w *workerImpl) applySettings(ctx context.Context, settings config.WorkerSettings) bool {
+	if _, err := w.config.GetConfig().WithWorkerSettings(settings); err != nil {
+		w.logger.Error(ctx, "Ignoring invalid remote worker settings", "error", err)
+		return false
+	}
+	w.settingsMu.RLock()
+	changed := !maps.Equal(w.labels, settings.Worker.Labels)
+	w.settingsMu.RUnlock()
+	w.setSettings(settings)
+	w.logger.Info(ctx, "Applied remote worker settings", "upload_endpoint", settings.Upload.Endpoint, "labels", settings.Worker.Labels)
+	return changed
 }
 
 // subscribe subscribes to a NATS subject
@@ -121,36 +235,209 @@ func (w *workerImpl) subscribe(ctx context.Context, subject string) (<-chan *nat
 	return msgCh, nil
 }
 
-// handleJobs processes incoming jobs
+// jobBatch carries several jobs of a session, run in order and answered together
+type jobBatch struct {
+	Jobs []Job `json:"jobs" yaml:"jobs"`
+}
+
+// handleJobs processes incoming jobs and job batches
 func (w *workerImpl) handleJobs(ctx context.Context, jobCh <-chan *nats.Msg) {
 	for msg := range jobCh {
+		var batch jobBatch
+		if err := json.Unmarshal(msg.Data, &batch); err == nil && len(batch.Jobs) > 0 {
+			w.logger.Info(ctx, "Received job batch", "jobs", len(batch.Jobs))
+			for i := range batch.Jobs {
+				w.runJob(ctx, &batch.Jobs[i])
+			}
+			w.publishResult(ctx, batch, "jobs", len(batch.Jobs))
+			continue
+		}
+
 		var job Job
 		if err := json.Unmarshal(msg.Data, &job); err != nil {
 			w.logger.Error(ctx, "Failed to unmarshal job", "error", err)
 			continue
 		}
 		w.logger.Info(ctx, "Received job", "job_uuid", job.UUID, "job_name", job.Name)
+		w.runJob(ctx, &job)
+		w.publishResult(ctx, job, "job_uuid", job.UUID)
+	}
+}
 
-		// Process the job (placeholder logic)
-		job.StartAt = time.Now().Unix()
-		job.Status = "processing"
-		// Example: Process InputData and set OutputData
-		job.OutputData = []byte(`{"result":"processed"}`)
+// runJob processes the job unless its cycle was cancelled after it was dispatched, and
+// sets its status and timings
+func (w *workerImpl) runJob(ctx context.Context, job *Job) {
+	start := time.Now()
+	job.StartAt = start.Unix()
+	job.Status = "processing"
+	if jobCtx := w.beginJob(ctx, job); jobCtx == nil {
+		w.logger.Info(ctx, "Dropped job of cancelled cycle", "job_uuid", job.UUID, "cycle_uuid", job.CycleUUID)
+		job.Status = "cancelled"
+	} else if err := w.executeJob(jobCtx, job); err != nil {
+		if jobCtx.Err() != nil && ctx.Err() == nil {
+			w.logger.Info(ctx, "Job cancelled", "job_uuid", job.UUID, "cycle_uuid", job.CycleUUID)
+			job.Status = "cancelled"
+		} else {
+			w.logger.Error(ctx, "Job failed", "job_uuid", job.UUID, "error", err)
+			job.Error = err.Error()
+			job.Status = "failed"
+		}
+	} else {
 		job.Status = "completed"
-		job.DoneAt = time.Now().Unix()
+	}
+	w.endJob()
+	job.DoneAt = time.Now().Unix()
+	job.DurationMs = time.Since(start).Milliseconds()
+}
+
+// publishResult sends the result of a job or a batch to the JobService
+func (w *workerImpl) publishResult(ctx context.Context, result interface{}, args ...interface{}) {
+	resultData, err := json.Marshal(result)
+	if err != nil {
+		w.logger.Error(ctx, "Failed to marshal job result", append(args, "error", err)...)
+		return
+	}
+	if err := w.nc.Publish("dispatcher.job.result", resultData); err != nil {
+		w.logger.Error(ctx, "Failed to publish job result", append(args, "error", err)...)
+		return
+	}
+	w.logger.Info(ctx, "Job completed", append(args, "worker_id", w.workerID)...)
+}
+
+// beginJob returns the context job runs under, or nil when its cycle was cancelled
+// after the job was dispatched
+func (w *workerImpl) beginJob(ctx context.Context, job *Job) context.Context {
+	w.jobMu.Lock()
+	defer w.jobMu.Unlock()
+	if at, ok := w.cancelledAt[job.CycleUUID]; ok && job.DispatchedAt <= at {
+		return nil
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	w.runningCycle, w.cancelRunning = job.CycleUUID, cancel
+	return jobCtx
+}
+
+// endJob releases the context of the running job
+func (w *workerImpl) endJob() {
+	w.jobMu.Lock()
+	defer w.jobMu.Unlock()
+	if w.cancelRunning != nil {
+		w.cancelRunning()
+	}
+	w.runningCycle, w.cancelRunning = "", nil
+}
+
+// handleCancellations cancels the running job of a cancelled cycle and remembers the
+// cancellation so its queued jobs are dropped
+func (w *workerImpl) handleCancellations(ctx context.Context, cancelCh <-chan *nats.Msg) {
+	for msg := range cancelCh {
+		var c models.CycleCancellation
+		if err := json.Unmarshal(msg.Data, &c); err != nil {
+			w.logger.Error(ctx, "Failed to unmarshal cycle cancellation", "error", err)
+			continue
+		}
+		w.jobMu.Lock()
+		if c.At > w.cancelledAt[c.CycleUUID] {
+			w.cancelledAt[c.CycleUUID] = c.At
+		}
+		if w.runningCycle == c.CycleUUID && w.cancelRunning != nil {
+			w.cancelRunning()
+		}
+		w.jobMu.Unlock()
+		w.logger.Info(ctx, "Cancelled cycle jobs", "cycle_uuid", c.CycleUUID)
+	}
+}
+
+// executeJob runs the job's action and sets its OutputData
+func (w *workerImpl) executeJob(ctx context.Context, job *Job) error {
+	var input struct {
+		FilePath string `json:"file_path"`
+	}
+	if len(job.InputData) > 0 {
+		if err := json.Unmarshal(job.InputData, &input); err != nil {
+			return fmt.Errorf("invalid input data: %w", err)
+		}
+	}
+
+	w.settingsMu.RLock()
+	uploader := w.uploader
+	w.settingsMu.RUnlock()
+	switch {
+	case job.Name == "upload_file" && uploader != nil && input.FilePath != "":
+		report, err := uploader.Upload(ctx, input.FilePath)
+		if report != nil {
+			if data, marshalErr := json.Marshal(report); marshalErr == nil {
+				job.OutputData = data
+			}
+		}
+		return err
+	default:
+		// Placeholder logic for actions without a target implementation
+		job.OutputData = []byte(`{"result":"processed"}`)
+		return nil
+	}
+}
+
+// preflightProbeTimeout bounds how long a preflight check waits for the target
+const preflightProbeTimeout = 5 * time.Second
+
+// handlePreflight answers the preflight requests of the JobService sent to this worker
+func (w *workerImpl) handlePreflight(ctx context.Context, preflightCh <-chan *nats.Msg) {
+	for msg := range preflightCh {
+		var req models.PreflightRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			w.logger.Error(ctx, "Failed to unmarshal preflight request", "error", err)
+			continue
+		}
 
-		// Publish result
-		resultData, err := json.Marshal(job)
+		report := w.preflight(ctx, req)
+		data, err := json.Marshal(report)
 		if err != nil {
-			w.logger.Error(ctx, "Failed to marshal job result", "job_uuid", job.UUID, "error", err)
+			w.logger.Error(ctx, "Failed to marshal preflight report", "cycle_uuid", req.CycleUUID, "error", err)
 			continue
 		}
-		if err := w.nc.Publish("dispatcher.job.result", resultData); err != nil {
-			w.logger.Error(ctx, "Failed to publish job result", "job_uuid", job.UUID, "error", err)
+		if err := msg.Respond(data); err != nil {
+			w.logger.Error(ctx, "Failed to respond to preflight request", "cycle_uuid", req.CycleUUID, "error", err)
 			continue
 		}
-		w.logger.Info(ctx, "Job completed", "job_uuid", job.UUID, "worker_id", job.WorkerID)
+		w.logger.Info(ctx, "Answered preflight request", "cycle_uuid", req.CycleUUID, "target", req.Target)
+	}
+}
+
+// preflight checks the target against req. Only uploads reach the target, so the
+// check is a probe of the upload endpoint, which tells whether the target accepts the
+// worker and the largest upload it takes; tus reports no free space, so the quota is
+// unknown (-1). Without an endpoint the worker only simulates its actions and fails
+// the check, as nothing of the target can be verified.
+func (w *workerImpl) preflight(ctx context.Context, req models.PreflightRequest) models.PreflightReport {
+	report := models.PreflightReport{
+		WorkerID:       w.workerID,
+		Permissions:    make(map[string]bool, len(req.Actions)),
+		AvailableBytes: -1,
+	}
+	for _, action := range req.Actions {
+		report.Permissions[action] = supportedActions[action]
+	}
+
+	w.settingsMu.RLock()
+	uploader := w.uploader
+	w.settingsMu.RUnlock()
+	if uploader == nil {
+		report.Errors = append(report.Errors, "no upload endpoint is configured, so the target cannot be checked")
+		return report
+	}
+	ctx, cancel := context.WithTimeout(ctx, preflightProbeTimeout)
+	defer cancel()
+	maxSize, err := uploader.Probe(ctx)
+	switch {
+	case errors.Is(err, errUnauthorized):
+	case err != nil:
+		report.Errors = append(report.Errors, err.Error())
+	default:
+		report.CredentialsOK = true
+		report.MaxUploadBytes = maxSize
 	}
+	return report
 }
 
 // sendHeartbeats sends periodic heartbeats
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songvi/robo/config"
+	"github.com/songvi/robo/logger"
+	"github.com/songvi/robo/models"
+)
+
+func TestCycleCancellation(t *testing.T) {
+	w := &workerImpl{logger: logger.NewSlogLogger(), cancelledAt: make(map[string]int64)}
+	ctx := context.Background()
+
+	running := &Job{UUID: "j1", CycleUUID: "c1", DispatchedAt: 100}
+	jobCtx := w.beginJob(ctx, running)
+	require.NotNil(t, jobCtx)
+
+	data, err := json.Marshal(models.CycleCancellation{CycleUUID: "c1", At: 200})
+	require.NoError(t, err)
+	cancelCh := make(chan *nats.Msg, 1)
+	cancelCh <- &nats.Msg{Data: data}
+	close(cancelCh)
+	w.handleCancellations(ctx, cancelCh)
+
+	assert.Error(t, jobCtx.Err(), "the running job of the cycle is cancelled")
+	w.endJob()
+
+	assert.Nil(t, w.beginJob(ctx, &Job{UUID: "j2", CycleUUID: "c1", DispatchedAt: 150}), "jobs queued before the cancellation are dropped")
+	other := w.beginJob(ctx, &Job{UUID: "j3", CycleUUID: "c2", DispatchedAt: 150})
+	require.NotNil(t, other, "other cycles are unaffected")
+	w.endJob()
+	resumed := w.beginJob(ctx, &Job{UUID: "j2", CycleUUID: "c1", DispatchedAt: 300})
+	require.NotNil(t, resumed, "jobs dispatched again after a resume run")
+	assert.NoError(t, resumed.Err())
+	w.endJob()
+}
+
+// staticConfig serves a fixed config
+type staticConfig config.Config
+
+func (c staticConfig) GetConfig() config.Config { return config.Config(c) }
+
+func TestApplySettings(t *testing.T) {
+	w := &workerImpl{logger: logger.NewSlogLogger(), config: staticConfig{}}
+	ctx := context.Background()
+	w.setSettings(config.WorkerSettings{Worker: config.WorkerConfig{Labels: map[string]string{"region": "eu"}}})
+	assert.Nil(t, w.uploader)
+
+	assert.False(t, w.applySettings(ctx, config.WorkerSettings{
+		Upload: config.UploadConfig{Endpoint: "http://target/files"},
+		Worker: config.WorkerConfig{Labels: map[string]string{"region": "eu"}},
+	}), "the labels are the same")
+	assert.NotNil(t, w.uploader)
+	assert.True(t, w.applySettings(ctx, config.WorkerSettings{Worker: config.WorkerConfig{Labels: map[string]string{"region": "us"}}}))
+	assert.Nil(t, w.uploader, "an empty endpoint disables real uploads")
+	assert.Equal(t, "us", w.labels["region"])
+
+	assert.False(t, w.applySettings(ctx, config.WorkerSettings{Upload: config.UploadConfig{ChunkSize: -1}}))
+	assert.Equal(t, "us", w.labels["region"], "invalid settings are ignored")
+}
+
+func TestPreflight(t *testing.T) {
+	status := http.StatusNoContent
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodOptions, r.Method)
+		w.Header().Set("Tus-Max-Size", "4096")
+		w.WriteHeader(status)
+	}))
+	defer target.Close()
+	w := &workerImpl{logger: logger.NewSlogLogger(), workerID: "w1"}
+	ctx := context.Background()
+	req := models.PreflightRequest{Actions: []string{"upload_file", "share_file"}, RequiredBytes: 10000, MaxFileBytes: 8192}
+
+	report := w.preflight(ctx, req)
+	assert.False(t, report.CredentialsOK, "workers without a target fail closed")
+	assert.NotEmpty(t, report.Failures(req))
+
+	w.setSettings(config.WorkerSettings{Upload: config.UploadConfig{Endpoint: target.URL}})
+	report = w.preflight(ctx, req)
+	assert.True(t, report.CredentialsOK)
+	assert.Equal(t, int64(-1), report.AvailableBytes)
+	assert.Equal(t, []string{
+		`missing permission for action "share_file"`,
+		"target accepts uploads of up to 4096 bytes, files of up to 8192 are planned",
+	}, report.Failures(req))
+
+	status = http.StatusUnauthorized
+	report = w.preflight(ctx, req)
+	assert.False(t, report.CredentialsOK)
+	assert.Contains(t, report.Failures(req), "target credentials were rejected")
+}